@@ -0,0 +1,155 @@
+package commands
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/git-lfs/git-lfs/filepathfilter"
+)
+
+// cleanRule is a config.CleanRule, with the filter its pattern is matched by.
+type cleanRule struct {
+	*config.CleanRule
+
+	filter *filepathfilter.Filter
+}
+
+// cleanRules are the rules given by lfs.cleanrule.<pattern>, which decide, by
+// their contents, whether files are cleaned into pointers, or are passed
+// through to Git unchanged.
+type cleanRules []*cleanRule
+
+var (
+	cleanRulesOnce sync.Once
+	loadedRules    cleanRules
+)
+
+// loadCleanRules returns the clean rules given in the configuration, which are
+// only read once per process, however many files are cleaned or smudged. An
+// invalid rule is fatal, since which files become pointers depends on it.
+func loadCleanRules() cleanRules {
+	cleanRulesOnce.Do(func() {
+		configured, err := cfg.CleanRules()
+		if err != nil {
+			ExitWithError(err)
+		}
+
+		for _, r := range configured {
+			loadedRules = append(loadedRules, &cleanRule{
+				CleanRule: r,
+				filter:    filepathfilter.New([]string{r.Pattern}, nil),
+			})
+		}
+	})
+	return loadedRules
+}
+
+// For returns the first rule whose pattern matches "filename", or nil if none
+// does.
+func (rules cleanRules) For(filename string) *cleanRule {
+	if len(filename) == 0 {
+		return nil
+	}
+
+	// Patterns are read in lower case, so match the file name in lower case,
+	// too.
+	name := strings.ToLower(filename)
+	for _, r := range rules {
+		if r.filter.Allows(name) {
+			return r
+		}
+	}
+	return nil
+}
+
+// peekSize returns how many bytes from the start of a file Cleans needs to
+// match each signature.
+func (r *cleanRule) peekSize() int {
+	var n int
+	for _, sig := range r.Magic {
+		if len(sig) > n {
+			n = len(sig)
+		}
+	}
+	return n
+}
+
+// Cleans returns whether a file of "size" bytes, which starts with "head",
+// should be cleaned into a pointer. If "size" is negative, it is unknown, and
+// only "head" is checked.
+func (r *cleanRule) Cleans(head []byte, size int64) bool {
+	if size >= 0 && uint64(size) < r.MinSize {
+		return false
+	}
+	if len(r.Magic) == 0 {
+		return true
+	}
+	for _, sig := range r.Magic {
+		if bytes.HasPrefix(head, sig) {
+			return true
+		}
+	}
+	return false
+}
+
+// Peek reads as much of the start of "from" as Cleans needs to match each
+// signature, and returns it, along with the size of "from", if all of it was
+// read, or -1 if not, and a reader of all of "from", including what was read.
+// Only the longest signature is held in memory; see Measure for the size.
+func (r *cleanRule) Peek(from io.Reader) ([]byte, int64, io.Reader, error) {
+	head := make([]byte, r.peekSize())
+	n, err := io.ReadFull(from, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, 0, nil, err
+	}
+	head = head[:n]
+
+	if err != nil {
+		// All of "from" has been read, and it is not read again,
+		// since some readers, like the payload of a filter-process
+		// request, go on to the next thing once they have ended.
+		return head, int64(n), bytes.NewReader(head), nil
+	}
+	return head, -1, io.MultiReader(bytes.NewReader(head), from), nil
+}
+
+// Measure reads up to MinSize bytes of "from", to tell whether it is smaller,
+// and returns its size if it is, or -1 if not, with a reader of all of "from",
+// including what was read. What is read is spooled to a temporary file in
+// "dir", rather than held in memory, which is removed by the func returned.
+func (r *cleanRule) Measure(from io.Reader, dir string) (int64, io.Reader, func(), error) {
+	if r.MinSize == 0 {
+		return -1, from, func() {}, nil
+	}
+
+	tmp, err := ioutil.TempFile(dir, "")
+	if err != nil {
+		return 0, nil, nil, errors.Wrap(err, "clean rule tmp")
+	}
+	done := func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}
+
+	n, err := io.CopyN(tmp, from, int64(r.MinSize))
+	if err != nil && err != io.EOF {
+		done()
+		return 0, nil, nil, errors.Wrap(err, "unable to spool")
+	}
+	if _, serr := tmp.Seek(0, io.SeekStart); serr != nil {
+		done()
+		return 0, nil, nil, errors.Wrap(serr, "unable to seek")
+	}
+
+	if err == io.EOF {
+		// As in Peek, "from" is not read again once it has ended.
+		return n, tmp, done, nil
+	}
+	return -1, io.MultiReader(tmp, from), done, nil
+}
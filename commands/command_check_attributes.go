@@ -0,0 +1,362 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/git-lfs/git-lfs/filepathfilter"
+	"github.com/git-lfs/git-lfs/git"
+	"github.com/git-lfs/git-lfs/tools"
+	"github.com/spf13/cobra"
+)
+
+var (
+	checkAttributesJSON   bool
+	checkAttributesSample int
+)
+
+// checkAttributesCommand reports problems with the rules in the gitattributes
+// files of the working copy which decide whether Git LFS tracks each file, by
+// matching every file in the working copy against them.
+func checkAttributesCommand(cmd *cobra.Command, args []string) {
+	requireGitVersion()
+
+	if config.LocalGitDir == "" {
+		Print("Not a git repository.")
+		os.Exit(128)
+	}
+
+	if config.LocalWorkingDir == "" {
+		Print("This operation must be run in a work tree.")
+		os.Exit(128)
+	}
+
+	files, err := workingTreeFiles(config.LocalWorkingDir)
+	if err != nil {
+		ExitWithError(err)
+	}
+
+	report := checkAttributes(git.GetFilterAttributeRules(config.LocalWorkingDir, config.LocalGitDir), files)
+	if checkAttributesSample >= 0 && len(report.Files) > checkAttributesSample {
+		report.Files = report.Files[:checkAttributesSample]
+	}
+
+	if checkAttributesJSON {
+		if err := json.NewEncoder(os.Stdout).Encode(report); err != nil {
+			ExitWithError(err)
+		}
+		return
+	}
+
+	report.Print()
+}
+
+// attributeRule is a rule from git.GetFilterAttributeRules, as it is reported.
+type attributeRule struct {
+	Pattern string `json:"pattern"`
+	Source  string `json:"source"`
+	Line    int    `json:"line"`
+	Filter  string `json:"filter"`
+	Tracked bool   `json:"tracked"`
+
+	pattern filepathfilter.Pattern
+	rank    int
+	matches int
+}
+
+func (r *attributeRule) String() string {
+	return fmt.Sprintf("%q (%s:%d)", r.Pattern, r.Source, r.Line)
+}
+
+// attributeConflict is a pair of rules which both match the same files, which
+// "Rule" applies to, rather than "Other", because it takes precedence.
+type attributeConflict struct {
+	Rule    *attributeRule `json:"rule"`
+	Other   *attributeRule `json:"other"`
+	Files   int            `json:"files"`
+	Example string         `json:"example"`
+}
+
+// attributeRedundancy is a rule which is never applied to anything, because
+// "By" takes precedence over it, and gives whatever it matches the same filter.
+type attributeRedundancy struct {
+	Rule   *attributeRule `json:"rule"`
+	By     *attributeRule `json:"by"`
+	Reason string         `json:"reason"`
+}
+
+// attributeFile is the classification of a file in the working copy.
+type attributeFile struct {
+	Path    string         `json:"path"`
+	Tracked bool           `json:"tracked"`
+	Rule    *attributeRule `json:"rule"`
+}
+
+type attributeReport struct {
+	Rules       []*attributeRule       `json:"rules"`
+	Redundant   []*attributeRedundancy `json:"redundant"`
+	Shadowed    []*attributeConflict   `json:"shadowed"`
+	Overlapping []*attributeConflict   `json:"overlapping"`
+	Unused      []*attributeRule       `json:"unused"`
+
+	TotalFiles   int              `json:"total_files"`
+	TrackedFiles int              `json:"tracked_files"`
+	Files        []*attributeFile `json:"files"`
+}
+
+// checkAttributes matches each of "files" against "rules", which are in order
+// of precedence, to find:
+//
+//   - redundant rules, which repeat a rule that takes precedence over them, or
+//     only match files which such rules give the same filter anyway,
+//   - shadowed rules, which would track files with Git LFS, or not, except
+//     that a rule which takes precedence over them says otherwise,
+//   - overlapping rules, which both track the same files with Git LFS, and
+//   - unused rules, which match no files at all.
+func checkAttributes(gitRules []*git.FilterAttributeRule, files []string) *attributeReport {
+	report := &attributeReport{
+		Rules:       make([]*attributeRule, 0, len(gitRules)),
+		Redundant:   make([]*attributeRedundancy, 0),
+		Shadowed:    make([]*attributeConflict, 0),
+		Overlapping: make([]*attributeConflict, 0),
+		Unused:      make([]*attributeRule, 0),
+		Files:       make([]*attributeFile, 0, len(files)),
+		TotalFiles:  len(files),
+	}
+
+	for i, r := range gitRules {
+		report.Rules = append(report.Rules, &attributeRule{
+			Pattern: r.Path,
+			Source:  r.Source.Path,
+			Line:    r.Line,
+			Filter:  r.Filter,
+			Tracked: r.LFS(),
+			pattern: filepathfilter.NewPattern(r.Path),
+			rank:    i,
+		})
+	}
+
+	// Repeated patterns are redundant whatever files there are.
+	duplicates := make(map[*attributeRule]bool)
+	seen := make(map[string]*attributeRule)
+	for _, r := range report.Rules {
+		if first, ok := seen[r.Pattern]; ok {
+			duplicates[r] = true
+			if first.Filter == r.Filter {
+				report.Redundant = append(report.Redundant, &attributeRedundancy{
+					Rule: r, By: first, Reason: "duplicate",
+				})
+			} else {
+				report.Shadowed = append(report.Shadowed, &attributeConflict{
+					Rule: first, Other: r,
+				})
+			}
+			continue
+		}
+		seen[r.Pattern] = r
+	}
+
+	conflicts := make(map[[2]*attributeRule]*attributeConflict)
+	overlaps := make(map[[2]*attributeRule]*attributeConflict)
+	// applied holds the rule which applies instead of each rule, for every
+	// file it matches, or nil if it applies to some of them itself, or
+	// they do not all agree.
+	applied := make(map[*attributeRule]*attributeRule)
+
+	for _, name := range files {
+		var matched []*attributeRule
+		for _, r := range report.Rules {
+			if r.pattern.Match(name) {
+				r.matches++
+				matched = append(matched, r)
+			}
+		}
+
+		file := &attributeFile{Path: name}
+		report.Files = append(report.Files, file)
+		if len(matched) == 0 {
+			continue
+		}
+
+		top := matched[0]
+		file.Rule = top
+		file.Tracked = top.Tracked
+		if file.Tracked {
+			report.TrackedFiles++
+		}
+		applied[top] = nil
+
+		for _, r := range matched[1:] {
+			if by, ok := applied[r]; !ok {
+				applied[r] = top
+			} else if by != top {
+				applied[r] = nil
+			}
+
+			if duplicates[r] {
+				continue
+			}
+
+			key := [2]*attributeRule{top, r}
+			set := overlaps
+			if r.Tracked != top.Tracked || r.Filter != top.Filter {
+				set = conflicts
+			} else if !r.Tracked {
+				continue
+			}
+
+			c, ok := set[key]
+			if !ok {
+				c = &attributeConflict{Rule: top, Other: r, Example: name}
+				set[key] = c
+			}
+			c.Files++
+		}
+	}
+
+	for _, r := range report.Rules {
+		if duplicates[r] {
+			continue
+		}
+		if r.matches == 0 {
+			report.Unused = append(report.Unused, r)
+			continue
+		}
+		if by := applied[r]; by != nil && by.Filter == r.Filter {
+			report.Redundant = append(report.Redundant, &attributeRedundancy{
+				Rule: r, By: by, Reason: "covered",
+			})
+			// That they overlap goes without saying.
+			delete(overlaps, [2]*attributeRule{by, r})
+		}
+	}
+
+	report.Shadowed = append(report.Shadowed, sortedConflicts(conflicts)...)
+	report.Overlapping = append(report.Overlapping, sortedConflicts(overlaps)...)
+
+	return report
+}
+
+// sortedConflicts returns the conflicts in "set" in order of the precedence of
+// their rules, so that they are reported in the same order every time.
+func sortedConflicts(set map[[2]*attributeRule]*attributeConflict) []*attributeConflict {
+	conflicts := make([]*attributeConflict, 0, len(set))
+	for _, c := range set {
+		conflicts = append(conflicts, c)
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		if a, b := conflicts[i].Rule.rank, conflicts[j].Rule.rank; a != b {
+			return a < b
+		}
+		return conflicts[i].Other.rank < conflicts[j].Other.rank
+	})
+	return conflicts
+}
+
+func (r *attributeReport) Print() {
+	Print("Git LFS attribute rules, in order of precedence:")
+	if len(r.Rules) == 0 {
+		Print("  (none)")
+	}
+	for _, rule := range r.Rules {
+		state := "tracked"
+		if !rule.Tracked {
+			state = "untracked"
+			if len(rule.Filter) > 0 {
+				state = fmt.Sprintf("filter=%s", rule.Filter)
+			}
+		}
+		Print("  %-30s %-12s %s:%d", rule.Pattern, state, rule.Source, rule.Line)
+	}
+
+	if len(r.Redundant) > 0 {
+		Print("\nRedundant patterns:")
+		for _, red := range r.Redundant {
+			switch red.Reason {
+			case "duplicate":
+				Print("  %s repeats %s", red.Rule, red.By)
+			default:
+				Print("  %s only matches files already matched by %s", red.Rule, red.By)
+			}
+		}
+	}
+
+	if len(r.Shadowed) > 0 {
+		Print("\nShadowed patterns:")
+		for _, c := range r.Shadowed {
+			if c.Files == 0 {
+				Print("  %s is overridden by %s", c.Other, c.Rule)
+				continue
+			}
+			Print("  %s is overridden by %s for %d file(s), e.g. %s", c.Other, c.Rule, c.Files, c.Example)
+		}
+	}
+
+	if len(r.Overlapping) > 0 {
+		Print("\nOverlapping patterns:")
+		for _, c := range r.Overlapping {
+			Print("  %s and %s both track %d file(s), e.g. %s", c.Rule, c.Other, c.Files, c.Example)
+		}
+	}
+
+	if len(r.Unused) > 0 {
+		Print("\nUnused patterns:")
+		for _, rule := range r.Unused {
+			Print("  %s matches no files", rule)
+		}
+	}
+
+	Print("\nFiles: %d of %d tracked by Git LFS", r.TrackedFiles, r.TotalFiles)
+	for _, f := range r.Files {
+		switch {
+		case f.Rule == nil:
+			Print("  %s: not matched", f.Path)
+		case f.Tracked:
+			Print("  %s: tracked by %s", f.Path, f.Rule)
+		default:
+			Print("  %s: not tracked, by %s", f.Path, f.Rule)
+		}
+	}
+	if len(r.Files) < r.TotalFiles {
+		Print("  ... and %d more", r.TotalFiles-len(r.Files))
+	}
+}
+
+// workingTreeFiles returns the paths of all the files in the working copy
+// rooted at "dir", relative to it and in order, except for those ignored by Git.
+func workingTreeFiles(dir string) ([]string, error) {
+	var files []string
+	var walkErr error
+
+	tools.FastWalkGitRepo(dir, func(parentDir string, info os.FileInfo, err error) {
+		if err != nil {
+			walkErr = err
+			return
+		}
+		if info.IsDir() {
+			return
+		}
+
+		rel, err := filepath.Rel(dir, filepath.Join(parentDir, info.Name()))
+		if err != nil {
+			walkErr = err
+			return
+		}
+		files = append(files, rel)
+	})
+
+	sort.Strings(files)
+	return files, walkErr
+}
+
+func init() {
+	RegisterCommand("check-attributes", checkAttributesCommand, func(cmd *cobra.Command) {
+		cmd.Flags().BoolVarP(&checkAttributesJSON, "json", "j", false, "Give the output in a stable json format for scripts.")
+		cmd.Flags().IntVarP(&checkAttributesSample, "sample", "n", 10, "Show how at most this many files are classified, or all of them if negative.")
+	})
+}
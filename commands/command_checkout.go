@@ -36,7 +36,10 @@ func checkoutCommand(cmd *cobra.Command, args []string) {
 		meter.FinishTransfer(p.Name)
 	})
 
-	chgitscanner.Filter = filepathfilter.New(rootedPaths(args), nil)
+	filter := filepathfilter.New(rootedPaths(args), nil)
+	requireCheckoutFreeSpace(ref.Sha, filter, false)
+
+	chgitscanner.Filter = filter
 
 	if err := chgitscanner.ScanTree(ref.Sha); err != nil {
 		ExitWithError(err)
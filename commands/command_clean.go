@@ -1,12 +1,17 @@
 package commands
 
 import (
+	"bytes"
+	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 
 	"github.com/git-lfs/git-lfs/errors"
 	"github.com/git-lfs/git-lfs/lfs"
+	"github.com/git-lfs/git-lfs/localstorage"
 	"github.com/git-lfs/git-lfs/progress"
+	"github.com/git-lfs/git-lfs/tools"
 	"github.com/spf13/cobra"
 )
 
@@ -21,7 +26,14 @@ import (
 // value from the `stat(1)` call will be used instead.
 //
 // If the object read from "from" is _already_ a clean pointer, then it will be
-// written out verbatim to "to", without trying to make it a pointer again.
+// written out verbatim to "to", without trying to make it a pointer again,
+// unless lfs.clean.rejectpointers is set, in which case an error is returned
+// and nothing is written. If lfs.clean.passpointers is false, the pointer is
+// cleaned in full instead; see recleanPointer.
+//
+// If an lfs.cleanrule matches fileName, and the object's size or its first
+// bytes do not meet it, the object is written out verbatim instead of a
+// pointer, so that Git stores it as it is.
 func clean(to io.Writer, from io.Reader, fileName string, fileSize int64) error {
 	var cb progress.CopyCallback
 	var file *os.File
@@ -43,48 +55,165 @@ func clean(to io.Writer, from io.Reader, fileName string, fileSize int64) error
 		}
 	}
 
-	cleaned, err := lfs.PointerClean(from, fileName, fileSize, cb)
-	if file != nil {
-		file.Close()
+	if rule := loadCleanRules().For(fileName); rule != nil {
+		// The size is counted from what is read, rather than taken
+		// from the working tree, which may have changed since Git
+		// read the file.
+		head, size, all, err := rule.Peek(from)
+		if err != nil {
+			if file != nil {
+				file.Close()
+			}
+			return err
+		}
+		from = all
+
+		if size < 0 && rule.Cleans(head, size) {
+			// Only as much as lfs.cleanrule.<pattern>.minsize
+			// is read to tell whether the file is smaller, and it
+			// is spooled to disk, rather than held in memory.
+			var done func()
+			size, from, done, err = rule.Measure(from, localstorage.Objects().TempDir)
+			if err != nil {
+				if file != nil {
+					file.Close()
+				}
+				return err
+			}
+			defer done()
+		}
+
+		if !rule.Cleans(head, size) {
+			if file != nil {
+				file.Close()
+			}
+			Debug("Passing %s through unchanged, according to lfs.cleanrule.%s", cleanFileName(fileName), rule.Pattern)
+			_, err := io.Copy(to, from)
+			return err
+		}
 	}
 
-	if cleaned != nil {
-		defer cleaned.Teardown()
+	ptr, err := lfs.CleanFile(from, lfs.ObjectStorage(), fileName, fileSize, cb)
+	if file != nil {
+		file.Close()
 	}
 
 	if errors.IsCleanPointerError(err) {
 		// If the contents read from the working directory was _already_
 		// a pointer, we'll get a `CleanPointerError`, with the context
 		// containing the bytes that we should write back out to Git.
+		//
+		// Empty files do not count, as they are never wrapped in a
+		// pointer anyway.
+		by := errors.GetContext(err, "bytes").([]byte)
+		if len(by) > 0 && cfg.CleanRejectsPointers() {
+			return errors.Errorf("%s is already a Git LFS pointer, not the contents of a file; see lfs.clean.rejectpointers in git-lfs-config(5)", cleanFileName(fileName))
+		}
+		if len(by) > 0 && !cfg.CleanPassesPointers() {
+			return recleanPointer(to, fileName, by)
+		}
+		if len(lfs.NonCanonicalOids(by)) > 0 {
+			// Pointers are only ever written with their OIDs in
+			// lower case.
+			Debug("Writing the pointer for %s with its OIDs in lower case", cleanFileName(fileName))
+			return recleanPointer(to, fileName, by)
+		}
 
-		_, err = to.Write(errors.GetContext(err, "bytes").([]byte))
-		return err
+		Debug("Passing the pointer for %s through", cleanFileName(fileName))
+		return writeCleanPointer(to, fileName, by)
 	}
 
 	if err != nil {
 		ExitWithError(errors.Wrap(err, "Error cleaning LFS object"))
 	}
 
-	tmpfile := cleaned.Filename
-	mediafile, err := lfs.LocalMediaPath(cleaned.Oid)
+	if len(fileName) > 0 && cfg.ObjectOrigins() {
+		recordObjectOrigin(ptr.Oid, fileName)
+	}
+
+	return encodeCleanPointer(to, fileName, ptr)
+}
+
+// recleanPointer writes out the pointer "by", which was read from the file
+// "fileName", when lfs.clean.passpointers is false. Rather than being written
+// verbatim, it is decoded and encoded again, and if its object is in the local
+// store, that object is hashed to check that it is the one the pointer names,
+// as it would be if the file had just been cleaned from its contents. Input
+// which cannot be decoded, such as a short file, is still written verbatim.
+func recleanPointer(to io.Writer, fileName string, by []byte) error {
+	ptr, err := lfs.DecodePointer(bytes.NewReader(by))
+	if err != nil {
+		return writeCleanPointer(to, fileName, by)
+	}
+
+	mediafile, err := lfs.LocalMediaPath(ptr.Oid)
 	if err != nil {
-		Panic(err, "Unable to get local media path.")
+		return err
 	}
 
 	if stat, _ := os.Stat(mediafile); stat != nil {
-		if stat.Size() != cleaned.Size && len(cleaned.Pointer.Extensions) == 0 {
-			Exit("Files don't match:\n%s\n%s", mediafile, tmpfile)
+		if stat.Size() != ptr.Size && len(ptr.Extensions) == 0 {
+			return errors.Errorf("%s is a pointer to %s, but the local copy of that object is %d bytes, not %d; run `git lfs fsck`", cleanFileName(fileName), ptr.Oid, stat.Size(), ptr.Size)
 		}
-		Debug("%s exists", mediafile)
-	} else {
-		if err := os.Rename(tmpfile, mediafile); err != nil {
-			Panic(err, "Unable to move %s to %s\n", tmpfile, mediafile)
+		if err := tools.VerifyFileHash(ptr.Oid, mediafile); err != nil {
+			return errors.Wrapf(err, "%s is a pointer to %s, but the local copy of that object is corrupt; run `git lfs fsck`", cleanFileName(fileName), ptr.Oid)
 		}
+	}
 
-		Debug("Writing %s", mediafile)
+	return encodeCleanPointer(to, fileName, ptr)
+}
+
+// recordObjectOrigin records the path and mode of the file "fileName" as an
+// origin of the object "oid", if lfs.objectorigins is set. Failing to do so
+// does not stop the file from being cleaned.
+func recordObjectOrigin(oid, fileName string) {
+	stat, err := os.Stat(fileName)
+	if err != nil {
+		return
 	}
 
-	_, err = lfs.EncodePointer(to, cleaned.Pointer)
+	if err := lfs.RecordObjectOrigin(oid, filepath.ToSlash(fileName), stat.Mode()); err != nil {
+		LoggedError(err, "Could not record the origin of %s: %s", fileName, err)
+	}
+}
+
+// encodeCleanPointer writes the pointer "ptr" for the file "fileName" to "to",
+// with the version given by lfs.pointer.version, if any.
+func encodeCleanPointer(to io.Writer, fileName string, ptr *lfs.Pointer) error {
+	version, err := cfg.PointerVersion()
+	if err != nil {
+		return err
+	}
+	if len(version) > 0 {
+		ptr.Version = version
+	}
+
+	if !cfg.PointerChecksums() {
+		_, err := lfs.EncodePointerWithNewline(to, ptr, cfg.PointerTrailingNewline())
+		return err
+	}
+
+	var buf bytes.Buffer
+	if _, err := lfs.EncodePointerWithNewline(&buf, ptr, cfg.PointerTrailingNewline()); err != nil {
+		return err
+	}
+	return writeCleanPointer(to, fileName, buf.Bytes())
+}
+
+// writeCleanPointer writes the bytes "by" of a pointer for the file "fileName"
+// to "to", recording their checksum first if lfs.debug.pointerchecksums is set,
+// so that they can be compared with the blob Git stores for it.
+func writeCleanPointer(to io.Writer, fileName string, by []byte) error {
+	if len(fileName) > 0 && len(by) > 0 && cfg.PointerChecksums() {
+		if p, err := lfs.DecodePointer(bytes.NewReader(by)); err == nil {
+			Debug("Writing pointer for %s (%s): blob %s", fileName, p.Oid, lfs.PointerBlobID(by))
+			if err := lfs.RecordPointerChecksum(fileName, p.Oid, by); err != nil {
+				LoggedError(err, "Could not record the checksum of the pointer for %s: %s", fileName, err)
+			}
+		}
+	}
+
+	_, err := to.Write(by)
 	return err
 }
 
@@ -98,8 +227,17 @@ func cleanCommand(cmd *cobra.Command, args []string) {
 	}
 
 	if err := clean(os.Stdout, os.Stdin, fileName, -1); err != nil {
-		Error(err.Error())
+		Exit("%s", err.Error())
+	}
+}
+
+// cleanFileName returns how to refer to "fileName" in messages, given that the
+// clean filter may be run without one.
+func cleanFileName(fileName string) string {
+	if len(fileName) == 0 {
+		return "The input"
 	}
+	return fmt.Sprintf("%q", fileName)
 }
 
 func init() {
@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/git-lfs/git-lfs/localstorage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cleanTmpDryRun bool
+)
+
+// cleanTmpCommand removes the temporary files left behind in the Git LFS
+// temporary directory by processes which are no longer running, such as ones
+// which crashed or were killed. Files which belong to a running process, or
+// which were not named by Git LFS, are left alone.
+func cleanTmpCommand(cmd *cobra.Command, args []string) {
+	requireInRepo()
+
+	paths, err := localstorage.OrphanedTempFiles()
+	if err != nil {
+		ExitWithError(err)
+	}
+
+	var failed int
+	for _, path := range paths {
+		name := filepath.Base(path)
+		if cleanTmpDryRun {
+			Print("Would remove %s", name)
+			continue
+		}
+
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			Error("Could not remove %s: %s", name, err)
+			failed++
+			continue
+		}
+		Print("Removed %s", name)
+	}
+
+	if failed > 0 {
+		Exit("Git LFS clean-tmp: %d of %d temporary file(s) could not be removed", failed, len(paths))
+	}
+
+	if cleanTmpDryRun {
+		Print("Git LFS clean-tmp: would remove %d temporary file(s)", len(paths))
+	} else {
+		Print("Git LFS clean-tmp: removed %d temporary file(s)", len(paths))
+	}
+}
+
+func init() {
+	RegisterCommand("clean-tmp", cleanTmpCommand, func(cmd *cobra.Command) {
+		cmd.Flags().BoolVarP(&cleanTmpDryRun, "dry-run", "d", false, "Don't delete anything, just report")
+	})
+}
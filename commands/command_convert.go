@@ -0,0 +1,137 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/git-lfs/git-lfs/git"
+	"github.com/git-lfs/git-lfs/lfs"
+	"github.com/git-lfs/git-lfs/subprocess"
+	"github.com/spf13/cobra"
+)
+
+// convertCommand tracks the given patterns with Git LFS, as "git lfs track"
+// does, and then runs each blob in the index matching them through the clean
+// filter again, so that it is converted to a pointer. Unlike "git lfs migrate
+// import", history is left untouched: the conversion only takes effect from the
+// next commit.
+func convertCommand(cmd *cobra.Command, args []string) {
+	if len(args) < 1 {
+		Print("git lfs convert <path> [path]*")
+		return
+	}
+
+	trackCommand(cmd, args)
+
+	var files []string
+	for _, pattern := range args {
+		tracked, err := git.GetTrackedFiles(cleanRootPath(pattern))
+		if err != nil {
+			Exit("Error getting tracked files for %q: %s", pattern, err)
+		}
+
+		for _, f := range tracked {
+			if blocklistItem(f) != "" {
+				continue
+			}
+
+			if isStagedPointer(f) {
+				Print("Skipping %q, already a Git LFS pointer", f)
+				continue
+			}
+
+			files = append(files, f)
+		}
+	}
+
+	if _, err := subprocess.SimpleExec("git", "add", "--", ".gitattributes"); err != nil {
+		ExitWithError(err)
+	}
+
+	if len(files) == 0 {
+		return
+	}
+
+	// Only the blobs already in the index are cleaned again, so that any
+	// changes in the working tree are left unstaged, and a file that fails
+	// to convert stays in the index as it was.
+	for _, f := range files {
+		if err := recleanStagedFile(f); err != nil {
+			ExitWithError(err)
+		}
+	}
+
+	for _, f := range files {
+		Print("Converted %q", f)
+	}
+}
+
+// recleanStagedFile runs the blob in the index for the file at "path" through
+// the clean filter again, by hashing it as if it were located at that path, and
+// replaces the index entry with the result. The working tree is not read.
+func recleanStagedFile(path string) error {
+	out, err := subprocess.SimpleExec("git", "ls-files", "--stage", "--", path)
+	if err != nil {
+		return err
+	}
+
+	tab := strings.IndexByte(out, '\t')
+	if tab < 0 {
+		return errors.Errorf("convert: %q is not in the index", path)
+	}
+	fields := strings.Fields(out[:tab])
+	if len(fields) != 3 {
+		return errors.Errorf("convert: invalid index entry %q", out)
+	}
+	mode, sha1 := fields[0], fields[1]
+
+	cat := subprocess.ExecCommand("git", "cat-file", "blob", sha1)
+	blob, err := cat.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cat.Start(); err != nil {
+		return err
+	}
+
+	hash := subprocess.ExecCommand("git", "hash-object", "-w", "--stdin", "--path", path)
+	hash.Stdin = blob
+	cleaned, err := hash.Output()
+	if werr := cat.Wait(); err == nil {
+		err = werr
+	}
+	if err != nil {
+		return errors.Wrapf(err, "convert: could not clean %q", path)
+	}
+
+	_, err = subprocess.SimpleExec("git", "update-index", "--cacheinfo",
+		mode, strings.TrimSpace(string(cleaned)), path)
+	return err
+}
+
+// isStagedPointer returns whether the blob in the index for the file at "path"
+// is already a Git LFS pointer.
+func isStagedPointer(path string) bool {
+	out, err := subprocess.SimpleExec("git", "cat-file", "-s", ":"+path)
+	if err != nil {
+		return false
+	}
+
+	// Pointers are small, so any larger blob is not worth reading.
+	if size, err := strconv.Atoi(out); err != nil || size > 1024 {
+		return false
+	}
+
+	blob, err := subprocess.SimpleExec("git", "cat-file", "blob", ":"+path)
+	if err != nil {
+		return false
+	}
+
+	_, err = lfs.DecodePointer(strings.NewReader(blob))
+	return err == nil
+}
+
+func init() {
+	RegisterCommand("convert", convertCommand, nil)
+}
@@ -0,0 +1,243 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/git-lfs/git-lfs/git"
+	"github.com/git-lfs/git-lfs/lfs"
+	"github.com/spf13/cobra"
+)
+
+var (
+	doctorFix    bool
+	doctorLocal  bool
+	doctorSystem bool
+)
+
+// doctorProblem is something which stops Git LFS from working as it should,
+// which "fix", if it is not nil, repairs. Otherwise, "hint" says how to.
+type doctorProblem struct {
+	message string
+	hint    string
+	fix     func() error
+}
+
+// doctorCommand checks that Git is set up to run Git LFS on the files which
+// the repository tracks with it, so that they are not left as pointers, and
+// repairs what it can when given --fix.
+func doctorCommand(cmd *cobra.Command, args []string) {
+	requireGitVersion()
+
+	if doctorLocal {
+		requireInRepo()
+	}
+	if doctorLocal && doctorSystem {
+		Exit("Only one of --local and --system options can be specified.")
+	}
+
+	problems := doctorCheckFilters()
+	if lfs.InRepo() {
+		problems = append(problems, doctorCheckHooks()...)
+	}
+	if config.LocalWorkingDir != "" {
+		problems = append(problems, doctorCheckAttributes()...)
+		problems = append(problems, doctorCheckExecutablePointers()...)
+	}
+
+	var unfixed int
+	for _, p := range problems {
+		Print("* %s", p.message)
+		if !doctorFix || p.fix == nil {
+			unfixed++
+			if len(p.hint) > 0 {
+				Print("  %s", p.hint)
+			} else if p.fix != nil {
+				Print("  Run `git lfs doctor --fix` to fix this.")
+			}
+			continue
+		}
+
+		if err := p.fix(); err != nil {
+			unfixed++
+			Print("  Could not fix this: %s", err)
+			continue
+		}
+		Print("  Fixed.")
+	}
+
+	if unfixed > 0 {
+		Exit("Git LFS doctor: %d problem(s) found", unfixed)
+	}
+	Print("Git LFS doctor OK")
+}
+
+// doctorCheckFilters checks that git-lfs can be run, and that the filter.lfs
+// keys are set as `git lfs install` sets them.
+func doctorCheckFilters() []*doctorProblem {
+	var problems []*doctorProblem
+
+	if _, err := exec.LookPath("git-lfs"); err != nil {
+		problems = append(problems, &doctorProblem{
+			message: "git-lfs was not found on your PATH, so Git cannot run the Git LFS filters.",
+			hint:    "Add the directory git-lfs is installed in to your PATH.",
+		})
+	}
+
+	mismatches, passThrough := lfs.CheckFilters()
+	if len(mismatches) == 0 {
+		return problems
+	}
+
+	lines := make([]string, 0, len(mismatches)+1)
+	lines = append(lines, "The Git LFS filters are not configured as `git lfs install` configures them:")
+	for _, m := range mismatches {
+		lines = append(lines, "    "+describeMismatch(m))
+	}
+
+	return append(problems, &doctorProblem{
+		message: strings.Join(lines, "\n"),
+		fix: func() error {
+			opt := lfs.InstallOptions{Force: true, Local: doctorLocal, System: doctorSystem}
+			if err := lfs.InstallFilters(opt, passThrough); err != nil {
+				return err
+			}
+			if remaining, _ := lfs.CheckFilters(); len(remaining) > 0 {
+				return errors.Errorf("%s, which may be set in another scope of your Git configuration", describeMismatch(remaining[0]))
+			}
+			return nil
+		},
+	})
+}
+
+func describeMismatch(m *lfs.AttributeMismatch) string {
+	switch {
+	case len(m.Actual) == 0:
+		return fmt.Sprintf("%s is not set; expected %q", m.Key, m.Expected)
+	case m.Upgradeable:
+		return fmt.Sprintf("%s is %q, as an earlier version of Git LFS set it; expected %q", m.Key, m.Actual, m.Expected)
+	default:
+		return fmt.Sprintf("%s is %q; expected %q", m.Key, m.Actual, m.Expected)
+	}
+}
+
+// doctorCheckHooks checks that the hooks of the current repository are those
+// which `git lfs install` installs.
+func doctorCheckHooks() []*doctorProblem {
+	var problems []*doctorProblem
+	for _, h := range lfs.CheckHooks() {
+		h := h
+
+		message := fmt.Sprintf("The %s hook is not installed.", h.Type)
+		if h.Exists() {
+			message = fmt.Sprintf("The %s hook at %s is not the one Git LFS installs.", h.Type, h.Path())
+		}
+		problems = append(problems, &doctorProblem{
+			message: message,
+			fix:     func() error { return h.Install(false) },
+		})
+	}
+	return problems
+}
+
+// doctorCheckAttributes checks that the gitattributes files of the working tree
+// track files with Git LFS, and that the pointers in HEAD are among those
+// files, since a pointer which is not is checked out as it is.
+func doctorCheckAttributes() []*doctorProblem {
+	rules := git.GetFilterAttributeRules(config.LocalWorkingDir, config.LocalGitDir)
+
+	var tracked int
+	for _, r := range rules {
+		if r.LFS() {
+			tracked++
+		}
+	}
+
+	var problems []*doctorProblem
+	if tracked == 0 {
+		problems = append(problems, &doctorProblem{
+			message: "No pattern in the gitattributes files of the working tree sets filter=lfs.",
+			hint:    "Run `git lfs track <pattern>` to track files with Git LFS.",
+		})
+	}
+
+	ref, err := git.CurrentRef()
+	if err != nil {
+		// There is nothing committed to have pointers yet.
+		return problems
+	}
+
+	var names []string
+	gitscanner := lfs.NewGitScanner(func(p *lfs.WrappedPointer, err error) {
+		if err != nil {
+			ExitWithError(errors.Wrap(err, "Could not scan for Git LFS tree"))
+		}
+		names = append(names, p.Name)
+	})
+	if err := gitscanner.ScanTree(ref.Sha); err != nil {
+		ExitWithError(errors.Wrap(err, "Could not scan for Git LFS tree"))
+	}
+	gitscanner.Close()
+
+	for _, f := range checkAttributes(rules, names).Files {
+		if f.Tracked {
+			continue
+		}
+		problems = append(problems, &doctorProblem{
+			message: fmt.Sprintf("%s is a Git LFS pointer in HEAD, but no pattern tracks it, so it is checked out as a pointer.", f.Path),
+			hint:    fmt.Sprintf("Run `git lfs track %q` to track it.", f.Path),
+		})
+	}
+	return problems
+}
+
+// doctorCheckExecutablePointers checks for files in the working tree which are
+// still Git LFS pointers, but are executable, in the working tree or in the
+// index. This is almost always a mistake, since whatever runs such a file,
+// expecting it to be the program it points to, fails in confusing ways.
+func doctorCheckExecutablePointers() []*doctorProblem {
+	entries, err := git.GetIndexEntries()
+	if err != nil {
+		ExitWithError(err)
+	}
+
+	var problems []*doctorProblem
+	for _, e := range entries {
+		if e.Stage != 0 {
+			continue
+		}
+
+		path := filepath.Join(config.LocalWorkingDir, e.Path)
+		stat, err := os.Lstat(path)
+		if err != nil || !stat.Mode().IsRegular() {
+			continue
+		}
+		if !e.Executable() && stat.Mode().Perm()&0111 == 0 {
+			continue
+		}
+		if _, err := lfs.DecodePointerFromFile(path); err != nil {
+			continue
+		}
+
+		problems = append(problems, &doctorProblem{
+			message: fmt.Sprintf("%s is checked out as a Git LFS pointer, but is executable (mode %s in the index, %04o in the working tree).",
+				e.Path, e.Mode, stat.Mode().Perm()),
+			hint: fmt.Sprintf("Run `git lfs checkout %q` to check out its contents, or `git update-index --chmod=-x %q` if it should not be executable.",
+				e.Path, e.Path),
+		})
+	}
+	return problems
+}
+
+func init() {
+	RegisterCommand("doctor", doctorCommand, func(cmd *cobra.Command) {
+		cmd.Flags().BoolVarP(&doctorFix, "fix", "", false, "Fix the problems which can be fixed.")
+		cmd.Flags().BoolVarP(&doctorLocal, "local", "l", false, "Fix the Git LFS config for the local Git repository only.")
+		cmd.Flags().BoolVarP(&doctorSystem, "system", "", false, "Fix the Git LFS config in system-wide scope.")
+	})
+}
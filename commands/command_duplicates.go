@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/git-lfs/git-lfs/lfs"
+	"github.com/git-lfs/git-lfs/localstorage"
+	"github.com/git-lfs/git-lfs/tools/humanize"
+	"github.com/spf13/cobra"
+)
+
+var (
+	duplicatesHash string
+	duplicatesLink bool
+	duplicatesJSON bool
+)
+
+// duplicatesCommand reports the objects in the local object store which have
+// the same contents as each other under different OIDs, such as those left by
+// pointers which were written with the wrong OID, and with --link, replaces all
+// but one of each with hard links to it.
+func duplicatesCommand(cmd *cobra.Command, args []string) {
+	requireInRepo()
+
+	if _, ok := lfs.DuplicateHashes[duplicatesHash]; !ok {
+		Exit("Invalid --hash %q: must be one of sha1, sha256 or sha512", duplicatesHash)
+	}
+	if duplicatesLink {
+		if err := localstorage.CheckAccess(true); err != nil {
+			ExitWithError(err)
+		}
+	}
+
+	var objects []localstorage.Object
+	for o := range lfs.ScanObjectsChan() {
+		objects = append(objects, o)
+	}
+
+	dups, err := lfs.FindDuplicateObjects(objects, duplicatesHash, cfg.StoreConcurrency(), lfs.LocalMediaPathReadOnly)
+	if err != nil {
+		ExitWithError(errors.Wrap(err, "Could not find duplicate objects"))
+	}
+
+	var wasted int64
+	for _, d := range dups {
+		wasted += d.Wasted()
+		if duplicatesLink && !d.Linked {
+			if err := lfs.LinkDuplicateObjects(d, lfs.LocalMediaPathReadOnly); err != nil {
+				LoggedError(err, "Could not link objects %s: %s", strings.Join(d.Oids, ", "), err)
+			}
+		}
+	}
+
+	if duplicatesJSON {
+		data := struct {
+			Hash       string                  `json:"hash"`
+			Duplicates []*lfs.DuplicateObjects `json:"duplicates"`
+			Wasted     int64                   `json:"wasted"`
+		}{duplicatesHash, dups, wasted}
+		if err := json.NewEncoder(os.Stdout).Encode(data); err != nil {
+			ExitWithError(err)
+		}
+		return
+	}
+
+	if len(dups) == 0 {
+		Print("No duplicate objects found in %d object(s)", len(objects))
+		return
+	}
+
+	for _, d := range dups {
+		state := "duplicated"
+		if d.Linked {
+			state = "linked"
+		}
+		Print("%s %s object(s) with the same %s %s:", humanize.FormatBytes(uint64(d.Size)), state, duplicatesHash, d.Hash)
+		for _, oid := range d.Oids {
+			Print("  %s", oid)
+		}
+	}
+
+	if duplicatesLink {
+		Print("Linked duplicate objects, saving %s", humanize.FormatBytes(uint64(wasted)))
+	} else if wasted > 0 {
+		Print("Duplicate objects waste %s; run `git lfs duplicates --link` to link them", humanize.FormatBytes(uint64(wasted)))
+	}
+}
+
+func init() {
+	RegisterCommand("duplicates", duplicatesCommand, func(cmd *cobra.Command) {
+		cmd.Flags().StringVarP(&duplicatesHash, "hash", "", "sha256", "The hash algorithm to compare contents with.")
+		cmd.Flags().BoolVarP(&duplicatesLink, "link", "", false, "Replace duplicate objects with hard links to one of them.")
+		cmd.Flags().BoolVarP(&duplicatesJSON, "json", "j", false, "Give the output in a stable json format for scripts.")
+	})
+}
@@ -0,0 +1,310 @@
+package commands
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/git-lfs/git-lfs/git"
+	"github.com/git-lfs/git-lfs/lfs"
+	"github.com/git-lfs/git-lfs/progress"
+	"github.com/git-lfs/git-lfs/tools"
+	"github.com/git-lfs/git-lfs/tq"
+	"github.com/rubyist/tracerx"
+	"github.com/spf13/cobra"
+)
+
+// exportStore is whether export writes the objects in the local store to
+// their recorded origins, rather than the files in a ref.
+var exportStore bool
+
+// exportCommand writes the contents of each Git LFS file in the tree of the
+// given ref to the same path underneath the given directory, downloading any
+// objects which are not present locally first. Neither the working tree nor
+// the index are touched, so this works from a bare repository, too.
+func exportCommand(cmd *cobra.Command, args []string) {
+	requireGitVersion()
+	requireInRepo()
+
+	if exportStore {
+		if len(args) != 1 {
+			Print("git lfs export --store <directory>")
+			os.Exit(1)
+		}
+		exportStoreCommand(args[0])
+		return
+	}
+
+	if len(args) != 2 {
+		Print("git lfs export <ref> <directory>")
+		os.Exit(1)
+	}
+
+	ref, err := git.ResolveRef(args[0])
+	if err != nil {
+		Exit("Could not resolve ref %q: %s", args[0], err)
+	}
+	if err := os.MkdirAll(args[1], 0755); err != nil {
+		ExitWithError(err)
+	}
+
+	remote, err := git.DefaultRemote()
+	if err != nil {
+		Exit("No default remote")
+	}
+	cfg.CurrentRemote = remote
+
+	includeArg, excludeArg := getIncludeExcludeArgs(cmd)
+	filter := buildFilepathFilter(cfg, includeArg, excludeArg)
+
+	e := &exporter{dir: args[1], manifest: getTransferManifest()}
+	pointers := newPointerMap()
+	meter := progress.NewMeter(progress.WithOSEnv(cfg.Os))
+	q := newDownloadQueue(e.manifest, remote, tq.WithProgress(meter))
+	gitscanner := lfs.NewGitScanner(func(p *lfs.WrappedPointer, err error) {
+		if err != nil {
+			LoggedError(err, "Scanner error: %s", err)
+			return
+		}
+
+		if pointers.Seen(p) {
+			return
+		}
+
+		// no need to download objects that exist locally already, or
+		// in one of the object alternates
+		lfs.LinkOrCopyFromReference(p.Oid, p.Size)
+		if lfs.ObjectAvailable(p.Oid, p.Size) {
+			e.Run(p)
+			return
+		}
+
+		meter.Add(p.Size)
+		meter.StartTransfer(p.Name)
+		tracerx.Printf("export %v [%v]", p.Name, p.Oid)
+		pointers.Add(p)
+		q.Add(downloadTransfer(p))
+	})
+
+	gitscanner.Filter = filter
+
+	dlwatch := q.Watch()
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		for oid := range dlwatch {
+			for _, p := range pointers.All(oid) {
+				e.Run(p)
+			}
+		}
+		wg.Done()
+	}()
+
+	if err := gitscanner.ScanTree(ref.Sha); err != nil {
+		ExitWithError(err)
+	}
+
+	meter.Start()
+	gitscanner.Close()
+	q.Wait()
+	wg.Wait()
+
+	for _, err := range q.Errors() {
+		e.failed++
+		FullError(err)
+	}
+	printTransferErrorSummary(q.Errors())
+
+	if e.failed > 0 {
+		Exit("Git LFS export: %d file(s) could not be exported to %s", e.failed, e.dir)
+	}
+	Print("Git LFS export: %d file(s) exported to %s", e.exported, e.dir)
+}
+
+// exportStoreCommand writes each object in the local store to the paths which
+// the clean filter recorded it at, when lfs.objectorigins was set, underneath
+// "dir". Executable files are written as such. Objects with no recorded origins
+// are skipped.
+func exportStoreCommand(dir string) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		ExitWithError(err)
+	}
+
+	e := &exporter{dir: dir, manifest: getTransferManifest()}
+
+	// Several objects are usually cleaned from the same path, as the file
+	// changes, so find every object for each path before writing any.
+	var files []*exportStoreFile
+	byPath := make(map[string][]*exportStoreFile)
+	var skipped int
+	for o := range lfs.ScanObjectsChan() {
+		origins, err := lfs.ObjectOrigins(o.Oid)
+		if err != nil {
+			e.failed++
+			FullError(err)
+			continue
+		}
+		if len(origins) == 0 {
+			tracerx.Printf("export: no origins recorded for %s", o.Oid)
+			skipped++
+			continue
+		}
+
+		for _, origin := range origins {
+			if !exportPathIsRelative(origin.Path) {
+				e.failed++
+				Error("Could not export %s: its origin %q is outside of the export directory", o.Oid, origin.Path)
+				continue
+			}
+
+			f := &exportStoreFile{
+				Pointer:    lfs.NewPointer(o.Oid, o.Size, nil),
+				Path:       origin.Path,
+				Executable: origin.Executable,
+			}
+			files = append(files, f)
+			byPath[f.Path] = append(byPath[f.Path], f)
+		}
+	}
+
+	for _, f := range files {
+		name := f.Path
+		if len(byPath[f.Path]) > 1 {
+			name = exportStoreName(f.Path, f.Oid)
+		}
+		e.RunWithMode(&lfs.WrappedPointer{Name: name, Pointer: f.Pointer}, exportFileMode(f.Executable))
+	}
+
+	if skipped > 0 {
+		Print("Git LFS export: %d object(s) without a recorded origin were skipped", skipped)
+	}
+	if e.failed > 0 {
+		Exit("Git LFS export: %d file(s) could not be exported to %s", e.failed, e.dir)
+	}
+	Print("Git LFS export: %d file(s) exported to %s", e.exported, e.dir)
+}
+
+// exportStoreFile is an object in the local store, and one of its origins.
+type exportStoreFile struct {
+	*lfs.Pointer
+	Path       string
+	Executable bool
+}
+
+// exportStoreName returns the name to export the object "oid" at, when other
+// objects were cleaned from "name", too. The start of the OID is added before
+// the extension, as in "video.0a1b2c3d4e5f.mp4", so that none overwrite another.
+func exportStoreName(name, oid string) string {
+	short := oid
+	if len(short) > 12 {
+		short = short[:12]
+	}
+	ext := path.Ext(name)
+	if ext == name[strings.LastIndex(name, "/")+1:] {
+		// A dotfile, like ".env", has no extension.
+		ext = ""
+	}
+	return strings.TrimSuffix(name, ext) + "." + short + ext
+}
+
+// exportPathIsRelative returns whether the slash-separated path "p" stays
+// underneath the directory it is joined to.
+func exportPathIsRelative(p string) bool {
+	if len(p) == 0 || path.IsAbs(p) || filepath.IsAbs(filepath.FromSlash(p)) {
+		return false
+	}
+	clean := path.Clean(p)
+	return clean != ".." && !strings.HasPrefix(clean, "../")
+}
+
+// exportFileMode returns the mode to create an exported file with, before the
+// umask is applied.
+func exportFileMode(executable bool) os.FileMode {
+	if executable {
+		return 0777
+	}
+	return 0666
+}
+
+// exporter writes the contents of Git LFS files underneath a directory
+// outside of the working tree.
+type exporter struct {
+	dir      string
+	manifest *tq.Manifest
+
+	exported int
+	failed   int
+	mu       sync.Mutex
+}
+
+// Run writes the contents of "p" to its path underneath the export directory,
+// printing any error encountered.
+func (e *exporter) Run(p *lfs.WrappedPointer) {
+	e.RunWithMode(p, exportFileMode(false))
+}
+
+// RunWithMode is like Run, but creates the file with the mode "mode", before
+// the umask is applied.
+func (e *exporter) RunWithMode(p *lfs.WrappedPointer, mode os.FileMode) {
+	err := e.export(p, mode)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err != nil {
+		e.failed++
+		FullError(errors.Wrapf(err, "Could not export %q", p.Name))
+		return
+	}
+	e.exported++
+}
+
+// export writes the contents of "p" to a temporary file alongside its path
+// underneath the export directory, checks that they match the pointer's OID,
+// and only then renames it into place.
+func (e *exporter) export(p *lfs.WrappedPointer, mode os.FileMode) error {
+	path := filepath.Join(e.dir, filepath.FromSlash(p.Name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.OpenFile(fmt.Sprintf("%s.lfs-export-%d", path, os.Getpid()), os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	hasher := tools.NewLfsContentHash()
+	_, err = lfs.PointerSmudge(io.MultiWriter(tmp, hasher), p.Pointer, p.Name, false, e.manifest, nil)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	// The contents of a pointer with extensions are those of the last
+	// extension's output, which is verified as it is smudged instead.
+	if len(p.Extensions) == 0 {
+		if oid := hex.EncodeToString(hasher.Sum(nil)); oid != p.Oid {
+			return errors.Errorf("exported contents have OID %s, expected %s", oid, p.Oid)
+		}
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func init() {
+	RegisterCommand("export", exportCommand, func(cmd *cobra.Command) {
+		cmd.Flags().StringVarP(&includeArg, "include", "I", "", "Include a list of paths")
+		cmd.Flags().StringVarP(&excludeArg, "exclude", "X", "", "Exclude a list of paths")
+		cmd.Flags().BoolVarP(&exportStore, "store", "s", false, "Export the local store to the paths its objects were cleaned from")
+	})
+}
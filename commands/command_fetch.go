@@ -1,22 +1,34 @@
 package commands
 
 import (
+	"bufio"
 	"fmt"
+	"os"
+	"strings"
 	"time"
 
+	"github.com/git-lfs/git-lfs/errors"
 	"github.com/git-lfs/git-lfs/filepathfilter"
 	"github.com/git-lfs/git-lfs/git"
 	"github.com/git-lfs/git-lfs/lfs"
 	"github.com/git-lfs/git-lfs/progress"
+	"github.com/git-lfs/git-lfs/tools"
 	"github.com/git-lfs/git-lfs/tq"
 	"github.com/rubyist/tracerx"
 	"github.com/spf13/cobra"
 )
 
 var (
-	fetchRecentArg bool
-	fetchAllArg    bool
-	fetchPruneArg  bool
+	fetchRecentArg  bool
+	fetchAllArg     bool
+	fetchPruneArg   bool
+	fetchRecheckArg bool
+	fetchStdinArg   bool
+	fetchOidsArg    []string
+
+	// fetchRechecked are the OIDs given by --oid, each of which is true
+	// once a pointer to it has been found.
+	fetchRechecked map[string]bool
 )
 
 func getIncludeExcludeArgs(cmd *cobra.Command) (include, exclude *string) {
@@ -47,7 +59,14 @@ func fetchCommand(cmd *cobra.Command, args []string) {
 		cfg.CurrentRemote = ""
 	}
 
-	if len(args) > 1 {
+	if fetchStdinArg {
+		if fetchAllArg || fetchRecentArg || len(args) > 1 {
+			Exit("Cannot combine --stdin with --all, --recent or ref arguments")
+		}
+		if cmd.Flag("include").Changed || cmd.Flag("exclude").Changed {
+			Exit("Cannot combine --stdin with --include or --exclude")
+		}
+	} else if len(args) > 1 {
 		resolvedrefs, err := git.ResolveRefs(args[1:])
 		if err != nil {
 			Panic(err, "Invalid ref argument: %v", args[1:])
@@ -61,13 +80,24 @@ func fetchCommand(cmd *cobra.Command, args []string) {
 		refs = []*git.Ref{ref}
 	}
 
+	if len(fetchOidsArg) > 0 && !fetchRecheckArg {
+		Exit("Cannot use --oid without --recheck")
+	}
+	fetchRechecked = make(map[string]bool, len(fetchOidsArg))
+	for _, oid := range fetchOidsArg {
+		fetchRechecked[oid] = false
+	}
+
 	success := true
 	gitscanner := lfs.NewGitScanner(nil)
 	defer gitscanner.Close()
 
 	include, exclude := getIncludeExcludeArgs(cmd)
 
-	if fetchAllArg {
+	if fetchStdinArg {
+		success = fetchStdin()
+
+	} else if fetchAllArg {
 		if fetchRecentArg || len(args) > 1 {
 			Exit("Cannot combine --all with ref arguments or --recent")
 		}
@@ -95,6 +125,13 @@ func fetchCommand(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	for _, oid := range fetchOidsArg {
+		if !fetchRechecked[oid] {
+			Error("Object %s is not referenced by the refs fetched", oid)
+			success = false
+		}
+	}
+
 	if fetchPruneArg {
 		fetchconf := cfg.FetchPruneConfig()
 		verify := fetchconf.PruneVerifyRemoteAlways
@@ -229,6 +266,52 @@ func fetchAll() bool {
 	return fetchAndReportToChan(pointers, nil, nil)
 }
 
+// fetchStdin fetches the objects read from stdin, one "<oid> <size>" pair on
+// each line, without scanning any refs for them. A malformed line is reported,
+// and the objects on the other lines are still fetched.
+func fetchStdin() bool {
+	ok := true
+	seen := make(map[string]bool)
+	var pointers []*lfs.WrappedPointer
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			Error("Line %d: expected \"<oid> <size>\", got %q", n, line)
+			ok = false
+			continue
+		}
+
+		oid, size, err := parseFetchObject(fields[0], fields[1])
+		if err != nil {
+			Error("Line %d: %s", n, err)
+			ok = false
+			continue
+		}
+
+		if seen[oid] {
+			continue
+		}
+		seen[oid] = true
+		pointers = append(pointers, &lfs.WrappedPointer{
+			Name:    oid,
+			Pointer: lfs.NewPointer(oid, size, nil),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		ExitWithError(errors.Wrap(err, "Could not read objects from stdin"))
+	}
+
+	Print("Fetching %d objects from stdin", len(pointers))
+	return fetchAndReportToChan(pointers, nil, nil) && ok
+}
+
 func scanAll() []*lfs.WrappedPointer {
 	// This could be a long process so use the chan version & report progress
 	Print("Scanning for all objects ever referenced...")
@@ -280,7 +363,12 @@ func fetchAndReportToChan(allpointers []*lfs.WrappedPointer, filter *filepathfil
 		cfg.CurrentRemote = defaultRemote
 	}
 
+	if fetchRecheckArg {
+		allpointers = recheckPointers(allpointers, filter)
+	}
+
 	ready, pointers, meter := readyAndMissingPointers(allpointers, filter)
+	requireFreeSpace(lfs.LocalMediaDir(), totalSize(pointers))
 	q := newDownloadQueue(getTransferManifest(), cfg.CurrentRemote, tq.WithProgress(meter))
 
 	if out != nil {
@@ -329,9 +417,66 @@ func fetchAndReportToChan(allpointers []*lfs.WrappedPointer, filter *filepathfil
 		ok = false
 		FullError(err)
 	}
+	printTransferErrorSummary(q.Errors())
 	return ok
 }
 
+// recheckPointers returns the pointers of "allpointers" which --recheck fetches:
+// those to the objects given by --oid, or all of them if there are none, which
+// "filter" allows. The local copy of each of their objects is hashed, and
+// removed if it is corrupt, so that it is downloaded again. The filter is
+// applied first, so that no object is removed which would not be fetched.
+func recheckPointers(allpointers []*lfs.WrappedPointer, filter *filepathfilter.Filter) []*lfs.WrappedPointer {
+	pointers := make([]*lfs.WrappedPointer, 0, len(allpointers))
+	checked := make(map[string]bool, len(allpointers))
+
+	for _, p := range allpointers {
+		if filter != nil && !filter.Allows(p.Name) {
+			continue
+		}
+		if len(fetchRechecked) > 0 {
+			if _, ok := fetchRechecked[p.Oid]; !ok {
+				continue
+			}
+			fetchRechecked[p.Oid] = true
+		}
+		pointers = append(pointers, p)
+
+		if checked[p.Oid] {
+			continue
+		}
+		checked[p.Oid] = true
+		recheckObject(p)
+	}
+	return pointers
+}
+
+// recheckObject hashes the local copy of the object "p" points to, if there is
+// one, and removes it if it is corrupt.
+func recheckObject(p *lfs.WrappedPointer) {
+	path := lfs.LocalMediaPathReadOnly(p.Oid)
+	stat, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return
+	} else if err != nil {
+		ExitWithError(err)
+	}
+
+	if stat.Size() == p.Size {
+		if err := tools.VerifyFileHash(p.Oid, path); err == nil {
+			Debug("Object %s (%s) is OK", p.Name, p.Oid)
+			return
+		} else if _, ok := err.(*os.PathError); ok {
+			ExitWithError(err)
+		}
+	}
+
+	Print("Object %s (%s) is corrupt; downloading it again", p.Name, p.Oid)
+	if err := os.Remove(path); err != nil {
+		ExitWithError(err)
+	}
+}
+
 func readyAndMissingPointers(allpointers []*lfs.WrappedPointer, filter *filepathfilter.Filter) ([]*lfs.WrappedPointer, []*lfs.WrappedPointer, *progress.ProgressMeter) {
 	meter := buildProgressMeter(false)
 	seen := make(map[string]bool, len(allpointers))
@@ -346,9 +491,10 @@ func readyAndMissingPointers(allpointers []*lfs.WrappedPointer, filter *filepath
 
 		seen[p.Oid] = true
 
-		// no need to download objects that exist locally already
+		// no need to download objects that exist locally already, or
+		// in one of the object alternates
 		lfs.LinkOrCopyFromReference(p.Oid, p.Size)
-		if lfs.ObjectExistsOfSize(p.Oid, p.Size) {
+		if lfs.ObjectAvailable(p.Oid, p.Size) {
 			ready = append(ready, p)
 			continue
 		}
@@ -367,5 +513,8 @@ func init() {
 		cmd.Flags().BoolVarP(&fetchRecentArg, "recent", "r", false, "Fetch recent refs & commits")
 		cmd.Flags().BoolVarP(&fetchAllArg, "all", "a", false, "Fetch all LFS files ever referenced")
 		cmd.Flags().BoolVarP(&fetchPruneArg, "prune", "p", false, "After fetching, prune old data")
+		cmd.Flags().BoolVarP(&fetchRecheckArg, "recheck", "", false, "Download objects whose local copies are corrupt again")
+		cmd.Flags().StringSliceVar(&fetchOidsArg, "oid", nil, "Only recheck the objects with these OIDs")
+		cmd.Flags().BoolVarP(&fetchStdinArg, "stdin", "", false, "Fetch the objects read from stdin, one \"<oid> <size>\" pair per line")
 	})
 }
@@ -0,0 +1,129 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/git-lfs/git-lfs/git"
+	"github.com/git-lfs/git-lfs/lfs"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// fetchObjectOidRE matches a fully-qualified, lowercase SHA-256 LFS
+	// object ID.
+	fetchObjectOidRE = regexp.MustCompile(`\A[0-9a-f]{64}\z`)
+
+	fetchObjectStdin bool
+)
+
+// fetchObjectCommand downloads the single object given by "<oid> <size>" from
+// the current remote into the local object store, without consulting any refs
+// or paths. On success, the path of the local copy is printed to stdout.
+//
+// With --stdin, objects are instead read one per line from stdin, and each is
+// answered on stdout as soon as it is available, so that a layer which fetches
+// objects as they are first read can keep a single process running.
+func fetchObjectCommand(cmd *cobra.Command, args []string) {
+	requireInRepo()
+
+	if fetchObjectStdin {
+		if len(args) != 0 {
+			Print("Usage: git lfs fetch-object --stdin")
+			os.Exit(1)
+		}
+		fetchObjectsFromStdin()
+		return
+	}
+
+	if len(args) != 2 {
+		Print("Usage: git lfs fetch-object <oid> <size>")
+		os.Exit(1)
+	}
+
+	oid, size, err := parseFetchObject(args[0], args[1])
+	if err != nil {
+		Exit("%s", err)
+	}
+
+	// The progress meter writes to stdout, which is reserved for the path
+	// of the downloaded object, so it is not used here.
+	path, err := lfs.FetchObject(getTransferManifest(), fetchObjectRemote(), oid, size)
+	if err != nil {
+		FullError(err)
+		os.Exit(2)
+	}
+
+	Print(path)
+}
+
+// fetchObjectsFromStdin answers each "<oid> <size>" line read from stdin with
+// "ok <oid> <path>" once the object is available locally, or with
+// "error <oid> <message>" if it could not be fetched, until stdin is closed.
+func fetchObjectsFromStdin() {
+	fetcher := lfs.NewObjectFetcher(getTransferManifest(), fetchObjectRemote())
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			fmt.Fprintf(os.Stdout, "error %s Expected \"<oid> <size>\", got %q\n", fields[0], line)
+			continue
+		}
+
+		oid, size, err := parseFetchObject(fields[0], fields[1])
+		if err == nil {
+			var path string
+			if path, err = fetcher.Fetch(oid, size); err == nil {
+				fmt.Fprintf(os.Stdout, "ok %s %s\n", oid, path)
+				continue
+			}
+		}
+		fmt.Fprintf(os.Stdout, "error %s %s\n", fields[0], strings.Replace(err.Error(), "\n", " ", -1))
+	}
+
+	if err := scanner.Err(); err != nil {
+		ExitWithError(errors.Wrap(err, "fetch-object"))
+	}
+}
+
+// parseFetchObject parses the OID and size of an object to fetch.
+func parseFetchObject(oid, size string) (string, int64, error) {
+	if !fetchObjectOidRE.MatchString(oid) {
+		return "", 0, errors.Errorf("Invalid object ID: %q", oid)
+	}
+
+	n, err := strconv.ParseInt(size, 10, 64)
+	if err != nil || n < 0 {
+		return "", 0, errors.Errorf("Invalid object size: %q", size)
+	}
+	return oid, n, nil
+}
+
+// fetchObjectRemote returns the remote objects are fetched from, which is the
+// default remote. If there is none, objects can still be found locally, or
+// fetched from lfs.url, so an empty remote is returned.
+func fetchObjectRemote() string {
+	remote, err := git.DefaultRemote()
+	if err != nil {
+		return ""
+	}
+	cfg.CurrentRemote = remote
+	return remote
+}
+
+func init() {
+	RegisterCommand("fetch-object", fetchObjectCommand, func(cmd *cobra.Command) {
+		cmd.Flags().BoolVarP(&fetchObjectStdin, "stdin", "", false, "Read the objects to fetch from stdin, one per line.")
+	})
+}
@@ -3,12 +3,19 @@ package commands
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"runtime"
+	"strings"
 
 	"github.com/git-lfs/git-lfs/errors"
 	"github.com/git-lfs/git-lfs/filepathfilter"
 	"github.com/git-lfs/git-lfs/git"
 	"github.com/git-lfs/git-lfs/lfs"
+	"github.com/git-lfs/git-lfs/localstorage"
+	"github.com/git-lfs/git-lfs/locking"
+	"github.com/git-lfs/git-lfs/logger"
+	"github.com/rubyist/tracerx"
 	"github.com/spf13/cobra"
 )
 
@@ -23,6 +30,12 @@ const (
 	// `*git.PacketWriter`'s internal buffer when the filter protocol
 	// dictates the "smudge" command.
 	smudgeFilterBufferCapacity = git.MaxPacketLength
+
+	// smudgeFilterSyncInterval is the number of bytes of an object's
+	// contents which the "smudge" command writes between syncing the
+	// `*git.PktlineWriter`, so that Git receives the contents of large
+	// objects steadily, rather than only once the rest has been written.
+	smudgeFilterSyncInterval = 16 * git.MaxPacketLength
 )
 
 // filterSmudgeSkip is a command-line flag owned by the `filter-process` command
@@ -42,70 +55,321 @@ func filterCommand(cmd *cobra.Command, args []string) {
 	if err := s.NegotiateCapabilities(); err != nil {
 		ExitWithError(err)
 	}
+	if cfg.Os.Bool("GIT_LFS_DEBUG", false) {
+		printFilterCapabilities(os.Stderr, s)
+	}
+
+	// Smudging only needs to read from the object store, so a store which
+	// cannot be written to only stops files from being cleaned.
+	if err := localstorage.CheckAccess(false); err != nil {
+		ExitWithError(err)
+	}
 
 	skip := filterSmudgeSkip || cfg.Os.Bool("GIT_LFS_SKIP_SMUDGE", false)
-	filter := filepathfilter.New(cfg.FetchIncludePaths(), cfg.FetchExcludePaths())
+	filter := filepathfilter.NewWithNegation(cfg.FetchIncludePaths(), cfg.FetchExcludePaths())
+
+	res, err := RunFilterProcess(s, os.Stdout, skip, filter)
+	if res != nil {
+		printFilterProcessResult(res)
+	}
+	if err != nil {
+		ExitWithError(err)
+	}
+}
+
+// FilterProcessResult is what a filter-process session did, for callers of
+// RunFilterProcess which report on it themselves, rather than as the
+// filter-process command does.
+type FilterProcessResult struct {
+	// Cleaned and Smudged are the numbers of files Git asked to have
+	// cleaned and smudged.
+	Cleaned int
+	Smudged int
+	// Malformed are the paths of files which should have been pointers,
+	// but were not, and were passed through unchanged, unless
+	// lfs.smudge.nonpointer says not to warn about them, or to fail.
+	Malformed []string
+	// MalformedOnWindows are the paths of files which may not have been
+	// copied correctly on Windows. See git-lfs-smudge(1).
+	MalformedOnWindows []string
+	// Errors are those which files failed to be cleaned or smudged with,
+	// each of which was reported to Git as the status of its file.
+	Errors []error
+	// Stats counts the objects which were smudged, by whether they were
+	// read from local storage or downloaded.
+	Stats SmudgeCacheStats
+}
 
-	var malformed []string
-	var malformedOnWindows []string
+// RunFilterProcess answers the requests Git sends over "s", which must already
+// have been initialized and have negotiated its capabilities, writing their
+// responses to "out", until Git ends the session. Objects are not downloaded
+// if "skip" is true, or if their paths are not matched by "filter".
+//
+// A file which fails to be cleaned or smudged does not stop the session, but
+// is recorded in the returned result. An error is only returned if the session
+// itself fails, such as when Git sends a request that cannot be understood, in
+// which case the result describes the requests answered until then.
+func RunFilterProcess(s *git.FilterProcessScanner, out io.Writer, skip bool, filter *filepathfilter.Filter) (*FilterProcessResult, error) {
+	storeWriteErr := localstorage.CheckAccess(true)
+
+	res := &FilterProcessResult{}
+	var locks cleanLockWarner
+	collisions := newCaseCollisionWarner(cfg.Git.Bool("core.ignorecase", false))
+	nonPointer := cfg.SmudgeNonPointer()
+	postSmudge := newPostSmudgeRunner(cfg.PostSmudgeCommands(), runtime.NumCPU())
+
+	// smudged is the last file whose contents were smudged. Git writes it
+	// once it has read the response, so post-smudge commands for it are
+	// queued when the next request arrives, or the session ends. Git waits
+	// for the filter process to exit, so commands still running then are
+	// left to finish on their own.
+	var smudged string
+	defer func() {
+		if len(smudged) > 0 {
+			postSmudge.Run(smudged)
+		}
+		postSmudge.Detach()
+	}()
 
 	for s.Scan() {
 		var n int64
 		var err error
 		var w *git.PktlineWriter
 
+		if len(smudged) > 0 {
+			postSmudge.Run(smudged)
+			smudged = ""
+		}
+
 		req := s.Request()
+		command, pathname := req.Header["command"], req.Header["pathname"]
 
 		s.WriteStatus(statusFromErr(nil))
 
-		switch req.Header["command"] {
+		switch command {
 		case "clean":
-			w = git.NewPktlineWriter(os.Stdout, cleanFilterBufferCapacity)
-			err = clean(w, req.Payload, req.Header["pathname"], -1)
+			res.Cleaned++
+			locks.Warn(pathname)
+
+			w = git.NewPktlineWriter(out, cleanFilterBufferCapacity)
+			if storeWriteErr != nil {
+				err = storeWriteErr
+				io.Copy(ioutil.Discard, req.Payload)
+			} else {
+				err = clean(w, req.Payload, pathname, -1)
+			}
+			if err != nil {
+				// Git only learns that cleaning failed from
+				// the status, so say why here.
+				Error("%s", err.Error())
+			}
 		case "smudge":
-			w = git.NewPktlineWriter(os.Stdout, smudgeFilterBufferCapacity)
-			n, err = smudge(w, req.Payload, req.Header["pathname"], skip, filter)
+			res.Smudged++
+			w = git.NewPktlineWriter(out, smudgeFilterBufferCapacity)
+			collisions.Warn(pathname)
+
+			sw := &syncingWriter{PktlineWriter: w, interval: smudgeFilterSyncInterval}
+			recorded := res.Stats.Hits + res.Stats.Misses
+			n, err = smudge(sw, req.Payload, pathname, skip, filter, &res.Stats)
+			if err == nil && res.Stats.Hits+res.Stats.Misses > recorded {
+				// Only objects whose contents were written
+				// are recorded, not pointers left in place.
+				smudged = pathname
+			}
 		default:
-			ExitWithError(fmt.Errorf("Unknown command %q", req.Header["command"]))
+			return res, fmt.Errorf("Unknown command %q", command)
 		}
 
 		if errors.IsNotAPointerError(err) {
-			malformed = append(malformed, req.Header["pathname"])
-			err = nil
+			// The contents have been passed through already,
+			// but are discarded by Git if the status is an error.
+			switch nonPointer {
+			case "error":
+				Error("%s", err.Error())
+			case "ignore":
+				err = nil
+			default:
+				res.Malformed = append(res.Malformed, pathname)
+				err = nil
+			}
 		} else if possiblyMalformedSmudge(n) {
-			malformedOnWindows = append(malformedOnWindows, req.Header["pathname"])
+			res.MalformedOnWindows = append(res.MalformedOnWindows, pathname)
 		}
 
-		var status string
 		if ferr := w.Flush(); ferr != nil {
-			status = statusFromErr(ferr)
-		} else {
-			status = statusFromErr(err)
+			err = ferr
+		}
+		status := statusFromErr(err)
+		if status != "success" {
+			res.Errors = append(res.Errors, errors.Wrapf(err, "%s %s", command, pathname))
+			smudged = ""
 		}
 
 		s.WriteStatus(status)
 	}
 
-	if len(malformed) > 0 {
-		fmt.Fprintf(os.Stderr, "Encountered %d file(s) that should have been pointers, but weren't:\n", len(malformed))
-		for _, m := range malformed {
-			fmt.Fprintf(os.Stderr, "\t%s\n", m)
+	if err := s.Err(); err != nil && err != io.EOF {
+		return res, err
+	}
+	return res, nil
+}
+
+// printFilterProcessResult reports what a filter-process session did, as the
+// filter-process command does once Git ends it.
+func printFilterProcessResult(res *FilterProcessResult) {
+	stats := res.Stats
+	if stats.Hits+stats.Misses > 0 {
+		tracerx.Printf("filter-process: smudge cache: %d hit(s) (%d bytes), %d miss(es) (%d bytes), hit ratio %.3f",
+			stats.Hits, stats.HitBytes, stats.Misses, stats.MissBytes, stats.HitRatio())
+
+		if cfg.SmudgeStats() {
+			logger.Log(logger.Info, logger.Fields{"hits": stats.Hits, "misses": stats.Misses},
+				"Git LFS: %s", stats.String())
+		}
+	}
+
+	if len(res.Malformed) > 0 {
+		logger.Log(logger.Warning, logger.Fields{"paths": res.Malformed},
+			"Encountered %d file(s) that should have been pointers, but weren't:\n%s",
+			len(res.Malformed), malformedList(res.Malformed))
+	}
+
+	if len(res.MalformedOnWindows) > 0 {
+		logger.Log(logger.Warning, logger.Fields{"paths": res.MalformedOnWindows},
+			"Encountered %d file(s) that may not have been copied correctly on Windows:\n%s\n\nSee: `git lfs help smudge` for more details.",
+			len(res.MalformedOnWindows), malformedList(res.MalformedOnWindows))
+	}
+}
+
+// syncingWriter writes to a `*git.PktlineWriter`, syncing it each time another
+// "interval" bytes have been written, so that they reach the other end instead
+// of waiting in its buffers.
+type syncingWriter struct {
+	*git.PktlineWriter
+
+	interval int
+	unsynced int
+}
+
+func (w *syncingWriter) Write(p []byte) (int, error) {
+	n, err := w.PktlineWriter.Write(p)
+	if err != nil {
+		return n, err
+	}
+
+	w.unsynced += n
+	if w.unsynced >= w.interval {
+		w.unsynced = 0
+		err = w.Sync()
+	}
+	return n, err
+}
+
+// malformedList returns the paths in "paths" one per line, each indented by a
+// tab, for the summaries of malformed files.
+func malformedList(paths []string) string {
+	lines := make([]string, 0, len(paths))
+	for _, p := range paths {
+		lines = append(lines, "\t"+p)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// cleanLockWarner warns about files being cleaned which are locked by someone
+// else. The remote's locks are only requested once per filter-process session,
+// the first time a file is cleaned, and only if lock verification is enabled for
+// the remote, so that neither smudging nor cleaning each file contacts the
+// server.
+type cleanLockWarner struct {
+	loaded bool
+	theirs map[string]locking.Lock
+}
+
+// Warn logs a warning if "pathname" is locked by someone else. It never fails:
+// if the locks cannot be retrieved, nothing is logged.
+func (w *cleanLockWarner) Warn(pathname string) {
+	if !w.loaded {
+		w.loaded = true
+		w.theirs = theirLocksForClean()
+	}
+
+	if l, ok := w.theirs[pathname]; ok {
+		owner := "another user"
+		if l.Owner != nil && len(l.Owner.Name) > 0 {
+			owner = l.Owner.Name
 		}
+		logger.Log(logger.Warning, logger.Fields{"path": pathname, "owner": owner},
+			"Warning: %s is locked by %s", pathname, owner)
+	}
+}
+
+// caseCollisionWarner warns about files being smudged whose paths differ only by
+// case from that of a file smudged earlier in the same filter-process session.
+// On a case-insensitive filesystem, both are the same file, so whichever is
+// smudged last overwrites the other. It does nothing unless Git has found the
+// filesystem to be case-insensitive, as given by "core.ignorecase".
+type caseCollisionWarner struct {
+	enabled bool
+	paths   map[string]string
+}
+
+func newCaseCollisionWarner(enabled bool) *caseCollisionWarner {
+	return &caseCollisionWarner{
+		enabled: enabled,
+		paths:   make(map[string]string),
+	}
+}
+
+// Warn logs a warning if "pathname" collides with a path smudged before it.
+func (w *caseCollisionWarner) Warn(pathname string) {
+	if !w.enabled {
+		return
 	}
 
-	if len(malformedOnWindows) > 0 {
-		fmt.Fprintf(os.Stderr, "Encountered %d file(s) that may not have been copied correctly on Windows:\n")
+	key := strings.ToLower(pathname)
+	first, ok := w.paths[key]
+	if !ok {
+		w.paths[key] = pathname
+		return
+	}
+	if first == pathname {
+		return
+	}
 
-		for _, m := range malformedOnWindows {
-			fmt.Fprintf(os.Stderr, "\t%s\n", m)
+	logger.Log(logger.Warning, logger.Fields{"path": pathname, "collides": first},
+		"Warning: %s and %s differ only in case, and are the same file on this filesystem", first, pathname)
+}
+
+// theirLocksForClean returns the locks held by others on the default remote,
+// keyed by path.
+func theirLocksForClean() map[string]locking.Lock {
+	remote := cfg.CurrentRemote
+	if len(remote) == 0 {
+		var err error
+		if remote, err = git.DefaultRemote(); err != nil {
+			return nil
 		}
+	}
 
-		fmt.Fprintf(os.Stderr, "\nSee: `git lfs help smudge` for more details.\n")
+	endpoint := getAPIClient().Endpoints.Endpoint("upload", remote)
+	if getVerifyStateFor(endpoint) != verifyStateEnabled {
+		return nil
 	}
 
-	if err := s.Err(); err != nil && err != io.EOF {
-		ExitWithError(err)
+	lockClient := newLockClient(remote)
+	defer lockClient.Close()
+
+	_, theirs, err := lockClient.VerifiableLocks(0)
+	if err != nil {
+		tracerx.Printf("filter-process: unable to retrieve locks for %q: %s", remote, err)
+		return nil
 	}
+
+	locks := make(map[string]locking.Lock, len(theirs))
+	for _, l := range theirs {
+		locks[l.Path] = l
+	}
+	return locks
 }
 
 // statusFromErr returns the status code that should be sent over the filter
@@ -117,6 +381,26 @@ func statusFromErr(err error) string {
 	return "success"
 }
 
+// printFilterCapabilities writes what "s" negotiated with Git to "w": the
+// protocol version, the capabilities Git offered, those which were accepted,
+// and whether delay is enabled, so that a filter which is not behaving as
+// expected can be diagnosed with GIT_LFS_DEBUG.
+func printFilterCapabilities(w io.Writer, s *git.FilterProcessScanner) {
+	offered, accepted := s.Capabilities()
+
+	delay := "disabled (not offered by Git)"
+	if s.Accepted("delay") {
+		delay = "enabled"
+	} else if s.Offered("delay") {
+		delay = "disabled (offered by Git, but not supported by Git LFS)"
+	}
+
+	fmt.Fprintf(w, "filter-process: protocol %s\n", s.Version())
+	fmt.Fprintf(w, "filter-process: Git offered capabilities: %s\n", strings.Join(offered, ", "))
+	fmt.Fprintf(w, "filter-process: accepted capabilities: %s\n", strings.Join(accepted, ", "))
+	fmt.Fprintf(w, "filter-process: delay: %s\n", delay)
+}
+
 func init() {
 	RegisterCommand("filter-process", filterCommand, func(cmd *cobra.Command) {
 		cmd.Flags().BoolVarP(&filterSmudgeSkip, "skip", "s", false, "")
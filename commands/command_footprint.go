@@ -0,0 +1,201 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/git-lfs/git-lfs/filepathfilter"
+	"github.com/git-lfs/git-lfs/git"
+	"github.com/git-lfs/git-lfs/lfs"
+	"github.com/git-lfs/git-lfs/tools/humanize"
+	"github.com/spf13/cobra"
+)
+
+var (
+	footprintJSON bool
+)
+
+// footprintCommand reports how much of the local object store the objects
+// reachable from a ref take up, by the pattern in the gitattributes files which
+// tracks each of their files, so that the patterns costing the most storage can
+// be found.
+func footprintCommand(cmd *cobra.Command, args []string) {
+	requireInRepo()
+
+	if config.LocalWorkingDir == "" {
+		Print("This operation must be run in a work tree.")
+		os.Exit(128)
+	}
+
+	refName := "HEAD"
+	if len(args) > 0 {
+		refName = args[0]
+	}
+	ref, err := git.ResolveRef(refName)
+	if err != nil {
+		Exit("Could not resolve %q: %s", refName, err)
+	}
+
+	var pointers []*lfs.WrappedPointer
+	gitscanner := lfs.NewGitScanner(func(p *lfs.WrappedPointer, err error) {
+		if err != nil {
+			ExitWithError(errors.Wrap(err, "Could not scan for Git LFS tree"))
+		}
+		pointers = append(pointers, p)
+	})
+	if err := gitscanner.ScanTree(ref.Sha); err != nil {
+		ExitWithError(errors.Wrap(err, "Could not scan for Git LFS tree"))
+	}
+	gitscanner.Close()
+
+	report := footprint(git.GetFilterAttributeRules(config.LocalWorkingDir, config.LocalGitDir), pointers, lfs.LocalMediaPathReadOnly)
+	report.Ref = refName
+
+	if footprintJSON {
+		if err := json.NewEncoder(os.Stdout).Encode(report); err != nil {
+			ExitWithError(err)
+		}
+		return
+	}
+
+	report.Print()
+}
+
+// footprintEntry is the storage taken up by the objects of the files which a
+// rule tracks with Git LFS, or of those which no rule does, if Rule is nil.
+// Objects which are not in the local object store are counted as missing, and
+// take up no storage.
+type footprintEntry struct {
+	Rule    *attributeRule `json:"rule"`
+	Files   int            `json:"files"`
+	Objects int            `json:"objects"`
+	Size    int64          `json:"size"`
+	Missing int            `json:"missing"`
+
+	oids map[string]bool
+}
+
+// add counts the file "p", whose object is "size" bytes in the local object
+// store, or missing from it if "size" is negative.
+func (e *footprintEntry) add(p *lfs.WrappedPointer, size int64) {
+	e.Files++
+	if e.oids[p.Oid] {
+		return
+	}
+	e.oids[p.Oid] = true
+
+	e.Objects++
+	if size < 0 {
+		e.Missing++
+	} else {
+		e.Size += size
+	}
+}
+
+type footprintReport struct {
+	Ref       string            `json:"ref"`
+	Patterns  []*footprintEntry `json:"patterns"`
+	Untracked *footprintEntry   `json:"untracked"`
+	Total     *footprintEntry   `json:"total"`
+}
+
+// footprint attributes each of "pointers" to the rule among "gitRules", which
+// are in order of precedence, which applies to its file, if that rule tracks it
+// with Git LFS, and sums the sizes of their objects, as found at the paths
+// returned by "objectPath". An object is counted once for each rule, however
+// many files it is the object of, and once in the total, so that the total is
+// the storage they take up together. Patterns are sorted by their footprint,
+// largest first.
+func footprint(gitRules []*git.FilterAttributeRule, pointers []*lfs.WrappedPointer, objectPath func(oid string) string) *footprintReport {
+	entries := make([]*footprintEntry, 0, len(gitRules))
+	rules := make([]*attributeRule, 0, len(gitRules))
+	for i, r := range gitRules {
+		rule := &attributeRule{
+			Pattern: r.Path,
+			Source:  r.Source.Path,
+			Line:    r.Line,
+			Filter:  r.Filter,
+			Tracked: r.LFS(),
+			pattern: filepathfilter.NewPattern(r.Path),
+			rank:    i,
+		}
+		rules = append(rules, rule)
+		entries = append(entries, &footprintEntry{Rule: rule, oids: make(map[string]bool)})
+	}
+
+	report := &footprintReport{
+		Patterns:  make([]*footprintEntry, 0, len(entries)),
+		Untracked: &footprintEntry{oids: make(map[string]bool)},
+		Total:     &footprintEntry{oids: make(map[string]bool)},
+	}
+
+	sizes := make(map[string]int64, len(pointers))
+	for _, p := range pointers {
+		size, ok := sizes[p.Oid]
+		if !ok {
+			size = -1
+			if stat, err := os.Stat(objectPath(p.Oid)); err == nil {
+				size = stat.Size()
+			}
+			sizes[p.Oid] = size
+		}
+
+		entry := report.Untracked
+		for i, r := range rules {
+			if r.pattern.Match(p.Name) {
+				if r.Tracked {
+					entry = entries[i]
+				}
+				break
+			}
+		}
+		entry.add(p, size)
+		report.Total.add(p, size)
+	}
+
+	for _, e := range entries {
+		if e.Rule.Tracked {
+			report.Patterns = append(report.Patterns, e)
+		}
+	}
+	sort.SliceStable(report.Patterns, func(i, j int) bool {
+		return report.Patterns[i].Size > report.Patterns[j].Size
+	})
+
+	return report
+}
+
+func (r *footprintReport) Print() {
+	Print("Git LFS storage footprint of %s, by pattern:", r.Ref)
+	if len(r.Patterns) == 0 {
+		Print("  (no patterns track files with Git LFS)")
+	}
+	for _, e := range r.Patterns {
+		Print("  %10s  %-30s %s", humanize.FormatBytes(uint64(e.Size)), e.Rule.Pattern, e.summary())
+	}
+	if r.Untracked.Files > 0 {
+		Print("  %10s  %-30s %s", humanize.FormatBytes(uint64(r.Untracked.Size)), "(not tracked by any pattern)", r.Untracked.summary())
+	}
+	Print("  %10s  %-30s %s", humanize.FormatBytes(uint64(r.Total.Size)), "total", r.Total.summary())
+}
+
+func (e *footprintEntry) summary() string {
+	s := fmt.Sprintf("%d object(s), %d file(s)", e.Objects, e.Files)
+	if e.Missing > 0 {
+		s += fmt.Sprintf(", %d not in the local store", e.Missing)
+	}
+	if e.Rule != nil {
+		s = fmt.Sprintf("%s:%d; %s", e.Rule.Source, e.Rule.Line, s)
+	}
+	return s
+}
+
+func init() {
+	RegisterCommand("footprint", footprintCommand, func(cmd *cobra.Command) {
+		cmd.Flags().BoolVarP(&footprintJSON, "json", "j", false, "Give the output in a stable json format for scripts.")
+	})
+}
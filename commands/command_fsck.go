@@ -1,51 +1,99 @@
 package commands
 
 import (
-	"crypto/sha256"
 	"encoding/hex"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"sync"
 
 	"github.com/git-lfs/git-lfs/config"
+	"github.com/git-lfs/git-lfs/errors"
 	"github.com/git-lfs/git-lfs/git"
 	"github.com/git-lfs/git-lfs/lfs"
+	"github.com/git-lfs/git-lfs/localstorage"
+	"github.com/git-lfs/git-lfs/progress"
+	"github.com/git-lfs/git-lfs/tools"
 	"github.com/spf13/cobra"
 )
 
 var (
-	fsckDryRun bool
+	fsckDryRun   bool
+	fsckProgress bool
+	fsckLayout   bool
 )
 
-// TODO(zeroshirts): 'git fsck' reports status (percentage, current#/total) as
-// it checks... we should do the same, as we are rehashing potentially gigs and
-// gigs of content.
-//
 // NOTE(zeroshirts): Ideally git would have hooks for fsck such that we could
 // chain a lfs-fsck, but I don't think it does.
 func fsckCommand(cmd *cobra.Command, args []string) {
 	lfs.InstallHooks(false)
 	requireInRepo()
 
+	if fsckLayout {
+		fsckStoreLayout()
+		return
+	}
+
 	ref, err := git.CurrentRef()
 	if err != nil {
 		ExitWithError(err)
 	}
 
+	// Objects are hashed by a pool of workers as the scanner finds
+	// pointers to them, since hashing is by far the slowest part.
+	meter := buildProgressMeter(!fsckProgress)
+	pointers := make(chan *lfs.WrappedPointer, cfg.StoreConcurrency())
+
+	// Errors are reported once the workers are done, rather than from
+	// their goroutines. Only the first few are kept, so that a worker is
+	// never held up sending one.
+	errs := make(chan error, cfg.StoreConcurrency())
+
 	var corruptOids []string
-	gitscanner := lfs.NewGitScanner(func(p *lfs.WrappedPointer, err error) {
-		if err == nil {
-			var pointerOk bool
-			pointerOk, err = fsckPointer(p.Name, p.Oid)
-			if !pointerOk {
-				corruptOids = append(corruptOids, p.Oid)
+	var corruptMu sync.Mutex
+	var workers sync.WaitGroup
+	for i := 0; i < cfg.StoreConcurrency(); i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for p := range pointers {
+				pointerOk, err := fsckPointer(p.Name, p.Oid, meter)
+				if err != nil {
+					select {
+					case errs <- err:
+					default:
+					}
+					continue
+				}
+				if !pointerOk {
+					corruptMu.Lock()
+					corruptOids = append(corruptOids, p.Oid)
+					corruptMu.Unlock()
+				}
 			}
-		}
+		}()
+	}
 
+	var invalidPointers []*lfs.InvalidPointer
+	gitscanner := lfs.NewGitScanner(func(p *lfs.WrappedPointer, err error) {
 		if err != nil {
 			Panic(err, "Error checking Git LFS files")
 		}
+
+		if p.Size <= 0 {
+			// It is reported as an invalid pointer instead.
+			return
+		}
+
+		meter.Add(p.Size)
+		pointers <- p
 	})
+	gitscanner.FoundInvalidPointer = func(p *lfs.InvalidPointer) {
+		invalidPointers = append(invalidPointers, p)
+	}
+
+	meter.Start()
 
 	if err := gitscanner.ScanRefWithDeleted(ref.Sha, nil); err != nil {
 		ExitWithError(err)
@@ -56,9 +104,29 @@ func fsckCommand(cmd *cobra.Command, args []string) {
 	}
 
 	gitscanner.Close()
+	close(pointers)
+	workers.Wait()
+	meter.Finish()
+
+	close(errs)
+	if err := <-errs; err != nil {
+		Panic(err, "Error checking Git LFS files")
+	}
+
+	// Workers finish in no particular order, so the objects are sorted to
+	// report and move them in a stable one.
+	sort.Strings(corruptOids)
+
+	// Invalid pointers are a problem with the history itself, not with
+	// any object, so there is nothing to move out of the way for them.
+	for _, p := range invalidPointers {
+		Print("Pointer %s (%s) is invalid: %s", fsckPointerName(p), p.Sha1, p.Err)
+	}
 
 	if len(corruptOids) == 0 {
-		Print("Git LFS fsck OK")
+		if len(invalidPointers) == 0 {
+			Print("Git LFS fsck OK")
+		}
 		return
 	}
 
@@ -76,17 +144,60 @@ func fsckCommand(cmd *cobra.Command, args []string) {
 
 	for _, oid := range corruptOids {
 		badFile := filepath.Join(badDir, oid)
-		if err := os.Rename(lfs.LocalMediaPathReadOnly(oid), badFile); err != nil {
+		if err := tools.RenameFile(lfs.LocalMediaPathReadOnly(oid), badFile); err != nil {
 			ExitWithError(err)
 		}
 	}
 }
 
-func fsckPointer(name, oid string) (bool, error) {
+// fsckStoreLayout moves each object in the local store which is outside the
+// shard directory given by its OID into it, or with --dry-run, lists them, and
+// reports the files which it could not put in place.
+func fsckStoreLayout() {
+	store := localstorage.Objects()
+	report, err := store.RepairLayout(fsckDryRun)
+	if err != nil {
+		ExitWithError(errors.Wrap(err, "Error repairing the layout of the Git LFS object store"))
+	}
+
+	for _, o := range report.Moved {
+		if fsckDryRun {
+			Print("Object %s is misplaced at %s", o.Oid, o.Path)
+		} else {
+			Print("Moved object %s from %s", o.Oid, o.Path)
+		}
+	}
+	for _, o := range report.Conflicts {
+		Print("Object %s is misplaced at %s, but %s already exists; left in place", o.Oid, o.Path, o.Want)
+	}
+	for _, path := range report.Unknown {
+		Print("File %s is not a Git LFS object; left in place", path)
+	}
+
+	if len(report.Moved) == 0 && len(report.Conflicts) == 0 && len(report.Unknown) == 0 {
+		Print("Git LFS fsck: all %d objects are in place", report.Checked)
+	}
+}
+
+// fsckPointerName returns the name of the file the invalid pointer "p" was found
+// at, or if it has none, says so.
+func fsckPointerName(p *lfs.InvalidPointer) string {
+	if len(p.Name) == 0 {
+		return "(unknown)"
+	}
+	return p.Name
+}
+
+// fsckPointer hashes the object "oid", which the file "name" has a pointer to,
+// and reports whether it matches, counting the bytes hashed towards "meter".
+func fsckPointer(name, oid string, meter progress.Meter) (bool, error) {
 	path := lfs.LocalMediaPathReadOnly(oid)
 
 	Debug("Examining %v (%v)", name, path)
 
+	meter.StartTransfer(name)
+	defer meter.FinishTransfer(name)
+
 	f, err := os.Open(path)
 	if pErr, pOk := err.(*os.PathError); pOk {
 		Print("Object %s (%s) could not be checked: %s", name, oid, pErr.Err)
@@ -97,8 +208,20 @@ func fsckPointer(name, oid string) (bool, error) {
 		return false, err
 	}
 
-	oidHash := sha256.New()
-	_, err = io.Copy(oidHash, f)
+	var size int64
+	if stat, err := f.Stat(); err == nil {
+		size = stat.Size()
+	}
+
+	oidHash := tools.NewLfsContentHash()
+	_, err = io.Copy(oidHash, &progress.CallbackReader{
+		C: func(total, read int64, current int) error {
+			meter.TransferBytes("check", name, read, total, current)
+			return nil
+		},
+		TotalSize: size,
+		Reader:    f,
+	})
 	f.Close()
 	if err != nil {
 		return false, err
@@ -116,5 +239,7 @@ func fsckPointer(name, oid string) (bool, error) {
 func init() {
 	RegisterCommand("fsck", fsckCommand, func(cmd *cobra.Command) {
 		cmd.Flags().BoolVarP(&fsckDryRun, "dry-run", "d", false, "List corrupt objects without deleting them.")
+		cmd.Flags().BoolVarP(&fsckProgress, "progress", "p", false, "Show the progress of checking objects.")
+		cmd.Flags().BoolVarP(&fsckLayout, "layout", "", false, "Move objects which are outside their shard directory into it.")
 	})
 }
@@ -0,0 +1,135 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/git-lfs/git-lfs/git"
+	"github.com/git-lfs/git-lfs/lfs"
+	"github.com/git-lfs/git-lfs/tools/humanize"
+	"github.com/spf13/cobra"
+)
+
+var (
+	localObjectsSort string
+	localObjectsJSON bool
+)
+
+// localObject is an object in the local store, as it is listed by
+// local-objects.
+type localObject struct {
+	Oid      string    `json:"oid"`
+	Size     int64     `json:"size"`
+	Accessed time.Time `json:"accessed"`
+	// Head is whether the tree at HEAD has a pointer to the object.
+	Head bool `json:"head"`
+}
+
+// localObjectsSorts are the orders which local-objects can list objects in, by
+// the name given to --sort. Objects are listed largest first by size, and least
+// recently accessed first by atime, which is the order in which they are most
+// worth removing.
+var localObjectsSorts = map[string]func(a, b *localObject) bool{
+	"oid": func(a, b *localObject) bool {
+		return a.Oid < b.Oid
+	},
+	"size": func(a, b *localObject) bool {
+		if a.Size != b.Size {
+			return a.Size > b.Size
+		}
+		return a.Oid < b.Oid
+	},
+	"atime": func(a, b *localObject) bool {
+		if !a.Accessed.Equal(b.Accessed) {
+			return a.Accessed.Before(b.Accessed)
+		}
+		return a.Oid < b.Oid
+	},
+}
+
+// localObjectsCommand lists every object in the local store, with its size,
+// when it was last accessed, and whether HEAD refers to it.
+func localObjectsCommand(cmd *cobra.Command, args []string) {
+	requireInRepo()
+
+	less, ok := localObjectsSorts[localObjectsSort]
+	if !ok {
+		Exit("Invalid sort %q: expected one of oid, size, or atime", localObjectsSort)
+	}
+
+	head, err := localObjectsAtHead()
+	if err != nil {
+		ExitWithError(err)
+	}
+
+	objects := make([]*localObject, 0)
+	for o := range lfs.ScanObjectsChan() {
+		objects = append(objects, &localObject{
+			Oid:      o.Oid,
+			Size:     o.Size,
+			Accessed: o.Accessed,
+			Head:     head[o.Oid],
+		})
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		return less(objects[i], objects[j])
+	})
+
+	if localObjectsJSON {
+		if err := json.NewEncoder(os.Stdout).Encode(struct {
+			Objects []*localObject `json:"objects"`
+		}{objects}); err != nil {
+			ExitWithError(err)
+		}
+		return
+	}
+
+	for _, o := range objects {
+		var suffix string
+		if o.Head {
+			suffix = " (HEAD)"
+		}
+		Print("%s %9s %s%s", o.Oid, humanize.FormatBytes(uint64(o.Size)),
+			o.Accessed.Format("2006-01-02 15:04:05 -0700"), suffix)
+	}
+}
+
+// localObjectsAtHead returns the OIDs of the objects which the tree at HEAD has
+// pointers to, which is none if there are no commits yet.
+func localObjectsAtHead() (map[string]bool, error) {
+	oids := make(map[string]bool)
+	if _, err := git.CurrentRef(); err != nil {
+		return oids, nil
+	}
+
+	var scanErr error
+	gitscanner := lfs.NewGitScanner(func(p *lfs.WrappedPointer, err error) {
+		if err != nil {
+			if scanErr == nil {
+				scanErr = err
+			}
+			return
+		}
+		oids[p.Oid] = true
+	})
+	defer gitscanner.Close()
+
+	if err := gitscanner.ScanTree("HEAD"); err != nil {
+		return nil, err
+	}
+	if scanErr != nil {
+		return nil, fmt.Errorf("Could not scan HEAD: %v", scanErr)
+	}
+	return oids, nil
+}
+
+func init() {
+	RegisterCommand("local-objects", localObjectsCommand, func(cmd *cobra.Command) {
+		cmd.Flags().StringVarP(&localObjectsSort, "sort", "s", "oid", "Sort by oid, size, or atime.")
+		cmd.Flags().BoolVarP(&localObjectsJSON, "json", "j", false, "Give the output in a stable json format for scripts.")
+	})
+}
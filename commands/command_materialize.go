@@ -0,0 +1,117 @@
+package commands
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/git-lfs/git-lfs/git"
+	"github.com/git-lfs/git-lfs/lfs"
+	"github.com/git-lfs/git-lfs/subprocess"
+	"github.com/spf13/cobra"
+)
+
+// materializeCommand replaces each of the given working tree files which is a
+// pointer with the contents of its object, downloading the object first if it
+// is not local, as the smudge filter would. Nothing but the files themselves
+// is changed, so the index only has their stat information refreshed, and no
+// other file is checked out.
+func materializeCommand(cmd *cobra.Command, args []string) {
+	requireInRepo()
+	if config.LocalWorkingDir == "" {
+		Print("This operation must be run in a work tree.")
+		os.Exit(128)
+	}
+	if len(args) == 0 {
+		Print("Usage: git lfs materialize <path>...")
+		os.Exit(1)
+	}
+
+	manifest := getTransferManifest()
+	indexer := &gitIndexer{}
+	for _, path := range args {
+		ptr, err := lfs.DecodePointerFromFile(path)
+		if err != nil {
+			if errors.IsNotAPointerError(err) {
+				Print("%s is not a pointer; left as it is", path)
+				continue
+			}
+			exitAfterIndexing(indexer, "Could not read %s: %s", path, err)
+		}
+
+		if err := requireStagedPointer(path, ptr); err != nil {
+			exitAfterIndexing(indexer, "Could not materialize %s: %s", path, err)
+		}
+		if err := lfs.PointerSmudgeToFile(path, ptr, true, manifest, nil); err != nil {
+			exitAfterIndexing(indexer, "Could not materialize %s: %s", path, err)
+		}
+		if err := indexer.Add(path); err != nil {
+			exitAfterIndexing(indexer, "Could not update the index: %s", err)
+		}
+		Print("Materialized %s", path)
+	}
+
+	closeIndexer(indexer)
+}
+
+// requireStagedPointer returns an error unless the file at "path" is tracked
+// by Git LFS, and "ptr", the pointer the clean filter makes of it, is the one
+// in the index. Updating the index for the file once it has been rewritten then
+// only refreshes its stat information, rather than staging anything.
+func requireStagedPointer(path string, ptr *lfs.Pointer) error {
+	attr, err := subprocess.SimpleExec("git", "check-attr", "filter", "--", path)
+	if err != nil {
+		return err
+	}
+	if !strings.HasSuffix(attr, ": filter: lfs") {
+		return errors.Errorf("%s is not tracked by Git LFS", path)
+	}
+
+	entry, err := git.GetIndexEntry(path)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return errors.Errorf("%s is not in the index", path)
+	}
+
+	// Pointers are small, so any larger blob is not worth reading.
+	out, err := subprocess.SimpleExec("git", "cat-file", "-s", entry.Sha1)
+	if err != nil {
+		return err
+	}
+	if size, err := strconv.Atoi(out); err != nil || size > 1024 {
+		return errors.Errorf("%s differs from the index", path)
+	}
+
+	blob, err := subprocess.SimpleExec("git", "cat-file", "blob", entry.Sha1)
+	if err != nil {
+		return err
+	}
+	staged, err := lfs.DecodePointer(strings.NewReader(blob))
+	if err != nil || staged.Oid != ptr.Oid || staged.Size != ptr.Size {
+		return errors.Errorf("%s differs from the index", path)
+	}
+	return nil
+}
+
+// closeIndexer waits for "indexer" to update the index for the files given to
+// it so far.
+func closeIndexer(indexer *gitIndexer) {
+	if err := indexer.Close(); err != nil {
+		LoggedError(err, "Error updating the git index:\n%s", indexer.Output())
+	}
+}
+
+// exitAfterIndexing closes "indexer", so that the files rewritten before an
+// error do not show up as modified, and then exits with the given message.
+func exitAfterIndexing(indexer *gitIndexer, format string, args ...interface{}) {
+	closeIndexer(indexer)
+	Exit(format, args...)
+}
+
+func init() {
+	RegisterCommand("materialize", materializeCommand, nil)
+}
@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/git-lfs/git-lfs/git"
+	"github.com/git-lfs/git-lfs/lfs"
+	"github.com/spf13/cobra"
+)
+
+var materializedQuiet bool
+
+// materializedCommand checks that every Git LFS file in the tree at HEAD, which
+// the include and exclude paths allow, has its contents in the working tree,
+// rather than its pointer or a placeholder. Only the start of each file is
+// read, so that nothing is hashed, and no objects are downloaded.
+func materializedCommand(cmd *cobra.Command, args []string) {
+	requireInRepo()
+	if config.LocalWorkingDir == "" {
+		Print("This operation must be run in a work tree.")
+		os.Exit(128)
+	}
+
+	ref, err := git.CurrentRef()
+	if err != nil {
+		Exit("Could not find HEAD: %s", err)
+	}
+
+	includeArg, excludeArg := getIncludeExcludeArgs(cmd)
+	filter := buildFilepathFilter(cfg, includeArg, excludeArg)
+
+	var checked, unmaterialized int
+	gitscanner := lfs.NewGitScanner(func(p *lfs.WrappedPointer, err error) {
+		if err != nil {
+			Exit("Could not scan for Git LFS tree: %s", err)
+			return
+		}
+
+		checked++
+		if reason := unmaterializedReason(p); len(reason) > 0 {
+			unmaterialized++
+			if !materializedQuiet {
+				Print("%s: %s", p.Name, reason)
+			}
+		}
+	})
+	gitscanner.Filter = filter
+
+	if err := gitscanner.ScanTree(ref.Sha); err != nil {
+		Exit("Could not scan for Git LFS tree: %s", err)
+	}
+	gitscanner.Close()
+
+	if unmaterialized > 0 {
+		Exit("Git LFS materialized: %d of %d files are not materialized", unmaterialized, checked)
+	}
+	if !materializedQuiet {
+		Print("Git LFS materialized: all %d files are materialized", checked)
+	}
+}
+
+// unmaterializedReason returns why the working tree does not have the contents
+// of the Git LFS file "p", or an empty string if it does. Only the start of the
+// file is read, which is enough to tell a pointer, or a placeholder's zeros,
+// from real contents. Files which have been modified since they were checked
+// out still count as materialized.
+func unmaterializedReason(p *lfs.WrappedPointer) string {
+	f, err := os.Open(filepath.Join(config.LocalWorkingDir, filepath.FromSlash(p.Name)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "missing"
+		}
+		return err.Error()
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return err.Error()
+	}
+
+	head := make([]byte, cfg.PointerMaxSize()+1)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err.Error()
+	}
+	head = head[:n]
+
+	if _, _, err := lfs.DecodeFrom(bytes.NewReader(head)); err == nil {
+		return "pointer"
+	}
+	if lfs.HasPlaceholder(p.Name) && stat.Size() == p.Size && len(bytes.Trim(head, "\x00")) == 0 {
+		return "placeholder"
+	}
+	return ""
+}
+
+func init() {
+	RegisterCommand("materialized", materializedCommand, func(cmd *cobra.Command) {
+		cmd.Flags().StringVarP(&includeArg, "include", "I", "", "Include a list of paths")
+		cmd.Flags().StringVarP(&excludeArg, "exclude", "X", "", "Exclude a list of paths")
+		cmd.Flags().BoolVarP(&materializedQuiet, "quiet", "q", false, "Only print how many files are not materialized, if any")
+	})
+}
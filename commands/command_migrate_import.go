@@ -109,12 +109,12 @@ func migrateImportCommand(cmd *cobra.Command, args []string) {
 func trackedFromFilter(filter *filepathfilter.Filter) *tools.OrderedSet {
 	tracked := tools.NewOrderedSet()
 
-	for _, include := range filter.Include() {
-		tracked.Add(fmt.Sprintf("%s filter=lfs diff=lfs merge=lfs -text", include))
-	}
-
-	for _, exclude := range filter.Exclude() {
-		tracked.Add(fmt.Sprintf("%s text -filter -merge -diff", exclude))
+	for _, r := range filter.Rules() {
+		if r.Negated {
+			tracked.Add(fmt.Sprintf("%s text -filter -merge -diff", r.Pattern))
+		} else {
+			tracked.Add(fmt.Sprintf("%s filter=lfs diff=lfs merge=lfs -text", r.Pattern))
+		}
 	}
 
 	return tracked
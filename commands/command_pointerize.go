@@ -0,0 +1,84 @@
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/git-lfs/git-lfs/lfs"
+	"github.com/spf13/cobra"
+)
+
+// pointerizeCommand replaces the contents of each of the given working tree
+// files with their pointer, as the clean filter writes it, so that their
+// objects are kept in the local store. Files which are already pointers are
+// left alone. Like materializeCommand, only the files themselves are changed.
+func pointerizeCommand(cmd *cobra.Command, args []string) {
+	requireInRepo()
+	if config.LocalWorkingDir == "" {
+		Print("This operation must be run in a work tree.")
+		os.Exit(128)
+	}
+	if len(args) == 0 {
+		Print("Usage: git lfs pointerize <path>...")
+		os.Exit(1)
+	}
+
+	indexer := &gitIndexer{}
+	for _, path := range args {
+		if _, err := lfs.DecodePointerFromFile(path); err == nil {
+			Print("%s is already a pointer; left as it is", path)
+			continue
+		} else if !errors.IsNotAPointerError(err) {
+			exitAfterIndexing(indexer, "Could not read %s: %s", path, err)
+		}
+
+		if err := pointerize(path); err != nil {
+			exitAfterIndexing(indexer, "Could not pointerize %s: %s", path, err)
+		}
+		if err := indexer.Add(path); err != nil {
+			exitAfterIndexing(indexer, "Could not update the index: %s", err)
+		}
+		Print("Replaced %s with its pointer", path)
+	}
+
+	closeIndexer(indexer)
+}
+
+// pointerize cleans the file "path", which stores its object, and then writes
+// the pointer over it, keeping its mode, if that pointer is the one in the
+// index.
+func pointerize(path string) error {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	err = clean(&buf, f, path, stat.Size())
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	ptr, err := lfs.DecodePointer(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		return err
+	}
+	if err := requireStagedPointer(path, ptr); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, buf.Bytes(), stat.Mode())
+}
+
+func init() {
+	RegisterCommand("pointerize", pointerizeCommand, nil)
+}
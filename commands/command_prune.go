@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -24,6 +25,7 @@ var (
 	pruneVerboseArg     bool
 	pruneVerifyArg      bool
 	pruneDoNotVerifyArg bool
+	pruneKeepRefsArg    []string
 )
 
 func pruneCommand(cmd *cobra.Command, args []string) {
@@ -33,6 +35,7 @@ func pruneCommand(cmd *cobra.Command, args []string) {
 	}
 
 	fetchPruneConfig := cfg.FetchPruneConfig()
+	fetchPruneConfig.PruneKeepRefs = append(fetchPruneConfig.PruneKeepRefs, pruneKeepRefsArg...)
 	verify := !pruneDoNotVerifyArg &&
 		(fetchPruneConfig.PruneVerifyRemoteAlways || pruneVerifyArg)
 	prune(fetchPruneConfig, verify, pruneDryRunArg, pruneVerboseArg)
@@ -62,9 +65,9 @@ func prune(fetchPruneConfig config.FetchPruneConfig, verifyRemote, dryRun, verbo
 	// Add all the base funcs to the waitgroup before starting them, in case
 	// one completes really fast & hits 0 unexpectedly
 	// each main process can Add() to the wg itself if it subdivides the task
-	taskwait.Add(4) // 1..4: localObjects, current & recent refs, unpushed, worktree
+	taskwait.Add(5) // 1..5: localObjects, current & recent refs, kept refs, unpushed, worktree
 	if verifyRemote {
-		taskwait.Add(1) // 5
+		taskwait.Add(1) // 6
 	}
 
 	progressChan := make(PruneProgressChan, 100)
@@ -84,6 +87,7 @@ func prune(fetchPruneConfig config.FetchPruneConfig, verifyRemote, dryRun, verbo
 
 	gitscanner := lfs.NewGitScanner(nil)
 	go pruneTaskGetRetainedCurrentAndRecentRefs(gitscanner, fetchPruneConfig, retainChan, errorChan, &taskwait)
+	go pruneTaskGetRetainedKeptRefs(gitscanner, fetchPruneConfig, retainChan, errorChan, &taskwait)
 	go pruneTaskGetRetainedUnpushed(gitscanner, fetchPruneConfig, retainChan, errorChan, &taskwait)
 	go pruneTaskGetRetainedWorktree(gitscanner, retainChan, errorChan, &taskwait)
 	if verifyRemote {
@@ -395,6 +399,64 @@ func pruneTaskGetRetainedCurrentAndRecentRefs(gitscanner *lfs.GitScanner, fetchc
 	}
 }
 
+// Background task, must call waitg.Done() once at end
+func pruneTaskGetRetainedKeptRefs(gitscanner *lfs.GitScanner, fetchconf config.FetchPruneConfig, retainChan chan string, errorChan chan error, waitg *sync.WaitGroup) {
+	defer waitg.Done()
+
+	if len(fetchconf.PruneKeepRefs) == 0 {
+		return
+	}
+
+	refs, err := git.AllRefs()
+	if err != nil {
+		errorChan <- err
+		return
+	}
+
+	// Kept refs are retained in addition to the recent window, and all of
+	// their history is kept, however old it is
+	for _, ref := range refs {
+		if !pruneKeepsRef(ref, fetchconf.PruneKeepRefs) {
+			continue
+		}
+		waitg.Add(1)
+		go pruneTaskGetRetainedHistoryOfRef(gitscanner, ref.Refspec(), retainChan, errorChan, waitg)
+	}
+}
+
+// pruneKeepsRef returns whether "ref" is kept by the ordered list of ref
+// patterns "patterns", which is decided by the last pattern that matches it.
+// A pattern starting with "!" stops the refs it matches from being kept.
+func pruneKeepsRef(ref *git.Ref, patterns []string) bool {
+	kept := false
+	for _, pattern := range patterns {
+		negated := strings.HasPrefix(pattern, "!")
+		if ref.MatchesPattern(strings.TrimPrefix(pattern, "!")) {
+			kept = !negated
+		}
+	}
+	return kept
+}
+
+// Background task, must call waitg.Done() once at end
+func pruneTaskGetRetainedHistoryOfRef(gitscanner *lfs.GitScanner, ref string, retainChan chan string, errorChan chan error, waitg *sync.WaitGroup) {
+	defer waitg.Done()
+
+	err := gitscanner.ScanRefWithDeleted(ref, func(p *lfs.WrappedPointer, err error) {
+		if err != nil {
+			errorChan <- err
+			return
+		}
+
+		retainChan <- p.Oid
+		tracerx.Printf("RETAIN: %v via kept ref %v", p.Oid, ref)
+	})
+
+	if err != nil {
+		errorChan <- err
+	}
+}
+
 // Background task, must call waitg.Done() once at end
 func pruneTaskGetRetainedUnpushed(gitscanner *lfs.GitScanner, fetchconf config.FetchPruneConfig, retainChan chan string, errorChan chan error, waitg *sync.WaitGroup) {
 	defer waitg.Done()
@@ -468,5 +530,6 @@ func init() {
 		cmd.Flags().BoolVarP(&pruneVerboseArg, "verbose", "v", false, "Print full details of what is/would be deleted")
 		cmd.Flags().BoolVarP(&pruneVerifyArg, "verify-remote", "c", false, "Verify that remote has LFS files before deleting")
 		cmd.Flags().BoolVar(&pruneDoNotVerifyArg, "no-verify-remote", false, "Override lfs.pruneverifyremotealways and don't verify")
+		cmd.Flags().StringSliceVar(&pruneKeepRefsArg, "keep-ref", nil, "Never prune objects reachable from refs matching these patterns")
 	})
 }
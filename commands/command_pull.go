@@ -45,6 +45,7 @@ func pull(remote string, filter *filepathfilter.Filter) {
 	if err != nil {
 		Panic(err, "Could not pull")
 	}
+	requireCheckoutFreeSpace(ref.Sha, filter, true)
 
 	pointers := newPointerMap()
 	meter := progress.NewMeter(progress.WithOSEnv(cfg.Os))
@@ -60,9 +61,10 @@ func pull(remote string, filter *filepathfilter.Filter) {
 			return
 		}
 
-		// no need to download objects that exist locally already
+		// no need to download objects that exist locally already, or
+		// in one of the object alternates
 		lfs.LinkOrCopyFromReference(p.Oid, p.Size)
-		if lfs.ObjectExistsOfSize(p.Oid, p.Size) {
+		if lfs.ObjectAvailable(p.Oid, p.Size) {
 			singleCheckout.Run(p)
 			return
 		}
@@ -109,6 +111,7 @@ func pull(remote string, filter *filepathfilter.Filter) {
 		success = false
 		FullError(err)
 	}
+	printTransferErrorSummary(q.Errors())
 
 	if !success {
 		c := getAPIClient()
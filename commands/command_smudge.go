@@ -4,12 +4,16 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"runtime"
 
+	"github.com/git-lfs/git-lfs/config"
 	"github.com/git-lfs/git-lfs/errors"
 	"github.com/git-lfs/git-lfs/filepathfilter"
 	"github.com/git-lfs/git-lfs/lfs"
+	"github.com/git-lfs/git-lfs/lfsapi"
 	"github.com/git-lfs/git-lfs/localstorage"
+	"github.com/git-lfs/git-lfs/logger"
 	"github.com/git-lfs/git-lfs/tools"
 	"github.com/git-lfs/git-lfs/tools/humanize"
 	"github.com/spf13/cobra"
@@ -19,6 +23,10 @@ var (
 	// smudgeSkip is a command-line flag belonging to the "git-lfs smudge"
 	// command specifying whether to skip the smudge process.
 	smudgeSkip = false
+	// smudgeToStdout is a command-line flag belonging to the "git-lfs
+	// smudge" command specifying whether to stream the contents of the
+	// given pointer to a consumer other than Git's smudge filter.
+	smudgeToStdout = false
 )
 
 // smudge smudges the given `*lfs.Pointer`, "ptr", and writes its objects
@@ -26,24 +34,56 @@ var (
 //
 // If the encoded LFS pointer is not parse-able as a pointer, the contents of
 // that file will instead be spooled to a temporary location on disk and then
-// copied out back to Git. If the pointer file is empty, an empty file will be
-// written with no error.
+// copied out back to Git, and a NotAPointerError is returned, which callers
+// handle as lfs.smudge.nonpointer says. If the pointer file is empty, or it is
+// expected not to be a pointer, because an lfs.cleanrule passed it through, it
+// is written with no error.
 //
 // If the smudged object did not "pass" the include and exclude filterset, it
 // will not be downloaded, and the object will remain a pointer on disk, as if
 // the smudge filter had not been applied at all.
 //
+// If Git LFS is in offline mode, objects are only ever read from the local
+// object store. An object that is not present locally is treated as a download
+// error naming its OID, rather than being fetched.
+//
+// If smudge placeholders are enabled, objects which are not downloaded are
+// written as zero-filled files of the object's size instead of as pointers, and
+// are recorded so that the clean filter turns them back into the same pointer.
+//
+// If an lfs.smudge.rewrite rule matches "filename", and lfs.smudge.allowrewrite
+// is set, the contents are either written to "to" as usual, with a symbolic
+// link to the file made at the rule's path, or written to the rule's path
+// instead, with the pointer written to "to".
+//
+// If "stats" is non-nil, each object smudged is recorded in it, according to
+// whether or not it had to be downloaded.
+//
 // Any errors encountered along the way will be returned immediately if they
 // were non-fatal, otherwise execution will halt and the process will be
 // terminated by using the `commands.Panic()` func.
-func smudge(to io.Writer, from io.Reader, filename string, skip bool, filter *filepathfilter.Filter) (int64, error) {
+func smudge(to io.Writer, from io.Reader, filename string, skip bool, filter *filepathfilter.Filter, stats *SmudgeCacheStats) (int64, error) {
 	ptr, pbuf, perr := lfs.DecodeFrom(from)
 	if perr != nil {
+		// A file which an lfs.cleanrule passed through unchanged is
+		// expected not to be a pointer.
+		var head []byte
+		rule := loadCleanRules().For(filename)
+		if rule != nil {
+			var err error
+			if head, _, pbuf, err = rule.Peek(pbuf); err != nil {
+				return 0, errors.Wrap(err, perr.Error())
+			}
+		}
+
 		n, err := tools.Spool(to, pbuf, localstorage.Objects().TempDir)
 		if err != nil {
 			return 0, errors.Wrap(err, perr.Error())
 		}
 
+		if n != 0 && rule != nil && !rule.Cleans(head, n) {
+			return 0, nil
+		}
 		if n != 0 {
 			return 0, errors.NewNotAPointerError(errors.Errorf(
 				"Unable to parse pointer at: %q", filename,
@@ -53,6 +93,8 @@ func smudge(to io.Writer, from io.Reader, filename string, skip bool, filter *fi
 	}
 
 	lfs.LinkOrCopyFromReference(ptr.Oid, ptr.Size)
+	local := lfs.ObjectAvailable(ptr.Oid, ptr.Size)
+
 	cb, file, err := lfs.CopyCallbackFile("download", filename, 1, 1)
 	if err != nil {
 		return 0, err
@@ -63,13 +105,70 @@ func smudge(to io.Writer, from io.Reader, filename string, skip bool, filter *fi
 		download = filter.Allows(filename)
 	}
 
-	n, err := ptr.Smudge(to, filename, download, getTransferManifest(), cb)
+	// In offline mode, objects that would otherwise be downloaded must
+	// already be present locally.
+	offline := download && cfg.Offline()
+
+	rewrite, target := loadSmudgeRewrites().For(filename)
+
+	if download && !offline && !local {
+		err = smudgeFreeSpaceError(ptr, true)
+	}
+
+	// A link may have been made to somewhere else since the rule was
+	// matched, so where the contents are moved to is checked again.
+	// Nor is anything at the target replaced, unless it was moved there
+	// from this file before.
+	if err == nil && rewrite != nil && rewrite.Mode == "move" {
+		cerr := rewrite.CheckParent(target)
+		if cerr == nil {
+			cerr = rewrite.CheckMove(filename, target)
+		}
+		if cerr != nil {
+			logger.Warningf("Not rewriting %s: %s", filename, cerr)
+			rewrite = nil
+		}
+	}
+
+	var n int64
+	switch {
+	case err != nil:
+		// There is no room to download the object.
+	case rewrite != nil && rewrite.Mode == "move":
+		// Git is given the pointer, so that the file is unchanged as
+		// far as it can tell, and the contents go to the target.
+		err = lfs.PointerSmudgeToFile(filepath.Join(config.LocalWorkingDir, target), ptr, download && !offline, getTransferManifest(), cb)
+		if err == nil {
+			if rerr := recordSmudgeMove(filename, target); rerr != nil {
+				logger.Warningf("Could not record that %s was moved to %s: %s", filename, target, rerr)
+			}
+
+			var pn int
+			pn, err = ptr.Encode(to)
+			n = int64(pn)
+		}
+	default:
+		n, err = ptr.Smudge(to, filename, download && !offline, getTransferManifest(), cb)
+	}
 	if file != nil {
 		file.Close()
 	}
 
 	if err != nil {
-		ptr.Encode(to)
+		if cfg.SmudgePlaceholders() {
+			pn, perr := lfs.WritePlaceholder(to, ptr, filename)
+			if perr != nil {
+				return pn, perr
+			}
+			n = pn
+		} else {
+			ptr.Encode(to)
+		}
+
+		if offline && errors.IsDownloadDeclinedError(err) {
+			err = newOfflineSmudgeError(ptr.Oid)
+		}
+
 		// Download declined error is ok to skip if we weren't requesting download
 		if !(errors.IsDownloadDeclinedError(err) && !download) {
 			var oid string = ptr.Oid
@@ -77,33 +176,160 @@ func smudge(to io.Writer, from io.Reader, filename string, skip bool, filter *fi
 				oid = oid[:7]
 			}
 
+			if aerr := localstorage.CheckAccess(true); aerr != nil {
+				// Objects which are not in the store cannot
+				// be downloaded into it.
+				err = errors.Wrap(err, aerr.Error())
+			}
+
 			LoggedError(err, "Error downloading object: %s (%s): %s", filename, oid, err)
 			if !cfg.SkipDownloadErrors() {
 				os.Exit(2)
 			}
 		}
+	} else {
+		if stats != nil && ptr.Size > 0 {
+			stats.Record(ptr.Size, local)
+		}
+
+		if rewrite != nil && rewrite.Mode == "link" {
+			if lerr := rewrite.Link(filename, target); lerr != nil {
+				logger.Warningf("Could not link %s to %s: %s", target, filename, lerr)
+			}
+		}
+
+		if cfg.SmudgePlaceholders() {
+			if err := lfs.RemovePlaceholder(filename); err != nil {
+				return n, err
+			}
+		}
 	}
 
 	return n, nil
 }
 
+// SmudgeCacheStats counts the objects smudged by a session, and their sizes,
+// according to whether they were read from local storage (hits), or had to be
+// downloaded (misses).
+type SmudgeCacheStats struct {
+	Hits      int
+	HitBytes  int64
+	Misses    int
+	MissBytes int64
+}
+
+// Record counts an object of "size" bytes, which was found in local storage if
+// "local" is true, and was downloaded otherwise.
+func (s *SmudgeCacheStats) Record(size int64, local bool) {
+	if local {
+		s.Hits++
+		s.HitBytes += size
+	} else {
+		s.Misses++
+		s.MissBytes += size
+	}
+}
+
+// HitRatio returns the fraction of the objects smudged which were found in
+// local storage, or zero if none were smudged.
+func (s *SmudgeCacheStats) HitRatio() float64 {
+	if total := s.Hits + s.Misses; total > 0 {
+		return float64(s.Hits) / float64(total)
+	}
+	return 0
+}
+
+func (s *SmudgeCacheStats) String() string {
+	return fmt.Sprintf("%d of %d object(s) read from local storage (%.1f%%): %s local, %s downloaded",
+		s.Hits, s.Hits+s.Misses, 100*s.HitRatio(),
+		humanize.FormatBytes(uint64(s.HitBytes)), humanize.FormatBytes(uint64(s.MissBytes)))
+}
+
+// smudgeStdout writes the contents of the pointer read from "from" to "to",
+// downloading them first if necessary, as smudge does. Unlike smudge, this is
+// meant for consumers other than Git's smudge filter, such as a pipe from "git
+// cat-file", so nothing is done to the working tree: there is no include and
+// exclude filter, placeholders are never written, and an error writes nothing
+// in place of the object's contents, so that the consumer never mistakes the
+// pointer for them.
+//
+// The contents are copied as they are read, whether from the local object
+// store or once they have been downloaded to it, so no more than a single copy
+// buffer of them is held in memory at a time.
+func smudgeStdout(to io.Writer, from io.Reader, filename string) (int64, error) {
+	ptr, pbuf, perr := lfs.DecodeFrom(from)
+	if perr != nil {
+		// Anything that is not a pointer is written out unchanged, so
+		// that blobs not stored in Git LFS can be piped through, too.
+		return tools.Spool(to, pbuf, localstorage.Objects().TempDir)
+	}
+
+	lfs.LinkOrCopyFromReference(ptr.Oid, ptr.Size)
+
+	cb, file, err := lfs.CopyCallbackFile("download", filename, 1, 1)
+	if err != nil {
+		return 0, err
+	}
+
+	if !cfg.Offline() && !lfs.ObjectAvailable(ptr.Oid, ptr.Size) {
+		err = smudgeFreeSpaceError(ptr, false)
+	}
+
+	var n int64
+	if err == nil {
+		n, err = ptr.Smudge(to, filename, !cfg.Offline(), getTransferManifest(), cb)
+	}
+	if file != nil {
+		file.Close()
+	}
+
+	if err != nil && cfg.Offline() && errors.IsDownloadDeclinedError(err) {
+		err = newOfflineSmudgeError(ptr.Oid)
+	}
+	return n, err
+}
+
+// newOfflineSmudgeError returns the error given when the object "oid" is
+// needed by the smudge filter, but is not present locally and cannot be
+// downloaded, because Git LFS is in offline mode.
+func newOfflineSmudgeError(oid string) error {
+	return errors.Errorf("object %s is not present locally: %s; fetch it with `git lfs fetch` before going offline", oid, lfsapi.ErrOffline)
+}
+
 func smudgeCommand(cmd *cobra.Command, args []string) {
+	if smudgeToStdout {
+		requireStdin("This command reads a Git LFS pointer from standard input")
+
+		if _, err := smudgeStdout(os.Stdout, os.Stdin, smudgeFilename(args)); err != nil {
+			LoggedError(err, "Error downloading object: %s: %s", smudgeFilename(args), err)
+			os.Exit(2)
+		}
+		return
+	}
+
 	requireStdin("This command should be run by the Git 'smudge' filter")
 	lfs.InstallHooks(false)
 
 	if !smudgeSkip && cfg.Os.Bool("GIT_LFS_SKIP_SMUDGE", false) {
 		smudgeSkip = true
 	}
-	filter := filepathfilter.New(cfg.FetchIncludePaths(), cfg.FetchExcludePaths())
+	filter := filepathfilter.NewWithNegation(cfg.FetchIncludePaths(), cfg.FetchExcludePaths())
 
-	if n, err := smudge(os.Stdout, os.Stdin, smudgeFilename(args), smudgeSkip, filter); err != nil {
-		if errors.IsNotAPointerError(err) {
-			fmt.Fprintln(os.Stderr, err.Error())
-		} else {
+	if n, err := smudge(os.Stdout, os.Stdin, smudgeFilename(args), smudgeSkip, filter, nil); err != nil {
+		if !errors.IsNotAPointerError(err) {
 			Error(err.Error())
+		} else {
+			switch cfg.SmudgeNonPointer() {
+			case "error":
+				Error("%s; refusing to smudge it, as lfs.smudge.nonpointer is \"error\"", err.Error())
+				os.Exit(2)
+			case "ignore":
+			default:
+				logger.Warningf("%s", err.Error())
+			}
 		}
 	} else if possiblyMalformedSmudge(n) {
-		fmt.Fprintln(os.Stderr, "Possibly malformed smudge on Windows: see `git lfs help smudge` for more info.")
+		logger.Warningf("Possibly malformed smudge on Windows: see `git lfs help smudge` for more info.")
 	}
 }
 
@@ -121,5 +347,6 @@ func possiblyMalformedSmudge(n int64) bool {
 func init() {
 	RegisterCommand("smudge", smudgeCommand, func(cmd *cobra.Command) {
 		cmd.Flags().BoolVarP(&smudgeSkip, "skip", "s", false, "")
+		cmd.Flags().BoolVarP(&smudgeToStdout, "to-stdout", "", false, "")
 	})
 }
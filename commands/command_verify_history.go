@@ -0,0 +1,166 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/git"
+	"github.com/git-lfs/git-lfs/lfs"
+	"github.com/git-lfs/git-lfs/tq"
+	"github.com/spf13/cobra"
+)
+
+// verifyHistoryBatchSize is the number of objects asked about in each batch
+// API request.
+const verifyHistoryBatchSize = 100
+
+// verifyHistoryCommand checks that the remote has the object of each pointer
+// added by any commit reachable from a ref, with the size that the pointer
+// gives. Each object is only asked about once, however many commits add it, and
+// problems are reported by commit.
+func verifyHistoryCommand(cmd *cobra.Command, args []string) {
+	requireInRepo()
+
+	if len(args) > 0 {
+		if err := git.ValidateRemote(args[0]); err != nil {
+			Exit("Invalid remote name %q", args[0])
+		}
+		cfg.CurrentRemote = args[0]
+	} else {
+		remote, err := git.DefaultRemote()
+		if err != nil {
+			Exit("No default remote")
+		}
+		cfg.CurrentRemote = remote
+	}
+
+	var commits []string
+	pointersByCommit := make(map[string][]*lfs.WrappedPointer)
+	var oids []string
+	// sizes are the distinct sizes given by the pointers to each object,
+	// in the order they were found.
+	sizes := make(map[string][]int64)
+
+	err := lfs.ScanHistory(func(commit string, p *lfs.WrappedPointer, err error) {
+		if err != nil {
+			ExitWithError(err)
+		}
+
+		if _, ok := pointersByCommit[commit]; !ok {
+			commits = append(commits, commit)
+		}
+		pointersByCommit[commit] = append(pointersByCommit[commit], p)
+
+		if _, ok := sizes[p.Oid]; !ok {
+			oids = append(oids, p.Oid)
+		}
+		for _, size := range sizes[p.Oid] {
+			if size == p.Size {
+				return
+			}
+		}
+		sizes[p.Oid] = append(sizes[p.Oid], p.Size)
+	})
+	if err != nil {
+		ExitWithError(err)
+	}
+
+	remote, err := verifyHistoryRemoteObjects(oids, sizes)
+	if err != nil {
+		ExitWithError(err)
+	}
+
+	failed := make(map[string]bool)
+	for _, commit := range commits {
+		var problems []string
+		for _, p := range pointersByCommit[commit] {
+			if problem := verifyHistoryPointer(p, sizes[p.Oid], remote[p.Oid]); len(problem) > 0 {
+				problems = append(problems, fmt.Sprintf("  %s (%s): %s", p.Name, p.Oid, problem))
+				failed[p.Oid] = true
+			}
+		}
+
+		if len(problems) > 0 {
+			Print("Commit %s:", commit)
+			for _, problem := range problems {
+				Print(problem)
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		Exit("Git LFS verify-history: %d of %d objects failed verification", len(failed), len(oids))
+	}
+	Print("Git LFS verify-history OK (%d objects in %d commits)", len(oids), len(commits))
+}
+
+// verifyHistoryRemoteObjects asks the remote about each of "oids", in batches,
+// and returns what it says about them, keyed by OID. Objects which the remote
+// does not mention are missing from the result.
+func verifyHistoryRemoteObjects(oids []string, sizes map[string][]int64) (map[string]*tq.Transfer, error) {
+	manifest := getTransferManifest()
+	remote := make(map[string]*tq.Transfer, len(oids))
+
+	for start := 0; start < len(oids); start += verifyHistoryBatchSize {
+		end := start + verifyHistoryBatchSize
+		if end > len(oids) {
+			end = len(oids)
+		}
+
+		objects := make([]*tq.Transfer, 0, end-start)
+		for _, oid := range oids[start:end] {
+			objects = append(objects, &tq.Transfer{Oid: oid, Size: sizes[oid][0]})
+		}
+
+		res, err := tq.Batch(manifest, tq.Download, cfg.CurrentRemote, objects)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range res.Objects {
+			remote[o.Oid] = o
+		}
+	}
+	return remote, nil
+}
+
+// verifyHistoryPointer returns what is wrong with the pointer "p", given the
+// sizes which all pointers to its object give and what the remote said about
+// it, or an empty string if nothing is.
+func verifyHistoryPointer(p *lfs.WrappedPointer, sizes []int64, remote *tq.Transfer) string {
+	if remote == nil {
+		return "not returned by the remote"
+	}
+
+	if remote.Error != nil {
+		if remote.Error.Code == 404 {
+			return "missing on the remote"
+		}
+		return fmt.Sprintf("error from the remote: %s", remote.Error)
+	}
+
+	if a, err := remote.Rel("download"); err != nil || a == nil {
+		return "missing on the remote"
+	}
+
+	if len(sizes) > 1 {
+		// Which of them is right can't be told from the remote, since
+		// it was only asked about one.
+		others := make([]string, 0, len(sizes)-1)
+		for _, size := range sizes {
+			if size != p.Size {
+				others = append(others, strconv.FormatInt(size, 10))
+			}
+		}
+		return fmt.Sprintf("size %d in the pointer, but %s in other pointers to the same object", p.Size, strings.Join(others, ", "))
+	}
+
+	if remote.Size != p.Size {
+		return fmt.Sprintf("size %d in the pointer, but %d on the remote", p.Size, remote.Size)
+	}
+	return ""
+}
+
+func init() {
+	RegisterCommand("verify-history", verifyHistoryCommand, nil)
+}
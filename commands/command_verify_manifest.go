@@ -0,0 +1,278 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/git-lfs/git-lfs/lfs"
+	"github.com/git-lfs/git-lfs/subprocess"
+	"github.com/git-lfs/git-lfs/tools"
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyManifestRehash           bool
+	verifyManifestRef              string
+	verifyManifestSignatureCommand string
+)
+
+// verifyManifestCommand checks each object recorded in the integrity manifest
+// against the local object store. By default, only the size and modification
+// time of each object are checked, which is cheap; with --rehash, the contents
+// of each object are hashed again as well.
+//
+// Given a release manifest instead, it checks the objects listed in it.
+func verifyManifestCommand(cmd *cobra.Command, args []string) {
+	requireInRepo()
+
+	if len(args) > 0 {
+		verifyReleaseManifest(args[0])
+		return
+	}
+	if len(verifyManifestRef) > 0 || len(verifyManifestSignatureCommand) > 0 {
+		Exit("--ref and --signature-command can only be given with a release manifest.")
+	}
+
+	entries, err := lfs.IntegrityEntries()
+	if err != nil {
+		ExitWithError(err)
+	}
+
+	oids := make([]string, 0, len(entries))
+	for oid := range entries {
+		oids = append(oids, oid)
+	}
+	sort.Strings(oids)
+
+	var problems int
+	for _, oid := range oids {
+		ok, err := verifyManifestEntry(entries[oid])
+		if err != nil {
+			ExitWithError(err)
+		}
+		if !ok {
+			problems++
+		}
+	}
+
+	if problems > 0 {
+		Exit("Git LFS verify-manifest: %d of %d objects failed verification", problems, len(oids))
+	}
+	Print("Git LFS verify-manifest OK (%d objects)", len(oids))
+}
+
+// verifyManifestEntry checks a single entry of the integrity manifest, printing
+// any problem found with it.
+func verifyManifestEntry(entry *lfs.IntegrityEntry) (bool, error) {
+	path := lfs.LocalMediaPathReadOnly(entry.Oid)
+
+	Debug("Examining %v (%v)", entry.Oid, path)
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			Print("Object %s is missing", entry.Oid)
+			return false, nil
+		}
+		return false, err
+	}
+
+	if stat.Size() != entry.Size {
+		Print("Object %s has size %d, expected %d", entry.Oid, stat.Size(), entry.Size)
+		return false, nil
+	}
+
+	if stat.ModTime().After(entry.Written) {
+		Print("Object %s was modified at %s, after it was written at %s", entry.Oid,
+			stat.ModTime().Format("2006-01-02 15:04:05 -0700"),
+			entry.Written.Format("2006-01-02 15:04:05 -0700"))
+		return false, nil
+	}
+
+	if !verifyManifestRehash {
+		return true, nil
+	}
+
+	if recalculatedOid, err := verifyManifestHash(path); err != nil {
+		return false, err
+	} else if recalculatedOid != entry.Oid {
+		Print("Object %s is corrupt", entry.Oid)
+		return false, nil
+	}
+	return true, nil
+}
+
+// verifyReleaseManifest checks that each object listed in the release manifest
+// at "path" is in the local object store, with the size listed, and hashes to
+// its OID. With --ref, the pointers in the tree of that ref which the manifest
+// does not list are reported as well, and so is any pointer with a different
+// size than listed. With --signature-command, the manifest must first pass the
+// command, so that it can be trusted. The manifest is read only once, so that
+// the entries checked are those which were verified.
+func verifyReleaseManifest(path string) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		ExitWithError(errors.Wrap(err, "release manifest"))
+	}
+
+	if len(verifyManifestSignatureCommand) > 0 {
+		verifyReleaseManifestSignature(path, data)
+	}
+
+	entries, err := lfs.ParseReleaseManifest(bytes.NewReader(data))
+	if err != nil {
+		ExitWithError(err)
+	}
+
+	listed := make(map[string]*lfs.ReleaseEntry, len(entries))
+	for _, e := range entries {
+		listed[e.Oid] = e
+	}
+
+	var problems int
+	if len(verifyManifestRef) > 0 {
+		problems += verifyReleaseRef(verifyManifestRef, listed)
+	}
+
+	for _, e := range entries {
+		ok, err := verifyReleaseEntry(e)
+		if err != nil {
+			ExitWithError(err)
+		}
+		if !ok {
+			problems++
+		}
+	}
+
+	if problems > 0 {
+		Exit("Git LFS verify-manifest: %d problem(s) found with the %d objects in %s", problems, len(entries), path)
+	}
+	Print("Git LFS verify-manifest OK (%d objects in %s)", len(entries), path)
+}
+
+// verifyReleaseManifestSignature runs the --signature-command, with the path of
+// a private copy of "data", the contents of the release manifest at "path", as
+// its last argument, and exits unless it succeeds. The copy is verified rather
+// than the manifest itself, which could be changed after it was read.
+func verifyReleaseManifestSignature(path string, data []byte) {
+	args := tools.QuotedFields(verifyManifestSignatureCommand)
+	if len(args) == 0 {
+		Exit("--signature-command must not be empty.")
+	}
+
+	tmp, err := lfs.TempFile("release-manifest")
+	if err != nil {
+		ExitWithError(errors.Wrap(err, "release manifest"))
+	}
+
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		ExitWithError(errors.Wrap(err, "release manifest"))
+	}
+
+	cmd := subprocess.ExecCommand(args[0], append(args[1:], tmp.Name())...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	os.Remove(tmp.Name())
+	if err != nil {
+		Exit("Git LFS verify-manifest: the signature of %s could not be verified: %s", path, err)
+	}
+}
+
+// verifyReleaseRef reports each pointer in the tree of "ref" which is not listed
+// in the release manifest, or is listed with a different size, and returns how
+// many there are.
+func verifyReleaseRef(ref string, listed map[string]*lfs.ReleaseEntry) int {
+	var problems int
+	gitscanner := lfs.NewGitScanner(func(p *lfs.WrappedPointer, err error) {
+		if err != nil {
+			ExitWithError(errors.Wrap(err, "Could not scan for Git LFS tree"))
+		}
+
+		e, ok := listed[p.Oid]
+		if !ok {
+			Print("Object %s (%s) in %s is not in the manifest", p.Oid, p.Name, ref)
+			problems++
+		} else if e.Size != p.Size {
+			Print("Object %s (%s) in %s has size %d, but the manifest lists %d", p.Oid, p.Name, ref, p.Size, e.Size)
+			problems++
+		}
+	})
+	if err := gitscanner.ScanTree(ref); err != nil {
+		ExitWithError(errors.Wrap(err, "Could not scan for Git LFS tree"))
+	}
+	gitscanner.Close()
+
+	return problems
+}
+
+// verifyReleaseEntry checks a single object of a release manifest against the
+// local object store, printing any problem found with it. Its contents are
+// always hashed, since the manifest is only worth checking against if the
+// objects are known to match it.
+func verifyReleaseEntry(e *lfs.ReleaseEntry) (bool, error) {
+	path := lfs.LocalMediaPathReadOnly(e.Oid)
+
+	Debug("Examining %v (%v)", e.Oid, path)
+
+	name := e.Oid
+	if len(e.Name) > 0 {
+		name = fmt.Sprintf("%s (%s)", e.Oid, e.Name)
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			Print("Object %s is missing", name)
+			return false, nil
+		}
+		return false, err
+	}
+
+	if stat.Size() != e.Size {
+		Print("Object %s has size %d, expected %d", name, stat.Size(), e.Size)
+		return false, nil
+	}
+
+	if recalculatedOid, err := verifyManifestHash(path); err != nil {
+		return false, err
+	} else if recalculatedOid != e.Oid {
+		Print("Object %s is corrupt", name)
+		return false, nil
+	}
+	return true, nil
+}
+
+// verifyManifestHash returns the OID of the contents of the file at "path".
+func verifyManifestHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	oidHash := tools.NewLfsContentHash()
+	if _, err := io.Copy(oidHash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(oidHash.Sum(nil)), nil
+}
+
+func init() {
+	RegisterCommand("verify-manifest", verifyManifestCommand, func(cmd *cobra.Command) {
+		cmd.Flags().BoolVarP(&verifyManifestRehash, "rehash", "r", false, "Recalculate the OID of each object.")
+		cmd.Flags().StringVarP(&verifyManifestRef, "ref", "", "", "Also check the pointers in the tree of the given ref against the release manifest.")
+		cmd.Flags().StringVarP(&verifyManifestSignatureCommand, "signature-command", "", "", "Verify the signature of the release manifest with the given command first.")
+	})
+}
@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"github.com/git-lfs/git-lfs/lfs"
+	"github.com/spf13/cobra"
+)
+
+// verifyPointersCommand checks the blob of each file in the tree at a ref,
+// HEAD by default, against the pointers which the clean filter recorded writing
+// for it while lfs.debug.pointerchecksums was set. A file whose blob is none of
+// them has a pointer which was changed after the clean filter wrote it.
+func verifyPointersCommand(cmd *cobra.Command, args []string) {
+	requireInRepo()
+
+	ref := "HEAD"
+	if len(args) > 0 {
+		ref = args[0]
+	}
+
+	checked, mismatches, err := lfs.VerifyPointerChecksums(ref)
+	if err != nil {
+		ExitWithError(err)
+	}
+
+	for _, m := range mismatches {
+		Print("Pointer for %s is blob %s, but the clean filter last wrote blob %s (%s) at %s",
+			m.Name, m.Blob, m.Last.Blob, m.Last.Oid,
+			m.Last.Written.Format("2006-01-02 15:04:05 -0700"))
+	}
+
+	if len(mismatches) > 0 {
+		Exit("Git LFS verify-pointers: %d of %d files failed verification", len(mismatches), checked)
+	}
+	Print("Git LFS verify-pointers OK (%d files)", checked)
+}
+
+func init() {
+	RegisterCommand("verify-pointers", verifyPointersCommand, nil)
+}
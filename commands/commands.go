@@ -19,6 +19,7 @@ import (
 	"github.com/git-lfs/git-lfs/lfs"
 	"github.com/git-lfs/git-lfs/lfsapi"
 	"github.com/git-lfs/git-lfs/locking"
+	"github.com/git-lfs/git-lfs/logger"
 	"github.com/git-lfs/git-lfs/progress"
 	"github.com/git-lfs/git-lfs/tools"
 	"github.com/git-lfs/git-lfs/tq"
@@ -95,14 +96,33 @@ func newDownloadCheckQueue(manifest *tq.Manifest, remote string, options ...tq.O
 	allOptions := make([]tq.Option, 0, len(options)+1)
 	allOptions = append(allOptions, options...)
 	allOptions = append(allOptions, tq.DryRun(true))
-	return newDownloadQueue(manifest, remote, allOptions...)
+	return tq.NewTransferQueue(tq.Download, manifest, remote, allOptions...)
 }
 
-// newDownloadQueue builds a DownloadQueue, allowing concurrent downloads.
+// newDownloadQueue builds a DownloadQueue, allowing concurrent downloads. It
+// exits if objects cannot be written to the local object store.
 func newDownloadQueue(manifest *tq.Manifest, remote string, options ...tq.Option) *tq.TransferQueue {
+	if err := lfs.CheckStoreLayout(); err != nil {
+		Exit("%s", err)
+	}
+	if cfg.IntegrityManifest() {
+		options = append(options, tq.WithCompletionCallback(recordIntegrity))
+	}
 	return tq.NewTransferQueue(tq.Download, manifest, remote, options...)
 }
 
+// recordIntegrity records a downloaded object in the integrity manifest. A
+// download of only a range of the object's bytes is not the object, and is not
+// recorded.
+func recordIntegrity(t *tq.Transfer) {
+	if t.Range != nil {
+		return
+	}
+	if err := lfs.RecordIntegrity(t.Oid, t.Size); err != nil {
+		LoggedError(err, "Could not record %s in the integrity manifest: %s", t.Oid, err)
+	}
+}
+
 // newUploadQueue builds an UploadQueue, allowing `workers` concurrent uploads.
 func newUploadQueue(manifest *tq.Manifest, remote string, options ...tq.Option) *tq.TransferQueue {
 	return tq.NewTransferQueue(tq.Upload, manifest, remote, options...)
@@ -110,7 +130,7 @@ func newUploadQueue(manifest *tq.Manifest, remote string, options ...tq.Option)
 
 func buildFilepathFilter(config *config.Configuration, includeArg, excludeArg *string) *filepathfilter.Filter {
 	inc, exc := determineIncludeExcludePaths(config, includeArg, excludeArg)
-	return filepathfilter.New(inc, exc)
+	return filepathfilter.NewWithNegation(inc, exc)
 }
 
 func downloadTransfer(p *lfs.WrappedPointer) (name, path, oid string, size int64) {
@@ -128,7 +148,12 @@ func uploadTransfer(p *lfs.WrappedPointer) (*tq.Transfer, error) {
 		return nil, errors.Wrapf(err, "Error uploading file %s (%s)", filename, oid)
 	}
 
-	if len(filename) > 0 {
+	// Objects which are only in one of the object alternates are uploaded
+	// from there, rather than being copied into the local object store.
+	path := localMediaPath
+	if altfile, ok := lfs.AlternateMediaPath(oid, p.Size); ok && !lfs.ObjectExistsOfSize(oid, p.Size) {
+		path = altfile
+	} else if len(filename) > 0 {
 		if err = ensureFile(filename, localMediaPath); err != nil && !errors.IsCleanPointerError(err) {
 			return nil, err
 		}
@@ -136,12 +161,68 @@ func uploadTransfer(p *lfs.WrappedPointer) (*tq.Transfer, error) {
 
 	return &tq.Transfer{
 		Name: filename,
-		Path: localMediaPath,
+		Path: path,
 		Oid:  oid,
 		Size: p.Size,
 	}, nil
 }
 
+// transferErrorCategories is the order in which printTransferErrorSummary lists
+// the causes of failed transfers.
+var transferErrorCategories = []errors.Category{
+	errors.CategoryAuth,
+	errors.CategoryNetwork,
+	errors.CategoryServer,
+	errors.CategoryVerification,
+	errors.CategoryDisk,
+	errors.CategoryOther,
+}
+
+// printTransferErrorSummary prints how many of the transfer errors "errs" there
+// were for each cause, such as "auth" or "network", after the errors themselves,
+// so that scripts can tell why a command failed without parsing each of them,
+// followed by each distinct message the server gave for them, with the ID of
+// its request, if it gave one. Nothing is printed if there are none.
+func printTransferErrorSummary(errs []error) {
+	if len(errs) == 0 {
+		return
+	}
+
+	counts := make(map[errors.Category]int)
+	messages := make(map[string]int)
+	var order []string
+	for _, err := range errs {
+		counts[errors.CategoryOf(err)]++
+
+		msg, requestID := errors.ServerMessageOf(err)
+		if len(msg) == 0 && len(requestID) == 0 {
+			continue
+		}
+		if len(msg) == 0 {
+			msg = "(no message)"
+		}
+		if len(requestID) > 0 {
+			msg = fmt.Sprintf("%s (request ID: %s)", msg, requestID)
+		}
+		if messages[msg] == 0 {
+			order = append(order, msg)
+		}
+		messages[msg]++
+	}
+
+	parts := make([]string, 0, len(counts))
+	for _, category := range transferErrorCategories {
+		if n := counts[category]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%s=%d", category, n))
+		}
+	}
+	Error("Transfer errors by cause: %s", strings.Join(parts, " "))
+
+	for _, msg := range order {
+		Error("Server said: %s, for %d transfer(s)", msg, messages[msg])
+	}
+}
+
 // ensureFile makes sure that the cleanPath exists before pushing it.  If it
 // does not exist, it attempts to clean it by reading the file at smudgePath.
 func ensureFile(smudgePath, cleanPath string) error {
@@ -242,7 +323,8 @@ func LoggedError(err error, format string, args ...interface{}) {
 	file := handlePanic(err)
 
 	if len(file) > 0 {
-		fmt.Fprintf(os.Stderr, "\nErrors logged to %s\nUse `git lfs logs last` to view the log.\n", file)
+		logger.Log(logger.Error, logger.Fields{"file": file},
+			"\nErrors logged to %s\nUse `git lfs logs last` to view the log.", file)
 	}
 }
 
@@ -255,7 +337,7 @@ func Panic(err error, format string, args ...interface{}) {
 
 func Cleanup() {
 	if err := lfs.ClearTempObjects(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error clearing old temp files: %s\n", err)
+		logger.Errorf("Error clearing old temp files: %s", err)
 	}
 }
 
@@ -386,6 +468,7 @@ func determineIncludeExcludePaths(config *config.Configuration, includeArg, excl
 func buildProgressMeter(dryRun bool) *progress.ProgressMeter {
 	return progress.NewMeter(
 		progress.WithOSEnv(cfg.Os),
+		progress.WithRateSamples(cfg.Git.Int("lfs.progress.samples", 0)),
 		progress.DryRun(dryRun),
 	)
 }
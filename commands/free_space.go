@@ -0,0 +1,146 @@
+package commands
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/git-lfs/git-lfs/filepathfilter"
+	"github.com/git-lfs/git-lfs/lfs"
+	"github.com/git-lfs/git-lfs/tools"
+	"github.com/git-lfs/git-lfs/tools/humanize"
+	"github.com/rubyist/tracerx"
+)
+
+// freeSpaceMargin returns the margin of lfs.minfreespace, and whether it is set,
+// exiting if it is invalid.
+func freeSpaceMargin() (uint64, bool) {
+	margin, ok, err := cfg.MinFreeSpace()
+	if err != nil {
+		Exit("%s", err)
+	}
+	return margin, ok
+}
+
+// requireFreeSpace exits, before anything has been written, if lfs.minfreespace
+// is set, and the volume which holds "dir" does not have room for "needed"
+// bytes, plus that margin. Nothing is checked if the free space of the volume
+// cannot be found.
+func requireFreeSpace(dir string, needed int64) {
+	margin, ok := freeSpaceMargin()
+	if !ok || needed <= 0 {
+		return
+	}
+
+	if err := freeSpaceError(dir, needed, margin); err != nil {
+		Exit("%s", err)
+	}
+}
+
+// freeSpaceError returns an error saying so if the volume which holds "dir"
+// does not have room for "needed" bytes, plus "margin". Nothing is checked if
+// the free space of the volume cannot be found.
+func freeSpaceError(dir string, needed int64, margin uint64) error {
+	available, err := tools.FreeSpace(dir)
+	if err != nil {
+		tracerx.Printf("free space: not checking %s: %s", dir, err)
+		return nil
+	}
+
+	if required := uint64(needed) + margin; available < required {
+		return errors.Errorf("Not enough free space in %s: %s required (%s of Git LFS files, plus %s from lfs.minfreespace), but only %s available",
+			dir, humanize.FormatBytes(required), humanize.FormatBytes(uint64(needed)),
+			humanize.FormatBytes(margin), humanize.FormatBytes(available))
+	}
+	return nil
+}
+
+// smudgeFreeSpaceError returns an error if there is no room for the object of
+// "ptr", which the smudge filter is about to download, in the local store, and,
+// if "checkout" is true, for its contents in the working tree as well, plus the
+// margin given by lfs.minfreespace. Unlike the other checks, this one is made
+// even if lfs.minfreespace is not set, with no margin, so that an object which
+// cannot fit is not downloaded only to fill the volume.
+func smudgeFreeSpaceError(ptr *lfs.Pointer, checkout bool) error {
+	margin, _, err := cfg.MinFreeSpace()
+	if err != nil {
+		return err
+	}
+
+	storeDir := lfs.LocalMediaDir()
+	if !checkout {
+		return freeSpaceError(storeDir, ptr.Size, margin)
+	}
+	if strings.HasPrefix(storeDir, config.LocalWorkingDir+string(filepath.Separator)) {
+		return freeSpaceError(config.LocalWorkingDir, 2*ptr.Size, margin)
+	}
+	if err := freeSpaceError(storeDir, ptr.Size, margin); err != nil {
+		return err
+	}
+	return freeSpaceError(config.LocalWorkingDir, ptr.Size, margin)
+}
+
+// totalSize returns the sum of the sizes of "pointers".
+func totalSize(pointers []*lfs.WrappedPointer) int64 {
+	var size int64
+	for _, p := range pointers {
+		size += p.Size
+	}
+	return size
+}
+
+// requireCheckoutFreeSpace is requireFreeSpace for a checkout of the Git LFS
+// files in the tree "sha" which "filter" allows, if lfs.minfreespace is set. The
+// local store must have room for the objects which are not already in it, if
+// "download" is true, and the working tree for the files which are not already
+// checked out, except for those whose objects are not local, unless "download"
+// is true. The store is usually in the ".git" directory of the working
+// tree, in which case the working tree must have room for both.
+func requireCheckoutFreeSpace(sha string, filter *filepathfilter.Filter, download bool) {
+	if _, ok := freeSpaceMargin(); !ok {
+		return
+	}
+
+	var downloads, checkouts int64
+	seen := make(map[string]bool)
+	gitscanner := lfs.NewGitScanner(func(p *lfs.WrappedPointer, err error) {
+		if err != nil {
+			return
+		}
+
+		if download && !seen[p.Oid] {
+			seen[p.Oid] = true
+			if !lfs.ObjectAvailable(p.Oid, p.Size) {
+				downloads += p.Size
+			}
+		}
+
+		// Files which have real contents are left alone by checkout,
+		// so only missing files and pointers need room, and without a
+		// download, only those whose objects are local.
+		if !download && !lfs.ObjectAvailable(p.Oid, p.Size) {
+			return
+		}
+		path := filepath.Join(config.LocalWorkingDir, filepath.FromSlash(p.Name))
+		if _, err := lfs.DecodePointerFromFile(path); err == nil || !tools.FileExists(path) {
+			checkouts += p.Size
+		}
+	})
+	gitscanner.Filter = filter
+
+	if err := gitscanner.ScanTree(sha); err != nil {
+		tracerx.Printf("free space: could not scan %s: %s", sha, err)
+		return
+	}
+	gitscanner.Close()
+
+	storeDir := lfs.LocalMediaDir()
+	if strings.HasPrefix(storeDir, config.LocalWorkingDir+string(filepath.Separator)) {
+		requireFreeSpace(config.LocalWorkingDir, downloads+checkouts)
+		return
+	}
+
+	requireFreeSpace(storeDir, downloads)
+	requireFreeSpace(config.LocalWorkingDir, checkouts)
+}
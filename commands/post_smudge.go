@@ -0,0 +1,219 @@
+package commands
+
+import (
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/git-lfs/git-lfs/filepathfilter"
+	"github.com/git-lfs/git-lfs/logger"
+	"github.com/rubyist/tracerx"
+)
+
+// postSmudgeCommand is a command given by lfs.postsmudge.<pattern>.command,
+// which is run for each smudged file matching its pattern.
+type postSmudgeCommand struct {
+	pattern string
+	command string
+	filter  *filepathfilter.Filter
+}
+
+// postSmudgeRunner runs the post-smudge commands for files written by the
+// filter process. Commands are run by a fixed number of workers in the
+// background, so that they do not hold up the checkout, and failures are
+// reported as warnings. No more than that many commands are ever running at
+// once, even once the runner is detached.
+type postSmudgeRunner struct {
+	commands []*postSmudgeCommand
+	workers  int
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	pending  []*postSmudgeJob
+	started  int
+	running  int
+	detached bool
+}
+
+// postSmudgeJob is a command to be run for a file which has been smudged.
+type postSmudgeJob struct {
+	command  *postSmudgeCommand
+	filename string
+}
+
+// newPostSmudgeRunner returns a postSmudgeRunner for the commands in
+// "commands", keyed by pattern, which runs no more than "workers" of them at
+// once.
+func newPostSmudgeRunner(commands map[string]string, workers int) *postSmudgeRunner {
+	r := &postSmudgeRunner{workers: workers}
+	r.cond = sync.NewCond(&r.mu)
+	for pattern, command := range commands {
+		if len(strings.TrimSpace(command)) == 0 {
+			continue
+		}
+
+		r.commands = append(r.commands, &postSmudgeCommand{
+			pattern: pattern,
+			command: command,
+			filter:  filepathfilter.New([]string{pattern}, nil),
+		})
+	}
+
+	sort.Slice(r.commands, func(i, j int) bool {
+		return r.commands[i].pattern < r.commands[j].pattern
+	})
+	return r
+}
+
+// Run queues each command whose pattern matches "filename", which must
+// already have been written to the working tree. It does not wait for any of
+// them to start.
+func (r *postSmudgeRunner) Run(filename string) {
+	// Patterns are read in lower case, so match the file name in lower case,
+	// too.
+	name := strings.ToLower(filename)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, c := range r.commands {
+		if !c.filter.Allows(name) {
+			continue
+		}
+
+		if r.detached {
+			// The session is over, so there is no worker to
+			// pick it up.
+			if !r.startDetached(c, filename) {
+				c.warn(filename, errors.New("too many post-smudge commands are still running"))
+			}
+			continue
+		}
+
+		r.pending = append(r.pending, &postSmudgeJob{command: c, filename: filename})
+		if r.started < r.workers {
+			r.started++
+			go r.work()
+		}
+		r.cond.Signal()
+	}
+}
+
+// Detach stops the workers from starting any more commands, and starts those
+// still queued without waiting for them, nor for those already running, to
+// exit, as long as fewer than the number of workers are running. The rest are
+// not run, which is reported as a warning. Git waits for the filter process to
+// exit before it does, so commands are left to finish on their own once the
+// session ends, and failures of those are not reported.
+func (r *postSmudgeRunner) Detach() {
+	r.mu.Lock()
+	r.detached = true
+	pending := r.pending
+	r.pending = nil
+
+	var dropped int
+	for _, job := range pending {
+		if !r.startDetached(job.command, job.filename) {
+			dropped++
+		}
+	}
+	r.mu.Unlock()
+	r.cond.Broadcast()
+
+	if dropped > 0 {
+		logger.Log(logger.Warning, logger.Fields{"dropped": dropped},
+			"warning: %d post-smudge command(s) were not run, as the checkout finished while %d were still running", dropped, r.workers)
+	}
+}
+
+// startDetached starts the command "c" for "filename" without waiting for it,
+// unless as many commands as there are workers are running already, and
+// returns whether it was started, or failed to start. It is called with "mu"
+// held.
+func (r *postSmudgeRunner) startDetached(c *postSmudgeCommand, filename string) bool {
+	if r.running >= r.workers {
+		tracerx.Printf("filter-process: not running post-smudge %q for %s", c.command, filename)
+		return false
+	}
+
+	cmd := c.cmd(filename)
+	if err := cmd.Start(); err != nil {
+		c.warn(filename, err)
+		return true
+	}
+
+	r.running++
+	go func() {
+		cmd.Wait()
+
+		r.mu.Lock()
+		r.running--
+		r.mu.Unlock()
+	}()
+	return true
+}
+
+func (r *postSmudgeRunner) work() {
+	for {
+		r.mu.Lock()
+		for len(r.pending) == 0 && !r.detached {
+			r.cond.Wait()
+		}
+		if r.detached {
+			r.mu.Unlock()
+			return
+		}
+		job := r.pending[0]
+		r.pending = r.pending[1:]
+		r.running++
+		r.mu.Unlock()
+
+		job.command.run(job.filename)
+
+		r.mu.Lock()
+		r.running--
+		r.mu.Unlock()
+	}
+}
+
+// cmd returns the command to run for "filename". Its output goes straight to
+// standard error, rather than through a pipe, so that it can outlive the
+// filter process.
+func (c *postSmudgeCommand) cmd(filename string) *exec.Cmd {
+	// As with extensions, "%f" is replaced by the file name. Otherwise, it
+	// is given as the last argument.
+	pieces := strings.Fields(c.command)
+	var args []string
+	var replaced bool
+	for _, value := range pieces[1:] {
+		if strings.Contains(value, "%f") {
+			replaced = true
+		}
+		args = append(args, strings.Replace(value, "%f", filename, -1))
+	}
+	if !replaced {
+		args = append(args, filename)
+	}
+
+	tracerx.Printf("filter-process: post-smudge %q for %s", c.command, filename)
+
+	cmd := exec.Command(pieces[0], args...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+// run runs the command for "filename", and waits for it to exit.
+func (c *postSmudgeCommand) run(filename string) {
+	if err := c.cmd(filename).Run(); err != nil {
+		c.warn(filename, err)
+	}
+}
+
+func (c *postSmudgeCommand) warn(filename string, err error) {
+	logger.Log(logger.Warning, logger.Fields{"path": filename, "command": c.command, "error": err},
+		"warning: post-smudge command %q for %s failed: %s", c.command, filename, err)
+}
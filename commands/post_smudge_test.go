@@ -0,0 +1,29 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostSmudgeRunnerDetachKeepsWorkerBound(t *testing.T) {
+	r := newPostSmudgeRunner(map[string]string{"*": "sleep %f"}, 1)
+	for i := 0; i < 3; i++ {
+		r.Run("1")
+	}
+	r.Detach()
+
+	r.mu.Lock()
+	assert.Equal(t, 1, r.running)
+	assert.Empty(t, r.pending)
+	r.mu.Unlock()
+
+	// Once detached, files are not queued, and are only run while the
+	// bound allows.
+	r.Run("1")
+
+	r.mu.Lock()
+	assert.Equal(t, 1, r.running)
+	assert.Empty(t, r.pending)
+	r.mu.Unlock()
+}
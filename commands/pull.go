@@ -2,7 +2,6 @@ package commands
 
 import (
 	"bytes"
-	"fmt"
 	"io"
 	"os"
 	"os/exec"
@@ -63,7 +62,7 @@ func (c *singleCheckout) Run(p *lfs.WrappedPointer) {
 			// acceptable error, data not local (fetch not run or include/exclude)
 			LoggedError(err, "Skipped checkout for %q, content not local. Use fetch to download.", p.Name)
 		} else {
-			FullError(fmt.Errorf("Could not check out %q", p.Name))
+			FullError(errors.Wrapf(err, "Could not check out %q", p.Name))
 		}
 		return
 	}
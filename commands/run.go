@@ -11,6 +11,8 @@ import (
 	"github.com/git-lfs/git-lfs/config"
 	"github.com/git-lfs/git-lfs/lfsapi"
 	"github.com/git-lfs/git-lfs/localstorage"
+	"github.com/git-lfs/git-lfs/logger"
+	"github.com/git-lfs/git-lfs/tools"
 	"github.com/spf13/cobra"
 )
 
@@ -80,11 +82,13 @@ func gitlfsCommand(cmd *cobra.Command, args []string) {
 func resolveLocalStorage(cmd *cobra.Command, args []string) {
 	localstorage.ResolveDirs()
 	setupHTTPLogger(getAPIClient())
+	tools.SetMaxOpenFiles(cfg.MaxOpenFiles())
 }
 
 func setupLocalStorage(cmd *cobra.Command, args []string) {
 	config.ResolveGitBasicDirs()
 	setupHTTPLogger(getAPIClient())
+	tools.SetMaxOpenFiles(cfg.MaxOpenFiles())
 }
 
 func helpCommand(cmd *cobra.Command, args []string) {
@@ -115,14 +119,14 @@ func setupHTTPLogger(c *lfsapi.Client) {
 
 	logBase := filepath.Join(config.LocalLogDir, "http")
 	if err := os.MkdirAll(logBase, 0755); err != nil {
-		fmt.Fprintf(os.Stderr, "Error logging http stats: %s\n", err)
+		logger.Errorf("Error logging http stats: %s", err)
 		return
 	}
 
 	logFile := fmt.Sprintf("http-%d.log", time.Now().Unix())
 	file, err := os.Create(filepath.Join(logBase, logFile))
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error logging http stats: %s\n", err)
+		logger.Errorf("Error logging http stats: %s", err)
 	} else {
 		c.LogHTTPStats(file)
 	}
@@ -0,0 +1,302 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/git-lfs/git-lfs/filepathfilter"
+	"github.com/git-lfs/git-lfs/logger"
+	"github.com/git-lfs/git-lfs/tools"
+)
+
+// smudgeRewrite is a config.SmudgeRewrite, with the filter its pattern is
+// matched by.
+type smudgeRewrite struct {
+	*config.SmudgeRewrite
+
+	filter *filepathfilter.Filter
+}
+
+// smudgeRewrites are the rules given by lfs.smudge.rewrite.<pattern>, which
+// place the contents of the files they match elsewhere in the working tree.
+type smudgeRewrites []*smudgeRewrite
+
+var (
+	smudgeRewritesOnce sync.Once
+	loadedRewrites     smudgeRewrites
+)
+
+// loadSmudgeRewrites returns the smudge rewrites given in the configuration,
+// which are only read once per process, however many files are smudged. An
+// invalid rule is fatal, since it would otherwise leave files where they were
+// not expected.
+func loadSmudgeRewrites() smudgeRewrites {
+	smudgeRewritesOnce.Do(func() {
+		configured, err := cfg.SmudgeRewrites()
+		if err != nil {
+			ExitWithError(err)
+		}
+
+		for _, r := range configured {
+			loadedRewrites = append(loadedRewrites, &smudgeRewrite{
+				SmudgeRewrite: r,
+				filter:        filepathfilter.New([]string{r.Pattern}, nil),
+			})
+		}
+	})
+	return loadedRewrites
+}
+
+// For returns the first rule whose pattern matches "filename", along with the
+// path it rewrites it to, or nil if none does. A rule which would rewrite the
+// file to somewhere it is not allowed to is warned about, and not used.
+func (rules smudgeRewrites) For(filename string) (*smudgeRewrite, string) {
+	if len(filename) == 0 {
+		return nil, ""
+	}
+
+	// Patterns are read in lower case, so match the file name in lower case,
+	// too.
+	name := strings.ToLower(filename)
+	for _, r := range rules {
+		if !r.filter.Allows(name) {
+			continue
+		}
+
+		target, err := r.Target(filename)
+		if err != nil {
+			logger.Warningf("Not rewriting %s: %s", filename, err)
+			return nil, ""
+		}
+		return r, target
+	}
+	return nil, ""
+}
+
+// Target returns the path, relative to the root of the working tree, which
+// the rule rewrites "filename" to. It must stay within the working tree,
+// outside of the ".git" directory, and be somewhere other than "filename".
+func (r *smudgeRewrite) Target(filename string) (string, error) {
+	name := filepath.ToSlash(filename)
+
+	target := r.Path
+	if strings.Contains(target, "%f") || strings.Contains(target, "%b") {
+		target = strings.Replace(target, "%f", name, -1)
+		target = strings.Replace(target, "%b", path.Base(name), -1)
+	} else {
+		target = path.Join(target, path.Base(name))
+	}
+
+	target = path.Clean(filepath.ToSlash(target))
+	switch {
+	case path.IsAbs(target) || filepath.IsAbs(filepath.FromSlash(target)):
+		return "", errors.Errorf("lfs.smudge.rewrite.%s.path gives %q, which is not relative to the working tree", r.Pattern, target)
+	case target == ".." || strings.HasPrefix(target, "../"):
+		return "", errors.Errorf("lfs.smudge.rewrite.%s.path gives %q, which is outside of the working tree", r.Pattern, target)
+	case strings.ToLower(strings.SplitN(target, "/", 2)[0]) == ".git":
+		return "", errors.Errorf("lfs.smudge.rewrite.%s.path gives %q, which is in the Git directory", r.Pattern, target)
+	case target == path.Clean(name):
+		return "", errors.Errorf("lfs.smudge.rewrite.%s.path gives the file's own path", r.Pattern)
+	}
+
+	target = filepath.FromSlash(target)
+	if err := r.CheckParent(target); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// CheckParent returns an error unless the directory which "target", relative to
+// the root of the working tree, is placed in stays within the working tree, and
+// outside of the Git directory, once any symbolic links to it are resolved. It
+// is checked again just before the target is written, in case a link has been
+// made since.
+func (r *smudgeRewrite) CheckParent(target string) error {
+	root, err := filepath.EvalSymlinks(config.LocalWorkingDir)
+	if err != nil {
+		return err
+	}
+
+	// Directories which do not exist yet are made when the target is
+	// written, so only those which already exist are resolved.
+	dir := filepath.Dir(filepath.Join(config.LocalWorkingDir, target))
+	var missing string
+	for {
+		resolved, err := filepath.EvalSymlinks(dir)
+		if err == nil {
+			dir = filepath.Join(resolved, missing)
+			break
+		}
+		if _, lerr := os.Lstat(dir); !os.IsNotExist(err) || lerr == nil {
+			return errors.Errorf("lfs.smudge.rewrite.%s.path gives %q, whose directory cannot be resolved: %s", r.Pattern, filepath.ToSlash(target), err)
+		}
+		missing = filepath.Join(filepath.Base(dir), missing)
+		dir = filepath.Dir(dir)
+	}
+
+	if !pathWithin(root, dir) {
+		return errors.Errorf("lfs.smudge.rewrite.%s.path gives %q, which is outside of the working tree once links are resolved", r.Pattern, filepath.ToSlash(target))
+	}
+
+	gitDir := filepath.Join(root, ".git")
+	if len(config.LocalGitDir) > 0 {
+		if resolved, err := filepath.EvalSymlinks(config.LocalGitDir); err == nil {
+			gitDir = resolved
+		}
+	}
+	if pathWithin(gitDir, dir) || pathWithin(filepath.Join(root, ".git"), dir) {
+		return errors.Errorf("lfs.smudge.rewrite.%s.path gives %q, which is in the Git directory once links are resolved", r.Pattern, filepath.ToSlash(target))
+	}
+	return nil
+}
+
+// pathWithin returns whether "p" is "dir", or inside of it. Both must be
+// absolute and clean.
+func pathWithin(dir, p string) bool {
+	rel, err := filepath.Rel(dir, p)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Link makes a symbolic link at "target" to the working tree file "filename",
+// both relative to the root of the working tree, replacing any link there
+// already. Anything other than a link at "target" is left alone, and returned
+// as an error, as is a "target" which CheckParent does not allow.
+func (r *smudgeRewrite) Link(filename, target string) error {
+	if err := r.CheckParent(target); err != nil {
+		return err
+	}
+
+	full := filepath.Join(config.LocalWorkingDir, target)
+	if fi, err := os.Lstat(full); err == nil {
+		if fi.Mode()&os.ModeSymlink == 0 {
+			return errors.Errorf("%s already exists, and is not a symbolic link", target)
+		}
+		if err := os.Remove(full); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		return err
+	}
+
+	rel, err := filepath.Rel(filepath.Dir(full), filepath.Join(config.LocalWorkingDir, filename))
+	if err != nil {
+		return err
+	}
+	return os.Symlink(rel, full)
+}
+
+// CheckMove returns an error if anything is at "target", relative to the root
+// of the working tree, other than a file which a rule moved "filename" to
+// before, so that move mode never replaces a file it did not write, such as
+// one tracked by Git, or another file rewritten to the same path.
+func (r *smudgeRewrite) CheckMove(filename, target string) error {
+	fi, err := os.Lstat(filepath.Join(config.LocalWorkingDir, target))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() {
+		return errors.Errorf("%s already exists, and is not a file", target)
+	}
+
+	moves, err := smudgeMoves()
+	if err != nil {
+		return err
+	}
+	if moves[filepath.ToSlash(target)] != filepath.ToSlash(filename) {
+		return errors.Errorf("%s already exists, and was not moved there from %s", target, filename)
+	}
+	return nil
+}
+
+// smudgeMovesPath returns the path of the file in the Git LFS storage directory
+// which records the files that move mode has written, and which files they
+// were moved from.
+func smudgeMovesPath() string {
+	return filepath.Join(cfg.StorageConfig().LfsStorageDir, "rewrites")
+}
+
+// smudgeMoves returns the targets recorded by recordSmudgeMove, with the file
+// each was last moved from.
+func smudgeMoves() (map[string]string, error) {
+	moves := make(map[string]string)
+
+	f, err := os.Open(smudgeMovesPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return moves, nil
+		}
+		return nil, errors.Wrap(err, "smudge rewrites")
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		target, filename, err := parseSmudgeMove(scanner.Text())
+		if err != nil {
+			return nil, errors.Wrapf(err, "smudge rewrites line %d", n)
+		}
+		moves[target] = filename
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "smudge rewrites")
+	}
+	return moves, nil
+}
+
+// parseSmudgeMove parses a line written by recordSmudgeMove, which gives the
+// target and the file moved there, each quoted, since either may contain
+// spaces, and separated by a tab, which quoting never leaves in either.
+func parseSmudgeMove(line string) (string, string, error) {
+	fields := strings.SplitN(line, "\t", 2)
+	if len(fields) != 2 {
+		return "", "", errors.Errorf("malformed entry: %q", line)
+	}
+
+	target, err := strconv.Unquote(fields[0])
+	if err != nil {
+		return "", "", errors.Errorf("malformed entry: %q", line)
+	}
+	filename, err := strconv.Unquote(fields[1])
+	if err != nil {
+		return "", "", errors.Errorf("malformed entry: %q", line)
+	}
+	return target, filename, nil
+}
+
+// recordSmudgeMove records that "filename" was moved to "target", both
+// relative to the root of the working tree, unless that is already the last
+// move recorded for "target".
+func recordSmudgeMove(filename, target string) error {
+	target = filepath.ToSlash(target)
+	filename = filepath.ToSlash(filename)
+
+	a := tools.NewLockedAppender(smudgeMovesPath())
+	return a.Locked(func(f *os.File) error {
+		moves, err := smudgeMoves()
+		if err != nil {
+			return err
+		}
+		if moves[target] == filename {
+			return nil
+		}
+
+		_, err = fmt.Fprintf(f, "%s\t%s\n", strconv.Quote(target), strconv.Quote(filename))
+		return err
+	})
+}
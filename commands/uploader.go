@@ -313,6 +313,7 @@ func (c *uploadContext) Await() {
 	for _, err := range others {
 		FullError(err)
 	}
+	printTransferErrorSummary(others)
 
 	if len(missing) > 0 || len(corrupt) > 0 {
 		var action string
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+// AllowedHosts is the list of hosts given by lfs.allowedhosts, which are the
+// only ones Git LFS contacts when it is set.
+type AllowedHosts struct {
+	patterns []string
+}
+
+// NewAllowedHosts returns the AllowedHosts given by "list", in which hosts are
+// separated by commas or whitespace. A host may be given with a port, to allow
+// only that port, and may start with "*." to allow any of its subdomains. If
+// "list" has no hosts, nil is returned, which allows any host.
+func NewAllowedHosts(list string) *AllowedHosts {
+	patterns := strings.FieldsFunc(strings.ToLower(list), func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	})
+	if len(patterns) == 0 {
+		return nil
+	}
+	return &AllowedHosts{patterns: patterns}
+}
+
+// Allows returns whether "host", which may include a port, is allowed. Any host
+// is allowed by a nil AllowedHosts.
+func (a *AllowedHosts) Allows(host string) bool {
+	if a == nil {
+		return true
+	}
+
+	host = strings.ToLower(host)
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	hostname = strings.Trim(hostname, "[]")
+
+	for _, pattern := range a.patterns {
+		name := hostname
+		if _, _, err := net.SplitHostPort(pattern); err == nil {
+			// The port has to match as well.
+			name = host
+		}
+
+		if strings.HasPrefix(pattern, "*.") {
+			if strings.HasSuffix(name, pattern[1:]) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsURL returns whether the host of "rawurl" is allowed. Both URLs and
+// scp-like SSH addresses, like "git@example.com:repo.git", are understood. A
+// URL which has no host, such as a local path, is always allowed.
+func (a *AllowedHosts) AllowsURL(rawurl string) bool {
+	host := URLHost(rawurl)
+	if a == nil || len(host) == 0 {
+		return true
+	}
+	return a.Allows(host)
+}
+
+// URLHost returns the host, with its port if it has one, of "rawurl", which may
+// be a URL or an scp-like SSH address. An empty string is returned if "rawurl"
+// has no host.
+func URLHost(rawurl string) string {
+	if strings.Contains(rawurl, "://") {
+		u, err := url.Parse(rawurl)
+		if err != nil {
+			return ""
+		}
+		return u.Host
+	}
+
+	// [user@]host:path
+	// A single letter before the colon is a Windows drive, not a host.
+	colon := strings.Index(rawurl, ":")
+	if colon < 2 || strings.ContainsAny(rawurl[:colon], "/\\") {
+		return ""
+	}
+	host := rawurl[:colon]
+	if at := strings.LastIndex(host, "@"); at >= 0 {
+		host = host[at+1:]
+	}
+	return host
+}
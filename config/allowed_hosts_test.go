@@ -0,0 +1,70 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowedHostsAllows(t *testing.T) {
+	a := NewAllowedHosts("git-server.com, *.example.com\tother.com:8443")
+
+	for host, allowed := range map[string]bool{
+		"git-server.com":      true,
+		"Git-Server.com":      true,
+		"git-server.com:8080": true,
+		"example.com":         false,
+		"lfs.example.com":     true,
+		"a.lfs.example.com":   true,
+		"badexample.com":      false,
+		"other.com":           false,
+		"other.com:8443":      true,
+		"other.com:443":       false,
+		"attacker.com":        false,
+	} {
+		assert.Equal(t, allowed, a.Allows(host), host)
+	}
+}
+
+func TestAllowedHostsEmptyAllowsAll(t *testing.T) {
+	a := NewAllowedHosts(" , ")
+	assert.Nil(t, a)
+	assert.True(t, a.Allows("attacker.com"))
+	assert.True(t, a.AllowsURL("https://attacker.com/repo"))
+}
+
+func TestAllowedHostsAllowsURL(t *testing.T) {
+	a := NewAllowedHosts("git-server.com")
+
+	for rawurl, allowed := range map[string]bool{
+		"https://git-server.com/repo.git/info/lfs": true,
+		"https://user@git-server.com:8443/repo":    true,
+		"ssh://git@git-server.com/repo":            true,
+		"git@git-server.com:repo.git":              true,
+		"https://attacker.com/repo.git/info/lfs":   false,
+		"git@attacker.com:repo.git":                false,
+		"/path/to/repo":                            true,
+		"file:///path/to/repo":                     true,
+		`C:\path\to\repo`:                          true,
+	} {
+		assert.Equal(t, allowed, a.AllowsURL(rawurl), rawurl)
+	}
+}
+
+func TestReadGitConfigIgnoresDisallowedURLsFromLfsConfigInStrictMode(t *testing.T) {
+	lfsconfig := NewGitConfig("lfs.url=https://attacker.com/repo\nremote.origin.lfsurl=https://git-server.com/repo\nlfs.allowedhosts=attacker.com", true)
+
+	gf, _, _ := ReadGitConfig(lfsconfig, NewGitConfig("lfs.allowedhosts=git-server.com", false))
+	url, ok := gf.Get("lfs.url")
+	assert.True(t, ok)
+	assert.Equal(t, "https://attacker.com/repo", url)
+
+	gf, _, _ = ReadGitConfig(lfsconfig, NewGitConfig("lfs.allowedhosts=git-server.com\nlfs.allowedhostsstrict=true", false))
+	_, ok = gf.Get("lfs.url")
+	assert.False(t, ok)
+	url, ok = gf.Get("remote.origin.lfsurl")
+	assert.True(t, ok)
+	assert.Equal(t, "https://git-server.com/repo", url)
+	hosts, _ := gf.Get("lfs.allowedhosts")
+	assert.Equal(t, "git-server.com", hosts)
+}
@@ -3,9 +3,12 @@
 package config
 
 import (
+	"encoding/hex"
 	"fmt"
 	"reflect"
 	"regexp"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -14,6 +17,7 @@ import (
 
 	"github.com/git-lfs/git-lfs/errors"
 	"github.com/git-lfs/git-lfs/tools"
+	"github.com/git-lfs/git-lfs/tools/humanize"
 )
 
 var (
@@ -42,11 +46,18 @@ type FetchPruneConfig struct {
 	PruneVerifyRemoteAlways bool `git:"lfs.pruneverifyremotealways"`
 	// Name of remote to check for unpushed and verify checks
 	PruneRemoteName string `git:"lfs.pruneremotetocheck"`
+	// Patterns of refs whose history is never pruned, however old, in
+	// order; a pattern starting with "!" excludes the refs an earlier one
+	// kept
+	PruneKeepRefs []string
 }
 
 // Storage configuration
 type StorageConfig struct {
 	LfsStorageDir string `git:"lfs.storage"`
+	// TempDir is where temporary files are written, if lfs.tempdir is
+	// set, rather than within LfsStorageDir.
+	TempDir string `git:"lfs.tempdir"`
 }
 
 type Configuration struct {
@@ -270,6 +281,13 @@ func (c *Configuration) FetchPruneConfig() FetchPruneConfig {
 	if err := c.Unmarshal(f); err != nil {
 		panic(err.Error())
 	}
+	for _, value := range c.Git.GetAll("lfs.prune.keeprefs") {
+		for _, pattern := range strings.Split(value, ",") {
+			if pattern = strings.TrimSpace(pattern); len(pattern) > 0 {
+				f.PruneKeepRefs = append(f.PruneKeepRefs, pattern)
+			}
+		}
+	}
 	return *f
 }
 
@@ -284,6 +302,9 @@ func (c *Configuration) StorageConfig() StorageConfig {
 	if !filepath.IsAbs(s.LfsStorageDir) {
 		s.LfsStorageDir = filepath.Join(LocalGitStorageDir, s.LfsStorageDir)
 	}
+	if len(s.TempDir) > 0 && !filepath.IsAbs(s.TempDir) {
+		s.TempDir = filepath.Join(LocalGitStorageDir, s.TempDir)
+	}
 	return *s
 }
 
@@ -291,6 +312,363 @@ func (c *Configuration) SkipDownloadErrors() bool {
 	return c.Os.Bool("GIT_LFS_SKIP_DOWNLOAD_ERRORS", false) || c.Git.Bool("lfs.skipdownloaderrors", false)
 }
 
+// IntegrityManifest returns whether Git LFS should record each object it writes
+// to the local object store in the integrity manifest, as checked by `git lfs
+// verify-manifest`. Default is false.
+func (c *Configuration) IntegrityManifest() bool {
+	return c.Git.Bool("lfs.integritymanifest", false)
+}
+
+// StoreConcurrency returns the number of workers which walk the local object
+// store, or hash the objects in it, at once, for commands like fsck and prune.
+// Default is the number of CPUs.
+func (c *Configuration) StoreConcurrency() int {
+	if v := c.Git.Int("lfs.storeconcurrency", 0); v > 0 {
+		return v
+	}
+	return runtime.NumCPU()
+}
+
+// MaxOpenFiles returns how many files Git LFS may hold open at once while it
+// smudges and downloads objects, or 0 if lfs.maxopenfiles is not set, or not
+// positive, in which case the limit is taken from `ulimit -n`.
+func (c *Configuration) MaxOpenFiles() int {
+	if n := c.Git.Int("lfs.maxopenfiles", 0); n > 0 {
+		return n
+	}
+	return 0
+}
+
+// ObjectOrigins returns whether the clean filter should record the path and
+// mode of each file it cleans beside the object it stores, as used by `git lfs
+// export --store`. It never affects the object's OID. Default is false.
+func (c *Configuration) ObjectOrigins() bool {
+	return c.Git.Bool("lfs.objectorigins", false)
+}
+
+// PointerChecksums returns whether the clean filter should record the Git blob
+// ID of each pointer it writes, as checked by `git lfs verify-pointers`. It is a
+// debugging aid, and defaults to false.
+func (c *Configuration) PointerChecksums() bool {
+	return c.Git.Bool("lfs.debug.pointerchecksums", false)
+}
+
+// ObjectAlternates returns the directories given by "lfs.alternates", each of
+// which is a read-only object store searched for objects before they are
+// downloaded.
+func (c *Configuration) ObjectAlternates() []string {
+	return c.Git.GetAll("lfs.alternates")
+}
+
+// RelocateLegacyObjects returns whether objects found in the object store of
+// the earliest versions of Git LFS, in ".git/media", should be moved into the
+// local object store when they are read, rather than read from where they are.
+// Default is false.
+func (c *Configuration) RelocateLegacyObjects() bool {
+	return c.Git.Bool("lfs.legacyobjects.relocate", false)
+}
+
+// SmudgeStats returns whether the filter process should print how many of the
+// objects it smudged were read from local storage, and how many had to be
+// downloaded, at the end of each session. Default is false.
+func (c *Configuration) SmudgeStats() bool {
+	return c.Os.Bool("GIT_LFS_SMUDGE_STATS", false) || c.Git.Bool("lfs.smudgestats", false)
+}
+
+// PostSmudgeCommands returns the commands given by
+// lfs.postsmudge.<pattern>.command, keyed by their pattern, which the filter
+// process runs once it has written files matching the pattern. Since config
+// keys are read in lower case, so are the patterns.
+func (c *Configuration) PostSmudgeCommands() map[string]string {
+	const prefix, suffix = "lfs.postsmudge.", ".command"
+
+	commands := make(map[string]string)
+	for key, values := range c.Git.All() {
+		if len(values) == 0 || len(key) <= len(prefix)+len(suffix) ||
+			!strings.HasPrefix(key, prefix) || !strings.HasSuffix(key, suffix) {
+			continue
+		}
+
+		pattern := key[len(prefix) : len(key)-len(suffix)]
+		commands[pattern] = values[len(values)-1]
+	}
+	return commands
+}
+
+// SmudgePlaceholders returns whether the smudge filter should write a
+// zero-filled placeholder of the object's size, rather than the pointer, when
+// an object cannot be downloaded or its download is skipped. Default is false.
+func (c *Configuration) SmudgePlaceholders() bool {
+	return c.Os.Bool("GIT_LFS_SMUDGE_PLACEHOLDERS", false) || c.Git.Bool("lfs.smudgeplaceholders", false)
+}
+
+// PointerTrailingNewline returns whether pointers written by the clean filter
+// should end with a trailing newline, as they do in the canonical form given
+// by the specification. Default is true, including if
+// lfs.pointer.trailingnewline is invalid.
+func (c *Configuration) PointerTrailingNewline() bool {
+	return c.Git.Bool("lfs.pointer.trailingnewline", true)
+}
+
+// DefaultPointerMaxSize is the default largest size, in bytes, of a pointer.
+// It leaves plenty of room for a pointer with the maximum number of extensions.
+const DefaultPointerMaxSize = 4096
+
+// PointerMaxSize returns the largest size, in bytes, that a pointer may have.
+// Anything larger is treated as not a pointer, without reading any further.
+// Default is DefaultPointerMaxSize, including if lfs.pointer.maxsize is invalid
+// or not positive.
+func (c *Configuration) PointerMaxSize() int {
+	if n := c.Git.Int("lfs.pointer.maxsize", DefaultPointerMaxSize); n > 0 {
+		return n
+	}
+	return DefaultPointerMaxSize
+}
+
+// PointerVersion returns the version line which lfs.pointer.version gives the
+// clean filter to write pointers with, instead of the one in the specification,
+// such as to test how a future version is handled, or an empty string if it is
+// not set. Since pointers written with it can only be read by clients which
+// accept it, it is an error to set it without
+// lfs.pointer.allowexperimentalversions, or to anything which cannot be a
+// version line.
+func (c *Configuration) PointerVersion() (string, error) {
+	v, ok := c.Git.Get("lfs.pointer.version")
+	if !ok || len(v) == 0 {
+		return "", nil
+	}
+	if !c.Git.Bool("lfs.pointer.allowexperimentalversions", false) {
+		return "", errors.Errorf("lfs.pointer.version is set to %q, but lfs.pointer.allowexperimentalversions is not; see git-lfs-config(5)", v)
+	}
+	if strings.ContainsAny(v, " \t\r\n") {
+		return "", errors.Errorf("invalid lfs.pointer.version %q: versions cannot contain whitespace", v)
+	}
+	return v, nil
+}
+
+// PointerStrictOids returns whether pointers whose OIDs are not written in
+// lower case, as Git LFS writes them, are rejected, as given by
+// lfs.pointer.strictoids, rather than read with their OIDs in lower case,
+// which they are by default.
+func (c *Configuration) PointerStrictOids() bool {
+	return c.Git.Bool("lfs.pointer.strictoids", false)
+}
+
+// PointerAcceptedVersions returns the versions, besides those in the
+// specification, of the pointers which are decoded, rather than rejected,
+// as given by lfs.pointer.acceptedversions, separated by commas, and by
+// lfs.pointer.version. They are only accepted if
+// lfs.pointer.allowexperimentalversions is true, so by default there are none.
+func (c *Configuration) PointerAcceptedVersions() []string {
+	if !c.Git.Bool("lfs.pointer.allowexperimentalversions", false) {
+		return nil
+	}
+
+	var versions []string
+	if v, ok := c.Git.Get("lfs.pointer.acceptedversions"); ok {
+		for _, version := range strings.Split(v, ",") {
+			if version = strings.TrimSpace(version); len(version) > 0 {
+				versions = append(versions, version)
+			}
+		}
+	}
+	if v, err := c.PointerVersion(); err == nil && len(v) > 0 {
+		versions = append(versions, v)
+	}
+	return versions
+}
+
+// CleanRejectsPointers returns whether the clean filter should fail when
+// its input is already a pointer, rather than passing it through unchanged,
+// which it does by default.
+func (c *Configuration) CleanRejectsPointers() bool {
+	return c.Git.Bool("lfs.clean.rejectpointers", false)
+}
+
+// CleanPassesPointers returns whether the clean filter should write out input
+// which is already a pointer exactly as it was read, which it does by default,
+// rather than checking it against the local copy of its object and encoding it
+// again.
+func (c *Configuration) CleanPassesPointers() bool {
+	return c.Git.Bool("lfs.clean.passpointers", true)
+}
+
+// MinFreeSpace returns the margin given by lfs.minfreespace, in bytes, and
+// whether it is set at all. When it is, fetch, pull and checkout refuse to
+// start unless there is room for the objects they would write, plus that
+// margin. The smudge filter checks each object it downloads whether or not it
+// is set. An invalid value is returned as an error.
+func (c *Configuration) MinFreeSpace() (uint64, bool, error) {
+	v, ok := c.Git.Get("lfs.minfreespace")
+	if !ok {
+		return 0, false, nil
+	}
+
+	n, err := humanize.ParseBytes(v)
+	if err != nil {
+		return 0, true, errors.Wrapf(err, "invalid lfs.minfreespace %q", v)
+	}
+	return n, true, nil
+}
+
+// CleanRule is the rule given by lfs.cleanrule.<pattern>.minsize and
+// lfs.cleanrule.<pattern>.magic, which decides, by their contents, whether files
+// matching Pattern, which are tracked by Git LFS, are cleaned into pointers, or
+// are passed through to Git unchanged.
+type CleanRule struct {
+	// Pattern is matched as in lfs.fetchinclude, but without regard to case,
+	// since config keys are read in lower case.
+	Pattern string
+	// MinSize is the smallest size, in bytes, of a file which is cleaned.
+	MinSize uint64
+	// Magic are the signatures, any of which a file must start with to be
+	// cleaned. If there are none, a file may start with anything.
+	Magic [][]byte
+}
+
+// CleanRules returns the rules given by lfs.cleanrule.<pattern>.minsize and
+// lfs.cleanrule.<pattern>.magic, sorted by their patterns. A size is given as in
+// lfs.minfreespace, and the magic as a comma separated list of signatures, each
+// in hexadecimal. An invalid value is returned as an error.
+func (c *Configuration) CleanRules() ([]*CleanRule, error) {
+	const prefix = "lfs.cleanrule."
+
+	rules := make(map[string]*CleanRule)
+	rule := func(pattern string) *CleanRule {
+		r, ok := rules[pattern]
+		if !ok {
+			r = &CleanRule{Pattern: pattern}
+			rules[pattern] = r
+		}
+		return r
+	}
+
+	for key, values := range c.Git.All() {
+		if len(values) == 0 || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		v := values[len(values)-1]
+		switch {
+		case strings.HasSuffix(key, ".minsize") && len(key) > len(prefix)+len(".minsize"):
+			n, err := humanize.ParseBytes(v)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid %s %q", key, v)
+			}
+			rule(key[len(prefix) : len(key)-len(".minsize")]).MinSize = n
+		case strings.HasSuffix(key, ".magic") && len(key) > len(prefix)+len(".magic"):
+			r := rule(key[len(prefix) : len(key)-len(".magic")])
+			for _, sig := range strings.Split(v, ",") {
+				by, err := hex.DecodeString(strings.TrimSpace(sig))
+				if err != nil || len(by) == 0 {
+					return nil, errors.Errorf("invalid %s %q: signatures must be given in hexadecimal", key, v)
+				}
+				r.Magic = append(r.Magic, by)
+			}
+		}
+	}
+
+	sorted := make([]*CleanRule, 0, len(rules))
+	for _, r := range rules {
+		sorted = append(sorted, r)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Pattern < sorted[j].Pattern
+	})
+	return sorted, nil
+}
+
+// SmudgeNonPointer returns what the smudge filter does with the contents of a
+// file which are not a pointer, as lfs.smudge.nonpointer gives: "warn", the
+// default, which passes them through unchanged with a warning, "ignore", which
+// does so silently, or "error", which fails to smudge the file. Any other
+// value is taken to be "warn".
+func (c *Configuration) SmudgeNonPointer() string {
+	v, _ := c.Git.Get("lfs.smudge.nonpointer")
+	switch v = strings.ToLower(v); v {
+	case "ignore", "error":
+		return v
+	}
+	return "warn"
+}
+
+// SmudgeRewrite is the rule given by lfs.smudge.rewrite.<pattern>.path and
+// lfs.smudge.rewrite.<pattern>.mode, which places the contents of files
+// matching Pattern somewhere else in the working tree when they are smudged.
+type SmudgeRewrite struct {
+	// Pattern is the pattern files are matched by. It is in lower case,
+	// since config keys are read in lower case.
+	Pattern string
+	// Path is where the contents are placed, relative to the root of the
+	// working tree, in which "%f" is replaced by the path of the file, and
+	// "%b" by its base name. If it has neither, it is the directory the
+	// file is placed in, under its base name.
+	Path string
+	// Mode is how the contents are placed at Path: "link", to have Git
+	// write them to the file, and make a symbolic link to it at Path, or
+	// "move", to write them to Path, and leave the pointer in the file.
+	Mode string
+}
+
+// SmudgeRewrites returns the rules given by lfs.smudge.rewrite.<pattern>.path
+// and lfs.smudge.rewrite.<pattern>.mode, sorted by their patterns, or none,
+// unless lfs.smudge.allowrewrite is set, since they write outside of the files
+// Git asks for. A rule without a path is ignored, and one with a mode other
+// than "link", the default, or "move" is returned as an error.
+func (c *Configuration) SmudgeRewrites() ([]*SmudgeRewrite, error) {
+	const prefix = "lfs.smudge.rewrite."
+
+	if !c.Git.Bool("lfs.smudge.allowrewrite", false) {
+		return nil, nil
+	}
+
+	rules := make(map[string]*SmudgeRewrite)
+	rule := func(pattern string) *SmudgeRewrite {
+		r, ok := rules[pattern]
+		if !ok {
+			r = &SmudgeRewrite{Pattern: pattern, Mode: "link"}
+			rules[pattern] = r
+		}
+		return r
+	}
+
+	for key, values := range c.Git.All() {
+		if len(values) == 0 || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		v := values[len(values)-1]
+		switch {
+		case strings.HasSuffix(key, ".path") && len(key) > len(prefix)+len(".path"):
+			rule(key[len(prefix) : len(key)-len(".path")]).Path = v
+		case strings.HasSuffix(key, ".mode") && len(key) > len(prefix)+len(".mode"):
+			switch mode := strings.ToLower(v); mode {
+			case "link", "move":
+				rule(key[len(prefix) : len(key)-len(".mode")]).Mode = mode
+			default:
+				return nil, errors.Errorf("invalid %s %q: must be \"link\" or \"move\"", key, v)
+			}
+		}
+	}
+
+	sorted := make([]*SmudgeRewrite, 0, len(rules))
+	for _, r := range rules {
+		if len(r.Path) > 0 {
+			sorted = append(sorted, r)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Pattern < sorted[j].Pattern
+	})
+	return sorted, nil
+}
+
+// Offline returns whether Git LFS should operate only on objects that are
+// already present locally, without contacting the network.
+func (c *Configuration) Offline() bool {
+	return c.Os.Bool("GIT_LFS_OFFLINE", false) || c.Git.Bool("lfs.offline", false)
+}
+
 func (c *Configuration) SetLockableFilesReadOnly() bool {
 	return c.Os.Bool("GIT_LFS_SET_LOCKABLE_READONLY", true) && c.Git.Bool("lfs.setlockablereadonly", true)
 }
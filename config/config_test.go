@@ -36,6 +36,377 @@ func TestBasicTransfersOnlyInvalidValue(t *testing.T) {
 	assert.Equal(t, false, b)
 }
 
+func TestPointerTrailingNewlineSetValue(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string][]string{
+			"lfs.pointer.trailingnewline": []string{"false"},
+		},
+	})
+
+	assert.False(t, cfg.PointerTrailingNewline())
+}
+
+func TestPointerTrailingNewlineDefault(t *testing.T) {
+	cfg := NewFrom(Values{})
+
+	assert.True(t, cfg.PointerTrailingNewline())
+}
+
+func TestPointerMaxSizeSetValue(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string][]string{
+			"lfs.pointer.maxsize": []string{"2048"},
+		},
+	})
+
+	assert.Equal(t, 2048, cfg.PointerMaxSize())
+}
+
+func TestPointerMaxSizeDefault(t *testing.T) {
+	cfg := NewFrom(Values{})
+	assert.Equal(t, DefaultPointerMaxSize, cfg.PointerMaxSize())
+
+	cfg = NewFrom(Values{
+		Git: map[string][]string{
+			"lfs.pointer.maxsize": []string{"-1"},
+		},
+	})
+	assert.Equal(t, DefaultPointerMaxSize, cfg.PointerMaxSize())
+}
+
+func TestPointerVersionDefault(t *testing.T) {
+	cfg := NewFrom(Values{})
+
+	v, err := cfg.PointerVersion()
+	assert.Nil(t, err)
+	assert.Empty(t, v)
+	assert.Empty(t, cfg.PointerAcceptedVersions())
+}
+
+func TestPointerVersionSetValue(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string][]string{
+			"lfs.pointer.version":                   []string{"https://git-lfs.github.com/spec/v2"},
+			"lfs.pointer.acceptedversions":          []string{"https://git-lfs.github.com/spec/v3, https://example.com/v1"},
+			"lfs.pointer.allowexperimentalversions": []string{"true"},
+		},
+	})
+
+	v, err := cfg.PointerVersion()
+	assert.Nil(t, err)
+	assert.Equal(t, "https://git-lfs.github.com/spec/v2", v)
+	assert.Equal(t, []string{
+		"https://git-lfs.github.com/spec/v3",
+		"https://example.com/v1",
+		"https://git-lfs.github.com/spec/v2",
+	}, cfg.PointerAcceptedVersions())
+}
+
+func TestPointerVersionRequiresOptIn(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string][]string{
+			"lfs.pointer.version":          []string{"https://git-lfs.github.com/spec/v2"},
+			"lfs.pointer.acceptedversions": []string{"https://git-lfs.github.com/spec/v3"},
+		},
+	})
+
+	_, err := cfg.PointerVersion()
+	if assert.NotNil(t, err) {
+		assert.Equal(t, `lfs.pointer.version is set to "https://git-lfs.github.com/spec/v2", but lfs.pointer.allowexperimentalversions is not; see git-lfs-config(5)`, err.Error())
+	}
+	assert.Empty(t, cfg.PointerAcceptedVersions())
+}
+
+func TestPointerVersionInvalid(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string][]string{
+			"lfs.pointer.version":                   []string{"spec v2"},
+			"lfs.pointer.allowexperimentalversions": []string{"true"},
+		},
+	})
+
+	_, err := cfg.PointerVersion()
+	assert.NotNil(t, err)
+	assert.Empty(t, cfg.PointerAcceptedVersions())
+}
+
+func TestPointerStrictOidsDefault(t *testing.T) {
+	cfg := NewFrom(Values{})
+
+	assert.False(t, cfg.PointerStrictOids())
+}
+
+func TestPointerStrictOidsSetValue(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string][]string{
+			"lfs.pointer.strictoids": []string{"true"},
+		},
+	})
+
+	assert.True(t, cfg.PointerStrictOids())
+}
+
+func TestCleanRejectsPointersDefault(t *testing.T) {
+	cfg := NewFrom(Values{})
+
+	assert.False(t, cfg.CleanRejectsPointers())
+}
+
+func TestCleanRejectsPointersSetValue(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string][]string{
+			"lfs.clean.rejectpointers": []string{"true"},
+		},
+	})
+
+	assert.True(t, cfg.CleanRejectsPointers())
+}
+
+func TestCleanPassesPointersDefault(t *testing.T) {
+	cfg := NewFrom(Values{})
+
+	assert.True(t, cfg.CleanPassesPointers())
+}
+
+func TestCleanPassesPointersSetValue(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string][]string{
+			"lfs.clean.passpointers": []string{"false"},
+		},
+	})
+
+	assert.False(t, cfg.CleanPassesPointers())
+}
+
+func TestMinFreeSpaceDefault(t *testing.T) {
+	cfg := NewFrom(Values{})
+
+	_, ok, err := cfg.MinFreeSpace()
+	assert.False(t, ok)
+	assert.Nil(t, err)
+}
+
+func TestMinFreeSpaceSetValue(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string][]string{
+			"lfs.minfreespace": []string{"1kb"},
+		},
+	})
+
+	margin, ok, err := cfg.MinFreeSpace()
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1000, margin)
+}
+
+func TestMinFreeSpaceInvalidValue(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string][]string{
+			"lfs.minfreespace": []string{"lots"},
+		},
+	})
+
+	_, ok, err := cfg.MinFreeSpace()
+	assert.True(t, ok)
+	assert.NotNil(t, err)
+}
+
+func TestSmudgeNonPointerDefault(t *testing.T) {
+	cfg := NewFrom(Values{})
+
+	assert.Equal(t, "warn", cfg.SmudgeNonPointer())
+}
+
+func TestSmudgeNonPointerSetValue(t *testing.T) {
+	for value, expected := range map[string]string{
+		"warn":    "warn",
+		"ignore":  "ignore",
+		"Error":   "error",
+		"invalid": "warn",
+	} {
+		cfg := NewFrom(Values{
+			Git: map[string][]string{
+				"lfs.smudge.nonpointer": []string{value},
+			},
+		})
+
+		assert.Equal(t, expected, cfg.SmudgeNonPointer(), value)
+	}
+}
+
+func TestOfflineDefault(t *testing.T) {
+	cfg := NewFrom(Values{})
+
+	assert.False(t, cfg.Offline())
+}
+
+func TestOfflineFromGit(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string][]string{
+			"lfs.offline": []string{"true"},
+		},
+	})
+
+	assert.True(t, cfg.Offline())
+}
+
+func TestOfflineFromOs(t *testing.T) {
+	cfg := NewFrom(Values{
+		Os: map[string][]string{
+			"GIT_LFS_OFFLINE": []string{"1"},
+		},
+	})
+
+	assert.True(t, cfg.Offline())
+}
+
+func TestSmudgePlaceholdersDefault(t *testing.T) {
+	cfg := NewFrom(Values{})
+
+	assert.False(t, cfg.SmudgePlaceholders())
+}
+
+func TestSmudgePlaceholdersFromGit(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string][]string{
+			"lfs.smudgeplaceholders": []string{"true"},
+		},
+	})
+
+	assert.True(t, cfg.SmudgePlaceholders())
+}
+
+func TestSmudgePlaceholdersFromOs(t *testing.T) {
+	cfg := NewFrom(Values{
+		Os: map[string][]string{
+			"GIT_LFS_SMUDGE_PLACEHOLDERS": []string{"1"},
+		},
+	})
+
+	assert.True(t, cfg.SmudgePlaceholders())
+}
+
+func TestSmudgeStatsDefault(t *testing.T) {
+	cfg := NewFrom(Values{})
+
+	assert.False(t, cfg.SmudgeStats())
+}
+
+func TestSmudgeStatsFromGit(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string][]string{
+			"lfs.smudgestats": []string{"true"},
+		},
+	})
+
+	assert.True(t, cfg.SmudgeStats())
+}
+
+func TestSmudgeStatsFromOs(t *testing.T) {
+	cfg := NewFrom(Values{
+		Os: map[string][]string{
+			"GIT_LFS_SMUDGE_STATS": []string{"1"},
+		},
+	})
+
+	assert.True(t, cfg.SmudgeStats())
+}
+
+func TestPostSmudgeCommands(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string][]string{
+			"lfs.postsmudge.*.mp4.command":       []string{"index", "index-mp4"},
+			"lfs.postsmudge.media/**.command":    []string{"thumbnail %f"},
+			"lfs.postsmudge.command":             []string{"ignored"},
+			"lfs.postsmudge.*.wav.somethingelse": []string{"ignored"},
+		},
+	})
+
+	assert.Equal(t, map[string]string{
+		"*.mp4":    "index-mp4",
+		"media/**": "thumbnail %f",
+	}, cfg.PostSmudgeCommands())
+}
+
+func TestCleanRules(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string][]string{
+			"lfs.cleanrule.*.png.minsize":   []string{"1KB", "10KB"},
+			"lfs.cleanrule.*.png.magic":     []string{"89504e47"},
+			"lfs.cleanrule.*.bin.magic":     []string{"7f454c46, 4d5a"},
+			"lfs.cleanrule.minsize":         []string{"ignored"},
+			"lfs.cleanrule.*.dat.something": []string{"ignored"},
+		},
+	})
+
+	rules, err := cfg.CleanRules()
+	assert.Nil(t, err)
+	assert.Equal(t, []*CleanRule{
+		{Pattern: "*.bin", Magic: [][]byte{{0x7f, 'E', 'L', 'F'}, {'M', 'Z'}}},
+		{Pattern: "*.png", MinSize: 10000, Magic: [][]byte{{0x89, 'P', 'N', 'G'}}},
+	}, rules)
+}
+
+func TestCleanRulesDefault(t *testing.T) {
+	rules, err := NewFrom(Values{}).CleanRules()
+	assert.Nil(t, err)
+	assert.Empty(t, rules)
+}
+
+func TestCleanRulesInvalid(t *testing.T) {
+	_, err := NewFrom(Values{Git: map[string][]string{
+		"lfs.cleanrule.*.png.magic": []string{"PNG"},
+	}}).CleanRules()
+	if assert.NotNil(t, err) {
+		assert.Equal(t, `invalid lfs.cleanrule.*.png.magic "PNG": signatures must be given in hexadecimal`, err.Error())
+	}
+
+	_, err = NewFrom(Values{Git: map[string][]string{
+		"lfs.cleanrule.*.png.minsize": []string{"big"},
+	}}).CleanRules()
+	assert.NotNil(t, err)
+}
+
+func TestSmudgeRewrites(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string][]string{
+			"lfs.smudge.allowrewrite":           []string{"true"},
+			"lfs.smudge.rewrite.*.png.path":     []string{"build/images"},
+			"lfs.smudge.rewrite.*.psd.path":     []string{"build/%f"},
+			"lfs.smudge.rewrite.*.psd.mode":     []string{"Move"},
+			"lfs.smudge.rewrite.*.dat.mode":     []string{"move"},
+			"lfs.smudge.rewrite.path":           []string{"ignored"},
+			"lfs.smudge.rewrite.*.bin.whatever": []string{"ignored"},
+		},
+	})
+
+	rules, err := cfg.SmudgeRewrites()
+	assert.Nil(t, err)
+	assert.Equal(t, []*SmudgeRewrite{
+		{Pattern: "*.png", Path: "build/images", Mode: "link"},
+		{Pattern: "*.psd", Path: "build/%f", Mode: "move"},
+	}, rules)
+}
+
+func TestSmudgeRewritesNotAllowed(t *testing.T) {
+	rules, err := NewFrom(Values{Git: map[string][]string{
+		"lfs.smudge.rewrite.*.png.path": []string{"build/images"},
+	}}).SmudgeRewrites()
+	assert.Nil(t, err)
+	assert.Empty(t, rules)
+}
+
+func TestSmudgeRewritesInvalid(t *testing.T) {
+	_, err := NewFrom(Values{Git: map[string][]string{
+		"lfs.smudge.allowrewrite":       []string{"true"},
+		"lfs.smudge.rewrite.*.png.path": []string{"build/images"},
+		"lfs.smudge.rewrite.*.png.mode": []string{"copy"},
+	}}).SmudgeRewrites()
+	if assert.NotNil(t, err) {
+		assert.Equal(t, `invalid lfs.smudge.rewrite.*.png.mode "copy": must be "link" or "move"`, err.Error())
+	}
+}
+
 func TestTusTransfersAllowedSetValue(t *testing.T) {
 	cfg := NewFrom(Values{
 		Git: map[string][]string{
@@ -108,7 +479,7 @@ func TestFetchPruneConfigDefault(t *testing.T) {
 	assert.Equal(t, 3, fp.PruneOffsetDays)
 	assert.Equal(t, "origin", fp.PruneRemoteName)
 	assert.False(t, fp.PruneVerifyRemoteAlways)
-
+	assert.Empty(t, fp.PruneKeepRefs)
 }
 func TestFetchPruneConfigCustom(t *testing.T) {
 	cfg := NewFrom(Values{
@@ -131,6 +502,17 @@ func TestFetchPruneConfigCustom(t *testing.T) {
 	assert.True(t, fp.PruneVerifyRemoteAlways)
 }
 
+func TestFetchPruneConfigKeepRefs(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string][]string{
+			"lfs.prune.keeprefs": []string{"release/*, refs/tags", "!release/old"},
+		},
+	})
+	fp := cfg.FetchPruneConfig()
+
+	assert.Equal(t, []string{"release/*", "refs/tags", "!release/old"}, fp.PruneKeepRefs)
+}
+
 func TestFetchIncludeExcludesAreCleaned(t *testing.T) {
 	cfg := NewFrom(Values{
 		Git: map[string][]string{
@@ -296,3 +678,67 @@ func TestUnmarshalErrsOnUnsupportedTypes(t *testing.T) {
 
 	assert.Equal(t, "lfs/config: unsupported target type for field \"Unsupported\": time.Duration", err.Error())
 }
+
+func TestObjectAlternates(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string][]string{
+			"lfs.alternates": []string{"/srv/lfs-cache", "/mnt/lfs"},
+		},
+	})
+
+	assert.Equal(t, []string{"/srv/lfs-cache", "/mnt/lfs"}, cfg.ObjectAlternates())
+	assert.Empty(t, NewFrom(Values{}).ObjectAlternates())
+}
+
+func TestRelocateLegacyObjectsDefault(t *testing.T) {
+	cfg := NewFrom(Values{})
+
+	assert.False(t, cfg.RelocateLegacyObjects())
+}
+
+func TestRelocateLegacyObjectsSetValue(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string][]string{
+			"lfs.legacyobjects.relocate": []string{"true"},
+		},
+	})
+
+	assert.True(t, cfg.RelocateLegacyObjects())
+}
+
+func TestMaxOpenFilesDefault(t *testing.T) {
+	assert.Equal(t, 0, NewFrom(Values{}).MaxOpenFiles())
+
+	cfg := NewFrom(Values{
+		Git: map[string][]string{
+			"lfs.maxopenfiles": []string{"-1"},
+		},
+	})
+	assert.Equal(t, 0, cfg.MaxOpenFiles())
+}
+
+func TestMaxOpenFilesSetValue(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string][]string{
+			"lfs.maxopenfiles": []string{"64"},
+		},
+	})
+
+	assert.Equal(t, 64, cfg.MaxOpenFiles())
+}
+
+func TestIntegrityManifestDefault(t *testing.T) {
+	cfg := NewFrom(Values{})
+
+	assert.False(t, cfg.IntegrityManifest())
+}
+
+func TestIntegrityManifestFromGit(t *testing.T) {
+	cfg := NewFrom(Values{
+		Git: map[string][]string{
+			"lfs.integritymanifest": []string{"true"},
+		},
+	})
+
+	assert.True(t, cfg.IntegrityManifest())
+}
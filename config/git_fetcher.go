@@ -34,6 +34,8 @@ func ReadGitConfig(configs ...*GitConfig) (gf *GitFetcher, extensions map[string
 	extensions = make(map[string]Extension)
 	uniqRemotes = make(map[string]bool)
 
+	allowedHosts, strict := readAllowedHosts(configs)
+
 	for _, gc := range configs {
 		uniqKeys := make(map[string]string)
 
@@ -95,12 +97,21 @@ func ReadGitConfig(configs ...*GitConfig) (gf *GitFetcher, extensions map[string
 				uniqRemotes[remote] = remote == "origin"
 			} else if len(parts) > 2 && parts[len(parts)-1] == "access" {
 				allowed = true
+			} else if strings.HasPrefix(key, "lfs.cleanrule.") {
+				// Clean rules decide what is committed,
+				// so every clone needs the same ones.
+				allowed = true
 			}
 
 			if !allowed && keyIsUnsafe(key) {
 				continue
 			}
 
+			if gc.OnlySafeKeys && strict && keyIsURL(parts) && !allowedHosts.AllowsURL(val) {
+				fmt.Fprintf(os.Stderr, "warning: ignoring %s = %q from .lfsconfig, since its host is not in lfs.allowedhosts\n", pieces[0], val)
+				continue
+			}
+
 			vals[key] = append(vals[key], val)
 		}
 	}
@@ -182,6 +193,47 @@ func getFileGitConfig(basename string) *GitConfig {
 	return nil
 }
 
+// readAllowedHosts returns the hosts given by lfs.allowedhosts, and whether
+// lfs.allowedhostsstrict is set, in "configs". Neither is read from configs
+// which only have safe keys, so that a repository cannot allow hosts for itself.
+func readAllowedHosts(configs []*GitConfig) (*AllowedHosts, bool) {
+	vals := make(map[string][]string)
+	for _, gc := range configs {
+		if gc.OnlySafeKeys {
+			continue
+		}
+
+		for _, line := range gc.Lines {
+			pieces := strings.SplitN(line, "=", 2)
+			if len(pieces) < 2 {
+				continue
+			}
+
+			switch key := strings.ToLower(pieces[0]); key {
+			case "lfs.allowedhosts", "lfs.allowedhostsstrict":
+				vals[key] = append(vals[key], pieces[1])
+			}
+		}
+	}
+
+	env := EnvironmentOf(MapFetcher(vals))
+	hosts, _ := env.Get("lfs.allowedhosts")
+	allowedHosts := NewAllowedHosts(hosts)
+	return allowedHosts, allowedHosts != nil && env.Bool("lfs.allowedhostsstrict", false)
+}
+
+// keyIsURL returns whether the key split into "parts" gives the URL of a Git
+// LFS endpoint.
+func keyIsURL(parts []string) bool {
+	switch {
+	case len(parts) == 2 && parts[0] == "lfs":
+		return parts[1] == "url" || parts[1] == "pushurl"
+	case len(parts) == 3 && parts[0] == "remote":
+		return parts[2] == "lfsurl" || parts[2] == "lfspushurl"
+	}
+	return false
+}
+
 func keyIsUnsafe(key string) bool {
 	for _, safe := range safeKeys {
 		if safe == key {
@@ -0,0 +1,52 @@
+package errors
+
+// Category is why a transfer failed, in the broad terms a script may want to
+// act on, such as asking for new credentials after an "auth" failure, or trying
+// again later after a "network" or "server" one.
+type Category string
+
+const (
+	// CategoryAuth is for credentials which were missing or not allowed
+	// to do what was asked (HTTP 401 or 403).
+	CategoryAuth Category = "auth"
+	// CategoryNetwork is for requests which could not be made, or whose
+	// responses could not be read.
+	CategoryNetwork Category = "network"
+	// CategoryVerification is for objects which were transferred, but
+	// not correctly.
+	CategoryVerification Category = "verification"
+	// CategoryServer is for HTTP 5xx responses.
+	CategoryServer Category = "server"
+	// CategoryDisk is for local files which could not be read or written.
+	CategoryDisk Category = "disk"
+	// CategoryOther is for any other error.
+	CategoryOther Category = "other"
+)
+
+// CategoryOf returns the Category of the error "err", according to the
+// IsXError() functions, or CategoryOther if none of them match. An error which
+// matches several is given the most specific: verification, then auth, server,
+// network and disk.
+func CategoryOf(err error) Category {
+	switch {
+	case IsVerificationError(err):
+		return CategoryVerification
+	case IsAuthError(err):
+		return CategoryAuth
+	}
+
+	if status := statusOf(err); status == 401 || status == 403 {
+		return CategoryAuth
+	}
+
+	switch {
+	case IsServerError(err):
+		return CategoryServer
+	case IsNetworkError(err):
+		return CategoryNetwork
+	case IsDiskError(err):
+		return CategoryDisk
+	default:
+		return CategoryOther
+	}
+}
@@ -0,0 +1,87 @@
+package errors_test
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type statusError struct {
+	status int
+}
+
+func (e statusError) Error() string {
+	return http.StatusText(e.status)
+}
+
+func (e statusError) HTTPResponse() *http.Response {
+	return &http.Response{StatusCode: e.status}
+}
+
+func TestCategoryOfAuthErrors(t *testing.T) {
+	assert.Equal(t, errors.CategoryAuth, errors.CategoryOf(errors.NewAuthError(errors.New("401"))))
+	assert.Equal(t, errors.CategoryAuth, errors.CategoryOf(errors.Wrap(statusError{403}, "upload")))
+}
+
+func TestCategoryOfServerErrors(t *testing.T) {
+	err := errors.NewFatalError(statusError{503})
+
+	assert.True(t, errors.IsServerError(err))
+	assert.False(t, errors.IsNetworkError(err))
+	assert.Equal(t, errors.CategoryServer, errors.CategoryOf(err))
+	assert.Equal(t, errors.CategoryOther, errors.CategoryOf(statusError{404}))
+}
+
+func TestCategoryOfNetworkErrors(t *testing.T) {
+	err := errors.NewRetriableError(&url.Error{Op: "Get", URL: "https://example.com", Err: TimeoutError{}})
+
+	assert.True(t, errors.IsNetworkError(err))
+	assert.Equal(t, errors.CategoryNetwork, errors.CategoryOf(err))
+}
+
+func TestCategoryOfDiskErrors(t *testing.T) {
+	err := errors.Wrap(&os.PathError{Op: "write", Path: "a.dat", Err: errors.New("no space left on device")}, "cannot write data")
+
+	assert.True(t, errors.IsDiskError(err))
+	assert.Equal(t, errors.CategoryDisk, errors.CategoryOf(err))
+}
+
+func TestCategoryOfVerificationErrors(t *testing.T) {
+	err := errors.NewVerificationError(errors.Errorf("Expected OID abc, got def"))
+
+	assert.Equal(t, "Expected OID abc, got def", err.Error())
+	assert.Equal(t, errors.CategoryVerification, errors.CategoryOf(err))
+
+	retriable := errors.NewRetriableError(err)
+	assert.True(t, errors.IsRetriableError(retriable))
+	assert.True(t, errors.IsVerificationError(retriable))
+	assert.Equal(t, errors.CategoryVerification, errors.CategoryOf(retriable))
+}
+
+func TestCategoryOfOtherErrors(t *testing.T) {
+	assert.Equal(t, errors.CategoryOther, errors.CategoryOf(errors.New("something else")))
+}
+
+type serverMessageError struct{}
+
+func (e serverMessageError) Error() string {
+	return "quota exceeded"
+}
+
+func (e serverMessageError) ServerMessage() (string, string) {
+	return "quota exceeded", "req-1234"
+}
+
+func TestServerMessageOf(t *testing.T) {
+	msg, id := errors.ServerMessageOf(errors.NewFatalError(errors.Wrap(serverMessageError{}, "batch response")))
+	assert.Equal(t, "quota exceeded", msg)
+	assert.Equal(t, "req-1234", id)
+
+	msg, id = errors.ServerMessageOf(errors.New("not from the server"))
+	assert.Empty(t, msg)
+	assert.Empty(t, id)
+}
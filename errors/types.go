@@ -2,7 +2,10 @@ package errors
 
 import (
 	"fmt"
+	"net"
+	"net/http"
 	"net/url"
+	"os"
 
 	"github.com/pkg/errors"
 )
@@ -144,6 +147,82 @@ func IsRetriableError(err error) bool {
 	return false
 }
 
+// IsVerificationError indicates that an object was transferred, but was not
+// what it should have been, such as a download whose contents do not match its
+// OID, or an upload which the server does not have afterwards.
+func IsVerificationError(err error) bool {
+	if e, ok := err.(interface {
+		VerificationError() bool
+	}); ok {
+		return e.VerificationError()
+	}
+	if parent := parentOf(err); parent != nil {
+		return IsVerificationError(parent)
+	}
+	return false
+}
+
+// IsServerError indicates that the server responded with an HTTP 5xx status,
+// either to a request, or for a single object of a batch API response.
+func IsServerError(err error) bool {
+	return statusOf(err) >= 500
+}
+
+// IsNetworkError indicates that a request could not be made, or its response
+// read, such as when the host could not be resolved or the connection was
+// reset, rather than the server responding with an error.
+func IsNetworkError(err error) bool {
+	if statusOf(err) != 0 {
+		return false
+	}
+	_, ok := Cause(err).(net.Error)
+	return ok
+}
+
+// IsDiskError indicates that a local file could not be read or written, such
+// as when the disk is full.
+func IsDiskError(err error) bool {
+	switch Cause(err).(type) {
+	case *os.PathError, *os.LinkError, *os.SyscallError:
+		return true
+	}
+	return false
+}
+
+// ServerMessageOf returns the message the server gave with the error "err",
+// and the ID it gave the request, so that users can quote it to its operators,
+// or empty strings for either which it did not give. Errors give them through a
+// ServerMessage() method, as lfsapi errors decoded from the server's response,
+// and the errors it gives for single objects, do.
+func ServerMessageOf(err error) (message, requestID string) {
+	if e, ok := Cause(err).(interface {
+		ServerMessage() (string, string)
+	}); ok {
+		return e.ServerMessage()
+	}
+	return "", ""
+}
+
+// statusOf returns the HTTP status the error "err" was caused by, or 0 if it
+// was not caused by one. Errors give their status through an HTTPResponse()
+// method, as lfsapi errors do, or an HTTPStatus() method, as the errors the
+// server gives for single objects do.
+func statusOf(err error) int {
+	switch e := Cause(err).(type) {
+	case interface {
+		HTTPResponse() *http.Response
+	}:
+		if res := e.HTTPResponse(); res != nil {
+			return res.StatusCode
+		}
+	case interface {
+		HTTPStatus() int
+	}:
+		return e.HTTPStatus()
+	}
+	return 0
+}
+
 type errorWithCause interface {
 	Cause() error
 	StackTrace() errors.StackTrace
@@ -335,6 +414,27 @@ func NewRetriableError(err error) error {
 	return retriableError{newWrappedError(err, "")}
 }
 
+// Definitions for IsVerificationError()
+
+// verificationError only marks the error it wraps, rather than adding a message
+// or a stack as wrappedError does, so that its message is unchanged, and it can
+// itself be wrapped by NewRetriableError.
+type verificationError struct {
+	error
+}
+
+func (e verificationError) VerificationError() bool {
+	return true
+}
+
+func (e verificationError) Cause() error {
+	return e.error
+}
+
+func NewVerificationError(err error) error {
+	return verificationError{err}
+}
+
 func parentOf(err error) error {
 	type causer interface {
 		Cause() error
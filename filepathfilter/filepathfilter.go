@@ -14,33 +14,121 @@ type Pattern interface {
 	String() string
 }
 
+// Rule is a Pattern in an ordered list of rules, which includes the paths it
+// matches, or excludes them if it is negated.
+type Rule struct {
+	Pattern Pattern
+	Negated bool
+}
+
+// String returns the pattern of the rule, with a leading "!" if it is negated,
+// as ParseRule parses it.
+func (r Rule) String() string {
+	if r.Negated {
+		return "!" + r.Pattern.String()
+	}
+	if strings.HasPrefix(r.Pattern.String(), "!") {
+		return "\\" + r.Pattern.String()
+	}
+	return r.Pattern.String()
+}
+
+// ParseRule parses "rawrule" as a rule, which is negated if it starts with "!".
+// A pattern which starts with a literal "!" is given as "\!".
+func ParseRule(rawrule string) Rule {
+	if strings.HasPrefix(rawrule, "!") {
+		return Rule{Pattern: NewPattern(rawrule[1:]), Negated: true}
+	}
+	if strings.HasPrefix(rawrule, "\\!") {
+		rawrule = rawrule[1:]
+	}
+	return Rule{Pattern: NewPattern(rawrule)}
+}
+
+// Filter decides which paths are allowed by an ordered list of rules, which
+// are evaluated in order, as the patterns of a .gitignore file are, so that the
+// last rule which matches a path decides whether it is allowed. A path which no
+// rule matches is allowed only if none of the rules include paths.
 type Filter struct {
-	include []Pattern
-	exclude []Pattern
+	rules []Rule
+	// includes is whether any of the rules is not negated.
+	includes bool
+}
+
+// NewFromRules returns a Filter of the ordered list of rules "rules".
+func NewFromRules(rules []Rule) *Filter {
+	f := &Filter{rules: rules}
+	for _, r := range rules {
+		f.includes = f.includes || !r.Negated
+	}
+	return f
+}
+
+// NewOrdered returns a Filter of the ordered list of rules "rawrules", each of
+// which is negated if it starts with "!", as ParseRule parses them.
+func NewOrdered(rawrules []string) *Filter {
+	rules := make([]Rule, 0, len(rawrules))
+	for _, raw := range rawrules {
+		rules = append(rules, ParseRule(raw))
+	}
+	return NewFromRules(rules)
 }
 
+// NewWithNegation returns a Filter of the rules "include", each of which may be
+// negated with a leading "!", so that a later rule can exclude some paths which
+// an earlier one included, followed by the patterns "exclude", which exclude
+// the paths they match, as they do for New. This is how the include and
+// exclude paths which Git LFS is configured with are combined.
+func NewWithNegation(include, exclude []string) *Filter {
+	rules := make([]Rule, 0, len(include)+len(exclude))
+	for _, raw := range include {
+		rules = append(rules, ParseRule(raw))
+	}
+	for _, raw := range exclude {
+		rules = append(rules, Rule{Pattern: NewPattern(raw), Negated: true})
+	}
+	return NewFromRules(rules)
+}
+
+// NewFromPatterns returns a Filter which allows the paths matched by any of
+// "include", or all paths if it is empty, except for those matched by any of
+// "exclude".
 func NewFromPatterns(include, exclude []Pattern) *Filter {
-	return &Filter{include: include, exclude: exclude}
+	rules := make([]Rule, 0, len(include)+len(exclude))
+	for _, p := range include {
+		rules = append(rules, Rule{Pattern: p})
+	}
+	for _, p := range exclude {
+		rules = append(rules, Rule{Pattern: p, Negated: true})
+	}
+	return NewFromRules(rules)
 }
 
 func New(include, exclude []string) *Filter {
 	return NewFromPatterns(convertToPatterns(include), convertToPatterns(exclude))
 }
 
+// Rules returns the rules of this *Filter, in order.
+func (f *Filter) Rules() []Rule {
+	return append([]Rule(nil), f.rules...)
+}
+
 // Include returns the result of calling String() on each Pattern in the
 // include set of this *Filter.
-func (f *Filter) Include() []string { return patternsToStrings(f.include...) }
+func (f *Filter) Include() []string { return f.patterns(false) }
 
 // Exclude returns the result of calling String() on each Pattern in the
 // exclude set of this *Filter.
-func (f *Filter) Exclude() []string { return patternsToStrings(f.exclude...) }
-
-// patternsToStrings maps the given set of Pattern's to a string slice by
-// calling String() on each pattern.
-func patternsToStrings(ps ...Pattern) []string {
-	s := make([]string, 0, len(ps))
-	for _, p := range ps {
-		s = append(s, p.String())
+func (f *Filter) Exclude() []string { return f.patterns(true) }
+
+// patterns returns the result of calling String() on the Pattern of each rule
+// which is negated, or is not, as "negated" gives.
+func (f *Filter) patterns(negated bool) []string {
+	s := make([]string, 0, len(f.rules))
+	for _, r := range f.rules {
+		if r.Negated == negated {
+			s = append(s, r.Pattern.String())
+		}
 	}
 
 	return s
@@ -53,7 +141,8 @@ func (f *Filter) Allows(filename string) bool {
 
 // AllowsPattern returns whether the given filename is permitted by the
 // inclusion/exclusion rules of this filter, as well as the pattern that either
-// allowed or disallowed that filename.
+// allowed or disallowed that filename. Of several rules in a row which match
+// the filename, and agree, the first one's pattern is returned.
 //
 // In special cases, such as a nil `*Filter` receiver, the absence of any
 // patterns, or the given filename not being matched by any pattern, the empty
@@ -63,35 +152,26 @@ func (f *Filter) AllowsPattern(filename string) (pattern string, allowed bool) {
 		return "", true
 	}
 
-	if len(f.include)+len(f.exclude) == 0 {
+	if len(f.rules) == 0 {
 		return "", true
 	}
 
 	cleanedName := filepath.Clean(filename)
 
-	if len(f.include) > 0 {
-		matched := false
-		for _, inc := range f.include {
-			matched = inc.Match(cleanedName)
-			if matched {
-				pattern = inc.String()
-				break
-			}
+	allowed = !f.includes
+	matched := false
+	for _, r := range f.rules {
+		if !r.Pattern.Match(cleanedName) {
+			continue
 		}
-		if !matched {
-			return "", false
-		}
-	}
-
-	if len(f.exclude) > 0 {
-		for _, ex := range f.exclude {
-			if ex.Match(cleanedName) {
-				return ex.String(), false
-			}
+		if !matched || allowed == r.Negated {
+			pattern = r.Pattern.String()
+			allowed = !r.Negated
 		}
+		matched = true
 	}
 
-	return pattern, true
+	return pattern, allowed
 }
 
 func NewPattern(rawpattern string) Pattern {
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPatternMatch(t *testing.T) {
@@ -217,3 +218,61 @@ func TestFilterReportsExcludePatterns(t *testing.T) {
 
 	assert.Equal(t, []string{"*.baz", "*.quux"}, filter.Exclude())
 }
+
+func TestFilterOrderedRules(t *testing.T) {
+	filter := NewOrdered([]string{"assets", "!assets/tmp", "assets/tmp/keep"})
+
+	for path, allowed := range map[string]bool{
+		"assets/a.png":        true,
+		"assets/tmp/a.png":    false,
+		"assets/tmp/keep/a":   true,
+		"other/a.png":         false,
+		"assets/tmpfile.png":  true,
+		"assets/sub/tmp/a.db": true,
+	} {
+		assert.Equal(t, allowed, filter.Allows(path), path)
+	}
+
+	pattern, allowed := filter.AllowsPattern("assets/tmp/a.png")
+	assert.False(t, allowed)
+	assert.Equal(t, "assets/tmp", pattern)
+}
+
+func TestFilterOrderedRulesOnlyNegated(t *testing.T) {
+	filter := NewOrdered([]string{"!*.tmp", "!build"})
+
+	assert.True(t, filter.Allows("a.dat"))
+	assert.False(t, filter.Allows("a.tmp"))
+	assert.False(t, filter.Allows("build/a.dat"))
+}
+
+func TestFilterOrderedRulesEscapedNegation(t *testing.T) {
+	filter := NewOrdered([]string{"\\!important.dat"})
+
+	assert.True(t, filter.Allows("!important.dat"))
+	assert.False(t, filter.Allows("important.dat"))
+	assert.Equal(t, []string{"!important.dat"}, filter.Include())
+	assert.Equal(t, "\\!important.dat", filter.Rules()[0].String())
+}
+
+func TestFilterWithNegation(t *testing.T) {
+	filter := NewWithNegation([]string{"assets", "!assets/tmp"}, []string{"*.psd"})
+
+	assert.True(t, filter.Allows("assets/a.png"))
+	assert.False(t, filter.Allows("assets/tmp/a.png"))
+	assert.False(t, filter.Allows("assets/a.psd"))
+	rules := filter.Rules()
+	require.Len(t, rules, 3)
+	assert.Equal(t, "assets", rules[0].String())
+	assert.Equal(t, "!assets/tmp", rules[1].String())
+	assert.Equal(t, "!*.psd", rules[2].String())
+	assert.Equal(t, []string{"assets"}, filter.Include())
+	assert.Equal(t, []string{"assets/tmp", "*.psd"}, filter.Exclude())
+}
+
+func TestFilterNewTreatsExclamationLiterally(t *testing.T) {
+	filter := New([]string{"!a.dat"}, nil)
+
+	assert.True(t, filter.Allows("!a.dat"))
+	assert.False(t, filter.Allows("a.dat"))
+}
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/git-lfs/git-lfs/tools"
@@ -35,11 +36,13 @@ func (s *AttributeSource) String() string {
 }
 
 // GetAttributePaths returns a list of entries in .gitattributes which are
-// configured with the filter=lfs attribute
+// configured with the filter=lfs attribute, including through an attribute
+// macro
 // workingDIr is the root of the working copy
 // gitDir is the root of the git repo
 func GetAttributePaths(workingDir, gitDir string) []AttributePath {
 	paths := make([]AttributePath, 0)
+	macros := attributeMacros(workingDir, gitDir)
 
 	for _, path := range findAttributeFiles(workingDir, gitDir) {
 		attributes, err := os.Open(path)
@@ -56,28 +59,25 @@ func GetAttributePaths(workingDir, gitDir string) []AttributePath {
 		scanner.Split(le.ScanLines)
 
 		for scanner.Scan() {
-			line := scanner.Text()
-			if strings.Contains(line, "filter=lfs") {
-				fields := strings.Fields(line)
-				pattern := fields[0]
-				if len(reldir) > 0 {
-					pattern = filepath.Join(reldir, pattern)
-				}
-				// Find lockable flag in any position after pattern to avoid
-				// edge case of matching "lockable" to a file pattern
-				lockable := false
-				for _, f := range fields[1:] {
-					if f == LockableAttrib {
-						lockable = true
-						break
-					}
-				}
-				paths = append(paths, AttributePath{
-					Path:     pattern,
-					Source:   source,
-					Lockable: lockable,
-				})
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 2 || strings.HasPrefix(fields[0], attributeMacroPrefix) {
+				continue
 			}
+
+			attrs := expandAttributeMacros(fields[1:], macros)
+			if !hasAttribute(attrs, "filter=lfs") {
+				continue
+			}
+
+			pattern := fields[0]
+			if len(reldir) > 0 {
+				pattern = filepath.Join(reldir, pattern)
+			}
+			paths = append(paths, AttributePath{
+				Path:     pattern,
+				Source:   source,
+				Lockable: hasAttribute(attrs, LockableAttrib),
+			})
 		}
 
 		source.LineEnding = le.LineEnding()
@@ -86,6 +86,86 @@ func GetAttributePaths(workingDir, gitDir string) []AttributePath {
 	return paths
 }
 
+// hasAttribute returns whether "attr" is among "attrs". Only attributes after
+// the pattern are given, to avoid the edge case of matching "lockable" to a
+// file pattern.
+func hasAttribute(attrs []string, attr string) bool {
+	for _, a := range attrs {
+		if a == attr {
+			return true
+		}
+	}
+	return false
+}
+
+// attributeMacroPrefix begins the lines of gitattributes files which define an
+// attribute macro, such as "[attr]lfs filter=lfs diff=lfs merge=lfs -text".
+const attributeMacroPrefix = "[attr]"
+
+// attributeMacros returns the attribute macros defined in the .gitattributes
+// file at the root of the working copy and in the repository's info/attributes,
+// which are the only files that Git reads them from, keyed by name. Where both
+// define the same macro, the definition in info/attributes is used, as it is by
+// Git.
+func attributeMacros(workingDir, gitDir string) map[string][]string {
+	macros := make(map[string][]string)
+
+	for _, path := range []string{
+		filepath.Join(workingDir, ".gitattributes"),
+		filepath.Join(gitDir, "info", "attributes"),
+	} {
+		attributes, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+
+		scanner := bufio.NewScanner(attributes)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) == 0 || !strings.HasPrefix(fields[0], attributeMacroPrefix) {
+				continue
+			}
+
+			name := strings.TrimPrefix(fields[0], attributeMacroPrefix)
+			macros[name] = fields[1:]
+		}
+		attributes.Close()
+	}
+
+	return macros
+}
+
+// expandAttributeMacros returns "attrs" with each macro that they set followed
+// by the attributes it expands to, in turn expanding any macros which those
+// set. Macros can only be set; unsetting one, as in "-lfs", does not affect the
+// attributes it expands to.
+func expandAttributeMacros(attrs []string, macros map[string][]string) []string {
+	if len(macros) == 0 {
+		return attrs
+	}
+	return expandAttributeMacrosExcept(attrs, macros, make(map[string]bool))
+}
+
+// expandAttributeMacrosExcept expands "attrs" as expandAttributeMacros does,
+// without expanding any of the macros in "expanding" again, so that a macro
+// which refers back to itself is only expanded once.
+func expandAttributeMacrosExcept(attrs []string, macros map[string][]string, expanding map[string]bool) []string {
+	expanded := make([]string, 0, len(attrs))
+	for _, attr := range attrs {
+		expanded = append(expanded, attr)
+
+		macro, ok := macros[attr]
+		if !ok || expanding[attr] {
+			continue
+		}
+
+		expanding[attr] = true
+		expanded = append(expanded, expandAttributeMacrosExcept(macro, macros, expanding)...)
+		delete(expanding, attr)
+	}
+	return expanded
+}
+
 // copies bufio.ScanLines(), counting LF vs CRLF in a file
 type lineEndingSplitter struct {
 	LFCount   int
@@ -155,3 +235,118 @@ func findAttributeFiles(workingDir, gitDir string) []string {
 
 	return paths
 }
+
+// FilterAttributeRule is a line in a gitattributes file which gives the filter
+// attribute a value, whether "lfs" or otherwise, or unsets or unspecifies it.
+type FilterAttributeRule struct {
+	// Path is the pattern of the line, relative to the root of the working
+	// copy
+	Path string
+	// Source is the attribute file which the line is in
+	Source *AttributeSource
+	// Line is the number of the line in Source, counting from one
+	Line int
+	// Filter is the value the line gives the filter attribute, which is
+	// empty if it unsets or unspecifies it
+	Filter string
+}
+
+// LFS returns whether the rule gives matching files the LFS filter.
+func (r *FilterAttributeRule) LFS() bool {
+	return r.Filter == "lfs"
+}
+
+// GetFilterAttributeRules returns every line in the gitattributes files of the
+// working copy, and those in the repository's info/attributes, which say
+// anything about the filter attribute, in order of precedence. That is, for a
+// file matched by more than one rule, the first of them applies: lines in
+// info/attributes come first, then those in deeper directories, and within a
+// file, later lines come before earlier ones. Lines which set the filter
+// attribute through an attribute macro are included.
+func GetFilterAttributeRules(workingDir, gitDir string) []*FilterAttributeRule {
+	repoAttributes := filepath.Join(gitDir, "info", "attributes")
+
+	files := findAttributeFiles(workingDir, gitDir)
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i] == repoAttributes || files[j] == repoAttributes {
+			return files[i] == repoAttributes && files[j] != repoAttributes
+		}
+
+		di := strings.Count(filepath.ToSlash(files[i]), "/")
+		dj := strings.Count(filepath.ToSlash(files[j]), "/")
+		if di != dj {
+			return di > dj
+		}
+		return files[i] < files[j]
+	})
+
+	macros := attributeMacros(workingDir, gitDir)
+
+	var rules []*FilterAttributeRule
+	for _, path := range files {
+		attributes, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+
+		relfile, _ := filepath.Rel(workingDir, path)
+		reldir := filepath.Dir(relfile)
+		if path == repoAttributes {
+			// info/attributes applies from the root of the
+			// working copy, not from where it is.
+			reldir = ""
+		}
+		source := &AttributeSource{Path: relfile}
+
+		le := &lineEndingSplitter{}
+		scanner := bufio.NewScanner(attributes)
+		scanner.Split(le.ScanLines)
+
+		var inFile []*FilterAttributeRule
+		for n := 1; scanner.Scan(); n++ {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 2 || strings.HasPrefix(fields[0], "#") ||
+				strings.HasPrefix(fields[0], attributeMacroPrefix) {
+				continue
+			}
+
+			filter, ok := filterAttribute(expandAttributeMacros(fields[1:], macros))
+			if !ok {
+				continue
+			}
+
+			pattern := fields[0]
+			if len(reldir) > 0 {
+				pattern = filepath.Join(reldir, pattern)
+			}
+			inFile = append(inFile, &FilterAttributeRule{
+				Path:   pattern,
+				Source: source,
+				Line:   n,
+				Filter: filter,
+			})
+		}
+		attributes.Close()
+
+		source.LineEnding = le.LineEnding()
+		for i := len(inFile) - 1; i >= 0; i-- {
+			rules = append(rules, inFile[i])
+		}
+	}
+
+	return rules
+}
+
+// filterAttribute returns the value that the last of "attrs" which mentions the
+// filter attribute gives it, or false if none do.
+func filterAttribute(attrs []string) (filter string, ok bool) {
+	for _, attr := range attrs {
+		switch {
+		case attr == "-filter", attr == "!filter":
+			filter, ok = "", true
+		case strings.HasPrefix(attr, "filter="):
+			filter, ok = strings.TrimPrefix(attr, "filter="), true
+		}
+	}
+	return filter, ok
+}
@@ -0,0 +1,76 @@
+package git
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeAttributes(t *testing.T, path, contents string) {
+	require.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.Nil(t, ioutil.WriteFile(path, []byte(contents), 0644))
+}
+
+func TestGetAttributePathsExpandsMacros(t *testing.T) {
+	dir, err := ioutil.TempDir("", "git-attribs")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	gitDir := filepath.Join(dir, ".git")
+	writeAttributes(t, filepath.Join(dir, ".gitattributes"),
+		"[attr]lfs filter=lfs diff=lfs merge=lfs -text\n"+
+			"[attr]lockedlfs lfs lockable\n"+
+			"*.dat lfs\n"+
+			"*.psd lockedlfs\n"+
+			"*.txt text\n")
+	writeAttributes(t, filepath.Join(gitDir, "info", "attributes"),
+		"[attr]media filter=lfs\n")
+	writeAttributes(t, filepath.Join(dir, "sub", ".gitattributes"),
+		"*.mov media\n")
+
+	paths := GetAttributePaths(dir, gitDir)
+	require.Len(t, paths, 3)
+
+	byPath := make(map[string]AttributePath)
+	for _, p := range paths {
+		byPath[p.Path] = p
+	}
+
+	assert.False(t, byPath["*.dat"].Lockable)
+	assert.True(t, byPath["*.psd"].Lockable)
+	assert.Contains(t, byPath, filepath.Join("sub", "*.mov"))
+}
+
+func TestGetFilterAttributeRulesExpandsMacros(t *testing.T) {
+	dir, err := ioutil.TempDir("", "git-attribs")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	gitDir := filepath.Join(dir, ".git")
+	writeAttributes(t, filepath.Join(dir, ".gitattributes"),
+		"[attr]lfs filter=lfs diff=lfs merge=lfs -text\n"+
+			"[attr]loop loop lfs\n"+
+			"*.dat lfs\n"+
+			"*.bin loop\n"+
+			"*.zip lfs -filter\n"+
+			"*.iso -lfs\n")
+	// Git ignores macros defined below the root of the working copy.
+	writeAttributes(t, filepath.Join(dir, "sub", ".gitattributes"),
+		"[attr]nested filter=lfs\n"+
+			"*.mov nested\n")
+
+	rules := GetFilterAttributeRules(dir, gitDir)
+	require.Len(t, rules, 3)
+
+	assert.Equal(t, "*.zip", rules[0].Path)
+	assert.False(t, rules[0].LFS())
+	assert.Equal(t, "*.bin", rules[1].Path)
+	assert.True(t, rules[1].LFS())
+	assert.Equal(t, "*.dat", rules[2].Path)
+	assert.True(t, rules[2].LFS())
+	assert.Equal(t, 3, rules[2].Line)
+}
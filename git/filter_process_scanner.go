@@ -39,6 +39,15 @@ type FilterProcessScanner struct {
 	// `Request()` function. It is cleared at the beginning of each `Scan()`
 	// invocation, and written to at the end of each `Scan()` invocation.
 	err error
+
+	// offeredCaps are the capabilities Git offered in
+	// NegotiateCapabilities, whether or not LFS accepted them.
+	offeredCaps []string
+	// acceptedCaps are the capabilities LFS accepted in
+	// NegotiateCapabilities.
+	acceptedCaps []string
+	// version is the protocol version agreed in Init.
+	version string
 }
 
 // NewFilterProcessScanner constructs a new instance of the
@@ -90,6 +99,7 @@ func (o *FilterProcessScanner) Init() error {
 	if err != nil {
 		return errors.Wrap(err, "writing filter-process initialization failed")
 	}
+	o.version = reqVer
 	return nil
 }
 
@@ -105,6 +115,9 @@ func (o *FilterProcessScanner) NegotiateCapabilities() error {
 	if err != nil {
 		return fmt.Errorf("reading filter-process capabilities failed with %s", err)
 	}
+	o.offeredCaps = supCaps
+	tracerx.Printf("filter-process: Git offered %s", strings.Join(supCaps, ", "))
+
 	for _, reqCap := range reqCaps {
 		if !isStringInSlice(supCaps, reqCap) {
 			return fmt.Errorf("filter '%s' not supported (your Git supports: %s)", reqCap, supCaps)
@@ -115,10 +128,48 @@ func (o *FilterProcessScanner) NegotiateCapabilities() error {
 	if err != nil {
 		return fmt.Errorf("writing filter-process capabilities failed with %s", err)
 	}
+	o.acceptedCaps = reqCaps
 
 	return nil
 }
 
+// Offered returns whether Git offered the capability "name", such as "delay", in
+// NegotiateCapabilities, whether or not LFS accepted it.
+//
+// Git's filter protocol has no capability for a filter to report its progress
+// on a file, so LFS keeps printing its own progress while smudging, rather than
+// sending it to Git. Offered lets such a capability be detected if Git ever
+// adds one, without changing the capabilities LFS accepts.
+func (o *FilterProcessScanner) Offered(name string) bool {
+	return isStringInSlice(o.offeredCaps, "capability="+name)
+}
+
+// Accepted returns whether LFS accepted the capability "name" in
+// NegotiateCapabilities.
+func (o *FilterProcessScanner) Accepted(name string) bool {
+	return isStringInSlice(o.acceptedCaps, "capability="+name)
+}
+
+// Capabilities returns the names of the capabilities Git offered, and of those
+// LFS accepted, in NegotiateCapabilities, without their "capability=" prefix.
+func (o *FilterProcessScanner) Capabilities() (offered, accepted []string) {
+	return trimCapabilities(o.offeredCaps), trimCapabilities(o.acceptedCaps)
+}
+
+// Version returns the protocol version agreed with Git in Init, such as
+// "version=2", or an empty string if Init has not succeeded.
+func (o *FilterProcessScanner) Version() string {
+	return o.version
+}
+
+func trimCapabilities(caps []string) []string {
+	names := make([]string, 0, len(caps))
+	for _, c := range caps {
+		names = append(names, strings.TrimPrefix(c, "capability="))
+	}
+	return names
+}
+
 // Request represents a single command sent to LFS from the parent Git process.
 type Request struct {
 	// Header maps header strings to values, and is encoded as the first
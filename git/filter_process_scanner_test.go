@@ -28,6 +28,7 @@ func TestFilterProcessScannerInitializesWithCorrectSupportedValues(t *testing.T)
 	out, err := newPktline(&to, nil).readPacketList()
 	assert.Nil(t, err)
 	assert.Equal(t, []string{"git-filter-server", "version=2"}, out)
+	assert.Equal(t, "version=2", fps.Version())
 }
 
 func TestFilterProcessScannerRejectsUnrecognizedInitializationMessages(t *testing.T) {
@@ -59,6 +60,7 @@ func TestFilterProcessScannerRejectsUnsupportedFilters(t *testing.T) {
 	require.NotNil(t, err)
 	assert.Equal(t, "filter 'version=2' not supported (your Git supports: [version=0])", err.Error())
 	assert.Empty(t, to.Bytes())
+	assert.Empty(t, fps.Version())
 }
 
 func TestFilterProcessScannerNegotitatesSupportedCapabilities(t *testing.T) {
@@ -79,6 +81,35 @@ func TestFilterProcessScannerNegotitatesSupportedCapabilities(t *testing.T) {
 	assert.Equal(t, []string{"capability=clean", "capability=smudge"}, out)
 }
 
+func TestFilterProcessScannerDoesNotNegotiateDelay(t *testing.T) {
+	var from, to bytes.Buffer
+
+	pl := newPktline(nil, &from)
+	require.Nil(t, pl.writePacketList([]string{
+		"capability=clean", "capability=smudge", "capability=delay",
+	}))
+
+	fps := NewFilterProcessScanner(&from, &to)
+	err := fps.NegotiateCapabilities()
+
+	assert.Nil(t, err)
+
+	out, err := newPktline(&to, nil).readPacketList()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"capability=clean", "capability=smudge"}, out)
+
+	assert.True(t, fps.Offered("delay"))
+	assert.True(t, fps.Offered("smudge"))
+	assert.False(t, fps.Offered("progress"))
+
+	assert.False(t, fps.Accepted("delay"))
+	assert.True(t, fps.Accepted("smudge"))
+
+	offered, accepted := fps.Capabilities()
+	assert.Equal(t, []string{"clean", "smudge", "delay"}, offered)
+	assert.Equal(t, []string{"clean", "smudge"}, accepted)
+}
+
 func TestFilterProcessScannerDoesNotNegotitatesUnsupportedCapabilities(t *testing.T) {
 	var from, to bytes.Buffer
 
@@ -11,6 +11,7 @@ import (
 	"io/ioutil"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"strconv"
@@ -70,6 +71,54 @@ type Ref struct {
 	Sha  string
 }
 
+// Refspec returns the fully qualified name of the ref, such as
+// "refs/heads/master", or just its name if it has no known prefix.
+func (r *Ref) Refspec() string {
+	if prefix, ok := r.Type.Prefix(); ok {
+		return prefix + "/" + r.Name
+	}
+	return r.Name
+}
+
+// refPatternPrefixes are the prefixes which a ref pattern that is not fully
+// qualified is tried under, as Git tries a short ref name under them.
+var refPatternPrefixes = []string{"refs/", "refs/tags/", "refs/heads/", "refs/remotes/"}
+
+// MatchesPattern returns whether the ref is matched by "pattern", either as a
+// glob, in which "*" does not match a "/", or literally, matching the whole ref
+// or any ref beneath it, as git-for-each-ref(1) matches them. So "refs/tags"
+// matches every tag. A pattern which does not start with "refs/" is also tried
+// under each of the prefixes Git tries a short ref name under, so that
+// "release/*" matches "refs/heads/release/1.0".
+func (r *Ref) MatchesPattern(pattern string) bool {
+	pattern = strings.TrimSuffix(pattern, "/")
+	if len(pattern) == 0 {
+		return false
+	}
+
+	refspec := r.Refspec()
+	if matchRefPattern(pattern, refspec) {
+		return true
+	}
+	if strings.HasPrefix(pattern, "refs/") {
+		return false
+	}
+	for _, prefix := range refPatternPrefixes {
+		if matchRefPattern(prefix+pattern, refspec) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchRefPattern(pattern, refspec string) bool {
+	if refspec == pattern || strings.HasPrefix(refspec, pattern+"/") {
+		return true
+	}
+	matched, _ := path.Match(pattern, refspec)
+	return matched
+}
+
 // Some top level information about a commit (only first line of message)
 type CommitSummary struct {
 	Sha            string
@@ -1191,6 +1240,83 @@ func GetTrackedFiles(pattern string) ([]string, error) {
 	return ret, cmd.Wait()
 }
 
+// IndexEntry is a file in the index, as `git ls-files --stage` gives it.
+type IndexEntry struct {
+	// Mode is the mode of the file, in octal, such as "100755" for an
+	// executable file.
+	Mode string
+	Sha1 string
+	// Stage is 0, unless the file has a merge conflict.
+	Stage int
+	// Path is relative to the root of the repository.
+	Path string
+}
+
+// Executable returns whether the file is executable in the index.
+func (e *IndexEntry) Executable() bool {
+	return e.Mode == "100755"
+}
+
+// GetIndexEntries returns every file in the index, in the order the index keeps
+// them in.
+func GetIndexEntries() ([]*IndexEntry, error) {
+	cmd := subprocess.ExecCommand("git", "ls-files", "--stage", "-z", "--full-name")
+	if root, err := RootDir(); err == nil {
+		cmd.Dir = root
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to call git ls-files: %v", err)
+	}
+	return parseIndexEntries(out)
+}
+
+// GetIndexEntry returns the entry in the index for the file at "path", relative
+// to the current directory, or nil if it is not in the index, or is unmerged.
+func GetIndexEntry(path string) (*IndexEntry, error) {
+	out, err := subprocess.ExecCommand("git", "ls-files", "--stage", "-z", "--full-name", "--", path).Output()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to call git ls-files: %v", err)
+	}
+
+	entries, err := parseIndexEntries(out)
+	if err != nil || len(entries) != 1 || entries[0].Stage != 0 {
+		return nil, err
+	}
+	return entries[0], nil
+}
+
+// parseIndexEntries parses the output of `git ls-files --stage -z`.
+func parseIndexEntries(out []byte) ([]*IndexEntry, error) {
+	var entries []*IndexEntry
+	for _, line := range bytes.Split(out, []byte{0}) {
+		if len(line) == 0 {
+			continue
+		}
+
+		tab := bytes.IndexByte(line, '\t')
+		if tab < 0 {
+			return nil, fmt.Errorf("git ls-files: invalid line %q", line)
+		}
+		fields := strings.Fields(string(line[:tab]))
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("git ls-files: invalid line %q", line)
+		}
+		stage, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("git ls-files: invalid stage in %q", line)
+		}
+
+		entries = append(entries, &IndexEntry{
+			Mode:  fields[0],
+			Sha1:  fields[1],
+			Stage: stage,
+			Path:  string(line[tab+1:]),
+		})
+	}
+	return entries, nil
+}
+
 func sanitizePattern(pattern string) string {
 	if strings.HasPrefix(pattern, "/") {
 		return pattern[1:]
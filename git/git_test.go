@@ -3,6 +3,7 @@ package git_test // to avoid import cycles
 import (
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"sort"
 	"testing"
@@ -532,6 +533,72 @@ func TestGetFilesChanges(t *testing.T) {
 
 }
 
+func TestGetIndexEntries(t *testing.T) {
+	repo := test.NewRepo(t)
+	repo.Pushd()
+	defer func() {
+		repo.Popd()
+		repo.Cleanup()
+	}()
+
+	repo.AddCommits([]*test.CommitInput{
+		{
+			Files: []*test.FileInput{
+				{Filename: "file1.txt", Size: 20},
+				{Filename: "folder/file 2.txt", Size: 10},
+			},
+		},
+	})
+	cmd := exec.Command("git", "update-index", "--chmod=+x", "file1.txt")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git update-index: %v: %s", err, out)
+	}
+
+	entries, err := GetIndexEntries()
+	assert.Nil(t, err)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "file1.txt", entries[0].Path)
+		assert.Equal(t, "100755", entries[0].Mode)
+		assert.True(t, entries[0].Executable())
+		assert.Len(t, entries[0].Sha1, 40)
+		assert.Equal(t, 0, entries[0].Stage)
+
+		assert.Equal(t, "folder/file 2.txt", entries[1].Path)
+		assert.Equal(t, "100644", entries[1].Mode)
+		assert.False(t, entries[1].Executable())
+	}
+}
+
+func TestGetIndexEntry(t *testing.T) {
+	repo := test.NewRepo(t)
+	repo.Pushd()
+	defer func() {
+		repo.Popd()
+		repo.Cleanup()
+	}()
+
+	repo.AddCommits([]*test.CommitInput{
+		{
+			Files: []*test.FileInput{
+				{Filename: "file1.txt", Size: 20},
+				{Filename: "folder/file 2.txt", Size: 10},
+			},
+		},
+	})
+
+	entry, err := GetIndexEntry("folder/file 2.txt")
+	assert.Nil(t, err)
+	if assert.NotNil(t, entry) {
+		assert.Equal(t, "folder/file 2.txt", entry.Path)
+		assert.Equal(t, "100644", entry.Mode)
+		assert.Len(t, entry.Sha1, 40)
+	}
+
+	entry, err = GetIndexEntry("missing.txt")
+	assert.Nil(t, err)
+	assert.Nil(t, entry)
+}
+
 func TestValidateRemoteURL(t *testing.T) {
 	assert.Nil(t, ValidateRemoteURL("https://github.com/git-lfs/git-lfs"))
 	assert.Nil(t, ValidateRemoteURL("http://github.com/git-lfs/git-lfs"))
@@ -562,6 +629,47 @@ func TestRefTypeKnownPrefixes(t *testing.T) {
 	}
 }
 
+func TestRefRefspec(t *testing.T) {
+	for expected, ref := range map[string]*Ref{
+		"refs/heads/release/1.0":     {Name: "release/1.0", Type: RefTypeLocalBranch},
+		"refs/remotes/origin/master": {Name: "origin/master", Type: RefTypeRemoteBranch},
+		"refs/tags/v1.0":             {Name: "v1.0", Type: RefTypeLocalTag},
+		"HEAD":                       {Name: "HEAD", Type: RefTypeHEAD},
+	} {
+		assert.Equal(t, expected, ref.Refspec())
+	}
+}
+
+func TestRefMatchesPattern(t *testing.T) {
+	release := &Ref{Name: "release/1.0", Type: RefTypeLocalBranch}
+	tag := &Ref{Name: "v1.0", Type: RefTypeLocalTag}
+	remote := &Ref{Name: "origin/release/1.0", Type: RefTypeRemoteBranch}
+
+	for _, c := range []struct {
+		Ref     *Ref
+		Pattern string
+		Matches bool
+	}{
+		{release, "release/*", true},
+		{release, "refs/heads/release/*", true},
+		{release, "release", true},
+		{release, "refs/heads/rel", false},
+		{release, "refs/tags", false},
+		{release, "*", false},
+		{tag, "refs/tags", true},
+		{tag, "refs/tags/", true},
+		{tag, "tags/*", true},
+		{tag, "v1.*", true},
+		{tag, "v2.*", false},
+		{remote, "origin/release/*", true},
+		{remote, "release/*", false},
+		{remote, "", false},
+	} {
+		assert.Equal(t, c.Matches, c.Ref.MatchesPattern(c.Pattern),
+			"%s against %q", c.Ref.Refspec(), c.Pattern)
+	}
+}
+
 func TestRefTypeUnknownPrefix(t *testing.T) {
 	defer func() {
 		if err := recover(); err != nil {
@@ -111,7 +111,7 @@ func (p *pktline) writePacket(data []byte) error {
 		return errors.New("Packet length exceeds maximal length")
 	}
 
-	if _, err := p.w.WriteString(fmt.Sprintf("%04x", len(data)+4)); err != nil {
+	if err := p.writeLength(len(data) + 4); err != nil {
 		return err
 	}
 
@@ -122,6 +122,20 @@ func (p *pktline) writePacket(data []byte) error {
 	return nil
 }
 
+// writeLength writes the four hexadecimal digits of the header of a packet of
+// "n" bytes, without formatting them through fmt, since it is done for every
+// packet.
+func (p *pktline) writeLength(n int) error {
+	const digits = "0123456789abcdef"
+
+	for _, shift := range [...]uint{12, 8, 4, 0} {
+		if err := p.w.WriteByte(digits[(n>>shift)&0xf]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // writeFlush writes the terminating "flush" packet and then flushes the
 // underlying buffered writer.
 //
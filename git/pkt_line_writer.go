@@ -1,6 +1,7 @@
 package git
 
 import (
+	"bufio"
 	"io"
 
 	"github.com/git-lfs/git-lfs/tools"
@@ -13,6 +14,8 @@ type PktlineWriter struct {
 	// collected to write a full packet, or the buffer was instructed to
 	// flush.
 	buf []byte
+	// c is the capacity that buf is made with.
+	c int
 	// pl is the place where packets get written.
 	pl *pktline
 }
@@ -20,8 +23,9 @@ type PktlineWriter struct {
 var _ io.Writer = new(PktlineWriter)
 
 // NewPktlineWriter returns a new *PktlineWriter, which will write to the
-// underlying data stream "w". The internal buffer is initialized with the given
-// capacity, "c".
+// underlying data stream "w". The internal buffer is given the capacity "c"
+// when it is first needed, which is never if everything written to the writer
+// comes in whole packets.
 //
 // If "w" is already a `*PktlineWriter`, it will be returned as-is.
 func NewPktlineWriter(w io.Writer, c int) *PktlineWriter {
@@ -30,8 +34,9 @@ func NewPktlineWriter(w io.Writer, c int) *PktlineWriter {
 	}
 
 	return &PktlineWriter{
-		buf: make([]byte, 0, c),
-		pl:  newPktline(nil, w),
+		c: c,
+		// Only the writing half of the pktline is needed.
+		pl: &pktline{w: bufio.NewWriter(w)},
 	}
 }
 
@@ -41,7 +46,9 @@ func NewPktlineWriter(w io.Writer, c int) *PktlineWriter {
 // As many bytes are removed from "p" as possible and stored in an internal
 // buffer until the amount of data in the internal buffer is enough to write a
 // single packet. Once the internal buffer is full, a packet is written to the
-// underlying stream of data, and the process repeats.
+// underlying stream of data, and the process repeats. While the internal
+// buffer is empty, whole packets are written straight from "p", without being
+// copied into it.
 //
 // When the caller has no more data to write in the given chunk of packets, a
 // subsequent call to `Flush()` SHOULD be made in order to signify that the
@@ -58,6 +65,21 @@ func (w *PktlineWriter) Write(p []byte) (int, error) {
 	var n int
 
 	for len(p[n:]) > 0 {
+		if len(w.buf) == 0 && len(p[n:]) >= MaxPacketLength {
+			// Nothing is buffered, so a whole packet can be
+			// written from "p" as-is.
+			if err := w.pl.writePacket(p[n : n+MaxPacketLength]); err != nil {
+				return n, err
+			}
+
+			n += MaxPacketLength
+			continue
+		}
+
+		if w.buf == nil {
+			w.buf = make([]byte, 0, tools.MaxInt(w.c, 0))
+		}
+
 		// While there is still data left to process in "p", grab as
 		// much of it as we can while not allowing the internal buffer
 		// to exceed the MaxPacketLength const.
@@ -98,6 +120,18 @@ func (w *PktlineWriter) Flush() error {
 	return nil
 }
 
+// Sync writes any data in the internal buffer out to the underlying stream as a
+// packet, and then flushes the stream itself, so that the reader receives all
+// of the data written so far. Unlike Flush, it does not write a FLUSH packet,
+// so the current pkt sequence continues with whatever is written next.
+func (w *PktlineWriter) Sync() error {
+	if _, err := w.flush(); err != nil {
+		return err
+	}
+
+	return w.pl.w.Flush()
+}
+
 // flush writes any data in the internal buffer out to the underlying protocol
 // stream. If the amount of data in the internal buffer exceeds the
 // MaxPacketLength, the data will be written in multiple packets to accommodate.
@@ -107,6 +141,10 @@ func (w *PktlineWriter) Flush() error {
 func (w *PktlineWriter) flush() (int, error) {
 	var n int
 
+	// Keep the start of the buffer, so that its capacity can be reused
+	// for the next packet once this data has been written.
+	buf := w.buf
+
 	for len(w.buf) > 0 {
 		if err := w.pl.writePacket(w.buf); err != nil {
 			return 0, err
@@ -119,5 +157,7 @@ func (w *PktlineWriter) flush() (int, error) {
 		n = n + m
 	}
 
+	w.buf = buf[:0]
+
 	return n, nil
 }
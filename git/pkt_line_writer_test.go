@@ -2,6 +2,7 @@ package git
 
 import (
 	"bytes"
+	"io/ioutil"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -81,6 +82,52 @@ func TestPktlineWriterWritesMultiplePacketsGreaterThanMaxPacketLength(t *testing
 	assertPacketRead(t, pl, nil)
 }
 
+func TestPktlineWriterSyncWritesBufferedData(t *testing.T) {
+	var buf bytes.Buffer
+
+	w := NewPktlineWriter(&buf, 0)
+	assertWriterWrite(t, w, []byte("first"), len("first"))
+	assert.Equal(t, 0, buf.Len())
+
+	assert.Nil(t, w.Sync())
+	assert.Equal(t, "0009first", buf.String())
+
+	// syncing with nothing buffered writes nothing at all
+	assert.Nil(t, w.Sync())
+	assert.Equal(t, "0009first", buf.String())
+
+	assertWriterWrite(t, w, []byte("second"), len("second"))
+	assertWriterWrite(t, w, nil, 0)
+
+	pl := newPktline(&buf, nil)
+	assertPacketRead(t, pl, []byte("first"))
+	assertPacketRead(t, pl, []byte("second"))
+	assertPacketRead(t, pl, nil)
+}
+
+func TestPktlineWriterWritesWholePacketsAroundBufferedData(t *testing.T) {
+	big := make([]byte, 2*MaxPacketLength+10)
+	for i := range big {
+		big[i] = byte(i)
+	}
+
+	var buf bytes.Buffer
+
+	w := NewPktlineWriter(&buf, MaxPacketLength)
+	assertWriterWrite(t, w, big[:MaxPacketLength], MaxPacketLength)
+	assert.Nil(t, w.buf, "whole packets should not be buffered")
+	assertWriterWrite(t, w, big[MaxPacketLength:MaxPacketLength+5], 5)
+	assertWriterWrite(t, w, big[MaxPacketLength+5:], MaxPacketLength+5)
+	assert.Equal(t, MaxPacketLength, cap(w.buf))
+	assertWriterWrite(t, w, nil, 0)
+
+	pl := newPktline(&buf, nil)
+	assertPacketRead(t, pl, big[:MaxPacketLength])
+	assertPacketRead(t, pl, big[MaxPacketLength:2*MaxPacketLength])
+	assertPacketRead(t, pl, big[2*MaxPacketLength:])
+	assertPacketRead(t, pl, nil)
+}
+
 func TestPktlineWriterDoesntWrapItself(t *testing.T) {
 	itself := &PktlineWriter{}
 	nw := NewPktlineWriter(itself, 0)
@@ -108,3 +155,27 @@ func assertPacketRead(t *testing.T, pl *pktline, expected []byte) {
 	assert.Nil(t, err)
 	assert.Equal(t, expected, got)
 }
+
+// BenchmarkPktlineWriterSmudge measures the throughput of writing a smudged
+// object to Git through a new PktlineWriter, in chunks of the size io.Copy
+// uses, as filter-process does for each file.
+func BenchmarkPktlineWriterSmudge(b *testing.B) {
+	chunk := make([]byte, 32*1024)
+	const size = 8 * 1024 * 1024
+
+	b.SetBytes(size)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		w := NewPktlineWriter(ioutil.Discard, MaxPacketLength)
+		for written := 0; written < size; written += len(chunk) {
+			if _, err := w.Write(chunk); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := w.Flush(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
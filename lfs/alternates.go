@@ -0,0 +1,111 @@
+package lfs
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/git-lfs/git-lfs/logger"
+	"github.com/rubyist/tracerx"
+)
+
+var (
+	// alternatesWarned holds the object alternates which have already been
+	// warned about, so that each is only warned about once per process.
+	alternatesWarned   = make(map[string]bool)
+	alternatesWarnedMu sync.Mutex
+)
+
+// AlternatesPath returns the path of the file listing the object alternates
+// of the local media directory, one per line.
+func AlternatesPath() string {
+	return filepath.Join(LocalMediaDir(), "info", "alternates")
+}
+
+// ObjectAlternates returns the directories of the read-only object stores which
+// are searched for objects missing from the local media directory, before
+// downloading them. They are listed in the file at AlternatesPath(), where
+// relative paths are relative to the local media directory, and blank lines
+// and lines starting with "#" are ignored, followed by the values of
+// "lfs.alternates".
+func ObjectAlternates() []string {
+	var alternates []string
+
+	if f, err := os.Open(AlternatesPath()); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if len(line) == 0 || strings.HasPrefix(line, "#") {
+				continue
+			}
+
+			if !filepath.IsAbs(line) {
+				line = filepath.Join(LocalMediaDir(), line)
+			}
+			alternates = append(alternates, line)
+		}
+		f.Close()
+	}
+
+	return append(alternates, config.Config.ObjectAlternates()...)
+}
+
+// AlternateMediaPath returns the path of the object given by "oid" and "size"
+// in the first of the object alternates that has it, or false if none do.
+// Alternates which are missing or cannot be read are skipped with a warning.
+// The legacy object store, if there is one, is searched after them, as though
+// it were the last alternate; see LegacyMediaPath.
+func AlternateMediaPath(oid string, size int64) (string, bool) {
+	if len(oid) < 4 {
+		return "", false
+	}
+
+	for _, dir := range ObjectAlternates() {
+		if _, err := os.Stat(dir); err != nil {
+			warnAlternate(dir, err)
+			continue
+		}
+
+		path := filepath.Join(dir, oid[0:2], oid[2:4], oid)
+		stat, err := os.Stat(path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				warnAlternate(dir, err)
+			}
+			continue
+		}
+
+		if stat.Size() == size {
+			tracerx.Printf("found %s in object alternate %s", oid, dir)
+			return path, true
+		}
+	}
+	return LegacyMediaPath(oid, size)
+}
+
+// ObjectAvailable returns whether the object given by "oid" and "size" can be
+// read without downloading it, from either the local media directory, one of
+// the object alternates, or the legacy object store.
+func ObjectAvailable(oid string, size int64) bool {
+	if ObjectExistsOfSize(oid, size) {
+		return true
+	}
+	_, ok := AlternateMediaPath(oid, size)
+	return ok
+}
+
+func warnAlternate(dir string, err error) {
+	alternatesWarnedMu.Lock()
+	defer alternatesWarnedMu.Unlock()
+
+	if alternatesWarned[dir] {
+		return
+	}
+	alternatesWarned[dir] = true
+
+	logger.Log(logger.Warning, logger.Fields{"path": dir, "error": err},
+		"warning: skipping Git LFS object alternate %s: %s", dir, err)
+}
@@ -2,6 +2,7 @@ package lfs
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/git-lfs/git-lfs/git"
@@ -99,6 +100,46 @@ func (a *Attribute) set(key, value string, upgradeables []string, opt InstallOpt
 	return nil
 }
 
+// AttributeMismatch is a key of an Attribute which Git sees with a value other
+// than the one Install sets, which is empty if the key is not set at all.
+type AttributeMismatch struct {
+	Key      string
+	Expected string
+	Actual   string
+	// Upgradeable is whether the value is one an earlier version of Git
+	// LFS set, which Install replaces without being forced to.
+	Upgradeable bool
+}
+
+// Check returns the keys of this Attribute which Git, reading every scope of
+// its configuration, sees with a value other than the one Install sets, in the
+// order of their keys.
+func (a *Attribute) Check() []*AttributeMismatch {
+	keys := make([]string, 0, len(a.Properties))
+	for k := range a.Properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var mismatches []*AttributeMismatch
+	for _, k := range keys {
+		key := a.normalizeKey(k)
+		expected := a.Properties[k]
+		actual := git.Config.Find(key)
+		if actual == expected {
+			continue
+		}
+
+		mismatches = append(mismatches, &AttributeMismatch{
+			Key:         key,
+			Expected:    expected,
+			Actual:      actual,
+			Upgradeable: len(actual) > 0 && shouldReset(actual, a.Upgradeables[k]),
+		})
+	}
+	return mismatches
+}
+
 // Uninstall removes all properties in the path of this property.
 func (a *Attribute) Uninstall(opt InstallOptions) {
 	if opt.Local {
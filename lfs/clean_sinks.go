@@ -0,0 +1,66 @@
+package lfs
+
+import (
+	"io"
+
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/rubyist/tracerx"
+)
+
+// CleanSink is a destination, besides the object store, for the contents of a
+// file as it is cleaned, such as an external analyzer. Sinks are written to as
+// the contents are read and hashed, a chunk at a time, so that large files are
+// only read once.
+type CleanSink struct {
+	io.Writer
+
+	// Fatal is whether an error writing to the sink fails the clean. If it
+	// is false, the sink is dropped at its first error, and the contents are
+	// still cleaned and stored without it.
+	Fatal bool
+}
+
+// cleanSinkWriter writes to each of the sinks it holds which has not failed.
+type cleanSinkWriter struct {
+	sinks []*CleanSink
+}
+
+// newCleanSinkReader returns a reader of "r" which writes what is read from it
+// to "sinks", or "r" itself if there are none.
+func newCleanSinkReader(r io.Reader, sinks []*CleanSink) io.Reader {
+	live := make([]*CleanSink, 0, len(sinks))
+	for _, s := range sinks {
+		if s != nil && s.Writer != nil {
+			live = append(live, s)
+		}
+	}
+	if len(live) == 0 {
+		return r
+	}
+	return io.TeeReader(r, &cleanSinkWriter{sinks: live})
+}
+
+// Write writes "p" to each sink in turn. An error from a fatal sink is
+// returned straight away, failing the read of the contents, while a sink
+// which is not fatal is dropped when it fails, so that the others, and the
+// object store, are written to regardless.
+func (w *cleanSinkWriter) Write(p []byte) (int, error) {
+	for i := 0; i < len(w.sinks); {
+		s := w.sinks[i]
+		n, err := s.Write(p)
+		if err == nil && n < len(p) {
+			err = io.ErrShortWrite
+		}
+
+		if err == nil {
+			i++
+			continue
+		}
+		if s.Fatal {
+			return 0, errors.Wrap(err, "clean: could not write to sink")
+		}
+		tracerx.Printf("clean: dropping sink after it failed: %s", err)
+		w.sinks = append(w.sinks[:i], w.sinks[i+1:]...)
+	}
+	return len(p), nil
+}
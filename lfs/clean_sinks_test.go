@@ -0,0 +1,74 @@
+package lfs
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingWriter struct {
+	writes int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return 0, errors.New("sink is full")
+}
+
+func TestCleanWritesToSinks(t *testing.T) {
+	store := &memoryObjectStore{objects: make(map[string][]byte)}
+	contents := bytes.Repeat([]byte("a"), 2048)
+
+	var first, second bytes.Buffer
+	ptr, err := Clean(bytes.NewReader(contents), store,
+		&CleanSink{Writer: &first}, &CleanSink{Writer: &second, Fatal: true})
+	require.Nil(t, err)
+
+	assert.Equal(t, "b2a3a502fdfc34f4e3edfa94b7f3109cd972d87a4fec63ab21a6673379ccf7ad", ptr.Oid)
+	assert.Equal(t, contents, store.objects[ptr.Oid])
+	assert.Equal(t, contents, first.Bytes())
+	assert.Equal(t, contents, second.Bytes())
+}
+
+func TestCleanDropsFailedSink(t *testing.T) {
+	store := &memoryObjectStore{objects: make(map[string][]byte)}
+	contents := bytes.Repeat([]byte("a"), 2048)
+
+	failing := &failingWriter{}
+	var buf bytes.Buffer
+	ptr, err := Clean(bytes.NewReader(contents), store,
+		&CleanSink{Writer: failing}, &CleanSink{Writer: &buf})
+	require.Nil(t, err)
+
+	assert.Equal(t, contents, store.objects[ptr.Oid])
+	assert.Equal(t, contents, buf.Bytes())
+	assert.Equal(t, 1, failing.writes)
+}
+
+func TestCleanFailsWithFatalSink(t *testing.T) {
+	store := &memoryObjectStore{objects: make(map[string][]byte)}
+
+	ptr, err := Clean(bytes.NewReader([]byte("contents")), store,
+		&CleanSink{Writer: &failingWriter{}, Fatal: true})
+	assert.Nil(t, ptr)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "sink is full")
+	}
+	assert.Empty(t, store.objects)
+}
+
+func TestCleanPointerSkipsSinks(t *testing.T) {
+	store := &memoryObjectStore{objects: make(map[string][]byte)}
+
+	var ptrbuf bytes.Buffer
+	_, err := EncodePointer(&ptrbuf, NewPointer("d1b2a59fbea7e20077af9f91b27e95e865061b270be03ff539ab3b73587882e8", 8, nil))
+	require.Nil(t, err)
+
+	var buf bytes.Buffer
+	_, err = Clean(bytes.NewReader(ptrbuf.Bytes()), store, &CleanSink{Writer: &buf})
+	assert.NotNil(t, err)
+	assert.Empty(t, buf.Bytes())
+}
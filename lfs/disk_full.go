@@ -0,0 +1,19 @@
+// +build !windows
+
+package lfs
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/git-lfs/git-lfs/errors"
+)
+
+// isDiskFullError returns whether "err" was caused by running out of space on
+// the disk being written to.
+func isDiskFullError(err error) bool {
+	if pathErr, ok := errors.Cause(err).(*os.PathError); ok {
+		return pathErr.Err == syscall.ENOSPC
+	}
+	return false
+}
@@ -0,0 +1,21 @@
+// +build !windows
+
+package lfs
+
+import (
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsDiskFullError(t *testing.T) {
+	full := &os.PathError{Op: "write", Path: "a.dat", Err: syscall.ENOSPC}
+
+	assert.True(t, isDiskFullError(full))
+	assert.True(t, isDiskFullError(errors.Wrap(full, "smudge")))
+	assert.False(t, isDiskFullError(&os.PathError{Op: "write", Path: "a.dat", Err: syscall.EACCES}))
+	assert.False(t, isDiskFullError(errors.New("some error")))
+}
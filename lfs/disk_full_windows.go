@@ -0,0 +1,24 @@
+// +build windows
+
+package lfs
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/git-lfs/git-lfs/errors"
+)
+
+const (
+	errorHandleDiskFull syscall.Errno = 39
+	errorDiskFull       syscall.Errno = 112
+)
+
+// isDiskFullError returns whether "err" was caused by running out of space on
+// the disk being written to.
+func isDiskFullError(err error) bool {
+	if pathErr, ok := errors.Cause(err).(*os.PathError); ok {
+		return pathErr.Err == errorDiskFull || pathErr.Err == errorHandleDiskFull
+	}
+	return false
+}
@@ -0,0 +1,276 @@
+package lfs
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/git-lfs/git-lfs/localstorage"
+	"github.com/git-lfs/git-lfs/tools"
+)
+
+// DuplicateHashes are the algorithms FindDuplicateObjects may hash objects
+// with, by name.
+var DuplicateHashes = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// DuplicateObjects are objects in the local object store which are stored
+// under different OIDs, but whose contents are the same.
+type DuplicateObjects struct {
+	// Size is the size of each of the objects, in bytes.
+	Size int64 `json:"size"`
+	// Hash is the hash of their contents, as FindDuplicateObjects was asked
+	// to compute it.
+	Hash string `json:"hash"`
+	// Oids are the OIDs the contents are stored under, in order.
+	Oids []string `json:"oids"`
+	// Linked is true if the objects are all links to the same file, such
+	// as LinkDuplicateObjects makes, so that they take up the space of
+	// only one.
+	Linked bool `json:"linked"`
+}
+
+// Wasted returns how many bytes the objects take up beyond those of a single
+// copy of their contents, unless they are linked together.
+func (d *DuplicateObjects) Wasted() int64 {
+	if d.Linked {
+		return 0
+	}
+	return d.Size * int64(len(d.Oids)-1)
+}
+
+// FindDuplicateObjects finds which of "objects", at the paths "objectPath"
+// gives, have the same contents, and returns them in groups, ordered by the
+// space they waste, most first.
+// Only objects which are the same size as another are read, hashing them with
+// "algorithm", one of DuplicateHashes, rather than trusting their OIDs, by as
+// many as "workers" at once. Objects which are already links to the same file
+// are still reported, as Linked.
+func FindDuplicateObjects(objects []localstorage.Object, algorithm string, workers int, objectPath func(oid string) string) ([]*DuplicateObjects, error) {
+	newHash, ok := DuplicateHashes[algorithm]
+	if !ok {
+		return nil, errors.Errorf("unknown hash algorithm %q", algorithm)
+	}
+
+	bySize := make(map[int64][]string)
+	for _, o := range objects {
+		if o.Size > 0 {
+			bySize[o.Size] = append(bySize[o.Size], o.Oid)
+		}
+	}
+
+	type hashed struct {
+		oid  string
+		size int64
+		sum  string
+		err  error
+	}
+
+	candidates := make(chan localstorage.Object)
+	results := make(chan hashed)
+
+	if workers < 1 {
+		workers = 1
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for o := range candidates {
+				sum, err := hashObjectFile(objectPath(o.Oid), newHash())
+				results <- hashed{oid: o.Oid, size: o.Size, sum: sum, err: err}
+			}
+		}()
+	}
+
+	go func() {
+		for size, oids := range bySize {
+			if len(oids) < 2 {
+				continue
+			}
+			for _, oid := range oids {
+				candidates <- localstorage.Object{Oid: oid, Size: size}
+			}
+		}
+		close(candidates)
+		wg.Wait()
+		close(results)
+	}()
+
+	groups := make(map[string]*DuplicateObjects)
+	var firstErr error
+	for r := range results {
+		if r.err != nil {
+			if firstErr == nil {
+				firstErr = errors.Wrapf(r.err, "could not hash object %s", r.oid)
+			}
+			continue
+		}
+
+		key := fmt.Sprintf("%d:%s", r.size, r.sum)
+		g, ok := groups[key]
+		if !ok {
+			g = &DuplicateObjects{Size: r.size, Hash: r.sum}
+			groups[key] = g
+		}
+		g.Oids = append(g.Oids, r.oid)
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	dups := make([]*DuplicateObjects, 0, len(groups))
+	for _, g := range groups {
+		if len(g.Oids) < 2 {
+			continue
+		}
+		sort.Strings(g.Oids)
+		g.Linked = allSameFile(g.Oids, objectPath)
+		dups = append(dups, g)
+	}
+	sort.Slice(dups, func(i, j int) bool {
+		if dups[i].Wasted() != dups[j].Wasted() {
+			return dups[i].Wasted() > dups[j].Wasted()
+		}
+		return dups[i].Oids[0] < dups[j].Oids[0]
+	})
+	return dups, nil
+}
+
+// LinkDuplicateObjects replaces each object in "d", other than the first, with
+// a hard link to the first, at the paths "objectPath" gives, so that they take
+// up the space of only one. Each is compared byte for byte with the first
+// before it is replaced, and is left as it is if they differ, or if it is
+// already a link to it. An object is only ever replaced by renaming a link
+// over it, so that it is never missing.
+func LinkDuplicateObjects(d *DuplicateObjects, objectPath func(oid string) string) error {
+	if len(d.Oids) < 2 {
+		return nil
+	}
+
+	first := objectPath(d.Oids[0])
+	firstStat, err := os.Stat(first)
+	if err != nil {
+		return err
+	}
+
+	for _, oid := range d.Oids[1:] {
+		path := objectPath(oid)
+		stat, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if os.SameFile(firstStat, stat) {
+			continue
+		}
+
+		same, err := sameFileContents(first, path)
+		if err != nil {
+			return err
+		}
+		if !same {
+			return errors.Errorf("object %s differs from %s, and was not linked to it", oid, d.Oids[0])
+		}
+
+		tmp := filepath.Join(filepath.Dir(path), fmt.Sprintf(".%s.lfs-link-%d", oid, os.Getpid()))
+		if err := os.Link(first, tmp); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+	}
+
+	d.Linked = true
+	return nil
+}
+
+// allSameFile returns whether the objects "oids", at the paths "objectPath"
+// gives, are all links to the same file.
+func allSameFile(oids []string, objectPath func(oid string) string) bool {
+	first, err := os.Stat(objectPath(oids[0]))
+	if err != nil {
+		return false
+	}
+	for _, oid := range oids[1:] {
+		stat, err := os.Stat(objectPath(oid))
+		if err != nil || !os.SameFile(first, stat) {
+			return false
+		}
+	}
+	return true
+}
+
+// hashObjectFile returns the hex encoded hash, computed by "h", of the contents
+// of the file at "path".
+func hashObjectFile(path string, h hash.Hash) (string, error) {
+	release := tools.AcquireFiles(1)
+	defer release()
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// sameFileContents returns whether the files at "a" and "b" have exactly the
+// same contents.
+func sameFileContents(a, b string) (bool, error) {
+	release := tools.AcquireFiles(2)
+	defer release()
+
+	fa, err := os.Open(a)
+	if err != nil {
+		return false, err
+	}
+	defer fa.Close()
+
+	fb, err := os.Open(b)
+	if err != nil {
+		return false, err
+	}
+	defer fb.Close()
+
+	bufA := make([]byte, 32*1024)
+	bufB := make([]byte, 32*1024)
+	for {
+		na, errA := io.ReadFull(fa, bufA)
+		nb, errB := io.ReadFull(fb, bufB)
+		if na != nb || !bytes.Equal(bufA[:na], bufB[:nb]) {
+			return false, nil
+		}
+
+		doneA := errA == io.EOF || errA == io.ErrUnexpectedEOF
+		doneB := errB == io.EOF || errB == io.ErrUnexpectedEOF
+		if errA != nil && !doneA {
+			return false, errA
+		}
+		if errB != nil && !doneB {
+			return false, errB
+		}
+		if doneA || doneB {
+			return doneA && doneB, nil
+		}
+	}
+}
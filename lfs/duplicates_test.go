@@ -0,0 +1,100 @@
+package lfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/git-lfs/git-lfs/localstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeDuplicateTestObjects(t *testing.T, contents map[string]string) (string, []localstorage.Object, func(string) string) {
+	dir, err := ioutil.TempDir("", "duplicates")
+	require.Nil(t, err)
+
+	var objects []localstorage.Object
+	for oid, data := range contents {
+		require.Nil(t, ioutil.WriteFile(filepath.Join(dir, oid), []byte(data), 0644))
+		objects = append(objects, localstorage.Object{Oid: oid, Size: int64(len(data))})
+	}
+	return dir, objects, func(oid string) string { return filepath.Join(dir, oid) }
+}
+
+func TestFindDuplicateObjects(t *testing.T) {
+	dir, objects, path := writeDuplicateTestObjects(t, map[string]string{
+		"a": "same contents",
+		"b": "same contents",
+		"c": "diff contents",
+		"d": "short",
+		"e": "short",
+		"f": "other",
+	})
+	defer os.RemoveAll(dir)
+
+	dups, err := FindDuplicateObjects(objects, "sha256", 2, path)
+	require.Nil(t, err)
+	require.Len(t, dups, 2)
+
+	assert.Equal(t, []string{"a", "b"}, dups[0].Oids)
+	assert.Equal(t, int64(13), dups[0].Size)
+	assert.Equal(t, int64(13), dups[0].Wasted())
+	assert.False(t, dups[0].Linked)
+
+	assert.Equal(t, []string{"d", "e"}, dups[1].Oids)
+	assert.Equal(t, int64(5), dups[1].Wasted())
+}
+
+func TestFindDuplicateObjectsUnknownHash(t *testing.T) {
+	_, err := FindDuplicateObjects(nil, "md5", 1, nil)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, `unknown hash algorithm "md5"`, err.Error())
+	}
+}
+
+func TestLinkDuplicateObjects(t *testing.T) {
+	dir, objects, path := writeDuplicateTestObjects(t, map[string]string{
+		"a": "same contents",
+		"b": "same contents",
+		"c": "same contents",
+	})
+	defer os.RemoveAll(dir)
+
+	dups, err := FindDuplicateObjects(objects, "sha1", 1, path)
+	require.Nil(t, err)
+	require.Len(t, dups, 1)
+
+	require.Nil(t, LinkDuplicateObjects(dups[0], path))
+	assert.True(t, dups[0].Linked)
+	assert.Equal(t, int64(0), dups[0].Wasted())
+
+	first, err := os.Stat(path("a"))
+	require.Nil(t, err)
+	for _, oid := range []string{"b", "c"} {
+		stat, err := os.Stat(path(oid))
+		require.Nil(t, err)
+		assert.True(t, os.SameFile(first, stat), oid)
+	}
+
+	dups, err = FindDuplicateObjects(objects, "sha1", 1, path)
+	require.Nil(t, err)
+	require.Len(t, dups, 1)
+	assert.True(t, dups[0].Linked)
+}
+
+func TestLinkDuplicateObjectsRefusesDifferentContents(t *testing.T) {
+	dir, _, path := writeDuplicateTestObjects(t, map[string]string{
+		"a": "same contents",
+		"b": "diff contents",
+	})
+	defer os.RemoveAll(dir)
+
+	err := LinkDuplicateObjects(&DuplicateObjects{Size: 13, Oids: []string{"a", "b"}}, path)
+	assert.NotNil(t, err)
+
+	data, err := ioutil.ReadFile(path("b"))
+	require.Nil(t, err)
+	assert.Equal(t, "diff contents", string(data))
+}
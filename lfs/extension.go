@@ -2,7 +2,6 @@ package lfs
 
 import (
 	"bytes"
-	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"hash"
@@ -12,6 +11,7 @@ import (
 	"strings"
 
 	"github.com/git-lfs/git-lfs/config"
+	"github.com/git-lfs/git-lfs/tools"
 )
 
 type pipeRequest struct {
@@ -80,7 +80,7 @@ func pipeExtensions(request *pipeRequest) (response pipeResponse, err error) {
 		extcmds = append(extcmds, ec)
 	}
 
-	hasher := sha256.New()
+	hasher := tools.NewLfsContentHash()
 	pipeReader, pipeWriter := io.Pipe()
 	multiWriter := io.MultiWriter(hasher, pipeWriter)
 
@@ -96,7 +96,7 @@ func pipeExtensions(request *pipeRequest) (response pipeResponse, err error) {
 
 	last := len(extcmds) - 1
 	for i, ec := range extcmds {
-		ec.hasher = sha256.New()
+		ec.hasher = tools.NewLfsContentHash()
 
 		if i == last {
 			ec.cmd.Stdout = io.MultiWriter(ec.hasher, output)
@@ -0,0 +1,124 @@
+package lfs
+
+import (
+	"io"
+	"os"
+	"sync"
+
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/git-lfs/git-lfs/tq"
+	"github.com/rubyist/tracerx"
+)
+
+// FetchObject makes the object "oid", of "size" bytes, available locally,
+// downloading it from "remote" with "manifest" unless the local object store,
+// a reference repository or an object alternate already has it, and returns
+// the path it can be read from. Nothing but the object itself is consulted, so
+// it needs no refs or paths.
+func FetchObject(manifest *tq.Manifest, remote, oid string, size int64) (string, error) {
+	mediafile, err := LocalMediaPath(oid)
+	if err != nil {
+		return "", err
+	}
+
+	LinkOrCopyFromReference(oid, size)
+	if ObjectExistsOfSize(oid, size) {
+		return mediafile, nil
+	}
+	if altfile, ok := AlternateMediaPath(oid, size); ok {
+		return altfile, nil
+	}
+
+	var options []tq.Option
+	if config.Config.IntegrityManifest() {
+		options = append(options, tq.WithCompletionCallback(func(t *tq.Transfer) {
+			if err := RecordIntegrity(t.Oid, t.Size); err != nil {
+				tracerx.Printf("could not record %s in the integrity manifest: %s", t.Oid, err)
+			}
+		}))
+	}
+
+	if err := CheckStoreLayout(); err != nil {
+		return "", err
+	}
+
+	q := tq.NewTransferQueue(tq.Download, manifest, remote, options...)
+	q.Add(oid, mediafile, oid, size)
+	q.Wait()
+
+	if errs := q.Errors(); len(errs) > 0 {
+		return "", errors.Wrapf(errs[0], "Error downloading %s", oid)
+	}
+	if !ObjectExistsOfSize(oid, size) {
+		return "", errors.Errorf("Object %s (%d bytes) was not found on %q", oid, size, remote)
+	}
+	return mediafile, nil
+}
+
+// ObjectFetcher downloads objects one at a time, as they are first needed, for
+// a layer which presents pointers as files whose contents are only fetched when
+// they are read, such as a FUSE filesystem. The layer calls Fetch or Open when a
+// file is first opened or read, rather than downloading every object up front.
+// It is safe to use from several goroutines at once, and an object asked for by
+// several of them at once is only downloaded once.
+type ObjectFetcher struct {
+	manifest *tq.Manifest
+	remote   string
+
+	mu       sync.Mutex
+	inflight map[string]*objectFetch
+}
+
+type objectFetch struct {
+	done chan struct{}
+	path string
+	err  error
+}
+
+// NewObjectFetcher returns an ObjectFetcher which downloads objects from
+// "remote" with "manifest".
+func NewObjectFetcher(manifest *tq.Manifest, remote string) *ObjectFetcher {
+	return &ObjectFetcher{
+		manifest: manifest,
+		remote:   remote,
+		inflight: make(map[string]*objectFetch),
+	}
+}
+
+// Fetch is FetchObject, but waits for a download of the same object which has
+// already been started by another goroutine, rather than starting another.
+func (f *ObjectFetcher) Fetch(oid string, size int64) (string, error) {
+	f.mu.Lock()
+	if fetch, ok := f.inflight[oid]; ok {
+		f.mu.Unlock()
+		<-fetch.done
+		return fetch.path, fetch.err
+	}
+
+	fetch := &objectFetch{done: make(chan struct{})}
+	f.inflight[oid] = fetch
+	f.mu.Unlock()
+
+	fetch.path, fetch.err = FetchObject(f.manifest, f.remote, oid, size)
+
+	f.mu.Lock()
+	delete(f.inflight, oid)
+	f.mu.Unlock()
+	close(fetch.done)
+
+	return fetch.path, fetch.err
+}
+
+// Open fetches the object "ptr" points to, as Fetch does, and opens it for
+// reading, from the ObjectStore, or from the object alternate which has it.
+func (f *ObjectFetcher) Open(ptr *Pointer) (io.ReadCloser, error) {
+	path, err := f.Fetch(ptr.Oid, ptr.Size)
+	if err != nil {
+		return nil, err
+	}
+	if ObjectExistsOfSize(ptr.Oid, ptr.Size) {
+		return ObjectStorage().Get(ptr.Oid)
+	}
+	return os.Open(path)
+}
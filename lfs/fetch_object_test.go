@@ -0,0 +1,44 @@
+package lfs
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObjectFetcherFindsLocalObjects(t *testing.T) {
+	oid := "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393"
+	store := &memoryObjectStore{
+		dir:     "store",
+		objects: map[string][]byte{oid: []byte("contents")},
+	}
+	SetObjectStore(store)
+	defer SetObjectStore(nil)
+
+	// Nothing is downloaded, so there is no need for a manifest.
+	f := NewObjectFetcher(nil, "origin")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			path, err := f.Fetch(oid, 8)
+			assert.Nil(t, err)
+			assert.Equal(t, filepath.Join("store", oid), path)
+		}()
+	}
+	wg.Wait()
+
+	r, err := f.Open(&Pointer{Oid: oid, Size: 8})
+	require.Nil(t, err)
+	defer r.Close()
+
+	by, err := ioutil.ReadAll(r)
+	require.Nil(t, err)
+	assert.Equal(t, "contents", string(by))
+}
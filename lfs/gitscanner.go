@@ -27,6 +27,10 @@ type GitScanner struct {
 	remote             string
 	skippedRefs        []string
 
+	// FoundInvalidPointer, if set, is called for each blob found while
+	// scanning refs which looks like a pointer, but is not a valid one.
+	FoundInvalidPointer GitScannerFoundInvalidPointer
+
 	closed  bool
 	started time.Time
 	mu      sync.Mutex
@@ -34,6 +38,7 @@ type GitScanner struct {
 
 type GitScannerFoundPointer func(*WrappedPointer, error)
 type GitScannerFoundLockable func(filename string)
+type GitScannerFoundInvalidPointer func(*InvalidPointer)
 
 type GitScannerSet interface {
 	Contains(string) bool
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/git-lfs/git-lfs/errors"
 	"github.com/git-lfs/git-lfs/git"
 )
 
@@ -14,9 +15,10 @@ import (
 // pointer. Git Blob SHA1s are read from the sha1Ch channel and fed to STDIN.
 // Results are parsed from STDOUT, and any eligible LFS pointers are sent to
 // pointerCh. If a Git Blob is not an LFS pointer, check the lockableSet to see
-// if that blob is for a locked file. Any errors are sent to errCh. An error is
-// returned if the 'git cat-file' command fails to start.
-func runCatFileBatch(pointerCh chan *WrappedPointer, lockableCh chan string, lockableSet *lockableNameSet, revs *StringChannelWrapper, errCh chan error) error {
+// if that blob is for a locked file. Blobs which look like LFS pointers, but are
+// not valid ones, are sent to invalidCh, unless it is nil. Any errors are sent
+// to errCh. An error is returned if the 'git cat-file' command fails to start.
+func runCatFileBatch(pointerCh chan *WrappedPointer, lockableCh chan string, lockableSet *lockableNameSet, invalidCh chan *InvalidPointer, revs *StringChannelWrapper, errCh chan error) error {
 	scanner, err := NewPointerScanner()
 	if err != nil {
 		scanner.Close()
@@ -30,11 +32,17 @@ func runCatFileBatch(pointerCh chan *WrappedPointer, lockableCh chan string, loc
 
 			if err := scanner.Err(); err != nil {
 				errCh <- err
-			} else if p := scanner.Pointer(); p != nil {
-				pointerCh <- p
-			} else if b := scanner.BlobSHA(); len(b) == 40 {
-				if name, ok := lockableSet.Check(b); ok {
-					lockableCh <- name
+			} else {
+				if invalid := scanner.Invalid(); invalid != nil && invalidCh != nil {
+					invalidCh <- &InvalidPointer{Sha1: scanner.BlobSHA(), Err: invalid}
+				}
+
+				if p := scanner.Pointer(); p != nil {
+					pointerCh <- p
+				} else if b := scanner.BlobSHA(); len(b) == 40 {
+					if name, ok := lockableSet.Check(b); ok {
+						lockableCh <- name
+					}
 				}
 			}
 
@@ -54,6 +62,9 @@ func runCatFileBatch(pointerCh chan *WrappedPointer, lockableCh chan string, loc
 		close(pointerCh)
 		close(errCh)
 		close(lockableCh)
+		if invalidCh != nil {
+			close(invalidCh)
+		}
 	}()
 
 	return nil
@@ -65,6 +76,7 @@ type PointerScanner struct {
 	blobSha     string
 	contentsSha string
 	pointer     *WrappedPointer
+	invalid     error
 	err         error
 }
 
@@ -89,18 +101,26 @@ func (s *PointerScanner) Pointer() *WrappedPointer {
 	return s.pointer
 }
 
+// Invalid returns why the blob last scanned is not a valid pointer, although it
+// looks like one, or nil if it does not look like one, or is valid. A pointer
+// with an implausible size is still returned by Pointer().
+func (s *PointerScanner) Invalid() error {
+	return s.invalid
+}
+
 func (s *PointerScanner) Err() error {
 	return s.err
 }
 
 func (s *PointerScanner) Scan(sha string) bool {
-	s.pointer, s.err = nil, nil
+	s.pointer, s.invalid, s.err = nil, nil, nil
 	s.blobSha, s.contentsSha = "", ""
 
-	b, c, p, err := s.next(sha)
+	b, c, p, invalid, err := s.next(sha)
 	s.blobSha = b
 	s.contentsSha = c
 	s.pointer = p
+	s.invalid = invalid
 
 	if err != nil {
 		if err != io.EOF {
@@ -116,12 +136,12 @@ func (s *PointerScanner) Close() error {
 	return s.scanner.Close()
 }
 
-func (s *PointerScanner) next(blob string) (string, string, *WrappedPointer, error) {
+func (s *PointerScanner) next(blob string) (string, string, *WrappedPointer, error, error) {
 	if !s.scanner.Scan(blob) {
 		if err := s.scanner.Err(); err != nil {
-			return "", "", nil, err
+			return "", "", nil, nil, err
 		}
-		return "", "", nil, io.EOF
+		return "", "", nil, nil, io.EOF
 	}
 
 	blobSha := s.scanner.Sha1()
@@ -129,27 +149,35 @@ func (s *PointerScanner) next(blob string) (string, string, *WrappedPointer, err
 
 	sha := sha256.New()
 
+	cutoff := int64(blobSizeCutoff())
+
 	var buf *bytes.Buffer
 	var to io.Writer = sha
-	if size <= blobSizeCutoff {
+	if size <= cutoff {
 		buf = bytes.NewBuffer(make([]byte, 0, size))
 		to = io.MultiWriter(to, buf)
 	}
 
 	read, err := io.CopyN(to, s.scanner.Contents(), int64(size))
 	if err != nil {
-		return blobSha, "", nil, err
+		return blobSha, "", nil, nil, err
 	}
 
 	if int64(size) != read {
-		return blobSha, "", nil, fmt.Errorf("expected %d bytes, read %d bytes", size, read)
+		return blobSha, "", nil, nil, fmt.Errorf("expected %d bytes, read %d bytes", size, read)
 	}
 
 	var pointer *WrappedPointer
 	var contentsSha string
+	var invalid error
+
+	if size <= cutoff {
+		p, perr := validatePointer(buf.Bytes())
+		if perr != nil && !errors.IsNotAPointerError(perr) {
+			invalid = perr
+		}
 
-	if size <= blobSizeCutoff {
-		if p, err := DecodePointer(bytes.NewReader(buf.Bytes())); err != nil {
+		if p == nil {
 			contentsSha = fmt.Sprintf("%x", sha.Sum(nil))
 		} else {
 			pointer = &WrappedPointer{
@@ -162,5 +190,5 @@ func (s *PointerScanner) next(blob string) (string, string, *WrappedPointer, err
 		contentsSha = fmt.Sprintf("%x", sha.Sum(nil))
 	}
 
-	return blobSha, contentsSha, pointer, err
+	return blobSha, contentsSha, pointer, invalid, err
 }
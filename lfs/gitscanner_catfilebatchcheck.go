@@ -8,7 +8,7 @@ import (
 )
 
 // runCatFileBatchCheck uses 'git cat-file --batch-check' to get the type and
-// size of a git object. Any object that isn't of type blob and under the
+// size of a git object. Any object that isn't of type blob and within the
 // blobSizeCutoff will be ignored, unless it's a locked file. revs is a channel
 // over which strings containing git sha1s will be sent. It returns a channel
 // from which sha1 strings can be read.
@@ -19,7 +19,7 @@ func runCatFileBatchCheck(smallRevCh chan string, lockableCh chan string, lockab
 	}
 
 	go func() {
-		scanner := &catFileBatchCheckScanner{s: bufio.NewScanner(cmd.Stdout), limit: blobSizeCutoff}
+		scanner := &catFileBatchCheckScanner{s: bufio.NewScanner(cmd.Stdout), limit: blobSizeCutoff()}
 		for r := range revs.Results {
 			cmd.Stdin.Write([]byte(r + "\n"))
 			hasNext := scanner.Scan()
@@ -104,7 +104,7 @@ func (s *catFileBatchCheckScanner) next() (string, string, bool) {
 	}
 
 	blobSha := line[0:40]
-	if size >= s.limit {
+	if size > s.limit {
 		return "", blobSha, hasNext
 	}
 
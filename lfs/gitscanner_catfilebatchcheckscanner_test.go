@@ -16,6 +16,8 @@ func TestCatFileBatchCheckScannerWithValidOutput(t *testing.T) {
 		"0000000000000000000000000000000000000002 blob 123",
 		"0000000000000000000000000000000000000003 blob 1 0",
 		"0000000000000000000000000000000000000004 blob 123456789",
+		"0000000000000000000000000000000000000005 blob 1024",
+		"0000000000000000000000000000000000000006 blob 1025",
 	}
 	r := strings.NewReader(strings.Join(lines, "\n"))
 	s := &catFileBatchCheckScanner{
@@ -29,6 +31,8 @@ func TestCatFileBatchCheckScannerWithValidOutput(t *testing.T) {
 	assertNextOID(t, s, "0000000000000000000000000000000000000002", "")
 	assertNextOID(t, s, "", "")
 	assertNextOID(t, s, "", "0000000000000000000000000000000000000004")
+	assertNextOID(t, s, "0000000000000000000000000000000000000005", "")
+	assertNextOID(t, s, "", "0000000000000000000000000000000000000006")
 	assertScannerDone(t, s)
 	assert.Equal(t, "", s.LFSBlobOID())
 	assert.Equal(t, "", s.GitBlobOID())
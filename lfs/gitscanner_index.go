@@ -65,7 +65,7 @@ func scanIndex(cb GitScannerFoundPointer, ref string) error {
 
 	ch := make(chan gitscannerResult, chanBufSize)
 
-	barePointerCh, _, err := catFileBatch(smallShas, nil)
+	barePointerCh, _, err := catFileBatch(smallShas, nil, nil)
 	if err != nil {
 		return err
 	}
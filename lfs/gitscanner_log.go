@@ -86,6 +86,34 @@ func parseScannerLogOutput(cb GitScannerFoundPointer, direction LogDiffDirection
 	}
 }
 
+// ScanHistory calls "cb" with each pointer that is added to a path by a commit
+// reachable from any ref, along with the SHA of that commit. The same pointer
+// is given once for each commit which adds it.
+func ScanHistory(cb func(commit string, p *WrappedPointer, err error)) error {
+	logArgs := []string{"log", "--all"}
+	// Add standard search args to find lfs references
+	logArgs = append(logArgs, logLfsSearchArgs...)
+
+	cmd, err := startCommand("git", logArgs...)
+	if err != nil {
+		return err
+	}
+	cmd.Stdin.Close()
+
+	scanner := newLogScanner(LogDiffAdditions, cmd.Stdout)
+	for scanner.Scan() {
+		if p := scanner.Pointer(); p != nil {
+			cb(scanner.Commit(), p, nil)
+		}
+	}
+
+	stderr, _ := ioutil.ReadAll(cmd.Stderr)
+	if err := cmd.Wait(); err != nil {
+		cb("", nil, fmt.Errorf("Error in git log: %v %v", err, string(stderr)))
+	}
+	return nil
+}
+
 // logPreviousVersions scans history for all previous versions of LFS pointers
 // from 'since' up to (but not including) the final state at ref
 func logPreviousSHAs(cb GitScannerFoundPointer, ref string, since time.Time) error {
@@ -129,6 +157,10 @@ type logScanner struct {
 	s       *bufio.Scanner
 	dir     LogDiffDirection
 	pointer *WrappedPointer
+	// commit is the SHA of the commit which pointer is from, and
+	// currentCommit that of the commit being read.
+	commit        string
+	currentCommit string
 
 	pointerData         *bytes.Buffer
 	currentFilename     string
@@ -162,6 +194,12 @@ func (s *logScanner) Pointer() *WrappedPointer {
 	return s.pointer
 }
 
+// Commit returns the SHA of the commit which the last pointer returned by
+// Pointer() is from.
+func (s *logScanner) Commit() string {
+	return s.commit
+}
+
 func (s *logScanner) Err() error {
 	return s.s.Err()
 }
@@ -181,6 +219,7 @@ func (s *logScanner) finishLastPointer() *WrappedPointer {
 
 	p, err := DecodePointer(s.pointerData)
 	s.pointerData.Reset()
+	s.commit = s.currentCommit
 
 	if err == nil {
 		return &WrappedPointer{Name: s.currentFilename, Pointer: p}
@@ -211,9 +250,12 @@ func (s *logScanner) scan() (*WrappedPointer, bool) {
 		line := s.s.Text()
 
 		if match := s.commitHeaderRegex.FindStringSubmatch(line); match != nil {
-			// Currently we're not pulling out commit groupings, but could if we wanted
-			// This just acts as a delimiter for finishing a multiline pointer
-			if p := s.finishLastPointer(); p != nil {
+			// This acts as a delimiter for finishing a multiline pointer,
+			// which belongs to the commit before this one
+			p := s.finishLastPointer()
+			s.currentCommit = match[1]
+
+			if p != nil {
 				return p, true
 			}
 		} else if match := s.fileHeaderRegex.FindStringSubmatch(line); match != nil {
@@ -83,6 +83,38 @@ func TestPointerScannerWithLargeBlobs(t *testing.T) {
 	assert.Nil(t, scanner.Pointer())
 }
 
+func TestPointerScannerWithInvalidPointers(t *testing.T) {
+	oid := "e71eefd918ea175b8f362611f981f648dbf9888ff74865077cb4c9077728f350"
+	blobs := []string{
+		"version https://git-lfs.github.com/spec/v1\noid sha256:" + oid + "\nsize 0\n",
+		"version https://git-lfs.github.com/spec/v1\noid sha256:abc\nsize 123\n",
+		"version https://git-lfs.github.com/spec/v1\noid sha256:" + oid + "\nsize -1\n",
+		"a text file, which mentions git-lfs\n",
+	}
+
+	fake := bytes.NewBuffer(nil)
+	for _, b := range blobs {
+		writeFakeBuffer(t, fake, []byte(b), len(b))
+	}
+
+	scanner := &PointerScanner{
+		scanner: git.NewObjectScannerFrom(fake),
+	}
+
+	// An implausible pointer is still a pointer.
+	assertNextPointer(t, scanner, oid)
+	assert.EqualError(t, scanner.Invalid(), "Invalid size: 0")
+
+	assertNextEmptyPointer(t, scanner)
+	assert.EqualError(t, scanner.Invalid(), "Invalid Oid: abc")
+
+	assertNextEmptyPointer(t, scanner)
+	assert.EqualError(t, scanner.Invalid(), `Invalid size: "-1"`)
+
+	assertNextEmptyPointer(t, scanner)
+	assert.Nil(t, scanner.Invalid())
+}
+
 func assertNextPointer(t *testing.T, scanner *PointerScanner, oid string) {
 	assert.True(t, scanner.Scan(""))
 	assert.Nil(t, scanner.Err())
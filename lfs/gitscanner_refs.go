@@ -63,7 +63,22 @@ func scanRefsToChan(scanner *GitScanner, pointerCb GitScannerFoundPointer, refLe
 		}
 	}(lockableCb, batchLockableCh)
 
-	pointers, checkLockableCh, err := catFileBatch(smallShas, lockableSet)
+	var invalidCh chan *InvalidPointer
+	invalids := make(chan []*InvalidPointer, 1)
+	if scanner.FoundInvalidPointer != nil {
+		invalidCh = make(chan *InvalidPointer, chanBufSize)
+		go func() {
+			var all []*InvalidPointer
+			for p := range invalidCh {
+				all = append(all, p)
+			}
+			invalids <- all
+		}()
+	} else {
+		close(invalids)
+	}
+
+	pointers, checkLockableCh, err := catFileBatch(smallShas, lockableSet, invalidCh)
 	if err != nil {
 		return err
 	}
@@ -79,6 +94,15 @@ func scanRefsToChan(scanner *GitScanner, pointerCb GitScannerFoundPointer, refLe
 		lockableCb(lockableName)
 	}
 
+	// Invalid pointers are reported once the scan is done, so that the
+	// callback is never called at the same time as any other.
+	for _, p := range <-invalids {
+		if name, ok := opt.GetName(p.Sha1); ok {
+			p.Name = name
+		}
+		scanner.FoundInvalidPointer(p)
+	}
+
 	if err := pointers.Wait(); err != nil {
 		pointerCb(nil, err)
 	}
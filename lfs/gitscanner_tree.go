@@ -178,7 +178,7 @@ func (s *lsTreeScanner) next() (*TreeBlob, bool) {
 		return nil, hasNext
 	}
 
-	if sz < blobSizeCutoff {
+	if sz <= int64(blobSizeCutoff()) {
 		sha1 := attrs[2]
 		filename := parts[1]
 		return &TreeBlob{Sha1: sha1, Filename: filename}, hasNext
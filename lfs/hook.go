@@ -44,6 +44,15 @@ func (h *Hook) Exists() bool {
 	return !os.IsNotExist(err)
 }
 
+// Installed returns whether this hook exists, with its current contents.
+func (h *Hook) Installed() bool {
+	by, err := ioutil.ReadFile(h.Path())
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(tools.Undent(string(by))) == h.Contents
+}
+
 // Path returns the desired (or actual, if installed) location where this hook
 // should be installed. It returns an absolute path in all cases.
 func (h *Hook) Path() string {
@@ -0,0 +1,92 @@
+package lfs
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/git-lfs/git-lfs/tools"
+)
+
+// IntegrityEntry records that an object was written to the local object store
+// by Git LFS, and when.
+type IntegrityEntry struct {
+	Oid     string
+	Size    int64
+	Written time.Time
+}
+
+// IntegrityManifestPath returns the path of the integrity manifest, in which
+// RecordIntegrity keeps an entry for each object written to the local object
+// store.
+func IntegrityManifestPath() string {
+	return filepath.Join(config.Config.StorageConfig().LfsStorageDir, "integrity")
+}
+
+// RecordIntegrity appends an entry for the object given by "oid" and "size",
+// written now, to the integrity manifest.
+func RecordIntegrity(oid string, size int64) error {
+	a := tools.NewLockedAppender(IntegrityManifestPath())
+	if err := a.Appendf("%s %d %d\n", oid, size, time.Now().UnixNano()); err != nil {
+		return errors.Wrap(err, "integrity manifest")
+	}
+	return nil
+}
+
+// IntegrityEntries returns the entries in the integrity manifest, keyed by OID.
+// If an object was written more than once, the latest entry is returned. A
+// missing manifest has no entries.
+func IntegrityEntries() (map[string]*IntegrityEntry, error) {
+	entries := make(map[string]*IntegrityEntry)
+
+	f, err := os.Open(IntegrityManifestPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return entries, nil
+		}
+		return nil, errors.Wrap(err, "integrity manifest")
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		entry, err := parseIntegrityEntry(scanner.Text())
+		if err != nil {
+			return nil, errors.Wrapf(err, "integrity manifest line %d", n)
+		}
+		entries[entry.Oid] = entry
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "integrity manifest")
+	}
+	return entries, nil
+}
+
+func parseIntegrityEntry(line string) (*IntegrityEntry, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 3 {
+		return nil, errors.Errorf("malformed entry: %q", line)
+	}
+
+	size, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return nil, errors.Errorf("malformed size: %q", fields[1])
+	}
+
+	written, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return nil, errors.Errorf("malformed timestamp: %q", fields[2])
+	}
+
+	return &IntegrityEntry{
+		Oid:     fields[0],
+		Size:    size,
+		Written: time.Unix(0, written),
+	}, nil
+}
@@ -0,0 +1,68 @@
+package lfs
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegrityEntriesWithoutManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "integrity")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	oldStorageDir := config.LocalGitStorageDir
+	config.LocalGitStorageDir = dir
+	defer func() { config.LocalGitStorageDir = oldStorageDir }()
+
+	entries, err := IntegrityEntries()
+	require.Nil(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestRecordIntegrity(t *testing.T) {
+	dir, err := ioutil.TempDir("", "integrity")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	oldStorageDir := config.LocalGitStorageDir
+	config.LocalGitStorageDir = dir
+	defer func() { config.LocalGitStorageDir = oldStorageDir }()
+
+	before := time.Now()
+	require.Nil(t, RecordIntegrity("oid-a", 1))
+	require.Nil(t, RecordIntegrity("oid-b", 2))
+	require.Nil(t, RecordIntegrity("oid-a", 3))
+
+	entries, err := IntegrityEntries()
+	require.Nil(t, err)
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, "oid-a", entries["oid-a"].Oid)
+	assert.EqualValues(t, 3, entries["oid-a"].Size)
+	assert.EqualValues(t, 2, entries["oid-b"].Size)
+	assert.False(t, entries["oid-b"].Written.Before(before))
+}
+
+func TestIntegrityEntriesMalformed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "integrity")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	oldStorageDir := config.LocalGitStorageDir
+	config.LocalGitStorageDir = dir
+	defer func() { config.LocalGitStorageDir = oldStorageDir }()
+
+	require.Nil(t, os.MkdirAll(dir+"/lfs", 0755))
+	require.Nil(t, ioutil.WriteFile(IntegrityManifestPath(), []byte("oid-a 1 2\noid-b x 2\n"), 0644))
+
+	_, err = IntegrityEntries()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "integrity manifest line 2")
+	}
+}
@@ -0,0 +1,75 @@
+package lfs
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/git-lfs/git-lfs/tools"
+	"github.com/rubyist/tracerx"
+)
+
+// legacyMediaDir is the directory, in the Git directory, which the earliest
+// versions of Git LFS kept objects in, before they moved to "lfs/objects".
+const legacyMediaDir = "media"
+
+// legacyMediaPaths returns the paths at which the earliest versions of Git LFS
+// may have kept the object given by "oid": sharded as the local media directory
+// is now, or directly in the legacy media directory.
+func legacyMediaPaths(oid string) []string {
+	if len(config.LocalGitStorageDir) == 0 || len(oid) < 4 {
+		return nil
+	}
+
+	dir := filepath.Join(config.LocalGitStorageDir, legacyMediaDir)
+	return []string{
+		filepath.Join(dir, oid[0:2], oid[2:4], oid),
+		filepath.Join(dir, oid),
+	}
+}
+
+// LegacyMediaPath returns the path of the object given by "oid" and "size" in
+// the object store of the earliest versions of Git LFS, as left behind in
+// repositories which have been used since then, or false if it is not there.
+func LegacyMediaPath(oid string, size int64) (string, bool) {
+	for _, path := range legacyMediaPaths(oid) {
+		if tools.FileExistsOfSize(path, size) {
+			tracerx.Printf("found %s in the legacy object store at %s", oid, path)
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// relocateLegacyObject moves the object given by "oid" and "size" from the
+// legacy object store into the local media directory, at "mediafile", if
+// lfs.legacyobjects.relocate is set and it is missing from there. An object
+// which cannot be moved is left where it is, and is still read from there.
+func relocateLegacyObject(oid string, size int64, mediafile string) {
+	if !config.Config.RelocateLegacyObjects() {
+		return
+	}
+
+	path, ok := LegacyMediaPath(oid, size)
+	if !ok {
+		return
+	}
+	if err := CheckStoreLayout(); err != nil {
+		tracerx.Printf("unable to relocate %s from the legacy object store: %s", oid, err)
+		return
+	}
+
+	if err := tools.RenameFile(path, mediafile); err != nil {
+		tracerx.Printf("unable to relocate %s from the legacy object store: %s", oid, err)
+		return
+	}
+	tracerx.Printf("relocated %s from the legacy object store to %s", oid, mediafile)
+
+	// Remove the shard directories the object was in, if it was the last
+	// one in them.
+	for dir := filepath.Dir(path); filepath.Base(dir) != legacyMediaDir; dir = filepath.Dir(dir) {
+		if err := os.Remove(dir); err != nil {
+			break
+		}
+	}
+}
@@ -45,7 +45,7 @@ func TempFile(prefix string) (*os.File, error) {
 }
 
 func LocalMediaPath(oid string) (string, error) {
-	return localstorage.Objects().BuildObjectPath(oid)
+	return ObjectStorage().Path(oid)
 }
 
 func LocalMediaPathReadOnly(oid string) string {
@@ -60,8 +60,7 @@ func LocalReferencePath(sha string) string {
 }
 
 func ObjectExistsOfSize(oid string, size int64) bool {
-	path := localstorage.Objects().ObjectPath(oid)
-	return tools.FileExistsOfSize(path, size)
+	return ObjectStorage().Exists(oid, size)
 }
 
 func Environ(cfg *config.Configuration, manifest *tq.Manifest) []string {
@@ -140,8 +139,10 @@ func ClearTempObjects() error {
 	return localstorage.Objects().ClearTempObjects()
 }
 
+// ScanObjectsChan returns a channel of all the objects in the local store, which
+// is walked by as many workers as lfs.storeconcurrency gives.
 func ScanObjectsChan() <-chan localstorage.Object {
-	return localstorage.Objects().ScanObjectsChan()
+	return localstorage.Objects().ScanObjectsChanWithWorkers(config.Config.StoreConcurrency())
 }
 
 func init() {
@@ -164,6 +165,10 @@ func AllObjects() []localstorage.Object {
 	return localstorage.Objects().AllObjects()
 }
 
+// LinkOrCopyFromReference brings the object given by "oid" and "size" into the
+// local media directory, if it is missing from there, from the repository this
+// one borrows Git objects from, as with `git clone --reference`, or, when
+// lfs.legacyobjects.relocate is set, from the legacy object store.
 func LinkOrCopyFromReference(oid string, size int64) error {
 	if ObjectExistsOfSize(oid, size) {
 		return nil
@@ -174,7 +179,11 @@ func LinkOrCopyFromReference(oid string, size int64) error {
 		return err
 	}
 	if altMediafile != "" && tools.FileExistsOfSize(altMediafile, size) {
+		if err := CheckStoreLayout(); err != nil {
+			return err
+		}
 		return LinkOrCopy(altMediafile, mediafile)
 	}
+	relocateLegacyObject(oid, size, mediafile)
 	return nil
 }
@@ -0,0 +1,114 @@
+package lfs
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/git-lfs/git-lfs/tools"
+)
+
+const (
+	originModeFile       = "100644"
+	originModeExecutable = "100755"
+)
+
+// ObjectOrigin is a path in the working tree which the clean filter read an
+// object from, and whether the file there was executable.
+type ObjectOrigin struct {
+	Path       string
+	Executable bool
+}
+
+// ObjectOriginsPath returns the path of the file beside the local object store
+// which records the origins of the object "oid". It has the same layout as the
+// object store, but is kept apart from it, so that it is never taken for an
+// object.
+func ObjectOriginsPath(oid string) string {
+	dir := filepath.Join(config.Config.StorageConfig().LfsStorageDir, "origins")
+	if len(oid) < 5 {
+		return filepath.Join(dir, oid)
+	}
+	return filepath.Join(dir, oid[0:2], oid[2:4], oid)
+}
+
+// RecordObjectOrigin records that the object "oid" was cleaned from the file
+// "name", with the mode "mode", unless it has already been recorded for that
+// path. Only the first mode seen for each path is kept.
+func RecordObjectOrigin(oid, name string, mode os.FileMode) error {
+	gitMode := originModeFile
+	if mode&0111 != 0 {
+		gitMode = originModeExecutable
+	}
+
+	// The record is read and appended to under the same lock, so that
+	// concurrent writers do not both add the same path.
+	a := tools.NewLockedAppender(ObjectOriginsPath(oid))
+	err := a.Locked(func(f *os.File) error {
+		origins, err := ObjectOrigins(oid)
+		if err != nil {
+			return err
+		}
+		for _, o := range origins {
+			if o.Path == name {
+				return nil
+			}
+		}
+
+		_, err = fmt.Fprintf(f, "%s %s\n", gitMode, name)
+		return err
+	})
+	if err != nil {
+		return errors.Wrap(err, "object origins")
+	}
+	return nil
+}
+
+// ObjectOrigins returns the origins recorded for the object "oid", in the order
+// they were first seen. An object without a record has no origins.
+func ObjectOrigins(oid string) ([]*ObjectOrigin, error) {
+	f, err := os.Open(ObjectOriginsPath(oid))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "object origins")
+	}
+	defer f.Close()
+
+	var origins []*ObjectOrigin
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		o, err := parseObjectOrigin(scanner.Text())
+		if err != nil {
+			return nil, errors.Wrapf(err, "object origins of %s line %d", oid, n)
+		}
+		origins = append(origins, o)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "object origins")
+	}
+	return origins, nil
+}
+
+func parseObjectOrigin(line string) (*ObjectOrigin, error) {
+	// The path comes last, since it may contain spaces.
+	fields := strings.SplitN(line, " ", 2)
+	if len(fields) != 2 || len(fields[1]) == 0 {
+		return nil, errors.Errorf("malformed entry: %q", line)
+	}
+
+	switch fields[0] {
+	case originModeFile:
+		return &ObjectOrigin{Path: fields[1]}, nil
+	case originModeExecutable:
+		return &ObjectOrigin{Path: fields[1], Executable: true}, nil
+	default:
+		return nil, errors.Errorf("malformed mode: %q", fields[0])
+	}
+}
@@ -0,0 +1,52 @@
+package lfs
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordObjectOrigin(t *testing.T) {
+	dir, err := ioutil.TempDir("", "object-origins")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	oldStorageDir := config.LocalGitStorageDir
+	config.LocalGitStorageDir = dir
+	defer func() { config.LocalGitStorageDir = oldStorageDir }()
+
+	oid := strings.Repeat("a", 64)
+
+	origins, err := ObjectOrigins(oid)
+	require.Nil(t, err)
+	assert.Empty(t, origins)
+
+	require.Nil(t, RecordObjectOrigin(oid, "a.dat", 0644))
+	require.Nil(t, RecordObjectOrigin(oid, "bin/run me.sh", 0755))
+	// The first mode seen for a path is kept.
+	require.Nil(t, RecordObjectOrigin(oid, "a.dat", 0755))
+
+	origins, err = ObjectOrigins(oid)
+	require.Nil(t, err)
+	assert.Equal(t, []*ObjectOrigin{
+		{Path: "a.dat"},
+		{Path: "bin/run me.sh", Executable: true},
+	}, origins)
+
+	other, err := ObjectOrigins(strings.Repeat("b", 64))
+	require.Nil(t, err)
+	assert.Empty(t, other)
+}
+
+func TestObjectOriginsMalformed(t *testing.T) {
+	_, err := parseObjectOrigin("100644")
+	assert.NotNil(t, err)
+
+	_, err = parseObjectOrigin("120000 link")
+	assert.NotNil(t, err)
+}
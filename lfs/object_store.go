@@ -0,0 +1,83 @@
+package lfs
+
+import (
+	"io"
+	"os"
+
+	"github.com/git-lfs/git-lfs/localstorage"
+	"github.com/git-lfs/git-lfs/tools"
+)
+
+// ObjectStore is the storage backend for LFS objects. By default, objects are
+// kept on the filesystem underneath .git/lfs/objects, but programs embedding
+// Git LFS may install their own implementation with SetObjectStore, for
+// instance to keep objects in a database or a content-addressable blob
+// service.
+type ObjectStore interface {
+	// Get opens the object with the given OID for reading.
+	Get(oid string) (io.ReadCloser, error)
+
+	// Put moves the contents of the temporary file at "path" into the
+	// store as the object with the given OID. Once Put returns
+	// successfully, the file at "path" is owned by the store, and may no
+	// longer exist.
+	Put(oid string, path string) error
+
+	// Exists returns whether an object with the given OID and size is
+	// present in the store.
+	Exists(oid string, size int64) bool
+
+	// Path returns a location on the local filesystem for the object with
+	// the given OID, creating any parent directories as needed. Transfer
+	// adapters read uploads from, and write downloads to, this path, so
+	// stores which do not keep objects on the filesystem should treat it
+	// as a local staging area, such that an object written there is
+	// visible to Get and Exists.
+	Path(oid string) (string, error)
+}
+
+var objectStore ObjectStore = &fileObjectStore{}
+
+// ObjectStorage returns the ObjectStore that LFS objects are read from and
+// written to.
+func ObjectStorage() ObjectStore {
+	return objectStore
+}
+
+// SetObjectStore replaces the ObjectStore that LFS objects are read from and
+// written to. Passing nil restores the default, filesystem-backed store.
+func SetObjectStore(s ObjectStore) {
+	if s == nil {
+		s = &fileObjectStore{}
+	}
+	objectStore = s
+}
+
+// fileObjectStore is the default ObjectStore, keeping objects in the
+// repository's local media directory.
+type fileObjectStore struct{}
+
+func (s *fileObjectStore) Get(oid string) (io.ReadCloser, error) {
+	return os.Open(localstorage.Objects().ObjectPath(oid))
+}
+
+func (s *fileObjectStore) Put(oid string, path string) error {
+	if err := CheckStoreLayout(); err != nil {
+		return err
+	}
+	mediafile, err := s.Path(oid)
+	if err != nil {
+		return err
+	}
+	// The temporary files objects are written to may be on another
+	// filesystem than the store, as when it is on a ramdisk.
+	return tools.RenameFile(path, mediafile)
+}
+
+func (s *fileObjectStore) Exists(oid string, size int64) bool {
+	return tools.FileExistsOfSize(localstorage.Objects().ObjectPath(oid), size)
+}
+
+func (s *fileObjectStore) Path(oid string) (string, error) {
+	return localstorage.Objects().BuildObjectPath(oid)
+}
@@ -0,0 +1,156 @@
+package lfs
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryObjectStore struct {
+	dir     string
+	objects map[string][]byte
+}
+
+func (s *memoryObjectStore) Get(oid string) (io.ReadCloser, error) {
+	data, ok := s.objects[oid]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *memoryObjectStore) Put(oid string, path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	s.objects[oid] = data
+	return os.Remove(path)
+}
+
+func (s *memoryObjectStore) Exists(oid string, size int64) bool {
+	data, ok := s.objects[oid]
+	return ok && int64(len(data)) == size
+}
+
+func (s *memoryObjectStore) Path(oid string) (string, error) {
+	return filepath.Join(s.dir, oid), nil
+}
+
+func TestSetObjectStore(t *testing.T) {
+	store := &memoryObjectStore{objects: make(map[string][]byte)}
+
+	SetObjectStore(store)
+	assert.Equal(t, store, ObjectStorage())
+
+	SetObjectStore(nil)
+	assert.IsType(t, &fileObjectStore{}, ObjectStorage())
+}
+
+func TestObjectStorePutAndSmudge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "object-store")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	store := &memoryObjectStore{dir: dir, objects: make(map[string][]byte)}
+	SetObjectStore(store)
+	defer SetObjectStore(nil)
+
+	tmp := filepath.Join(dir, "tmp")
+	require.Nil(t, ioutil.WriteFile(tmp, []byte("contents"), 0644))
+
+	oid := "d1b2a59fbea7e20077af9f91b27e95e865061b270be03ff539ab3b73587882e8"
+	require.Nil(t, store.Put(oid, tmp))
+
+	assert.True(t, ObjectExistsOfSize(oid, 8))
+	assert.False(t, ObjectExistsOfSize(oid, 9))
+
+	path, err := LocalMediaPath(oid)
+	require.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, oid), path)
+
+	var buf bytes.Buffer
+	n, err := PointerSmudge(&buf, NewPointer(oid, 8, nil), "a.dat", false, nil, nil)
+	require.Nil(t, err)
+	assert.EqualValues(t, 8, n)
+	assert.Equal(t, "contents", buf.String())
+}
+
+func TestObjectStoreSmudgeMissingObject(t *testing.T) {
+	dir, err := ioutil.TempDir("", "object-store")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	SetObjectStore(&memoryObjectStore{dir: dir, objects: make(map[string][]byte)})
+	defer SetObjectStore(nil)
+
+	oid := "d1b2a59fbea7e20077af9f91b27e95e865061b270be03ff539ab3b73587882e8"
+
+	var buf bytes.Buffer
+	_, err = PointerSmudge(&buf, NewPointer(oid, 8, nil), "a.dat", false, nil, nil)
+	assert.True(t, errors.IsDownloadDeclinedError(err))
+	assert.Empty(t, buf.String())
+}
+
+func TestCleanStoresObject(t *testing.T) {
+	store := &memoryObjectStore{objects: make(map[string][]byte)}
+
+	// Larger than the first read made of the contents, to check that the
+	// rest of a reader of an unknown size is cleaned too.
+	contents := bytes.Repeat([]byte("a"), 2048)
+
+	ptr, err := Clean(bytes.NewReader(contents), store)
+	require.Nil(t, err)
+
+	assert.Equal(t, "b2a3a502fdfc34f4e3edfa94b7f3109cd972d87a4fec63ab21a6673379ccf7ad", ptr.Oid)
+	assert.EqualValues(t, 2048, ptr.Size)
+	assert.Equal(t, contents, store.objects[ptr.Oid])
+}
+
+func TestCleanExistingObject(t *testing.T) {
+	oid := "d1b2a59fbea7e20077af9f91b27e95e865061b270be03ff539ab3b73587882e8"
+	store := &memoryObjectStore{objects: map[string][]byte{
+		oid: []byte("contents"),
+	}}
+
+	ptr, err := Clean(bytes.NewReader([]byte("contents")), store)
+	require.Nil(t, err)
+
+	assert.Equal(t, oid, ptr.Oid)
+	assert.EqualValues(t, 8, ptr.Size)
+	assert.Len(t, store.objects, 1)
+}
+
+func TestCleanExistingObjectOfAnotherSize(t *testing.T) {
+	oid := "d1b2a59fbea7e20077af9f91b27e95e865061b270be03ff539ab3b73587882e8"
+	store := &memoryObjectStore{objects: map[string][]byte{
+		oid: []byte("contents, corrupted"),
+	}}
+
+	ptr, err := Clean(bytes.NewReader([]byte("contents")), store)
+	assert.Nil(t, ptr)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "files don't match")
+	assert.Equal(t, []byte("contents, corrupted"), store.objects[oid])
+}
+
+func TestCleanPointer(t *testing.T) {
+	store := &memoryObjectStore{objects: make(map[string][]byte)}
+
+	var buf bytes.Buffer
+	_, err := EncodePointer(&buf, NewPointer("d1b2a59fbea7e20077af9f91b27e95e865061b270be03ff539ab3b73587882e8", 8, nil))
+	require.Nil(t, err)
+
+	ptr, err := Clean(bytes.NewReader(buf.Bytes()), store)
+	assert.Nil(t, ptr)
+	require.True(t, errors.IsCleanPointerError(err))
+	assert.Equal(t, buf.Bytes(), errors.GetContext(err, "bytes"))
+	assert.Empty(t, store.objects)
+}
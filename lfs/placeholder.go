@@ -0,0 +1,112 @@
+package lfs
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/git-lfs/git-lfs/tools"
+)
+
+// placeholderDir returns the directory in which the placeholders written by
+// WritePlaceholder are recorded.
+func placeholderDir() string {
+	return filepath.Join(config.Config.StorageConfig().LfsStorageDir, "placeholders")
+}
+
+// placeholderPath returns the path of the record kept for the placeholder
+// written to the working file at "workingfile".
+func placeholderPath(workingfile string) string {
+	h := tools.NewLfsContentHash()
+	h.Write([]byte(workingfile))
+	return filepath.Join(placeholderDir(), hex.EncodeToString(h.Sum(nil)))
+}
+
+// WritePlaceholder writes ptr.Size zero bytes to "writer" in place of the
+// contents of the object that "ptr" points to, and records that "workingfile"
+// holds a placeholder for "ptr". As long as the placeholder is left unmodified,
+// PlaceholderPointer returns the original pointer when "workingfile" is
+// cleaned again, so that the zeros are never mistaken for real content.
+func WritePlaceholder(writer io.Writer, ptr *Pointer, workingfile string) (int64, error) {
+	if err := os.MkdirAll(placeholderDir(), 0755); err != nil {
+		return 0, errors.Wrap(err, "placeholder")
+	}
+
+	hasher := tools.NewLfsContentHash()
+	zeros := io.LimitReader(zeroReader{}, ptr.Size)
+
+	n, err := io.Copy(io.MultiWriter(writer, hasher), zeros)
+	if err != nil {
+		return n, errors.Wrap(err, "placeholder")
+	}
+
+	var record bytes.Buffer
+	record.WriteString(hex.EncodeToString(hasher.Sum(nil)) + "\n")
+	if _, err := EncodePointer(&record, ptr); err != nil {
+		return n, errors.Wrap(err, "placeholder")
+	}
+
+	if err := ioutil.WriteFile(placeholderPath(workingfile), record.Bytes(), 0644); err != nil {
+		return n, errors.Wrap(err, "placeholder")
+	}
+	return n, nil
+}
+
+// PlaceholderPointer returns the pointer that "workingfile" was given a
+// placeholder for by WritePlaceholder, if the contents being cleaned, given by
+// "oid" and "size", are still exactly that placeholder. Otherwise, it returns
+// false.
+func PlaceholderPointer(workingfile, oid string, size int64) (*Pointer, bool) {
+	f, err := os.Open(placeholderPath(workingfile))
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	placeholderOid, err := r.ReadString('\n')
+	if err != nil || strings.TrimSpace(placeholderOid) != oid {
+		return nil, false
+	}
+
+	ptr, err := DecodePointer(r)
+	if err != nil || ptr.Size != size {
+		return nil, false
+	}
+	return ptr, true
+}
+
+// HasPlaceholder returns whether a placeholder is recorded for "workingfile",
+// which is the case from when WritePlaceholder writes it until
+// RemovePlaceholder is called, once its real contents have been written.
+func HasPlaceholder(workingfile string) bool {
+	_, err := os.Stat(placeholderPath(workingfile))
+	return err == nil
+}
+
+// RemovePlaceholder forgets any placeholder recorded for "workingfile", such as
+// when its real contents have since been written.
+func RemovePlaceholder(workingfile string) error {
+	err := os.Remove(placeholderPath(workingfile))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// zeroReader is an io.Reader that reads an endless stream of zero bytes.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
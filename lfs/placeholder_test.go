@@ -0,0 +1,50 @@
+package lfs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlaceholderRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "placeholder")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	oldStorageDir := config.LocalGitStorageDir
+	config.LocalGitStorageDir = dir
+	defer func() { config.LocalGitStorageDir = oldStorageDir }()
+
+	ptr := NewPointer("fcf5015df7a9089a7aa7fe74139d4b8f7d62e52d5a34f9a87aeffc8e8c668254", 9, nil)
+
+	var buf bytes.Buffer
+	n, err := WritePlaceholder(&buf, ptr, "a.dat")
+	require.Nil(t, err)
+	assert.EqualValues(t, 9, n)
+	assert.Equal(t, make([]byte, 9), buf.Bytes())
+
+	// sha256 of nine zero bytes
+	zeroOid := "3e7077fd2f66d689e0cee6a7cf5b37bf2dca7c979af356d0a31cbc5c85605c7d"
+
+	found, ok := PlaceholderPointer("a.dat", zeroOid, 9)
+	require.True(t, ok)
+	assert.Equal(t, ptr.Oid, found.Oid)
+	assert.EqualValues(t, ptr.Size, found.Size)
+
+	_, ok = PlaceholderPointer("b.dat", zeroOid, 9)
+	assert.False(t, ok)
+
+	_, ok = PlaceholderPointer("a.dat", ptr.Oid, 9)
+	assert.False(t, ok, "modified placeholders should be cleaned as content")
+
+	require.Nil(t, RemovePlaceholder("a.dat"))
+	_, ok = PlaceholderPointer("a.dat", zeroOid, 9)
+	assert.False(t, ok)
+
+	assert.Nil(t, RemovePlaceholder("a.dat"))
+}
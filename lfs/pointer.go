@@ -11,6 +11,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/git-lfs/git-lfs/config"
 	"github.com/git-lfs/git-lfs/errors"
 	"github.com/git-lfs/git-lfs/progress"
 	"github.com/git-lfs/git-lfs/tq"
@@ -24,10 +25,12 @@ var (
 	}
 	latest      = "https://git-lfs.github.com/spec/v1"
 	oidType     = "sha256"
-	oidRE       = regexp.MustCompile(`\A[[:alnum:]]{64}`)
+	oidRE       = regexp.MustCompile(`\A[0-9a-fA-F]{64}\z`)
 	matcherRE   = regexp.MustCompile("git-media|hawser|git-lfs")
 	extRE       = regexp.MustCompile(`\Aext-\d{1}-\w+`)
 	pointerKeys = []string{"version", "oid", "size"}
+	// emptyOid is the OID of an empty object.
+	emptyOid = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
 )
 
 type Pointer struct {
@@ -73,8 +76,13 @@ func (p *Pointer) Encoded() string {
 		return ""
 	}
 
+	version := p.Version
+	if len(version) == 0 {
+		version = latest
+	}
+
 	var buffer bytes.Buffer
-	buffer.WriteString(fmt.Sprintf("version %s\n", latest))
+	buffer.WriteString(fmt.Sprintf("version %s\n", version))
 	for _, ext := range p.Extensions {
 		buffer.WriteString(fmt.Sprintf("ext-%d-%s %s:%s\n", ext.Priority, ext.Name, ext.OidType, ext.Oid))
 	}
@@ -87,13 +95,27 @@ func EncodePointer(writer io.Writer, pointer *Pointer) (int, error) {
 	return writer.Write([]byte(pointer.Encoded()))
 }
 
+// EncodePointerWithNewline writes the encoded form of "pointer" to "writer" in
+// the same way as EncodePointer. The canonical encoding always terminates with
+// exactly one "\n"; if "newline" is false, that final newline is omitted.
+//
+// The encoding never depends on the platform, so given the same pointer and
+// value of "newline", the same bytes are always written.
+func EncodePointerWithNewline(writer io.Writer, pointer *Pointer, newline bool) (int, error) {
+	encoded := pointer.Encoded()
+	if !newline {
+		encoded = strings.TrimSuffix(encoded, "\n")
+	}
+	return writer.Write([]byte(encoded))
+}
+
 func DecodePointerFromFile(file string) (*Pointer, error) {
 	// Check size before reading
 	stat, err := os.Stat(file)
 	if err != nil {
 		return nil, err
 	}
-	if stat.Size() > blobSizeCutoff {
+	if stat.Size() > int64(config.Config.PointerMaxSize()) {
 		return nil, errors.NewNotAPointerError(errors.New("file size exceeds lfs pointer size cutoff"))
 	}
 	f, err := os.OpenFile(file, os.O_RDONLY, 0644)
@@ -114,18 +136,77 @@ func DecodePointer(reader io.Reader) (*Pointer, error) {
 //
 // If the pointer could not be decoded, an io.Reader containing the entire
 // blob's data will be returned, along with a parse error.
+//
+// No more than one byte beyond the maximum size of a pointer, given by
+// "lfs.pointer.maxsize", is read before decoding, and anything larger than
+// that is not a pointer, however it starts.
 func DecodeFrom(reader io.Reader) (*Pointer, io.Reader, error) {
-	buf := make([]byte, blobSizeCutoff)
-	n, err := reader.Read(buf)
+	return decodeFrom(reader, config.Config.PointerMaxSize())
+}
+
+// validatePointer decodes the pointer in "data", the whole of a blob, like
+// DecodePointer does, but tells data which is not a pointer at all apart from
+// pointers which are malformed, or give a size which no object Git LFS stores
+// could have, as either is usually the sign of a corrupt commit. The error is a
+// NotAPointerError if "data" does not start with the version line of a pointer.
+// An implausible pointer is returned along with the error saying why.
+func validatePointer(data []byte) (*Pointer, error) {
+	p, err := DecodePointer(bytes.NewReader(data))
+	if err != nil {
+		if !errors.IsNotAPointerError(err) && !hasPointerVersion(data) {
+			err = errors.NewNotAPointerError(err)
+		}
+		return nil, err
+	}
+
+	if p.Size <= 0 {
+		// Empty files are never cleaned into pointers.
+		return p, fmt.Errorf("Invalid size: %d", p.Size)
+	}
+	if oids := NonCanonicalOids(data); len(oids) > 0 {
+		// The pointer is still read, but its blob is not the one Git
+		// LFS would write for it.
+		return p, fmt.Errorf("Oid %s is not in lower case", oids[0])
+	}
+	return p, nil
+}
+
+// hasPointerVersion returns whether "data" starts with the version line of a
+// pointer, rather than just mentioning Git LFS somewhere.
+func hasPointerVersion(data []byte) bool {
+	for _, v := range acceptedVersions() {
+		if bytes.HasPrefix(data, []byte("version "+v)) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptedVersions returns the versions of the pointers which are decoded: those
+// in the specification, and any given by lfs.pointer.acceptedversions.
+func acceptedVersions() []string {
+	extra := config.Config.PointerAcceptedVersions()
+	if len(extra) == 0 {
+		return v1Aliases
+	}
+	return append(append(make([]string, 0, len(v1Aliases)+len(extra)), v1Aliases...), extra...)
+}
+
+func decodeFrom(reader io.Reader, maxSize int) (*Pointer, io.Reader, error) {
+	buf := make([]byte, maxSize+1)
+	n, err := io.ReadFull(reader, buf)
 	buf = buf[:n]
 
 	var contents io.Reader = bytes.NewReader(buf)
-	if err != io.EOF {
-		contents = io.MultiReader(contents, reader)
+	if err == nil {
+		// The buffer was filled, so there is more to read, and too
+		// much of it for a pointer.
+		return nil, io.MultiReader(contents, reader), errors.NewNotAPointerError(
+			errors.Errorf("pointer exceeds the maximum size of %d bytes", maxSize))
 	}
 
-	if err != nil && err != io.EOF {
-		return nil, contents, err
+	if err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, io.MultiReader(contents, reader), err
 	}
 
 	p, err := decodeKV(bytes.TrimSpace(buf))
@@ -137,7 +218,7 @@ func verifyVersion(version string) error {
 		return errors.NewNotAPointerError(errors.New("Missing version"))
 	}
 
-	for _, v := range v1Aliases {
+	for _, v := range acceptedVersions() {
 		if v == version {
 			return nil
 		}
@@ -190,7 +271,22 @@ func decodeKV(data []byte) (*Pointer, error) {
 		sort.Sort(ByPriority(extensions))
 	}
 
-	return NewPointer(oid, size, extensions), nil
+	p := NewPointer(oid, size, extensions)
+	if !isV1Alias(kvps["version"]) {
+		// Pointers of a version accepted by lfs.pointer.acceptedversions
+		// keep it, rather than being upgraded to the latest one.
+		p.Version = kvps["version"]
+	}
+	return p, nil
+}
+
+func isV1Alias(version string) bool {
+	for _, v := range v1Aliases {
+		if v == version {
+			return true
+		}
+	}
+	return false
 }
 
 func parseOid(value string) (string, error) {
@@ -202,12 +298,48 @@ func parseOid(value string) (string, error) {
 		return "", errors.New("Invalid Oid type: " + parts[0])
 	}
 	oid := parts[1]
-	if !oidRE.Match([]byte(oid)) {
+	if !oidRE.MatchString(oid) {
 		return "", errors.New("Invalid Oid: " + oid)
 	}
+	if lower := strings.ToLower(oid); lower != oid {
+		// OIDs written in upper case by other tools name the same
+		// object, so they are read in lower case, as the object store
+		// and servers name objects, unless lfs.pointer.strictoids is
+		// set.
+		if config.Config.PointerStrictOids() {
+			return "", errors.New("Invalid Oid: " + oid + " is not in lower case")
+		}
+		oid = lower
+	}
 	return oid, nil
 }
 
+// NonCanonicalOids returns the OIDs in the pointer "data", of its object and of
+// any extensions, which are not written in lower case, as they are when Git LFS
+// writes a pointer. DecodePointer reads them in lower case, unless
+// lfs.pointer.strictoids is set.
+func NonCanonicalOids(data []byte) []string {
+	kvps, exts, err := decodeKVData(bytes.TrimSpace(data))
+	if err != nil {
+		return nil
+	}
+
+	values := []string{kvps["oid"]}
+	for _, value := range exts {
+		values = append(values, value)
+	}
+
+	var oids []string
+	for _, value := range values {
+		parts := strings.SplitN(value, ":", 2)
+		if len(parts) == 2 && parts[1] != strings.ToLower(parts[1]) {
+			oids = append(oids, parts[1])
+		}
+	}
+	sort.Strings(oids)
+	return oids
+}
+
 func parsePointerExtension(key string, value string) (*PointerExtension, error) {
 	keyParts := strings.SplitN(key, "-", 3)
 	if len(keyParts) != 3 || keyParts[0] != "ext" {
@@ -243,7 +375,7 @@ func validatePointerExtensions(exts []*PointerExtension) error {
 func decodeKVData(data []byte) (kvps map[string]string, exts map[string]string, err error) {
 	kvps = make(map[string]string)
 
-	if !matcherRE.Match(data) {
+	if !matcherRE.Match(data) && !hasPointerVersion(data) {
 		err = errors.NewNotAPointerError(errors.New("invalid header"))
 		return
 	}
@@ -264,7 +396,9 @@ func decodeKVData(data []byte) (kvps map[string]string, exts map[string]string,
 		}
 
 		key := parts[0]
-		value := parts[1]
+		// Some tools have written pointers with whitespace after values,
+		// such as a stray space after the OID, which is not part of them.
+		value := strings.TrimRight(parts[1], " \t\r")
 
 		if numKeys <= line {
 			err = fmt.Errorf("Extra line: %s", text)
@@ -0,0 +1,177 @@
+package lfs
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/git-lfs/git-lfs/tools"
+)
+
+// PointerChecksumEntry records that the clean filter wrote a pointer for the
+// file "Name", and the ID of the Git blob made of exactly the bytes it wrote.
+type PointerChecksumEntry struct {
+	Blob    string
+	Oid     string
+	Written time.Time
+	Name    string
+}
+
+// PointerChecksumMismatch is a file whose blob in a tree is not any of the
+// pointers which the clean filter recorded writing for it.
+type PointerChecksumMismatch struct {
+	Name string
+	Blob string
+	// Last is the latest pointer recorded for the file.
+	Last *PointerChecksumEntry
+}
+
+// PointerChecksumsPath returns the path of the log of pointer checksums, in
+// which RecordPointerChecksum keeps an entry for each pointer written by the
+// clean filter.
+func PointerChecksumsPath() string {
+	return filepath.Join(config.Config.StorageConfig().LfsStorageDir, "pointer-checksums")
+}
+
+// PointerBlobID returns the ID which Git gives a blob of the bytes "by".
+func PointerBlobID(by []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(by))
+	h.Write(by)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// RecordPointerChecksum appends an entry for the pointer "by" to the object
+// "oid", written now for the file "name", to the log of pointer checksums.
+func RecordPointerChecksum(name, oid string, by []byte) error {
+	a := tools.NewLockedAppender(PointerChecksumsPath())
+	if err := a.Appendf("%s %s %d %s\n", PointerBlobID(by), oid, time.Now().UnixNano(), quotePointerChecksumName(name)); err != nil {
+		return errors.Wrap(err, "pointer checksums")
+	}
+	return nil
+}
+
+// PointerChecksumEntries returns the entries in the log of pointer checksums,
+// keyed by file name, in the order they were written. A missing log has no
+// entries.
+func PointerChecksumEntries() (map[string][]*PointerChecksumEntry, error) {
+	entries := make(map[string][]*PointerChecksumEntry)
+
+	f, err := os.Open(PointerChecksumsPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return entries, nil
+		}
+		return nil, errors.Wrap(err, "pointer checksums")
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		entry, err := parsePointerChecksumEntry(scanner.Text())
+		if err != nil {
+			return nil, errors.Wrapf(err, "pointer checksums line %d", n)
+		}
+		entries[entry.Name] = append(entries[entry.Name], entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "pointer checksums")
+	}
+	return entries, nil
+}
+
+// quotePointerChecksumName returns the file name "name" as it is written in the
+// log of pointer checksums, which has one entry per line: quoted as a Go string
+// literal if it contains a line break, or starts with a quote, and as it is
+// otherwise.
+func quotePointerChecksumName(name string) string {
+	if strings.ContainsAny(name, "\r\n") || strings.HasPrefix(name, `"`) {
+		return strconv.Quote(name)
+	}
+	return name
+}
+
+func parsePointerChecksumEntry(line string) (*PointerChecksumEntry, error) {
+	// The name comes last, since it may contain spaces.
+	fields := strings.SplitN(line, " ", 4)
+	if len(fields) != 4 || len(fields[3]) == 0 {
+		return nil, errors.Errorf("malformed entry: %q", line)
+	}
+
+	written, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return nil, errors.Errorf("malformed timestamp: %q", fields[2])
+	}
+
+	name := fields[3]
+	if strings.HasPrefix(name, `"`) {
+		if name, err = strconv.Unquote(name); err != nil {
+			return nil, errors.Errorf("malformed name: %q", fields[3])
+		}
+	}
+
+	return &PointerChecksumEntry{
+		Blob:    fields[0],
+		Oid:     fields[1],
+		Written: time.Unix(0, written),
+		Name:    name,
+	}, nil
+}
+
+// VerifyPointerChecksums checks the blob of each file in the tree at "ref"
+// which has entries in the log of pointer checksums against them, and returns
+// the number of files checked and those whose blob was not written by the clean
+// filter. Files without entries are not checked, since their pointers were
+// written without the log being kept, if they are pointers at all.
+func VerifyPointerChecksums(ref string) (int, []*PointerChecksumMismatch, error) {
+	entries, err := PointerChecksumEntries()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	blobs, err := lsTreeBlobs(ref, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var checked int
+	var mismatches []*PointerChecksumMismatch
+	for t := range blobs.Results {
+		written, ok := entries[t.Filename]
+		if !ok {
+			continue
+		}
+		checked++
+
+		if !pointerChecksumsInclude(written, t.Sha1) {
+			mismatches = append(mismatches, &PointerChecksumMismatch{
+				Name: t.Filename,
+				Blob: t.Sha1,
+				Last: written[len(written)-1],
+			})
+		}
+	}
+
+	if err := blobs.Wait(); err != nil {
+		return 0, nil, err
+	}
+	return checked, mismatches, nil
+}
+
+func pointerChecksumsInclude(entries []*PointerChecksumEntry, blob string) bool {
+	for _, e := range entries {
+		if e.Blob == blob {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package lfs
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPointerBlobID(t *testing.T) {
+	// git hash-object --stdin <<< "hello"
+	assert.Equal(t, "ce013625030ba8dba906f756967f9e9ca394464a", PointerBlobID([]byte("hello\n")))
+}
+
+func TestRecordPointerChecksum(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pointer-checksums")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	oldStorageDir := config.LocalGitStorageDir
+	config.LocalGitStorageDir = dir
+	defer func() { config.LocalGitStorageDir = oldStorageDir }()
+
+	entries, err := PointerChecksumEntries()
+	require.Nil(t, err)
+	assert.Empty(t, entries)
+
+	require.Nil(t, RecordPointerChecksum("a.dat", "oid-a", []byte("a")))
+	require.Nil(t, RecordPointerChecksum("b c.dat", "oid-b", []byte("b")))
+	require.Nil(t, RecordPointerChecksum("a.dat", "oid-c", []byte("c")))
+	require.Nil(t, RecordPointerChecksum("d\ne.dat", "oid-d", []byte("d")))
+	require.Nil(t, RecordPointerChecksum(`"f".dat`, "oid-f", []byte("f")))
+
+	entries, err = PointerChecksumEntries()
+	require.Nil(t, err)
+	require.Len(t, entries, 4)
+
+	require.Len(t, entries["a.dat"], 2)
+	assert.Equal(t, PointerBlobID([]byte("a")), entries["a.dat"][0].Blob)
+	assert.Equal(t, "oid-a", entries["a.dat"][0].Oid)
+	assert.Equal(t, "oid-c", entries["a.dat"][1].Oid)
+	assert.False(t, entries["a.dat"][1].Written.Before(entries["a.dat"][0].Written))
+
+	require.Len(t, entries["b c.dat"], 1)
+	assert.Equal(t, "oid-b", entries["b c.dat"][0].Oid)
+
+	// Names with line breaks do not split their entries.
+	require.Len(t, entries["d\ne.dat"], 1)
+	assert.Equal(t, "oid-d", entries["d\ne.dat"][0].Oid)
+	require.Len(t, entries[`"f".dat`], 1)
+	assert.Equal(t, "oid-f", entries[`"f".dat`][0].Oid)
+}
+
+func TestPointerChecksumEntriesWithMalformedLine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pointer-checksums")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	oldStorageDir := config.LocalGitStorageDir
+	config.LocalGitStorageDir = dir
+	defer func() { config.LocalGitStorageDir = oldStorageDir }()
+
+	require.Nil(t, os.MkdirAll(config.Config.StorageConfig().LfsStorageDir, 0755))
+	require.Nil(t, ioutil.WriteFile(PointerChecksumsPath(), []byte("blob oid 1\n"), 0644))
+
+	_, err = PointerChecksumEntries()
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "pointer checksums line 1")
+}
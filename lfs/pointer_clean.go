@@ -1,16 +1,18 @@
 package lfs
 
 import (
+	"bufio"
 	"bytes"
-	"crypto/sha256"
 	"encoding/hex"
 	"io"
+	"io/ioutil"
 	"os"
 
 	"github.com/git-lfs/git-lfs/config"
 	"github.com/git-lfs/git-lfs/errors"
 	"github.com/git-lfs/git-lfs/progress"
 	"github.com/git-lfs/git-lfs/tools"
+	"github.com/rubyist/tracerx"
 )
 
 type cleanedAsset struct {
@@ -18,12 +20,35 @@ type cleanedAsset struct {
 	*Pointer
 }
 
-func PointerClean(reader io.Reader, fileName string, fileSize int64, cb progress.CopyCallback) (*cleanedAsset, error) {
+// PointerClean reads the contents of the file "fileName" from "reader", giving
+// them to any extensions, and writes them to a temporary file, along with each
+// of "sinks", returning it with the pointer to them.
+func PointerClean(reader io.Reader, fileName string, fileSize int64, cb progress.CopyCallback, sinks ...*CleanSink) (*cleanedAsset, error) {
 	extensions, err := config.Config.SortedExtensions()
 	if err != nil {
 		return nil, err
 	}
 
+	// Input which is already a pointer must not be wrapped in another
+	// pointer, which could never be smudged back into the original file,
+	// whether or not it would be given to any extensions.
+	ptr, reader, err := DecodeFrom(reader)
+	if err == nil {
+		by, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+		return nil, errors.NewCleanPointerError(ptr, by)
+	}
+
+	// An empty file is the pointer for an empty file, so it is passed
+	// through unchanged, rather than given to any extensions, or stored.
+	buffered := bufio.NewReader(reader)
+	if _, err := buffered.Peek(1); err == io.EOF {
+		return nil, errors.NewCleanPointerError(nil, []byte{})
+	}
+	reader = newCleanSinkReader(buffered, sinks)
+
 	var oid string
 	var size int64
 	var tmp *os.File
@@ -61,6 +86,69 @@ func PointerClean(reader io.Reader, fileName string, fileSize int64, cb progress
 	return &cleanedAsset{tmp.Name(), pointer}, err
 }
 
+// Clean reads the contents of a file from "r", and stores them in "store" as
+// the object they hash to, unless it already has that object, returning the
+// pointer to it. Any extensions configured are run on the contents first.
+//
+// Contents which are empty, or are already a pointer, are not stored, and a
+// CleanPointerError is returned instead, whose "bytes" context holds what was
+// read, to be written out verbatim in place of a new pointer.
+//
+// The contents are also written to each of "sinks" as they are read, before
+// any extensions are run on them, unless they are empty or already a pointer.
+func Clean(r io.Reader, store ObjectStore, sinks ...*CleanSink) (*Pointer, error) {
+	return CleanFile(r, store, "", -1, nil, sinks...)
+}
+
+// CleanFile is Clean for the contents of the working file "fileName", which is
+// given to any extensions, and are "fileSize" bytes long, or of an unknown size
+// if it is negative. If "cb" is not nil, it is called as they are read. If they
+// are an unmodified placeholder written by WritePlaceholder, the pointer it
+// stands in for is returned, and nothing is stored. If the store already has an
+// object with the OID they hash to, but of another size, it is left alone, and
+// an error is returned.
+func CleanFile(r io.Reader, store ObjectStore, fileName string, fileSize int64, cb progress.CopyCallback, sinks ...*CleanSink) (*Pointer, error) {
+	cleaned, err := PointerClean(r, fileName, fileSize, cb, sinks...)
+	if err != nil {
+		return nil, err
+	}
+	defer cleaned.Teardown()
+
+	if len(fileName) > 0 {
+		if ptr, ok := PlaceholderPointer(fileName, cleaned.Oid, cleaned.Size); ok {
+			return ptr, nil
+		}
+	}
+
+	if store.Exists(cleaned.Oid, cleaned.Size) {
+		tracerx.Printf("clean: %s exists", cleaned.Oid)
+		return cleaned.Pointer, nil
+	}
+
+	// An object of another size is never overwritten, as it may be
+	// corrupt, and should be left for fsck to find.
+	if existing, err := store.Get(cleaned.Oid); err == nil {
+		existing.Close()
+		if len(cleaned.Extensions) == 0 {
+			return nil, errors.Errorf("files don't match: %s is in the object store with a different size than %d", cleaned.Oid, cleaned.Size)
+		}
+		tracerx.Printf("clean: %s exists", cleaned.Oid)
+		return cleaned.Pointer, nil
+	}
+
+	if err := store.Put(cleaned.Oid, cleaned.Filename); err != nil {
+		return nil, errors.Wrapf(err, "unable to store %s", cleaned.Oid)
+	}
+	tracerx.Printf("clean: stored %s", cleaned.Oid)
+
+	if config.Config.IntegrityManifest() {
+		if err := RecordIntegrity(cleaned.Oid, cleaned.Size); err != nil {
+			tracerx.Printf("could not record %s in the integrity manifest: %s", cleaned.Oid, err)
+		}
+	}
+	return cleaned.Pointer, nil
+}
+
 func copyToTemp(reader io.Reader, fileSize int64, cb progress.CopyCallback) (oid string, size int64, tmp *os.File, err error) {
 	tmp, err = TempFile("")
 	if err != nil {
@@ -69,28 +157,30 @@ func copyToTemp(reader io.Reader, fileSize int64, cb progress.CopyCallback) (oid
 
 	defer tmp.Close()
 
-	oidHash := sha256.New()
+	oidHash := tools.NewLfsContentHash()
 	writer := io.MultiWriter(oidHash, tmp)
 
 	if fileSize == 0 {
 		cb = nil
 	}
 
-	ptr, buf, err := DecodeFrom(reader)
-
-	by := make([]byte, blobSizeCutoff)
-	n, rerr := buf.Read(by)
+	by := make([]byte, blobSizeCutoff())
+	n, rerr := reader.Read(by)
 	by = by[:n]
 
-	if rerr != nil || (err == nil && len(by) < 512) {
-		err = errors.NewCleanPointerError(ptr, by)
+	if rerr == io.EOF {
+		err = errors.NewCleanPointerError(nil, by)
+		return
+	} else if rerr != nil {
+		err = rerr
 		return
 	}
 
 	var from io.Reader = bytes.NewReader(by)
-	if int64(len(by)) < fileSize {
-		// If there is still more data to be read from the file, tack on
-		// the original reader and continue the read from there.
+	if fileSize < 0 || int64(len(by)) < fileSize {
+		// If there is still more data to be read from the file, or
+		// there may be, as its size is not known, tack on the rest of
+		// the reader, and continue the read from there.
 		from = io.MultiReader(from, reader)
 	}
 
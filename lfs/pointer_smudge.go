@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/git-lfs/git-lfs/logger"
 	"github.com/git-lfs/git-lfs/tools"
 	"github.com/git-lfs/git-lfs/tools/humanize"
 	"github.com/git-lfs/git-lfs/tq"
@@ -16,31 +17,143 @@ import (
 	"github.com/rubyist/tracerx"
 )
 
+// PointerSmudgeToFile smudges "ptr" into the working directory file at
+// "filename". The contents are written to a temporary file alongside it, which
+// is only renamed into place once it has been written in full, so that a
+// failure part way through, such as running out of disk space, never leaves a
+// truncated file behind. In that case, the object in the local media directory
+// is left intact, so that retrying once space has been freed does not download
+// it again.
+//
+// The object is downloaded, if need be, before the temporary file is created,
+// so that the two files it is copied between are only counted against the
+// open file limit once nothing else has to be opened to smudge it.
 func PointerSmudgeToFile(filename string, ptr *Pointer, download bool, manifest *tq.Manifest, cb progress.CopyCallback) error {
+	src, err := prepareSmudge(ptr, filename, download, manifest, cb)
+
+	release := tools.AcquireFiles(2)
+	defer release()
+
 	os.MkdirAll(filepath.Dir(filename), 0755)
-	file, err := os.Create(filename)
-	if err != nil {
-		return fmt.Errorf("Could not create working directory file: %v", err)
-	}
-	defer file.Close()
-	if _, err := PointerSmudge(file, ptr, filename, download, manifest, cb); err != nil {
-		if errors.IsDownloadDeclinedError(err) {
-			// write placeholder data instead
-			file.Seek(0, os.SEEK_SET)
-			ptr.Encode(file)
-			return err
-		} else {
-			return fmt.Errorf("Could not write working directory file: %v", err)
+	file, ferr := createSmudgeTempFile(filename)
+	if ferr != nil {
+		return fmt.Errorf("Could not create working directory file: %v", ferr)
+	}
+	defer os.Remove(file.Name())
+
+	written := &countingWriter{w: file}
+	if err == nil {
+		_, err = src.read(written, ptr, filename)
+	}
+	if errors.IsDownloadDeclinedError(err) {
+		// write placeholder data instead
+		if _, perr := ptr.Encode(written); perr != nil {
+			err = perr
 		}
 	}
-	return nil
+
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil && !errors.IsDownloadDeclinedError(err) {
+		if isDiskFullError(err) {
+			return fmt.Errorf("Not enough disk space to write working directory file %s: wrote %d of %d bytes (%d bytes short)",
+				filename, written.n, ptr.Size, ptr.Size-written.n)
+		}
+		return fmt.Errorf("Could not write working directory file: %v", err)
+	}
+
+	if rerr := os.Rename(file.Name(), filename); rerr != nil {
+		return fmt.Errorf("Could not write working directory file: %v", rerr)
+	}
+	return err
+}
+
+// createSmudgeTempFile creates a temporary file in the same directory as
+// "filename", so that it can be renamed into place. If "filename" already
+// exists, the temporary file is given the same mode, otherwise it is created
+// with the same mode as os.Create would use.
+func createSmudgeTempFile(filename string) (*os.File, error) {
+	var mode os.FileMode = 0666
+	if stat, err := os.Stat(filename); err == nil {
+		mode = stat.Mode().Perm()
+	}
+
+	dir, base := filepath.Split(filename)
+	for i := 0; ; i++ {
+		tmp := filepath.Join(dir, fmt.Sprintf(".%s.lfs-smudge-%d-%d", base, os.Getpid(), i))
+		f, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_EXCL, mode)
+		if os.IsExist(err) && i < 100 {
+			continue
+		}
+		if err == nil && mode != 0666 {
+			// Honor the existing file's mode exactly, rather than
+			// having it masked by the umask.
+			if err = f.Chmod(mode); err != nil {
+				f.Close()
+				os.Remove(tmp)
+				return nil, err
+			}
+		}
+		return f, err
+	}
+}
+
+// countingWriter is an io.Writer which counts the number of bytes written
+// through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
 }
 
 func PointerSmudge(writer io.Writer, ptr *Pointer, workingfile string, download bool, manifest *tq.Manifest, cb progress.CopyCallback) (int64, error) {
-	mediafile, err := LocalMediaPath(ptr.Oid)
+	src, err := prepareSmudge(ptr, workingfile, download, manifest, cb)
 	if err != nil {
 		return 0, err
 	}
+	return src.read(writer, ptr, workingfile)
+}
+
+// smudgeSource is where the contents of an object are read from to smudge it,
+// once prepareSmudge has made sure that they are there.
+type smudgeSource struct {
+	// mediafile is the path of the object in the local media directory.
+	mediafile string
+	// altfile is the path of the object in one of the object alternates,
+	// if it is read from there instead, or empty.
+	altfile string
+	// cb is given the progress of reading the object, or nil, if it was
+	// given the progress of downloading it instead.
+	cb progress.CopyCallback
+}
+
+// prepareSmudge finds the object that "ptr" points to, downloading it into the
+// local media directory if it is not available locally and "download" is true,
+// and returns where to read it from. It returns nil if there is nothing to read.
+func prepareSmudge(ptr *Pointer, workingfile string, download bool, manifest *tq.Manifest, cb progress.CopyCallback) (*smudgeSource, error) {
+	if ptr.Size == 0 && ptr.Oid == emptyOid {
+		// Git LFS never cleans empty files into pointers, but others
+		// might: there is nothing to download into an empty file. Any
+		// other OID cannot be right, and is looked up as usual, so that
+		// it fails.
+		return nil, nil
+	}
+
+	mediafile, err := LocalMediaPath(ptr.Oid)
+	if err != nil {
+		// A read-only store can still smudge the objects it has.
+		if !ObjectStorage().Exists(ptr.Oid, ptr.Size) {
+			return nil, err
+		}
+		mediafile = LocalMediaPathReadOnly(ptr.Oid)
+	}
 
 	LinkOrCopyFromReference(ptr.Oid, ptr.Size)
 
@@ -50,38 +163,69 @@ func PointerSmudge(writer io.Writer, ptr *Pointer, workingfile string, download
 		if fileSize == 0 || fileSize != ptr.Size {
 			tracerx.Printf("Removing %s, size %d is invalid", mediafile, fileSize)
 			os.RemoveAll(mediafile)
-			stat = nil
 		}
 	}
 
-	var n int64
-
-	if statErr != nil || stat == nil {
-		if download {
-			n, err = downloadFile(writer, ptr, workingfile, mediafile, manifest, cb)
+	src := &smudgeSource{mediafile: mediafile, cb: cb}
+	if !ObjectStorage().Exists(ptr.Oid, ptr.Size) {
+		if altfile, ok := AlternateMediaPath(ptr.Oid, ptr.Size); ok {
+			src.altfile = altfile
+		} else if download {
+			if err := downloadFile(ptr, workingfile, mediafile, manifest, cb); err != nil {
+				return nil, errors.NewSmudgeError(err, ptr.Oid, mediafile)
+			}
+			src.cb = nil
 		} else {
-			return 0, errors.NewDownloadDeclinedError(statErr, "smudge")
+			return nil, errors.NewDownloadDeclinedError(statErr, "smudge")
 		}
+	}
+	return src, nil
+}
+
+// read writes the contents of the object that "ptr" points to into "writer".
+func (s *smudgeSource) read(writer io.Writer, ptr *Pointer, workingfile string) (int64, error) {
+	if s == nil {
+		return 0, nil
+	}
+
+	var n int64
+	var err error
+	if len(s.altfile) > 0 {
+		n, err = readAlternateFile(writer, ptr, s.altfile, workingfile, s.cb)
 	} else {
-		n, err = readLocalFile(writer, ptr, mediafile, workingfile, cb)
+		n, err = readLocalFile(writer, ptr, s.mediafile, workingfile, s.cb)
 	}
 
 	if err != nil {
-		return 0, errors.NewSmudgeError(err, ptr.Oid, mediafile)
+		return 0, errors.NewSmudgeError(err, ptr.Oid, s.mediafile)
 	}
 
 	return n, nil
 }
 
-func downloadFile(writer io.Writer, ptr *Pointer, workingfile, mediafile string, manifest *tq.Manifest, cb progress.CopyCallback) (int64, error) {
-	fmt.Fprintf(os.Stderr, "Downloading %s (%s)\n", workingfile, humanize.FormatBytes(uint64(ptr.Size)))
+func downloadFile(ptr *Pointer, workingfile, mediafile string, manifest *tq.Manifest, cb progress.CopyCallback) error {
+	if err := CheckStoreLayout(); err != nil {
+		return err
+	}
+
+	logger.Log(logger.Info, logger.Fields{"path": workingfile, "oid": ptr.Oid, "size": ptr.Size},
+		"Downloading %s (%s)", workingfile, humanize.FormatBytes(uint64(ptr.Size)))
 
 	// NOTE: if given, "cb" is a progress.CopyCallback which writes updates
 	// to the logpath specified by GIT_LFS_PROGRESS.
 	//
 	// Either way, forward it into the *tq.TransferQueue so that updates are
 	// sent over correctly.
-	q := tq.NewTransferQueue(tq.Download, manifest, "", tq.WithProgressCallback(cb))
+	options := []tq.Option{tq.WithProgressCallback(cb)}
+	if config.Config.IntegrityManifest() {
+		options = append(options, tq.WithCompletionCallback(func(t *tq.Transfer) {
+			if err := RecordIntegrity(t.Oid, t.Size); err != nil {
+				tracerx.Printf("could not record %s in the integrity manifest: %s", t.Oid, err)
+			}
+		}))
+	}
+
+	q := tq.NewTransferQueue(tq.Download, manifest, "", options...)
 	q.Add(filepath.Base(workingfile), mediafile, ptr.Oid, ptr.Size)
 	q.Wait()
 
@@ -93,20 +237,37 @@ func downloadFile(writer io.Writer, ptr *Pointer, workingfile, mediafile string,
 			} else {
 				multiErr = e
 			}
-			return 0, errors.Wrapf(multiErr, "Error downloading %s (%s)", workingfile, ptr.Oid)
+			return errors.Wrapf(multiErr, "Error downloading %s (%s)", workingfile, ptr.Oid)
 		}
 	}
 
-	return readLocalFile(writer, ptr, mediafile, workingfile, nil)
+	return nil
 }
 
 func readLocalFile(writer io.Writer, ptr *Pointer, mediafile string, workingfile string, cb progress.CopyCallback) (int64, error) {
-	reader, err := os.Open(mediafile)
+	reader, err := ObjectStorage().Get(ptr.Oid)
 	if err != nil {
 		return 0, errors.Wrapf(err, "Error opening media file.")
 	}
 	defer reader.Close()
 
+	return readMediaFile(writer, ptr, reader, mediafile, workingfile, cb)
+}
+
+// readAlternateFile reads the object that "ptr" points to from "altfile", in
+// one of the object alternates, without copying it to the local media
+// directory.
+func readAlternateFile(writer io.Writer, ptr *Pointer, altfile string, workingfile string, cb progress.CopyCallback) (int64, error) {
+	reader, err := os.Open(altfile)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Error opening media file.")
+	}
+	defer reader.Close()
+
+	return readMediaFile(writer, ptr, reader, altfile, workingfile, cb)
+}
+
+func readMediaFile(writer io.Writer, ptr *Pointer, reader io.Reader, mediafile string, workingfile string, cb progress.CopyCallback) (int64, error) {
 	if ptr.Size == 0 {
 		if stat, _ := os.Stat(mediafile); stat != nil {
 			ptr.Size = stat.Size()
@@ -169,11 +330,12 @@ func readLocalFile(writer io.Writer, ptr *Pointer, mediafile string, workingfile
 		}
 
 		// setup reader
-		reader, err = os.Open(response.file.Name())
+		smudged, err := os.Open(response.file.Name())
 		if err != nil {
 			return 0, errors.Wrapf(err, "Error opening smudged file: %s", err)
 		}
-		defer reader.Close()
+		defer smudged.Close()
+		reader = smudged
 	}
 
 	n, err := tools.CopyWithCallback(writer, reader, ptr.Size, cb)
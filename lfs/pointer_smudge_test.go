@@ -0,0 +1,107 @@
+package lfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPointerSmudgeToFileReplacesFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "smudge-to-file")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	store := &memoryObjectStore{dir: dir, objects: make(map[string][]byte)}
+	SetObjectStore(store)
+	defer SetObjectStore(nil)
+
+	oid := "d1b2a59fbea7e20077af9f91b27e95e865061b270be03ff539ab3b73587882e8"
+	store.objects[oid] = []byte("contents")
+
+	filename := filepath.Join(dir, "work", "a.dat")
+	require.Nil(t, os.MkdirAll(filepath.Dir(filename), 0755))
+	require.Nil(t, ioutil.WriteFile(filename, []byte("pointer"), 0755))
+
+	require.Nil(t, PointerSmudgeToFile(filename, NewPointer(oid, 8, nil), false, nil, nil))
+
+	by, err := ioutil.ReadFile(filename)
+	require.Nil(t, err)
+	assert.Equal(t, "contents", string(by))
+
+	if runtime.GOOS != "windows" {
+		stat, err := os.Stat(filename)
+		require.Nil(t, err)
+		assert.Equal(t, os.FileMode(0755), stat.Mode().Perm())
+	}
+
+	files, err := ioutil.ReadDir(filepath.Dir(filename))
+	require.Nil(t, err)
+	assert.Len(t, files, 1, "temporary files should not remain")
+}
+
+func TestPointerSmudgeToFileWritesEmptyFileForEmptyPointer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "smudge-to-file")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	SetObjectStore(&memoryObjectStore{dir: dir, objects: make(map[string][]byte)})
+	defer SetObjectStore(nil)
+
+	// Nothing needs to be downloaded, or even present locally.
+	ptr := NewPointer("e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", 0, nil)
+	filename := filepath.Join(dir, "work", "empty.dat")
+	require.Nil(t, os.MkdirAll(filepath.Dir(filename), 0755))
+	require.Nil(t, ioutil.WriteFile(filename, []byte(ptr.Encoded()), 0644))
+
+	require.Nil(t, PointerSmudgeToFile(filename, ptr, false, nil, nil))
+
+	by, err := ioutil.ReadFile(filename)
+	require.Nil(t, err)
+	assert.Empty(t, by)
+}
+
+func TestPointerSmudgeToFileDoesNotTrustEmptyPointerWithOtherOid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "smudge-to-file")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	SetObjectStore(&memoryObjectStore{dir: dir, objects: make(map[string][]byte)})
+	defer SetObjectStore(nil)
+
+	// A size of 0 does not make the object empty, so it is not available
+	// without being downloaded.
+	ptr := NewPointer("d1b2a59fbea7e20077af9f91b27e95e865061b270be03ff539ab3b73587882e8", 0, nil)
+	filename := filepath.Join(dir, "work", "a.dat")
+
+	err = PointerSmudgeToFile(filename, ptr, false, nil, nil)
+	assert.True(t, errors.IsDownloadDeclinedError(err))
+}
+
+func TestPointerSmudgeToFileWritesPointerWhenDeclined(t *testing.T) {
+	dir, err := ioutil.TempDir("", "smudge-to-file")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	SetObjectStore(&memoryObjectStore{dir: dir, objects: make(map[string][]byte)})
+	defer SetObjectStore(nil)
+
+	ptr := NewPointer("d1b2a59fbea7e20077af9f91b27e95e865061b270be03ff539ab3b73587882e8", 8, nil)
+	filename := filepath.Join(dir, "work", "a.dat")
+
+	err = PointerSmudgeToFile(filename, ptr, false, nil, nil)
+	assert.True(t, errors.IsDownloadDeclinedError(err))
+
+	by, err := ioutil.ReadFile(filename)
+	require.Nil(t, err)
+	assert.Equal(t, ptr.Encoded(), string(by))
+
+	files, err := ioutil.ReadDir(filepath.Dir(filename))
+	require.Nil(t, err)
+	assert.Len(t, files, 1, "temporary files should not remain")
+}
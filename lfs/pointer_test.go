@@ -3,13 +3,17 @@ package lfs
 import (
 	"bufio"
 	"bytes"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/git-lfs/git-lfs/config"
 	"github.com/git-lfs/git-lfs/errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestEncode(t *testing.T) {
@@ -30,6 +34,23 @@ func TestEncode(t *testing.T) {
 	assert.Equal(t, "EOF", err.Error())
 }
 
+func TestEncodeVersion(t *testing.T) {
+	pointer := NewPointer("booya", 12345, nil)
+	pointer.Version = "https://git-lfs.github.com/spec/v2"
+
+	assert.Equal(t, "version https://git-lfs.github.com/spec/v2\noid sha256:booya\nsize 12345\n", pointer.Encoded())
+}
+
+func TestDecodeRejectsUnacceptedVersion(t *testing.T) {
+	_, err := DecodePointer(bytes.NewBufferString(`version https://git-lfs.github.com/spec/v2
+oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393
+size 12345`))
+
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "Invalid version: https://git-lfs.github.com/spec/v2", err.Error())
+	}
+}
+
 func TestEncodeEmpty(t *testing.T) {
 	var buf bytes.Buffer
 	pointer := NewPointer("", 0, nil)
@@ -68,6 +89,81 @@ func TestEncodeExtensions(t *testing.T) {
 	assert.Equal(t, "EOF", err.Error())
 }
 
+func TestEncodeWithNewline(t *testing.T) {
+	var buf bytes.Buffer
+	pointer := NewPointer("booya", 12345, nil)
+	_, err := EncodePointerWithNewline(&buf, pointer, true)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "version https://git-lfs.github.com/spec/v1\n"+
+		"oid sha256:booya\n"+
+		"size 12345\n", buf.String())
+}
+
+func TestEncodeWithoutNewline(t *testing.T) {
+	var buf bytes.Buffer
+	pointer := NewPointer("booya", 12345, nil)
+	_, err := EncodePointerWithNewline(&buf, pointer, false)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "version https://git-lfs.github.com/spec/v1\n"+
+		"oid sha256:booya\n"+
+		"size 12345", buf.String())
+}
+
+func TestEncodeWithoutNewlineEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	pointer := NewPointer("", 0, nil)
+	_, err := EncodePointerWithNewline(&buf, pointer, false)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "", buf.String())
+}
+
+func TestDecodeWithoutNewline(t *testing.T) {
+	ex := "version https://git-lfs.github.com/spec/v1\n" +
+		"oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393\n" +
+		"size 12345"
+
+	p, err := DecodePointer(bytes.NewBufferString(ex))
+	assert.Nil(t, err)
+	assert.Equal(t, "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393", p.Oid)
+	assert.Equal(t, int64(12345), p.Size)
+}
+
+func TestDecodeTrailingWhitespace(t *testing.T) {
+	examples := []string{
+		// a stray space after the OID
+		"version https://git-lfs.github.com/spec/v1\n" +
+			"oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393 \n" +
+			"size 12345\n",
+
+		// whitespace after every value
+		"version https://git-lfs.github.com/spec/v1 \n" +
+			"oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393\t \n" +
+			"size 12345  \n",
+
+		// carriage returns, as from a checkout with CRLF line endings
+		"version https://git-lfs.github.com/spec/v1\r\n" +
+			"oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393\r\n" +
+			"size 12345\r\n",
+	}
+
+	canonical := "version https://git-lfs.github.com/spec/v1\n" +
+		"oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393\n" +
+		"size 12345\n"
+
+	for _, ex := range examples {
+		p, err := DecodePointer(bytes.NewBufferString(ex))
+		if !assert.Nil(t, err, "Example:\n%q", ex) {
+			continue
+		}
+		assertEqualWithExample(t, ex, "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393", p.Oid)
+		assertEqualWithExample(t, ex, int64(12345), p.Size)
+		assertEqualWithExample(t, ex, canonical, p.Encoded())
+	}
+}
+
 func assertLine(t *testing.T, r *bufio.Reader, expected string) {
 	actual, err := r.ReadString('\n')
 	assert.Nil(t, err)
@@ -99,6 +195,40 @@ size 12345`
 	assertEqualWithExample(t, ex, int64(12345), p.Size)
 }
 
+func TestDecodeUpperCaseOid(t *testing.T) {
+	ex := `version https://git-lfs.github.com/spec/v1
+ext-0-foo sha256:FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF
+oid sha256:4D7A214614AB2935C943F9E0FF69D22EADBB8F32B1258DAAA5E2CA24D17E2393
+size 12345`
+
+	p, err := DecodePointer(bytes.NewBufferString(ex))
+	require.Nil(t, err)
+	assert.Equal(t, "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393", p.Oid)
+	require.Len(t, p.Extensions, 1)
+	assert.Equal(t, "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", p.Extensions[0].Oid)
+
+	assert.Equal(t, []string{
+		"4D7A214614AB2935C943F9E0FF69D22EADBB8F32B1258DAAA5E2CA24D17E2393",
+		"FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF",
+	}, NonCanonicalOids([]byte(ex)))
+	assert.Empty(t, NonCanonicalOids([]byte(p.Encoded())))
+}
+
+func TestDecodeUpperCaseOidStrict(t *testing.T) {
+	oldConfig := config.Config
+	config.Config = config.NewFrom(config.Values{
+		Git: map[string][]string{"lfs.pointer.strictoids": []string{"true"}},
+	})
+	defer func() { config.Config = oldConfig }()
+
+	_, err := DecodePointer(bytes.NewBufferString(`version https://git-lfs.github.com/spec/v1
+oid sha256:4D7A214614AB2935C943F9E0FF69D22EADBB8F32B1258DAAA5E2CA24D17E2393
+size 12345`))
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "Invalid Oid: 4D7A214614AB2935C943F9E0FF69D22EADBB8F32B1258DAAA5E2CA24D17E2393 is not in lower case", err.Error())
+	}
+}
+
 func TestDecodeExtensions(t *testing.T) {
 	ex := `version https://git-lfs.github.com/spec/v1
 ext-0-foo sha256:ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff
@@ -178,6 +308,71 @@ func TestDecodeFromEmptyReader(t *testing.T) {
 	assert.Empty(t, by)
 }
 
+// endlessPointerReader reads as the header of a pointer, followed by an endless
+// run of extension lines, counting the bytes read from it.
+type endlessPointerReader struct {
+	header io.Reader
+	read   int
+}
+
+func (r *endlessPointerReader) Read(p []byte) (int, error) {
+	n, _ := r.header.Read(p)
+	for ; n < len(p); n++ {
+		p[n] = 'x'
+	}
+	r.read += n
+	return n, nil
+}
+
+func TestDecodeHugePointer(t *testing.T) {
+	r := &endlessPointerReader{header: strings.NewReader(`version https://git-lfs.github.com/spec/v1
+ext-0-foo sha256:`)}
+
+	p, buf, err := DecodeFrom(r)
+	assert.Nil(t, p)
+	assert.True(t, errors.IsNotAPointerError(err))
+	assert.Equal(t, "Pointer file error: pointer exceeds the maximum size of 4096 bytes", err.Error())
+	assert.Equal(t, 4097, r.read)
+
+	// The contents read so far are still returned, followed by the rest.
+	by := make([]byte, 10)
+	_, rerr := io.ReadFull(buf, by)
+	assert.Nil(t, rerr)
+	assert.Equal(t, "version ht", string(by))
+}
+
+func TestDecodeMaxSize(t *testing.T) {
+	ex := `version https://git-lfs.github.com/spec/v1
+oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393
+size 12345`
+
+	p, _, err := decodeFrom(strings.NewReader(ex), len(ex))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(12345), p.Size)
+
+	p, buf, err := decodeFrom(strings.NewReader(ex), len(ex)-1)
+	assert.Nil(t, p)
+	assert.True(t, errors.IsNotAPointerError(err))
+
+	by, rerr := ioutil.ReadAll(buf)
+	assert.Nil(t, rerr)
+	assert.Equal(t, ex, string(by))
+}
+
+func TestDecodeMaximumExtensions(t *testing.T) {
+	var ex bytes.Buffer
+	ex.WriteString("version https://git-lfs.github.com/spec/v1\n")
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(&ex, "ext-%d-%s sha256:%s\n", i, strings.Repeat("e", 32), strings.Repeat("a", 64))
+	}
+	ex.WriteString("oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393\n")
+	ex.WriteString("size 12345\n")
+
+	p, err := DecodePointer(&ex)
+	assert.Nil(t, err)
+	assert.Len(t, p.Extensions, 10)
+}
+
 func TestDecodeInvalid(t *testing.T) {
 	examples := []string{
 		"invalid stuff",
@@ -188,6 +383,26 @@ func TestDecodeInvalid(t *testing.T) {
 		// bad oid
 		`version https://git-lfs.github.com/spec/v1
 oid sha256:boom
+size 12345`,
+
+		// oid too long
+		`version https://git-lfs.github.com/spec/v1
+oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e23930
+size 12345`,
+
+		// oid too short, with whitespace after it
+		"version https://git-lfs.github.com/spec/v1\n" +
+			"oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e239 \n" +
+			"size 12345",
+
+		// oid with whitespace inside it
+		`version https://git-lfs.github.com/spec/v1
+oid sha256:4d7a214614ab2935c943f9e0ff69d22e adbb8f32b1258daaa5e2ca24d17e2393
+size 12345`,
+
+		// oid which is not hex
+		`version https://git-lfs.github.com/spec/v1
+oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e239z
 size 12345`,
 
 		// bad oid type
@@ -0,0 +1,115 @@
+package lfs
+
+import "sort"
+
+// RefDiff is the difference between the Git LFS objects in the trees of two
+// refs, by path. A file which is moved is both removed from its old path and
+// added at its new one.
+type RefDiff struct {
+	// Added are the pointers at paths which only the second ref has.
+	Added []*WrappedPointer
+	// Removed are the pointers at paths which only the first ref has.
+	Removed []*WrappedPointer
+	// Modified are the paths which point to different objects in each.
+	Modified []*ModifiedPointer
+}
+
+// ModifiedPointer is a path which points to the object "From" in the first of
+// two refs, and to "To" in the second.
+type ModifiedPointer struct {
+	Name string
+	From *WrappedPointer
+	To   *WrappedPointer
+}
+
+// SizeDelta returns the number of bytes by which the objects in the second ref
+// are larger than those in the first, in total, which is negative if they are
+// smaller.
+func (d *RefDiff) SizeDelta() int64 {
+	var delta int64
+	for _, p := range d.Added {
+		delta += p.Size
+	}
+	for _, p := range d.Removed {
+		delta -= p.Size
+	}
+	for _, m := range d.Modified {
+		delta += m.To.Size - m.From.Size
+	}
+	return delta
+}
+
+// DiffRefs compares the pointers in the tree at the ref "a" with those in the
+// tree at "b", by path and oid, and returns which were added, removed, and
+// modified in going from "a" to "b". Each list is in order of path.
+func DiffRefs(a, b string) (*RefDiff, error) {
+	from, err := treePointersByName(a)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := treePointersByName(b)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &RefDiff{
+		Added:    make([]*WrappedPointer, 0),
+		Removed:  make([]*WrappedPointer, 0),
+		Modified: make([]*ModifiedPointer, 0),
+	}
+
+	for name, p := range to {
+		prev, ok := from[name]
+		if !ok {
+			diff.Added = append(diff.Added, p)
+		} else if prev.Oid != p.Oid {
+			diff.Modified = append(diff.Modified, &ModifiedPointer{
+				Name: name, From: prev, To: p,
+			})
+		}
+	}
+
+	for name, p := range from {
+		if _, ok := to[name]; !ok {
+			diff.Removed = append(diff.Removed, p)
+		}
+	}
+
+	sort.Slice(diff.Added, func(i, j int) bool {
+		return diff.Added[i].Name < diff.Added[j].Name
+	})
+	sort.Slice(diff.Removed, func(i, j int) bool {
+		return diff.Removed[i].Name < diff.Removed[j].Name
+	})
+	sort.Slice(diff.Modified, func(i, j int) bool {
+		return diff.Modified[i].Name < diff.Modified[j].Name
+	})
+
+	return diff, nil
+}
+
+// treePointersByName returns the pointers in the tree at "ref", keyed by path.
+func treePointersByName(ref string) (map[string]*WrappedPointer, error) {
+	pointers := make(map[string]*WrappedPointer)
+
+	var scanErr error
+	gitscanner := NewGitScanner(func(p *WrappedPointer, err error) {
+		if err != nil {
+			if scanErr == nil {
+				scanErr = err
+			}
+			return
+		}
+		pointers[p.Name] = p
+	})
+	defer gitscanner.Close()
+
+	if err := gitscanner.ScanTree(ref); err != nil {
+		return nil, err
+	}
+	if scanErr != nil {
+		return nil, scanErr
+	}
+	return pointers, nil
+}
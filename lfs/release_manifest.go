@@ -0,0 +1,69 @@
+package lfs
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/errors"
+)
+
+// ReleaseEntry is an object which a release manifest lists as belonging to a
+// release, such as a tag, with the size it is expected to have. Name is the
+// name of the file it is for, if the manifest gives one.
+type ReleaseEntry struct {
+	Oid  string
+	Size int64
+	Name string
+}
+
+// ParseReleaseManifest parses the release manifest read from "r", which lists
+// an object on each line, as its OID and size, optionally followed by the name
+// of the file it is for. Blank lines, and lines which start with "#", are
+// ignored.
+func ParseReleaseManifest(r io.Reader) ([]*ReleaseEntry, error) {
+	var entries []*ReleaseEntry
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		entry, err := parseReleaseEntry(line)
+		if err != nil {
+			return nil, errors.Wrapf(err, "release manifest line %d", n)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "release manifest")
+	}
+	return entries, nil
+}
+
+func parseReleaseEntry(line string) (*ReleaseEntry, error) {
+	fields := strings.SplitN(line, " ", 3)
+	if len(fields) < 2 {
+		return nil, errors.Errorf("malformed entry: %q", line)
+	}
+
+	// OIDs are listed as Git LFS writes them, in lower case.
+	if !oidRE.MatchString(fields[0]) || fields[0] != strings.ToLower(fields[0]) {
+		return nil, errors.Errorf("malformed oid: %q", fields[0])
+	}
+
+	size, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil || size < 0 {
+		return nil, errors.Errorf("malformed size: %q", fields[1])
+	}
+
+	entry := &ReleaseEntry{Oid: fields[0], Size: size}
+	if len(fields) == 3 {
+		entry.Name = strings.TrimSpace(fields[2])
+	}
+	return entry, nil
+}
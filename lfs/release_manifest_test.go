@@ -0,0 +1,43 @@
+package lfs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReleaseManifest(t *testing.T) {
+	entries, err := ParseReleaseManifest(strings.NewReader(
+		"# release v1.0\n" +
+			"\n" +
+			"4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393 12345\n" +
+			"ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb 1 docs/a file.txt\n"))
+	require.Nil(t, err)
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393", entries[0].Oid)
+	assert.EqualValues(t, 12345, entries[0].Size)
+	assert.Empty(t, entries[0].Name)
+
+	assert.Equal(t, "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb", entries[1].Oid)
+	assert.EqualValues(t, 1, entries[1].Size)
+	assert.Equal(t, "docs/a file.txt", entries[1].Name)
+}
+
+func TestParseReleaseManifestInvalid(t *testing.T) {
+	for desc, manifest := range map[string]string{
+		"missing size": "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393\n",
+		"short oid":    "4d7a2146 12345\n",
+		"upper oid":    "4D7A214614AB2935C943F9E0FF69D22EADBB8F32B1258DAAA5E2CA24D17E2393 12345\n",
+		"bad size":     "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393 abc\n",
+		"negative":     "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393 -1\n",
+	} {
+		_, err := ParseReleaseManifest(strings.NewReader(manifest))
+		assert.NotNil(t, err, desc)
+		if err != nil {
+			assert.Contains(t, err.Error(), "release manifest line 1", desc)
+		}
+	}
+}
@@ -1,12 +1,11 @@
 package lfs
 
-import "github.com/git-lfs/git-lfs/tools"
+import (
+	"github.com/git-lfs/git-lfs/config"
+	"github.com/git-lfs/git-lfs/tools"
+)
 
 const (
-	// blobSizeCutoff is used to determine which files to scan for Git LFS
-	// pointers.  Any file with a size below this cutoff will be scanned.
-	blobSizeCutoff = 1024
-
 	// stdoutBufSize is the size of the buffers given to a sub-process stdout
 	stdoutBufSize = 16384
 
@@ -15,6 +14,13 @@ const (
 	chanBufSize = 100
 )
 
+// blobSizeCutoff is used to determine which files to scan for Git LFS pointers.
+// Any file no larger than this cutoff, which is the largest size a pointer may
+// have, as lfs.pointer.maxsize gives, will be scanned.
+func blobSizeCutoff() int {
+	return config.Config.PointerMaxSize()
+}
+
 // WrappedPointer wraps a pointer.Pointer and provides the git sha1
 // and the file name associated with the object, taken from the
 // rev-list output.
@@ -26,9 +32,17 @@ type WrappedPointer struct {
 	*Pointer
 }
 
+// InvalidPointer is a blob which looks like a Git LFS pointer, but is not a
+// valid one, along with the name it was found at, if any, and the reason why.
+type InvalidPointer struct {
+	Sha1 string
+	Name string
+	Err  error
+}
+
 // catFileBatchCheck uses git cat-file --batch-check to get the type
 // and size of a git object. Any object that isn't of type blob and
-// under the blobSizeCutoff will be ignored. revs is a channel over
+// within the blobSizeCutoff will be ignored. revs is a channel over
 // which strings containing git sha1s will be sent. It returns a channel
 // from which sha1 strings can be read.
 func catFileBatchCheck(revs *StringChannelWrapper, lockableSet *lockableNameSet) (*StringChannelWrapper, chan string, error) {
@@ -45,11 +59,13 @@ func catFileBatchCheck(revs *StringChannelWrapper, lockableSet *lockableNameSet)
 // of a git object, given its sha1. The contents will be decoded into
 // a Git LFS pointer. revs is a channel over which strings containing Git SHA1s
 // will be sent. It returns a channel from which point.Pointers can be read.
-func catFileBatch(revs *StringChannelWrapper, lockableSet *lockableNameSet) (*PointerChannelWrapper, chan string, error) {
+// Invalid pointers are sent to invalidCh, which may be nil, and which is closed
+// once the scan is done.
+func catFileBatch(revs *StringChannelWrapper, lockableSet *lockableNameSet, invalidCh chan *InvalidPointer) (*PointerChannelWrapper, chan string, error) {
 	pointerCh := make(chan *WrappedPointer, chanBufSize)
 	lockableCh := make(chan string, chanBufSize)
 	errCh := make(chan error, 5) // shared by 2 goroutines & may add more detail errors?
-	if err := runCatFileBatch(pointerCh, lockableCh, lockableSet, revs, errCh); err != nil {
+	if err := runCatFileBatch(pointerCh, lockableCh, lockableSet, invalidCh, revs, errCh); err != nil {
 		return nil, nil, err
 	}
 	return NewPointerChannelWrapper(pointerCh, errCh), lockableCh, nil
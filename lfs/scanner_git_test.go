@@ -204,3 +204,58 @@ func scanPreviousVersions(t *testing.T, ref string, since time.Time) ([]*Wrapped
 	err := gitscanner.ScanPreviousVersions(ref, since, nil)
 	return pointers, err
 }
+
+func TestDiffRefs(t *testing.T) {
+	repo := test.NewRepo(t)
+	repo.Pushd()
+	defer func() {
+		repo.Popd()
+		repo.Cleanup()
+	}()
+
+	inputs := []*test.CommitInput{
+		{ // 0
+			Files: []*test.FileInput{
+				{Filename: "file1.txt", Size: 20},
+				{Filename: "file2.txt", Size: 30},
+			},
+		},
+		{ // 1
+			Files: []*test.FileInput{
+				{Filename: "file1.txt", Size: 25},
+				{Filename: "file3.txt", Size: 40},
+			},
+		},
+	}
+	outputs := repo.AddCommits(inputs)
+
+	diff, err := DiffRefs(outputs[0].Sha, outputs[1].Sha)
+	assert.Nil(t, err)
+	if assert.Len(t, diff.Added, 1) {
+		assert.Equal(t, "file3.txt", diff.Added[0].Name)
+		assert.Equal(t, outputs[1].Files[1].Oid, diff.Added[0].Oid)
+	}
+	assert.Empty(t, diff.Removed)
+	if assert.Len(t, diff.Modified, 1) {
+		assert.Equal(t, "file1.txt", diff.Modified[0].Name)
+		assert.Equal(t, outputs[0].Files[0].Oid, diff.Modified[0].From.Oid)
+		assert.Equal(t, outputs[1].Files[0].Oid, diff.Modified[0].To.Oid)
+	}
+	assert.EqualValues(t, 45, diff.SizeDelta())
+
+	diff, err = DiffRefs(outputs[1].Sha, outputs[0].Sha)
+	assert.Nil(t, err)
+	assert.Empty(t, diff.Added)
+	if assert.Len(t, diff.Removed, 1) {
+		assert.Equal(t, "file3.txt", diff.Removed[0].Name)
+	}
+	assert.Len(t, diff.Modified, 1)
+	assert.EqualValues(t, -45, diff.SizeDelta())
+
+	diff, err = DiffRefs(outputs[1].Sha, outputs[1].Sha)
+	assert.Nil(t, err)
+	assert.Empty(t, diff.Added)
+	assert.Empty(t, diff.Removed)
+	assert.Empty(t, diff.Modified)
+	assert.EqualValues(t, 0, diff.SizeDelta())
+}
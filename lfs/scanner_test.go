@@ -149,6 +149,26 @@ func TestLogScannerAdditionsNoFiltering(t *testing.T) {
 	assert.Nil(t, scanner.Pointer())
 }
 
+func TestLogScannerCommits(t *testing.T) {
+	r := strings.NewReader(pointerParseLogOutput)
+	scanner := newLogScanner(LogDiffAdditions, r)
+
+	commits := make(map[string]string)
+	for scanner.Scan() {
+		if p := scanner.Pointer(); p != nil {
+			commits[p.Name] = scanner.Commit()
+		}
+	}
+
+	assert.Equal(t, map[string]string{
+		"radial_1.png":         "07d571b413957508679042e45508af5945b3f1e5",
+		"radial_2.png":         "07d571b413957508679042e45508af5945b3f1e5",
+		"1D_Noise.png":         "60fde3d23553e10a55e2a32ed18c20f65edd91e7",
+		"waveNM.png":           "60fde3d23553e10a55e2a32ed18c20f65edd91e7",
+		"hobbit_5armies_2.mov": "64b3372e108daaa593412d5e1d9df8169a9547ea",
+	}, commits)
+}
+
 func TestLogScannerAdditionsFilterInclude(t *testing.T) {
 	r := strings.NewReader(pointerParseLogOutput)
 	scanner := newLogScanner(LogDiffAdditions, r)
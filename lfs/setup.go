@@ -107,6 +107,36 @@ func InstallFilters(opt InstallOptions, passThrough bool) error {
 	return filters.Install(opt)
 }
 
+// CheckFilters returns the keys of the "filter.lfs" section which Git does not
+// see as InstallFilters sets them. Since the filters which InstallFilters sets
+// when "passThrough" is true are just as valid, those are checked too, and
+// whichever of the two has fewer mismatches is returned, along with whether it
+// was the pass through one. The mismatches are empty if either is installed.
+func CheckFilters() (mismatches []*AttributeMismatch, passThrough bool) {
+	mismatches = filters.Check()
+	if len(mismatches) == 0 {
+		return nil, false
+	}
+
+	if pass := passFilters.Check(); len(pass) < len(mismatches) {
+		return pass, true
+	}
+	return mismatches, false
+}
+
+// CheckHooks returns the hooks which InstallHooks would install or upgrade,
+// because they are missing, or are not the current version, in the order they
+// are installed in.
+func CheckHooks() []*Hook {
+	var outdated []*Hook
+	for _, h := range hooks {
+		if !h.Installed() {
+			outdated = append(outdated, h)
+		}
+	}
+	return outdated
+}
+
 // UninstallFilters proxies into the Uninstall method on the Filters type to
 // remove all installed filters.
 func UninstallFilters(opt InstallOptions) error {
@@ -0,0 +1,158 @@
+package lfs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/git-lfs/git-lfs/localstorage"
+	"github.com/git-lfs/git-lfs/logger"
+	"github.com/rubyist/tracerx"
+)
+
+const (
+	// storeLayoutVersion is the version of the layout of the Git LFS
+	// storage directory which this version of Git LFS reads and writes,
+	// with each object at "objects/<oid[0:2]>/<oid[2:4]>/<oid>".
+	storeLayoutVersion = 1
+
+	// storeLayoutFile is the name of the file in the storage directory
+	// which records the version of its layout. It is kept beside the
+	// objects directory, rather than in it, so that anything listing the
+	// files there only finds objects.
+	storeLayoutFile = "layout"
+
+	storeLayoutPrefix = "git-lfs-store-layout "
+)
+
+var (
+	// storeLayoutChecks records which storage directories have been
+	// given to checkStoreLayout, so that each marker is only read once per
+	// process.
+	storeLayoutChecks   = make(map[string]*storeLayoutCheck)
+	storeLayoutChecksMu sync.Mutex
+)
+
+type storeLayoutCheck struct {
+	once sync.Once
+	err  error
+}
+
+// CheckStoreLayout returns an error if objects cannot be written to the local
+// object store, because it has a layout newer than this version of Git LFS
+// knows. It is called before objects are written there, by being cleaned,
+// downloaded or copied in, and writes the layout marker to stores which do not
+// have one yet.
+func CheckStoreLayout() error {
+	if _, ok := objectStore.(*fileObjectStore); !ok || localstorage.Objects() == nil {
+		return nil
+	}
+	// The layout is that of the storage directory the objects are in.
+	return checkStoreLayout(filepath.Dir(localstorage.Objects().RootDir))
+}
+
+// checkStoreLayout checks that this version of Git LFS knows the layout of the
+// storage directory "dir", once per process, and is called before an object is
+// written there. Stores without a layout marker, including those written before
+// it was introduced, have the marker written to them. If the marker gives a
+// layout newer than this version of Git LFS knows, as when the store is shared
+// with a newer version, an error is returned, so that nothing is written there.
+//
+// Reading objects never checks the marker, and a marker which cannot be written
+// or read does not stop objects from being written.
+func checkStoreLayout(dir string) error {
+	if len(dir) == 0 {
+		return nil
+	}
+
+	storeLayoutChecksMu.Lock()
+	c, ok := storeLayoutChecks[dir]
+	if !ok {
+		c = &storeLayoutCheck{}
+		storeLayoutChecks[dir] = c
+	}
+	storeLayoutChecksMu.Unlock()
+
+	c.once.Do(func() {
+		c.err = checkStoreLayoutMarker(dir)
+	})
+	return c.err
+}
+
+func checkStoreLayoutMarker(dir string) error {
+	version, err := readStoreLayout(dir)
+	if os.IsNotExist(err) {
+		if err := writeStoreLayout(dir); err != nil {
+			// Another process may have written it first.
+			if _, rerr := readStoreLayout(dir); rerr != nil {
+				tracerx.Printf("store: unable to write layout to %s: %s", dir, err)
+			}
+		}
+		return nil
+	}
+	if err != nil {
+		logger.Log(logger.Warning, logger.Fields{"path": dir, "error": err},
+			"warning: unable to read the layout of the Git LFS object store in %s: %s", dir, err)
+		return nil
+	}
+
+	if version > storeLayoutVersion {
+		return errors.Errorf("the Git LFS object store in %s has layout version %d, but this version of Git LFS only knows layout version %d, so objects cannot be written to it; upgrade Git LFS", dir, version, storeLayoutVersion)
+	}
+	if version != storeLayoutVersion {
+		logger.Log(logger.Warning, logger.Fields{"path": dir, "version": version},
+			"warning: the Git LFS object store in %s has layout version %d, but this version of Git LFS only knows layout version %d", dir, version, storeLayoutVersion)
+	}
+	return nil
+}
+
+// readStoreLayout returns the layout version given by the marker in the
+// storage directory "dir".
+func readStoreLayout(dir string) (int, error) {
+	by, err := ioutil.ReadFile(filepath.Join(dir, storeLayoutFile))
+	if err != nil {
+		return 0, err
+	}
+
+	line := strings.TrimSpace(string(by))
+	if !strings.HasPrefix(line, storeLayoutPrefix) {
+		return 0, fmt.Errorf("invalid layout marker %q", line)
+	}
+
+	version, err := strconv.Atoi(strings.TrimPrefix(line, storeLayoutPrefix))
+	if err != nil {
+		return 0, fmt.Errorf("invalid layout marker %q", line)
+	}
+	return version, nil
+}
+
+// writeStoreLayout writes the marker for this version's layout to the storage
+// directory "dir". It is written to a temporary file and renamed into
+// place, so that other processes never see it half written.
+func writeStoreLayout(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(dir, storeLayoutFile)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := fmt.Fprintf(f, "%s%d\n", storeLayoutPrefix, storeLayoutVersion); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	tracerx.Printf("store: writing layout version %d to %s", storeLayoutVersion, dir)
+	return os.Rename(f.Name(), filepath.Join(dir, storeLayoutFile))
+}
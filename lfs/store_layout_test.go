@@ -0,0 +1,81 @@
+package lfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckStoreLayoutWritesMarker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lfs-store-layout")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	require.Nil(t, checkStoreLayout(dir))
+
+	version, err := readStoreLayout(dir)
+	require.Nil(t, err)
+	assert.Equal(t, storeLayoutVersion, version)
+
+	by, err := ioutil.ReadFile(filepath.Join(dir, storeLayoutFile))
+	require.Nil(t, err)
+	assert.Equal(t, "git-lfs-store-layout 1\n", string(by))
+
+	entries, err := ioutil.ReadDir(dir)
+	require.Nil(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestCheckStoreLayoutRefusesNewerLayouts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lfs-store-layout")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	marker := filepath.Join(dir, storeLayoutFile)
+	require.Nil(t, ioutil.WriteFile(marker, []byte("git-lfs-store-layout 2\n"), 0644))
+
+	err = checkStoreLayout(dir)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "has layout version 2")
+	}
+	// The refusal is remembered with the rest of the check.
+	assert.Equal(t, err, checkStoreLayout(dir))
+
+	version, err := readStoreLayout(dir)
+	require.Nil(t, err)
+	assert.Equal(t, 2, version)
+}
+
+func TestCheckStoreLayoutIsCachedPerDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lfs-store-layout")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	require.Nil(t, checkStoreLayout(dir))
+	require.Nil(t, os.Remove(filepath.Join(dir, storeLayoutFile)))
+
+	require.Nil(t, checkStoreLayout(dir))
+
+	_, err = readStoreLayout(dir)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestReadStoreLayoutRejectsInvalidMarkers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lfs-store-layout")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, contents := range []string{"", "layout 1\n", "git-lfs-store-layout one\n"} {
+		marker := filepath.Join(dir, storeLayoutFile)
+		require.Nil(t, ioutil.WriteFile(marker, []byte(contents), 0644))
+
+		_, err := readStoreLayout(dir)
+		if assert.NotNil(t, err, contents) {
+			assert.Contains(t, err.Error(), "invalid layout marker", contents)
+		}
+	}
+}
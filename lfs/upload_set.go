@@ -0,0 +1,116 @@
+package lfs
+
+import (
+	"fmt"
+
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/git-lfs/git-lfs/tq"
+)
+
+// uploadSetBatchSize is the number of objects checked against the remote in
+// each batch API request made by PushUploadSet.
+const uploadSetBatchSize = 100
+
+// UploadSet is the set of LFS objects that a push would upload, because the
+// remote does not have them yet.
+type UploadSet struct {
+	// Pointers holds one pointer for each object to be uploaded, in the
+	// order in which they were found.
+	Pointers []*WrappedPointer
+	// Size is the total size of the objects to be uploaded, in bytes.
+	Size int64
+}
+
+// PushUploadSet returns the LFS objects that pushing "refs" to "remote" would
+// upload. The pointers in the commits that the remote does not have are found
+// as by ScanLeftToRemote, and the server is then asked which of their objects
+// it still needs, without uploading any of them. Each object is included only
+// once, however many pointers refer to it.
+func PushUploadSet(manifest *tq.Manifest, remote string, refs []string) (*UploadSet, error) {
+	var pointers []*WrappedPointer
+	var multiErr error
+	seen := make(map[string]bool)
+
+	gitscanner := NewGitScanner(func(p *WrappedPointer, err error) {
+		if err != nil {
+			if multiErr != nil {
+				multiErr = fmt.Errorf("%v\n%v", multiErr, err)
+			} else {
+				multiErr = err
+			}
+			return
+		}
+
+		if !seen[p.Oid] {
+			seen[p.Oid] = true
+			pointers = append(pointers, p)
+		}
+	})
+	defer gitscanner.Close()
+
+	if err := gitscanner.RemoteForPush(remote); err != nil {
+		return nil, err
+	}
+
+	for _, ref := range refs {
+		if err := gitscanner.ScanLeftToRemote(ref, nil); err != nil {
+			return nil, err
+		}
+	}
+	if multiErr != nil {
+		return nil, multiErr
+	}
+
+	set := &UploadSet{}
+	for i := 0; i < len(pointers); i += uploadSetBatchSize {
+		end := i + uploadSetBatchSize
+		if end > len(pointers) {
+			end = len(pointers)
+		}
+
+		needed, err := uploadsNeeded(manifest, remote, pointers[i:end])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range pointers[i:end] {
+			if needed[p.Oid] {
+				set.Pointers = append(set.Pointers, p)
+				set.Size += p.Size
+			}
+		}
+	}
+
+	return set, nil
+}
+
+// uploadsNeeded makes a batch API request for uploading "pointers" to
+// "remote", and returns the OIDs of those objects that the server gave an
+// upload action for, since it does not have them.
+func uploadsNeeded(manifest *tq.Manifest, remote string, pointers []*WrappedPointer) (map[string]bool, error) {
+	objects := make([]*tq.Transfer, 0, len(pointers))
+	for _, p := range pointers {
+		objects = append(objects, &tq.Transfer{Oid: p.Oid, Size: p.Size})
+	}
+
+	bRes, err := tq.Batch(manifest, tq.Upload, remote, objects)
+	if err != nil {
+		return nil, err
+	}
+
+	needed := make(map[string]bool, len(bRes.Objects))
+	for _, o := range bRes.Objects {
+		if o.Error != nil {
+			return nil, errors.Errorf("[%v] %v", o.Oid, o.Error.Message)
+		}
+
+		a, err := o.Rel("upload")
+		if err != nil {
+			return nil, errors.Wrapf(err, "[%v]", o.Oid)
+		}
+		if a != nil {
+			needed[o.Oid] = true
+		}
+	}
+	return needed, nil
+}
@@ -0,0 +1,93 @@
+package lfs_test // to avoid import cycles
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	. "github.com/git-lfs/git-lfs/lfs"
+	"github.com/git-lfs/git-lfs/lfsapi"
+	"github.com/git-lfs/git-lfs/test"
+	"github.com/git-lfs/git-lfs/tq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushUploadSet(t *testing.T) {
+	repo := test.NewRepo(t)
+	repo.Pushd()
+	defer func() {
+		repo.Popd()
+		repo.Cleanup()
+	}()
+
+	outputs := repo.AddCommits([]*test.CommitInput{
+		{
+			Files: []*test.FileInput{
+				{Filename: "a.dat", Size: 20},
+				{Filename: "b.dat", Size: 30},
+			},
+		},
+	})
+	repo.AddRemote("origin")
+	test.RunGitCommand(t, true, "push", "origin", "master")
+
+	outputs = append(outputs, repo.AddCommits([]*test.CommitInput{
+		{
+			Files: []*test.FileInput{
+				{Filename: "a.dat", Size: 25},
+				{Filename: "c.dat", Size: 40},
+			},
+		},
+	})...)
+
+	newA := outputs[1].Files[0]
+	newC := outputs[1].Files[1]
+
+	var requested []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Operation string         `json:"operation"`
+			Objects   []*tq.Transfer `json:"objects"`
+		}
+		require.Nil(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "upload", req.Operation)
+
+		res := &tq.BatchResponse{}
+		for _, o := range req.Objects {
+			requested = append(requested, o.Oid)
+
+			// The server already has c.dat.
+			obj := &tq.Transfer{Oid: o.Oid, Size: o.Size}
+			if o.Oid != newC.Oid {
+				obj.Actions = tq.ActionSet{"upload": &tq.Action{Href: "http://example.com/upload"}}
+			}
+			res.Objects = append(res.Objects, obj)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		require.Nil(t, json.NewEncoder(w).Encode(res))
+	}))
+	defer srv.Close()
+
+	cli, err := lfsapi.NewClient(nil, lfsapi.UniqTestEnv(map[string]string{
+		"lfs.url": srv.URL,
+	}))
+	require.Nil(t, err)
+
+	set, err := PushUploadSet(tq.NewManifestWithClient(cli), "origin", []string{"master"})
+	require.Nil(t, err)
+
+	sort.Strings(requested)
+	expected := []string{newA.Oid, newC.Oid}
+	sort.Strings(expected)
+	assert.Equal(t, expected, requested)
+
+	if assert.Len(t, set.Pointers, 1) {
+		assert.Equal(t, newA.Oid, set.Pointers[0].Oid)
+		assert.Equal(t, "a.dat", set.Pointers[0].Name)
+	}
+	assert.EqualValues(t, 25, set.Size)
+}
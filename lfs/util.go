@@ -3,7 +3,6 @@ package lfs
 import (
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -187,7 +186,10 @@ func IsWindows() bool {
 }
 
 func CopyFileContents(src string, dst string) error {
-	tmp, err := ioutil.TempFile(TempDir(), filepath.Base(dst))
+	release := tools.AcquireFiles(2)
+	defer release()
+
+	tmp, err := TempFile(filepath.Base(dst))
 	if err != nil {
 		return err
 	}
@@ -208,7 +210,7 @@ func CopyFileContents(src string, dst string) error {
 	if err != nil {
 		return err
 	}
-	return os.Rename(tmp.Name(), dst)
+	return tools.RenameFile(tmp.Name(), dst)
 }
 
 func LinkOrCopy(src string, dst string) error {
@@ -21,6 +21,24 @@ var (
 )
 
 func (c *Client) DoWithAuth(remote string, req *http.Request) (*http.Response, error) {
+	return c.doWithAuth(remote, req, 0, nil)
+}
+
+// doWithAuth performs DoWithAuth, where "refreshes" is the number of times the
+// request has already been retried with credentials from c.Refresher, and
+// "refreshed" are the credentials it is being retried with, if any.
+func (c *Client) doWithAuth(remote string, req *http.Request, refreshes int, refreshed Creds) (*http.Response, error) {
+	if c.Offline {
+		// Don't ask for credentials that can't be used.
+		return nil, ErrOffline
+	}
+
+	if !c.AllowedHosts.Allows(req.URL.Host) {
+		// Don't ask for credentials for a host which may not be
+		// trusted with them.
+		return nil, newDisallowedHostError(req.URL.Host)
+	}
+
 	credHelper := c.Credentials
 	if credHelper == nil {
 		credHelper = defaultCredentialHelper
@@ -36,12 +54,36 @@ func (c *Client) DoWithAuth(remote string, req *http.Request) (*http.Response, e
 		ef = defaultEndpointFinder
 	}
 
+	if c.Refresher != nil && !requestHasAuth(req) {
+		e := ef.Endpoint(getReqOperation(req), remote)
+		if cached := c.refreshedCreds(e, req.URL); cached != nil {
+			tracerx.Printf("api: using refreshed credentials for %s", e.Url)
+			setRequestAuth(req, cached["username"], cached["password"])
+		}
+	}
+
 	apiEndpoint, access, creds, credsURL, err := getCreds(credHelper, netrcFinder, ef, remote, req)
 	if err != nil {
 		return nil, err
 	}
 
 	res, err := c.doWithCreds(req, credHelper, creds, credsURL, access)
+	if err != nil && c.Refresher != nil && isRefreshableResponse(res) && refreshes < maxCredentialRefreshes {
+		// Whichever credentials were rejected are not used again.
+		c.setRefreshedCreds(apiEndpoint, req.URL, nil)
+
+		next, rerr := c.Refresher.Refresh(apiEndpoint, refreshes+1)
+		if rerr != nil {
+			return res, errors.Wrap(rerr, "creds")
+		}
+
+		if next != nil {
+			tracerx.Printf("api: http response %d, retrying with refreshed credentials", res.StatusCode)
+			setRequestAuth(req, next["username"], next["password"])
+			return c.doWithAuth(remote, req, refreshes+1, next)
+		}
+	}
+
 	if err != nil {
 		if errors.IsAuthError(err) {
 			newAccess := getAuthAccess(res)
@@ -55,13 +97,16 @@ func (c *Client) DoWithAuth(remote string, req *http.Request) (*http.Response, e
 				if creds != nil {
 					credHelper.Reject(creds)
 				}
-				return c.DoWithAuth(remote, req)
+				return c.doWithAuth(remote, req, refreshes, nil)
 			}
 		}
 	}
 
 	if res != nil && res.StatusCode < 300 && res.StatusCode > 199 {
 		credHelper.Approve(creds)
+		if refreshed != nil {
+			c.setRefreshedCreds(apiEndpoint, req.URL, refreshed)
+		}
 	}
 
 	return res, err
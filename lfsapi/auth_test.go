@@ -578,3 +578,115 @@ func (f *fakeCredentialFiller) Approve(creds Creds) error {
 func (f *fakeCredentialFiller) Reject(creds Creds) error {
 	return errors.New("Not implemented")
 }
+
+type mockCredentialRefresher struct {
+	creds     Creds
+	endpoints []string
+	attempts  []int
+}
+
+func (r *mockCredentialRefresher) Refresh(e Endpoint, attempt int) (Creds, error) {
+	r.endpoints = append(r.endpoints, e.Url)
+	r.attempts = append(r.attempts, attempt)
+	return r.creds, nil
+}
+
+func TestDoWithAuthRefresh(t *testing.T) {
+	var called uint32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddUint32(&called, 1)
+
+		body := &authRequest{}
+		err := json.NewDecoder(req.Body).Decode(body)
+		assert.Nil(t, err)
+		assert.Equal(t, "Refresh", body.Test)
+
+		expected := "Basic " + strings.TrimSpace(
+			base64.StdEncoding.EncodeToString([]byte("user:fresh-token")),
+		)
+		if req.Header.Get("Authorization") != expected {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(nil, UniqTestEnv(map[string]string{
+		"lfs.url": srv.URL + "/repo/lfs",
+	}))
+	require.Nil(t, err)
+	refresher := &mockCredentialRefresher{creds: Creds{
+		"username": "user",
+		"password": "fresh-token",
+	}}
+	c.Refresher = refresher
+
+	req, err := http.NewRequest("POST", srv.URL+"/repo/lfs/foo", nil)
+	require.Nil(t, err)
+	require.Nil(t, MarshalToRequest(req, &authRequest{Test: "Refresh"}))
+
+	res, err := c.DoWithAuth("", req)
+	require.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.EqualValues(t, 2, called)
+	assert.Equal(t, []string{srv.URL + "/repo/lfs"}, refresher.endpoints)
+	assert.Equal(t, []int{1}, refresher.attempts)
+
+	// The refreshed credentials are kept for the next request.
+	req, err = http.NewRequest("POST", srv.URL+"/repo/lfs/bar", nil)
+	require.Nil(t, err)
+	require.Nil(t, MarshalToRequest(req, &authRequest{Test: "Refresh"}))
+
+	res, err = c.DoWithAuth("", req)
+	require.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.EqualValues(t, 3, called)
+	assert.Equal(t, []int{1}, refresher.attempts)
+}
+
+func TestDoWithAuthRefreshGivesUp(t *testing.T) {
+	var called uint32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddUint32(&called, 1)
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(nil, UniqTestEnv(map[string]string{
+		"lfs.url": srv.URL + "/repo/lfs",
+	}))
+	require.Nil(t, err)
+	refresher := &mockCredentialRefresher{creds: Creds{
+		"username": "user",
+		"password": "stale-token",
+	}}
+	c.Refresher = refresher
+
+	req, err := http.NewRequest("GET", srv.URL+"/repo/lfs/foo", nil)
+	require.Nil(t, err)
+
+	_, err = c.DoWithAuth("", req)
+	assert.NotNil(t, err)
+
+	assert.EqualValues(t, maxCredentialRefreshes+1, called)
+	assert.Equal(t, []int{1, 2, 3}, refresher.attempts)
+}
+
+func TestCommandCredentialRefresher(t *testing.T) {
+	r := newCredentialRefresher(UniqTestEnv(map[string]string{
+		"lfs.credentialrefresh": `sh -c "sort; echo username=user; echo password=token"`,
+	}))
+	require.NotNil(t, r)
+
+	creds, err := r.Refresh(Endpoint{Url: "https://example.com/repo/lfs"}, 2)
+	require.Nil(t, err)
+	assert.Equal(t, "user", creds["username"])
+	assert.Equal(t, "token", creds["password"])
+	assert.Equal(t, "2", creds["attempt"])
+	assert.Equal(t, "https://example.com/repo/lfs", creds["url"])
+
+	assert.Nil(t, newCredentialRefresher(UniqTestEnv(map[string]string{})))
+}
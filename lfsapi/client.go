@@ -21,7 +21,40 @@ var UserAgent = "git-lfs"
 
 const MediaType = "application/vnd.git-lfs+json; charset=utf-8"
 
+// defaultMaxRedirects is the most redirects followed for a single request when
+// lfs.maxredirects is not set.
+const defaultMaxRedirects = 2
+
+// redirectCredentialHeaders are the canonical names of the headers which are
+// dropped from a request when it is redirected to another host.
+var redirectCredentialHeaders = map[string]bool{
+	"Authorization":    true,
+	"Www-Authenticate": true,
+	"Cookie":           true,
+	"Cookie2":          true,
+}
+
 func (c *Client) NewRequest(method string, e Endpoint, suffix string, body interface{}) (*http.Request, error) {
+	if c.Offline {
+		// Resolving an SSH endpoint would connect to the remote.
+		return nil, ErrOffline
+	}
+
+	if len(e.SshUserAndHost) > 0 {
+		host := e.SshUserAndHost
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+		if len(e.SshPort) > 0 {
+			host = net.JoinHostPort(host, e.SshPort)
+		}
+
+		if !c.AllowedHosts.Allows(host) {
+			// Resolving an SSH endpoint would connect to the host.
+			return nil, newDisallowedHostError(host)
+		}
+	}
+
 	sshRes, err := c.SSH.Resolve(e, method)
 	if err != nil {
 		tracerx.Printf("ssh: %s failed, error: %s, message: %s",
@@ -69,10 +102,23 @@ func joinURL(prefix, suffix string) string {
 }
 
 func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	if c.Offline {
+		return nil, ErrOffline
+	}
+
 	req.Header = c.extraHeadersFor(req)
-	req.Header.Set("User-Agent", UserAgent)
+	if len(req.Header.Get("User-Agent")) == 0 {
+		// A User-Agent given by the server for an action is kept,
+		// since it may be covered by the action's signature.
+		req.Header.Set("User-Agent", UserAgent)
+	}
+
+	cli := c.httpClient(req.Host)
+	if t, ok := timeoutsFor(req); ok {
+		cli = c.httpClientWithTimeouts(req.Host, &t)
+	}
 
-	res, err := c.doWithRedirects(c.httpClient(req.Host), req, nil)
+	res, err := c.doWithRedirects(cli, req, nil)
 	if err != nil {
 		return res, err
 	}
@@ -117,6 +163,10 @@ func (c *Client) extraHeaders(u *url.URL) map[string][]string {
 }
 
 func (c *Client) doWithRedirects(cli *http.Client, req *http.Request, via []*http.Request) (*http.Response, error) {
+	if !c.AllowedHosts.Allows(req.URL.Host) {
+		return nil, newDisallowedHostError(req.URL.Host)
+	}
+
 	tracedReq, err := c.traceRequest(req)
 	if err != nil {
 		return nil, err
@@ -151,8 +201,8 @@ func (c *Client) doWithRedirects(cli *http.Client, req *http.Request, via []*htt
 	}
 
 	via = append(via, req)
-	if len(via) >= 3 {
-		return res, errors.New("too many redirects")
+	if len(via) > c.maxRedirects() {
+		return res, errors.Errorf("too many redirects; stopped after %d", len(via)-1)
 	}
 
 	redirectedReq, err := newRequestForRetry(req, redirectTo)
@@ -164,6 +214,13 @@ func (c *Client) doWithRedirects(cli *http.Client, req *http.Request, via []*htt
 }
 
 func (c *Client) httpClient(host string) *http.Client {
+	return c.httpClientWithTimeouts(host, nil)
+}
+
+// httpClientWithTimeouts returns the client for requests to "host", made with
+// the timeouts "t", if they are not nil, and otherwise those the client was
+// configured with. Requests with different timeouts never share connections.
+func (c *Client) httpClientWithTimeouts(host string, t *Timeouts) *http.Client {
 	c.clientMu.Lock()
 	defer c.clientMu.Unlock()
 
@@ -179,7 +236,11 @@ func (c *Client) httpClient(host string) *http.Client {
 		c.hostClients = make(map[string]*http.Client)
 	}
 
-	if client, ok := c.hostClients[host]; ok {
+	key := host
+	if t != nil {
+		key = fmt.Sprintf("%s connect=%s header=%s", host, t.Connect, t.Header)
+	}
+	if client, ok := c.hostClients[key]; ok {
 		return client
 	}
 
@@ -203,10 +264,21 @@ func (c *Client) httpClient(host string) *http.Client {
 		tlstime = 30
 	}
 
+	dialDuration := time.Duration(dialtime) * time.Second
+	tlsDuration := time.Duration(tlstime) * time.Second
+	var headerDuration time.Duration
+	if t != nil {
+		if t.Connect > 0 {
+			dialDuration, tlsDuration = t.Connect, t.Connect
+		}
+		headerDuration = t.Header
+	}
+
 	tr := &http.Transport{
-		Proxy:               proxyFromClient(c),
-		TLSHandshakeTimeout: time.Duration(tlstime) * time.Second,
-		MaxIdleConnsPerHost: concurrentTransfers,
+		Proxy:                 proxyFromClient(c),
+		TLSHandshakeTimeout:   tlsDuration,
+		ResponseHeaderTimeout: headerDuration,
+		MaxIdleConnsPerHost:   concurrentTransfers,
 	}
 
 	activityTimeout := 10
@@ -219,7 +291,7 @@ func (c *Client) httpClient(host string) *http.Client {
 	}
 
 	dialer := &net.Dialer{
-		Timeout:   time.Duration(dialtime) * time.Second,
+		Timeout:   dialDuration,
 		KeepAlive: time.Duration(keepalivetime) * time.Second,
 		DualStack: true,
 	}
@@ -262,7 +334,7 @@ func (c *Client) httpClient(host string) *http.Client {
 		},
 	}
 
-	c.hostClients[host] = httpClient
+	c.hostClients[key] = httpClient
 	if c.VerboseOut == nil {
 		c.VerboseOut = os.Stderr
 	}
@@ -276,24 +348,37 @@ func (c *Client) CurrentUser() (string, string) {
 	return userName, userEmail
 }
 
+// maxRedirects returns the most redirects followed for a single request.
+func (c *Client) maxRedirects() int {
+	if c.MaxRedirects > 0 {
+		return c.MaxRedirects
+	}
+	return defaultMaxRedirects
+}
+
+// newRequestForRetry returns a copy of "req" for "location", to which it was
+// redirected. Headers which carry credentials are only kept if "location" is
+// on the same host, as browsers and Go's own client do, so that they are never
+// sent to a host which they were not given for, such as a CDN.
 func newRequestForRetry(req *http.Request, location string) (*http.Request, error) {
 	newReq, err := http.NewRequest(req.Method, location, nil)
 	if err != nil {
 		return nil, err
 	}
+	// Keep any deadline, and the timeouts given by WithTimeouts.
+	newReq = newReq.WithContext(req.Context())
 
 	if req.URL.Scheme == "https" && newReq.URL.Scheme == "http" {
 		return nil, errors.New("lfsapi/client: refusing insecure redirect, https->http")
 	}
 
-	sameHost := req.URL.Host == newReq.URL.Host
-	for key := range req.Header {
-		if key == "Authorization" {
-			if !sameHost {
-				continue
-			}
+	sameHost := strings.EqualFold(req.URL.Host, newReq.URL.Host)
+	for key, values := range req.Header {
+		if !sameHost && redirectCredentialHeaders[http.CanonicalHeaderKey(key)] {
+			tracerx.Printf("api: not sending %s to %s", key, newReq.URL.Host)
+			continue
 		}
-		newReq.Header.Set(key, req.Header.Get(key))
+		newReq.Header[key] = values
 	}
 
 	oldestURL := strings.SplitN(req.URL.String(), "?", 2)[0]
@@ -293,3 +293,173 @@ func TestMarshalToRequest(t *testing.T) {
 	assert.Equal(t, "15", req.Header.Get("Content-Length"))
 	assert.EqualValues(t, 15, req.ContentLength)
 }
+
+func TestClientOffline(t *testing.T) {
+	var called uint32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint32(&called, 1)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(nil, UniqTestEnv(map[string]string{
+		"lfs.offline": "true",
+	}))
+	require.Nil(t, err)
+	assert.True(t, c.Offline)
+
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	require.Nil(t, err)
+
+	_, err = c.Do(req)
+	assert.Equal(t, ErrOffline, err)
+
+	_, err = c.DoWithAuth("origin", req)
+	assert.Equal(t, ErrOffline, err)
+
+	_, err = c.NewRequest("GET", Endpoint{Url: srv.URL}, "test", nil)
+	assert.Equal(t, ErrOffline, err)
+
+	assert.EqualValues(t, 0, called)
+}
+
+func TestClientOfflineFromOSEnv(t *testing.T) {
+	c, err := NewClient(UniqTestEnv(map[string]string{
+		"GIT_LFS_OFFLINE": "1",
+	}), nil)
+	require.Nil(t, err)
+	assert.True(t, c.Offline)
+}
+
+func TestClientAllowedHosts(t *testing.T) {
+	var called uint32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint32(&called, 1)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(nil, UniqTestEnv(map[string]string{
+		"lfs.allowedhosts": "git-server.com, *.git-server.com",
+	}))
+	require.Nil(t, err)
+
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	require.Nil(t, err)
+	host := req.URL.Host
+
+	_, err = c.Do(req)
+	require.NotNil(t, err)
+	assert.Equal(t, fmt.Sprintf("refusing to contact %s, which is not in lfs.allowedhosts", host), err.Error())
+
+	_, err = c.DoWithAuth("origin", req)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "not in lfs.allowedhosts")
+
+	_, err = c.NewRequest("GET", Endpoint{Url: "ssh://attacker.com/repo", SshUserAndHost: "git@attacker.com"}, "test", nil)
+	require.NotNil(t, err)
+	assert.Equal(t, "refusing to contact attacker.com, which is not in lfs.allowedhosts", err.Error())
+
+	assert.EqualValues(t, 0, called)
+
+	c, err = NewClient(nil, UniqTestEnv(map[string]string{
+		"lfs.allowedhosts": host,
+	}))
+	require.Nil(t, err)
+
+	res, err := c.Do(req)
+	require.Nil(t, err)
+	assert.Equal(t, 200, res.StatusCode)
+	assert.EqualValues(t, 1, called)
+}
+
+func TestClientAllowedHostsRejectsRedirects(t *testing.T) {
+	var called uint32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint32(&called, 1)
+		w.Header().Set("Location", "https://attacker.com/objects")
+		w.WriteHeader(307)
+	}))
+	defer srv.Close()
+
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	require.Nil(t, err)
+
+	c, err := NewClient(nil, UniqTestEnv(map[string]string{
+		"lfs.allowedhosts": req.URL.Host,
+	}))
+	require.Nil(t, err)
+
+	_, err = c.Do(req)
+	require.NotNil(t, err)
+	assert.Equal(t, "refusing to contact attacker.com, which is not in lfs.allowedhosts", err.Error())
+	assert.EqualValues(t, 1, called)
+}
+
+func TestClientMaxRedirects(t *testing.T) {
+	var called uint32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddUint32(&called, 1)
+		if n > 4 {
+			w.WriteHeader(200)
+			return
+		}
+		w.Header().Set("Location", fmt.Sprintf("/%d", n))
+		w.WriteHeader(307)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(nil, UniqTestEnv(map[string]string{}))
+	require.Nil(t, err)
+
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	require.Nil(t, err)
+
+	_, err = c.Do(req)
+	require.NotNil(t, err)
+	assert.Equal(t, "too many redirects; stopped after 2", err.Error())
+	assert.EqualValues(t, 3, called)
+
+	atomic.StoreUint32(&called, 0)
+	c, err = NewClient(nil, UniqTestEnv(map[string]string{
+		"lfs.maxredirects": "4",
+	}))
+	require.Nil(t, err)
+	assert.Equal(t, 4, c.MaxRedirects)
+
+	req, err = http.NewRequest("GET", srv.URL, nil)
+	require.Nil(t, err)
+
+	res, err := c.Do(req)
+	require.Nil(t, err)
+	assert.Equal(t, 200, res.StatusCode)
+	assert.EqualValues(t, 5, called)
+}
+
+func TestClientRedirectDropsCredentialsAcrossHosts(t *testing.T) {
+	srv2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "", r.Header.Get("Authorization"))
+		assert.Equal(t, "", r.Header.Get("Cookie"))
+		assert.Equal(t, "1", r.Header.Get("A"))
+	}))
+	defer srv2.Close()
+
+	srv1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "auth", r.Header.Get("Authorization"))
+		assert.Equal(t, "session=1", r.Header.Get("Cookie"))
+		w.Header().Set("Location", srv2.URL+"/ok")
+		w.WriteHeader(307)
+	}))
+	defer srv1.Close()
+
+	c, err := NewClient(nil, UniqTestEnv(map[string]string{}))
+	require.Nil(t, err)
+
+	req, err := http.NewRequest("GET", srv1.URL, nil)
+	require.Nil(t, err)
+	req.Header.Set("Authorization", "auth")
+	req.Header.Set("Cookie", "session=1")
+	req.Header.Set("A", "1")
+
+	res, err := c.Do(req)
+	require.Nil(t, err)
+	assert.Equal(t, 200, res.StatusCode)
+}
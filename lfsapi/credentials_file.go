@@ -0,0 +1,78 @@
+package lfsapi
+
+import (
+	"encoding/json"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/bgentry/go-netrc/netrc"
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/rubyist/tracerx"
+)
+
+// credentialsFile is a NetrcFinder for the credentials in the JSON file given
+// by "lfs.credentialsfile", which are kept in memory once it has been read. The
+// file is an object keyed by host name, without any port, such as:
+//
+//	{
+//	  "git-server.com": { "username": "ci", "password": "token" }
+//	}
+type credentialsFile struct {
+	machines map[string]*netrc.Machine
+}
+
+type credentialsFileEntry struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// parseCredentialsFile reads the credentials file at "name", warning if anyone
+// else can read it. Its contents are never included in any error.
+func parseCredentialsFile(name string) (*credentialsFile, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "lfs.credentialsfile")
+	}
+	defer f.Close()
+
+	if stat, err := f.Stat(); err == nil && runtime.GOOS != "windows" && stat.Mode().Perm()&0044 != 0 {
+		tracerx.Printf("api: %s (lfs.credentialsfile) can be read by other users", name)
+	}
+
+	var entries map[string]*credentialsFileEntry
+	if err := json.NewDecoder(f).Decode(&entries); err != nil {
+		return nil, errors.Errorf("lfs.credentialsfile: %s is not a JSON object of credentials keyed by host", name)
+	}
+
+	machines := make(map[string]*netrc.Machine, len(entries))
+	for host, e := range entries {
+		if e == nil {
+			continue
+		}
+		host = strings.ToLower(host)
+		machines[host] = &netrc.Machine{
+			Name:     host,
+			Login:    e.Username,
+			Password: e.Password,
+		}
+	}
+	return &credentialsFile{machines: machines}, nil
+}
+
+func (f *credentialsFile) FindMachine(host string) *netrc.Machine {
+	return f.machines[strings.ToLower(host)]
+}
+
+// netrcFinders is a NetrcFinder which looks for credentials in each of its
+// finders in turn.
+type netrcFinders []NetrcFinder
+
+func (f netrcFinders) FindMachine(host string) *netrc.Machine {
+	for _, finder := range f {
+		if m := finder.FindMachine(host); m != nil {
+			return m
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package lfsapi
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClientWithCredentialsFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lfsapi-credentials-file")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "credentials.json")
+	require.Nil(t, ioutil.WriteFile(name, []byte(`{
+		"Git-Server.com": { "username": "ci", "password": "token" }
+	}`), 0600))
+
+	c, err := NewClient(UniqTestEnv(map[string]string{}), UniqTestEnv(map[string]string{
+		"lfs.credentialsfile": name,
+	}))
+	require.Nil(t, err)
+
+	m := c.Netrc.FindMachine("git-server.com")
+	require.NotNil(t, m)
+	assert.Equal(t, "ci", m.Login)
+	assert.Equal(t, "token", m.Password)
+	assert.Nil(t, c.Netrc.FindMachine("other-server.com"))
+
+	u, err := url.Parse("https://git-server.com:8443/repo.git/info/lfs/objects/batch")
+	require.Nil(t, err)
+	req := &http.Request{URL: u, Header: http.Header{}}
+	require.True(t, setAuthFromNetrc(c.Netrc, req))
+	assert.Equal(t, "Basic Y2k6dG9rZW4=", req.Header.Get("Authorization"))
+}
+
+func TestNewClientWithBadCredentialsFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lfsapi-credentials-file")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "credentials.json")
+	require.Nil(t, ioutil.WriteFile(name, []byte(`["secret"]`), 0600))
+
+	_, err = NewClient(nil, UniqTestEnv(map[string]string{
+		"lfs.credentialsfile": name,
+	}))
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "is not a JSON object of credentials keyed by host")
+	assert.NotContains(t, err.Error(), "secret")
+
+	_, err = NewClient(nil, UniqTestEnv(map[string]string{
+		"lfs.credentialsfile": filepath.Join(dir, "missing.json"),
+	}))
+	assert.NotNil(t, err)
+}
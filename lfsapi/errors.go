@@ -20,18 +20,45 @@ func IsHTTP(err error) (*http.Response, bool) {
 	return nil, false
 }
 
+// ErrOffline is returned instead of making a network request when the client
+// is in offline mode.
+var ErrOffline = errors.New("network access is disabled in offline mode (GIT_LFS_OFFLINE or lfs.offline)")
+
+// newDisallowedHostError returns the error given instead of making a request to
+// "host", which is not in lfs.allowedhosts.
+func newDisallowedHostError(host string) error {
+	return errors.Errorf("refusing to contact %s, which is not in lfs.allowedhosts", host)
+}
+
+// ClientError is an error response from the server, in the JSON format given
+// by the LFS API, or, if the server gave no message, a generic one for its
+// HTTP status.
 type ClientError struct {
 	Message          string `json:"message"`
 	DocumentationUrl string `json:"documentation_url,omitempty"`
 	RequestId        string `json:"request_id,omitempty"`
 	response         *http.Response
+	// generic is true if Message was not given by the server.
+	generic bool
 }
 
 func (e *ClientError) HTTPResponse() *http.Response {
 	return e.response
 }
 
+// ServerMessage returns the message the server gave, if any, and the ID it
+// gave the request, for its operators to look it up by.
+func (e *ClientError) ServerMessage() (string, string) {
+	if e.generic {
+		return "", e.RequestId
+	}
+	return e.Message, e.RequestId
+}
+
 func (e *ClientError) Error() string {
+	if len(e.RequestId) > 0 {
+		return fmt.Sprintf("%s (request ID: %s)", e.Message, e.RequestId)
+	}
 	return e.Message
 }
 
@@ -48,7 +75,9 @@ func (c *Client) handleResponse(res *http.Response) error {
 
 	if err == nil {
 		if len(cliErr.Message) == 0 {
-			err = defaultError(res)
+			defErr := defaultError(res)
+			defErr.RequestId = cliErr.RequestId
+			err = defErr
 		} else {
 			err = cliErr
 		}
@@ -100,7 +129,7 @@ var (
 	}
 )
 
-func defaultError(res *http.Response) error {
+func defaultError(res *http.Response) *ClientError {
 	var msgFmt string
 
 	if f, ok := defaultErrors[res.StatusCode]; ok {
@@ -111,5 +140,11 @@ func defaultError(res *http.Response) error {
 		msgFmt = defaultErrors[500] + fmt.Sprintf(" from HTTP %d", res.StatusCode)
 	}
 
-	return errors.Errorf(msgFmt, res.Request.URL)
+	// The response is kept, so that errors.CategoryOf can tell why the
+	// request failed.
+	return &ClientError{
+		Message:  fmt.Sprintf(msgFmt, res.Request.URL),
+		response: res,
+		generic:  true,
+	}
 }
@@ -25,6 +25,9 @@ type Client struct {
 	Credentials CredentialHelper
 	SSH         SSHResolver
 	Netrc       NetrcFinder
+	// Refresher, if non-nil, obtains new credentials for requests which
+	// fail authentication, before they are retried.
+	Refresher CredentialRefresher
 
 	DialTimeout         int
 	KeepaliveTimeout    int
@@ -35,6 +38,19 @@ type Client struct {
 	NoProxy             string
 	SkipSSLVerify       bool
 
+	// Offline is whether or not the client refuses to make any network
+	// requests, as configured by GIT_LFS_OFFLINE or lfs.offline.
+	Offline bool
+
+	// AllowedHosts are the only hosts which the client sends requests to,
+	// when it is non-nil, as configured by lfs.allowedhosts.
+	AllowedHosts *config.AllowedHosts
+
+	// MaxRedirects is the most redirects followed for a single request,
+	// as configured by lfs.maxredirects, or defaultMaxRedirects if it is
+	// not positive.
+	MaxRedirects int
+
 	Verbose          bool
 	DebuggingVerbose bool
 	VerboseOut       io.Writer
@@ -45,6 +61,11 @@ type Client struct {
 	ntlmSessions map[string]ntlm.ClientSession
 	ntlmMu       sync.Mutex
 
+	// refreshed holds the credentials which Refresher last gave for each
+	// endpoint and host, once a request has succeeded with them.
+	refreshed   map[string]Creds
+	refreshedMu sync.Mutex
+
 	httpLogger *syncLogger
 
 	LoggingStats bool // DEPRECATED
@@ -69,6 +90,16 @@ func NewClient(osEnv Env, gitEnv Env) (*Client, error) {
 		return nil, err
 	}
 
+	if name, _ := gitEnv.Get("lfs.credentialsfile"); len(name) > 0 {
+		credsFile, err := parseCredentialsFile(name)
+		if err != nil {
+			return nil, err
+		}
+		// Credentials given for Git LFS specifically take precedence
+		// over those for any other use.
+		netrc = netrcFinders{credsFile, netrc}
+	}
+
 	httpsProxy, httpProxy, noProxy := getProxyServers(osEnv, gitEnv)
 
 	var creds CredentialHelper = &commandCredentialHelper{
@@ -86,6 +117,7 @@ func NewClient(osEnv Env, gitEnv Env) (*Client, error) {
 		Credentials:         creds,
 		SSH:                 sshResolver,
 		Netrc:               netrc,
+		Refresher:           newCredentialRefresher(gitEnv),
 		DialTimeout:         gitEnv.Int("lfs.dialtimeout", 0),
 		KeepaliveTimeout:    gitEnv.Int("lfs.keepalive", 0),
 		TLSTimeout:          gitEnv.Int("lfs.tlstimeout", 0),
@@ -96,6 +128,9 @@ func NewClient(osEnv Env, gitEnv Env) (*Client, error) {
 		HTTPSProxy:          httpsProxy,
 		HTTPProxy:           httpProxy,
 		NoProxy:             noProxy,
+		Offline:             osEnv.Bool("GIT_LFS_OFFLINE", false) || gitEnv.Bool("lfs.offline", false),
+		AllowedHosts:        newAllowedHosts(gitEnv),
+		MaxRedirects:        gitEnv.Int("lfs.maxredirects", 0),
 		gitEnv:              gitEnv,
 		osEnv:               osEnv,
 		uc:                  config.NewURLConfig(gitEnv),
@@ -104,6 +139,11 @@ func NewClient(osEnv Env, gitEnv Env) (*Client, error) {
 	return c, nil
 }
 
+func newAllowedHosts(gitEnv Env) *config.AllowedHosts {
+	hosts, _ := gitEnv.Get("lfs.allowedhosts")
+	return config.NewAllowedHosts(hosts)
+}
+
 func (c *Client) GitEnv() Env {
 	return c.gitEnv
 }
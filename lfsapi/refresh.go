@@ -0,0 +1,125 @@
+package lfsapi
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+
+	"github.com/git-lfs/git-lfs/tools"
+	"github.com/rubyist/tracerx"
+)
+
+// maxCredentialRefreshes is the number of times a single request is retried
+// with credentials from a CredentialRefresher before its failure is returned.
+const maxCredentialRefreshes = 3
+
+// CredentialRefresher is consulted when a request made with DoWithAuth is
+// rejected with a 401 or 403 response, before anything else is done about it.
+// Unlike a CredentialHelper, which is asked for credentials that are already
+// known, it is meant to actively obtain new ones, such as by refreshing a
+// short-lived token.
+type CredentialRefresher interface {
+	// Refresh returns credentials to retry the request to the endpoint "e"
+	// with, after "attempt" failed attempts. If it returns no credentials,
+	// the request's failure is handled as it would be otherwise.
+	Refresh(e Endpoint, attempt int) (Creds, error)
+}
+
+// newCredentialRefresher returns the CredentialRefresher configured by
+// "lfs.credentialrefresh", or nil if there is none.
+func newCredentialRefresher(gitEnv Env) CredentialRefresher {
+	cmd, ok := gitEnv.Get("lfs.credentialrefresh")
+	if !ok || len(strings.TrimSpace(cmd)) == 0 {
+		return nil
+	}
+	return &commandCredentialRefresher{Command: cmd}
+}
+
+// commandCredentialRefresher is a CredentialRefresher which runs a command.
+// The command is given the URL of the endpoint and the number of failed
+// attempts on its standard input, as "url=<url>" and "attempt=<n>" lines, in
+// the same format as git-credential(1), and prints the new "username" and
+// "password" to its standard output in the same way. If it prints no password,
+// no credentials are returned.
+type commandCredentialRefresher struct {
+	Command string
+}
+
+func (r *commandCredentialRefresher) Refresh(e Endpoint, attempt int) (Creds, error) {
+	args := tools.QuotedFields(r.Command)
+	if len(args) == 0 {
+		return nil, nil
+	}
+
+	tracerx.Printf("creds: refresh %s (attempt %d): %s", e.Url, attempt, r.Command)
+
+	output := new(bytes.Buffer)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = bufferCreds(Creds{
+		"url":     e.Url,
+		"attempt": fmt.Sprintf("%d", attempt),
+	})
+	cmd.Stdout = output
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("credential refresh %q error: %s", r.Command, err)
+	}
+
+	creds := make(Creds)
+	for _, line := range strings.Split(output.String(), "\n") {
+		pieces := strings.SplitN(strings.TrimRight(line, "\r"), "=", 2)
+		if len(pieces) < 2 || len(pieces[1]) < 1 {
+			continue
+		}
+		creds[pieces[0]] = pieces[1]
+	}
+
+	if len(creds["password"]) == 0 {
+		return nil, nil
+	}
+	return creds, nil
+}
+
+// refreshedCreds returns the credentials which a request to "u" for the endpoint
+// "e" last succeeded with after they were refreshed, or nil, if there are none.
+func (c *Client) refreshedCreds(e Endpoint, u *url.URL) Creds {
+	c.refreshedMu.Lock()
+	defer c.refreshedMu.Unlock()
+
+	return c.refreshed[refreshedCredsKey(e, u)]
+}
+
+// setRefreshedCreds records "creds" as the refreshed credentials for requests to
+// "u" for the endpoint "e", or forgets them, if "creds" is nil. They are only
+// kept in memory, for the rest of the command.
+func (c *Client) setRefreshedCreds(e Endpoint, u *url.URL, creds Creds) {
+	c.refreshedMu.Lock()
+	defer c.refreshedMu.Unlock()
+
+	if c.refreshed == nil {
+		c.refreshed = make(map[string]Creds)
+	}
+
+	key := refreshedCredsKey(e, u)
+	if creds == nil {
+		delete(c.refreshed, key)
+	} else {
+		c.refreshed[key] = creds
+	}
+}
+
+// refreshedCredsKey returns the key of the refreshed credentials for requests to
+// "u" for the endpoint "e". The host is part of it, so that credentials which
+// were refreshed for one host are never sent to another.
+func refreshedCredsKey(e Endpoint, u *url.URL) string {
+	return e.Url + " " + u.Scheme + "://" + u.Host
+}
+
+// isRefreshableResponse returns whether "res" rejected the credentials of its
+// request, such that a CredentialRefresher should be consulted.
+func isRefreshableResponse(res *http.Response) bool {
+	return res != nil && (res.StatusCode == 401 || res.StatusCode == 403)
+}
@@ -182,3 +182,46 @@ func TestWithNonFatal500WithoutBody(t *testing.T) {
 
 	assert.EqualValues(t, 3, called)
 }
+
+func TestErrWithRequestID(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/vnd.git-lfs+json")
+		w.WriteHeader(507)
+		w.Write([]byte(`{"message":"quota exceeded","request_id":"req-1234"}`))
+	}))
+	defer srv.Close()
+
+	req, err := http.NewRequest("GET", srv.URL+"/test", nil)
+	assert.Nil(t, err)
+
+	c := &Client{}
+	_, err = c.Do(req)
+	assert.NotNil(t, err)
+	assert.Equal(t, "quota exceeded (request ID: req-1234)", err.Error())
+
+	msg, id := errors.ServerMessageOf(err)
+	assert.Equal(t, "quota exceeded", msg)
+	assert.Equal(t, "req-1234", id)
+}
+
+func TestErrWithRequestIDWithoutMessage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/vnd.git-lfs+json")
+		w.WriteHeader(404)
+		w.Write([]byte(`{"request_id":"req-1234"}`))
+	}))
+	defer srv.Close()
+
+	req, err := http.NewRequest("GET", srv.URL+"/test", nil)
+	assert.Nil(t, err)
+
+	c := &Client{}
+	_, err = c.Do(req)
+	assert.NotNil(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "Repository or object not found:"), err.Error())
+	assert.True(t, strings.HasSuffix(err.Error(), "(request ID: req-1234)"), err.Error())
+
+	msg, id := errors.ServerMessageOf(err)
+	assert.Empty(t, msg)
+	assert.Equal(t, "req-1234", id)
+}
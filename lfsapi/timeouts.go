@@ -0,0 +1,40 @@
+package lfsapi
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Timeouts are how long making a request may take, overriding those of the
+// client, for a kind of request which should fail quickly, rather than wait as
+// long as a transfer may, such as a batch API request. A zero timeout leaves
+// that of the client in place.
+type Timeouts struct {
+	// Connect is how long connecting to the server may take, and then,
+	// separately, the TLS handshake.
+	Connect time.Duration
+	// Header is how long the headers of the response may take to arrive,
+	// once the request has been sent.
+	Header time.Duration
+}
+
+type timeoutsContextKey string
+
+const timeoutsKey = timeoutsContextKey("timeouts")
+
+// WithTimeouts returns a copy of "req" which is made with the timeouts "t". A
+// timeout for the whole request, including reading the body of its response,
+// is given as a deadline of its context instead, as for any other request.
+func WithTimeouts(req *http.Request, t Timeouts) *http.Request {
+	if t.Connect <= 0 && t.Header <= 0 {
+		return req
+	}
+	return req.WithContext(context.WithValue(req.Context(), timeoutsKey, t))
+}
+
+// timeoutsFor returns the timeouts given to WithTimeouts for "req", if any.
+func timeoutsFor(req *http.Request) (Timeouts, bool) {
+	t, ok := req.Context().Value(timeoutsKey).(Timeouts)
+	return t, ok
+}
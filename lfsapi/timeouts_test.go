@@ -0,0 +1,65 @@
+package lfsapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTimeoutsHeader(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(200)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(nil, nil)
+	require.Nil(t, err)
+
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	require.Nil(t, err)
+
+	_, err = c.Do(WithTimeouts(req, Timeouts{Header: 50 * time.Millisecond}))
+	assert.NotNil(t, err)
+
+	req, err = http.NewRequest("GET", srv.URL, nil)
+	require.Nil(t, err)
+
+	res, err := c.Do(req)
+	require.Nil(t, err)
+	assert.Equal(t, 200, res.StatusCode)
+}
+
+func TestHTTPClientWithTimeouts(t *testing.T) {
+	c, err := NewClient(nil, nil)
+	require.Nil(t, err)
+
+	timeouts := &Timeouts{Connect: 2 * time.Second, Header: 3 * time.Second}
+	cli := c.httpClientWithTimeouts("example.com", timeouts)
+	tr, ok := cli.Transport.(*http.Transport)
+	require.True(t, ok)
+	assert.Equal(t, 2*time.Second, tr.TLSHandshakeTimeout)
+	assert.Equal(t, 3*time.Second, tr.ResponseHeaderTimeout)
+
+	assert.True(t, cli == c.httpClientWithTimeouts("example.com", timeouts))
+	assert.False(t, cli == c.httpClient("example.com"))
+
+	tr, ok = c.httpClient("example.com").Transport.(*http.Transport)
+	require.True(t, ok)
+	assert.Equal(t, 30*time.Second, tr.TLSHandshakeTimeout)
+	assert.Equal(t, time.Duration(0), tr.ResponseHeaderTimeout)
+}
+
+func TestWithTimeoutsZero(t *testing.T) {
+	req, err := http.NewRequest("GET", "https://example.com", nil)
+	require.Nil(t, err)
+
+	assert.True(t, req == WithTimeouts(req, Timeouts{}))
+
+	_, ok := timeoutsFor(req)
+	assert.False(t, ok)
+}
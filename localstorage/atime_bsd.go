@@ -0,0 +1,18 @@
+// +build darwin freebsd netbsd
+
+package localstorage
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// accessTime returns when the file described by "fi" was last accessed, or its
+// modification time if that is not known.
+func accessTime(fi os.FileInfo) time.Time {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(int64(st.Atimespec.Sec), int64(st.Atimespec.Nsec))
+	}
+	return fi.ModTime()
+}
@@ -0,0 +1,14 @@
+// +build !linux,!darwin,!freebsd,!netbsd,!windows
+
+package localstorage
+
+import (
+	"os"
+	"time"
+)
+
+// accessTime returns the modification time of the file described by "fi", since
+// when it was last accessed is not known on this platform.
+func accessTime(fi os.FileInfo) time.Time {
+	return fi.ModTime()
+}
@@ -0,0 +1,18 @@
+// +build windows
+
+package localstorage
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// accessTime returns when the file described by "fi" was last accessed, or its
+// modification time if that is not known.
+func accessTime(fi os.FileInfo) time.Time {
+	if d, ok := fi.Sys().(*syscall.Win32FileAttributeData); ok {
+		return time.Unix(0, d.LastAccessTime.Nanoseconds())
+	}
+	return fi.ModTime()
+}
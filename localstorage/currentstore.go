@@ -1,13 +1,14 @@
 package localstorage
 
 import (
-	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 
 	"github.com/git-lfs/git-lfs/config"
 	"github.com/git-lfs/git-lfs/errors"
+	"github.com/git-lfs/git-lfs/logger"
 )
 
 const (
@@ -35,31 +36,96 @@ func InitStorage() error {
 	cfg := config.Config.StorageConfig()
 
 	TempDir = filepath.Join(cfg.LfsStorageDir, "tmp") // temp files per worktree
-	objs, err := NewStorage(
-		filepath.Join(cfg.LfsStorageDir, "objects"),
-		filepath.Join(TempDir, "objects"),
-	)
-
+	if len(cfg.TempDir) > 0 {
+		TempDir = cfg.TempDir
+	}
+	rootDir := filepath.Join(cfg.LfsStorageDir, "objects")
+	objs, err := NewStorage(rootDir, filepath.Join(TempDir, "objects"))
+	readOnly := false
 	if err != nil {
-		return errors.Wrap(err, "init LocalStorage")
+		// A store which exists, but cannot be written to, can still
+		// be read from, such as to smudge files which are present in
+		// it. Commands which write to it are told why they cannot by
+		// CheckAccess.
+		if stat, serr := os.Stat(rootDir); serr != nil || !stat.IsDir() {
+			return errors.Wrap(err, "init LocalStorage")
+		}
+		objs = &LocalStorage{RootDir: rootDir, TempDir: filepath.Join(TempDir, "objects")}
+		readOnly = true
 	}
 
 	objects = objs
 	config.LocalLogDir = filepath.Join(objs.RootDir, "logs")
-	if err := os.MkdirAll(config.LocalLogDir, localLogDirPerms); err != nil {
+	if err := os.MkdirAll(config.LocalLogDir, localLogDirPerms); err != nil && !readOnly {
 		return errors.Wrap(err, "create log dir")
 	}
 
 	return nil
 }
 
+// CheckAccess returns an error saying why the local object store cannot be
+// used, if it cannot be read, or, when "write" is true, written to. A store
+// which does not exist yet is readable, since it has nothing to read.
+func CheckAccess(write bool) error {
+	if objects == nil {
+		return nil
+	}
+	dir := objects.RootDir
+
+	if err := checkReadable(dir); err != nil {
+		return errors.Errorf("the Git LFS object store in %s is not readable (%s), so objects cannot be read from it; check its permissions, or set lfs.storage to another directory", dir, err)
+	}
+
+	if !write {
+		return nil
+	}
+
+	for _, d := range []string{dir, objects.TempDir} {
+		if err := checkWritable(d); err != nil {
+			return errors.Errorf("the Git LFS object store in %s is not writable (%s), so objects cannot be added to it; check its permissions, or set lfs.storage to another directory", dir, err)
+		}
+	}
+	return nil
+}
+
+// checkReadable returns an error if "dir" exists, but cannot be listed.
+func checkReadable(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Readdirnames(1); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// checkWritable returns an error if a file cannot be created in "dir".
+func checkWritable(dir string) error {
+	if err := os.MkdirAll(dir, dirPerms); err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(dir, ".git-lfs-access")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
 func InitStorageOrFail() {
 	if err := InitStorage(); err != nil {
 		if err == notInRepoErr {
 			return
 		}
 
-		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+		logger.Errorf("ERROR: %s", err)
 		os.Exit(1)
 	}
 }
@@ -77,7 +143,7 @@ func TempFile(prefix string) (*os.File, error) {
 		checkedTempDir = TempDir
 	}
 
-	return ioutil.TempFile(TempDir, prefix)
+	return ioutil.TempFile(TempDir, tempFileNamePrefix(prefix))
 }
 
 func ResetTempDir() error {
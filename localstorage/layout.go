@@ -0,0 +1,108 @@
+package localstorage
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// layoutOidRE matches the names of object files exactly, unlike oidRE, so that
+// nothing but a real object is ever moved by RepairLayout.
+var layoutOidRE = regexp.MustCompile(`\A[0-9a-f]{64}\z`)
+
+// MisplacedObject is an object file found outside the shard directory given by
+// its OID.
+type MisplacedObject struct {
+	Oid string
+	// Path is where the object was found.
+	Path string
+	// Want is where the object belongs.
+	Want string
+}
+
+// LayoutReport is what RepairLayout found in a store.
+type LayoutReport struct {
+	// Checked is the number of object files found.
+	Checked int
+	// Moved are the objects which were outside their shard directory, and
+	// were moved into it, or would have been, in a dry run.
+	Moved []*MisplacedObject
+	// Conflicts are the objects which were outside their shard directory,
+	// but were left where they are, since there is already a file where
+	// they belong.
+	Conflicts []*MisplacedObject
+	// Unknown are the paths of files which are not named like objects, so
+	// have no place in the store.
+	Unknown []string
+}
+
+// RepairLayout walks every file in the store, and moves each whose name is an
+// OID, but which is not in the shard directory "<oid[0:2]>/<oid[2:4]>" under
+// RootDir, into it, as happens when a store is copied without its directories.
+// Files which are not named like objects are reported, but left alone. If
+// "dryRun" is true, nothing is moved.
+func (s *LocalStorage) RepairLayout(dryRun bool) (*LayoutReport, error) {
+	report := &LayoutReport{}
+	var misplaced []*MisplacedObject
+
+	err := filepath.Walk(s.RootDir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			if path != s.RootDir && path == s.TempDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		name := fi.Name()
+		if !fi.Mode().IsRegular() || !layoutOidRE.MatchString(name) {
+			report.Unknown = append(report.Unknown, path)
+			return nil
+		}
+
+		report.Checked++
+		if want := s.ObjectPath(name); path != want {
+			misplaced = append(misplaced, &MisplacedObject{Oid: name, Path: path, Want: want})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Objects are moved once the walk is over, so that none of them is
+	// found twice.
+	for _, o := range misplaced {
+		if _, err := os.Lstat(o.Want); err == nil {
+			report.Conflicts = append(report.Conflicts, o)
+			continue
+		}
+
+		if !dryRun {
+			if err := os.MkdirAll(filepath.Dir(o.Want), dirPerms); err != nil {
+				return nil, err
+			}
+			if err := os.Rename(o.Path, o.Want); err != nil {
+				return nil, err
+			}
+			s.removeEmptyDirs(filepath.Dir(o.Path))
+		}
+		report.Moved = append(report.Moved, o)
+	}
+
+	return report, nil
+}
+
+// removeEmptyDirs removes "dir", and then each of its parents, for as long as
+// they are empty, stopping at RootDir, which is always kept.
+func (s *LocalStorage) removeEmptyDirs(dir string) {
+	root := filepath.Clean(s.RootDir)
+	for dir = filepath.Clean(dir); dir != root && strings.HasPrefix(dir, root+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"time"
 )
 
 const (
@@ -28,6 +29,10 @@ type LocalStorage struct {
 type Object struct {
 	Oid  string
 	Size int64
+	// Accessed is when the object was last read, as far as the
+	// filesystem knows. Where that is not recorded, it is when the object
+	// was last modified.
+	Accessed time.Time
 }
 
 func NewStorage(storageDir, tempDir string) (*LocalStorage, error) {
@@ -0,0 +1,12 @@
+// +build !windows
+
+package localstorage
+
+import "syscall"
+
+// processExists returns whether a process with the given PID is running. A
+// process owned by another user, which cannot be signalled, still exists.
+func processExists(pid int) bool {
+	err := syscall.Kill(pid, syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
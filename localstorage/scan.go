@@ -3,6 +3,7 @@ package localstorage
 import (
 	"os"
 	"path/filepath"
+	"sync"
 
 	"github.com/rubyist/tracerx"
 )
@@ -23,26 +24,60 @@ func (s *LocalStorage) AllObjects() []Object {
 // just stored. You should not alter the store until this channel is closed.
 // Note: reports final SHA only, extensions are ignored.
 func (s *LocalStorage) ScanObjectsChan() <-chan Object {
+	return s.ScanObjectsChanWithWorkers(1)
+}
+
+// ScanObjectsChanWithWorkers is like ScanObjectsChan, but walks up to "workers"
+// of the shard directories at the top of the store at once, which is faster on
+// stores with many objects. Objects are sent as they are found, so they are in
+// no particular order when "workers" is more than one.
+func (s *LocalStorage) ScanObjectsChanWithWorkers(workers int) <-chan Object {
 	ch := make(chan Object, chanBufSize)
 
 	go func() {
 		defer close(ch)
-		scanObjects(s.RootDir, ch)
+		if workers > 1 {
+			scanObjectsWithWorkers(s.RootDir, workers, ch)
+		} else {
+			scanObjects(s.RootDir, ch)
+		}
 	}()
 
 	return ch
 }
 
-func scanObjects(dir string, ch chan<- Object) {
-	dirf, err := os.Open(dir)
+func scanObjectsWithWorkers(dir string, workers int, ch chan<- Object) {
+	direntries, err := readDirEntries(dir)
 	if err != nil {
 		return
 	}
-	defer dirf.Close()
 
-	direntries, err := dirf.Readdir(0)
+	shards := make(chan string, len(direntries))
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for shard := range shards {
+				scanObjects(shard, ch)
+			}
+		}()
+	}
+
+	for _, dirfi := range direntries {
+		if dirfi.IsDir() {
+			shards <- filepath.Join(dir, dirfi.Name())
+		} else {
+			sendObject(dirfi, ch)
+		}
+	}
+	close(shards)
+	wg.Wait()
+}
+
+func scanObjects(dir string, ch chan<- Object) {
+	direntries, err := readDirEntries(dir)
 	if err != nil {
-		tracerx.Printf("Problem with Readdir in %q: %s", dir, err)
 		return
 	}
 
@@ -51,10 +86,33 @@ func scanObjects(dir string, ch chan<- Object) {
 			subpath := filepath.Join(dir, dirfi.Name())
 			scanObjects(subpath, ch)
 		} else {
-			// Make sure it's really an object file & not .DS_Store etc
-			if oidRE.MatchString(dirfi.Name()) {
-				ch <- Object{dirfi.Name(), dirfi.Size()}
-			}
+			sendObject(dirfi, ch)
+		}
+	}
+}
+
+func readDirEntries(dir string) ([]os.FileInfo, error) {
+	dirf, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer dirf.Close()
+
+	direntries, err := dirf.Readdir(0)
+	if err != nil {
+		tracerx.Printf("Problem with Readdir in %q: %s", dir, err)
+		return nil, err
+	}
+	return direntries, nil
+}
+
+func sendObject(fi os.FileInfo, ch chan<- Object) {
+	// Make sure it's really an object file & not .DS_Store etc
+	if oidRE.MatchString(fi.Name()) {
+		ch <- Object{
+			Oid:      fi.Name(),
+			Size:     fi.Size(),
+			Accessed: accessTime(fi),
 		}
 	}
 }
@@ -1,9 +1,12 @@
 package localstorage
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/rubyist/tracerx"
@@ -61,3 +64,159 @@ func shouldDeleteTempObject(s *LocalStorage, path string) bool {
 
 	return false
 }
+
+// tempFilePrefix begins the name of each temporary file created by TempFile.
+// It is followed by the host name and PID of the process which created the file
+// and the Unix time at which it did so, so that files left behind by processes
+// which have since died can be told apart from those which are still being
+// written.
+const tempFilePrefix = "git-lfs-tmp-"
+
+// orphanedTempFileAge is how old a temporary file must be before it is taken
+// to be left behind by a process which is no longer running, so that a file
+// whose process has only just started, or whose PID has been reused by
+// another, is never taken.
+const orphanedTempFileAge = time.Hour
+
+var (
+	tempFileHost     string
+	tempFileHostOnce sync.Once
+)
+
+// tempFileHostName returns the host name which temporary files are named with.
+// Dashes, which separate the parts of the name, and any other characters
+// which cannot appear in a file name, are replaced.
+func tempFileHostName() string {
+	tempFileHostOnce.Do(func() {
+		host, err := os.Hostname()
+		if err != nil || len(host) == 0 {
+			host = "localhost"
+		}
+		tempFileHost = strings.Map(func(r rune) rune {
+			if r == '.' || (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+				return r
+			}
+			return '_'
+		}, host)
+	})
+	return tempFileHost
+}
+
+func tempFileNamePrefix(prefix string) string {
+	return fmt.Sprintf("%s%s-%d-%d-%s", tempFilePrefix, tempFileHostName(), os.Getpid(), time.Now().Unix(), prefix)
+}
+
+// TempFileName returns the name of a temporary file called "name", owned by
+// this process, which is not created. Files named this way are found by
+// ClaimTempFile.
+func TempFileName(name string) string {
+	return tempFileNamePrefix(name)
+}
+
+// ParseTempFileName returns the host name and PID of the process which created
+// the temporary file called "name", when it did so, and the name it was given,
+// or false if "name" was not created by TempFile or named by TempFileName.
+func ParseTempFileName(name string) (host string, pid int, created time.Time, base string, ok bool) {
+	if !strings.HasPrefix(name, tempFilePrefix) {
+		return "", 0, time.Time{}, "", false
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(name, tempFilePrefix), "-", 4)
+	if len(parts) < 4 || len(parts[0]) == 0 {
+		return "", 0, time.Time{}, "", false
+	}
+
+	pid, err := strconv.Atoi(parts[1])
+	if err != nil || pid <= 0 {
+		return "", 0, time.Time{}, "", false
+	}
+
+	secs, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return "", 0, time.Time{}, "", false
+	}
+
+	return parts[0], pid, time.Unix(secs, 0), parts[3], true
+}
+
+// isOrphanedTempFile returns whether the temporary file called "name" was left
+// behind by a process on this host which is no longer running, at least
+// orphanedTempFileAge ago. Files from other hosts, which may share the
+// directory, are never orphaned, since their processes cannot be checked.
+func isOrphanedTempFile(name string) bool {
+	host, pid, created, _, ok := ParseTempFileName(name)
+	if !ok || host != tempFileHostName() {
+		return false
+	}
+	if time.Since(created) < orphanedTempFileAge {
+		return false
+	}
+	return pid != os.Getpid() && !processExists(pid)
+}
+
+// ClaimTempFile looks in "dir" for a temporary file called "name", which was
+// named by TempFileName in this process, or in one on this host which is no
+// longer running, however recently, and renames it to the name TempFileName
+// gives "name" now, so that no other process claims it. It returns the new
+// path, or false if there was no such file.
+func ClaimTempFile(dir, name string) (string, bool) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return "", false
+	}
+	names, _ := d.Readdirnames(-1)
+	d.Close()
+
+	for _, n := range names {
+		host, pid, _, base, ok := ParseTempFileName(n)
+		if !ok || base != name || host != tempFileHostName() {
+			continue
+		}
+		if pid != os.Getpid() && processExists(pid) {
+			continue
+		}
+
+		path := filepath.Join(dir, TempFileName(name))
+		// Another process may have claimed it first.
+		if err := os.Rename(filepath.Join(dir, n), path); err != nil {
+			tracerx.Printf("tmp: could not claim %s: %s", n, err)
+			continue
+		}
+		return path, true
+	}
+	return "", false
+}
+
+// OrphanedTempFiles returns the paths of the temporary files in TempDir which
+// were created by TempFile in a process on this host which is no longer
+// running, at least an hour ago. Files which were not created by TempFile are
+// never included.
+func OrphanedTempFiles() ([]string, error) {
+	d, err := os.Open(TempDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer d.Close()
+
+	infos, err := d.Readdir(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	var orphaned []string
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+
+		if !isOrphanedTempFile(info.Name()) {
+			continue
+		}
+
+		orphaned = append(orphaned, filepath.Join(TempDir, info.Name()))
+	}
+	return orphaned, nil
+}
@@ -0,0 +1,128 @@
+// Package logger provides the Logger which diagnostics from Git LFS, such as
+// warnings about malformed files and end of run summaries, are sent to. By
+// default they are written to stderr, as the git-lfs command prints them, but
+// programs using Git LFS as a library may replace it with SetLogger.
+// NOTE: Subject to change, do not rely on this package from outside git-lfs source
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Level is how important an Entry is.
+type Level int
+
+const (
+	// Info is for messages which tell the user what is happening, such as
+	// a file being downloaded, or a summary of the work done.
+	Info Level = iota
+	// Warning is for problems which Git LFS works around, but which the
+	// user should know about.
+	Warning
+	// Error is for problems which stop something from being done.
+	Error
+)
+
+func (l Level) String() string {
+	switch l {
+	case Info:
+		return "info"
+	case Warning:
+		return "warning"
+	case Error:
+		return "error"
+	default:
+		return fmt.Sprintf("<unknown level %d>", int(l))
+	}
+}
+
+// Fields are the structured details of an Entry, such as the path of the file
+// or the OID of the object it is about, keyed by name.
+type Fields map[string]interface{}
+
+// Entry is a single diagnostic.
+type Entry struct {
+	Level Level
+	// Message is the text the git-lfs command prints, without a trailing
+	// newline. It may span several lines.
+	Message string
+	// Fields are the details of the Entry, if any, which are already part
+	// of the Message.
+	Fields Fields
+}
+
+// Logger receives each Entry logged by Git LFS. It may be called from several
+// goroutines at once.
+type Logger interface {
+	Log(e *Entry)
+}
+
+// NewWriterLogger returns a Logger which writes the message of each Entry to
+// "w", followed by a newline, which is how the git-lfs command prints them.
+func NewWriterLogger(w io.Writer) Logger {
+	return &writerLogger{w: w}
+}
+
+type writerLogger struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *writerLogger) Log(e *Entry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	fmt.Fprintln(l.w, e.Message)
+}
+
+var (
+	mu      sync.RWMutex
+	current Logger = NewWriterLogger(os.Stderr)
+)
+
+// SetLogger sends every Entry logged from now on to "l", or, if it is nil, to
+// stderr again.
+func SetLogger(l Logger) {
+	if l == nil {
+		l = NewWriterLogger(os.Stderr)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	current = l
+}
+
+// Log formats according to "format" and sends the result to the current Logger
+// at "level", with the details "fields", which may be nil. A trailing newline
+// is dropped from the message.
+func Log(level Level, fields Fields, format string, args ...interface{}) {
+	mu.RLock()
+	l := current
+	mu.RUnlock()
+
+	l.Log(&Entry{
+		Level:   level,
+		Message: strings.TrimSuffix(fmt.Sprintf(format, args...), "\n"),
+		Fields:  fields,
+	})
+}
+
+// Infof logs an Entry at the Info level, without any Fields.
+func Infof(format string, args ...interface{}) {
+	Log(Info, nil, format, args...)
+}
+
+// Warningf logs an Entry at the Warning level, without any Fields.
+func Warningf(format string, args ...interface{}) {
+	Log(Warning, nil, format, args...)
+}
+
+// Errorf logs an Entry at the Error level, without any Fields.
+func Errorf(format string, args ...interface{}) {
+	Log(Error, nil, format, args...)
+}
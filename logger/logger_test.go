@@ -0,0 +1,56 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingLogger struct {
+	entries []*Entry
+}
+
+func (l *recordingLogger) Log(e *Entry) {
+	l.entries = append(l.entries, e)
+}
+
+func TestWriterLoggerWritesMessages(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf)
+
+	l.Log(&Entry{Level: Warning, Message: "warning: one"})
+	l.Log(&Entry{Level: Info, Message: "two\n\tthree", Fields: Fields{"path": "a.dat"}})
+
+	assert.Equal(t, "warning: one\ntwo\n\tthree\n", buf.String())
+}
+
+func TestSetLoggerReceivesEntries(t *testing.T) {
+	l := &recordingLogger{}
+	SetLogger(l)
+	defer SetLogger(nil)
+
+	Log(Warning, Fields{"oid": "abc"}, "warning: object %s is bad\n", "abc")
+	Infof("Downloading %s", "a.dat")
+	Errorf("ERROR: %s", "failed")
+
+	require.Len(t, l.entries, 3)
+	assert.Equal(t, Warning, l.entries[0].Level)
+	assert.Equal(t, "warning: object abc is bad", l.entries[0].Message)
+	assert.Equal(t, Fields{"oid": "abc"}, l.entries[0].Fields)
+
+	assert.Equal(t, Info, l.entries[1].Level)
+	assert.Equal(t, "Downloading a.dat", l.entries[1].Message)
+	assert.Nil(t, l.entries[1].Fields)
+
+	assert.Equal(t, Error, l.entries[2].Level)
+	assert.Equal(t, "ERROR: failed", l.entries[2].Message)
+}
+
+func TestLevelString(t *testing.T) {
+	assert.Equal(t, "info", Info.String())
+	assert.Equal(t, "warning", Warning.String())
+	assert.Equal(t, "error", Error.String())
+	assert.Equal(t, "<unknown level 9>", Level(9).String())
+}
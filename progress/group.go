@@ -0,0 +1,136 @@
+package progress
+
+import (
+	"sync/atomic"
+)
+
+// GroupMeter is a Meter which can report on groups of transfers separately, as
+// well as on all of them together.
+type GroupMeter interface {
+	Meter
+
+	// Group returns a Meter which passes everything it is told on to this
+	// one, while also counting it towards the group "name".
+	Group(name string) Meter
+}
+
+// GroupStatus is the progress of a single group of transfers made through a
+// ProgressMeter, as returned by its Groups() method.
+type GroupStatus struct {
+	Name           string
+	FinishedFiles  int64
+	EstimatedFiles int64
+	SkippedFiles   int64
+	CurrentBytes   int64
+	EstimatedBytes int64
+	SkippedBytes   int64
+
+	// Finished is true once Finish() has been called on the group's Meter.
+	Finished bool
+}
+
+// meterGroup holds the counts for a single group of a ProgressMeter.
+type meterGroup struct {
+	finishedFiles  int64 // int64s must come first for struct alignment
+	estimatedFiles int64
+	skippedFiles   int64
+	currentBytes   int64
+	estimatedBytes int64
+	skippedBytes   int64
+	finished       int32
+	name           string
+}
+
+func (g *meterGroup) status() *GroupStatus {
+	return &GroupStatus{
+		Name:           g.name,
+		FinishedFiles:  atomic.LoadInt64(&g.finishedFiles),
+		EstimatedFiles: atomic.LoadInt64(&g.estimatedFiles),
+		SkippedFiles:   atomic.LoadInt64(&g.skippedFiles),
+		CurrentBytes:   atomic.LoadInt64(&g.currentBytes),
+		EstimatedBytes: atomic.LoadInt64(&g.estimatedBytes),
+		SkippedBytes:   atomic.LoadInt64(&g.skippedBytes),
+		Finished:       atomic.LoadInt32(&g.finished) == 1,
+	}
+}
+
+// Group returns a Meter for the group of transfers "name", which updates this
+// ProgressMeter as well as the counts for the group. Meters returned for the
+// same name share their counts.
+//
+// Calling Finish() on the returned Meter only marks the group as finished, since
+// other groups may still be transferring; this ProgressMeter must be finished
+// on its own once every group is done.
+func (p *ProgressMeter) Group(name string) Meter {
+	p.groupsMutex.Lock()
+	defer p.groupsMutex.Unlock()
+
+	for _, g := range p.groups {
+		if g.name == name {
+			return &groupMeter{p: p, g: g}
+		}
+	}
+
+	g := &meterGroup{name: name}
+	p.groups = append(p.groups, g)
+	return &groupMeter{p: p, g: g}
+}
+
+// Groups returns the progress of each group of transfers, in the order in which
+// they were first given to Group().
+func (p *ProgressMeter) Groups() []*GroupStatus {
+	p.groupsMutex.Lock()
+	defer p.groupsMutex.Unlock()
+
+	groups := make([]*GroupStatus, 0, len(p.groups))
+	for _, g := range p.groups {
+		groups = append(groups, g.status())
+	}
+	return groups
+}
+
+// groupMeter is the Meter returned by ProgressMeter.Group().
+type groupMeter struct {
+	p *ProgressMeter
+	g *meterGroup
+}
+
+func (m *groupMeter) Start() {
+	m.p.Start()
+}
+
+func (m *groupMeter) Pause() {
+	m.p.Pause()
+}
+
+func (m *groupMeter) Add(size int64) {
+	m.p.Add(size)
+	atomic.AddInt64(&m.g.estimatedFiles, 1)
+	atomic.AddInt64(&m.g.estimatedBytes, size)
+}
+
+func (m *groupMeter) Skip(size int64) {
+	m.p.Skip(size)
+	atomic.AddInt64(&m.g.skippedFiles, 1)
+	atomic.AddInt64(&m.g.skippedBytes, size)
+	atomic.AddInt64(&m.g.estimatedFiles, -1)
+	atomic.AddInt64(&m.g.estimatedBytes, -size)
+}
+
+func (m *groupMeter) StartTransfer(name string) {
+	m.p.StartTransfer(name)
+}
+
+func (m *groupMeter) TransferBytes(direction, name string, read, total int64, current int) {
+	m.p.TransferBytes(direction, name, read, total, current)
+	atomic.AddInt64(&m.g.currentBytes, int64(current))
+}
+
+func (m *groupMeter) FinishTransfer(name string) {
+	m.p.FinishTransfer(name)
+	atomic.AddInt64(&m.g.finishedFiles, 1)
+}
+
+func (m *groupMeter) Finish() {
+	atomic.StoreInt32(&m.g.finished, 1)
+}
@@ -0,0 +1,57 @@
+package progress
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupsCountTowardsMeterAndGroup(t *testing.T) {
+	m := NewMeter(DryRun(true))
+
+	a := m.Group("a")
+	a.Add(10)
+	a.Add(20)
+	a.StartTransfer("a.dat")
+	a.TransferBytes("download", "a.dat", 10, 10, 10)
+	a.FinishTransfer("a.dat")
+	a.Skip(20)
+	a.Finish()
+
+	b := m.Group("b")
+	b.Add(5)
+	b.TransferBytes("download", "b.dat", 2, 5, 2)
+
+	m.Group("a").Add(1)
+
+	s := m.status()
+	assert.EqualValues(t, 1, s.FinishedFiles)
+	assert.EqualValues(t, 3, s.EstimatedFiles)
+	assert.EqualValues(t, 12, s.CurrentBytes)
+	assert.EqualValues(t, 16, s.EstimatedBytes)
+
+	require.Len(t, s.Groups, 2)
+	assert.Equal(t, &GroupStatus{
+		Name: "a", FinishedFiles: 1, EstimatedFiles: 2, SkippedFiles: 1,
+		CurrentBytes: 10, EstimatedBytes: 11, SkippedBytes: 20,
+		Finished: true,
+	}, s.Groups[0])
+	assert.Equal(t, &GroupStatus{
+		Name: "b", EstimatedFiles: 1, CurrentBytes: 2, EstimatedBytes: 5,
+	}, s.Groups[1])
+}
+
+func TestWithTemplateRendersGroups(t *testing.T) {
+	opt, err := WithTemplate("{{range .Groups}}{{.Name}} {{.FinishedFiles}}/{{.EstimatedFiles}}; {{end}}")
+	require.Nil(t, err)
+
+	m := NewMeter(opt)
+	m.Group("a").Add(1)
+	m.Group("b").Add(1)
+	m.Group("b").FinishTransfer("b.dat")
+
+	out, err := renderTemplate(m.template, m.status())
+	require.Nil(t, err)
+	assert.Equal(t, "a 0/1; b 1/1; ", out)
+}
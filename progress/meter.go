@@ -7,6 +7,7 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"text/template"
 	"time"
 
 	"github.com/olekukonko/ts"
@@ -31,6 +32,14 @@ type ProgressMeter struct {
 	fileIndex         map[string]int64 // Maps a file name to its transfer number
 	fileIndexMutex    *sync.Mutex
 	dryRun            bool
+	rateSamples       int
+	rate              *rateWindow
+	template          *template.Template
+
+	// groups are the groups of transfers given to Group(), guarded by
+	// groupsMutex.
+	groups      []*meterGroup
+	groupsMutex sync.Mutex
 }
 
 type env interface {
@@ -47,6 +56,16 @@ func DryRun(dryRun bool) meterOption {
 	}
 }
 
+// WithRateSamples is an option for NewMeter() that sets the number of recent
+// samples used to compute the transfer rate and estimated time remaining. Only
+// that many samples are ever retained. If "n" is less than two, a default is
+// used.
+func WithRateSamples(n int) meterOption {
+	return func(m *ProgressMeter) {
+		m.rateSamples = n
+	}
+}
+
 // WithLogFile is an option for NewMeter() that sends updates to a text file.
 func WithLogFile(name string) meterOption {
 	printErr := func(err string) {
@@ -95,12 +114,15 @@ func NewMeter(options ...meterOption) *ProgressMeter {
 		fileIndex:      make(map[string]int64),
 		fileIndexMutex: &sync.Mutex{},
 		finished:       make(chan interface{}),
+		template:       defaultTemplate,
 	}
 
 	for _, opt := range options {
 		opt(m)
 	}
 
+	m.rate = newRateWindow(m.rateSamples)
+
 	return m
 }
 
@@ -196,19 +218,56 @@ func (p *ProgressMeter) update() {
 		return
 	}
 
-	// (%d of %d files, %d skipped) %f B / %f B, %f B skipped
-	// skipped counts only show when > 0
-
-	out := fmt.Sprintf("\rGit LFS: (%d of %d files", p.finishedFiles, p.estimatedFiles)
-	if p.skippedFiles > 0 {
-		out += fmt.Sprintf(", %d skipped", p.skippedFiles)
+	out, err := renderTemplate(p.template, p.status())
+	if err != nil {
+		// Templates are checked when they are given, so this is
+		// not expected to happen.
+		out = fmt.Sprintf("Git LFS: %s", err)
 	}
-	out += fmt.Sprintf(") %s / %s", formatBytes(p.currentBytes), formatBytes(p.estimatedBytes))
-	if p.skippedBytes > 0 {
-		out += fmt.Sprintf(", %s skipped", formatBytes(p.skippedBytes))
+
+	fmt.Fprint(os.Stdout, pad("\r"+out))
+}
+
+// status returns the state of the meter, which its template is executed against
+// to render its status line, and adds a sample of the bytes transferred so far
+// to those used to compute the rate.
+func (p *ProgressMeter) status() *MeterStatus {
+	p.rate.Add(time.Now(), atomic.LoadInt64(&p.currentBytes))
+	return p.Snapshot()
+}
+
+// Snapshot returns the current state of the meter, for a user interface which
+// embeds Git LFS to render as often as it likes, rather than parsing the status
+// line. It may be called from any goroutine, at any time, even after Finish().
+// Each count is read atomically, although, since transfers carry on while it
+// is called, they may not all have been read at quite the same instant. Unlike
+// the status line, it takes no sample for the rate, which is computed from the
+// samples taken each time the status line is updated, and so is only known once
+// the meter has been started.
+func (p *ProgressMeter) Snapshot() *MeterStatus {
+	currentBytes := atomic.LoadInt64(&p.currentBytes)
+
+	s := &MeterStatus{
+		FinishedFiles:  atomic.LoadInt64(&p.finishedFiles),
+		EstimatedFiles: int64(atomic.LoadInt32(&p.estimatedFiles)),
+		SkippedFiles:   atomic.LoadInt64(&p.skippedFiles),
+		CurrentBytes:   currentBytes,
+		EstimatedBytes: atomic.LoadInt64(&p.estimatedBytes),
+		SkippedBytes:   atomic.LoadInt64(&p.skippedBytes),
+		Groups:         p.Groups(),
 	}
 
-	fmt.Fprintf(os.Stdout, pad(out))
+	// Rate and ETA are only shown when known.
+	if rate, ok := p.rate.Rate(); ok && rate > 0 {
+		s.Rate, s.HasRate = int64(rate), true
+
+		if remaining := s.EstimatedBytes - currentBytes; remaining > 0 {
+			if eta, ok := p.rate.ETA(remaining); ok {
+				s.ETA, s.HasETA = eta-eta%time.Second, true
+			}
+		}
+	}
+	return s
 }
 
 func formatBytes(i int64) string {
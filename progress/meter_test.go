@@ -0,0 +1,71 @@
+package progress
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotReturnsCounts(t *testing.T) {
+	m := NewMeter(DryRun(true))
+	m.Add(10)
+	m.Add(20)
+	m.Skip(20)
+	m.StartTransfer("a.dat")
+	m.TransferBytes("download", "a.dat", 4, 10, 4)
+
+	s := m.Snapshot()
+	assert.EqualValues(t, 0, s.FinishedFiles)
+	assert.EqualValues(t, 1, s.EstimatedFiles)
+	assert.EqualValues(t, 1, s.SkippedFiles)
+	assert.EqualValues(t, 4, s.CurrentBytes)
+	assert.EqualValues(t, 10, s.EstimatedBytes)
+	assert.EqualValues(t, 20, s.SkippedBytes)
+	assert.False(t, s.HasRate)
+
+	m.TransferBytes("download", "a.dat", 10, 10, 6)
+	m.FinishTransfer("a.dat")
+	m.Finish()
+
+	s = m.Snapshot()
+	assert.EqualValues(t, 1, s.FinishedFiles)
+	assert.EqualValues(t, 10, s.CurrentBytes)
+}
+
+func TestSnapshotDoesNotSampleRate(t *testing.T) {
+	m := NewMeter(DryRun(true))
+	m.Add(10)
+	m.TransferBytes("download", "a.dat", 5, 10, 5)
+
+	for i := 0; i < 5; i++ {
+		m.Snapshot()
+	}
+
+	_, ok := m.rate.Rate()
+	assert.False(t, ok)
+}
+
+func TestSnapshotWhileTransferring(t *testing.T) {
+	m := NewMeter(DryRun(true))
+	m.Add(1000)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			m.TransferBytes("download", "a.dat", int64(i+1), 1000, 1)
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		s := m.Snapshot()
+		assert.True(t, s.CurrentBytes <= s.EstimatedBytes)
+	}
+	wg.Wait()
+
+	assert.EqualValues(t, 1000, m.Snapshot().CurrentBytes)
+}
+
+var _ SnapshotMeter = (*ProgressMeter)(nil)
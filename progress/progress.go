@@ -12,3 +12,12 @@ type Meter interface {
 	FinishTransfer(name string)
 	Finish()
 }
+
+// SnapshotMeter is a Meter which can be asked for its current state, such as by
+// a user interface which polls it.
+type SnapshotMeter interface {
+	Meter
+
+	// Snapshot returns the current state of the meter.
+	Snapshot() *MeterStatus
+}
@@ -0,0 +1,100 @@
+package progress
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// defaultRateSamples is the number of samples retained by a rateWindow
+	// when no (or an invalid) sample count is given. The ProgressMeter takes
+	// a sample on each update, so this covers roughly the last five seconds
+	// of a transfer.
+	defaultRateSamples = 25
+)
+
+// rateSample is a single observation of the number of bytes transferred at a
+// given instant.
+type rateSample struct {
+	at    time.Time
+	bytes int64
+}
+
+// rateWindow estimates the rate of a transfer from only the most recent
+// samples given to it. It holds at most a fixed number of samples, discarding
+// the oldest as new ones arrive, so that its memory use remains constant no
+// matter how long the transfer runs.
+//
+// rateWindow is safe to use across multiple goroutines.
+type rateWindow struct {
+	// mu guards samples, next, and full.
+	mu sync.Mutex
+	// samples is a ring buffer of the retained samples.
+	samples []rateSample
+	// next is the index in samples at which the next sample is written.
+	next int
+	// full is whether or not samples has wrapped around at least once.
+	full bool
+}
+
+// newRateWindow returns a new *rateWindow retaining at most "n" samples. If
+// "n" is less than two, defaultRateSamples is used instead, since at least two
+// samples are needed to compute a rate.
+func newRateWindow(n int) *rateWindow {
+	if n < 2 {
+		n = defaultRateSamples
+	}
+
+	return &rateWindow{samples: make([]rateSample, n)}
+}
+
+// Add records that "bytes" bytes had been transferred in total as of "at",
+// evicting the oldest sample if the window is full.
+func (w *rateWindow) Add(at time.Time, bytes int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.samples[w.next] = rateSample{at: at, bytes: bytes}
+	w.next = (w.next + 1) % len(w.samples)
+	if w.next == 0 {
+		w.full = true
+	}
+}
+
+// Rate returns the average number of bytes transferred per second between the
+// oldest and newest samples in the window. If fewer than two samples have been
+// recorded or no time has elapsed between them, Rate returns false.
+func (w *rateWindow) Rate() (float64, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.full && w.next < 2 {
+		return 0, false
+	}
+
+	oldest := w.samples[0]
+	if w.full {
+		oldest = w.samples[w.next]
+	}
+	newest := w.samples[(w.next+len(w.samples)-1)%len(w.samples)]
+
+	elapsed := newest.at.Sub(oldest.at).Seconds()
+	if elapsed <= 0 {
+		return 0, false
+	}
+
+	return float64(newest.bytes-oldest.bytes) / elapsed, true
+}
+
+// ETA returns the estimated time remaining to transfer "remaining" more bytes
+// at the rate given by Rate. If the rate is unknown, or no progress is being
+// made, ETA returns false.
+func (w *rateWindow) ETA(remaining int64) (time.Duration, bool) {
+	rate, ok := w.Rate()
+	if !ok || rate <= 0 {
+		return 0, false
+	}
+
+	secs := float64(remaining) / rate
+	return time.Duration(secs * float64(time.Second)), true
+}
@@ -0,0 +1,72 @@
+package progress
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateWindowWithoutSamples(t *testing.T) {
+	w := newRateWindow(4)
+
+	_, ok := w.Rate()
+	assert.False(t, ok)
+
+	w.Add(time.Unix(0, 0), 10)
+
+	_, ok = w.Rate()
+	assert.False(t, ok)
+}
+
+func TestRateWindowComputesRate(t *testing.T) {
+	w := newRateWindow(4)
+	w.Add(time.Unix(0, 0), 0)
+	w.Add(time.Unix(1, 0), 100)
+	w.Add(time.Unix(2, 0), 200)
+
+	rate, ok := w.Rate()
+	assert.True(t, ok)
+	assert.Equal(t, float64(100), rate)
+}
+
+func TestRateWindowDiscardsOldSamples(t *testing.T) {
+	w := newRateWindow(3)
+	w.Add(time.Unix(0, 0), 0)
+	w.Add(time.Unix(1, 0), 1000)
+	// Only the last three samples are retained, so the initial burst of
+	// 1000 B/s no longer contributes to the rate.
+	w.Add(time.Unix(2, 0), 1010)
+	w.Add(time.Unix(3, 0), 1020)
+	w.Add(time.Unix(4, 0), 1030)
+
+	assert.Len(t, w.samples, 3)
+
+	rate, ok := w.Rate()
+	assert.True(t, ok)
+	assert.Equal(t, float64(10), rate)
+}
+
+func TestRateWindowDefaultsInvalidSampleCount(t *testing.T) {
+	assert.Len(t, newRateWindow(0).samples, defaultRateSamples)
+	assert.Len(t, newRateWindow(1).samples, defaultRateSamples)
+}
+
+func TestRateWindowETA(t *testing.T) {
+	w := newRateWindow(4)
+	w.Add(time.Unix(0, 0), 0)
+	w.Add(time.Unix(2, 0), 200)
+
+	eta, ok := w.ETA(500)
+	assert.True(t, ok)
+	assert.Equal(t, 5*time.Second, eta)
+}
+
+func TestRateWindowETAWithoutProgress(t *testing.T) {
+	w := newRateWindow(4)
+	w.Add(time.Unix(0, 0), 100)
+	w.Add(time.Unix(2, 0), 100)
+
+	_, ok := w.ETA(500)
+	assert.False(t, ok)
+}
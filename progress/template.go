@@ -0,0 +1,109 @@
+package progress
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"time"
+)
+
+// DefaultTemplate is the template that the ProgressMeter renders its status
+// line with, unless it is given another with WithTemplate().
+const DefaultTemplate = "Git LFS: ({{.FinishedFiles}} of {{.EstimatedFiles}} files" +
+	"{{if gt .SkippedFiles 0}}, {{.SkippedFiles}} skipped{{end}}) " +
+	"{{bytes .CurrentBytes}} / {{bytes .EstimatedBytes}}" +
+	"{{if gt .SkippedBytes 0}}, {{bytes .SkippedBytes}} skipped{{end}}" +
+	"{{if .HasRate}}, {{bytes .Rate}}/s{{if .HasETA}}, ETA {{.ETA}}{{end}}{{end}}"
+
+// MeterStatus is the state of a ProgressMeter, which its template is executed
+// against each time it updates its status line.
+type MeterStatus struct {
+	FinishedFiles  int64
+	EstimatedFiles int64
+	SkippedFiles   int64
+	CurrentBytes   int64
+	EstimatedBytes int64
+	SkippedBytes   int64
+
+	// Rate is the number of bytes transferred per second, if HasRate is
+	// true.
+	Rate    int64
+	HasRate bool
+	// ETA is the estimated time remaining, to the second, if HasETA is
+	// true. It is never known without the rate.
+	ETA    time.Duration
+	HasETA bool
+
+	// Groups are the progress of each group of transfers given to Group(),
+	// if any.
+	Groups []*GroupStatus
+}
+
+// templateFuncs are the functions which templates given to WithTemplate() may
+// call, as well as those built in to text/template. "bytes" formats a number of
+// bytes in the largest unit it exceeds, such as "1.50 MB".
+var templateFuncs = template.FuncMap{
+	"bytes": formatBytes,
+}
+
+// WithTemplate returns an option for NewMeter() that renders the status line
+// with the text/template "tmpl", executed against a MeterStatus, instead of
+// with DefaultTemplate. The line is still written over the previous one, and
+// padded to the width of the terminal.
+//
+// An error is returned if "tmpl" cannot be parsed, or cannot be executed
+// against a MeterStatus, such as when it refers to a field which does not exist.
+func WithTemplate(tmpl string) (meterOption, error) {
+	t, err := parseTemplate(tmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(m *ProgressMeter) {
+		m.template = t
+	}, nil
+}
+
+func parseTemplate(tmpl string) (*template.Template, error) {
+	t, err := template.New("progress").Funcs(templateFuncs).Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("progress: invalid template: %s", err)
+	}
+
+	// Try both branches of any conditions on whether there is anything to
+	// show, so that mistakes in either are found now, not part way through
+	// a transfer.
+	for _, s := range templateSamples {
+		if _, err := renderTemplate(t, s); err != nil {
+			return nil, fmt.Errorf("progress: invalid template: %s", err)
+		}
+	}
+	return t, nil
+}
+
+var templateSamples = []*MeterStatus{
+	&MeterStatus{},
+	&MeterStatus{
+		FinishedFiles: 1, EstimatedFiles: 2, SkippedFiles: 1,
+		CurrentBytes: 1, EstimatedBytes: 2, SkippedBytes: 1,
+		Rate: 1, HasRate: true,
+		ETA: time.Second, HasETA: true,
+		Groups: []*GroupStatus{
+			&GroupStatus{
+				Name: "sample", FinishedFiles: 1, EstimatedFiles: 2,
+				SkippedFiles: 1, CurrentBytes: 1, EstimatedBytes: 2,
+				SkippedBytes: 1, Finished: true,
+			},
+		},
+	},
+}
+
+func renderTemplate(t *template.Template, s *MeterStatus) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, s); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+var defaultTemplate = template.Must(parseTemplate(DefaultTemplate))
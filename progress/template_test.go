@@ -0,0 +1,73 @@
+package progress
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultTemplate(t *testing.T) {
+	for desc, c := range map[string]struct {
+		Status   *MeterStatus
+		Expected string
+	}{
+		"started": {
+			&MeterStatus{EstimatedFiles: 3, EstimatedBytes: 2048},
+			"Git LFS: (0 of 3 files) 0 B / 2.00 KB",
+		},
+		"skipped": {
+			&MeterStatus{
+				FinishedFiles: 1, EstimatedFiles: 2, SkippedFiles: 1,
+				CurrentBytes: 10, EstimatedBytes: 20, SkippedBytes: 5,
+			},
+			"Git LFS: (1 of 2 files, 1 skipped) 10 B / 20 B, 5 B skipped",
+		},
+		"rate and eta": {
+			&MeterStatus{
+				FinishedFiles: 1, EstimatedFiles: 2,
+				CurrentBytes: 1536, EstimatedBytes: 3072,
+				Rate: 512, HasRate: true,
+				ETA: 3 * time.Second, HasETA: true,
+			},
+			"Git LFS: (1 of 2 files) 1.50 KB / 3.00 KB, 512 B/s, ETA 3s",
+		},
+		"eta without rate": {
+			&MeterStatus{EstimatedFiles: 1, ETA: time.Second, HasETA: true},
+			"Git LFS: (0 of 1 files) 0 B / 0 B",
+		},
+	} {
+		out, err := renderTemplate(defaultTemplate, c.Status)
+		require.Nil(t, err, desc)
+		assert.Equal(t, c.Expected, out, desc)
+	}
+}
+
+func TestWithTemplate(t *testing.T) {
+	opt, err := WithTemplate("{{.FinishedFiles}}/{{.EstimatedFiles}} {{bytes .CurrentBytes}}")
+	require.Nil(t, err)
+
+	m := NewMeter(opt)
+	m.Add(2048)
+	m.TransferBytes("download", "a.dat", 1024, 2048, 1024)
+
+	out, err := renderTemplate(m.template, m.status())
+	require.Nil(t, err)
+	assert.Equal(t, "0/1 1024 B", out)
+}
+
+func TestWithTemplateRejectsBadTemplates(t *testing.T) {
+	for desc, tmpl := range map[string]string{
+		"syntax":         "{{.FinishedFiles",
+		"unknown field":  "{{.Unknown}}",
+		"unknown func":   "{{kilobytes .CurrentBytes}}",
+		"bad comparison": "{{if gt .Rate 0.5}}fast{{end}}",
+	} {
+		opt, err := WithTemplate(tmpl)
+		assert.Nil(t, opt, desc)
+		if assert.NotNil(t, err, desc) {
+			assert.Contains(t, err.Error(), "progress: invalid template: ", desc)
+		}
+	}
+}
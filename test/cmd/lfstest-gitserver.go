@@ -287,6 +287,13 @@ func lfsBatchHandler(w http.ResponseWriter, r *http.Request, id, repo string) {
 		return
 	}
 
+	if strings.HasSuffix(repo, "batch-quota-exceeded") {
+		w.Header().Set("Content-Type", "application/vnd.git-lfs+json")
+		w.WriteHeader(507)
+		w.Write([]byte(`{"message":"quota exceeded","request_id":"req-1234"}`))
+		return
+	}
+
 	if repo == "netrctest" {
 		user, pass, err := extractAuth(r.Header.Get("Authorization"))
 		if err != nil || (user != "netrcuser" || pass != "netrcpass") {
@@ -416,7 +423,8 @@ func lfsBatchHandler(w http.ResponseWriter, r *http.Request, id, repo string) {
 				o.Actions["verify"] = &lfsLink{
 					Href: server.URL + "/verify",
 					Header: map[string]string{
-						"repo": repo,
+						"repo":      repo,
+						"operation": action,
 					},
 				}
 			}
@@ -515,6 +523,7 @@ var (
 
 func verifyHandler(w http.ResponseWriter, r *http.Request) {
 	repo := r.Header.Get("repo")
+	operation := r.Header.Get("operation")
 	var payload struct {
 		Oid  string `json:"oid"`
 		Size int64  `json:"size"`
@@ -532,7 +541,9 @@ func verifyHandler(w http.ResponseWriter, r *http.Request) {
 		max, _ = strconv.Atoi(matches[1])
 	}
 
-	key := strings.Join([]string{repo, payload.Oid}, ":")
+	// Uploads and downloads are counted separately, so that downloads of
+	// an object fail as often as its upload did.
+	key := strings.Join([]string{repo, operation, payload.Oid}, ":")
 
 	vmu.Lock()
 	verifyCounts[key] = verifyCounts[key] + 1
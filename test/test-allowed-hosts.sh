@@ -0,0 +1,64 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "allowed hosts"
+(
+  set -e
+
+  reponame="allowed-hosts"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "a" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  host="$(echo "$GITSERVER" | sed -e "s|^[a-z]*://||")"
+
+  git -c lfs.allowedhosts="git-server.com" lfs push origin master > push.log 2>&1 && exit 1
+  cat push.log
+  grep "refusing to contact $host, which is not in lfs.allowedhosts" push.log
+  refute_server_object "$reponame" "$(calc_oid "a")"
+
+  git -c lfs.allowedhosts="git-server.com, ${host%%:*}" lfs push origin master 2>&1 | tee push.log
+  grep "(1 of 1 files)" push.log
+  assert_server_object "$reponame" "$(calc_oid "a")"
+)
+end_test
+
+begin_test "allowed hosts: strict mode ignores off-list urls in .lfsconfig"
+(
+  set -e
+
+  reponame="allowed-hosts-strict"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  host="$(echo "$GITSERVER" | sed -e "s|^[a-z]*://||")"
+  git config -f .lfsconfig lfs.url "http://attacker.example.com/repo.git/info/lfs"
+
+  # .lfsconfig cannot allow hosts for itself
+  git config -f .lfsconfig lfs.allowedhosts "attacker.example.com"
+
+  git config lfs.allowedhosts "${host%%:*}"
+  git lfs env > env.log 2>&1
+  cat env.log
+  grep "Endpoint=http://attacker.example.com/repo.git/info/lfs" env.log
+  [ "0" -eq "$(grep -c "ignoring" env.log)" ]
+
+  git config lfs.allowedhostsstrict true
+  git lfs env > env.log 2>&1
+  cat env.log
+  grep "warning: ignoring lfs.url = \"http://attacker.example.com/repo.git/info/lfs\" from .lfsconfig, since its host is not in lfs.allowedhosts" env.log
+  grep "Endpoint=$GITSERVER/$reponame.git/info/lfs" env.log
+
+  git lfs track "*.dat"
+  printf "b" > b.dat
+  git add .gitattributes b.dat
+  git commit -m "add b.dat"
+  git push origin master 2>&1 | tee push.log
+  assert_server_object "$reponame" "$(calc_oid "b")"
+)
+end_test
@@ -0,0 +1,127 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "alternates: smudge reads from an alternate"
+(
+  set -e
+
+  reponame="$(basename "$0" ".sh")-smudge"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" alternates-smudge
+
+  git lfs track "*.dat"
+  contents="alternate contents"
+  contents_oid="$(calc_oid "$contents")"
+  printf "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  # move the object into a separate store; it was never pushed
+  mkdir -p "$TRASHDIR/alternate-store"
+  mv .git/lfs/objects/* "$TRASHDIR/alternate-store"
+  refute_local_object "$contents_oid"
+
+  mkdir -p .git/lfs/objects/info
+  echo "# shared cache" > .git/lfs/objects/info/alternates
+  echo "$TRASHDIR/alternate-store" >> .git/lfs/objects/info/alternates
+
+  pointer="$(pointer "$contents_oid" "${#contents}")"
+  [ "$contents" = "$(echo "$pointer" | git lfs smudge a.dat)" ]
+
+  # reading from an alternate never writes to the primary store
+  refute_local_object "$contents_oid"
+
+  # nor does fetching an object which an alternate has
+  git lfs fetch-object "$contents_oid" "${#contents}" > fetch.log
+  [ "$TRASHDIR/alternate-store/${contents_oid:0:2}/${contents_oid:2:2}/$contents_oid" = "$(cat fetch.log)" ]
+  refute_local_object "$contents_oid"
+
+  # and pushing uploads it from the alternate
+  git push origin master 2>&1 | tee push.log
+  grep "(1 of 1 files)" push.log
+  assert_server_object "$reponame" "$contents_oid"
+  refute_local_object "$contents_oid"
+)
+end_test
+
+begin_test "alternates: config and missing alternates"
+(
+  set -e
+
+  reponame="$(basename "$0" ".sh")-config"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" alternates-config
+
+  git lfs track "*.dat"
+  contents="configured alternate"
+  contents_oid="$(calc_oid "$contents")"
+  printf "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  mkdir -p "$TRASHDIR/configured-store"
+  mv .git/lfs/objects/* "$TRASHDIR/configured-store"
+
+  git config --add lfs.alternates "$TRASHDIR/does-not-exist"
+  git config --add lfs.alternates "$TRASHDIR/configured-store"
+
+  pointer="$(pointer "$contents_oid" "${#contents}")"
+  echo "$pointer" | git lfs smudge a.dat > smudge.out 2> smudge.log
+  [ "$contents" = "$(cat smudge.out)" ]
+  grep "warning: skipping Git LFS object alternate $TRASHDIR/does-not-exist" smudge.log
+  refute_local_object "$contents_oid"
+)
+end_test
+
+begin_test "alternates: objects in the legacy object store"
+(
+  set -e
+
+  reponame="$(basename "$0" ".sh")-legacy"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" alternates-legacy
+
+  git lfs track "*.dat"
+  contents="legacy contents"
+  contents_oid="$(calc_oid "$contents")"
+  printf "$contents" > a.dat
+  flat_contents="flat legacy contents"
+  flat_contents_oid="$(calc_oid "$flat_contents")"
+  printf "$flat_contents" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "add a.dat and b.dat"
+
+  # move the objects where the earliest versions of Git LFS kept them; they
+  # were never pushed
+  mkdir -p ".git/media/${contents_oid:0:2}/${contents_oid:2:2}"
+  mv ".git/lfs/objects/${contents_oid:0:2}/${contents_oid:2:2}/$contents_oid" \
+    ".git/media/${contents_oid:0:2}/${contents_oid:2:2}/$contents_oid"
+  mv ".git/lfs/objects/${flat_contents_oid:0:2}/${flat_contents_oid:2:2}/$flat_contents_oid" \
+    ".git/media/$flat_contents_oid"
+  refute_local_object "$contents_oid"
+  refute_local_object "$flat_contents_oid"
+
+  pointer="$(pointer "$contents_oid" "${#contents}")"
+  [ "$contents" = "$(echo "$pointer" | git lfs smudge a.dat)" ]
+  flat_pointer="$(pointer "$flat_contents_oid" "${#flat_contents}")"
+  [ "$flat_contents" = "$(echo "$flat_pointer" | git lfs smudge b.dat)" ]
+
+  # which are read from where they are, by default
+  refute_local_object "$contents_oid"
+  refute_local_object "$flat_contents_oid"
+
+  git lfs fetch-object "$flat_contents_oid" "${#flat_contents}" > fetch.log
+  [ "$(pwd)/.git/media/$flat_contents_oid" = "$(cat fetch.log)" ]
+
+  # or moved into the local object store, if lfs.legacyobjects.relocate is set
+  git config lfs.legacyobjects.relocate true
+  [ "$contents" = "$(echo "$pointer" | git lfs smudge a.dat)" ]
+  [ "$flat_contents" = "$(echo "$flat_pointer" | git lfs smudge b.dat)" ]
+
+  assert_local_object "$contents_oid" "${#contents}"
+  assert_local_object "$flat_contents_oid" "${#flat_contents}"
+  [ ! -e ".git/media/${contents_oid:0:2}" ]
+  [ ! -e ".git/media/$flat_contents_oid" ]
+)
+end_test
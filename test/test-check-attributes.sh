@@ -0,0 +1,101 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "check-attributes"
+(
+  set -e
+
+  reponame="check-attributes"
+  git init "$reponame"
+  cd "$reponame"
+
+  mkdir -p data big sub
+  touch a.dat data/c.dat big/d.psd e.psd sub/f.bin g.txt
+  printf '%s\n' \
+    "*.dat filter=lfs diff=lfs merge=lfs -text" \
+    "*.psd filter=lfs diff=lfs merge=lfs -text" \
+    "data/*.dat filter=lfs diff=lfs merge=lfs -text" \
+    "*.dat filter=lfs diff=lfs merge=lfs -text" \
+    "big/*.psd -filter" \
+    "*.zip filter=lfs diff=lfs merge=lfs -text" > .gitattributes
+  echo "*.bin filter=lfs diff=lfs merge=lfs -text" > sub/.gitattributes
+
+  git lfs check-attributes | tee ../check.log
+  grep '"\*.dat" (.gitattributes:1) repeats "\*.dat" (.gitattributes:4)' ../check.log
+  grep '"data/\*.dat" (.gitattributes:3) only matches files already matched by "\*.dat" (.gitattributes:4)' ../check.log
+  grep '"\*.psd" (.gitattributes:2) is overridden by "big/\*.psd" (.gitattributes:5) for 1 file(s), e.g. big/d.psd' ../check.log
+  grep '"\*.zip" (.gitattributes:6) matches no files' ../check.log
+  grep "Files: 4 of 8 tracked by Git LFS" ../check.log
+  grep 'big/d.psd: not tracked, by "big/\*.psd" (.gitattributes:5)' ../check.log
+  grep 'sub/f.bin: tracked by "sub/\*.bin" (sub/.gitattributes:1)' ../check.log
+  grep "g.txt: not matched" ../check.log
+
+  git lfs check-attributes --sample=1 | tee ../check.log
+  grep "and 7 more" ../check.log
+
+  git lfs check-attributes --json --sample=-1 > ../check.json
+  for key in rules redundant shadowed overlapping unused files; do
+    grep "\"$key\":" ../check.json
+  done
+  grep '"total_files":8' ../check.json
+  grep '"tracked_files":4' ../check.json
+  grep '"path":"sub/f.bin","tracked":true' ../check.json
+)
+end_test
+
+begin_test "check-attributes with overlapping patterns"
+(
+  set -e
+
+  reponame="check-attributes-overlapping"
+  git init "$reponame"
+  cd "$reponame"
+
+  mkdir -p assets
+  touch assets/a.bin assets/b.png c.bin
+  printf '%s\n' \
+    "*.bin filter=lfs diff=lfs merge=lfs -text" \
+    "assets/* filter=lfs diff=lfs merge=lfs -text" > .gitattributes
+
+  git lfs check-attributes | tee ../check.log
+  grep '"assets/\*" (.gitattributes:2) and "\*.bin" (.gitattributes:1) both track 1 file(s), e.g. assets/a.bin' ../check.log
+  [ "0" -eq "$(grep -c "Redundant patterns" ../check.log)" ]
+)
+end_test
+
+begin_test "check-attributes without rules"
+(
+  set -e
+
+  reponame="check-attributes-empty"
+  git init "$reponame"
+  cd "$reponame"
+
+  touch a.txt
+
+  git lfs check-attributes | tee ../check.log
+  grep "(none)" ../check.log
+  grep "Files: 0 of 1 tracked by Git LFS" ../check.log
+)
+end_test
+
+begin_test "check-attributes with attribute macros"
+(
+  set -e
+
+  reponame="check-attributes-macros"
+  git init "$reponame"
+  cd "$reponame"
+
+  touch a.dat b.txt
+  printf '%s\n' \
+    "[attr]lfs filter=lfs diff=lfs merge=lfs -text" \
+    "*.dat lfs" > .gitattributes
+
+  git lfs check-attributes | tee ../check.log
+  grep "Files: 1 of 3 tracked by Git LFS" ../check.log
+  grep 'a.dat: tracked by "\*.dat" (.gitattributes:2)' ../check.log
+  [ "0" -eq "$(grep -c "\[attr\]" ../check.log)" ]
+)
+end_test
@@ -0,0 +1,45 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "clean-tmp"
+(
+  set -e
+
+  reponame="clean-tmp"
+  git init "$reponame"
+  cd "$reponame"
+
+  # the PID of a process which has already exited
+  sh -c "exit 0" &
+  deadpid=$!
+  wait "$deadpid"
+
+  host="$(hostname | tr -c 'A-Za-z0-9.\n' '_')"
+  now="$(date +%s)"
+  old="$((now - 7200))"
+  mkdir -p .git/lfs/tmp
+  touch ".git/lfs/tmp/git-lfs-tmp-$host-$deadpid-$old-123456"
+  touch ".git/lfs/tmp/git-lfs-tmp-$host-$$-$old-654321"
+  # too recent to be orphaned, even though its process has exited
+  touch ".git/lfs/tmp/git-lfs-tmp-$host-$deadpid-$now-234567"
+  # from another host, whose processes cannot be checked
+  touch ".git/lfs/tmp/git-lfs-tmp-other.host-$deadpid-$old-345678"
+  touch ".git/lfs/tmp/not-from-git-lfs"
+
+  git lfs clean-tmp --dry-run | tee clean-tmp.log
+  grep "Would remove git-lfs-tmp-$host-$deadpid-$old-123456" clean-tmp.log
+  grep "would remove 1 temporary file(s)" clean-tmp.log
+  [ -f ".git/lfs/tmp/git-lfs-tmp-$host-$deadpid-$old-123456" ]
+
+  git lfs clean-tmp | tee clean-tmp.log
+  grep "Removed git-lfs-tmp-$host-$deadpid-$old-123456" clean-tmp.log
+  grep "removed 1 temporary file(s)" clean-tmp.log
+
+  [ ! -e ".git/lfs/tmp/git-lfs-tmp-$host-$deadpid-$old-123456" ]
+  [ -f ".git/lfs/tmp/git-lfs-tmp-$host-$$-$old-654321" ]
+  [ -f ".git/lfs/tmp/git-lfs-tmp-$host-$deadpid-$now-234567" ]
+  [ -f ".git/lfs/tmp/git-lfs-tmp-other.host-$deadpid-$old-345678" ]
+  [ -f ".git/lfs/tmp/not-from-git-lfs" ]
+)
+end_test
@@ -18,6 +18,25 @@ begin_test "clean simple file"
 )
 end_test
 
+begin_test "clean simple file without trailing newline"
+(
+  set -e
+  clean_setup "no-trailing-newline"
+
+  git config lfs.pointer.trailingnewline false
+
+  echo "whatever" | git lfs clean > clean.log
+  printf "%s" "$(pointer cd293be6cea034bd45a0352775a219ef5dc7825ce55d1f7dae9762d80ce64411 9)" > expected.log
+  cmp expected.log clean.log
+
+  git config --unset lfs.pointer.trailingnewline
+
+  echo "whatever" | git lfs clean > clean.log
+  pointer cd293be6cea034bd45a0352775a219ef5dc7825ce55d1f7dae9762d80ce64411 9 > expected.log
+  cmp expected.log clean.log
+)
+end_test
+
 begin_test "clean a pointer"
 (
   set -e
@@ -28,6 +47,104 @@ begin_test "clean a pointer"
 )
 end_test
 
+begin_test "clean a pointer with extensions"
+(
+  set -e
+  clean_setup "pointer-extensions"
+
+  # the extension is never run, so it doesn't matter that it would fail
+  git config lfs.extension.missing.clean "missing-extension-command %f"
+  git config lfs.extension.missing.smudge "missing-extension-command %f"
+  git config lfs.extension.missing.priority 0
+
+  pointer cd293be6cea034bd45a0352775a219ef5dc7825ce55d1f7dae9762d80ce64411 9 | git lfs clean | tee clean.log
+  [ "$(pointer cd293be6cea034bd45a0352775a219ef5dc7825ce55d1f7dae9762d80ce64411 9)" = "$(cat clean.log)" ]
+)
+end_test
+
+begin_test "clean an empty file"
+(
+  set -e
+  clean_setup "empty"
+
+  # an empty file is the pointer for an empty file, even with extensions
+  git config lfs.extension.missing.clean "missing-extension-command %f"
+  git config lfs.extension.missing.smudge "missing-extension-command %f"
+  git config lfs.extension.missing.priority 0
+
+  printf "" | git lfs clean > clean.log
+  [ ! -s clean.log ]
+  [ ! -d .git/lfs/objects ] || [ -z "$(find .git/lfs/objects -type f)" ]
+)
+end_test
+
+begin_test "clean a pointer with lfs.clean.passpointers"
+(
+  set -e
+  clean_setup "pass-pointers"
+
+  oid="cd293be6cea034bd45a0352775a219ef5dc7825ce55d1f7dae9762d80ce64411"
+  old_pointer="$(printf "version https://hawser.github.com/spec/v1\noid sha256:$oid\nsize 9\n")"
+
+  # by default, a pointer is passed through exactly as it was read
+  printf "%s\n" "$old_pointer" | git lfs clean a.dat > clean.log
+  [ "$old_pointer" = "$(cat clean.log)" ]
+
+  # otherwise, it is encoded again
+  git config lfs.clean.passpointers false
+  printf "%s\n" "$old_pointer" | git lfs clean a.dat > clean.log
+  [ "$(pointer $oid 9)" = "$(cat clean.log)" ]
+
+  # and its local object is checked
+  echo "whatever" | git lfs clean > /dev/null
+  assert_local_object "$oid" 9
+  printf "whatevex\n" > ".git/lfs/objects/cd/29/$oid"
+
+  set +e
+  pointer $oid 9 | git lfs clean a.dat > clean.log 2> clean.err
+  res=${PIPESTATUS[1]}
+  set -e
+
+  [ "0" != "$res" ]
+  [ ! -s clean.log ]
+  grep '"a.dat" is a pointer to '"$oid"', but the local copy of that object is corrupt' clean.err
+
+  git config lfs.clean.passpointers true
+  pointer $oid 9 | git lfs clean a.dat > clean.log
+  [ "$(pointer $oid 9)" = "$(cat clean.log)" ]
+)
+end_test
+
+begin_test "clean a pointer with lfs.clean.rejectpointers"
+(
+  set -e
+  clean_setup "reject-pointers"
+
+  git config lfs.clean.rejectpointers true
+
+  set +e
+  pointer cd293be6cea034bd45a0352775a219ef5dc7825ce55d1f7dae9762d80ce64411 9 | git lfs clean a.dat > clean.log 2> clean.err
+  res=${PIPESTATUS[1]}
+  set -e
+
+  [ "0" != "$res" ]
+  [ ! -s clean.log ]
+  grep '"a.dat" is already a Git LFS pointer' clean.err
+
+  # files which are not pointers are still cleaned
+  echo "whatever" | git lfs clean | tee clean.log
+  [ "$(pointer cd293be6cea034bd45a0352775a219ef5dc7825ce55d1f7dae9762d80ce64411 9)" = "$(cat clean.log)" ]
+
+  echo "*.dat filter=lfs diff=lfs merge=lfs -text" > .gitattributes
+  pointer cd293be6cea034bd45a0352775a219ef5dc7825ce55d1f7dae9762d80ce64411 9 > b.dat
+  git add .gitattributes
+  git add b.dat > add.log 2>&1 && exit 1
+  cat add.log
+  grep '"b.dat" is already a Git LFS pointer' add.log
+  [ -z "$(git ls-files b.dat)" ]
+)
+end_test
+
 begin_test "clean pseudo pointer"
 (
   set -e
@@ -56,3 +173,81 @@ This is my test pointer.  There are many like it, but this one is mine.\n" | git
   [ "$(pointer c2f909f6961bf85a92e2942ef3ed80c938a3d0ebaee6e72940692581052333be 586)" = "$(cat clean.log)" ]
 )
 end_test
+
+begin_test "clean with lfs.cleanrule"
+(
+  set -e
+  clean_setup "clean-rules"
+
+  git lfs track "*.png"
+  git config -f .lfsconfig lfs.cleanrule.*.png.minsize 16
+  git config -f .lfsconfig lfs.cleanrule.*.png.magic 89504e47
+
+  printf "\x89PNG large enough image" > large.png
+  printf "\x89PNG tiny" > small.png
+  printf "not an image, but large" > fake.png
+  git add .gitattributes .lfsconfig large.png small.png fake.png 2>&1 | tee add.log
+  git commit -m "add images"
+
+  large_oid="$(calc_oid "$(cat large.png)")"
+  [ "$(pointer $large_oid 23)" = "$(git cat-file -p :large.png)" ]
+  assert_local_object "$large_oid" 23
+
+  # files which do not meet the rule are stored in Git as they are
+  [ "$(cat small.png)" = "$(git cat-file -p :small.png)" ]
+  [ "$(cat fake.png)" = "$(git cat-file -p :fake.png)" ]
+
+  # and are not warned about when they are checked out
+  rm large.png small.png fake.png
+  git checkout -- large.png small.png fake.png 2>&1 | tee checkout.log
+  [ "0" -eq "$(grep -c "should have been pointers" checkout.log)" ]
+  [ "$(printf "\x89PNG large enough image")" = "$(cat large.png)" ]
+  [ "not an image, but large" = "$(cat fake.png)" ]
+
+  # the size is that of the contents given, rather than that of the file in
+  # the working tree, which may have changed since
+  [ "$(printf "\x89PNG tiny")" = "$(printf "\x89PNG tiny" | git lfs clean large.png)" ]
+
+  # a large minsize is not held in memory for each file
+  git config -f .lfsconfig lfs.cleanrule.*.png.minsize 1tb
+  [ "$(printf "\x89PNG large enough image")" = "$(printf "\x89PNG large enough image" | git lfs clean large.png)" ]
+  [ "$(printf "\x89PNG large enough image")" = "$(printf "\x89PNG large enough image" | git lfs smudge large.png)" ]
+  git config -f .lfsconfig lfs.cleanrule.*.png.minsize 16
+
+  git config -f .lfsconfig lfs.cleanrule.*.png.magic "PNG"
+  echo "changed" >> fake.png
+  git add fake.png > add.log 2>&1 && exit 1
+  grep 'invalid lfs.cleanrule.\*.png.magic "PNG"' add.log
+)
+end_test
+
+begin_test "clean with lfs.pointer.version"
+(
+  set -e
+  clean_setup "pointer-version"
+
+  v2="https://git-lfs.github.com/spec/v2"
+  oid="$(calc_oid "whatever")"
+
+  # by default, the version in the specification is written
+  printf "whatever" | git lfs clean | tee clean.log
+  [ "$(pointer $oid 8)" = "$(cat clean.log)" ]
+
+  # another version needs opting in to
+  git config lfs.pointer.version "$v2"
+  printf "whatever" | git lfs clean > clean.log 2>&1 && exit 1
+  grep "lfs.pointer.version is set to \"$v2\", but lfs.pointer.allowexperimentalversions is not" clean.log
+
+  git config lfs.pointer.allowexperimentalversions true
+  printf "whatever" | git lfs clean | tee clean.log
+  [ "$(pointer $oid 8 "$v2")" = "$(cat clean.log)" ]
+
+  # and pointers of that version can be read back
+  [ "whatever" = "$(cat clean.log | git lfs smudge)" ]
+
+  # as can those of other versions accepted
+  v3="https://git-lfs.github.com/spec/v3"
+  git config lfs.pointer.acceptedversions "$v3"
+  [ "whatever" = "$(pointer $oid 8 "$v3" | git lfs smudge)" ]
+)
+end_test
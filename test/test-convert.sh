@@ -0,0 +1,89 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "convert"
+(
+  set -e
+
+  reponame="convert"
+  git init "$reponame"
+  cd "$reponame"
+
+  contents="large file contents"
+  contents_oid="$(calc_oid "$contents")"
+
+  printf "$contents" > a.dat
+  printf "small" > a.txt
+  git add a.dat a.txt
+  git commit -m "initial commit"
+  before="$(git rev-parse HEAD)"
+
+  git lfs convert "*.dat" | tee convert.log
+  grep "Tracking \"\*.dat\"" convert.log
+  grep "Converted \"a.dat\"" convert.log
+
+  [ "$before" = "$(git rev-parse HEAD)" ]
+  [ "$contents" = "$(git cat-file -p HEAD:a.dat)" ]
+
+  git cat-file -p :a.dat | grep "$contents_oid"
+  [ "small" = "$(git cat-file -p :a.txt)" ]
+  git cat-file -p :.gitattributes | grep "\*.dat filter=lfs"
+  assert_local_object "$contents_oid" "${#contents}"
+
+  git diff --cached --name-only | tee staged.log
+  grep "a.dat" staged.log
+  grep ".gitattributes" staged.log
+  [ "2" -eq "$(wc -l < staged.log)" ]
+)
+end_test
+
+begin_test "convert skips existing pointers"
+(
+  set -e
+
+  reponame="convert-existing-pointers"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs track "*.dat"
+  printf "contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "initial commit"
+
+  git lfs convert "*.dat" | tee convert.log
+  grep "Skipping \"a.dat\", already a Git LFS pointer" convert.log
+  [ "0" -eq "$(grep -c "Converted" convert.log)" ]
+
+  [ -z "$(git diff --cached --name-only)" ]
+)
+end_test
+
+begin_test "convert leaves unstaged changes alone"
+(
+  set -e
+
+  reponame="convert-unstaged-changes"
+  git init "$reponame"
+  cd "$reponame"
+
+  contents="staged contents"
+  contents_oid="$(calc_oid "$contents")"
+
+  printf "$contents" > a.dat
+  git add a.dat
+  git commit -m "initial commit"
+
+  printf "unstaged contents" > a.dat
+
+  git lfs convert "*.dat" | tee convert.log
+  grep "Converted \"a.dat\"" convert.log
+
+  git cat-file -p :a.dat | grep "$contents_oid"
+  assert_local_object "$contents_oid" "${#contents}"
+  [ "unstaged contents" = "$(cat a.dat)" ]
+
+  git diff --name-only | tee unstaged.log
+  grep "a.dat" unstaged.log
+)
+end_test
@@ -292,3 +292,42 @@ begin_test "credentials from remote.origin.url"
   grep "(1 of 1 files)" fetch.log
 )
 end_test
+
+begin_test "credentials from lfs.credentialsfile"
+(
+  set -e
+
+  # only the credentials file can give the right password
+  mv "$NETRCFILE" "$NETRCFILE.bak" || true
+  printf "machine localhost\nlogin netrcuser\npassword badpass\n" > "$NETRCFILE"
+
+  export SSH_ASKPASS=
+
+  reponame="netrctest"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" credsfile
+
+  git remote add "netrc" "$(echo $GITSERVER | sed s/127.0.0.1/localhost/)/netrctest"
+
+  credsfile="$TRASHDIR/credentials.json"
+  printf '{ "localhost": { "username": "netrcuser", "password": "netrcpass" } }' > "$credsfile"
+  chmod 600 "$credsfile"
+  git config lfs.credentialsfile "$credsfile"
+
+  git lfs track "*.dat"
+  echo "push credsfile" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  GIT_TRACE=1 git lfs push netrc master 2>&1 | tee push.log
+  grep "(1 of 1 files)" push.log
+  [ "0" -eq "$(grep -c "netrcpass" push.log)" ]
+  [ "0" -eq "$(grep -c "can be read by other users" push.log)" ]
+
+  chmod 644 "$credsfile"
+  GIT_TRACE=1 git lfs push netrc master 2>&1 | tee push.log
+  grep "can be read by other users" push.log
+
+  mv "$NETRCFILE.bak" "$NETRCFILE" || rm "$NETRCFILE"
+)
+end_test
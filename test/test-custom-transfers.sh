@@ -38,6 +38,41 @@ begin_test "custom-transfer-wrong-path"
 )
 end_test
 
+begin_test "custom-transfer-disabled"
+(
+  set -e
+
+  # this repo name is the indicator to the server to support custom transfer
+  reponame="test-custom-transfer-disabled"
+  setup_remote_repo "$reponame"
+
+  clone_repo "$reponame" $reponame
+
+  # the adapter would fail, if it were ever offered to the server
+  git config lfs.customtransfer.testcustom.path path-to-nothing
+  git config lfs.transfer.disableadapters testcustom
+
+  git lfs track "*.dat"
+  contents="disabled custom transfer"
+  contents_oid=$(calc_oid "$contents")
+
+  printf "$contents" > a.dat
+  git add a.dat .gitattributes
+  git commit -m "add a.dat"
+
+  GIT_TRACE=1 GIT_TRANSFER_TRACE=1 git push origin master 2>&1 | tee pushcustom.log
+  [ ${PIPESTATUS[0]} = "0" ]
+  [ "0" -eq "$(grep -c "testcustom" pushcustom.log)" ]
+  assert_server_object "$reponame" "$contents_oid"
+
+  git config --unset lfs.transfer.disableadapters
+  rm -rf .git/lfs/objects
+  GIT_LFS_DISABLE_ADAPTERS=testcustom git lfs fetch 2>&1 | tee fetchcustom.log
+  [ ${PIPESTATUS[0]} = "0" ]
+  assert_local_object "$contents_oid" "${#contents}"
+)
+end_test
+
 begin_test "custom-transfer-upload-download"
 (
   set -e
@@ -189,3 +224,49 @@ begin_test "custom-transfer-standalone"
   [ "$(echo "$objectlist" | wc -l)" -eq 12 ]
 )
 end_test
+
+begin_test "custom-transfer-standalone-url"
+(
+  set -e
+
+  reponame="test-custom-transfer-standalone-url"
+  setup_remote_repo "$reponame"
+
+  clone_repo_url "$REMOTEDIR/$reponame.git" $reponame
+
+  # only the endpoint "grpc://store.example.com/repo" uses the agent, which is
+  # never asked to speak a scheme that Git LFS knows about
+  git config remote.origin.lfsurl grpc://store.example.com/repo
+  git config lfs.grpc://store.example.com/repo.locksverify false
+  git config lfs.customtransfer.testcustom.path lfstest-standalonecustomadapter
+  git config lfs.grpc://store.example.com.standalonetransferagent testcustom
+  export TEST_STANDALONE_BACKUP_PATH="$(pwd)/test-custom-transfer-standalone-url-backup"
+  mkdir -p $TEST_STANDALONE_BACKUP_PATH
+  rm -rf $TEST_STANDALONE_BACKUP_PATH/*
+
+  git lfs track "*.dat"
+  contents="contents"
+  printf "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  GIT_TRACE=1 GIT_TRANSFER_TRACE=1 git push origin master 2>&1 | tee pushcustom.log
+  [ ${PIPESTATUS[0]} = "0" ]
+  grep "xfer: started custom adapter process" pushcustom.log
+  grep "1 of 1 files" pushcustom.log
+
+  rm -rf .git/lfs/objects
+  GIT_TRACE=1 GIT_TRANSFER_TRACE=1 git lfs fetch 2>&1 | tee fetchcustom.log
+  [ ${PIPESTATUS[0]} = "0" ]
+  grep "xfer: started custom adapter process" fetchcustom.log
+  grep "1 of 1 files" fetchcustom.log
+  assert_local_object "$(calc_oid "$contents")" 8
+
+  # an empty value for the URL turns the agent off again
+  git config lfs.grpc://store.example.com/repo.standalonetransferagent ""
+  rm -rf .git/lfs/objects
+  git lfs fetch > fetchcustom.log 2>&1 && exit 1
+  cat fetchcustom.log
+  refute_local_object "$(calc_oid "$contents")"
+)
+end_test
@@ -0,0 +1,118 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "doctor"
+(
+  set -e
+
+  reponame="doctor"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs install
+  git lfs track "*.dat"
+  printf "contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  git lfs doctor | tee doctor.log
+  grep "Git LFS doctor OK" doctor.log
+)
+end_test
+
+begin_test "doctor --fix with misconfigured filters and hooks"
+(
+  set -e
+
+  reponame="doctor-fix"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs install
+  git lfs track "*.dat"
+  git add .gitattributes
+  git commit -m "track *.dat"
+
+  git config --global --unset filter.lfs.process
+  git config --global filter.lfs.clean "git-lfs clean %f"
+  rm .git/hooks/pre-push
+
+  git lfs doctor > doctor.log 2>&1 && exit 1
+  cat doctor.log
+  grep 'filter.lfs.process is not set; expected "git-lfs filter-process"' doctor.log
+  grep 'filter.lfs.clean is "git-lfs clean %f", as an earlier version of Git LFS set it; expected "git-lfs clean -- %f"' doctor.log
+  grep "The pre-push hook is not installed." doctor.log
+  grep "Git LFS doctor: 2 problem(s) found" doctor.log
+
+  git lfs doctor --fix | tee doctor.log
+  [ "2" -eq "$(grep -c "Fixed." doctor.log)" ]
+  grep "Git LFS doctor OK" doctor.log
+
+  [ "git-lfs filter-process" = "$(git config --global filter.lfs.process)" ]
+  [ "git-lfs clean -- %f" = "$(git config --global filter.lfs.clean)" ]
+  [ -f .git/hooks/pre-push ]
+
+  git lfs doctor
+)
+end_test
+
+begin_test "doctor with untracked pointers"
+(
+  set -e
+
+  reponame="doctor-untracked"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs install
+  git lfs track "*.dat"
+  printf "contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  git rm .gitattributes
+  git commit -m "remove .gitattributes"
+
+  git lfs doctor --fix > doctor.log 2>&1 && exit 1
+  cat doctor.log
+  grep "No pattern in the gitattributes files of the working tree sets filter=lfs." doctor.log
+  grep "a.dat is a Git LFS pointer in HEAD, but no pattern tracks it, so it is checked out as a pointer." doctor.log
+  grep 'Run `git lfs track "a.dat"` to track it.' doctor.log
+  grep "Git LFS doctor: 2 problem(s) found" doctor.log
+)
+end_test
+
+begin_test "doctor with executable pointers"
+(
+  set -e
+
+  reponame="doctor-executable"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs install
+  git lfs track "*.bin"
+  contents="#!/bin/sh"
+  oid="$(calc_oid "$contents")"
+  printf "$contents" > tool.bin
+  chmod +x tool.bin
+  git add .gitattributes tool.bin
+  git commit -m "add tool.bin"
+
+  git lfs doctor | tee doctor.log
+  grep "Git LFS doctor OK" doctor.log
+
+  # as if tool.bin had been checked out without Git LFS
+  pointer "$oid" 9 > tool.bin
+
+  git lfs doctor > doctor.log 2>&1 && exit 1
+  cat doctor.log
+  grep "tool.bin is checked out as a Git LFS pointer, but is executable (mode 100755 in the index, 0755 in the working tree)." doctor.log
+  grep "Git LFS doctor: 1 problem(s) found" doctor.log
+
+  git lfs checkout tool.bin
+  [ "$contents" = "$(cat tool.bin)" ]
+  git lfs doctor
+)
+end_test
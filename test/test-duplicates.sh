@@ -0,0 +1,54 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "duplicates"
+(
+  set -e
+
+  reponame="duplicates"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs track "*.dat"
+  printf "contents" > a.dat
+  printf "contentz" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "add files"
+
+  git lfs duplicates | tee duplicates.log
+  grep "No duplicate objects found in 2 object(s)" duplicates.log
+
+  # store the contents of a.dat again under another oid, as a pointer with
+  # the wrong oid would have
+  aoid="$(calc_oid "contents")"
+  other="0000000000000000000000000000000000000000000000000000000000000001"
+  mkdir -p ".git/lfs/objects/00/00"
+  cp ".git/lfs/objects/${aoid:0:2}/${aoid:2:2}/$aoid" ".git/lfs/objects/00/00/$other"
+
+  git lfs duplicates | tee duplicates.log
+  grep "8 B duplicated object(s) with the same sha256 $aoid:" duplicates.log
+  grep "  $aoid" duplicates.log
+  grep "  $other" duplicates.log
+  grep "Duplicate objects waste 8 B" duplicates.log
+
+  git lfs duplicates --hash=sha1 --json | tee duplicates.json
+  grep "\"hash\":\"sha1\"" duplicates.json
+  grep "\"wasted\":8" duplicates.json
+
+  # nothing was changed without --link
+  [ "1" -eq "$(stat -c %h ".git/lfs/objects/00/00/$other" 2>/dev/null || stat -f %l ".git/lfs/objects/00/00/$other")" ]
+
+  git lfs duplicates --link | tee duplicates.log
+  grep "Linked duplicate objects, saving 8 B" duplicates.log
+  [ "2" -eq "$(stat -c %h ".git/lfs/objects/00/00/$other" 2>/dev/null || stat -f %l ".git/lfs/objects/00/00/$other")" ]
+  [ "contents" = "$(cat ".git/lfs/objects/00/00/$other")" ]
+
+  git lfs duplicates | tee duplicates.log
+  grep "8 B linked object(s)" duplicates.log
+  grep "Duplicate objects waste" duplicates.log && exit 1
+
+  git lfs duplicates --hash=md5 > duplicates.log 2>&1 && exit 1
+  grep "Invalid --hash \"md5\"" duplicates.log
+)
+end_test
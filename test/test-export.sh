@@ -0,0 +1,145 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "export"
+(
+  set -e
+
+  reponame="export"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+
+  contents="a"
+  contents_oid="$(calc_oid "$contents")"
+  nested="nested"
+  nested_oid="$(calc_oid "$nested")"
+
+  mkdir -p dir
+  printf "$contents" > a.dat
+  printf "$nested" > dir/b.dat
+  printf "not in lfs" > c.txt
+  git add .gitattributes a.dat dir/b.dat c.txt
+  git commit -m "initial commit"
+  git push origin master
+
+  printf "changed" > a.dat
+  git add a.dat
+  git commit -m "change a.dat"
+
+  rm -rf .git/lfs/objects
+  refute_local_object "$contents_oid"
+
+  outdir="$TRASHDIR/$reponame-export"
+  git lfs export HEAD~1 "$outdir" 2>&1 | tee export.log
+  grep "Git LFS export: 2 file(s) exported to $outdir" export.log
+
+  [ "$contents" = "$(cat "$outdir/a.dat")" ]
+  [ "$nested" = "$(cat "$outdir/dir/b.dat")" ]
+  [ ! -e "$outdir/c.txt" ]
+  [ "2" -eq "$(find "$outdir" -type f | wc -l)" ]
+
+  assert_local_object "$contents_oid" "${#contents}"
+  assert_local_object "$nested_oid" "${#nested}"
+
+  # the working tree and index are left alone
+  [ "changed" = "$(cat a.dat)" ]
+  [ -z "$(git status --porcelain --untracked-files=no)" ]
+)
+end_test
+
+begin_test "export with include"
+(
+  set -e
+
+  reponame="export-include"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+
+  mkdir -p dir
+  printf "a" > a.dat
+  printf "b" > dir/b.dat
+  git add .gitattributes a.dat dir/b.dat
+  git commit -m "initial commit"
+  git push origin master
+
+  rm -rf .git/lfs/objects
+
+  outdir="$TRASHDIR/$reponame-export"
+  git lfs export --include="dir/*" master "$outdir" 2>&1 | tee export.log
+  grep "Git LFS export: 1 file(s) exported to $outdir" export.log
+
+  [ "b" = "$(cat "$outdir/dir/b.dat")" ]
+  [ ! -e "$outdir/a.dat" ]
+  refute_local_object "$(calc_oid "a")"
+)
+end_test
+
+begin_test "export with invalid ref"
+(
+  set -e
+
+  reponame="export-invalid-ref"
+  git init "$reponame"
+  cd "$reponame"
+
+  git commit --allow-empty -m "initial commit"
+
+  set +e
+  git lfs export not-a-ref "$TRASHDIR/$reponame-export" 2>&1 | tee export.log
+  res=${PIPESTATUS[0]}
+  set -e
+
+  [ "$res" != "0" ]
+  grep "Could not resolve ref \"not-a-ref\"" export.log
+)
+end_test
+
+begin_test "export --store"
+(
+  set -e
+
+  reponame="export-store"
+  git init "$reponame"
+  cd "$reponame"
+
+  git config lfs.objectorigins true
+  git lfs track "*.dat" "*.sh"
+
+  mkdir -p dir
+  printf "a" > a.dat
+  printf "#!/bin/sh" > dir/run.sh
+  chmod +x dir/run.sh
+  git add .gitattributes a.dat dir/run.sh
+  git commit -m "initial commit"
+
+  printf "changed" > a.dat
+  git add a.dat
+  git commit -m "change a.dat"
+
+  # An object which was stored before origins were recorded.
+  git config lfs.objectorigins false
+  printf "untracked origin" > c.dat
+  git add c.dat
+
+  a_oid="$(calc_oid "a")"
+  changed_oid="$(calc_oid "changed")"
+
+  outdir="$TRASHDIR/$reponame-export"
+  git lfs export --store "$outdir" 2>&1 | tee export.log
+  grep "Git LFS export: 1 object(s) without a recorded origin were skipped" export.log
+  grep "Git LFS export: 3 file(s) exported to $outdir" export.log
+
+  # Both versions of a.dat are kept, named after their OIDs.
+  [ "a" = "$(cat "$outdir/a.${a_oid:0:12}.dat")" ]
+  [ "changed" = "$(cat "$outdir/a.${changed_oid:0:12}.dat")" ]
+  [ "#!/bin/sh" = "$(cat "$outdir/dir/run.sh")" ]
+  [ -x "$outdir/dir/run.sh" ]
+  [ ! -x "$outdir/a.${a_oid:0:12}.dat" ]
+  [ "3" -eq "$(find "$outdir" -type f | wc -l)" ]
+)
+end_test
@@ -223,6 +223,43 @@ begin_test "fetch with include filter overriding exclude filter"
 )
 end_test
 
+begin_test "fetch with negated include filters"
+(
+  set -e
+
+  reponame="fetch-negated-include"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  mkdir -p assets/tmp other
+  printf "asset" > assets/a.dat
+  printf "scratch" > assets/tmp/b.dat
+  printf "keep" > assets/tmp/keep.dat
+  printf "other" > other/c.dat
+  git add .gitattributes assets other
+  git commit -m "add files"
+  git push origin master
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 git clone "$GITSERVER/$reponame" "$reponame-clone"
+  cd "$reponame-clone"
+
+  git lfs fetch --include="assets,!assets/tmp,assets/tmp/keep.dat"
+  assert_local_object "$(calc_oid "asset")" 5
+  refute_local_object "$(calc_oid "scratch")"
+  assert_local_object "$(calc_oid "keep")" 4
+  refute_local_object "$(calc_oid "other")"
+
+  # lfs.fetchexclude still applies after every include rule
+  rm -rf .git/lfs/objects
+  git -c lfs.fetchinclude="assets,!assets/tmp,assets/tmp/keep.dat" \
+    -c lfs.fetchexclude="keep.dat" lfs fetch
+  assert_local_object "$(calc_oid "asset")" 5
+  refute_local_object "$(calc_oid "keep")"
+)
+end_test
+
 begin_test "fetch with missing object"
 (
   set -e
@@ -534,3 +571,347 @@ begin_test "fetch with invalid remote"
   grep "Invalid remote name" fetch.log
 )
 end_test
+
+begin_test "fetch-object"
+(
+  set -e
+  cd clone
+  rm -rf .git/lfs/objects
+
+  git lfs fetch-object "$contents_oid" 1 | tee fetch-object.log
+  assert_local_object "$contents_oid" 1
+  [ "$(git lfs env | grep LocalMediaDir | cut -d= -f2)/${contents_oid:0:2}/${contents_oid:2:2}/$contents_oid" = "$(cat fetch-object.log)" ]
+  refute_local_object "$b_oid"
+
+  # fetching an object twice is a no-op
+  git lfs fetch-object "$contents_oid" 1
+)
+end_test
+
+begin_test "fetch-object with invalid arguments"
+(
+  set -e
+  cd clone
+
+  git lfs fetch-object "not-an-oid" 1 2>&1 | tee fetch-object.log
+  grep "Invalid object ID" fetch-object.log
+
+  git lfs fetch-object "$b_oid" "not-a-size" 2>&1 | tee fetch-object.log
+  grep "Invalid object size" fetch-object.log
+)
+end_test
+
+begin_test "fetch-object --stdin"
+(
+  set -e
+  cd clone
+  rm -rf .git/lfs/objects
+
+  mediadir="$(git lfs env | grep LocalMediaDir | cut -d= -f2)"
+  local_path="$mediadir/${contents_oid:0:2}/${contents_oid:2:2}/$contents_oid"
+
+  # b.dat was deleted from the server by "fetch with missing object"
+  printf "%s 1\n\nnot-an-oid 1\n%s 1\n%s 1\n" \
+    "$contents_oid" "$b_oid" "$contents_oid" |
+    git lfs fetch-object --stdin > fetch-object.log
+  cat fetch-object.log
+
+  [ "4" -eq "$(wc -l < fetch-object.log)" ]
+  [ "ok $contents_oid $local_path" = "$(sed -n 1p fetch-object.log)" ]
+  sed -n 2p fetch-object.log | grep "^error not-an-oid Invalid object ID"
+  sed -n 3p fetch-object.log | grep "^error $b_oid .*does not exist"
+  [ "ok $contents_oid $local_path" = "$(sed -n 4p fetch-object.log)" ]
+
+  assert_local_object "$contents_oid" 1
+  refute_local_object "$b_oid"
+)
+end_test
+
+begin_test "fetch --stdin"
+(
+  set -e
+  cd clone
+  rm -rf .git/lfs/objects
+
+  printf "%s 1\n\nnot-an-oid 1\n%s\n" "$contents_oid" "$contents_oid" |
+    git lfs fetch --stdin 2>&1 | tee fetch.log
+  grep "Fetching 1 objects from stdin" fetch.log
+  grep "Line 3: Invalid object ID: \"not-an-oid\"" fetch.log
+  grep "Line 4: expected \"<oid> <size>\"" fetch.log
+  grep "error: failed to fetch some objects" fetch.log
+  assert_local_object "$contents_oid" 1
+
+  # b.dat was deleted from the server by "fetch with missing object", which
+  # does not stop the other objects from being fetched
+  rm -rf .git/lfs/objects
+  printf "%s 1\n%s 1\n" "$b_oid" "$contents_oid" |
+    git lfs fetch --stdin > fetch.log 2>&1 && exit 1
+  grep "$b_oid" fetch.log
+  assert_local_object "$contents_oid" 1
+  refute_local_object "$b_oid"
+
+  echo "$contents_oid 1" | git lfs fetch --stdin origin master > fetch.log 2>&1 && exit 1
+  grep "Cannot combine --stdin with --all, --recent or ref arguments" fetch.log
+)
+end_test
+
+begin_test "fetch with pointer size mismatch"
+(
+  set -e
+
+  reponame="fetch-size-mismatch"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+
+  contents="size mismatch"
+  contents_oid="$(calc_oid "$contents")"
+
+  printf "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  git push origin master
+
+  # A pointer to the same object, with the wrong size, as written by some
+  # older clients.
+  git rm a.dat
+  pointer "$contents_oid" 100 > b.dat
+  git add b.dat
+  git commit -m "add b.dat with the wrong size"
+
+  rm -rf .git/lfs/objects
+
+  set +e
+  git -c lfs.transfer.maxretries=1 lfs fetch 2>&1 | tee fetch.log
+  res="${PIPESTATUS[0]}"
+  set -e
+
+  [ "$res" != "0" ]
+  grep "Expected 100 bytes for $contents_oid, got ${#contents}" fetch.log
+  refute_local_object "$contents_oid"
+
+  GIT_TRACE=1 git -c lfs.transfer.trustserversize=true lfs fetch 2>&1 | tee fetch.log
+  grep "xfer: object $contents_oid is ${#contents} bytes, not 100" fetch.log
+  assert_local_object "$contents_oid" "${#contents}"
+)
+end_test
+
+begin_test "fetch with lfs.minfreespace"
+(
+  set -e
+  cd clone
+  rm -rf .git/lfs/objects
+
+  git -c lfs.minfreespace=100PB lfs fetch > fetch.log 2>&1 && exit 1
+  grep "Not enough free space in .*: 100 PB required (1 B of Git LFS files, plus 100 PB from lfs.minfreespace), but only .* available" fetch.log
+  refute_local_object "$contents_oid"
+
+  git -c lfs.minfreespace=lots lfs fetch > fetch.log 2>&1 && exit 1
+  grep "invalid lfs.minfreespace \"lots\"" fetch.log
+
+  git -c lfs.minfreespace=1KB lfs fetch 2>&1 | tee fetch.log
+  grep "(1 of 1 files)" fetch.log
+  assert_local_object "$contents_oid" 1
+
+  # Nothing is needed once the objects are local.
+  git -c lfs.minfreespace=100PB lfs fetch
+)
+end_test
+
+begin_test "fetch with lfs.mirrorstore"
+(
+  set -e
+  cd clone
+  rm -rf .git/lfs/objects
+
+  mirror="$TRASHDIR/mirror"
+  git -c lfs.mirrorstore="$mirror" lfs fetch 2>&1 | tee fetch.log
+  grep "(1 of 1 files)" fetch.log
+  assert_local_object "$contents_oid" 1
+  [ "$contents" = "$(cat "$mirror/${contents_oid:0:2}/${contents_oid:2:2}/$contents_oid")" ]
+
+  # A leading "~/" is expanded to the home directory.
+  rm -rf .git/lfs/objects
+  HOME="$TRASHDIR/home" git -c lfs.mirrorstore="~/mirror" lfs fetch
+  assert_local_object "$contents_oid" 1
+  [ -f "$TRASHDIR/home/mirror/${contents_oid:0:2}/${contents_oid:2:2}/$contents_oid" ]
+
+  # A mirror store which cannot be written to does not fail the download.
+  rm -rf .git/lfs/objects
+  printf "not a directory" > "$TRASHDIR/not-a-mirror"
+  GIT_TRACE=1 git -c lfs.mirrorstore="$TRASHDIR/not-a-mirror" lfs fetch 2>&1 | tee fetch.log
+  grep "tq: could not write $contents_oid to the mirror store" fetch.log
+  assert_local_object "$contents_oid" 1
+)
+end_test
+
+begin_test "fetch with lfs.auditlog"
+(
+  set -e
+  cd clone
+  rm -rf .git/lfs/objects
+
+  audit="$TRASHDIR/audit/download.log"
+  git -c lfs.auditlog="$audit" lfs fetch
+  assert_local_object "$contents_oid" 1
+
+  cat "$audit"
+  [ "1" -eq "$(wc -l < "$audit")" ]
+  grep "\"oid\":\"$contents_oid\",\"size\":1,\"remote\":\"origin\"" "$audit"
+  grep "\"outcome\":\"success\"" "$audit"
+  [ ! -e "$audit.lock" ]
+
+  # b.dat was deleted from the server by "fetch with missing object"
+  rm -rf .git/lfs/objects
+  printf "%s 1\n" "$b_oid" |
+    git -c lfs.auditlog="$audit" lfs fetch --stdin > fetch.log 2>&1 && exit 1
+
+  [ "2" -eq "$(wc -l < "$audit")" ]
+  tail -n 1 "$audit" | grep "\"oid\":\"$b_oid\""
+  tail -n 1 "$audit" | grep "\"outcome\":\"failure\""
+)
+end_test
+
+begin_test "fetch --recheck"
+(
+  set -e
+
+  reponame="fetch-recheck"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  contents_a="recheck a"
+  contents_b="recheck b"
+  oid_a="$(calc_oid "$contents_a")"
+  oid_b="$(calc_oid "$contents_b")"
+  printf "$contents_a" > a.dat
+  printf "$contents_b" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "add files"
+  git push origin master
+
+  corrupt () {
+    local path=".git/lfs/objects/${1:0:2}/${1:2:2}/$1"
+    chmod u+w "$path"
+    printf "corrupt!!" > "$path"
+  }
+
+  # without --recheck, a corrupt object of the right size is left alone
+  corrupt "$oid_a"
+  git lfs fetch 2>&1 | tee fetch.log
+  [ "corrupt!!" = "$(cat ".git/lfs/objects/${oid_a:0:2}/${oid_a:2:2}/$oid_a")" ]
+
+  git lfs fetch --recheck 2>&1 | tee fetch.log
+  grep "Object a.dat ($oid_a) is corrupt; downloading it again" fetch.log
+  [ "0" -eq "$(grep -c "b.dat" fetch.log)" ]
+  [ "$contents_a" = "$(cat ".git/lfs/objects/${oid_a:0:2}/${oid_a:2:2}/$oid_a")" ]
+
+  # --oid only rechecks the objects given
+  corrupt "$oid_a"
+  corrupt "$oid_b"
+  git lfs fetch --recheck --oid "$oid_b" 2>&1 | tee fetch.log
+  grep "Object b.dat ($oid_b) is corrupt; downloading it again" fetch.log
+  [ "$contents_b" = "$(cat ".git/lfs/objects/${oid_b:0:2}/${oid_b:2:2}/$oid_b")" ]
+  [ "corrupt!!" = "$(cat ".git/lfs/objects/${oid_a:0:2}/${oid_a:2:2}/$oid_a")" ]
+
+  # a missing object is downloaded
+  rm ".git/lfs/objects/${oid_a:0:2}/${oid_a:2:2}/$oid_a"
+  git lfs fetch --recheck --oid "$oid_a"
+  assert_local_object "$oid_a" 9
+  git lfs fsck | grep "Git LFS fsck OK"
+
+  missing="$(calc_oid "not referenced")"
+  git lfs fetch --recheck --oid "$missing" > fetch.log 2>&1 && exit 1
+  grep "Object $missing is not referenced by the refs fetched" fetch.log
+
+  git lfs fetch --oid "$oid_a" > fetch.log 2>&1 && exit 1
+  grep "Cannot use --oid without --recheck" fetch.log
+)
+end_test
+
+begin_test "fetch --recheck with include/exclude"
+(
+  set -e
+
+  reponame="fetch-recheck-filter"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  contents_old="recheck old"
+  oid_old="$(calc_oid "$contents_old")"
+  printf "$contents_old" > b.dat
+  git add .gitattributes b.dat
+  git commit -m "add b.dat"
+  printf "recheck new" > b.dat
+  git add b.dat
+  git commit -m "change b.dat"
+  git push origin master
+
+  path=".git/lfs/objects/${oid_old:0:2}/${oid_old:2:2}/$oid_old"
+  chmod u+w "$path"
+  printf "corrupt!!!!" > "$path"
+
+  # previous versions of excluded files are not rechecked, nor removed
+  git config lfs.fetchrecentcommitsdays 1
+  git lfs fetch --recheck --recent --exclude "b.dat" 2>&1 | tee fetch.log
+  [ "0" -eq "$(grep -c "is corrupt" fetch.log)" ]
+  [ "corrupt!!!!" = "$(cat "$path")" ]
+
+  git lfs fetch --recheck --recent 2>&1 | tee fetch.log
+  grep "Object b.dat ($oid_old) is corrupt; downloading it again" fetch.log
+  [ "$contents_old" = "$(cat "$path")" ]
+)
+end_test
+
+begin_test "fetch resumes after being interrupted"
+(
+  set -e
+
+  reponame="fetch-resume"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  contents_a="resume a"
+  contents_b="resume b"
+  contents_c="resume c"
+  oid_a="$(calc_oid "$contents_a")"
+  oid_b="$(calc_oid "$contents_b")"
+  oid_c="$(calc_oid "$contents_c")"
+  printf "$contents_a" > a.dat
+  printf "$contents_b" > b.dat
+  printf "$contents_c" > c.dat
+  git add .gitattributes a.dat b.dat c.dat
+  git commit -m "add files"
+  git push origin master
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 git clone "$GITSERVER/$reponame" "$reponame-clone"
+  cd "$reponame-clone"
+
+  # As if an earlier fetch was interrupted after downloading a.dat, and
+  # part of the way through b.dat.
+  git lfs fetch --include=a.dat
+  assert_local_object "$oid_a" 8
+  sh -c "exit 0" &
+  deadpid=$!
+  wait "$deadpid"
+  host="$(hostname | tr -c 'A-Za-z0-9.\n' '_')"
+  mkdir -p .git/lfs/objects/incomplete
+  printf "resu" > ".git/lfs/objects/incomplete/git-lfs-tmp-$host-$deadpid-$(date +%s)-$oid_b"
+
+  GIT_TRACE=1 git lfs fetch 2>&1 | tee fetch.log
+  grep "(2 of 2 files)" fetch.log
+  grep "fetch b.dat \[$oid_b\]" fetch.log
+  grep "fetch c.dat \[$oid_c\]" fetch.log
+  [ "0" -eq "$(grep -c "fetch a.dat" fetch.log)" ]
+  grep "xfer: Attempting to resume download of \"$oid_b\" from byte 4" fetch.log
+
+  assert_local_object "$oid_b" 8
+  assert_local_object "$oid_c" 8
+  git lfs fsck | grep "Git LFS fsck OK"
+)
+end_test
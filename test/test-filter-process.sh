@@ -65,6 +65,50 @@ begin_test "filter process: checking out a branch"
 )
 end_test
 
+begin_test "filter process: checking out multiple paths with the same OID"
+(
+  set -e
+
+  reponame="filter_process_duplicate_oids"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" repo-duplicate-oids
+
+  git lfs track "*.dat"
+  git add .gitattributes
+  git commit -m "initial commit"
+
+  contents="contents"
+  contents_oid="$(calc_oid "$contents")"
+  mkdir -p dir
+  printf "$contents" > a.dat
+  printf "$contents" > b.dat
+  printf "$contents" > dir/c.dat
+
+  git add a.dat b.dat dir/c.dat
+  git commit -m "add a.dat, b.dat, dir/c.dat"
+
+  git push origin master
+
+  pushd ..
+    git \
+      -c "filter.lfs.process=git-lfs filter-process" \
+      -c "filter.lfs.clean=false"\
+      -c "filter.lfs.smudge=false" \
+      -c "filter.lfs.required=true" \
+      clone "$GITSERVER/$reponame" "$reponame-assert"
+
+    cd "$reponame-assert"
+
+    # Every path backed by the same object must be smudged, not just the
+    # first one to request it.
+    [ "$contents" = "$(cat a.dat)" ]
+    [ "$contents" = "$(cat b.dat)" ]
+    [ "$contents" = "$(cat dir/c.dat)" ]
+    assert_local_object "$contents_oid" "${#contents}"
+  popd
+)
+end_test
+
 begin_test "filter process: adding a file"
 (
   set -e
@@ -105,5 +149,220 @@ begin_test "filter process: add a file with 1024 bytes"
 )
 end_test
 
+begin_test "filter process: checking out a file larger than many packets"
+(
+  set -e
+
+  reponame="filter-process-large-file"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs track "*.dat"
+  # several times the interval at which the smudge filter syncs its output,
+  # and not a multiple of the packet size
+  dd if=/dev/urandom of=big.dat bs=1000 count=3500
+  cp big.dat ../big.dat.orig
+  git add .gitattributes big.dat
+  git commit -m "add big.dat"
+
+  rm big.dat
+  git checkout -- big.dat
+  cmp ../big.dat.orig big.dat
+)
+end_test
+
+
 
 
+begin_test "filter process: warns about paths which differ only by case"
+(
+  set -e
+
+  reponame="filter-process-case-collisions"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs track "*.dat"
+  printf "upper" > A.dat
+  printf "lower" > a.dat
+  git add .gitattributes A.dat a.dat
+  git commit -m "add A.dat and a.dat"
+
+  # this filesystem may well be case-sensitive, but Git LFS only goes by what
+  # Git says it is
+  git config core.ignorecase true
+
+  rm A.dat a.dat
+  git checkout -- A.dat a.dat 2>&1 | tee checkout.log
+  grep "Warning: A.dat and a.dat differ only in case" checkout.log
+  [ "lower" = "$(cat a.dat)" ]
+
+  git config core.ignorecase false
+  rm A.dat a.dat
+  git checkout -- A.dat a.dat 2>&1 | tee checkout.log
+  [ "0" -eq "$(grep -c "differ only in case" checkout.log)" ]
+)
+end_test
+
+begin_test "filter process: warns when cleaning a file locked by someone else"
+(
+  set -e
+
+  reponame="filter-process-their-lock"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  # any lock path with "theirs" is returned as "their" lock by /locks/verify
+  printf "locked contents" > locked_theirs.dat
+  printf "unlocked contents" > unlocked.dat
+  git add .gitattributes locked_theirs.dat unlocked.dat
+  git commit -m "add files"
+  git push origin master
+
+  git lfs lock --json "locked_theirs.dat" | tee lock.log
+  id=$(assert_lock lock.log locked_theirs.dat)
+  assert_server_lock $id
+
+  pushd "$TRASHDIR" >/dev/null
+    clone_repo "$reponame" "$reponame-assert"
+    git config lfs.locksverify true
+
+    printf "unauthorized changes" >> locked_theirs.dat
+    printf "more changes" >> unlocked.dat
+    git add locked_theirs.dat unlocked.dat 2>&1 | tee add.log
+
+    grep "Warning: locked_theirs.dat is locked by Git LFS Tests" add.log
+    [ "0" -eq "$(grep -c "unlocked.dat is locked" add.log)" ]
+
+    # the files are cleaned regardless
+    git diff --cached --name-only | grep "locked_theirs.dat"
+
+    # without lock verification, the server is not consulted
+    git config lfs.locksverify false
+    printf "even more changes" >> locked_theirs.dat
+    git add locked_theirs.dat 2>&1 | tee add.log
+    [ "0" -eq "$(grep -c "is locked" add.log)" ]
+  popd >/dev/null
+)
+end_test
+
+begin_test "filter process: reports smudge cache statistics"
+(
+  set -e
+
+  reponame="filter-process-smudge-stats"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  contents_a="local contents"
+  contents_b="downloaded contents"
+  oid_b="$(calc_oid "$contents_b")"
+  printf "$contents_a" > a.dat
+  printf "$contents_b" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "add files"
+  git push origin master
+
+  rm a.dat b.dat
+  rm -rf ".git/lfs/objects/${oid_b:0:2}/${oid_b:2:2}"
+  refute_local_object "$oid_b"
+
+  git config lfs.smudgestats true
+  GIT_TRACE=1 git checkout -- a.dat b.dat 2>&1 | tee checkout.log
+
+  [ "$contents_a" = "$(cat a.dat)" ]
+  [ "$contents_b" = "$(cat b.dat)" ]
+
+  grep "Git LFS: 1 of 2 object(s) read from local storage (50.0%): 14 B local, 19 B downloaded" checkout.log
+  grep "filter-process: smudge cache: 1 hit(s) (14 bytes), 1 miss(es) (19 bytes), hit ratio 0.500" checkout.log
+
+  # without lfs.smudgestats, the summary is only traced
+  rm a.dat
+  git config lfs.smudgestats false
+  git checkout -- a.dat 2>&1 | tee checkout.log
+  [ "0" -eq "$(grep -c "read from local storage" checkout.log)" ]
+)
+end_test
+
+begin_test "filter process: runs post-smudge commands"
+(
+  set -e
+
+  reponame="filter_process_post_smudge"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" repo-post-smudge
+
+  git lfs track "*.dat" "*.bin"
+  printf "contents_a" > a.dat
+  printf "contents_b" > b.bin
+  git add .gitattributes a.dat b.bin
+  git commit -m "add files"
+  git push origin master
+
+  indexer="$TRASHDIR/post-smudge-indexer"
+  cat > "$indexer" <<-EOS
+	#!/bin/sh
+	cat "\$1" > "$TRASHDIR/indexed-\$(basename "\$1")"
+	EOS
+  chmod +x "$indexer"
+
+  failing="$TRASHDIR/post-smudge-failing"
+  cat > "$failing" <<-EOS
+	#!/bin/sh
+	touch "$TRASHDIR/failed-\$(basename "\$1")"
+	exit 1
+	EOS
+  chmod +x "$failing"
+
+  cd ..
+  git \
+    -c "filter.lfs.process=git-lfs filter-process" \
+    -c "filter.lfs.clean=false"\
+    -c "filter.lfs.smudge=false" \
+    -c "filter.lfs.required=true" \
+    -c "lfs.postsmudge.*.DAT.command=$indexer" \
+    -c "lfs.postsmudge.*.bin.command=$failing %f" \
+    clone "$GITSERVER/$reponame" "$reponame-assert" 2>&1 | tee clone.log
+  [ "0" -eq "${PIPESTATUS[0]}" ]
+
+  # Commands may outlive the clone, but write to its standard error, so tee
+  # has waited for them. Each sees its file once it has been written.
+  [ "contents_a" = "$(cat "$TRASHDIR/indexed-a.dat")" ]
+  [ ! -e "$TRASHDIR/indexed-b.bin" ]
+
+  # A command which fails does not fail the clone.
+  [ -e "$TRASHDIR/failed-b.bin" ]
+  [ "contents_b" = "$(cat "$reponame-assert/b.bin")" ]
+)
+end_test
+
+begin_test "filter process: prints negotiated capabilities with GIT_LFS_DEBUG"
+(
+  set -e
+
+  reponame="filter-process-debug-capabilities"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  rm a.dat
+  GIT_LFS_DEBUG=1 git checkout -- a.dat 2>&1 | tee checkout.log
+  [ "contents" = "$(cat a.dat)" ]
+
+  grep "filter-process: protocol version=2" checkout.log
+  grep "filter-process: Git offered capabilities: clean, smudge" checkout.log
+  grep "filter-process: accepted capabilities: clean, smudge$" checkout.log
+  grep "filter-process: delay: disabled" checkout.log
+
+  # without GIT_LFS_DEBUG, nothing is printed
+  rm a.dat
+  git checkout -- a.dat 2>&1 | tee checkout.log
+  [ "0" -eq "$(grep -c "filter-process:" checkout.log)" ]
+)
+end_test
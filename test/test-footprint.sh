@@ -0,0 +1,48 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "footprint"
+(
+  set -e
+
+  reponame="footprint"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs track "*.psd" "*.dat"
+  mkdir -p art
+  printf "large psd file" > art/a.psd
+  printf "large psd file" > art/copy.psd
+  printf "small" > a.dat
+  git add .gitattributes art a.dat
+  git commit -m "add files"
+
+  git lfs footprint | tee footprint.log
+  grep "Git LFS storage footprint of HEAD, by pattern:" footprint.log
+  # *.psd is listed first, since it takes up the most storage, and its two
+  # files share one object
+  sed -n 2p footprint.log | grep "14 B  \*.psd .*1 object(s), 2 file(s)"
+  sed -n 3p footprint.log | grep "5 B  \*.dat .*1 object(s), 1 file(s)"
+  grep "19 B  total .*2 object(s), 3 file(s)" footprint.log
+
+  # objects which have not been fetched take up no storage
+  oid="$(calc_oid "small")"
+  rm ".git/lfs/objects/${oid:0:2}/${oid:2:2}/$oid"
+  git lfs footprint --json | tee footprint.json
+  grep "\"ref\":\"HEAD\"" footprint.json
+  grep "\"pattern\":\"\*.psd\"" footprint.json
+  grep "\"total\":{\"rule\":null,\"files\":3,\"objects\":2,\"size\":14,\"missing\":1}" footprint.json
+
+  # pointers which no pattern tracks any longer
+  git lfs untrack "*.dat"
+  git add .gitattributes
+  git commit -m "untrack *.dat"
+  git lfs footprint | tee footprint.log
+  grep "(not tracked by any pattern) *1 object(s), 1 file(s), 1 not in the local store" footprint.log
+
+  git lfs footprint HEAD~1 | grep "Git LFS storage footprint of HEAD~1"
+  git lfs footprint not-a-ref > footprint.log 2>&1 && exit 1
+  grep "Could not resolve \"not-a-ref\"" footprint.log
+)
+end_test
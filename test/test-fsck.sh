@@ -98,6 +98,72 @@ begin_test "fsck dry run"
 )
 end_test
 
+begin_test "fsck invalid pointers"
+(
+  set -e
+
+  reponame="fsck-invalid-pointers"
+  git init $reponame
+  cd $reponame
+
+  git lfs track *.dat
+  echo "test data" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "first commit"
+
+  # commit pointers which are not valid as they are, without cleaning them
+  oid="$(calc_oid "test data")"
+  printf "version https://git-lfs.github.com/spec/v1\noid sha256:%s\nsize 0\n" "$oid" > empty.txt
+  printf "version https://git-lfs.github.com/spec/v1\noid sha256:abc\nsize 10\n" > badoid.txt
+  echo "notes about git-lfs" > notes.txt
+  git add empty.txt badoid.txt notes.txt
+  git commit -m "add invalid pointers"
+
+  git lfs fsck > fsck.log 2>&1
+  cat fsck.log
+  grep "Pointer empty.txt ($(git rev-parse HEAD:empty.txt)) is invalid: Invalid size: 0" fsck.log
+  grep "Pointer badoid.txt ($(git rev-parse HEAD:badoid.txt)) is invalid: Invalid Oid: abc" fsck.log
+  [ "0" -eq "$(grep -c "notes.txt" fsck.log)" ]
+  [ "0" -eq "$(grep -c "Git LFS fsck OK" fsck.log)" ]
+)
+end_test
+
+begin_test "fsck upper case oids"
+(
+  set -e
+
+  reponame="fsck-upper-case-oids"
+  git init $reponame
+  cd $reponame
+
+  git lfs track "*.dat"
+  echo "test data" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "first commit"
+
+  oid="$(calc_oid "test data\n")"
+  upper="$(echo "$oid" | tr a-f A-F)"
+  printf "version https://git-lfs.github.com/spec/v1\noid sha256:%s\nsize 10\n" "$upper" > upper.txt
+  git add upper.txt
+  git commit -m "add a pointer with an upper case oid"
+
+  git lfs fsck > fsck.log 2>&1
+  cat fsck.log
+  grep "Pointer upper.txt ($(git rev-parse HEAD:upper.txt)) is invalid: Oid $upper is not in lower case" fsck.log
+  [ "0" -eq "$(grep -c "Git LFS fsck OK" fsck.log)" ]
+
+  # the pointer is read in lower case, so its object is found
+  git lfs smudge < upper.txt | grep "test data"
+
+  # and the clean filter writes it in lower case
+  git lfs clean < upper.txt | tee clean.log
+  grep "oid sha256:$oid" clean.log
+
+  git -c lfs.pointer.strictoids=true lfs smudge < upper.txt | tee smudge.log
+  grep "oid sha256:$upper" smudge.log
+)
+end_test
+
 begin_test "fsck: outside git repository"
 (
   set +e
@@ -113,3 +179,87 @@ begin_test "fsck: outside git repository"
   grep "Not in a git repository" fsck.log
 )
 end_test
+
+begin_test "fsck with workers"
+(
+  set -e
+
+  reponame="fsck-workers"
+  git init $reponame
+  cd $reponame
+
+  git lfs track "*.dat"
+  for i in 1 2 3 4 5 6; do
+    echo "test data $i" > "$i.dat"
+  done
+  git add .gitattributes *.dat
+  git commit -m "first commit"
+
+  [ "Git LFS fsck OK" = "$(git -c lfs.storeconcurrency=1 lfs fsck)" ]
+  [ "Git LFS fsck OK" = "$(git -c lfs.storeconcurrency=4 lfs fsck)" ]
+
+  git lfs fsck --progress 2>&1 | tee fsck.log
+  grep "Git LFS: (6 of 6 files)" fsck.log
+  grep "Git LFS fsck OK" fsck.log
+
+  oid3="$(calc_oid "test data 3\n")"
+  oid5="$(calc_oid "test data 5\n")"
+  echo "CORRUPTION" >> ".git/lfs/objects/${oid3:0:2}/${oid3:2:2}/$oid3"
+  echo "CORRUPTION" >> ".git/lfs/objects/${oid5:0:2}/${oid5:2:2}/$oid5"
+
+  git -c lfs.storeconcurrency=4 lfs fsck --dry-run 2>&1 | sort | tee fsck.log
+  [ "2" -eq "$(grep -c "is corrupt" fsck.log)" ]
+  grep "Object 3.dat ($oid3) is corrupt" fsck.log
+  grep "Object 5.dat ($oid5) is corrupt" fsck.log
+)
+end_test
+
+begin_test "fsck --layout"
+(
+  set -e
+
+  reponame="fsck-layout"
+  git init $reponame
+  cd $reponame
+
+  git lfs track "*.dat"
+  echo "layout a" > a.dat
+  echo "layout b" > b.dat
+  echo "layout c" > c.dat
+  git add .gitattributes *.dat
+  git commit -m "first commit"
+
+  [ "Git LFS fsck: all 3 objects are in place" = "$(git lfs fsck --layout)" ]
+
+  aOid="$(calc_oid "layout a\n")"
+  bOid="$(calc_oid "layout b\n")"
+  cOid="$(calc_oid "layout c\n")"
+  objects=".git/lfs/objects"
+
+  # a.dat is flattened into the top of the store, and b.dat put in the
+  # wrong shard
+  mv "$objects/${aOid:0:2}/${aOid:2:2}/$aOid" "$objects/$aOid"
+  mkdir -p "$objects/00/00"
+  mv "$objects/${bOid:0:2}/${bOid:2:2}/$bOid" "$objects/00/00/$bOid"
+  # a second copy of c.dat
+  cp "$objects/${cOid:0:2}/${cOid:2:2}/$cOid" "$objects/00/$cOid"
+  echo "junk" > "$objects/00/00/.DS_Store"
+
+  git lfs fsck --layout --dry-run | tee fsck.log
+  grep "Object $aOid is misplaced at .*$objects/$aOid" fsck.log
+  grep "Object $bOid is misplaced at .*$objects/00/00/$bOid" fsck.log
+  refute_local_object "$aOid"
+  refute_local_object "$bOid"
+
+  git lfs fsck --layout | tee fsck.log
+  grep "Moved object $aOid from .*$objects/$aOid" fsck.log
+  grep "Moved object $bOid from .*$objects/00/00/$bOid" fsck.log
+  grep "Object $cOid is misplaced at .*$objects/00/$cOid, but .*$objects/${cOid:0:2}/${cOid:2:2}/$cOid already exists; left in place" fsck.log
+  grep "File .*$objects/00/00/.DS_Store is not a Git LFS object; left in place" fsck.log
+
+  assert_local_object "$aOid" 9
+  assert_local_object "$bOid" 9
+  [ -f "$objects/00/$cOid" ]
+  [ "Git LFS fsck OK" = "$(git lfs fsck)" ]
+)
+end_test
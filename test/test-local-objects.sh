@@ -0,0 +1,61 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "local-objects"
+(
+  set -e
+
+  reponame="local-objects"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs track "*.dat"
+  printf "small" > small.dat
+  printf "a larger file" > large.dat
+  git add .gitattributes small.dat large.dat
+  git commit -m "add files"
+
+  small_oid="$(calc_oid "small")"
+  large_oid="$(calc_oid "a larger file")"
+
+  git rm -q large.dat
+  git commit -m "remove large.dat"
+
+  touch -a -d "2001-01-01 00:00:00" ".git/lfs/objects/${large_oid:0:2}/${large_oid:2:2}/$large_oid"
+  touch -a -d "2002-01-01 00:00:00" ".git/lfs/objects/${small_oid:0:2}/${small_oid:2:2}/$small_oid"
+
+  git lfs local-objects --sort=size | tee ../objects.log
+  [ "2" -eq "$(wc -l < ../objects.log | tr -d ' ')" ]
+  head -n 1 ../objects.log | grep "^$large_oid  *13 B 2001-01-01"
+  tail -n 1 ../objects.log | grep "^$small_oid  *5 B 2002-01-01 .* (HEAD)$"
+  [ "0" -eq "$(grep -c "$large_oid.*(HEAD)" ../objects.log)" ]
+
+  git lfs local-objects --sort=atime | head -n 1 | grep "^$large_oid"
+
+  git lfs local-objects --json > ../objects.json
+  grep "\"oid\":\"$small_oid\",\"size\":5,\"accessed\":\"2002-01-01T00:00:00" ../objects.json
+  grep "\"oid\":\"$large_oid\",\"size\":13,.*\"head\":false" ../objects.json
+
+  git lfs local-objects --sort=name > ../objects.log 2>&1 && exit 1
+  grep "Invalid sort \"name\"" ../objects.log
+)
+end_test
+
+begin_test "local-objects without commits"
+(
+  set -e
+
+  reponame="local-objects-empty"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs track "*.dat"
+  printf "a" > a.dat
+  git add a.dat
+
+  git lfs local-objects | tee ../objects.log
+  grep "^$(calc_oid "a")" ../objects.log
+  [ "0" -eq "$(grep -c "(HEAD)" ../objects.log)" ]
+)
+end_test
@@ -27,6 +27,25 @@ begin_test "ls-files"
 )
 end_test
 
+begin_test "ls-files: with lfs.pointer.maxsize"
+(
+  set -e
+
+  mkdir repo-pointer-maxsize
+  cd repo-pointer-maxsize
+  git init
+  git lfs track "*.dat"
+  echo "some data" > some.dat
+  git add .gitattributes some.dat
+  git commit -m "add some.dat"
+
+  git lfs ls-files | grep some.dat
+
+  # The pointer is larger than 100 bytes, so it is not scanned.
+  [ "" = "$(git -c lfs.pointer.maxsize=100 lfs ls-files)" ]
+)
+end_test
+
 begin_test "ls-files: outside git repository"
 (
   set +e
@@ -0,0 +1,101 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "materialize and pointerize"
+(
+  set -e
+
+  reponame="materialize-pointerize"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  contents_a="a"
+  contents_a_oid="$(calc_oid "$contents_a")"
+  printf "$contents_a" > a.dat
+  printf "b" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "add files"
+  git push origin master
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 git clone "$GITSERVER/$reponame" "$reponame-skipped"
+  cd "$reponame-skipped"
+
+  [ "$(cat a.dat | head -1)" = "version https://git-lfs.github.com/spec/v1" ]
+
+  git lfs materialize a.dat 2>&1 | tee materialize.log
+  grep "Materialized a.dat" materialize.log
+  [ "$contents_a" = "$(cat a.dat)" ]
+  assert_local_object "$contents_a_oid" 1
+
+  # Nothing else is checked out.
+  [ "$(cat b.dat | head -1)" = "version https://git-lfs.github.com/spec/v1" ]
+  [ -z "$(git status --porcelain --untracked-files=no)" ]
+
+  git lfs materialize a.dat 2>&1 | tee materialize.log
+  grep "a.dat is not a pointer; left as it is" materialize.log
+
+  git lfs pointerize a.dat 2>&1 | tee pointerize.log
+  grep "Replaced a.dat with its pointer" pointerize.log
+  grep "oid sha256:$contents_a_oid" a.dat
+  assert_local_object "$contents_a_oid" 1
+  [ -z "$(git status --porcelain --untracked-files=no)" ]
+
+  git lfs pointerize a.dat 2>&1 | tee pointerize.log
+  grep "a.dat is already a pointer; left as it is" pointerize.log
+
+  git lfs materialize missing.dat > materialize.log 2>&1 && exit 1
+  grep "Could not read missing.dat" materialize.log
+
+  exit 0
+)
+end_test
+
+begin_test "materialize and pointerize refuse files which would be staged"
+(
+  set -e
+
+  reponame="materialize-pointerize-refuse"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "a" > a.dat
+  printf "b" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "add files"
+
+  # Not tracked by Git LFS, so the pointer would be its contents.
+  printf "plain" > plain.txt
+  git add plain.txt
+  git commit -m "add plain.txt"
+  git lfs pointerize plain.txt > pointerize.log 2>&1 && exit 1
+  grep "plain.txt is not tracked by Git LFS" pointerize.log
+  [ "plain" = "$(cat plain.txt)" ]
+
+  # Modified since it was staged.
+  printf "changed" > a.dat
+  git lfs pointerize a.dat > pointerize.log 2>&1 && exit 1
+  grep "a.dat differs from the index" pointerize.log
+  [ "changed" = "$(cat a.dat)" ]
+  git checkout -- a.dat
+
+  # A pointer other than the staged one.
+  git lfs pointerize b.dat
+  git lfs pointer --file=a.dat > b.dat 2>/dev/null
+  git lfs materialize b.dat > materialize.log 2>&1 && exit 1
+  grep "b.dat differs from the index" materialize.log
+  git checkout -- b.dat
+
+  # Only stat information is refreshed: other changes stay unstaged.
+  printf "unstaged" > unstaged.txt
+  git add unstaged.txt
+  git commit -m "add unstaged.txt"
+  printf "edited" > unstaged.txt
+  git lfs pointerize a.dat
+  [ -z "$(git diff --cached --name-only)" ]
+  [ "unstaged.txt" = "$(git diff --name-only)" ]
+)
+end_test
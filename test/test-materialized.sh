@@ -0,0 +1,75 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "materialized"
+(
+  set -e
+
+  reponame="materialized"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  mkdir -p dir
+  printf "a" > a.dat
+  printf "b" > dir/b.dat
+  printf "c" > c.dat
+  git add .gitattributes a.dat dir/b.dat c.dat
+  git commit -m "add files"
+  git push origin master
+
+  git lfs materialized 2>&1 | tee materialized.log
+  grep "Git LFS materialized: all 3 files are materialized" materialized.log
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 git clone "$GITSERVER/$reponame" "$reponame-skipped"
+  cd "$reponame-skipped"
+
+  git lfs materialized > materialized.log 2>&1 && exit 1
+  grep "a.dat: pointer" materialized.log
+  grep "dir/b.dat: pointer" materialized.log
+  grep "Git LFS materialized: 3 of 3 files are not materialized" materialized.log
+
+  git lfs pull --include="a.dat,dir/b.dat"
+  rm c.dat
+
+  git lfs materialized > materialized.log 2>&1 && exit 1
+  grep "c.dat: missing" materialized.log
+  grep "Git LFS materialized: 1 of 3 files are not materialized" materialized.log
+
+  git lfs materialized --quiet > materialized.log 2>&1 && exit 1
+  [ "Git LFS materialized: 1 of 3 files are not materialized" = "$(cat materialized.log)" ]
+
+  git lfs materialized --exclude="c.dat" 2>&1 | tee materialized.log
+  grep "Git LFS materialized: all 2 files are materialized" materialized.log
+
+  # Subdirectories are checked against the root of the working tree.
+  cd dir
+  git lfs materialized --quiet --exclude="c.dat" > materialized.log
+  [ -z "$(cat materialized.log)" ]
+)
+end_test
+
+begin_test "materialized: placeholders"
+(
+  set -e
+
+  reponame="materialized-placeholders"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "a" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  git push origin master
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 GIT_LFS_SMUDGE_PLACEHOLDERS=1 git clone "$GITSERVER/$reponame" "$reponame-placeholders"
+  cd "$reponame-placeholders"
+
+  git lfs materialized > materialized.log 2>&1 && exit 1
+  grep "a.dat: placeholder" materialized.log
+)
+end_test
@@ -99,6 +99,89 @@ begin_test "prune unreferenced and old"
 end_test
 
 
+begin_test "prune keep refs"
+(
+  set -e
+
+  reponame="prune_keep_refs"
+  setup_remote_repo "remote_$reponame"
+
+  clone_repo "remote_$reponame" "clone_$reponame"
+
+  git lfs track "*.dat" 2>&1 | tee track.log
+  grep "Tracking \"\*.dat\"" track.log
+
+  content_releasebase="Keep: in the history of a kept release branch"
+  content_release="Keep: at a kept release branch"
+  content_oldrelease="To delete: at a release branch which is not kept"
+  content_tagged="Keep with --keep-ref: at a tag"
+  content_current="Keep: current"
+  oid_releasebase=$(calc_oid "$content_releasebase")
+  oid_release=$(calc_oid "$content_release")
+  oid_oldrelease=$(calc_oid "$content_oldrelease")
+  oid_tagged=$(calc_oid "$content_tagged")
+  oid_current=$(calc_oid "$content_current")
+
+  echo "[
+  {
+    \"CommitDate\":\"$(get_date -30d)\",
+    \"Files\":[
+      {\"Filename\":\"file.dat\",\"Size\":${#content_releasebase}, \"Data\":\"$content_releasebase\"}]
+  },
+  {
+    \"CommitDate\":\"$(get_date -25d)\",
+    \"NewBranch\":\"release/1.0\",
+    \"Files\":[
+      {\"Filename\":\"file.dat\",\"Size\":${#content_release}, \"Data\":\"$content_release\"}]
+  },
+  {
+    \"CommitDate\":\"$(get_date -25d)\",
+    \"ParentBranches\":[\"master\"],
+    \"NewBranch\":\"release/old\",
+    \"Files\":[
+      {\"Filename\":\"file.dat\",\"Size\":${#content_oldrelease}, \"Data\":\"$content_oldrelease\"}]
+  },
+  {
+    \"CommitDate\":\"$(get_date -20d)\",
+    \"ParentBranches\":[\"master\"],
+    \"Tags\":[\"v1.0\"],
+    \"Files\":[
+      {\"Filename\":\"file.dat\",\"Size\":${#content_tagged}, \"Data\":\"$content_tagged\"}]
+  },
+  {
+    \"ParentBranches\":[\"master\"],
+    \"Files\":[
+      {\"Filename\":\"file.dat\",\"Size\":${#content_current}, \"Data\":\"$content_current\"}]
+  }
+  ]" | lfstest-testutils addcommits
+
+  git push origin master release/1.0 release/old v1.0
+
+  git config lfs.fetchrecentrefsdays 0
+  git config lfs.fetchrecentcommitsdays 0
+
+  git lfs prune --dry-run 2>&1 | tee prune.log
+  grep "5 local objects, 1 retained" prune.log
+
+  git config --add lfs.prune.keeprefs "release/*"
+  git config --add lfs.prune.keeprefs "!release/old"
+
+  git lfs prune --dry-run 2>&1 | tee prune.log
+  grep "5 local objects, 3 retained" prune.log
+
+  git lfs prune --keep-ref refs/tags --verbose 2>&1 | tee prune.log
+  grep "5 local objects, 4 retained" prune.log
+  grep "Pruning 1 files" prune.log
+  grep "$oid_oldrelease" prune.log
+
+  refute_local_object "$oid_oldrelease"
+  assert_local_object "$oid_releasebase" "${#content_releasebase}"
+  assert_local_object "$oid_release" "${#content_release}"
+  assert_local_object "$oid_tagged" "${#content_tagged}"
+  assert_local_object "$oid_current" "${#content_current}"
+)
+end_test
+
 begin_test "prune keep unpushed"
 (
   set -e
@@ -179,3 +179,39 @@ begin_test "pull: outside git repository"
   grep "Not in a git repository" pull.log
 )
 end_test
+
+begin_test "pull with lfs.minfreespace"
+(
+  set -e
+
+  reponame="pull-minfreespace"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "abc" > a.dat
+  printf "de" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "add files"
+  git push origin master
+
+  cd ..
+  GIT_LFS_SKIP_SMUDGE=1 git clone "$GITSERVER/$reponame" "$reponame-skipped"
+  cd "$reponame-skipped"
+
+  # The objects are downloaded into the store, and then written into the
+  # working tree, which are on the same volume.
+  git -c lfs.minfreespace=100PB lfs pull > pull.log 2>&1 && exit 1
+  grep "(10 B of Git LFS files, plus 100 PB from lfs.minfreespace)" pull.log
+  refute_local_object "$(calc_oid "abc")"
+  [ "$(head -1 a.dat)" = "version https://git-lfs.github.com/spec/v1" ]
+
+  git lfs fetch
+  git -c lfs.minfreespace=100PB lfs checkout > checkout.log 2>&1 && exit 1
+  grep "(5 B of Git LFS files, plus 100 PB from lfs.minfreespace)" checkout.log
+
+  git -c lfs.minfreespace=1KB lfs pull
+  [ "abc" = "$(cat a.dat)" ]
+  [ "de" = "$(cat b.dat)" ]
+)
+end_test
@@ -77,7 +77,7 @@ begin_test "push: upload file with storage 500"
 (
   set -e
 
-  push_fail_test "status-storage-500"
+  push_fail_test "status-storage-500" "Transfer errors by cause: server=1"
 )
 end_test
 
@@ -93,7 +93,7 @@ begin_test "push: upload file with api 403"
 (
   set -e
 
-  push_fail_test "status-batch-403"
+  push_fail_test "status-batch-403" "Transfer errors by cause: auth=1"
 )
 end_test
 
@@ -128,3 +128,27 @@ begin_test "push: upload file with api 500"
   push_fail_test "status-batch-500"
 )
 end_test
+
+begin_test "push: upload file with api error message"
+(
+  set -e
+
+  reponame="$(basename "$0" ".sh")-batch-quota-exceeded"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "hi" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  set +e
+  git push origin master 2>&1 | tee push.log
+  res="${PIPESTATUS[0]}"
+  set -e
+
+  [ "$res" != "0" ]
+  grep "quota exceeded (request ID: req-1234)" push.log
+  grep "Server said: quota exceeded (request ID: req-1234), for 1 transfer(s)" push.log
+)
+end_test
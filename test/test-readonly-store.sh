@@ -0,0 +1,73 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+ensure_git_version_isnt $VERSION_LOWER "2.11.0"
+
+begin_test "read-only store: smudge reads present objects, clean fails clearly"
+(
+  set -e
+
+  reponame="readonly-store"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "a" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  git push origin master
+
+  # A temporary directory which cannot be created makes the store
+  # unwritable, even for users whom permissions do not stop.
+  printf "not a directory" > "$TRASHDIR/$reponame-file"
+  git config lfs.tempdir "$TRASHDIR/$reponame-file/tmp"
+
+  rm a.dat
+  git checkout a.dat 2>&1 | tee checkout.log
+  [ "a" = "$(cat a.dat)" ]
+  [ "0" -eq "$(grep -c "ERROR" checkout.log)" ]
+
+  printf "b" > b.dat
+  git add b.dat > add.log 2>&1 && exit 1
+  grep "is not writable" add.log
+  grep "so objects cannot be added to it" add.log
+  refute_local_object "$(calc_oid "b")"
+)
+end_test
+
+begin_test "read-only store: permissions"
+(
+  set -e
+
+  if [ "$(id -u)" -eq 0 ]; then
+    echo "skip: permissions do not apply to root"
+    exit 0
+  fi
+
+  reponame="readonly-store-permissions"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "a" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  chmod -R a-w .git/lfs
+  rm a.dat
+  git checkout a.dat
+  [ "a" = "$(cat a.dat)" ]
+
+  printf "b" > b.dat
+  git add b.dat > add.log 2>&1 && { chmod -R u+w .git/lfs; exit 1; }
+  grep "is not writable" add.log
+
+  chmod u+w .git/lfs/objects
+  chmod a-r .git/lfs/objects
+  rm a.dat
+  git checkout a.dat > checkout.log 2>&1 || true
+  chmod -R u+rw .git/lfs
+  grep "is not readable" checkout.log
+)
+end_test
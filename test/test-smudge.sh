@@ -28,6 +28,58 @@ begin_test "smudge"
 )
 end_test
 
+begin_test "smudge with lfs.minfreespace"
+(
+  set -e
+
+  cd repo
+
+  oid="fcf5015df7a9089a7aa7fe74139d4b8f7d62e52d5a34f9a87aeffc8e8c668254"
+  rm -rf .git/lfs/objects
+
+  # The object is downloaded into the store, and then written into the
+  # working tree, which are on the same volume.
+  pointer "$oid" 9 | git -c lfs.minfreespace=100PB lfs smudge a.dat > smudge.log 2>&1 && exit 1
+  grep "(18 B of Git LFS files, plus 100 PB from lfs.minfreespace)" smudge.log
+  refute_local_object "$oid"
+
+  # Only the store is written to with --to-stdout.
+  pointer "$oid" 9 | git -c lfs.minfreespace=100PB lfs smudge --to-stdout a.dat > smudge.log 2>&1 && exit 1
+  grep "(9 B of Git LFS files, plus 100 PB from lfs.minfreespace)" smudge.log
+
+  # Without a margin, the object only has to fit.
+  output="$(pointer "$oid" 9 | git lfs smudge a.dat)"
+  [ "smudge a" = "$output" ]
+)
+end_test
+
+begin_test "smudge pointer with trailing whitespace"
+(
+  set -e
+
+  cd repo
+
+  oid="fcf5015df7a9089a7aa7fe74139d4b8f7d62e52d5a34f9a87aeffc8e8c668254"
+  printf "version https://git-lfs.github.com/spec/v1\noid sha256:$oid \nsize 9 \n" > ../padded.ptr
+
+  output="$(cat ../padded.ptr | git lfs smudge)"
+  [ "smudge a" = "$output" ]
+
+  # such a pointer, as some tools have committed, can be checked out
+  blob="$(git hash-object -w --no-filters ../padded.ptr)"
+  git update-index --add --cacheinfo 100644 "$blob" padded.dat
+  git commit -m "add padded.dat"
+  rm -f padded.dat
+  git checkout -- padded.dat
+  [ "smudge a" = "$(cat padded.dat)" ]
+
+  git lfs ls-files | grep "${oid:0:10} \* padded.dat"
+
+  # and is cleaned into its canonical form when it is next added
+  [ "$(pointer "$oid" 9)" = "$(git lfs clean < padded.dat)" ]
+)
+end_test
+
 begin_test "smudge with temp file"
 (
   set -e
@@ -57,6 +109,78 @@ begin_test "smudge with invalid pointer"
 )
 end_test
 
+begin_test "smudge with lfs.smudge.nonpointer"
+(
+  set -e
+
+  reponame="smudge-nonpointer"
+  git init "$reponame"
+  cd "$reponame"
+
+  # By default, content which is not a pointer is passed through, with a
+  # warning.
+  [ "wat" = "$(echo "wat" | git lfs smudge a.dat 2> smudge.log)" ]
+  grep "Unable to parse pointer at: \"a.dat\"" smudge.log
+
+  [ "wat" = "$(echo "wat" | git -c lfs.smudge.nonpointer=ignore lfs smudge a.dat 2> smudge.log)" ]
+  [ ! -s smudge.log ]
+
+  echo "wat" | git -c lfs.smudge.nonpointer=error lfs smudge a.dat > smudge.log 2>&1 && exit 1
+  grep "refusing to smudge it, as lfs.smudge.nonpointer is \"error\"" smudge.log
+
+  # A file committed before it was tracked, as in a repository which was
+  # never migrated.
+  printf "raw content" > a.dat
+  git add a.dat
+  git commit -m "add a.dat"
+  git lfs track "*.dat"
+  git add .gitattributes
+  git commit -m "track *.dat"
+
+  rm a.dat
+  git checkout -- a.dat 2>&1 | tee checkout.log
+  [ "raw content" = "$(cat a.dat)" ]
+  grep "Encountered 1 file(s) that should have been pointers, but weren't:" checkout.log
+
+  rm a.dat
+  git -c lfs.smudge.nonpointer=ignore checkout -- a.dat 2>&1 | tee checkout.log
+  [ "raw content" = "$(cat a.dat)" ]
+  [ "0" -eq "$(grep -c "should have been pointers" checkout.log)" ]
+
+  rm a.dat
+  git -c lfs.smudge.nonpointer=error checkout -- a.dat > checkout.log 2>&1 && exit 1
+  grep "Unable to parse pointer at: \"a.dat\"" checkout.log
+  [ ! -e a.dat ]
+)
+end_test
+
+begin_test "smudge an empty pointer"
+(
+  set -e
+
+  reponame="smudge-empty-pointer"
+  git init "$reponame"
+  cd "$reponame"
+
+  # Git LFS never writes such a pointer, but the empty object it points to
+  # needs neither a server, nor to be present locally
+  pointer e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855 0 | git lfs smudge > smudge.log
+  [ ! -s smudge.log ]
+
+  git lfs track "*.dat"
+  printf "" > empty.dat
+  git add .gitattributes empty.dat
+  git commit -m "add empty.dat"
+
+  # empty files are committed as empty blobs, not as pointers
+  [ "0" -eq "$(git cat-file -s :empty.dat)" ]
+
+  rm empty.dat
+  git checkout -- empty.dat
+  [ -f empty.dat ] && [ ! -s empty.dat ]
+)
+end_test
+
 begin_test "smudge include/exclude"
 (
   set -e
@@ -223,3 +347,228 @@ begin_test "smudge skip download failure"
 
 )
 end_test
+
+begin_test "smudge in offline mode"
+(
+  set -e
+
+  reponame="$(basename "$0" ".sh")-offline"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" smudge-offline
+
+  git lfs track "*.dat"
+  echo "smudge a" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  pointer="$(pointer fcf5015df7a9089a7aa7fe74139d4b8f7d62e52d5a34f9a87aeffc8e8c668254 9)"
+
+  # local objects are still smudged in offline mode
+  [ "smudge a" = "$(echo "$pointer" | GIT_LFS_OFFLINE=1 git lfs smudge a.dat)" ]
+
+  git push origin master
+  rm -rf .git/lfs/objects
+
+  set +e
+  echo "$pointer" | GIT_LFS_OFFLINE=1 git lfs smudge a.dat 2> smudge.log
+  res=${PIPESTATUS[1]}
+  set -e
+  [ "$res" -ne 0 ]
+  grep "fcf5015df7a9089a7aa7fe74139d4b8f7d62e52d5a34f9a87aeffc8e8c668254" smudge.log
+  grep "offline mode" smudge.log
+
+  git config lfs.offline true
+  git config lfs.skipdownloaderrors true
+  [ "$pointer" = "$(echo "$pointer" | git lfs smudge a.dat)" ]
+  refute_local_object "fcf5015df7a9089a7aa7fe74139d4b8f7d62e52d5a34f9a87aeffc8e8c668254"
+)
+end_test
+
+begin_test "smudge with placeholders"
+(
+  set -e
+
+  reponame="$(basename "$0" ".sh")-placeholders"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" smudge-placeholders
+
+  git lfs track "*.dat"
+  echo "smudge a" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  git push origin master
+
+  pointer="$(pointer fcf5015df7a9089a7aa7fe74139d4b8f7d62e52d5a34f9a87aeffc8e8c668254 9)"
+
+  rm -rf .git/lfs/objects
+  git config lfs.smudgeplaceholders true
+
+  echo "$pointer" | git lfs smudge --skip a.dat > placeholder.out
+  [ "9" -eq "$(wc -c < placeholder.out | tr -d ' ')" ]
+  head -c 9 /dev/zero | cmp - placeholder.out
+
+  # an unmodified placeholder cleans back to the original pointer
+  [ "$pointer" = "$(cat placeholder.out | git lfs clean a.dat)" ]
+
+  # a modified one is cleaned as new content
+  [ "$pointer" != "$(echo "changed" | git lfs clean a.dat)" ]
+
+  # real contents forget the placeholder
+  echo "$pointer" | git lfs smudge a.dat > smudged.out
+  [ "smudge a" = "$(cat smudged.out)" ]
+  [ "$pointer" != "$(cat placeholder.out | git lfs clean a.dat)" ]
+)
+end_test
+
+begin_test "smudge --to-stdout"
+(
+  set -e
+
+  reponame="$(basename "$0" ".sh")-to-stdout"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" smudge-to-stdout
+
+  git lfs track "*.dat"
+  echo "smudge a" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  git push origin master
+
+  rm -rf .git/lfs/objects
+
+  # the include and exclude filter only applies to the working tree, so the
+  # object is downloaded anyway
+  git config lfs.fetchexclude "*.dat"
+  [ "smudge a" = "$(git cat-file blob HEAD:a.dat | git lfs smudge --to-stdout a.dat)" ]
+  assert_local_object "fcf5015df7a9089a7aa7fe74139d4b8f7d62e52d5a34f9a87aeffc8e8c668254" 9
+
+  # blobs which are not pointers are passed through unchanged
+  [ "$(git cat-file blob HEAD:.gitattributes)" = "$(git cat-file blob HEAD:.gitattributes | git lfs smudge --to-stdout)" ]
+
+  # nothing is written for an object which cannot be read
+  set +e
+  pointer "0000000000000000000000000000000000000000000000000000000000000000" 9 | git lfs smudge --to-stdout missing.dat > smudge.out 2> smudge.log
+  res=${PIPESTATUS[1]}
+  set -e
+  [ "$res" -ne 0 ]
+  [ ! -s smudge.out ]
+  grep "Error downloading object: missing.dat" smudge.log
+)
+end_test
+
+begin_test "smudge with rewrite rules"
+(
+  set -e
+
+  reponame="$(basename "$0" ".sh")-rewrite"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" smudge-rewrite
+
+  git lfs track "*.dat" "*.bin"
+  echo "smudge a" > a.dat
+  echo "smudge b" > b.bin
+  git add .gitattributes a.dat b.bin
+  git commit -m "add a.dat and b.bin"
+  git push origin master
+
+  git config lfs.smudge.rewrite.*.dat.path "build/%b"
+  git config lfs.smudge.rewrite.*.bin.path "build"
+  git config lfs.smudge.rewrite.*.bin.mode "move"
+
+  # rules are ignored unless they are allowed
+  rm a.dat b.bin
+  git checkout -- a.dat b.bin
+  [ ! -e build ]
+
+  git config lfs.smudge.allowrewrite true
+
+  rm a.dat b.bin
+  git checkout -- a.dat b.bin
+
+  # a link is made to a file smudged as usual
+  [ "smudge a" = "$(cat a.dat)" ]
+  [ -L build/a.dat ]
+  [ "smudge a" = "$(cat build/a.dat)" ]
+
+  # a moved file is left as a pointer, which Git sees as unchanged
+  git cat-file blob HEAD:b.bin | cmp - b.bin
+  [ "smudge b" = "$(cat build/b.bin)" ]
+  [ -z "$(git status --porcelain -- a.dat b.bin)" ]
+
+  # a rule may not write outside of the working tree
+  git config lfs.smudge.rewrite.*.dat.path "../%f"
+  rm a.dat
+  git checkout -- a.dat 2>&1 | tee checkout.log
+  grep "Not rewriting a.dat" checkout.log
+  [ ! -e ../a.dat ]
+
+  # nor through a link to a directory outside of it, in either mode
+  mkdir ../outside
+  rm -rf build
+  ln -s ../outside build
+  git config lfs.smudge.rewrite.*.dat.path "build/%b"
+  rm a.dat b.bin
+  git checkout -- a.dat b.bin 2>&1 | tee checkout.log
+  grep "Not rewriting a.dat" checkout.log
+  grep "Not rewriting b.bin" checkout.log
+  [ ! -e ../outside/a.dat ]
+  [ ! -e ../outside/b.bin ]
+
+  # nor into the Git directory
+  rm build
+  ln -s .git/lfs build
+  rm a.dat b.bin
+  git checkout -- a.dat b.bin 2>&1 | tee checkout.log
+  grep "Not rewriting a.dat" checkout.log
+  grep "Not rewriting b.bin" checkout.log
+  [ ! -e .git/lfs/a.dat ]
+  [ ! -e .git/lfs/b.bin ]
+  rm build
+)
+end_test
+
+begin_test "smudge with rewrite rules which would replace other files"
+(
+  set -e
+
+  reponame="$(basename "$0" ".sh")-rewrite-replace"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" smudge-rewrite-replace
+
+  git lfs track "*.bin"
+  mkdir one two
+  echo "smudge one" > one/c.bin
+  echo "smudge two" > two/c.bin
+  echo "smudge d" > d.bin
+  mkdir build
+  echo "tracked" > build/d.bin
+  git add .gitattributes one two d.bin build
+  git commit -m "add files"
+  git push origin master
+
+  git config lfs.smudge.allowrewrite true
+  git config lfs.smudge.rewrite.*.bin.path "build"
+  git config lfs.smudge.rewrite.*.bin.mode "move"
+
+  # the first file moved to a path keeps it
+  rm one/c.bin two/c.bin
+  git checkout -- one/c.bin
+  git checkout -- two/c.bin 2>&1 | tee checkout.log
+  grep "Not rewriting two/c.bin" checkout.log
+  [ "smudge one" = "$(cat build/c.bin)" ]
+  [ "smudge two" = "$(cat two/c.bin)" ]
+
+  # and may move there again
+  rm one/c.bin
+  git checkout -- one/c.bin 2>&1 | tee checkout.log
+  [ "0" -eq "$(grep -c "Not rewriting" checkout.log)" ]
+  git cat-file blob HEAD:one/c.bin | cmp - one/c.bin
+
+  # a file tracked by Git is never replaced
+  rm d.bin
+  git checkout -- d.bin 2>&1 | tee checkout.log
+  grep "Not rewriting d.bin" checkout.log
+  [ "tracked" = "$(cat build/d.bin)" ]
+  [ "smudge d" = "$(cat d.bin)" ]
+)
+end_test
@@ -0,0 +1,43 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "store layout marker"
+(
+  set -e
+
+  reponame="store-layout"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs track "*.dat"
+  printf "a" > a.dat
+  git add .gitattributes a.dat 2>&1 | tee ../add.log
+
+  [ "git-lfs-store-layout 1" = "$(cat .git/lfs/layout)" ]
+  [ "0" -eq "$(grep -c "warning" ../add.log)" ]
+  assert_local_object "$(calc_oid "a")" 1
+
+  git commit -m "add a.dat"
+
+  # reading objects does not write the marker
+  rm .git/lfs/layout
+  rm a.dat
+  git checkout -- a.dat
+  [ "a" = "$(cat a.dat)" ]
+  git lfs ls-files | grep "a.dat"
+  [ ! -e .git/lfs/layout ]
+
+  echo "git-lfs-store-layout 99" > .git/lfs/layout
+  printf "b" > b.dat
+  set +e
+  git add b.dat 2>&1 | tee ../add.log
+  res="${PIPESTATUS[0]}"
+  set -e
+
+  [ "$res" != "0" ]
+  grep "has layout version 99, but this version of Git LFS only knows layout version 1" ../add.log
+  [ "git-lfs-store-layout 99" = "$(cat .git/lfs/layout)" ]
+  refute_local_object "$(calc_oid "b")"
+)
+end_test
@@ -0,0 +1,70 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "tempdir: storage on another filesystem"
+(
+  set -e
+
+  # /dev/shm is a tmpfs on most Linux systems, so a store there is on a
+  # different filesystem from the repository.
+  storage="/dev/shm/git-lfs-tempdir-$$"
+  if ! mkdir "$storage" 2>/dev/null; then
+    echo "skip: cannot write to /dev/shm"
+    exit 0
+  fi
+  trap "rm -rf '$storage'" EXIT
+
+  reponame="tempdir-storage"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git config lfs.storage "$storage"
+  git config lfs.tempdir "$TRASHDIR/$reponame-tmp"
+
+  git lfs track "*.dat"
+  contents="a"
+  contents_oid=$(calc_oid "$contents")
+  printf "$contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  [ -f "$storage/objects/${contents_oid:0:2}/${contents_oid:2:2}/$contents_oid" ]
+  [ -d "$TRASHDIR/$reponame-tmp" ]
+  [ ! -d "$storage/tmp" ]
+
+  git push origin master
+  assert_server_object "$reponame" "$contents_oid"
+
+  rm -rf "$storage/objects"
+  rm a.dat
+  git lfs fetch
+  git lfs checkout
+
+  [ "$contents" = "$(cat a.dat)" ]
+  [ -f "$storage/objects/${contents_oid:0:2}/${contents_oid:2:2}/$contents_oid" ]
+  [ 0 -eq "$(find "$TRASHDIR/$reponame-tmp" -type f | wc -l)" ]
+
+  git lfs fsck
+)
+end_test
+
+begin_test "tempdir: relative path"
+(
+  set -e
+
+  reponame="tempdir-relative"
+  git init "$reponame"
+  cd "$reponame"
+
+  git config lfs.tempdir "lfs-tmp"
+  git lfs env | grep "TempDir=$(native_path "$TRASHDIR/$reponame/.git/lfs-tmp")"
+
+  git lfs track "*.dat"
+  printf "b" > b.dat
+  git add b.dat
+
+  [ -d ".git/lfs-tmp" ]
+  assert_local_object "$(calc_oid "b")" 1
+)
+end_test
@@ -488,3 +488,24 @@ begin_test "track (symlinked repository)"
   popd > /dev/null
 )
 end_test
+
+begin_test "track with attribute macro"
+(
+  set -e
+
+  reponame="track-attribute-macro"
+  git init "$reponame"
+  cd "$reponame"
+
+  printf '%s\n' \
+    "[attr]lfs filter=lfs diff=lfs merge=lfs -text" \
+    "*.dat lfs" > .gitattributes
+
+  git lfs track | tee ../track.log
+  grep "\*.dat (.gitattributes)" ../track.log
+  [ "0" -eq "$(grep -c "\[attr\]" ../track.log)" ]
+
+  git lfs track "*.dat" | grep "\"\*.dat\" already supported"
+  [ "2" -eq "$(wc -l < .gitattributes | tr -d ' ')" ]
+)
+end_test
@@ -0,0 +1,71 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "verify-history"
+(
+  set -e
+
+  reponame="verify-history"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "pushed" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  git push origin master
+
+  git lfs verify-history 2>&1 | tee ../verify.log
+  grep "Git LFS verify-history OK (1 objects in 1 commits)" ../verify.log
+
+  unpushed_oid="$(calc_oid "unpushed")"
+  printf "unpushed" > b.dat
+  cp a.dat c.dat
+  git add b.dat c.dat
+  git commit -m "add b.dat and c.dat"
+  unpushed_commit="$(git rev-parse HEAD)"
+
+  GIT_TRACE=1 git lfs verify-history origin > ../verify.log 2>&1 && exit 1
+  cat ../verify.log
+
+  grep "Commit $unpushed_commit:" ../verify.log
+  grep "  b.dat ($unpushed_oid): missing on the remote" ../verify.log
+  [ "0" -eq "$(grep -c "a.dat\|c.dat" ../verify.log)" ]
+  grep "Git LFS verify-history: 1 of 2 objects failed verification" ../verify.log
+  [ "1" -eq "$(grep -c "objects/batch" ../verify.log)" ]
+)
+end_test
+
+begin_test "verify-history with a bad size"
+(
+  set -e
+
+  reponame="verify-history-size"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  printf "contents" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+  git push origin master
+
+  oid="$(calc_oid "contents")"
+  printf "version https://git-lfs.github.com/spec/v1
+oid sha256:%s
+size 100
+" "$oid" > bad.txt
+  git add bad.txt
+  git commit -m "add bad pointer"
+  bad_commit="$(git rev-parse HEAD)"
+
+  git lfs verify-history > ../verify.log 2>&1 && exit 1
+  cat ../verify.log
+
+  grep "Commit $bad_commit:" ../verify.log
+  grep "  bad.txt ($oid): size 100 in the pointer, but 8 in other pointers to the same object" ../verify.log
+  grep "  a.dat ($oid): size 8 in the pointer, but 100 in other pointers to the same object" ../verify.log
+  grep "Git LFS verify-history: 1 of 1 objects failed verification" ../verify.log
+)
+end_test
@@ -0,0 +1,132 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "verify-manifest"
+(
+  set -e
+
+  reponame="verify-manifest"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git config lfs.integritymanifest true
+  git lfs track "*.dat"
+
+  contents_a="a"
+  contents_a_oid="$(calc_oid "$contents_a")"
+  contents_b="b"
+  contents_b_oid="$(calc_oid "$contents_b")"
+
+  printf "$contents_a" > a.dat
+  printf "$contents_b" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "add files"
+  git push origin master
+
+  grep "$contents_a_oid 1 " .git/lfs/integrity
+  grep "$contents_b_oid 1 " .git/lfs/integrity
+
+  git lfs verify-manifest 2>&1 | tee verify.log
+  grep "Git LFS verify-manifest OK (2 objects)" verify.log
+
+  # downloaded objects are recorded too
+  rm -rf .git/lfs/objects .git/lfs/integrity
+  git lfs fetch
+  grep "$contents_a_oid 1 " .git/lfs/integrity
+  git lfs verify-manifest --rehash 2>&1 | tee verify.log
+  grep "Git LFS verify-manifest OK (2 objects)" verify.log
+
+  # an object modified in place with the same size is only caught by --rehash
+  a_path=".git/lfs/objects/${contents_a_oid:0:2}/${contents_a_oid:2:2}/$contents_a_oid"
+  chmod u+w "$a_path"
+  printf "c" > "$a_path"
+  touch -d "1999-01-01 00:00:00" "$a_path"
+
+  git lfs verify-manifest
+  set +e
+  git lfs verify-manifest --rehash 2>&1 | tee verify.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" -ne 0 ]
+  grep "Object $contents_a_oid is corrupt" verify.log
+
+  # modification after the object was written
+  touch "$a_path"
+  set +e
+  git lfs verify-manifest 2>&1 | tee verify.log
+  res=${PIPESTATUS[0]}
+  set -e
+  [ "$res" -ne 0 ]
+  grep "Object $contents_a_oid was modified" verify.log
+
+  rm "$a_path"
+  set +e
+  git lfs verify-manifest 2>&1 | tee verify.log
+  set -e
+  grep "Object $contents_a_oid is missing" verify.log
+  grep "1 of 2 objects failed verification" verify.log
+)
+end_test
+
+begin_test "verify-manifest with a release manifest"
+(
+  set -e
+
+  reponame="verify-manifest-release"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+
+  contents_a="a"
+  contents_a_oid="$(calc_oid "$contents_a")"
+  contents_b="b"
+  contents_b_oid="$(calc_oid "$contents_b")"
+
+  printf "$contents_a" > a.dat
+  printf "$contents_b" > b.dat
+  git add .gitattributes a.dat b.dat
+  git commit -m "add files"
+  git tag v1.0
+
+  printf "# release v1.0\n$contents_a_oid 1 a.dat\n$contents_b_oid 1 b.dat\n" > release.manifest
+
+  git lfs verify-manifest --ref v1.0 release.manifest 2>&1 | tee verify.log
+  grep "Git LFS verify-manifest OK (2 objects in release.manifest)" verify.log
+
+  # the signature command is given the manifest, and must succeed
+  git lfs verify-manifest --signature-command "grep -q $contents_a_oid" release.manifest
+  git lfs verify-manifest --signature-command "false" release.manifest > verify.log 2>&1 && exit 1
+  grep "the signature of release.manifest could not be verified" verify.log
+
+  # the entries checked are those which were verified, even if the manifest
+  # is changed afterwards
+  cp release.manifest signed.manifest
+  printf "#!/bin/sh\nprintf 'not a manifest\\n' > release.manifest\n" > swap.sh
+  chmod +x swap.sh
+  git lfs verify-manifest --signature-command "./swap.sh" release.manifest 2>&1 | tee verify.log
+  grep "Git LFS verify-manifest OK (2 objects in release.manifest)" verify.log
+  mv signed.manifest release.manifest
+
+  # files in the ref which the manifest does not list
+  printf "$contents_a_oid 1 a.dat\n" > partial.manifest
+  git lfs verify-manifest --ref v1.0 partial.manifest > verify.log 2>&1 && exit 1
+  grep "Object $contents_b_oid (b.dat) in v1.0 is not in the manifest" verify.log
+  grep "1 problem(s) found" verify.log
+
+  # objects which are corrupt or missing
+  a_path=".git/lfs/objects/${contents_a_oid:0:2}/${contents_a_oid:2:2}/$contents_a_oid"
+  chmod u+w "$a_path"
+  printf "c" > "$a_path"
+  rm ".git/lfs/objects/${contents_b_oid:0:2}/${contents_b_oid:2:2}/$contents_b_oid"
+  git lfs verify-manifest release.manifest > verify.log 2>&1 && exit 1
+  grep "Object $contents_a_oid (a.dat) is corrupt" verify.log
+  grep "Object $contents_b_oid (b.dat) is missing" verify.log
+  grep "2 problem(s) found" verify.log
+
+  printf "not a manifest\n" > bad.manifest
+  git lfs verify-manifest bad.manifest > verify.log 2>&1 && exit 1
+  grep "release manifest line 1" verify.log
+)
+end_test
@@ -0,0 +1,63 @@
+#!/usr/bin/env bash
+
+. "test/testlib.sh"
+
+begin_test "verify-pointers"
+(
+  set -e
+
+  reponame="verify-pointers"
+  git init "$reponame"
+  cd "$reponame"
+
+  git config lfs.debug.pointerchecksums true
+  git lfs track "*.dat"
+
+  contents_a="a"
+  contents_a_oid="$(calc_oid "$contents_a")"
+  contents_b="b"
+
+  printf "$contents_a" > a.dat
+  printf "$contents_b" > "b c.dat"
+  git add .gitattributes a.dat "b c.dat"
+  git commit -m "add files"
+
+  a_blob="$(git rev-parse HEAD:a.dat)"
+  grep "^$a_blob $contents_a_oid [0-9]* a.dat$" .git/lfs/pointer-checksums
+  grep " b c.dat$" .git/lfs/pointer-checksums
+  [ "$(grep -c .gitattributes .git/lfs/pointer-checksums)" -eq 0 ]
+
+  git lfs verify-pointers 2>&1 | tee verify.log
+  grep "Git LFS verify-pointers OK (2 files)" verify.log
+
+  # a pointer Git stored differently from what clean wrote
+  corrupt_blob="$(git show HEAD:a.dat | sed -e "s/size 1/size 2/" | git hash-object -w --stdin)"
+  git update-index --cacheinfo 100644 "$corrupt_blob" a.dat
+  git commit -m "corrupt a.dat"
+
+  git lfs verify-pointers HEAD~1
+  git lfs verify-pointers > verify.log 2>&1 && exit 1
+  cat verify.log
+  grep "Pointer for a.dat is blob $corrupt_blob, but the clean filter last wrote blob $a_blob ($contents_a_oid)" verify.log
+  grep "Git LFS verify-pointers: 1 of 2 files failed verification" verify.log
+)
+end_test
+
+begin_test "verify-pointers: off by default"
+(
+  set -e
+
+  reponame="verify-pointers-off"
+  git init "$reponame"
+  cd "$reponame"
+
+  git lfs track "*.dat"
+  printf "a" > a.dat
+  git add .gitattributes a.dat
+  git commit -m "add a.dat"
+
+  [ ! -e .git/lfs/pointer-checksums ]
+  git lfs verify-pointers 2>&1 | tee verify.log
+  grep "Git LFS verify-pointers OK (0 files)" verify.log
+)
+end_test
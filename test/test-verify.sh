@@ -123,3 +123,98 @@ begin_test "verify with retries (bad .gitconfig)"
   [ "2" -eq "$(grep -c "verify $contents_short_oid attempt" push.log)" ]
 )
 end_test
+
+begin_test "verify download with retries"
+(
+  set -e
+
+  reponame="download-verify-fail-2-times"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  git add .gitattributes
+  git commit -m "initial commit"
+
+  contents="send-verify-action"
+  contents_oid="$(calc_oid "$contents")"
+  contents_short_oid="$(echo "$contents_oid" | head -c 7)"
+  printf "$contents" > a.dat
+
+  git add a.dat
+  git commit -m "add a.dat"
+  git push origin master
+
+  rm -rf .git/lfs/objects
+  GIT_TRACE=1 git lfs fetch 2>&1 | tee fetch.log
+  [ "0" -eq "${PIPESTATUS[0]}" ]
+
+  [ "2" -eq "$(grep -c "verify $contents_short_oid attempt" fetch.log)" ]
+  assert_local_object "$contents_oid" "${#contents}"
+)
+end_test
+
+begin_test "verify download with retries (retries transfer)"
+(
+  set -e
+
+  reponame="download-verify-fail-4-times"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  git add .gitattributes
+  git commit -m "initial commit"
+
+  contents="send-verify-action"
+  contents_oid="$(calc_oid "$contents")"
+  contents_short_oid="$(echo "$contents_oid" | head -c 7)"
+  printf "$contents" > a.dat
+
+  git add a.dat
+  git commit -m "add a.dat"
+  git config lfs.transfer.maxverifies 4
+  git push origin master
+  git config --unset lfs.transfer.maxverifies
+
+  rm -rf .git/lfs/objects
+  GIT_TRACE=1 git lfs fetch 2>&1 | tee fetch.log
+  [ "0" -eq "${PIPESTATUS[0]}" ]
+
+  # The first transfer fails after three verify requests, and the retry only
+  # repeats the verify request, rather than the download.
+  [ "4" -eq "$(grep -c "verify $contents_short_oid attempt" fetch.log)" ]
+  grep "already downloaded; verifying" fetch.log
+  assert_local_object "$contents_oid" "${#contents}"
+)
+end_test
+
+begin_test "verify download with retries (insufficient retries)"
+(
+  set -e
+
+  reponame="download-verify-fail-10-times"
+  setup_remote_repo "$reponame"
+  clone_repo "$reponame" "$reponame"
+
+  git lfs track "*.dat"
+  git add .gitattributes
+  git commit -m "initial commit"
+
+  contents="send-verify-action"
+  contents_oid="$(calc_oid "$contents")"
+  printf "$contents" > a.dat
+
+  git add a.dat
+  git commit -m "add a.dat"
+  git config lfs.transfer.maxverifies 10
+  git push origin master
+  git config --unset lfs.transfer.maxverifies
+
+  rm -rf .git/lfs/objects
+  git -c lfs.transfer.maxretries=1 lfs fetch > fetch.log 2>&1 && exit 1
+  grep "Unable to verify download of $contents_oid" fetch.log
+
+  refute_local_object "$contents_oid"
+)
+end_test
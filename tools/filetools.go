@@ -7,6 +7,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
@@ -62,6 +63,83 @@ func ResolveSymlinks(path string) string {
 	return path
 }
 
+// RenameFile moves srcfile to destfile, replacing destfile if it exists. When
+// they are on different filesystems, which rename(2) cannot move between,
+// srcfile is copied to a temporary file beside destfile, which is then renamed
+// over it, so that destfile is still replaced atomically, and srcfile is only
+// removed once it has been. If the copy fails, srcfile is left in place.
+func RenameFile(srcfile, destfile string) error {
+	err := os.Rename(srcfile, destfile)
+	if err == nil || !isCrossDeviceError(err) {
+		return err
+	}
+
+	if cerr := copyFileAcross(srcfile, destfile); cerr != nil {
+		return fmt.Errorf("cannot copy %q to %q on another filesystem: %v", srcfile, destfile, cerr)
+	}
+	if rerr := os.Remove(srcfile); rerr != nil && !os.IsNotExist(rerr) {
+		return rerr
+	}
+	return nil
+}
+
+// copyFileAcross copies srcfile to destfile through a temporary file in the
+// directory of destfile, with the permissions of srcfile. The temporary file's
+// name starts with a dot, so that it is never mistaken for an object while it
+// is being written.
+func copyFileAcross(srcfile, destfile string) error {
+	src, err := os.Open(srcfile)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	stat, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(destfile), "."+filepath.Base(destfile)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		return err
+	}
+	// Make sure the contents are on disk before they replace destfile,
+	// since the original is about to be removed.
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), stat.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), destfile)
+}
+
+// LinkOrCopyFile makes destfile a hard link to srcfile, or, if they cannot be
+// linked, such as when they are on different filesystems, a copy of it. Either
+// way, destfile is replaced atomically, so that it is never seen partly
+// written, and srcfile is left in place.
+func LinkOrCopyFile(srcfile, destfile string) error {
+	tmp := filepath.Join(filepath.Dir(destfile), fmt.Sprintf(".%s.%d.link", filepath.Base(destfile), os.Getpid()))
+	if err := os.Link(srcfile, tmp); err != nil {
+		return copyFileAcross(srcfile, destfile)
+	}
+	if err := os.Rename(tmp, destfile); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
 // RenameFileCopyPermissions moves srcfile to destfile, replacing destfile if
 // necessary and also copying the permissions of destfile if it already exists
 func RenameFileCopyPermissions(srcfile, destfile string) error {
@@ -76,12 +154,28 @@ func RenameFileCopyPermissions(srcfile, destfile string) error {
 		}
 	}
 
-	if err := os.Rename(srcfile, destfile); err != nil {
+	if err := RenameFile(srcfile, destfile); err != nil {
 		return fmt.Errorf("cannot replace %q with %q: %v", destfile, srcfile, err)
 	}
 	return nil
 }
 
+// ExpandPath returns "path" with a leading "~" replaced by the home directory
+// "home", as Git does for path settings. "path" is returned as it is if it
+// does not start with "~" or "~/", or if "home" is empty.
+func ExpandPath(path, home string) string {
+	if len(home) == 0 {
+		return path
+	}
+	if path == "~" {
+		return home
+	}
+	if strings.HasPrefix(path, "~/") {
+		return filepath.Join(home, path[2:])
+	}
+	return path
+}
+
 // CleanPaths splits the given `paths` argument by the delimiter argument, and
 // then "cleans" that path according to the path.Clean function (see
 // https://golang.org/pkg/path#Clean).
@@ -0,0 +1,15 @@
+// +build !windows
+
+package tools
+
+import (
+	"os"
+	"syscall"
+)
+
+// isCrossDeviceError returns whether "err" was returned by os.Rename because
+// its source and destination are on different filesystems.
+func isCrossDeviceError(err error) bool {
+	lerr, ok := err.(*os.LinkError)
+	return ok && lerr.Err == syscall.EXDEV
+}
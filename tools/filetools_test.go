@@ -11,6 +11,7 @@ import (
 
 	"github.com/git-lfs/git-lfs/subprocess"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCleanPathsCleansPaths(t *testing.T) {
@@ -25,6 +26,121 @@ func TestCleanPathsReturnsNoResultsWhenGivenNoPaths(t *testing.T) {
 	assert.Empty(t, cleaned)
 }
 
+func TestExpandPath(t *testing.T) {
+	home := filepath.Join("home", "user")
+
+	assert.Equal(t, home, ExpandPath("~", home))
+	assert.Equal(t, filepath.Join(home, "mirror"), ExpandPath("~/mirror", home))
+	assert.Equal(t, "~other/mirror", ExpandPath("~other/mirror", home))
+	assert.Equal(t, "/srv/mirror", ExpandPath("/srv/mirror", home))
+	assert.Equal(t, "~/mirror", ExpandPath("~/mirror", ""))
+}
+
+func TestRenameFileReplacesDestination(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rename-file")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+	require.Nil(t, ioutil.WriteFile(src, []byte("new"), 0644))
+	require.Nil(t, ioutil.WriteFile(dst, []byte("old"), 0644))
+
+	require.Nil(t, RenameFile(src, dst))
+
+	by, err := ioutil.ReadFile(dst)
+	require.Nil(t, err)
+	assert.Equal(t, "new", string(by))
+	_, err = os.Stat(src)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRenameFileAcrossFilesystems(t *testing.T) {
+	// /dev/shm is a tmpfs on most Linux systems, so moving a file from it
+	// to the usual temporary directory crosses filesystems.
+	if _, err := os.Stat("/dev/shm"); err != nil {
+		t.Skip("no /dev/shm")
+	}
+
+	srcDir, err := ioutil.TempDir("/dev/shm", "rename-file")
+	if err != nil {
+		t.Skip("cannot write to /dev/shm")
+	}
+	defer os.RemoveAll(srcDir)
+
+	dstDir, err := ioutil.TempDir("", "rename-file")
+	require.Nil(t, err)
+	defer os.RemoveAll(dstDir)
+
+	src := filepath.Join(srcDir, "src")
+	dst := filepath.Join(dstDir, "dst")
+	require.Nil(t, ioutil.WriteFile(src, []byte("contents"), 0640))
+
+	require.Nil(t, RenameFile(src, dst))
+
+	by, err := ioutil.ReadFile(dst)
+	require.Nil(t, err)
+	assert.Equal(t, "contents", string(by))
+	_, err = os.Stat(src)
+	assert.True(t, os.IsNotExist(err))
+
+	// Only the destination is left behind.
+	entries, err := ioutil.ReadDir(dstDir)
+	require.Nil(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "dst", entries[0].Name())
+}
+
+func TestCopyFileAcrossKeepsMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rename-file")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+	require.Nil(t, ioutil.WriteFile(src, []byte("contents"), 0600))
+
+	require.Nil(t, copyFileAcross(src, dst))
+
+	by, err := ioutil.ReadFile(dst)
+	require.Nil(t, err)
+	assert.Equal(t, "contents", string(by))
+
+	if runtime.GOOS != "windows" {
+		stat, err := os.Stat(dst)
+		require.Nil(t, err)
+		assert.Equal(t, os.FileMode(0600), stat.Mode().Perm())
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	require.Nil(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestLinkOrCopyFileReplacesDestination(t *testing.T) {
+	dir, err := ioutil.TempDir("", "link-or-copy-file")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+	require.Nil(t, ioutil.WriteFile(src, []byte("new"), 0644))
+	require.Nil(t, ioutil.WriteFile(dst, []byte("old"), 0644))
+
+	require.Nil(t, LinkOrCopyFile(src, dst))
+
+	by, err := ioutil.ReadFile(dst)
+	require.Nil(t, err)
+	assert.Equal(t, "new", string(by))
+
+	_, err = os.Stat(src)
+	assert.Nil(t, err)
+
+	entries, err := ioutil.ReadDir(dir)
+	require.Nil(t, err)
+	assert.Len(t, entries, 2)
+}
+
 func TestFastWalkBasic(t *testing.T) {
 	rootDir, err := ioutil.TempDir(os.TempDir(), "GitLfsTestFastWalkBasic")
 	if err != nil {
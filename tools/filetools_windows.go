@@ -0,0 +1,19 @@
+// +build windows
+
+package tools
+
+import (
+	"os"
+	"syscall"
+)
+
+// errorNotSameDevice is ERROR_NOT_SAME_DEVICE, which MoveFileEx returns when
+// asked to move a file to another volume without copying it.
+const errorNotSameDevice syscall.Errno = 17
+
+// isCrossDeviceError returns whether "err" was returned by os.Rename because
+// its source and destination are on different volumes.
+func isCrossDeviceError(err error) bool {
+	lerr, ok := err.(*os.LinkError)
+	return ok && lerr.Err == errorNotSameDevice
+}
@@ -0,0 +1,7 @@
+package tools
+
+import "github.com/git-lfs/git-lfs/errors"
+
+// ErrFreeSpaceUnknown is returned by FreeSpace on platforms where the free
+// space of a volume cannot be found.
+var ErrFreeSpaceUnknown = errors.New("the free space of a volume cannot be found on this platform")
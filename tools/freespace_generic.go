@@ -0,0 +1,9 @@
+// +build !linux,!darwin,!freebsd,!windows
+
+package tools
+
+// FreeSpace returns ErrFreeSpaceUnknown, since the free space of a volume
+// cannot be found on this platform.
+func FreeSpace(path string) (uint64, error) {
+	return 0, ErrFreeSpaceUnknown
+}
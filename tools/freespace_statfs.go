@@ -0,0 +1,18 @@
+// +build linux darwin freebsd
+
+package tools
+
+import (
+	"os"
+	"syscall"
+)
+
+// FreeSpace returns how many bytes can be written to the volume which holds
+// "path" by the current user.
+func FreeSpace(path string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, &os.PathError{Op: "statfs", Path: path, Err: err}
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
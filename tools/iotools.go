@@ -40,9 +40,34 @@ func CopyWithCallback(writer io.Writer, reader io.Reader, totalSize int64, cb pr
 	return io.Copy(writer, cbReader)
 }
 
+var (
+	// lfsContentHash is the function used by NewLfsContentHash to create
+	// new hash.Hash instances. It is replaced by SetLfsContentHash.
+	lfsContentHash = sha256.New
+)
+
 // Get a new Hash instance of the type used to hash LFS content
 func NewLfsContentHash() hash.Hash {
-	return sha256.New()
+	return lfsContentHash()
+}
+
+// SetLfsContentHash replaces the implementation of the hash used for LFS
+// content with the one returned by "fn". This allows an accelerated SHA-256
+// implementation to be used (for example, one selected by a build tag or by
+// detecting CPU features at runtime) in place of the one from crypto/sha256,
+// without changing any callers of NewLfsContentHash.
+//
+// The implementation given MUST compute SHA-256 digests, otherwise the OIDs it
+// produces will not match those of other clients. If "fn" is nil, the default
+// implementation is restored.
+//
+// SetLfsContentHash is not safe to call concurrently with NewLfsContentHash,
+// and so should be called during initialization, before any content is hashed.
+func SetLfsContentHash(fn func() hash.Hash) {
+	if fn == nil {
+		fn = sha256.New
+	}
+	lfsContentHash = fn
 }
 
 // HashingReader wraps a reader and calculates the hash of the data as it is read
@@ -2,7 +2,10 @@ package tools_test
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"hash"
 	"io"
+	"io/ioutil"
 	"testing"
 
 	"github.com/git-lfs/git-lfs/errors"
@@ -74,3 +77,66 @@ type ErrReader struct {
 func (e *ErrReader) Read(p []byte) (n int, err error) {
 	return 0, e.err
 }
+
+type countingHash struct {
+	hash.Hash
+	writes int
+}
+
+func (h *countingHash) Write(p []byte) (int, error) {
+	h.writes++
+	return h.Hash.Write(p)
+}
+
+func TestSetLfsContentHashReplacesImplementation(t *testing.T) {
+	var created []*countingHash
+	tools.SetLfsContentHash(func() hash.Hash {
+		h := &countingHash{Hash: sha256.New()}
+		created = append(created, h)
+		return h
+	})
+	defer tools.SetLfsContentHash(nil)
+
+	r := tools.NewHashingReader(bytes.NewBufferString("hello"))
+	_, err := ioutil.ReadAll(r)
+
+	assert.Nil(t, err)
+	assert.Len(t, created, 1)
+	assert.NotZero(t, created[0].writes)
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", r.Hash())
+}
+
+func TestSetLfsContentHashNilRestoresDefault(t *testing.T) {
+	tools.SetLfsContentHash(func() hash.Hash { return sha256.New224() })
+	tools.SetLfsContentHash(nil)
+
+	assert.Equal(t, sha256.Size, tools.NewLfsContentHash().Size())
+}
+
+// benchmarkContentHash hashes 64 MiB of data per iteration using hashes
+// created by "fn", reporting throughput in MB/s.
+func benchmarkContentHash(b *testing.B, fn func() hash.Hash) {
+	buf := make([]byte, 1024*1024)
+	for i := range buf {
+		buf[i] = byte(i)
+	}
+
+	b.SetBytes(int64(len(buf)) * 64)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		h := fn()
+		for j := 0; j < 64; j++ {
+			h.Write(buf)
+		}
+		h.Sum(nil)
+	}
+}
+
+func BenchmarkLfsContentHash(b *testing.B) {
+	benchmarkContentHash(b, tools.NewLfsContentHash)
+}
+
+func BenchmarkStdlibSHA256(b *testing.B) {
+	benchmarkContentHash(b, sha256.New)
+}
@@ -0,0 +1,184 @@
+package tools
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/rubyist/tracerx"
+)
+
+var (
+	// lockedAppenderTimeout is how long a LockedAppender waits for another
+	// writer to release its lock before giving up.
+	lockedAppenderTimeout = 10 * time.Second
+	// lockedAppenderStaleAge is how old a lock has to be before it is
+	// taken to have been left behind by a writer which died holding it.
+	lockedAppenderStaleAge = 30 * time.Second
+	// lockedAppenderRetryDelay is how long a LockedAppender waits between
+	// attempts to take its lock.
+	lockedAppenderRetryDelay = 5 * time.Millisecond
+
+	// lockedAppenderMus serializes writers to the same path in this
+	// process, keyed by that path, so that they never contend for its lock
+	// file.
+	lockedAppenderMus   = make(map[string]*sync.Mutex)
+	lockedAppenderMusMu sync.Mutex
+
+	// lockedAppenderSeq tells apart the lock files, and the names stale ones
+	// are moved to, of writers in this process.
+	lockedAppenderSeq uint64
+)
+
+// LockedAppender appends to a file which several Git LFS processes in the same
+// repository may write at once, such as the integrity manifest. Each write holds
+// a lock file beside it, "<path>.lock", which is created exclusively, as Git
+// does for its own files, so that entries from concurrent writers are never
+// interleaved, and read-then-write updates do not race. Writers in the same
+// process are serialized by a mutex before they take the lock.
+type LockedAppender struct {
+	path string
+}
+
+// NewLockedAppender returns a LockedAppender for the file "path", which is
+// created, along with its directory, when it is first written.
+func NewLockedAppender(path string) *LockedAppender {
+	return &LockedAppender{path: path}
+}
+
+// Append writes "by" to the end of the file in one write, while holding its
+// lock.
+func (a *LockedAppender) Append(by []byte) error {
+	return a.Locked(func(f *os.File) error {
+		_, err := f.Write(by)
+		return err
+	})
+}
+
+// Appendf formats according to "format" and appends the result, like Append.
+func (a *LockedAppender) Appendf(format string, args ...interface{}) error {
+	return a.Append([]byte(fmt.Sprintf(format, args...)))
+}
+
+// Locked takes the lock, opens the file for appending, and calls "fn" with it.
+// Other writers wait until "fn" returns, so it may read the file before
+// deciding what to append.
+func (a *LockedAppender) Locked(fn func(f *os.File) error) error {
+	if err := os.MkdirAll(filepath.Dir(a.path), 0755); err != nil {
+		return err
+	}
+
+	mu := lockedAppenderMutex(a.path)
+	mu.Lock()
+	defer mu.Unlock()
+
+	unlock, err := a.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, err := os.OpenFile(a.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+
+	err = fn(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// lockedAppenderMutex returns the mutex which serializes writers to "path" in
+// this process.
+func lockedAppenderMutex(path string) *sync.Mutex {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	lockedAppenderMusMu.Lock()
+	defer lockedAppenderMusMu.Unlock()
+
+	mu, ok := lockedAppenderMus[path]
+	if !ok {
+		mu = new(sync.Mutex)
+		lockedAppenderMus[path] = mu
+	}
+	return mu
+}
+
+// lockedAppenderToken returns a name which no other writer, in this process or
+// any other, uses at the same time.
+func lockedAppenderToken() string {
+	return fmt.Sprintf("%d.%d", os.Getpid(), atomic.AddUint64(&lockedAppenderSeq, 1))
+}
+
+func (a *LockedAppender) lock() (func(), error) {
+	lockPath := a.path + ".lock"
+	deadline := time.Now().Add(lockedAppenderTimeout)
+	token := lockedAppenderToken()
+
+	for {
+		f, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			fmt.Fprintf(f, "%s\n", token)
+			f.Close()
+			return func() { unlockLockedAppender(lockPath, token) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if stat, serr := os.Stat(lockPath); serr == nil && time.Since(stat.ModTime()) > lockedAppenderStaleAge {
+			breakStaleLock(lockPath)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, errors.Errorf("timed out waiting for %s; if no other Git LFS process is running, remove it", lockPath)
+		}
+		time.Sleep(lockedAppenderRetryDelay)
+	}
+}
+
+// unlockLockedAppender removes the lock file at "lockPath", unless it is no
+// longer the one holding "token", as when it was broken for being stale and
+// taken by another writer since.
+func unlockLockedAppender(lockPath, token string) {
+	by, err := ioutil.ReadFile(lockPath)
+	if err != nil || strings.TrimSpace(string(by)) != token {
+		tracerx.Printf("lfs: lock %s is no longer held", lockPath)
+		return
+	}
+	os.Remove(lockPath)
+}
+
+// breakStaleLock removes the lock file at "lockPath", which was found to be
+// stale. Another writer may have broken it and taken the lock since, so it is
+// first moved out of the way under a name no other writer uses, and only
+// removed if the file moved is still stale. Otherwise, it is a live lock, and
+// is put back.
+func breakStaleLock(lockPath string) {
+	stalePath := lockPath + ".stale." + lockedAppenderToken()
+	if err := os.Rename(lockPath, stalePath); err != nil {
+		return
+	}
+	defer os.Remove(stalePath)
+
+	if stat, err := os.Stat(stalePath); err == nil && time.Since(stat.ModTime()) > lockedAppenderStaleAge {
+		tracerx.Printf("lfs: removing stale lock %s", lockPath)
+		return
+	}
+
+	// Linking fails rather than replace a lock taken in the meantime.
+	if err := os.Link(stalePath, lockPath); err != nil {
+		tracerx.Printf("lfs: could not put back lock %s: %s", lockPath, err)
+	}
+}
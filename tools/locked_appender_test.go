@@ -0,0 +1,176 @@
+package tools
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockedAppenderConcurrentWriters(t *testing.T) {
+	dir, err := ioutil.TempDir("", "locked-appender")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "sub", "log")
+
+	// Each writer has its own LockedAppender, sharing nothing but the
+	// file, as separate processes would. Entries are large, so that
+	// unlocked writes would be likely to interleave.
+	const writers, entries = 8, 25
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			a := NewLockedAppender(path)
+			for e := 0; e < entries; e++ {
+				line := fmt.Sprintf("%d %d %s\n", w, e, strings.Repeat(strconv.Itoa(w), 64*1024))
+				assert.Nil(t, a.Append([]byte(line)))
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	f, err := os.Open(path)
+	require.Nil(t, err)
+	defer f.Close()
+
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 128*1024), 128*1024)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		require.Len(t, fields, 3)
+		assert.Equal(t, strings.Repeat(fields[0], 64*1024), fields[2])
+		seen[fields[0]+" "+fields[1]] = true
+	}
+	require.Nil(t, scanner.Err())
+	assert.Len(t, seen, writers*entries)
+
+	_, err = os.Stat(path + ".lock")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestLockedAppenderSerializesReadThenWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "locked-appender")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "counter")
+
+	// Each writer appends the number of lines it found, so the lines
+	// only count up without gaps or repeats if no two writers held the
+	// lock at once.
+	const writers = 20
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := NewLockedAppender(path).Locked(func(f *os.File) error {
+				by, err := ioutil.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				_, err = fmt.Fprintf(f, "%d\n", strings.Count(string(by), "\n"))
+				return err
+			})
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+
+	by, err := ioutil.ReadFile(path)
+	require.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(by)), "\n")
+	require.Len(t, lines, writers)
+	for i, line := range lines {
+		assert.Equal(t, strconv.Itoa(i), line)
+	}
+}
+
+func TestLockedAppenderRemovesStaleLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "locked-appender")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "log")
+	require.Nil(t, ioutil.WriteFile(path+".lock", []byte("1\n"), 0644))
+	old := time.Now().Add(-2 * lockedAppenderStaleAge)
+	require.Nil(t, os.Chtimes(path+".lock", old, old))
+
+	require.Nil(t, NewLockedAppender(path).Append([]byte("entry\n")))
+
+	by, err := ioutil.ReadFile(path)
+	require.Nil(t, err)
+	assert.Equal(t, "entry\n", string(by))
+}
+
+func TestLockedAppenderTimesOut(t *testing.T) {
+	defer func(d time.Duration) { lockedAppenderTimeout = d }(lockedAppenderTimeout)
+	lockedAppenderTimeout = 20 * time.Millisecond
+
+	dir, err := ioutil.TempDir("", "locked-appender")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "log")
+	require.Nil(t, ioutil.WriteFile(path+".lock", []byte("1\n"), 0644))
+
+	err = NewLockedAppender(path).Append([]byte("entry\n"))
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "timed out waiting for")
+
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestLockedAppenderKeepsLockTakenSinceFoundStale(t *testing.T) {
+	dir, err := ioutil.TempDir("", "locked-appender")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// The lock was found to be stale, but has been broken and taken
+	// afresh by another writer before this one breaks it.
+	lockPath := filepath.Join(dir, "log.lock")
+	require.Nil(t, ioutil.WriteFile(lockPath, []byte("1.1\n"), 0644))
+
+	breakStaleLock(lockPath)
+
+	by, err := ioutil.ReadFile(lockPath)
+	require.Nil(t, err)
+	assert.Equal(t, "1.1\n", string(by))
+
+	entries, err := ioutil.ReadDir(dir)
+	require.Nil(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestLockedAppenderUnlockLeavesAnotherWritersLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "locked-appender")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	lockPath := filepath.Join(dir, "log.lock")
+	require.Nil(t, ioutil.WriteFile(lockPath, []byte("1.2\n"), 0644))
+
+	unlockLockedAppender(lockPath, "1.1")
+
+	_, err = os.Stat(lockPath)
+	assert.Nil(t, err)
+
+	unlockLockedAppender(lockPath, "1.2")
+
+	_, err = os.Stat(lockPath)
+	assert.True(t, os.IsNotExist(err))
+}
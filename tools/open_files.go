@@ -0,0 +1,96 @@
+package tools
+
+import "sync"
+
+// openFiles limits how many files are held open at once by those who call
+// AcquireFiles before opening them.
+var openFiles = newFileLimiter(defaultMaxOpenFiles())
+
+// fileLimiter is a counting semaphore for open files, whose size can be
+// changed while it is in use.
+type fileLimiter struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	limit int
+	open  int
+}
+
+func newFileLimiter(limit int) *fileLimiter {
+	l := &fileLimiter{limit: limit}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// SetMaxOpenFiles limits how many files may be held open at once by callers
+// of AcquireFiles to "n". If "n" is zero or negative, the default is restored,
+// which leaves a quarter of the open file limit of the process, as given by
+// `ulimit -n`, for the network connections, pipes and other files which it
+// does not count, or no limit, where the process has none.
+func SetMaxOpenFiles(n int) {
+	if n <= 0 {
+		n = defaultMaxOpenFiles()
+	}
+
+	openFiles.mu.Lock()
+	openFiles.limit = n
+	openFiles.mu.Unlock()
+	openFiles.cond.Broadcast()
+}
+
+// MaxOpenFiles returns how many files may be held open at once by callers of
+// AcquireFiles, or 0, if there is no limit.
+func MaxOpenFiles() int {
+	openFiles.mu.Lock()
+	defer openFiles.mu.Unlock()
+	return openFiles.limit
+}
+
+// AcquireFiles blocks until "n" more files may be held open at once, without
+// going over the limit given by SetMaxOpenFiles, and returns a function which
+// gives them back, which must be called once they are closed, whether or not
+// opening them succeeded. Calling it more than once has no further effect.
+//
+// Files which are held open together should be acquired together, rather than
+// one after the other, which could leave each of several callers waiting for
+// the files the others hold.
+func AcquireFiles(n int) func() {
+	openFiles.acquire(n)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { openFiles.release(n) })
+	}
+}
+
+// acquire waits until "n" more files are allowed to be open, and counts them
+// as open. Callers asking for more than the limit would never be allowed them,
+// so they wait for every other file to be given back instead.
+func (l *fileLimiter) acquire(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for l.limit > 0 && l.open > 0 && l.open+n > l.limit {
+		l.cond.Wait()
+	}
+	l.open += n
+}
+
+func (l *fileLimiter) release(n int) {
+	l.mu.Lock()
+	l.open -= n
+	l.mu.Unlock()
+	l.cond.Broadcast()
+}
+
+// defaultMaxOpenFiles returns the limit AcquireFiles enforces unless
+// SetMaxOpenFiles is given another.
+func defaultMaxOpenFiles() int {
+	n := openFileLimit()
+	if n <= 0 {
+		return 0
+	}
+	if n -= n / 4; n < 1 {
+		n = 1
+	}
+	return n
+}
@@ -0,0 +1,9 @@
+// +build !linux,!darwin,!freebsd
+
+package tools
+
+// openFileLimit returns 0, since the number of files a process may have open
+// is not limited in a way that can be found on this platform.
+func openFileLimit() int {
+	return 0
+}
@@ -0,0 +1,23 @@
+// +build linux darwin freebsd
+
+package tools
+
+import (
+	"math"
+	"syscall"
+)
+
+// openFileLimit returns the soft limit on the number of files the process may
+// have open, or 0 if it has none, or one so large that it might as well be.
+func openFileLimit() int {
+	var rlim syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlim); err != nil {
+		return 0
+	}
+
+	cur := uint64(rlim.Cur)
+	if cur > math.MaxInt32 {
+		return 0
+	}
+	return int(cur)
+}
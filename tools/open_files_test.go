@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileLimiterBlocksOverLimit(t *testing.T) {
+	l := newFileLimiter(2)
+	l.acquire(2)
+
+	acquired := make(chan struct{})
+	go func() {
+		l.acquire(1)
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("acquired a file over the limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	l.release(1)
+
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("file was not acquired once one was given back")
+	}
+}
+
+func TestFileLimiterAllowsMoreThanLimitAlone(t *testing.T) {
+	l := newFileLimiter(1)
+	l.acquire(3)
+	l.release(3)
+
+	assert.Equal(t, 0, l.open)
+}
+
+func TestFileLimiterNeverExceedsLimit(t *testing.T) {
+	l := newFileLimiter(3)
+
+	var mu sync.Mutex
+	var most int
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			l.acquire(1)
+			mu.Lock()
+			l.mu.Lock()
+			if l.open > most {
+				most = l.open
+			}
+			l.mu.Unlock()
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+			l.release(1)
+		}()
+	}
+	wg.Wait()
+
+	assert.True(t, most <= 3, "held %d files open at once", most)
+	assert.Equal(t, 0, l.open)
+}
+
+func TestAcquireFilesReleasesOnce(t *testing.T) {
+	release := AcquireFiles(1)
+	release()
+	release()
+
+	openFiles.mu.Lock()
+	defer openFiles.mu.Unlock()
+	assert.Equal(t, 0, openFiles.open)
+}
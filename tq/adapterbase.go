@@ -1,9 +1,11 @@
 package tq
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"sync"
+	"sync/atomic"
 
 	"github.com/git-lfs/git-lfs/lfsapi"
 	"github.com/rubyist/tracerx"
@@ -27,6 +29,53 @@ type adapterBase struct {
 	jobWait *sync.WaitGroup
 	// WaitGroup to serialise the first transfer response to perform login if needed
 	authWait sync.WaitGroup
+	// active holds the transfers currently being processed by a worker,
+	// including those waiting for a pause to end before they start, keyed
+	// by OID
+	active   map[string]*activeTransfer
+	activeMu sync.Mutex
+	// pause, if not nil, is the gate which holds back workers from
+	// starting transfers while it is paused, and holdActive is whether it
+	// holds back the transfers already in flight as well.
+	pause      *pauseGate
+	holdActive bool
+}
+
+// activeTransfer is a transfer which is being processed by a worker, and which
+// may be cancelled by calling "cancel".
+type activeTransfer struct {
+	t *Transfer
+	// bytesSoFar is the number of bytes transferred so far, and must be
+	// accessed atomically.
+	bytesSoFar int64
+
+	// inFlight is whether the transfer has started, rather than waiting
+	// for a pause to end, and is guarded by the adapter's activeMu.
+	inFlight bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// ActiveTransfer describes a transfer which is currently in flight.
+type ActiveTransfer struct {
+	Name       string
+	Oid        string
+	Size       int64
+	BytesSoFar int64
+}
+
+// activeTransferAdapter is implemented by adapters which are able to list and
+// cancel their in-flight transfers.
+type activeTransferAdapter interface {
+	ActiveTransfers() []*ActiveTransfer
+	CancelTransfer(oid string) bool
+}
+
+// pausableAdapter is implemented by adapters which are able to stop starting
+// transfers, and to hold those in flight, while a queue is paused.
+type pausableAdapter interface {
+	setPauseGate(g *pauseGate, holdActive bool)
 }
 
 // transferImplementation must be implemented to provide the actual upload/download
@@ -53,6 +102,7 @@ func newAdapterBase(name string, dir Direction, ti transferImplementation) *adap
 		direction:    dir,
 		transferImpl: ti,
 		jobWait:      new(sync.WaitGroup),
+		active:       make(map[string]*activeTransfer),
 	}
 }
 
@@ -168,7 +218,20 @@ func (a *adapterBase) worker(workerNum int, ctx interface{}) {
 		if t.Size < 0 {
 			err = fmt.Errorf("Git LFS: object %q has invalid size (got: %d)", t.Oid, t.Size)
 		} else {
-			err = a.transferImpl.DoTransfer(ctx, t, a.cb, authCallback)
+			at := a.startTransfer(t)
+			if a.pause != nil {
+				// The transfer is recorded before waiting, so
+				// that it can be cancelled while paused.
+				a.pause.wait(at.ctx.Done())
+			}
+
+			if a.setInFlight(at) {
+				err = a.transferImpl.DoTransfer(ctx, t, a.transferCallback(at), authCallback)
+			}
+			if at.ctx.Err() != nil {
+				err = newTransferCancelledError(t.Name, t.Oid)
+			}
+			a.finishTransfer(at)
 		}
 
 		// Mark the job as completed, and alter all listeners
@@ -185,6 +248,109 @@ func (a *adapterBase) worker(workerNum int, ctx interface{}) {
 	a.workerWait.Done()
 }
 
+// startTransfer records "t" as being processed by a worker, until
+// finishTransfer is called, so that it can be cancelled. It is not in flight
+// until setInFlight is called.
+func (a *adapterBase) startTransfer(t *Transfer) *activeTransfer {
+	ctx, cancel := context.WithCancel(context.Background())
+	at := &activeTransfer{t: t, ctx: ctx, cancel: cancel}
+
+	a.activeMu.Lock()
+	a.active[t.Oid] = at
+	a.activeMu.Unlock()
+
+	return at
+}
+
+// setInFlight records "at" as being in flight, and returns true, unless it has
+// already been cancelled.
+func (a *adapterBase) setInFlight(at *activeTransfer) bool {
+	a.activeMu.Lock()
+	defer a.activeMu.Unlock()
+
+	if at.ctx.Err() != nil {
+		return false
+	}
+	at.inFlight = true
+	return true
+}
+
+func (a *adapterBase) finishTransfer(at *activeTransfer) {
+	a.activeMu.Lock()
+	delete(a.active, at.t.Oid)
+	a.activeMu.Unlock()
+
+	at.cancel()
+}
+
+// transferCallback returns a ProgressCallback which records the progress of
+// "at" before passing it along to the adapter's own callback, and which aborts
+// the copy in progress once "at" has been cancelled.
+func (a *adapterBase) transferCallback(at *activeTransfer) ProgressCallback {
+	return func(name string, totalSize, readSoFar int64, readSinceLast int) error {
+		atomic.StoreInt64(&at.bytesSoFar, readSoFar)
+		if a.holdActive && a.pause != nil {
+			// Hold the transfer between chunks while paused, but
+			// still let it be cancelled.
+			a.pause.wait(at.ctx.Done())
+		}
+		if at.ctx.Err() != nil {
+			return newTransferCancelledError(at.t.Name, at.t.Oid)
+		}
+
+		if a.cb != nil {
+			return a.cb(name, totalSize, readSoFar, readSinceLast)
+		}
+		return nil
+	}
+}
+
+// setPauseGate has workers wait on "g" before starting each transfer, and if
+// "holdActive" is true, has transfers in flight wait on it between chunks. It
+// must be called before Begin.
+func (a *adapterBase) setPauseGate(g *pauseGate, holdActive bool) {
+	a.pause = g
+	a.holdActive = holdActive
+}
+
+// ActiveTransfers returns the transfers which are currently in flight, in no
+// particular order. Those waiting for a pause to end are not included.
+func (a *adapterBase) ActiveTransfers() []*ActiveTransfer {
+	a.activeMu.Lock()
+	defer a.activeMu.Unlock()
+
+	transfers := make([]*ActiveTransfer, 0, len(a.active))
+	for _, at := range a.active {
+		if !at.inFlight {
+			continue
+		}
+		transfers = append(transfers, &ActiveTransfer{
+			Name:       at.t.Name,
+			Oid:        at.t.Oid,
+			Size:       at.t.Size,
+			BytesSoFar: atomic.LoadInt64(&at.bytesSoFar),
+		})
+	}
+	return transfers
+}
+
+// CancelTransfer aborts the in-flight transfer of the object given by "oid",
+// or the one waiting for a pause to end before it starts, freeing up the worker
+// processing it. The transfer finishes with a TransferCancelledError, and is
+// not retried. It returns false if no transfer of that object is in flight or
+// waiting.
+func (a *adapterBase) CancelTransfer(oid string) bool {
+	a.activeMu.Lock()
+	at, ok := a.active[oid]
+	a.activeMu.Unlock()
+
+	if ok {
+		a.Trace("xfer: adapter %q cancelling transfer of %q", a.Name(), oid)
+		at.cancel()
+	}
+	return ok
+}
+
 func (a *adapterBase) newHTTPRequest(method string, rel *Action) (*http.Request, error) {
 	req, err := http.NewRequest(method, rel.Href, nil)
 	if err != nil {
@@ -199,6 +365,15 @@ func (a *adapterBase) newHTTPRequest(method string, rel *Action) (*http.Request,
 }
 
 func (a *adapterBase) doHTTP(t *Transfer, req *http.Request) (*http.Response, error) {
+	a.activeMu.Lock()
+	at, ok := a.active[t.Oid]
+	a.activeMu.Unlock()
+
+	if ok {
+		// Abort the request if the transfer is cancelled.
+		req = req.WithContext(at.ctx)
+	}
+
 	if t.Authenticated {
 		return a.apiClient.Do(req)
 	}
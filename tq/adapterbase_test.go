@@ -0,0 +1,221 @@
+package tq
+
+import (
+	"testing"
+	"time"
+
+	"github.com/git-lfs/git-lfs/lfsapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingTransferImpl is a transferImplementation which reports progress
+// until its callback returns an error.
+type blockingTransferImpl struct {
+	started chan struct{}
+}
+
+func (i *blockingTransferImpl) WorkerStarting(workerNum int) (interface{}, error) {
+	return nil, nil
+}
+
+func (i *blockingTransferImpl) WorkerEnding(workerNum int, ctx interface{}) {}
+
+func (i *blockingTransferImpl) DoTransfer(ctx interface{}, t *Transfer, cb ProgressCallback, authOkFunc func()) error {
+	if authOkFunc != nil {
+		authOkFunc()
+	}
+
+	for read := int64(1); ; read++ {
+		if err := cb(t.Name, t.Size, read, 1); err != nil {
+			return err
+		}
+
+		if read == 1 {
+			close(i.started)
+		}
+	}
+}
+
+func TestAdapterBaseCancelsActiveTransfer(t *testing.T) {
+	impl := &blockingTransferImpl{started: make(chan struct{})}
+	a := newAdapterBase("blocking", Download, impl)
+
+	cli, err := lfsapi.NewClient(nil, nil)
+	require.Nil(t, err)
+	require.Nil(t, a.Begin(&adapterConfig{
+		apiClient:           cli,
+		concurrentTransfers: 1,
+	}, nil))
+
+	assert.False(t, a.CancelTransfer("oid"))
+
+	results := a.Add(&Transfer{Name: "a.dat", Oid: "oid", Size: 1024})
+	<-impl.started
+
+	active := a.ActiveTransfers()
+	require.Len(t, active, 1)
+	assert.Equal(t, "a.dat", active[0].Name)
+	assert.Equal(t, "oid", active[0].Oid)
+	assert.EqualValues(t, 1024, active[0].Size)
+	assert.True(t, active[0].BytesSoFar > 0)
+
+	assert.True(t, a.CancelTransfer("oid"))
+
+	res := <-results
+	if assert.NotNil(t, res.Error) {
+		assert.IsType(t, &TransferCancelledError{}, res.Error)
+	}
+	a.End()
+
+	assert.Empty(t, a.ActiveTransfers())
+}
+
+func TestTransferQueueCancelWithoutAdapter(t *testing.T) {
+	q := &TransferQueue{}
+
+	assert.Nil(t, q.ActiveTransfers())
+	assert.False(t, q.Cancel("oid"))
+}
+
+func TestAdapterBaseWaitsToStartTransfersWhilePaused(t *testing.T) {
+	impl := &blockingTransferImpl{started: make(chan struct{})}
+	a := newAdapterBase("blocking", Download, impl)
+
+	var gate pauseGate
+	gate.pause()
+	a.setPauseGate(&gate, false)
+
+	cli, err := lfsapi.NewClient(nil, nil)
+	require.Nil(t, err)
+	require.Nil(t, a.Begin(&adapterConfig{
+		apiClient:           cli,
+		concurrentTransfers: 1,
+	}, nil))
+
+	results := a.Add(&Transfer{Name: "a.dat", Oid: "oid", Size: 1024})
+
+	select {
+	case <-impl.started:
+		t.Fatal("transfer started while paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Empty(t, a.ActiveTransfers())
+
+	gate.resume()
+
+	select {
+	case <-impl.started:
+	case <-time.After(time.Second):
+		t.Fatal("transfer did not start once resumed")
+	}
+
+	assert.True(t, a.CancelTransfer("oid"))
+	<-results
+	a.End()
+}
+
+func TestAdapterBaseCancelsTransfersWaitingWhilePaused(t *testing.T) {
+	impl := &blockingTransferImpl{started: make(chan struct{})}
+	a := newAdapterBase("blocking", Download, impl)
+
+	var gate pauseGate
+	gate.pause()
+	a.setPauseGate(&gate, false)
+
+	cli, err := lfsapi.NewClient(nil, nil)
+	require.Nil(t, err)
+	require.Nil(t, a.Begin(&adapterConfig{
+		apiClient:           cli,
+		concurrentTransfers: 1,
+	}, nil))
+
+	results := a.Add(&Transfer{Name: "a.dat", Oid: "oid", Size: 1024})
+
+	// The worker takes the job before it waits on the gate.
+	deadline := time.Now().Add(time.Second)
+	for !a.CancelTransfer("oid") {
+		if time.Now().After(deadline) {
+			t.Fatal("transfer could not be cancelled while paused")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	select {
+	case res := <-results:
+		if assert.NotNil(t, res.Error) {
+			assert.IsType(t, &TransferCancelledError{}, res.Error)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("cancelled transfer did not finish while paused")
+	}
+
+	select {
+	case <-impl.started:
+		t.Fatal("cancelled transfer started")
+	default:
+	}
+
+	gate.resume()
+	a.End()
+	assert.Empty(t, a.ActiveTransfers())
+}
+
+func TestAdapterBaseHoldsActiveTransfersWhilePaused(t *testing.T) {
+	impl := &blockingTransferImpl{started: make(chan struct{})}
+	a := newAdapterBase("blocking", Download, impl)
+
+	var gate pauseGate
+	a.setPauseGate(&gate, true)
+
+	cli, err := lfsapi.NewClient(nil, nil)
+	require.Nil(t, err)
+	require.Nil(t, a.Begin(&adapterConfig{
+		apiClient:           cli,
+		concurrentTransfers: 1,
+	}, nil))
+
+	results := a.Add(&Transfer{Name: "a.dat", Oid: "oid", Size: 1024})
+	<-impl.started
+
+	gate.pause()
+	time.Sleep(10 * time.Millisecond)
+
+	held := a.ActiveTransfers()
+	require.Len(t, held, 1)
+	time.Sleep(50 * time.Millisecond)
+	active := a.ActiveTransfers()
+	require.Len(t, active, 1)
+	assert.Equal(t, held[0].BytesSoFar, active[0].BytesSoFar)
+
+	gate.resume()
+	time.Sleep(10 * time.Millisecond)
+	active = a.ActiveTransfers()
+	require.Len(t, active, 1)
+	assert.True(t, active[0].BytesSoFar > held[0].BytesSoFar)
+
+	// A held transfer can still be cancelled.
+	gate.pause()
+	assert.True(t, a.CancelTransfer("oid"))
+
+	res := <-results
+	if assert.NotNil(t, res.Error) {
+		assert.IsType(t, &TransferCancelledError{}, res.Error)
+	}
+	gate.resume()
+	a.End()
+}
+
+func TestTransferQueuePauseAndResume(t *testing.T) {
+	q := &TransferQueue{}
+
+	assert.False(t, q.Paused())
+	assert.False(t, q.Resume())
+
+	assert.True(t, q.Pause())
+	assert.False(t, q.Pause())
+	assert.True(t, q.Paused())
+
+	assert.True(t, q.Resume())
+	assert.False(t, q.Paused())
+}
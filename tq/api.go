@@ -1,6 +1,11 @@
 package tq
 
 import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/git-lfs/git-lfs/errors"
@@ -8,7 +13,37 @@ import (
 	"github.com/rubyist/tracerx"
 )
 
+var (
+	// batchRetryBaseDelay is the delay before the first retry of a failed
+	// batch API request by the default BackoffStrategy, which doubles after
+	// each attempt, up to batchRetryMaxDelay, unless the server sends a
+	// Retry-After header. A Retry-After header is not honored beyond
+	// batchRetryMaxDelay either.
+	batchRetryBaseDelay = time.Second
+	batchRetryMaxDelay  = 30 * time.Second
+
+	// streamBatchFlushDelay is how long StreamBatch holds a partial batch,
+	// waiting for more objects, before requesting it anyway.
+	streamBatchFlushDelay = 100 * time.Millisecond
+)
+
 type tqClient struct {
+	// maxRetries is the number of times a batch API request which fails
+	// with a transient error is retried.
+	maxRetries int
+	// cache answers batch API requests for objects which earlier responses
+	// described, if it is not nil.
+	cache *objectCache
+	// backoff decides how long to wait before each retry, or
+	// defaultBackoff() if it is nil.
+	backoff BackoffStrategy
+	// timeout is how long each attempt at a batch API request may take,
+	// including reading the response, or 0 if there is no limit of its own.
+	timeout time.Duration
+	// timeouts are the connect and header timeouts of batch API requests,
+	// which override those of the client for them.
+	timeouts lfsapi.Timeouts
+
 	*lfsapi.Client
 }
 
@@ -29,13 +64,93 @@ func Batch(m *Manifest, dir Direction, remote string, objects []*Transfer) (*Bat
 		return &BatchResponse{}, nil
 	}
 
-	return m.batchClient().Batch(remote, &batchRequest{
+	return batchWith(m.batchClient(), m, dir, remote, objects)
+}
+
+// batchWith is Batch, but makes the request with the client "c", rather than
+// the manifest's own.
+func batchWith(c *tqClient, m *Manifest, dir Direction, remote string, objects []*Transfer) (*BatchResponse, error) {
+	return c.Batch(remote, &batchRequest{
 		Operation:            dir.String(),
 		Objects:              objects,
 		TransferAdapterNames: m.GetAdapterNames(dir),
 	})
 }
 
+// BatchResult is an object sent by StreamBatch, as the server described it in a
+// batch API response, or with the error which the request for it failed with.
+type BatchResult struct {
+	Transfer *Transfer
+	// Adapter is the name of the transfer adapter which the server chose
+	// for the batch the object was in.
+	Adapter string
+	Err     error
+}
+
+// StreamBatch makes batch API requests for the objects received from
+// "objects", "batchSize" at a time, and sends each object in the responses on
+// the returned channel as soon as its batch is answered, so that callers can
+// start acting on the first objects while later batches are still being
+// requested. A partial batch is requested once no more objects have filled it
+// for a short while, so that objects are not held back by a slow producer. If
+// a request fails, each object in it is sent with the error. The
+// returned channel is closed once "objects" is closed and every batch has been
+// answered.
+func StreamBatch(m *Manifest, dir Direction, remote string, objects <-chan *Transfer, batchSize int) <-chan *BatchResult {
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	results := make(chan *BatchResult, batchSize)
+	go func() {
+		defer close(results)
+
+		pending := make([]*Transfer, 0, batchSize)
+		var flush <-chan time.Time
+		for {
+			select {
+			case t, ok := <-objects:
+				if !ok {
+					streamBatch(m, dir, remote, pending, results)
+					return
+				}
+
+				pending = append(pending, t)
+				if len(pending) == 1 {
+					flush = time.After(streamBatchFlushDelay)
+				}
+				if len(pending) < batchSize {
+					continue
+				}
+			case <-flush:
+			}
+
+			streamBatch(m, dir, remote, pending, results)
+			pending = make([]*Transfer, 0, batchSize)
+			flush = nil
+		}
+	}()
+	return results
+}
+
+func streamBatch(m *Manifest, dir Direction, remote string, objects []*Transfer, results chan<- *BatchResult) {
+	if len(objects) == 0 {
+		return
+	}
+
+	bRes, err := Batch(m, dir, remote, objects)
+	if err != nil {
+		for _, t := range objects {
+			results <- &BatchResult{Transfer: t, Err: err}
+		}
+		return
+	}
+
+	for _, t := range bRes.Objects {
+		results <- &BatchResult{Transfer: t, Adapter: bRes.TransferAdapterName}
+	}
+}
+
 func (c *tqClient) Batch(remote string, bReq *batchRequest) (*BatchResponse, error) {
 	bRes := &BatchResponse{}
 	if len(bReq.Objects) == 0 {
@@ -47,23 +162,76 @@ func (c *tqClient) Batch(remote string, bReq *batchRequest) (*BatchResponse, err
 	}
 
 	bRes.endpoint = c.Endpoints.Endpoint(bReq.Operation, remote)
-	requestedAt := time.Now()
 
-	req, err := c.NewRequest("POST", bRes.endpoint, "objects/batch", bReq)
-	if err != nil {
-		return nil, errors.Wrap(err, "batch request")
+	var cached []*Transfer
+	if c.cache != nil {
+		var rest []*Transfer
+		cached, rest, bRes.TransferAdapterName = c.cache.Lookup(remote, bReq)
+		if len(rest) == 0 {
+			tracerx.Printf("api: batch %d files answered from cache", len(cached))
+			bRes.Objects = cached
+			return bRes, nil
+		}
+
+		if len(cached) > 0 {
+			tracerx.Printf("api: %d of %d files answered from cache", len(cached), len(bReq.Objects))
+			bReq = &batchRequest{
+				Operation:            bReq.Operation,
+				Objects:              rest,
+				TransferAdapterNames: bReq.TransferAdapterNames,
+			}
+		}
 	}
 
-	tracerx.Printf("api: batch %d files", len(bReq.Objects))
+	var requestedAt time.Time
+	var res *http.Response
+	cancel := func() {}
+	for attempt := 1; ; attempt++ {
+		req, err := c.NewRequest("POST", bRes.endpoint, "objects/batch", bReq)
+		if err != nil {
+			return nil, errors.Wrap(err, "batch request")
+		}
+
+		tracerx.Printf("api: batch %d files", len(bReq.Objects))
+
+		requestedAt = time.Now()
+		req, cancel = c.withTimeouts(req)
+		req = c.LogRequest(req, "lfs.batch")
+		res, err = c.DoWithAuth(remote, req)
+		if err == nil {
+			break
+		}
+		if res != nil && res.Body != nil {
+			// Drain the body of a failed response so its connection can
+			// be reused by the next attempt.
+			io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+		}
+		cancel()
+
+		if req.Context().Err() == context.DeadlineExceeded {
+			err = errors.NewRetriableError(errors.Errorf("batch request timed out after %s", c.timeout))
+		}
 
-	req = c.LogRequest(req, "lfs.batch")
-	res, err := c.DoWithAuth(remote, req)
-	if err != nil {
 		tracerx.Printf("api error: %s", err)
-		return nil, errors.Wrap(err, "batch response")
+		if !isRetriableBatchError(res, err) {
+			return nil, errors.Wrap(err, "batch response")
+		}
+		if attempt > c.maxRetries {
+			if c.maxRetries > 0 {
+				return nil, errors.Wrapf(err, "batch negotiation failed after %d attempts", attempt)
+			}
+			return nil, errors.Wrap(err, "batch response")
+		}
+
+		delay := batchRetryDelay(res, attempt, c.backoff)
+		tracerx.Printf("api: retrying batch request in %s (retry %d of %d)", delay, attempt, c.maxRetries)
+		time.Sleep(delay)
 	}
 
-	if err := lfsapi.DecodeJSON(res, bRes); err != nil {
+	err := lfsapi.DecodeJSON(res, bRes)
+	cancel()
+	if err != nil {
 		return bRes, errors.Wrap(err, "batch response")
 	}
 
@@ -77,5 +245,73 @@ func (c *tqClient) Batch(remote string, bReq *batchRequest) (*BatchResponse, err
 		}
 	}
 
+	if c.cache != nil {
+		c.cache.Record(remote, bReq.Operation, bRes)
+		bRes.Objects = append(bRes.Objects, cached...)
+	}
+
 	return bRes, nil
 }
+
+// withTimeouts returns a copy of "req" which is made with the timeouts of batch
+// API requests, and the function which cancels it, once its response has been
+// read.
+func (c *tqClient) withTimeouts(req *http.Request) (*http.Request, context.CancelFunc) {
+	req = lfsapi.WithTimeouts(req, c.timeouts)
+	if c.timeout <= 0 {
+		return req, func() {}
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), c.timeout)
+	return req.WithContext(ctx), cancel
+}
+
+// isRetriableBatchError returns whether a batch API request which failed with
+// "err", and the response "res" (if any), may succeed if it is retried. Server
+// errors and rate limiting are transient, but authentication and other client
+// errors are not, so are never retried.
+func isRetriableBatchError(res *http.Response, err error) bool {
+	if errors.IsAuthError(err) {
+		return false
+	}
+	if res == nil {
+		return errors.IsRetriableError(err)
+	}
+
+	switch res.StatusCode {
+	case 408, 429, 500, 502, 503, 504:
+		return true
+	}
+	return false
+}
+
+// batchRetryDelay returns how long to wait before making the given retry of a
+// batch API request, numbered from one, honoring any Retry-After header in the
+// failed response "res" over the BackoffStrategy "b", or the default one if "b"
+// is nil. A Retry-After header is honored up to batchRetryMaxDelay, so that a
+// server cannot stall a push or fetch indefinitely.
+func batchRetryDelay(res *http.Response, retry int, b BackoffStrategy) time.Duration {
+	if res != nil {
+		if after := res.Header.Get("Retry-After"); len(after) > 0 {
+			if secs, err := strconv.Atoi(after); err == nil && secs >= 0 {
+				if secs > int(batchRetryMaxDelay/time.Second) {
+					return batchRetryMaxDelay
+				}
+				return time.Duration(secs) * time.Second
+			}
+			if at, err := http.ParseTime(after); err == nil {
+				if d := at.Sub(time.Now()); d > batchRetryMaxDelay {
+					return batchRetryMaxDelay
+				} else if d > 0 {
+					return d
+				}
+				return 0
+			}
+		}
+	}
+
+	if b == nil {
+		b = defaultBackoff()
+	}
+	return b.Next(retry)
+}
@@ -8,7 +8,9 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/git-lfs/git-lfs/lfsapi"
 	"github.com/stretchr/testify/assert"
@@ -141,6 +143,215 @@ func TestAPIBatchEmptyObjects(t *testing.T) {
 	assert.Equal(t, 0, len(bRes.Objects))
 }
 
+func TestAPIBatchRetriesTransientErrors(t *testing.T) {
+	defer func(d time.Duration) { batchRetryBaseDelay = d }(batchRetryBaseDelay)
+	batchRetryBaseDelay = time.Millisecond
+
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(503)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		err := json.NewEncoder(w).Encode(&BatchResponse{
+			TransferAdapterName: "basic",
+			Objects:             []*Transfer{&Transfer{Oid: "a", Size: 1}},
+		})
+		assert.Nil(t, err)
+	}))
+	defer srv.Close()
+
+	c, err := lfsapi.NewClient(nil, lfsapi.UniqTestEnv(map[string]string{
+		"lfs.url": srv.URL + "/api",
+	}))
+	require.Nil(t, err)
+
+	tqc := &tqClient{Client: c, maxRetries: 2}
+	bRes, err := tqc.Batch("remote", &batchRequest{
+		Objects: []*Transfer{&Transfer{Oid: "a", Size: 1}},
+	})
+	require.Nil(t, err)
+	assert.Equal(t, "basic", bRes.TransferAdapterName)
+	assert.EqualValues(t, 3, atomic.LoadInt32(&requests))
+}
+
+func TestAPIBatchTimeout(t *testing.T) {
+	defer func(d time.Duration) { batchRetryBaseDelay = d }(batchRetryBaseDelay)
+	batchRetryBaseDelay = time.Millisecond
+
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 2 {
+			time.Sleep(200 * time.Millisecond)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		err := json.NewEncoder(w).Encode(&BatchResponse{
+			TransferAdapterName: "basic",
+			Objects:             []*Transfer{&Transfer{Oid: "a", Size: 1}},
+		})
+		assert.Nil(t, err)
+	}))
+	defer srv.Close()
+
+	c, err := lfsapi.NewClient(nil, lfsapi.UniqTestEnv(map[string]string{
+		"lfs.url": srv.URL + "/api",
+	}))
+	require.Nil(t, err)
+
+	tqc := &tqClient{Client: c, timeout: 50 * time.Millisecond}
+	_, err = tqc.Batch("remote", &batchRequest{
+		Objects: []*Transfer{&Transfer{Oid: "a", Size: 1}},
+	})
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "batch request timed out after 50ms")
+
+	atomic.StoreInt32(&requests, 0)
+	tqc.maxRetries = 1
+	bRes, err := tqc.Batch("remote", &batchRequest{
+		Objects: []*Transfer{&Transfer{Oid: "a", Size: 1}},
+	})
+	require.Nil(t, err)
+	assert.Equal(t, "basic", bRes.TransferAdapterName)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&requests))
+}
+
+func TestAPIBatchHeaderTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(500)
+	}))
+	defer srv.Close()
+
+	c, err := lfsapi.NewClient(nil, lfsapi.UniqTestEnv(map[string]string{
+		"lfs.url": srv.URL + "/api",
+	}))
+	require.Nil(t, err)
+
+	tqc := &tqClient{Client: c, timeouts: lfsapi.Timeouts{Header: 50 * time.Millisecond}}
+	_, err = tqc.Batch("remote", &batchRequest{
+		Objects: []*Transfer{&Transfer{Oid: "a", Size: 1}},
+	})
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "timeout")
+}
+
+func TestAPIBatchRetriesExhausted(t *testing.T) {
+	defer func(d time.Duration) { batchRetryBaseDelay = d }(batchRetryBaseDelay)
+	batchRetryBaseDelay = time.Millisecond
+
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(500)
+	}))
+	defer srv.Close()
+
+	c, err := lfsapi.NewClient(nil, lfsapi.UniqTestEnv(map[string]string{
+		"lfs.url": srv.URL + "/api",
+	}))
+	require.Nil(t, err)
+
+	tqc := &tqClient{Client: c, maxRetries: 2}
+	_, err = tqc.Batch("remote", &batchRequest{
+		Objects: []*Transfer{&Transfer{Oid: "a", Size: 1}},
+	})
+	require.NotNil(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "batch negotiation failed after 3 attempts: "), err.Error())
+	assert.EqualValues(t, 3, atomic.LoadInt32(&requests))
+}
+
+func TestAPIBatchDoesNotRetryClientErrors(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(403)
+	}))
+	defer srv.Close()
+
+	c, err := lfsapi.NewClient(nil, lfsapi.UniqTestEnv(map[string]string{
+		"lfs.url": srv.URL + "/api",
+	}))
+	require.Nil(t, err)
+
+	tqc := &tqClient{Client: c, maxRetries: 2}
+	_, err = tqc.Batch("remote", &batchRequest{
+		Objects: []*Transfer{&Transfer{Oid: "a", Size: 1}},
+	})
+	require.NotNil(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "batch response: "), err.Error())
+	assert.EqualValues(t, 1, atomic.LoadInt32(&requests))
+}
+
+func TestBatchRetryDelay(t *testing.T) {
+	b := &ExponentialBackoff{Base: time.Second, Max: batchRetryMaxDelay}
+
+	assert.Equal(t, 1*time.Second, batchRetryDelay(nil, 1, b))
+	assert.Equal(t, 2*time.Second, batchRetryDelay(nil, 2, b))
+	assert.Equal(t, 4*time.Second, batchRetryDelay(nil, 3, b))
+	assert.Equal(t, batchRetryMaxDelay, batchRetryDelay(nil, 20, b))
+
+	res := &http.Response{Header: make(http.Header)}
+	res.Header.Set("Retry-After", "7")
+	assert.Equal(t, 7*time.Second, batchRetryDelay(res, 1, b))
+
+	res.Header.Set("Retry-After", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+	assert.Equal(t, time.Duration(0), batchRetryDelay(res, 1, b))
+
+	res.Header.Set("Retry-After", "86400000000000")
+	assert.Equal(t, batchRetryMaxDelay, batchRetryDelay(res, 1, b))
+
+	res.Header.Set("Retry-After", time.Now().Add(24*time.Hour).UTC().Format(http.TimeFormat))
+	assert.Equal(t, batchRetryMaxDelay, batchRetryDelay(res, 1, b))
+
+	res.Header.Set("Retry-After", "soon")
+	assert.Equal(t, 2*time.Second, batchRetryDelay(res, 2, b))
+}
+
+func TestBatchRetryDelayDefaultsToJitteredBackoff(t *testing.T) {
+	defer func(d time.Duration) { batchRetryBaseDelay = d }(batchRetryBaseDelay)
+	batchRetryBaseDelay = time.Second
+
+	for i := 0; i < 100; i++ {
+		d := batchRetryDelay(nil, 2, nil)
+		assert.True(t, d >= time.Second && d <= 2*time.Second, d.String())
+	}
+}
+
+type recordingBackoff struct {
+	attempts []int
+}
+
+func (b *recordingBackoff) Next(attempt int) time.Duration {
+	b.attempts = append(b.attempts, attempt)
+	return 0
+}
+
+func TestAPIBatchRetriesWithBackoff(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(503)
+	}))
+	defer srv.Close()
+
+	c, err := lfsapi.NewClient(nil, lfsapi.UniqTestEnv(map[string]string{
+		"lfs.url": srv.URL + "/api",
+	}))
+	require.Nil(t, err)
+
+	b := &recordingBackoff{}
+	tqc := &tqClient{Client: c, maxRetries: 3, backoff: b}
+	_, err = tqc.Batch("remote", &batchRequest{
+		Objects: []*Transfer{&Transfer{Oid: "a", Size: 1}},
+	})
+	require.NotNil(t, err)
+	assert.EqualValues(t, 4, atomic.LoadInt32(&requests))
+	assert.Equal(t, []int{1, 2, 3}, b.attempts)
+}
+
 var (
 	batchReqSchema *sourcedSchema
 	batchResSchema *sourcedSchema
@@ -186,3 +397,241 @@ func assertSchema(t *testing.T, schema *sourcedSchema, dataLoader gojsonschema.J
 		t.Errorf("Schema: %s\n%s", schema.Source, strings.Join(valErrors, "\n"))
 	}
 }
+
+func TestAPIBatchCachesUploads(t *testing.T) {
+	var requested []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bReq := &batchRequest{}
+		require.Nil(t, json.NewDecoder(r.Body).Decode(bReq))
+
+		objects := make([]*Transfer, 0, len(bReq.Objects))
+		for _, o := range bReq.Objects {
+			requested = append(requested, o.Oid)
+
+			obj := &Transfer{Oid: o.Oid, Size: o.Size}
+			if o.Oid != "a" {
+				obj.Actions = ActionSet{"upload": &Action{Href: "https://example.com/" + o.Oid}}
+			}
+			objects = append(objects, obj)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		assert.Nil(t, json.NewEncoder(w).Encode(&BatchResponse{Objects: objects}))
+	}))
+	defer srv.Close()
+
+	c, err := lfsapi.NewClient(nil, lfsapi.UniqTestEnv(map[string]string{
+		"lfs.url": srv.URL + "/api",
+	}))
+	require.Nil(t, err)
+
+	tqc := &tqClient{Client: c, cache: newObjectCache()}
+	batch := func() *BatchResponse {
+		bRes, err := tqc.Batch("remote", &batchRequest{
+			Operation: "upload",
+			Objects:   []*Transfer{&Transfer{Oid: "a", Size: 1}, &Transfer{Oid: "b", Size: 2}},
+		})
+		require.Nil(t, err)
+		require.Len(t, bRes.Objects, 2)
+		return bRes
+	}
+
+	batch()
+	assert.Equal(t, []string{"a", "b"}, requested)
+
+	bRes := batch()
+	assert.Equal(t, []string{"a", "b", "b"}, requested)
+	for _, o := range bRes.Objects {
+		_, ok := o.Actions["upload"]
+		assert.Equal(t, o.Oid == "b", ok, o.Oid)
+	}
+
+	tqc.cache.Uploaded("remote", "b", 2)
+	bRes = batch()
+	assert.Equal(t, []string{"a", "b", "b"}, requested)
+	for _, o := range bRes.Objects {
+		assert.Empty(t, o.Actions, o.Oid)
+	}
+}
+
+func TestAPIBatchCachesDownloads(t *testing.T) {
+	var requests int32
+	expiresIn := 3600
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		bReq := &batchRequest{}
+		require.Nil(t, json.NewDecoder(r.Body).Decode(bReq))
+
+		objects := make([]*Transfer, 0, len(bReq.Objects))
+		for _, o := range bReq.Objects {
+			objects = append(objects, &Transfer{
+				Oid: o.Oid, Size: o.Size,
+				Actions: ActionSet{"download": &Action{
+					Href:      "https://example.com/" + o.Oid,
+					ExpiresIn: expiresIn,
+				}},
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		assert.Nil(t, json.NewEncoder(w).Encode(&BatchResponse{
+			TransferAdapterName: "basic",
+			Objects:             objects,
+		}))
+	}))
+	defer srv.Close()
+
+	c, err := lfsapi.NewClient(nil, lfsapi.UniqTestEnv(map[string]string{
+		"lfs.url": srv.URL + "/api",
+	}))
+	require.Nil(t, err)
+
+	tqc := &tqClient{Client: c, cache: newObjectCache()}
+	batch := func(remote string, objects ...*Transfer) *BatchResponse {
+		bRes, err := tqc.Batch(remote, &batchRequest{
+			Operation: "download",
+			Objects:   objects,
+		})
+		require.Nil(t, err)
+		return bRes
+	}
+
+	batch("remote", &Transfer{Oid: "a", Size: 1})
+	assert.EqualValues(t, 1, atomic.LoadInt32(&requests))
+
+	bRes := batch("remote", &Transfer{Oid: "a", Size: 1})
+	assert.EqualValues(t, 1, atomic.LoadInt32(&requests))
+	assert.Equal(t, "basic", bRes.TransferAdapterName)
+	require.Len(t, bRes.Objects, 1)
+	assert.Equal(t, "https://example.com/a", bRes.Objects[0].Actions["download"].Href)
+
+	// objects which are not all cached, or cached for another remote, are
+	// requested again
+	batch("remote", &Transfer{Oid: "a", Size: 1}, &Transfer{Oid: "b", Size: 1})
+	assert.EqualValues(t, 2, atomic.LoadInt32(&requests))
+	batch("other", &Transfer{Oid: "a", Size: 1})
+	assert.EqualValues(t, 3, atomic.LoadInt32(&requests))
+
+	// as are those whose actions are about to expire
+	expiresIn = 1
+	batch("remote", &Transfer{Oid: "c", Size: 1})
+	batch("remote", &Transfer{Oid: "c", Size: 1})
+	assert.EqualValues(t, 5, atomic.LoadInt32(&requests))
+
+	tqc.cache.Forget("remote", "a")
+	batch("remote", &Transfer{Oid: "a", Size: 1})
+	assert.EqualValues(t, 6, atomic.LoadInt32(&requests))
+}
+
+func TestStreamBatchSendsEachBatchWhenAnswered(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		bReq := &batchRequest{}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(bReq))
+
+		w.Header().Set("Content-Type", "application/json")
+		assert.Nil(t, json.NewEncoder(w).Encode(&BatchResponse{
+			TransferAdapterName: "basic",
+			Objects:             bReq.Objects,
+		}))
+	}))
+	defer srv.Close()
+
+	c, err := lfsapi.NewClient(nil, lfsapi.UniqTestEnv(map[string]string{
+		"lfs.url": srv.URL + "/api",
+	}))
+	require.Nil(t, err)
+
+	objects := make(chan *Transfer)
+	results := StreamBatch(NewManifestWithClient(c), Download, "origin", objects, 2)
+
+	objects <- &Transfer{Oid: "a", Size: 1}
+	objects <- &Transfer{Oid: "b", Size: 1}
+
+	// The first batch is answered before any more objects are given.
+	for _, oid := range []string{"a", "b"} {
+		res := <-results
+		require.Nil(t, res.Err)
+		assert.Equal(t, oid, res.Transfer.Oid)
+		assert.Equal(t, "basic", res.Adapter)
+	}
+
+	objects <- &Transfer{Oid: "c", Size: 1}
+	close(objects)
+
+	res := <-results
+	require.Nil(t, res.Err)
+	assert.Equal(t, "c", res.Transfer.Oid)
+
+	_, ok := <-results
+	assert.False(t, ok)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&requests))
+}
+
+func TestStreamBatchFlushesPartialBatches(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		bReq := &batchRequest{}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(bReq))
+
+		w.Header().Set("Content-Type", "application/json")
+		assert.Nil(t, json.NewEncoder(w).Encode(&BatchResponse{
+			TransferAdapterName: "basic",
+			Objects:             bReq.Objects,
+		}))
+	}))
+	defer srv.Close()
+
+	c, err := lfsapi.NewClient(nil, lfsapi.UniqTestEnv(map[string]string{
+		"lfs.url": srv.URL + "/api",
+	}))
+	require.Nil(t, err)
+
+	objects := make(chan *Transfer)
+	results := StreamBatch(NewManifestWithClient(c), Download, "origin", objects, 100)
+
+	// The batch is not full, and "objects" is still open, but the object
+	// is requested all the same.
+	objects <- &Transfer{Oid: "a", Size: 1}
+	select {
+	case res := <-results:
+		require.Nil(t, res.Err)
+		assert.Equal(t, "a", res.Transfer.Oid)
+	case <-time.After(5 * time.Second):
+		t.Fatal("partial batch was not requested")
+	}
+
+	close(objects)
+	_, ok := <-results
+	assert.False(t, ok)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&requests))
+}
+
+func TestStreamBatchSendsErrorsForEachObject(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+	}))
+	defer srv.Close()
+
+	c, err := lfsapi.NewClient(nil, lfsapi.UniqTestEnv(map[string]string{
+		"lfs.url": srv.URL + "/api",
+	}))
+	require.Nil(t, err)
+
+	objects := make(chan *Transfer, 2)
+	objects <- &Transfer{Oid: "a", Size: 1}
+	objects <- &Transfer{Oid: "b", Size: 1}
+	close(objects)
+
+	var oids []string
+	for res := range StreamBatch(NewManifestWithClient(c), Upload, "origin", objects, 0) {
+		assert.NotNil(t, res.Err)
+		oids = append(oids, res.Transfer.Oid)
+	}
+	assert.Equal(t, []string{"a", "b"}, oids)
+}
@@ -0,0 +1,85 @@
+package tq
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/git-lfs/git-lfs/tools"
+	"github.com/rubyist/tracerx"
+)
+
+// auditURLRE matches the URLs in an error message, which are written to the
+// audit log without their credentials. Punctuation which follows a URL in the
+// message, such as in "Get <url>: ...", is not taken to be part of it.
+var auditURLRE = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s"'<>]*[^\s"'<>:,.;)]`)
+
+// auditRecord is an entry of the audit log given by lfs.auditlog, which is one
+// line of JSON for each object downloaded, or which failed to download. Its
+// schema is documented in git-lfs-config(5), so fields may be added to it, but
+// never renamed or removed.
+type auditRecord struct {
+	Time     string `json:"time"`
+	Oid      string `json:"oid"`
+	Size     int64  `json:"size"`
+	Remote   string `json:"remote"`
+	Endpoint string `json:"endpoint"`
+	Outcome  string `json:"outcome"`
+	Error    string `json:"error,omitempty"`
+	Retries  int    `json:"retries"`
+}
+
+// audit appends the outcome of the download "t" to the audit log, if there is
+// one: success if "err" is nil, and otherwise failure. It is called once for
+// each object, once it has been downloaded, or will not be retried again. A
+// download of only a range of an object is not the object, and is not recorded.
+// Neither the endpoint nor the error recorded
+// include any credentials, or the query of any URL, which may hold a token. A
+// failure to write the record is only warned about.
+func (q *TransferQueue) audit(t *Transfer, err error) {
+	if q.direction != Download || len(q.manifest.auditLog) == 0 || t.Range != nil {
+		return
+	}
+	oid := t.Oid
+
+	rec := &auditRecord{
+		Time:    time.Now().UTC().Format(time.RFC3339Nano),
+		Oid:     oid,
+		Size:    t.Size,
+		Remote:  q.remote,
+		Outcome: "success",
+	}
+	if c := q.manifest.APIClient(); c != nil && c.Endpoints != nil {
+		rec.Endpoint = auditURL(c.Endpoints.Endpoint(Download.String(), q.remote).Url)
+	}
+	if q.rc != nil {
+		rec.Retries = q.rc.CountFor(oid)
+	}
+	if err != nil {
+		rec.Outcome = "failure"
+		rec.Error = auditURLRE.ReplaceAllStringFunc(err.Error(), auditURL)
+	}
+
+	by, err := json.Marshal(rec)
+	if err == nil {
+		err = tools.NewLockedAppender(q.manifest.auditLog).Append(append(by, '\n'))
+	}
+	if err != nil {
+		tracerx.Printf("tq: could not write %s to the audit log %q: %s", oid, q.manifest.auditLog, err)
+	}
+}
+
+// auditURL returns "rawurl" without its user information, query or fragment.
+func auditURL(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil || len(u.Scheme) == 0 {
+		return rawurl
+	}
+
+	u.User = nil
+	u.RawQuery = ""
+	u.ForceQuery = false
+	u.Fragment = ""
+	return u.String()
+}
@@ -0,0 +1,63 @@
+package tq
+
+import (
+	"math/rand"
+	"time"
+)
+
+// BackoffStrategy decides how long to wait before retrying a failed batch API
+// request. Embedders may give their own to WithBackoff, such as one which never
+// waits, or one which follows a fixed schedule in tests.
+type BackoffStrategy interface {
+	// Next returns the delay before the given retry, numbered from one.
+	Next(attempt int) time.Duration
+}
+
+// ExponentialBackoff is a BackoffStrategy which waits Base before the first
+// retry, and doubles the delay before each retry after it, up to Max.
+type ExponentialBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+	// Jitter picks each delay at random from the upper half of its range,
+	// so that clients which failed at the same time do not all retry at the
+	// same time, too.
+	Jitter bool
+}
+
+// NewExponentialBackoff returns an ExponentialBackoff from "base" up to "max",
+// with jitter.
+func NewExponentialBackoff(base, max time.Duration) *ExponentialBackoff {
+	return &ExponentialBackoff{Base: base, Max: max, Jitter: true}
+}
+
+// Next implements the BackoffStrategy interface.
+func (b *ExponentialBackoff) Next(attempt int) time.Duration {
+	delay := b.Base
+	for i := 1; i < attempt && delay < b.Max; i++ {
+		delay *= 2
+	}
+	if delay > b.Max {
+		delay = b.Max
+	}
+
+	if b.Jitter && delay > 1 {
+		half := delay / 2
+		delay = delay - half + time.Duration(rand.Int63n(int64(half)+1))
+	}
+	return delay
+}
+
+// ConstantBackoff is a BackoffStrategy which waits the same time before every
+// retry.
+type ConstantBackoff time.Duration
+
+// Next implements the BackoffStrategy interface.
+func (b ConstantBackoff) Next(attempt int) time.Duration {
+	return time.Duration(b)
+}
+
+// defaultBackoff returns the BackoffStrategy used when none is given to
+// WithBackoff.
+func defaultBackoff() BackoffStrategy {
+	return NewExponentialBackoff(batchRetryBaseDelay, batchRetryMaxDelay)
+}
@@ -0,0 +1,45 @@
+package tq
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExponentialBackoffWithoutJitter(t *testing.T) {
+	b := &ExponentialBackoff{Base: 100 * time.Millisecond, Max: time.Second}
+
+	assert.Equal(t, 100*time.Millisecond, b.Next(1))
+	assert.Equal(t, 200*time.Millisecond, b.Next(2))
+	assert.Equal(t, 400*time.Millisecond, b.Next(3))
+	assert.Equal(t, 800*time.Millisecond, b.Next(4))
+	assert.Equal(t, time.Second, b.Next(5))
+	assert.Equal(t, time.Second, b.Next(50))
+}
+
+func TestExponentialBackoffWithJitter(t *testing.T) {
+	b := NewExponentialBackoff(100*time.Millisecond, time.Second)
+
+	for i := 0; i < 100; i++ {
+		d := b.Next(3)
+		assert.True(t, d >= 200*time.Millisecond && d <= 400*time.Millisecond, d.String())
+
+		d = b.Next(10)
+		assert.True(t, d >= 500*time.Millisecond && d <= time.Second, d.String())
+	}
+}
+
+func TestExponentialBackoffWithoutBase(t *testing.T) {
+	b := NewExponentialBackoff(0, time.Second)
+
+	assert.Equal(t, time.Duration(0), b.Next(1))
+	assert.Equal(t, time.Duration(0), b.Next(5))
+}
+
+func TestConstantBackoff(t *testing.T) {
+	b := ConstantBackoff(3 * time.Second)
+
+	assert.Equal(t, 3*time.Second, b.Next(1))
+	assert.Equal(t, 3*time.Second, b.Next(20))
+}
@@ -1,9 +1,11 @@
 package tq
 
 import (
+	"encoding/hex"
 	"fmt"
 	"hash"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -18,6 +20,17 @@ import (
 // Adapter for basic HTTP downloads, includes resuming via HTTP Range
 type basicDownloadAdapter struct {
 	*adapterBase
+
+	// trustServerSize is whether to accept objects whose size differs
+	// from the size they were requested with, as long as their OID still
+	// matches, rather than rejecting them.
+	trustServerSize bool
+	// parallelThreshold is the size from which an object is downloaded in
+	// parallelConnections ranges at once, or 0 if none are.
+	parallelThreshold   int64
+	parallelConnections int
+	// partRetries is how many times each of those ranges is retried.
+	partRetries int
 }
 
 func (a *basicDownloadAdapter) ClearTempStorage() error {
@@ -28,7 +41,11 @@ func (a *basicDownloadAdapter) tempDir() string {
 	// Must be dedicated to this adapter as deleted by ClearTempStorage
 	// Also make local to this repo not global, and separate to localstorage temp,
 	// which gets cleared at the end of every invocation
-	d := filepath.Join(localstorage.Objects().RootDir, "incomplete")
+	objects := localstorage.Objects()
+	if objects == nil {
+		return os.TempDir()
+	}
+	d := filepath.Join(objects.RootDir, "incomplete")
 	if err := os.MkdirAll(d, 0755); err != nil {
 		return os.TempDir()
 	}
@@ -42,20 +59,62 @@ func (a *basicDownloadAdapter) WorkerEnding(workerNum int, ctx interface{}) {
 }
 
 func (a *basicDownloadAdapter) DoTransfer(ctx interface{}, t *Transfer, cb ProgressCallback, authOkFunc func()) error {
+	// Each way of downloading "t" writes it to a single file, which is
+	// counted against the open file limit shared with the smudge filter.
+	release := tools.AcquireFiles(1)
+	defer release()
+
+	if t.Range != nil {
+		return a.downloadRange(t, cb, authOkFunc)
+	}
+	if a.useParallelDownload(t) {
+		return a.downloadParallel(t, cb, authOkFunc)
+	}
+
 	f, fromByte, hashSoFar, err := a.checkResumeDownload(t)
 	if err != nil {
 		return err
 	}
+
+	if fromByte == t.Size && hashSoFar != nil && hex.EncodeToString(hashSoFar.Sum(nil)) == t.Oid {
+		// The whole object was downloaded by an earlier attempt, which
+		// failed to verify it, so only the verify request is needed.
+		f.Close()
+		tracerx.Printf("xfer: %q already downloaded; verifying", t.Oid)
+		advanceCallbackProgress(cb, t, fromByte)
+		if err := a.finishDownload(t, f.Name()); err != nil {
+			return err
+		}
+		if authOkFunc != nil {
+			authOkFunc()
+		}
+		return nil
+	}
 	return a.download(t, cb, authOkFunc, f, fromByte, hashSoFar)
 }
 
+// finishDownload calls the "verify" action of "t", if it has one, and then
+// moves the completely downloaded file "dlfilename" to t.Path. If verification
+// fails, the file is kept, so that retrying does not download it again.
+func (a *basicDownloadAdapter) finishDownload(t *Transfer, dlfilename string) error {
+	if err := verifyDownload(a.apiClient, a.remote, t); err != nil {
+		return err
+	}
+	return tools.RenameFileCopyPermissions(dlfilename, t.Path)
+}
+
 // Checks to see if a download can be resumed, and if so returns a non-nil locked file, byte start and hash
 func (a *basicDownloadAdapter) checkResumeDownload(t *Transfer) (outFile *os.File, fromByte int64, hashSoFar hash.Hash, e error) {
-	// lock the file by opening it for read/write, rather than checking Stat() etc
-	// which could be subject to race conditions by other processes
-	f, err := os.OpenFile(a.downloadFilename(t), os.O_RDWR, 0644)
+	// A partial download left behind by a process which is no longer
+	// running is claimed by renaming it, so that no other process resumes
+	// it at the same time.
+	var f *os.File
+	var err error
+	if path, ok := localstorage.ClaimTempFile(a.tempDir(), t.Oid); ok {
+		f, err = os.OpenFile(path, os.O_RDWR, 0644)
+	}
 
-	if err != nil {
+	if f == nil || err != nil {
 		// Create a new file instead, must not already exist or error (permissions / race condition)
 		newfile, err := os.OpenFile(a.downloadFilename(t), os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
 		return newfile, 0, nil, err
@@ -74,10 +133,11 @@ func (a *basicDownloadAdapter) checkResumeDownload(t *Transfer) (outFile *os.Fil
 
 }
 
-// Create or open a download file for resuming
+// downloadFilename returns the name of a new file to download "t" to, which is
+// named after the process writing it, so that it can be resumed later, by a
+// process which finds it with localstorage.ClaimTempFile.
 func (a *basicDownloadAdapter) downloadFilename(t *Transfer) string {
-	// Not a temp file since we will be resuming it
-	return filepath.Join(a.tempDir(), t.Oid+".tmp")
+	return filepath.Join(a.tempDir(), localstorage.TempFileName(t.Oid))
 }
 
 // download starts or resumes and download. Always closes dlFile if non-nil
@@ -211,8 +271,117 @@ func (a *basicDownloadAdapter) download(t *Transfer, cb ProgressCallback, authOk
 		return fmt.Errorf("can't close tempfile %q: %v", dlfilename, err)
 	}
 
+	if size := fromByte + written; size != t.Size {
+		if !a.trustServerSize {
+			err := errors.NewVerificationError(fmt.Errorf("Expected %d bytes for %s, got %d", t.Size, t.Oid, size))
+			if size < t.Size {
+				// Keep a short download, so that the next
+				// attempt can resume it.
+				return err
+			}
+			// Start again from scratch when retrying, rather than
+			// resuming from content that cannot be right.
+			os.Remove(dlfilename)
+			return errors.NewRetriableError(err)
+		}
+		tracerx.Printf("xfer: object %s is %d bytes, not %d; accepting the size sent by the server", t.Oid, size, t.Size)
+	}
+
 	if actual := hasher.Hash(); actual != t.Oid {
-		return fmt.Errorf("Expected OID %s, got %s after %d bytes written", t.Oid, actual, written)
+		return errors.NewVerificationError(fmt.Errorf("Expected OID %s, got %s after %d bytes written", t.Oid, actual, written))
+	}
+
+	return a.finishDownload(t, dlfilename)
+}
+
+// downloadRange downloads only the bytes of t.Range, writing them to t.Path. It
+// is never resumed, and the bytes are verified by their length, rather than by
+// the object's OID.
+func (a *basicDownloadAdapter) downloadRange(t *Transfer, cb ProgressCallback, authOkFunc func()) error {
+	r := t.Range
+	if r.Offset < 0 || r.Length <= 0 || r.Offset+r.Length > t.Size {
+		return errors.Errorf("Invalid range %d-%d for object %s (%d bytes)", r.Offset, r.Offset+r.Length-1, t.Oid, t.Size)
+	}
+
+	rel, err := t.Rel("download")
+	if err != nil {
+		return err
+	}
+	if rel == nil {
+		return errors.Errorf("Object %s not found on the server.", t.Oid)
+	}
+
+	req, err := a.newHTTPRequest("GET", rel)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Range", r.String())
+
+	req = a.apiClient.LogRequest(req, "lfs.data.download")
+	res, err := a.doHTTP(t, req)
+	if err != nil {
+		return errors.NewRetriableError(err)
+	}
+	defer res.Body.Close()
+
+	var body io.Reader = res.Body
+	switch res.StatusCode {
+	case 206:
+		regex := regexp.MustCompile(`bytes (\d+)\-.*`)
+		match := regex.FindStringSubmatch(res.Header.Get("Content-Range"))
+		if match == nil {
+			return errors.Errorf("badly formatted Content-Range header: %q", res.Header.Get("Content-Range"))
+		}
+		if start, _ := strconv.ParseInt(match[1], 10, 64); start != r.Offset {
+			return errors.Errorf("Content-Range start byte incorrect: %s expected %d", match[1], r.Offset)
+		}
+	case 200:
+		// The server ignored the Range header and sent the whole
+		// object, so skip to the start of the range.
+		tracerx.Printf("xfer: server ignored range request for %q; discarding %d bytes", t.Oid, r.Offset)
+		if _, err := io.CopyN(ioutil.Discard, body, r.Offset); err != nil {
+			return errors.NewRetriableError(err)
+		}
+		body = io.LimitReader(body, r.Length)
+	default:
+		return errors.Errorf("Invalid status for range request of %s: %d", t.Oid, res.StatusCode)
+	}
+
+	if authOkFunc != nil {
+		authOkFunc()
+	}
+
+	// Partial downloads are not resumed, so write them to a file of their
+	// own in tempDir(), rather than the one download resumes from, and
+	// move it into place once it is complete.
+	dlFile, err := ioutil.TempFile(a.tempDir(), t.Oid+".part")
+	if err != nil {
+		return err
+	}
+	dlfilename := dlFile.Name()
+	defer os.Remove(dlfilename)
+	defer dlFile.Close()
+
+	ccb := func(totalSize int64, readSoFar int64, readSinceLast int) error {
+		if cb != nil {
+			return cb(t.Name, totalSize, readSoFar, readSinceLast)
+		}
+		return nil
+	}
+
+	// Read one byte past the end of the range, so that a server sending
+	// too much is caught, as well as one sending too little.
+	from := io.LimitReader(tools.NewRetriableReader(body), r.Length+1)
+	written, err := tools.CopyWithCallback(dlFile, from, r.Length, ccb)
+	if err != nil {
+		return errors.Wrapf(err, "cannot write data to tempfile %q", dlfilename)
+	}
+	if err := dlFile.Close(); err != nil {
+		return fmt.Errorf("can't close tempfile %q: %v", dlfilename, err)
+	}
+
+	if written != r.Length {
+		return errors.NewVerificationError(fmt.Errorf("Expected %d bytes of %s from byte %d, got %d", r.Length, t.Oid, r.Offset, written))
 	}
 
 	return tools.RenameFileCopyPermissions(dlfilename, t.Path)
@@ -222,7 +391,13 @@ func configureBasicDownloadAdapter(m *Manifest) {
 	m.RegisterNewAdapterFunc(BasicAdapterName, Download, func(name string, dir Direction) Adapter {
 		switch dir {
 		case Download:
-			bd := &basicDownloadAdapter{newAdapterBase(name, dir, nil)}
+			bd := &basicDownloadAdapter{
+				adapterBase:         newAdapterBase(name, dir, nil),
+				trustServerSize:     m.trustServerSize,
+				parallelThreshold:   m.parallelObjectThreshold,
+				parallelConnections: m.parallelObjectConnections,
+				partRetries:         m.maxRetries,
+			}
 			// self implements impl
 			bd.transferImpl = bd
 			return bd
@@ -0,0 +1,218 @@
+package tq
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/git-lfs/git-lfs/tools"
+	"github.com/rubyist/tracerx"
+)
+
+const (
+	// defaultParallelObjectConnections is how many ranges an object is
+	// downloaded in at once, when it is at least as large as
+	// lfs.transfer.parallelobjectthreshold, unless
+	// lfs.transfer.parallelobjectconnections says otherwise.
+	defaultParallelObjectConnections = 4
+)
+
+// errRangesUnsupported is returned by downloadPart when the server ignores the
+// Range header, and sends the whole object instead.
+var errRangesUnsupported = errors.New("server does not support range requests")
+
+var contentRangeStartRE = regexp.MustCompile(`bytes (\d+)\-.*`)
+
+// useParallelDownload returns whether "t" should be downloaded with several
+// range requests at once, rather than over a single connection.
+func (a *basicDownloadAdapter) useParallelDownload(t *Transfer) bool {
+	return a.parallelThreshold > 0 && a.parallelConnections > 1 &&
+		t.Size >= a.parallelThreshold && t.Size >= int64(a.parallelConnections)
+}
+
+// downloadParallel downloads "t" in as many ranges as the adapter has parallel
+// connections, each written to its place in the same file, and then checks the
+// hash of the whole object, before moving it into place as download does. A
+// range which fails is retried on its own, from where it got to, so that the
+// others are not downloaded again. If the server turns out not to support range
+// requests, the object is downloaded over a single connection instead.
+func (a *basicDownloadAdapter) downloadParallel(t *Transfer, cb ProgressCallback, authOkFunc func()) error {
+	// The ranges are not kept if the download fails, since they cannot be
+	// resumed as a single file can, so write them to a file of their own
+	// in tempDir(), rather than the one download resumes from, so that
+	// nothing is ever left with the objects.
+	f, err := ioutil.TempFile(a.tempDir(), t.Oid+".parallel")
+	if err != nil {
+		return err
+	}
+	dlfilename := f.Name()
+	defer os.Remove(dlfilename)
+	defer f.Close()
+
+	var cbMu sync.Mutex
+	var read int64
+	partCb := func(n int) error {
+		cbMu.Lock()
+		defer cbMu.Unlock()
+
+		read += int64(n)
+		if cb != nil {
+			return cb(t.Name, t.Size, read, n)
+		}
+		return nil
+	}
+
+	var authOnce sync.Once
+	partAuthOk := func() {
+		if authOkFunc != nil {
+			authOnce.Do(authOkFunc)
+		}
+	}
+
+	parts := splitRanges(t.Size, a.parallelConnections)
+	errs := make([]error, len(parts))
+
+	var wg sync.WaitGroup
+	for i, r := range parts {
+		wg.Add(1)
+		go func(i int, r *ByteRange) {
+			defer wg.Done()
+			errs[i] = a.downloadPartWithRetries(t, r, f, partCb, partAuthOk)
+		}(i, r)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err == errRangesUnsupported {
+			tracerx.Printf("xfer: %s for %q; downloading it over a single connection", err, t.Oid)
+			f.Close()
+			os.Remove(dlfilename)
+			return a.download(t, cb, authOkFunc, nil, 0, nil)
+		}
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	hash := tools.NewLfsContentHash()
+	if _, err := io.Copy(hash, f); err != nil {
+		return err
+	}
+	if actual := hex.EncodeToString(hash.Sum(nil)); actual != t.Oid {
+		return errors.NewVerificationError(fmt.Errorf("Expected OID %s, got %s after downloading %d bytes in %d ranges", t.Oid, actual, t.Size, len(parts)))
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("can't close tempfile %q: %v", dlfilename, err)
+	}
+	return a.finishDownload(t, dlfilename)
+}
+
+// downloadPartWithRetries downloads the range "r" of "t" into "f", retrying it
+// from where it got to, as many times as an object may be retried, as long as
+// each failure is retriable.
+func (a *basicDownloadAdapter) downloadPartWithRetries(t *Transfer, r *ByteRange, f io.WriterAt, cb func(n int) error, authOkFunc func()) error {
+	var err error
+	for attempt := 0; attempt <= a.partRetries; attempt++ {
+		var written int64
+		written, err = a.downloadPart(t, r, f, cb, authOkFunc)
+		r = &ByteRange{Offset: r.Offset + written, Length: r.Length - written}
+		if err == nil || r.Length == 0 || !errors.IsRetriableError(err) {
+			break
+		}
+		tracerx.Printf("xfer: retrying %s of %q: %s", r, t.Oid, err)
+	}
+	return err
+}
+
+// downloadPart makes a single range request for "r" of "t", and writes what it
+// receives to its place in "f", returning how much was written.
+func (a *basicDownloadAdapter) downloadPart(t *Transfer, r *ByteRange, f io.WriterAt, cb func(n int) error, authOkFunc func()) (int64, error) {
+	rel, err := t.Rel("download")
+	if err != nil {
+		return 0, err
+	}
+	if rel == nil {
+		return 0, errors.Errorf("Object %s not found on the server.", t.Oid)
+	}
+
+	req, err := a.newHTTPRequest("GET", rel)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Range", r.String())
+
+	req = a.apiClient.LogRequest(req, "lfs.data.download")
+	res, err := a.doHTTP(t, req)
+	if err != nil {
+		return 0, errors.NewRetriableError(err)
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case 206:
+		match := contentRangeStartRE.FindStringSubmatch(res.Header.Get("Content-Range"))
+		if match == nil {
+			return 0, errors.Errorf("badly formatted Content-Range header: %q", res.Header.Get("Content-Range"))
+		}
+		if start, _ := strconv.ParseInt(match[1], 10, 64); start != r.Offset {
+			return 0, errors.Errorf("Content-Range start byte incorrect: %s expected %d", match[1], r.Offset)
+		}
+	case 200:
+		return 0, errRangesUnsupported
+	default:
+		return 0, errors.Errorf("Invalid status for range request of %s: %d", t.Oid, res.StatusCode)
+	}
+
+	authOkFunc()
+
+	w := &offsetWriter{w: f, offset: r.Offset}
+	written, err := tools.CopyWithCallback(w, io.LimitReader(res.Body, r.Length), r.Length, func(_, _ int64, n int) error {
+		return cb(n)
+	})
+	if err != nil {
+		return written, errors.NewRetriableError(err)
+	}
+	if written != r.Length {
+		return written, errors.NewRetriableError(errors.NewVerificationError(fmt.Errorf("Expected %d bytes of %s from byte %d, got %d", r.Length, t.Oid, r.Offset, written)))
+	}
+	return written, nil
+}
+
+// splitRanges splits an object of "size" bytes into "n" ranges, as close in
+// size as they can be.
+func splitRanges(size int64, n int) []*ByteRange {
+	ranges := make([]*ByteRange, 0, n)
+	partSize := size / int64(n)
+	for i := 0; i < n; i++ {
+		r := &ByteRange{Offset: int64(i) * partSize, Length: partSize}
+		if i == n-1 {
+			r.Length = size - r.Offset
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// offsetWriter writes to "w" at "offset", advancing it after each write.
+type offsetWriter struct {
+	w      io.WriterAt
+	offset int64
+}
+
+func (w *offsetWriter) Write(p []byte) (int, error) {
+	n, err := w.w.WriteAt(p, w.offset)
+	w.offset += int64(n)
+	return n, err
+}
@@ -0,0 +1,367 @@
+package tq
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/git-lfs/git-lfs/lfsapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const rangeContents = "the quick brown fox jumps over the lazy dog"
+
+func downloadRange(t *testing.T, handler http.HandlerFunc, r *ByteRange) (string, error) {
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "basic-download")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	cli, err := lfsapi.NewClient(nil, nil)
+	require.Nil(t, err)
+
+	m := NewManifestWithClient(cli)
+	a := m.NewDownloadAdapter(BasicAdapterName)
+	require.Nil(t, a.Begin(&adapterConfig{
+		apiClient:           cli,
+		concurrentTransfers: 1,
+	}, nil))
+
+	path := filepath.Join(dir, "partial")
+	results := a.Add(&Transfer{
+		Name:          "a.dat",
+		Oid:           strings.Repeat("0", 64),
+		Size:          int64(len(rangeContents)),
+		Path:          path,
+		Authenticated: true,
+		Range:         r,
+		Actions: ActionSet{
+			"download": &Action{Href: srv.URL + "/download"},
+		},
+	})
+
+	var resErr error
+	for res := range results {
+		resErr = res.Error
+	}
+	a.End()
+
+	if resErr != nil {
+		return "", resErr
+	}
+
+	// Nothing but the download itself is left beside it.
+	entries, err := ioutil.ReadDir(dir)
+	require.Nil(t, err)
+	require.Len(t, entries, 1)
+
+	by, err := ioutil.ReadFile(path)
+	require.Nil(t, err)
+	return string(by), nil
+}
+
+func TestBasicDownloadRange(t *testing.T) {
+	contents, err := downloadRange(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "bytes=4-8", r.Header.Get("Range"))
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes 4-8/%d", len(rangeContents)))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte(rangeContents[4:9]))
+	}, &ByteRange{Offset: 4, Length: 5})
+
+	require.Nil(t, err)
+	assert.Equal(t, "quick", contents)
+}
+
+func TestBasicDownloadRangeIgnoredByServer(t *testing.T) {
+	contents, err := downloadRange(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(rangeContents))
+	}, &ByteRange{Offset: 10, Length: 5})
+
+	require.Nil(t, err)
+	assert.Equal(t, "brown", contents)
+}
+
+func TestBasicDownloadRangeWrongStart(t *testing.T) {
+	_, err := downloadRange(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-4/%d", len(rangeContents)))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte(rangeContents[0:5]))
+	}, &ByteRange{Offset: 4, Length: 5})
+
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Content-Range start byte incorrect")
+}
+
+func TestBasicDownloadRangeShort(t *testing.T) {
+	_, err := downloadRange(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes 4-8/%d", len(rangeContents)))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte(rangeContents[4:7]))
+	}, &ByteRange{Offset: 4, Length: 5})
+
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Expected 5 bytes")
+}
+
+func TestBasicDownloadRangeOutOfBounds(t *testing.T) {
+	_, err := downloadRange(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("unexpected request")
+	}, &ByteRange{Offset: 40, Length: 5})
+
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Invalid range 40-44")
+}
+
+// basicTestRedirectedDownload downloads an object with the basic adapter, using
+// a download action with an Authorization header whose Href redirects to the
+// same host, if "sameHost" is true, or to another one, and returns what the
+// Authorization header of the redirected request was. The whole object is
+// downloaded as a range, so that no local storage is needed for a temporary
+// file.
+func basicTestRedirectedDownload(t *testing.T, sameHost bool) string {
+	contents := "redirected download"
+	sum := sha256.Sum256([]byte(contents))
+	oid := hex.EncodeToString(sum[:])
+
+	var auth string
+	var redirected bool
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/objects/"+oid {
+			auth = r.Header.Get("Authorization")
+			redirected = true
+			w.Write([]byte(contents))
+			return
+		}
+		w.WriteHeader(404)
+	}
+
+	target := httptest.NewServer(http.HandlerFunc(handler))
+	defer target.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/download" {
+			assert.Equal(t, "Basic abc", r.Header.Get("Authorization"))
+			if sameHost {
+				w.Header().Set("Location", "/objects/"+oid)
+			} else {
+				w.Header().Set("Location", target.URL+"/objects/"+oid)
+			}
+			w.WriteHeader(http.StatusTemporaryRedirect)
+			return
+		}
+		handler(w, r)
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "basic-download")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	cli, err := lfsapi.NewClient(nil, nil)
+	require.Nil(t, err)
+
+	m := NewManifestWithClient(cli)
+	a := m.NewDownloadAdapter(BasicAdapterName)
+	require.Nil(t, a.Begin(&adapterConfig{
+		apiClient:           cli,
+		concurrentTransfers: 1,
+	}, nil))
+
+	path := filepath.Join(dir, oid)
+	results := a.Add(&Transfer{
+		Name:          "a.dat",
+		Oid:           oid,
+		Size:          int64(len(contents)),
+		Path:          path,
+		Authenticated: true,
+		Range:         &ByteRange{Offset: 0, Length: int64(len(contents))},
+		Actions: ActionSet{
+			"download": &Action{
+				Href:   srv.URL + "/download",
+				Header: map[string]string{"Authorization": "Basic abc"},
+			},
+		},
+	})
+
+	for res := range results {
+		assert.Nil(t, res.Error)
+	}
+	a.End()
+
+	require.True(t, redirected)
+	by, err := ioutil.ReadFile(path)
+	require.Nil(t, err)
+	assert.Equal(t, contents, string(by))
+	return auth
+}
+
+func TestBasicDownloadSameHostRedirectKeepsAuthorization(t *testing.T) {
+	assert.Equal(t, "Basic abc", basicTestRedirectedDownload(t, true))
+}
+
+func TestBasicDownloadCrossHostRedirectDropsAuthorization(t *testing.T) {
+	assert.Equal(t, "", basicTestRedirectedDownload(t, false))
+}
+
+// basicTestParallelDownload downloads an object of rangeContents, whose OID is
+// "oid", with the basic adapter, configured to download it in 4 ranges at once,
+// and returns what was downloaded.
+func basicTestParallelDownload(t *testing.T, handler http.HandlerFunc, oid string) (string, error) {
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "basic-download")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	cli, err := lfsapi.NewClient(nil, lfsapi.UniqTestEnv(map[string]string{
+		"lfs.transfer.parallelobjectthreshold":   "16",
+		"lfs.transfer.parallelobjectconnections": "4",
+		"lfs.transfer.maxretries":                "2",
+	}))
+	require.Nil(t, err)
+
+	m := NewManifestWithClient(cli)
+	a := m.NewDownloadAdapter(BasicAdapterName)
+	require.Nil(t, a.Begin(&adapterConfig{
+		apiClient:           cli,
+		concurrentTransfers: 1,
+	}, nil))
+
+	path := filepath.Join(dir, oid)
+	results := a.Add(&Transfer{
+		Name:          "a.dat",
+		Oid:           oid,
+		Size:          int64(len(rangeContents)),
+		Path:          path,
+		Authenticated: true,
+		Actions: ActionSet{
+			"download": &Action{Href: srv.URL + "/download"},
+		},
+	})
+
+	var resErr error
+	for res := range results {
+		resErr = res.Error
+	}
+	a.End()
+
+	if resErr != nil {
+		return "", resErr
+	}
+
+	// Nothing but the download itself is left beside it.
+	entries, err := ioutil.ReadDir(dir)
+	require.Nil(t, err)
+	require.Len(t, entries, 1)
+
+	by, err := ioutil.ReadFile(path)
+	require.Nil(t, err)
+	return string(by), nil
+}
+
+// serveRange sets the Content-Range header for the range given by the Range
+// header of "r", and returns its first and last bytes, or -1 if there is none.
+func serveRange(w http.ResponseWriter, r *http.Request) (int, int) {
+	var from, to int
+	if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &from, &to); err != nil {
+		return -1, -1
+	}
+	w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", from, to, len(rangeContents)))
+	return from, to
+}
+
+func rangeContentsOid() string {
+	sum := sha256.Sum256([]byte(rangeContents))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestBasicDownloadParallel(t *testing.T) {
+	var mu sync.Mutex
+	var ranges []string
+
+	contents, err := basicTestParallelDownload(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		mu.Unlock()
+
+		from, to := serveRange(w, r)
+		require.True(t, from >= 0)
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte(rangeContents[from : to+1]))
+	}, rangeContentsOid())
+
+	require.Nil(t, err)
+	assert.Equal(t, rangeContents, contents)
+
+	sort.Strings(ranges)
+	assert.Equal(t, []string{"bytes=0-9", "bytes=10-19", "bytes=20-29", "bytes=30-42"}, ranges)
+}
+
+func TestBasicDownloadParallelRetriesFailedRange(t *testing.T) {
+	var mu sync.Mutex
+	var retried []string
+	failed := false
+
+	contents, err := basicTestParallelDownload(t, func(w http.ResponseWriter, r *http.Request) {
+		from, to := serveRange(w, r)
+		require.True(t, from >= 0)
+
+		mu.Lock()
+		fail := from == 10 && !failed
+		if fail {
+			failed = true
+		} else if from >= 10 && from < 20 {
+			retried = append(retried, r.Header.Get("Range"))
+		}
+		mu.Unlock()
+
+		if fail {
+			// Send 4 of the 10 bytes promised, and then hang up.
+			w.Header().Set("Content-Length", "10")
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write([]byte(rangeContents[from : from+4]))
+			return
+		}
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte(rangeContents[from : to+1]))
+	}, rangeContentsOid())
+
+	require.Nil(t, err)
+	assert.Equal(t, rangeContents, contents)
+	assert.Equal(t, []string{"bytes=14-19"}, retried)
+}
+
+func TestBasicDownloadParallelWrongOid(t *testing.T) {
+	_, err := basicTestParallelDownload(t, func(w http.ResponseWriter, r *http.Request) {
+		from, to := serveRange(w, r)
+		require.True(t, from >= 0)
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte(strings.ToUpper(rangeContents[from : to+1])))
+	}, rangeContentsOid())
+
+	require.NotNil(t, err)
+	assert.True(t, errors.IsVerificationError(err))
+	assert.Contains(t, err.Error(), "Expected OID "+rangeContentsOid())
+}
+
+func TestSplitRanges(t *testing.T) {
+	assert.Equal(t, []*ByteRange{
+		{Offset: 0, Length: 3},
+		{Offset: 3, Length: 3},
+		{Offset: 6, Length: 4},
+	}, splitRanges(10, 3))
+}
@@ -56,7 +56,11 @@ func (a *basicUploadAdapter) DoTransfer(ctx interface{}, t *Transfer, cb Progres
 		return err
 	}
 
-	if len(req.Header.Get("Content-Type")) == 0 {
+	// The upload action of an authenticated object, such as a presigned
+	// URL, is complete as it is, and its signature may cover the headers
+	// given with it, so it is sent with exactly those, and no Content-Type
+	// of our own. Nor are credentials added to it; see doHTTP.
+	if !t.Authenticated && len(req.Header.Get("Content-Type")) == 0 {
 		req.Header.Set("Content-Type", "application/octet-stream")
 	}
 
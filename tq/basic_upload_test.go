@@ -0,0 +1,112 @@
+package tq
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/git-lfs/git-lfs/lfsapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// basicTestUpload uploads an object with the basic adapter, using the upload
+// action "action", whose Href is a path on a test server, and returns the
+// headers of the PUT request the server received.
+func basicTestUpload(t *testing.T, authenticated bool, action *Action) http.Header {
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			header = r.Header
+		}
+		ioutil.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	contents := "basic upload"
+	sum := sha256.Sum256([]byte(contents))
+	oid := hex.EncodeToString(sum[:])
+
+	dir, err := ioutil.TempDir("", "basic-upload")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, oid)
+	require.Nil(t, ioutil.WriteFile(path, []byte(contents), 0644))
+
+	cli, err := lfsapi.NewClient(nil, lfsapi.UniqTestEnv{
+		"lfs.url": srv.URL + "/api",
+	})
+	require.Nil(t, err)
+
+	m := NewManifestWithClient(cli)
+	a := m.NewUploadAdapter(BasicAdapterName)
+	require.NotNil(t, a)
+	require.Nil(t, a.Begin(&adapterConfig{
+		apiClient:           cli,
+		concurrentTransfers: 1,
+		remote:              "origin",
+	}, nil))
+
+	action.Href = srv.URL + action.Href
+	results := a.Add(&Transfer{
+		Name:          "a.dat",
+		Oid:           oid,
+		Size:          int64(len(contents)),
+		Path:          path,
+		Authenticated: authenticated,
+		Actions:       ActionSet{"upload": action},
+	})
+
+	for res := range results {
+		assert.Nil(t, res.Error)
+	}
+	a.End()
+
+	require.NotNil(t, header)
+	return header
+}
+
+func TestBasicUploadSendsOnlyActionHeadersWhenAuthenticated(t *testing.T) {
+	header := basicTestUpload(t, true, &Action{
+		Href: "/presigned?X-Amz-Signature=abc",
+		Header: map[string]string{
+			"User-Agent": "signed-agent",
+			"X-Amz-Date": "20170101T000000Z",
+		},
+	})
+
+	var names []string
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Go's transport always asks for compressed responses, and the length
+	// of the body is part of the request itself.
+	assert.Equal(t, []string{"Accept-Encoding", "Content-Length", "User-Agent", "X-Amz-Date"}, names)
+	assert.Equal(t, "signed-agent", header.Get("User-Agent"))
+	assert.Equal(t, "20170101T000000Z", header.Get("X-Amz-Date"))
+}
+
+func TestBasicUploadSendsDefaultContentType(t *testing.T) {
+	header := basicTestUpload(t, false, &Action{Href: "/upload"})
+
+	assert.Equal(t, "application/octet-stream", header.Get("Content-Type"))
+	assert.Equal(t, lfsapi.UserAgent, header.Get("User-Agent"))
+}
+
+func TestBasicUploadKeepsActionContentType(t *testing.T) {
+	header := basicTestUpload(t, true, &Action{
+		Href:   "/upload",
+		Header: map[string]string{"Content-Type": "binary/octet-stream"},
+	})
+
+	assert.Equal(t, "binary/octet-stream", header.Get("Content-Type"))
+}
@@ -261,6 +261,9 @@ func (a *customAdapter) DoTransfer(ctx interface{}, t *Transfer, cb ProgressCall
 	if !ok {
 		return fmt.Errorf("Context object for custom transfer %q was of the wrong type", a.name)
 	}
+	if t.Range != nil {
+		return fmt.Errorf("Custom transfer %q does not support partial downloads", a.name)
+	}
 	var authCalled bool
 
 	rel, err := t.Rel(a.getOperationName())
@@ -311,6 +314,9 @@ func (a *customAdapter) DoTransfer(ctx interface{}, t *Transfer, cb ProgressCall
 				if err = tools.VerifyFileHash(t.Oid, resp.Path); err != nil {
 					return fmt.Errorf("Downloaded file failed checks: %v", err)
 				}
+				if err = verifyDownload(a.apiClient, a.remote, t); err != nil {
+					return err
+				}
 				// Move file to final location
 				if err = tools.RenameFileCopyPermissions(resp.Path, t.Path); err != nil {
 					return fmt.Errorf("Failed to copy downloaded file: %v", err)
@@ -365,9 +371,10 @@ func configureCustomAdapters(git Env, m *Manifest) {
 		}
 
 		// Separate closure for each since we need to capture vars above
+		// Whether the adapter is used standalone depends on the
+		// endpoint, so it is decided by the queue which uses it.
 		newfunc := func(name string, dir Direction) Adapter {
-			standalone := m.standaloneTransferAgent != ""
-			return newCustomAdapter(name, dir, path, args, concurrent, standalone)
+			return newCustomAdapter(name, dir, path, args, concurrent, false)
 		}
 
 		if direction == "download" || direction == "both" {
@@ -27,3 +27,18 @@ func (e MalformedObjectError) Error() string {
 	}
 	return fmt.Sprintf("missing object: %s (%s)", e.Name, e.Oid)
 }
+
+// TransferCancelledError is returned for a transfer which was cancelled while
+// it was in flight.
+type TransferCancelledError struct {
+	Name string
+	Oid  string
+}
+
+func newTransferCancelledError(name, oid string) error {
+	return &TransferCancelledError{Name: name, Oid: oid}
+}
+
+func (e TransferCancelledError) Error() string {
+	return fmt.Sprintf("transfer cancelled: %s (%s)", e.Name, e.Oid)
+}
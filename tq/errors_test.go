@@ -21,3 +21,11 @@ func TestCorruptObjectErrorsAreRecognizable(t *testing.T) {
 	assert.Equal(t, "some-oid", err.Oid)
 	assert.True(t, err.Corrupt())
 }
+
+func TestTransferCancelledErrorsAreRecognizable(t *testing.T) {
+	err := newTransferCancelledError("some-name", "some-oid").(*TransferCancelledError)
+
+	assert.Equal(t, "some-name", err.Name)
+	assert.Equal(t, "some-oid", err.Oid)
+	assert.Equal(t, "transfer cancelled: some-name (some-oid)", err.Error())
+}
@@ -1,9 +1,13 @@
 package tq
 
 import (
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/git-lfs/git-lfs/config"
 	"github.com/git-lfs/git-lfs/lfsapi"
+	"github.com/git-lfs/git-lfs/tools"
 	"github.com/rubyist/tracerx"
 )
 
@@ -15,11 +19,41 @@ const (
 type Manifest struct {
 	// maxRetries is the maximum number of retries a single object can
 	// attempt to make before it will be dropped.
-	maxRetries              int
+	maxRetries int
+	// batchRetries is the number of times a failed batch API request is
+	// retried, independently of the retries of the objects in it.
+	batchRetries int
+	// batchTimeout, batchConnectTimeout and batchHeaderTimeout limit how
+	// long a batch API request may take, separately from the timeouts of
+	// the transfers themselves, so that negotiating fails quickly.
+	batchTimeout        time.Duration
+	batchConnectTimeout time.Duration
+	batchHeaderTimeout  time.Duration
+	// trustServerSize is whether downloaded objects may have a different
+	// size than their pointers give, as long as their OID matches. It is
+	// for servers which are known to report object sizes incorrectly.
+	trustServerSize bool
+	// parallelObjectThreshold is the size, in bytes, from which an object
+	// is downloaded in parallelObjectConnections ranges at once, rather
+	// than over a single connection, or 0 if no object is.
+	parallelObjectThreshold   int64
+	parallelObjectConnections int
+	// mirrorStore is the directory to which each downloaded object is also
+	// written, as lfs.mirrorstore gives with a leading "~" expanded, or
+	// empty if there is none.
+	mirrorStore string
+	// auditLog is the file to which a record of each object downloaded, or
+	// which failed to download, is appended, as lfs.auditlog gives, or
+	// empty if there is none.
+	auditLog string
+
 	concurrentTransfers     int
 	basicTransfersOnly      bool
 	standaloneTransferAgent string
+	disabledAdapters        map[string]bool
+	urlConfig               *config.URLConfig
 	tusTransfersAllowed     bool
+	tusChunkSize            int64
 	downloadAdapterFuncs    map[string]NewAdapterFunc
 	uploadAdapterFuncs      map[string]NewAdapterFunc
 	apiClient               *lfsapi.Client
@@ -35,10 +69,36 @@ func (m *Manifest) MaxRetries() int {
 	return m.maxRetries
 }
 
+func (m *Manifest) BatchRetries() int {
+	return m.batchRetries
+}
+
+func (m *Manifest) TrustServerSize() bool {
+	return m.trustServerSize
+}
+
 func (m *Manifest) ConcurrentTransfers() int {
 	return m.concurrentTransfers
 }
 
+// standaloneTransferAgentFor returns the name of the custom transfer agent to
+// use directly for transfers in direction "dir" with "remote", without asking
+// the server how to make them, or an empty string if the server is to be asked.
+// It is given by "lfs.<url>.standalonetransferagent" for the remote's endpoint,
+// which applies to endpoints with schemes the client does not speak itself,
+// such as "grpc://", too, and otherwise by "lfs.standalonetransferagent".
+func (m *Manifest) standaloneTransferAgentFor(dir Direction, remote string) string {
+	if m.urlConfig != nil {
+		e := m.apiClient.Endpoints.Endpoint(dir.String(), remote)
+		if len(e.Url) > 0 {
+			if name, ok := m.urlConfig.Get("lfs", e.Url, "standalonetransferagent"); ok {
+				return name
+			}
+		}
+	}
+	return m.standaloneTransferAgent
+}
+
 func (m *Manifest) batchClient() *tqClient {
 	return m.tqClient
 }
@@ -56,29 +116,76 @@ func NewManifest() *Manifest {
 func NewManifestWithClient(apiClient *lfsapi.Client) *Manifest {
 	m := &Manifest{
 		apiClient:            apiClient,
-		tqClient:             &tqClient{Client: apiClient},
+		tqClient:             &tqClient{Client: apiClient, cache: newObjectCache()},
 		downloadAdapterFuncs: make(map[string]NewAdapterFunc),
 		uploadAdapterFuncs:   make(map[string]NewAdapterFunc),
 	}
 
-	var tusAllowed bool
+	var tusAllowed, multipartAllowed bool
 	if git := apiClient.GitEnv(); git != nil {
 		if v := git.Int("lfs.transfer.maxretries", 0); v > 0 {
 			m.maxRetries = v
 		}
+		if v := git.Int("lfs.transfer.batchretries", 0); v > 0 {
+			m.batchRetries = v
+		}
+		if v := git.Int("lfs.transfer.batchtimeout", 0); v > 0 {
+			m.batchTimeout = time.Duration(v) * time.Second
+		}
+		if v := git.Int("lfs.transfer.batchconnecttimeout", 0); v > 0 {
+			m.batchConnectTimeout = time.Duration(v) * time.Second
+		}
+		if v := git.Int("lfs.transfer.batchheadertimeout", 0); v > 0 {
+			m.batchHeaderTimeout = time.Duration(v) * time.Second
+		}
 		if v := git.Int("lfs.concurrenttransfers", 0); v > 0 {
 			m.concurrentTransfers = v
 		}
+		m.trustServerSize = git.Bool("lfs.transfer.trustserversize", false)
+		if v := git.Int("lfs.transfer.parallelobjectthreshold", 0); v > 0 {
+			m.parallelObjectThreshold = int64(v)
+		}
+		m.parallelObjectConnections = git.Int("lfs.transfer.parallelobjectconnections", 0)
+		if dir, _ := git.Get("lfs.mirrorstore"); len(dir) > 0 {
+			var home string
+			if osEnv := apiClient.OSEnv(); osEnv != nil {
+				home, _ = osEnv.Get("HOME")
+			}
+			m.mirrorStore = tools.ExpandPath(dir, home)
+		}
+		m.auditLog, _ = git.Get("lfs.auditlog")
 		m.basicTransfersOnly = git.Bool("lfs.basictransfersonly", false)
 		m.standaloneTransferAgent, _ = git.Get("lfs.standalonetransferagent")
+		m.urlConfig = config.NewURLConfig(git)
 		tusAllowed = git.Bool("lfs.tustransfers", false)
+		if v := git.Int("lfs.tustransfers.chunksize", 0); v > 0 {
+			m.tusChunkSize = int64(v)
+		}
+		multipartAllowed = git.Bool("lfs.multiparttransfers", false)
 		configureCustomAdapters(git, m)
+		disabled, _ := git.Get("lfs.transfer.disableadapters")
+		m.disableAdapters(disabled)
+	}
+	if osEnv := apiClient.OSEnv(); osEnv != nil {
+		disabled, _ := osEnv.Get("GIT_LFS_DISABLE_ADAPTERS")
+		m.disableAdapters(disabled)
 	}
 
 	if m.maxRetries < 1 {
 		m.maxRetries = defaultMaxRetries
 	}
 
+	m.tqClient.maxRetries = m.batchRetries
+	m.tqClient.timeout = m.batchTimeout
+	m.tqClient.timeouts = lfsapi.Timeouts{
+		Connect: m.batchConnectTimeout,
+		Header:  m.batchHeaderTimeout,
+	}
+
+	if m.parallelObjectConnections < 1 {
+		m.parallelObjectConnections = defaultParallelObjectConnections
+	}
+
 	if m.concurrentTransfers < 1 {
 		m.concurrentTransfers = defaultConcurrentTransfers
 	}
@@ -88,9 +195,33 @@ func NewManifestWithClient(apiClient *lfsapi.Client) *Manifest {
 	if tusAllowed {
 		configureTusAdapter(m)
 	}
+	if multipartAllowed {
+		configureMultipartAdapter(m)
+	}
 	return m
 }
 
+// disableAdapters prevents the adapters with the names given in the comma
+// separated "list" from being advertised to the server, or created. The basic
+// adapter cannot be disabled, so that there is always one to fall back on.
+func (m *Manifest) disableAdapters(list string) {
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if len(name) == 0 {
+			continue
+		}
+		if name == BasicAdapterName {
+			tracerx.Printf("tq: the %q transfer adapter cannot be disabled", name)
+			continue
+		}
+
+		if m.disabledAdapters == nil {
+			m.disabledAdapters = make(map[string]bool)
+		}
+		m.disabledAdapters[name] = true
+	}
+}
+
 // GetAdapterNames returns a list of the names of adapters available to be created
 func (m *Manifest) GetAdapterNames(dir Direction) []string {
 	switch dir {
@@ -123,6 +254,9 @@ func (m *Manifest) getAdapterNames(adapters map[string]NewAdapterFunc) []string
 
 	ret := make([]string, 0, len(adapters))
 	for n, _ := range adapters {
+		if m.disabledAdapters[n] {
+			continue
+		}
 		ret = append(ret, n)
 	}
 	return ret
@@ -157,8 +291,14 @@ func (m *Manifest) NewAdapterOrDefault(name string, dir Direction) Adapter {
 	return a
 }
 
-// Create a new adapter by name and direction, or nil if doesn't exist
+// Create a new adapter by name and direction, or nil if doesn't exist, or has
+// been disabled
 func (m *Manifest) NewAdapter(name string, dir Direction) Adapter {
+	if m.disabledAdapters[name] {
+		tracerx.Printf("tq: not using disabled transfer adapter %q", name)
+		return nil
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -1,7 +1,9 @@
 package tq
 
 import (
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/git-lfs/git-lfs/lfsapi"
 	"github.com/stretchr/testify/assert"
@@ -18,6 +20,139 @@ func TestManifestIsConfigurable(t *testing.T) {
 	assert.Equal(t, 3, m.MaxRetries())
 }
 
+func TestManifestBatchRetries(t *testing.T) {
+	cli, err := lfsapi.NewClient(nil, lfsapi.UniqTestEnv(map[string]string{
+		"lfs.transfer.batchretries": "4",
+	}))
+	require.Nil(t, err)
+
+	m := NewManifestWithClient(cli)
+	assert.Equal(t, 4, m.BatchRetries())
+	assert.Equal(t, 4, m.batchClient().maxRetries)
+
+	cli, err = lfsapi.NewClient(nil, nil)
+	require.Nil(t, err)
+
+	m = NewManifestWithClient(cli)
+	assert.Equal(t, 0, m.BatchRetries())
+}
+
+func TestManifestBatchTimeouts(t *testing.T) {
+	cli, err := lfsapi.NewClient(nil, lfsapi.UniqTestEnv(map[string]string{
+		"lfs.transfer.batchtimeout":        "20",
+		"lfs.transfer.batchconnecttimeout": "5",
+		"lfs.transfer.batchheadertimeout":  "10",
+	}))
+	require.Nil(t, err)
+
+	m := NewManifestWithClient(cli)
+	assert.Equal(t, 20*time.Second, m.batchClient().timeout)
+	assert.Equal(t, 5*time.Second, m.batchClient().timeouts.Connect)
+	assert.Equal(t, 10*time.Second, m.batchClient().timeouts.Header)
+
+	cli, err = lfsapi.NewClient(nil, nil)
+	require.Nil(t, err)
+
+	m = NewManifestWithClient(cli)
+	assert.Equal(t, time.Duration(0), m.batchClient().timeout)
+	assert.Equal(t, lfsapi.Timeouts{}, m.batchClient().timeouts)
+}
+
+func TestManifestTrustServerSize(t *testing.T) {
+	cli, err := lfsapi.NewClient(nil, lfsapi.UniqTestEnv(map[string]string{
+		"lfs.transfer.trustserversize": "true",
+	}))
+	require.Nil(t, err)
+
+	m := NewManifestWithClient(cli)
+	assert.True(t, m.TrustServerSize())
+	a := m.NewDownloadAdapter(BasicAdapterName).(*basicDownloadAdapter)
+	assert.True(t, a.trustServerSize)
+
+	cli, err = lfsapi.NewClient(nil, nil)
+	require.Nil(t, err)
+
+	m = NewManifestWithClient(cli)
+	assert.False(t, m.TrustServerSize())
+	a = m.NewDownloadAdapter(BasicAdapterName).(*basicDownloadAdapter)
+	assert.False(t, a.trustServerSize)
+}
+
+func TestManifestExpandsMirrorStore(t *testing.T) {
+	cli, err := lfsapi.NewClient(lfsapi.UniqTestEnv(map[string]string{
+		"HOME": "/home/user",
+	}), lfsapi.UniqTestEnv(map[string]string{
+		"lfs.mirrorstore": "~/mirror",
+	}))
+	require.Nil(t, err)
+
+	m := NewManifestWithClient(cli)
+	assert.Equal(t, filepath.Join("/home/user", "mirror"), m.mirrorStore)
+}
+
+func TestManifestStandaloneTransferAgentForURL(t *testing.T) {
+	cli, err := lfsapi.NewClient(nil, lfsapi.UniqTestEnv(map[string]string{
+		"remote.origin.url":   "https://example.com/repo.git",
+		"remote.store.url":    "https://example.com/store.git",
+		"remote.store.lfsurl": "grpc://store.example.com/repo",
+		"lfs.grpc://store.example.com.standalonetransferagent": "grpc-agent",
+		"lfs.customtransfer.grpc-agent.path":                   "/path/to/grpc-agent",
+	}))
+	require.Nil(t, err)
+
+	m := NewManifestWithClient(cli)
+	assert.Equal(t, "grpc-agent", m.standaloneTransferAgentFor(Download, "store"))
+	assert.Equal(t, "grpc-agent", m.standaloneTransferAgentFor(Upload, "store"))
+	assert.Equal(t, "", m.standaloneTransferAgentFor(Download, "origin"))
+
+	// the agent is only used standalone with the endpoint it is set for
+	q := NewTransferQueue(Download, m, "store")
+	q.useAdapter("grpc-agent")
+	assert.True(t, q.adapter.(*customAdapter).standalone)
+	q.Wait()
+
+	q = NewTransferQueue(Download, m, "origin")
+	q.useAdapter("grpc-agent")
+	assert.False(t, q.adapter.(*customAdapter).standalone)
+	q.Wait()
+}
+
+func TestManifestStandaloneTransferAgentFallback(t *testing.T) {
+	cli, err := lfsapi.NewClient(nil, lfsapi.UniqTestEnv(map[string]string{
+		"remote.origin.url":           "https://example.com/repo.git",
+		"lfs.standalonetransferagent": "agent",
+		"lfs.https://example.com/repo.git/info/lfs.standalonetransferagent": "",
+		"remote.other.url": "https://example.com/other.git",
+	}))
+	require.Nil(t, err)
+
+	m := NewManifestWithClient(cli)
+	assert.Equal(t, "", m.standaloneTransferAgentFor(Download, "origin"))
+	assert.Equal(t, "agent", m.standaloneTransferAgentFor(Download, "other"))
+}
+
+func TestManifestDisablesAdapters(t *testing.T) {
+	cli, err := lfsapi.NewClient(lfsapi.UniqTestEnv(map[string]string{
+		"GIT_LFS_DISABLE_ADAPTERS": "multipart",
+	}), lfsapi.UniqTestEnv(map[string]string{
+		"lfs.tustransfers":                   "true",
+		"lfs.multiparttransfers":             "true",
+		"lfs.customtransfer.testsimple.path": "/path/to/binary",
+		"lfs.transfer.disableadapters":       "tus, testsimple,basic",
+	}))
+	require.Nil(t, err)
+
+	m := NewManifestWithClient(cli)
+	assert.Equal(t, []string{BasicAdapterName}, m.GetUploadAdapterNames())
+	assert.Equal(t, []string{BasicAdapterName}, m.GetDownloadAdapterNames())
+
+	assert.Nil(t, m.NewAdapter("tus", Upload))
+	assert.Nil(t, m.NewAdapter("testsimple", Download))
+	assert.Nil(t, m.NewAdapter("multipart", Upload))
+	assert.Equal(t, BasicAdapterName, m.NewUploadAdapter("tus").Name())
+	assert.Equal(t, BasicAdapterName, m.NewDownloadAdapter(BasicAdapterName).Name())
+}
+
 func TestManifestChecksNTLM(t *testing.T) {
 	cli, err := lfsapi.NewClient(nil, lfsapi.UniqTestEnv(map[string]string{
 		"lfs.url":                 "http://foo",
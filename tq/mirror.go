@@ -0,0 +1,45 @@
+package tq
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/git-lfs/git-lfs/tools"
+	"github.com/rubyist/tracerx"
+)
+
+// mirror writes the object "t" has just downloaded to t.Path into the mirror
+// store given by lfs.mirrorstore, if there is one, so that a shared cache is
+// filled by the downloads of everyone who uses it. The object is laid out as it
+// is in the local object store, and is hard linked there if it can be, rather
+// than copied. A range of an object is not mirrored, since it is only part of
+// it. A failure to mirror the object is only warned about, since the object has
+// been downloaded all the same.
+func (q *TransferQueue) mirror(t *Transfer) {
+	if len(q.manifest.mirrorStore) == 0 || t.Range != nil || len(t.Oid) < 5 {
+		return
+	}
+
+	path := mirrorPath(q.manifest.mirrorStore, t.Oid)
+	if tools.FileExistsOfSize(path, t.Size) {
+		return
+	}
+
+	if err := mirrorObject(t.Path, path); err != nil {
+		tracerx.Printf("tq: could not write %s to the mirror store %q: %s", t.Oid, path, err)
+		return
+	}
+	tracerx.Printf("tq: mirrored %s to %q", t.Oid, path)
+}
+
+// mirrorPath returns where the object "oid" is kept in the mirror store "dir".
+func mirrorPath(dir, oid string) string {
+	return filepath.Join(dir, oid[0:2], oid[2:4], oid)
+}
+
+func mirrorObject(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return tools.LinkOrCopyFile(src, dst)
+}
@@ -0,0 +1,360 @@
+package tq
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/git-lfs/git-lfs/lfsapi"
+	"github.com/git-lfs/git-lfs/progress"
+	"github.com/git-lfs/git-lfs/tools"
+	"github.com/rubyist/tracerx"
+)
+
+const (
+	MultipartAdapterName = "multipart"
+
+	// multipartPartRelPrefix is the prefix of the relation names of the
+	// actions used to upload each part of an object, as in "part-1",
+	// "part-2", and so on.
+	multipartPartRelPrefix = "part-"
+)
+
+// Adapter for multipart uploads, where the server splits an object into parts
+// that are uploaded in parallel, followed by a request telling the server that
+// all parts have been sent.
+type multipartUploadAdapter struct {
+	*adapterBase
+
+	// partc is a semaphore limiting the number of parts in flight across
+	// all workers to the number of concurrent transfers.
+	partc chan struct{}
+}
+
+// multipartPart is an individual part of an object being uploaded.
+type multipartPart struct {
+	Number int
+	Offset int64
+	Size   int64
+	Action *Action
+}
+
+// multipartCompletedPart is the representation of an uploaded part sent in the
+// body of the "complete" request.
+type multipartCompletedPart struct {
+	Number int    `json:"part_number"`
+	ETag   string `json:"etag,omitempty"`
+}
+
+func (a *multipartUploadAdapter) ClearTempStorage() error {
+	// nothing to do, all temp state is on the server end
+	return nil
+}
+
+func (a *multipartUploadAdapter) Begin(cfg AdapterConfig, cb ProgressCallback) error {
+	a.partc = make(chan struct{}, tools.MaxInt(1, cfg.ConcurrentTransfers()))
+	return a.adapterBase.Begin(cfg, cb)
+}
+
+func (a *multipartUploadAdapter) WorkerStarting(workerNum int) (interface{}, error) {
+	return nil, nil
+}
+func (a *multipartUploadAdapter) WorkerEnding(workerNum int, ctx interface{}) {
+}
+
+func (a *multipartUploadAdapter) DoTransfer(ctx interface{}, t *Transfer, cb ProgressCallback, authOkFunc func()) error {
+	parts, err := a.parts(t)
+	if err != nil {
+		return err
+	}
+
+	complete, err := t.Rel("complete")
+	if err != nil {
+		return err
+	}
+	if complete == nil {
+		return errors.Errorf("No complete action for object: %s", t.Oid)
+	}
+
+	f, err := os.OpenFile(t.Path, os.O_RDONLY, 0644)
+	if err != nil {
+		return errors.Wrap(err, "multipart upload")
+	}
+	defer f.Close()
+
+	// Parts are uploaded out of order, so the object is hashed in its
+	// entirety up front to make sure that what is sent matches its OID.
+	hasher := tools.NewHashingReader(f)
+	if _, err := io.Copy(ioutil.Discard, hasher); err != nil {
+		return errors.Wrap(err, "multipart upload")
+	}
+	if actual := hasher.Hash(); actual != t.Oid {
+		return newCorruptObjectError(t.Name, t.Oid)
+	}
+
+	// Wrap callback to give name context, and to report progress across
+	// all parts rather than per-part.
+	var readSoFar int64
+	ccb := func(totalSize int64, partSoFar int64, readSinceLast int) error {
+		read := atomic.AddInt64(&readSoFar, int64(readSinceLast))
+		if cb != nil {
+			return cb(t.Name, t.Size, read, readSinceLast)
+		}
+		return nil
+	}
+
+	// Signal auth was ok on the first read of any part; this frees up
+	// other workers to start
+	var authOnce sync.Once
+	onStart := func() error {
+		if authOkFunc != nil {
+			authOnce.Do(authOkFunc)
+		}
+		return nil
+	}
+
+	etags := make([]string, len(parts))
+	errs := make([]error, len(parts))
+
+	var wg sync.WaitGroup
+	wg.Add(len(parts))
+
+	for i, part := range parts {
+		go func(i int, part *multipartPart) {
+			defer wg.Done()
+
+			a.partc <- struct{}{}
+			defer func() { <-a.partc }()
+
+			section := io.NewSectionReader(f, part.Offset, part.Size)
+			etags[i], errs[i] = a.uploadPartWithRetries(t, part, section, ccb, onStart)
+		}(i, part)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	completed := make([]*multipartCompletedPart, 0, len(parts))
+	for i, part := range parts {
+		completed = append(completed, &multipartCompletedPart{
+			Number: part.Number,
+			ETag:   etags[i],
+		})
+	}
+
+	if err := a.complete(t, complete, completed); err != nil {
+		return err
+	}
+
+	return verifyUpload(a.apiClient, a.remote, t)
+}
+
+// parts returns the parts of the object "t", as given by its "part-1" through
+// "part-N" actions. The object is split into N parts of equal size, rounded up,
+// with the last part holding the remainder. An empty object is sent as a single
+// empty part, however many parts the server asked for.
+func (a *multipartUploadAdapter) parts(t *Transfer) ([]*multipartPart, error) {
+	var actions []*Action
+	for n := 1; ; n++ {
+		rel, err := t.Rel(multipartPartRelPrefix + strconv.Itoa(n))
+		if err != nil {
+			return nil, err
+		}
+		if rel == nil {
+			break
+		}
+		actions = append(actions, rel)
+	}
+
+	if len(actions) == 0 {
+		return nil, errors.Errorf("No upload parts for object: %s", t.Oid)
+	}
+
+	if t.Size == 0 {
+		return []*multipartPart{{Number: 1, Action: actions[0]}}, nil
+	}
+
+	count := int64(len(actions))
+	partSize := (t.Size + count - 1) / count
+
+	parts := make([]*multipartPart, 0, len(actions))
+	for i, action := range actions {
+		offset := int64(i) * partSize
+		size := tools.MinInt64(partSize, t.Size-offset)
+		if size <= 0 {
+			return nil, errors.Errorf("Too many upload parts (%d) for object: %s (%d bytes)", count, t.Oid, t.Size)
+		}
+
+		parts = append(parts, &multipartPart{
+			Number: i + 1,
+			Offset: offset,
+			Size:   size,
+			Action: action,
+		})
+	}
+	return parts, nil
+}
+
+// uploadPartWithRetries uploads a single part, retrying it on its own when a
+// retriable error is encountered, so that one failed part does not cause the
+// others to be uploaded again. It returns the ETag given by the server for the
+// part, if any.
+func (a *multipartUploadAdapter) uploadPartWithRetries(t *Transfer, part *multipartPart, section *io.SectionReader, cb progress.CopyCallback, onStart func() error) (string, error) {
+	maxAttempts := tools.MaxInt(1, a.apiClient.GitEnv().Int("lfs.transfer.maxretries", defaultMaxRetries))
+
+	var err error
+	for i := 1; i <= maxAttempts; i++ {
+		var etag string
+		etag, err = a.uploadPart(t, part, section, cb, onStart)
+		if err == nil {
+			return etag, nil
+		}
+
+		if !errors.IsRetriableError(err) {
+			break
+		}
+
+		tracerx.Printf("xfer: multipart upload of part %d of %q failed (attempt #%d of %d): %s", part.Number, t.Oid, i, maxAttempts, err)
+	}
+	return "", err
+}
+
+func (a *multipartUploadAdapter) uploadPart(t *Transfer, part *multipartPart, section *io.SectionReader, cb progress.CopyCallback, onStart func() error) (string, error) {
+	a.Trace("xfer: sending multipart upload of part %d of %q (%d bytes from %d)", part.Number, t.Oid, part.Size, part.Offset)
+
+	req, err := a.newHTTPRequest("PUT", part.Action)
+	if err != nil {
+		return "", err
+	}
+
+	if len(req.Header.Get("Content-Type")) == 0 {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	req.Header.Set("Content-Length", strconv.FormatInt(part.Size, 10))
+	req.ContentLength = part.Size
+
+	if _, err := section.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	cbr := progress.NewBodyWithCallback(&multipartSection{section}, part.Size, cb)
+	req.Body = newStartCallbackReader(cbr, onStart)
+	if part.Size == 0 {
+		// An empty part has no body to read, which would otherwise
+		// signal that the upload has started.
+		if err := onStart(); err != nil {
+			return "", err
+		}
+		req.Body = http.NoBody
+	}
+
+	req = a.apiClient.LogRequest(req, "lfs.data.upload")
+	res, err := a.doHTTP(t, req)
+	if err != nil {
+		// Decrement the number of bytes sent for this part so far, so
+		// that a retry doesn't count them twice.
+		if perr := cbr.ResetProgress(); perr != nil {
+			err = errors.Wrap(err, perr.Error())
+		}
+
+		return "", errors.NewRetriableError(err)
+	}
+
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+
+	// A status code of 403 likely means that an authentication token for the
+	// upload has expired. This can be safely retried.
+	if res.StatusCode == 403 {
+		cbr.ResetProgress()
+		err = errors.New("http: received status 403")
+		return "", errors.NewRetriableError(err)
+	}
+
+	if res.StatusCode > 299 {
+		return "", errors.Wrapf(nil, "Invalid status for %s %s: %d",
+			req.Method,
+			strings.SplitN(req.URL.String(), "?", 2)[0],
+			res.StatusCode,
+		)
+	}
+
+	return res.Header.Get("ETag"), nil
+}
+
+// complete tells the server that all parts of "t" have been uploaded.
+func (a *multipartUploadAdapter) complete(t *Transfer, action *Action, parts []*multipartCompletedPart) error {
+	a.Trace("xfer: sending multipart complete request for %q (%d parts)", t.Oid, len(parts))
+
+	req, err := http.NewRequest("POST", action.Href, nil)
+	if err != nil {
+		return err
+	}
+
+	err = lfsapi.MarshalToRequest(req, struct {
+		Oid   string                    `json:"oid"`
+		Size  int64                     `json:"size"`
+		Parts []*multipartCompletedPart `json:"parts"`
+	}{Oid: t.Oid, Size: t.Size, Parts: parts})
+	if err != nil {
+		return err
+	}
+
+	for key, value := range action.Header {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("Content-Type", "application/vnd.git-lfs+json")
+
+	req = a.apiClient.LogRequest(req, "lfs.data.upload")
+	res, err := a.doHTTP(t, req)
+	if err != nil {
+		return errors.NewRetriableError(err)
+	}
+
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+
+	if res.StatusCode > 299 {
+		return fmt.Errorf("Invalid status for %s %s: %d",
+			req.Method,
+			strings.SplitN(req.URL.String(), "?", 2)[0],
+			res.StatusCode,
+		)
+	}
+	return nil
+}
+
+// multipartSection adapts an *io.SectionReader into an
+// lfsapi.ReadSeekCloser. Closing it is a no-op, since the underlying file is
+// shared between all parts.
+type multipartSection struct {
+	*io.SectionReader
+}
+
+func (s *multipartSection) Close() error { return nil }
+
+func configureMultipartAdapter(m *Manifest) {
+	m.RegisterNewAdapterFunc(MultipartAdapterName, Upload, func(name string, dir Direction) Adapter {
+		switch dir {
+		case Upload:
+			mu := &multipartUploadAdapter{adapterBase: newAdapterBase(name, dir, nil)}
+			// self implements impl
+			mu.transferImpl = mu
+			return mu
+		case Download:
+			panic("Should never ask multipart to download")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,303 @@
+package tq
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/git-lfs/git-lfs/lfsapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultipartAdapterIsOptIn(t *testing.T) {
+	cli, err := lfsapi.NewClient(nil, nil)
+	require.Nil(t, err)
+
+	m := NewManifestWithClient(cli)
+	assert.Nil(t, m.NewAdapter(MultipartAdapterName, Upload))
+
+	cli, err = lfsapi.NewClient(nil, lfsapi.UniqTestEnv{
+		"lfs.multiparttransfers": "true",
+	})
+	require.Nil(t, err)
+
+	m = NewManifestWithClient(cli)
+	assert.NotNil(t, m.NewAdapter(MultipartAdapterName, Upload))
+	assert.Contains(t, m.GetUploadAdapterNames(), MultipartAdapterName)
+	assert.NotContains(t, m.GetDownloadAdapterNames(), MultipartAdapterName)
+}
+
+func TestMultipartPartsSplitsObject(t *testing.T) {
+	a := &multipartUploadAdapter{}
+	parts, err := a.parts(&Transfer{
+		Oid:  "oid",
+		Size: 10,
+		Actions: ActionSet{
+			"part-1":   &Action{Href: "1"},
+			"part-2":   &Action{Href: "2"},
+			"part-3":   &Action{Href: "3"},
+			"part-5":   &Action{Href: "5"},
+			"complete": &Action{Href: "complete"},
+		},
+	})
+	require.Nil(t, err)
+	require.Len(t, parts, 3)
+
+	assert.Equal(t, &multipartPart{Number: 1, Offset: 0, Size: 4, Action: &Action{Href: "1"}}, parts[0])
+	assert.Equal(t, &multipartPart{Number: 2, Offset: 4, Size: 4, Action: &Action{Href: "2"}}, parts[1])
+	assert.Equal(t, &multipartPart{Number: 3, Offset: 8, Size: 2, Action: &Action{Href: "3"}}, parts[2])
+}
+
+func TestMultipartPartsRejectsEmptyParts(t *testing.T) {
+	a := &multipartUploadAdapter{}
+	_, err := a.parts(&Transfer{
+		Oid:  "oid",
+		Size: 9,
+		Actions: ActionSet{
+			"part-1": &Action{Href: "1"},
+			"part-2": &Action{Href: "2"},
+			"part-3": &Action{Href: "3"},
+			"part-4": &Action{Href: "4"},
+		},
+	})
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Too many upload parts")
+}
+
+func TestMultipartPartsOfEmptyObject(t *testing.T) {
+	a := &multipartUploadAdapter{}
+	parts, err := a.parts(&Transfer{
+		Oid:  "oid",
+		Size: 0,
+		Actions: ActionSet{
+			"part-1": &Action{Href: "1"},
+			"part-2": &Action{Href: "2"},
+		},
+	})
+	require.Nil(t, err)
+	require.Len(t, parts, 1)
+
+	assert.Equal(t, &multipartPart{Number: 1, Offset: 0, Size: 0, Action: &Action{Href: "1"}}, parts[0])
+}
+
+func TestMultipartUploadEmptyObject(t *testing.T) {
+	sum := sha256.Sum256(nil)
+	oid := hex.EncodeToString(sum[:])
+
+	var mu sync.Mutex
+	received := make(map[string][]string)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		by, err := ioutil.ReadAll(r.Body)
+		assert.Nil(t, err)
+
+		mu.Lock()
+		received[r.URL.Path] = append(received[r.URL.Path], string(by))
+		mu.Unlock()
+
+		w.Header().Set("ETag", "etag")
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "multipart-upload")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, oid)
+	require.Nil(t, ioutil.WriteFile(path, nil, 0644))
+
+	cli, err := lfsapi.NewClient(nil, lfsapi.UniqTestEnv{
+		"lfs.multiparttransfers": "true",
+	})
+	require.Nil(t, err)
+
+	a := NewManifestWithClient(cli).NewUploadAdapter(MultipartAdapterName)
+	require.Nil(t, a.Begin(&adapterConfig{
+		apiClient:           cli,
+		concurrentTransfers: 2,
+		remote:              "origin",
+	}, nil))
+
+	results := a.Add(&Transfer{
+		Name:          "empty.dat",
+		Oid:           oid,
+		Size:          0,
+		Path:          path,
+		Authenticated: true,
+		Actions: ActionSet{
+			"part-1":   &Action{Href: srv.URL + "/part-1"},
+			"part-2":   &Action{Href: srv.URL + "/part-2"},
+			"complete": &Action{Href: srv.URL + "/complete"},
+		},
+	})
+
+	for res := range results {
+		assert.Nil(t, res.Error)
+	}
+	a.End()
+
+	assert.Equal(t, []string{""}, received["/part-1"])
+	assert.Empty(t, received["/part-2"])
+	assert.Len(t, received["/complete"], 1)
+}
+
+func TestMultipartPartsWithoutParts(t *testing.T) {
+	a := &multipartUploadAdapter{}
+	_, err := a.parts(&Transfer{Oid: "oid", Size: 9})
+	require.NotNil(t, err)
+	assert.Equal(t, "No upload parts for object: oid", err.Error())
+}
+
+func TestMultipartUploadRetriesFailedPartOnly(t *testing.T) {
+	contents := "the quick brown fox jumps over the lazy dog"
+	sum := sha256.Sum256([]byte(contents))
+	oid := hex.EncodeToString(sum[:])
+
+	var mu sync.Mutex
+	received := make(map[string][]string)
+	failed := false
+
+	var completed struct {
+		Oid   string                    `json:"oid"`
+		Size  int64                     `json:"size"`
+		Parts []*multipartCompletedPart `json:"parts"`
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/complete" {
+			assert.Equal(t, "POST", r.Method)
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&completed))
+			return
+		}
+
+		assert.Equal(t, "PUT", r.Method)
+		by, err := ioutil.ReadAll(r.Body)
+		assert.Nil(t, err)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		received[r.URL.Path] = append(received[r.URL.Path], string(by))
+		if r.URL.Path == "/part-2" && !failed {
+			failed = true
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("ETag", "etag"+strings.TrimPrefix(r.URL.Path, "/part-"))
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "multipart-upload")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, oid)
+	require.Nil(t, ioutil.WriteFile(path, []byte(contents), 0644))
+
+	cli, err := lfsapi.NewClient(nil, lfsapi.UniqTestEnv{
+		"lfs.multiparttransfers": "true",
+	})
+	require.Nil(t, err)
+
+	m := NewManifestWithClient(cli)
+	a := m.NewUploadAdapter(MultipartAdapterName)
+
+	var mr sync.Mutex
+	var reported []int64
+	cb := func(name string, total, read int64, current int) error {
+		mr.Lock()
+		reported = append(reported, read)
+		mr.Unlock()
+		return nil
+	}
+
+	require.Nil(t, a.Begin(&adapterConfig{
+		apiClient:           cli,
+		concurrentTransfers: 2,
+		remote:              "origin",
+	}, cb))
+
+	results := a.Add(&Transfer{
+		Name:          "a.dat",
+		Oid:           oid,
+		Size:          int64(len(contents)),
+		Path:          path,
+		Authenticated: true,
+		Actions: ActionSet{
+			"part-1":   &Action{Href: srv.URL + "/part-1"},
+			"part-2":   &Action{Href: srv.URL + "/part-2"},
+			"part-3":   &Action{Href: srv.URL + "/part-3"},
+			"complete": &Action{Href: srv.URL + "/complete"},
+		},
+	})
+
+	for res := range results {
+		assert.Nil(t, res.Error)
+	}
+	a.End()
+
+	assert.Equal(t, []string{contents[0:15]}, received["/part-1"])
+	assert.Equal(t, []string{contents[15:30], contents[15:30]}, received["/part-2"])
+	assert.Equal(t, []string{contents[30:]}, received["/part-3"])
+
+	assert.Equal(t, oid, completed.Oid)
+	assert.EqualValues(t, len(contents), completed.Size)
+	assert.Equal(t, []*multipartCompletedPart{
+		{Number: 1, ETag: "etag1"},
+		{Number: 2, ETag: "etag2"},
+		{Number: 3, ETag: "etag3"},
+	}, completed.Parts)
+
+	sort.Slice(reported, func(i, j int) bool { return reported[i] < reported[j] })
+	assert.EqualValues(t, len(contents), reported[len(reported)-1])
+}
+
+func TestMultipartUploadRejectsCorruptObject(t *testing.T) {
+	dir, err := ioutil.TempDir("", "multipart-upload")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "object")
+	require.Nil(t, ioutil.WriteFile(path, []byte("contents"), 0644))
+
+	cli, err := lfsapi.NewClient(nil, lfsapi.UniqTestEnv{
+		"lfs.multiparttransfers": "true",
+	})
+	require.Nil(t, err)
+
+	m := NewManifestWithClient(cli)
+	a := m.NewUploadAdapter(MultipartAdapterName)
+	require.Nil(t, a.Begin(&adapterConfig{
+		apiClient:           cli,
+		concurrentTransfers: 1,
+	}, nil))
+
+	results := a.Add(&Transfer{
+		Name: "a.dat",
+		Oid:  strings.Repeat("0", 64),
+		Size: 8,
+		Path: path,
+		Actions: ActionSet{
+			"part-1":   &Action{Href: "http://example.com/part-1"},
+			"complete": &Action{Href: "http://example.com/complete"},
+		},
+	})
+
+	for res := range results {
+		if assert.NotNil(t, res.Error) {
+			assert.Equal(t, "corrupt object: a.dat (0000000000000000000000000000000000000000000000000000000000000000)", res.Error.Error())
+		}
+	}
+	a.End()
+}
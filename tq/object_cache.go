@@ -0,0 +1,189 @@
+package tq
+
+import "sync"
+
+// objectCache remembers what batch API responses have said about the objects
+// on each remote during this process, so that later batch requests for the
+// same objects need not be sent to the server again:
+//
+//  1. Objects which the server has, and their sizes, are remembered from
+//     download responses which gave a download action, and upload responses
+//     which gave no upload action, as well as from successful uploads. An
+//     upload request for such an object is answered without any actions,
+//     since there is nothing to upload.
+//  2. The download actions themselves are remembered too, and a download
+//     request is answered with them as long as none have expired, and every
+//     object in the request can be answered this way.
+//
+// An upload response which gives an upload action for an object means that
+// the server does not have it, and is never answered from the cache, so
+// anything remembered about the object on that remote is forgotten, as it is
+// when transferring an object fails.
+type objectCache struct {
+	// present holds the size of each object known to be on each remote,
+	// keyed by remote, and then OID.
+	present map[string]map[string]int64
+	// downloads holds the download responses for each remote, which
+	// include their actions, keyed by remote, and then OID.
+	downloads map[string]map[string]*cachedDownload
+	// adapters holds the transfer adapter named by the latest response
+	// from each remote, keyed by remote and operation.
+	adapters map[string]string
+
+	mu sync.Mutex
+}
+
+type cachedDownload struct {
+	t       *Transfer
+	adapter string
+}
+
+func newObjectCache() *objectCache {
+	return &objectCache{
+		present:   make(map[string]map[string]int64),
+		downloads: make(map[string]map[string]*cachedDownload),
+		adapters:  make(map[string]string),
+	}
+}
+
+// Lookup splits the objects of "bReq" into those which can be answered from
+// the cache, which are returned as they would be in a batch response, and the
+// rest, which must be sent to the server "remote". The name of the transfer
+// adapter to use for the cached objects is returned too.
+func (c *objectCache) Lookup(remote string, bReq *batchRequest) (cached, rest []*Transfer, adapter string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	adapter = c.adapters[remote+"\x00"+bReq.Operation]
+
+	switch bReq.Operation {
+	case Upload.String():
+		for _, o := range bReq.Objects {
+			if size, ok := c.present[remote][o.Oid]; ok && size == o.Size {
+				cached = append(cached, &Transfer{Oid: o.Oid, Size: o.Size})
+			} else {
+				rest = append(rest, o)
+			}
+		}
+		return cached, rest, adapter
+
+	case Download.String():
+		// The cached actions are specific to the adapter they were
+		// given for, so they can only be used if every object has
+		// them, for the same adapter, which is still acceptable.
+		var name string
+		for i, o := range bReq.Objects {
+			d := c.downloads[remote][o.Oid]
+			if d == nil || d.t.Size != o.Size || (i > 0 && d.adapter != name) ||
+				!allowsAdapter(bReq, d.adapter) || isExpired(d.t) {
+				return nil, bReq.Objects, adapter
+			}
+			name = d.adapter
+			cached = append(cached, newTransfer(d.t, "", ""))
+		}
+		return cached, nil, name
+	}
+
+	return nil, bReq.Objects, adapter
+}
+
+// Record remembers what "bRes", the response from "remote" to a batch request
+// for "operation", says about its objects.
+func (c *objectCache) Record(remote, operation string, bRes *BatchResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.adapters[remote+"\x00"+operation] = bRes.TransferAdapterName
+
+	for _, o := range bRes.Objects {
+		if o.Error != nil {
+			continue
+		}
+
+		switch operation {
+		case Upload.String():
+			if len(o.Actions) == 0 && len(o.Links) == 0 {
+				c.setPresent(remote, o.Oid, o.Size)
+			} else {
+				c.forget(remote, o.Oid)
+			}
+
+		case Download.String():
+			if _, ok := o.Actions["download"]; !ok {
+				if _, ok := o.Links["download"]; !ok {
+					continue
+				}
+			}
+
+			c.setPresent(remote, o.Oid, o.Size)
+			if c.downloads[remote] == nil {
+				c.downloads[remote] = make(map[string]*cachedDownload)
+			}
+			c.downloads[remote][o.Oid] = &cachedDownload{
+				t:       newTransfer(o, "", ""),
+				adapter: bRes.TransferAdapterName,
+			}
+		}
+	}
+}
+
+// Uploaded remembers that the object given by "oid" and "size" was uploaded
+// to "remote" successfully.
+func (c *objectCache) Uploaded(remote, oid string, size int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.setPresent(remote, oid, size)
+}
+
+// Forget discards everything remembered about the object given by "oid" on
+// "remote", such as when transferring it failed.
+func (c *objectCache) Forget(remote, oid string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.forget(remote, oid)
+}
+
+func (c *objectCache) setPresent(remote, oid string, size int64) {
+	if c.present[remote] == nil {
+		c.present[remote] = make(map[string]int64)
+	}
+	c.present[remote][oid] = size
+}
+
+func (c *objectCache) forget(remote, oid string) {
+	delete(c.present[remote], oid)
+	delete(c.downloads[remote], oid)
+}
+
+// allowsAdapter returns whether the adapter "name" is one of those "bReq"
+// accepts, where an empty list of names, or an empty name, means the basic
+// adapter.
+func allowsAdapter(bReq *batchRequest, name string) bool {
+	if len(name) == 0 {
+		name = BasicAdapterName
+	}
+	if len(bReq.TransferAdapterNames) == 0 {
+		return name == BasicAdapterName
+	}
+
+	for _, n := range bReq.TransferAdapterNames {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isExpired returns whether any action of "t" has expired, or is about to.
+func isExpired(t *Transfer) bool {
+	for _, set := range []ActionSet{t.Actions, t.Links} {
+		for _, a := range set {
+			if _, expired := a.IsExpiredWithin(objectExpirationToTransfer); expired {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package tq
+
+import "sync"
+
+// pauseGate holds back the goroutines which wait on it while it is paused,
+// until it is resumed. The zero value is a gate which is not paused.
+type pauseGate struct {
+	mu sync.Mutex
+	// resumed is nil unless the gate is paused, and is closed when it is
+	// resumed.
+	resumed chan struct{}
+}
+
+// pause pauses the gate, returning false if it was already paused.
+func (g *pauseGate) pause() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.resumed != nil {
+		return false
+	}
+	g.resumed = make(chan struct{})
+	return true
+}
+
+// resume lets everyone waiting on the gate carry on, returning false if it was
+// not paused.
+func (g *pauseGate) resume() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.resumed == nil {
+		return false
+	}
+	close(g.resumed)
+	g.resumed = nil
+	return true
+}
+
+// paused returns whether the gate is paused.
+func (g *pauseGate) paused() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.resumed != nil
+}
+
+// wait blocks for as long as the gate is paused, or until "done" is closed,
+// if it is not nil.
+func (g *pauseGate) wait(done <-chan struct{}) {
+	for {
+		g.mu.Lock()
+		resumed := g.resumed
+		g.mu.Unlock()
+
+		if resumed == nil {
+			return
+		}
+
+		select {
+		case <-resumed:
+			// The gate may have been paused again before this
+			// goroutine got to run, so check it again.
+		case <-done:
+			return
+		}
+	}
+}
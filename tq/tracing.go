@@ -0,0 +1,142 @@
+package tq
+
+// Tracer starts the spans a TransferQueue given to WithTracer records its work
+// in, so that its batch API requests and transfers can be followed in a
+// distributed tracing system. It is small enough to be implemented on top of
+// an OpenTelemetry tracer, or any other, without Git LFS depending on one.
+type Tracer interface {
+	// Start begins a span called "name", which ends when End is called on
+	// the Span returned.
+	Start(name string) Span
+}
+
+// Span is a single operation a TransferQueue has started. It may be used from
+// a different goroutine than the one which started it, but only from one at a
+// time.
+type Span interface {
+	// SetAttribute records "value", which is a string, int, int64 or bool,
+	// under "key".
+	SetAttribute(key string, value interface{})
+	// End ends the span, with "err" as its outcome, or with success if
+	// "err" is nil.
+	End(err error)
+}
+
+const (
+	// BatchSpanName is the name of the span started for each batch API
+	// request, or each batch sent to a standalone transfer agent.
+	BatchSpanName = "git-lfs.batch"
+	// TransferSpanName is the name of the span started for each attempt to
+	// transfer an object. An object which is retried has one such span for
+	// each attempt.
+	TransferSpanName = "git-lfs.transfer"
+)
+
+// The attributes recorded on spans.
+const (
+	// AttrOperation is "upload" or "download".
+	AttrOperation = "lfs.operation"
+	// AttrRemote is the name of the remote objects are transferred to or
+	// from.
+	AttrRemote = "lfs.remote"
+	// AttrObjects is the number of objects in a batch.
+	AttrObjects = "lfs.objects"
+	// AttrAdapter is the name of the transfer adapter the server chose.
+	AttrAdapter = "lfs.adapter"
+	// AttrEndpoint is the URL of the LFS API objects are transferred with,
+	// without its user information, query or fragment.
+	AttrEndpoint = "lfs.endpoint"
+	// AttrOid is the OID of the object being transferred.
+	AttrOid = "lfs.oid"
+	// AttrSize is the size of the object being transferred, in bytes.
+	AttrSize = "lfs.size"
+	// AttrBytes is the number of bytes transferred, which is less than the
+	// size of the object when only a range of it is downloaded.
+	AttrBytes = "lfs.bytes"
+	// AttrRetries is how many times the object has been retried before the
+	// attempt the span is for.
+	AttrRetries = "lfs.retries"
+)
+
+// WithTracer records each batch and object transfer the queue makes as a span
+// started by "t".
+func WithTracer(t Tracer) Option {
+	return func(tq *TransferQueue) {
+		tq.tracer = t
+	}
+}
+
+// startSpan starts the span "name" with the tracer given to WithTracer, or
+// returns one which does nothing, if there is none.
+func (q *TransferQueue) startSpan(name string) Span {
+	if q.tracer == nil {
+		return noopSpan{}
+	}
+
+	s := q.tracer.Start(name)
+	s.SetAttribute(AttrOperation, q.direction.String())
+	s.SetAttribute(AttrRemote, q.remote)
+	return s
+}
+
+// startTransferSpan starts the span for an attempt to transfer "t", with
+// "adapter" from "endpoint", which is ended by endTransferSpan.
+func (q *TransferQueue) startTransferSpan(t *Transfer, adapter, endpoint string) Span {
+	s := q.startSpan(TransferSpanName)
+	s.SetAttribute(AttrOid, t.Oid)
+	s.SetAttribute(AttrSize, t.Size)
+	s.SetAttribute(AttrAdapter, adapter)
+	s.SetAttribute(AttrRetries, q.rc.CountFor(t.Oid))
+	if len(endpoint) > 0 {
+		s.SetAttribute(AttrEndpoint, auditURL(endpoint))
+	}
+	return s
+}
+
+// trackTransferSpan starts the span for an attempt to transfer "t", and keeps
+// it until endTransferSpan is called with its result.
+func (q *TransferQueue) trackTransferSpan(t *Transfer, adapter, endpoint string) {
+	if q.tracer == nil {
+		return
+	}
+
+	s := q.startTransferSpan(t, adapter, endpoint)
+
+	q.trMutex.Lock()
+	defer q.trMutex.Unlock()
+
+	if q.spans == nil {
+		q.spans = make(map[string]Span)
+	}
+	q.spans[t.Oid] = s
+}
+
+// endTransferSpan ends the span started for the transfer of "t" by
+// trackTransferSpan, with "err" as its outcome.
+func (q *TransferQueue) endTransferSpan(t *Transfer, err error) {
+	if q.tracer == nil {
+		return
+	}
+
+	q.trMutex.Lock()
+	s, ok := q.spans[t.Oid]
+	delete(q.spans, t.Oid)
+	q.trMutex.Unlock()
+
+	if !ok {
+		return
+	}
+	if err == nil {
+		bytes := t.Size
+		if t.Range != nil {
+			bytes = t.Range.Length
+		}
+		s.SetAttribute(AttrBytes, bytes)
+	}
+	s.End(err)
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+func (noopSpan) End(err error)                              {}
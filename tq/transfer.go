@@ -3,6 +3,7 @@
 package tq
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -38,6 +39,89 @@ type Transfer struct {
 	Links         ActionSet    `json:"_links,omitempty"`
 	Error         *ObjectError `json:"error,omitempty"`
 	Path          string       `json:"path,omitempty"`
+
+	// Range, if non-nil, restricts a download to the given range of bytes
+	// of the object. Such partial downloads are written to Path as-is,
+	// and verified by their length only, since the OID of the object
+	// cannot be checked against part of its contents.
+	Range *ByteRange `json:"-"`
+
+	// Extra holds any fields of the object in a batch API request or
+	// response other than those above, such as metadata specific to a
+	// server. They are sent with the object in batch API requests, and
+	// read from the object in batch API responses, but are otherwise left
+	// alone.
+	Extra map[string]json.RawMessage `json:"-"`
+
+	// Group is the name given to the WithGroup option of the queue which
+	// made the transfer, if any. It is never sent to the server.
+	Group string `json:"-"`
+}
+
+// transferFields are the names of the fields of a Transfer in its JSON
+// encoding, which are never treated as Extra fields.
+var transferFields = map[string]bool{
+	"name": true, "oid": true, "size": true, "authenticated": true,
+	"actions": true, "_links": true, "error": true, "path": true,
+}
+
+// MarshalJSON encodes the transfer, along with its Extra fields.
+func (t *Transfer) MarshalJSON() ([]byte, error) {
+	type transfer Transfer
+
+	by, err := json.Marshal((*transfer)(t))
+	if err != nil || len(t.Extra) == 0 {
+		return by, err
+	}
+
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(by, &fields); err != nil {
+		return nil, err
+	}
+	for k, v := range t.Extra {
+		if !transferFields[k] {
+			fields[k] = v
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// UnmarshalJSON decodes the transfer, keeping any fields it does not know of
+// in Extra.
+func (t *Transfer) UnmarshalJSON(by []byte) error {
+	type transfer Transfer
+
+	if err := json.Unmarshal(by, (*transfer)(t)); err != nil {
+		return err
+	}
+
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(by, &fields); err != nil {
+		return err
+	}
+	for k := range fields {
+		if transferFields[k] {
+			delete(fields, k)
+		}
+	}
+
+	t.Extra = nil
+	if len(fields) > 0 {
+		t.Extra = fields
+	}
+	return nil
+}
+
+// ByteRange is a range of "Length" bytes within an object, starting at
+// "Offset".
+type ByteRange struct {
+	Offset int64
+	Length int64
+}
+
+// String returns the range as it appears in an HTTP Range header.
+func (r *ByteRange) String() string {
+	return fmt.Sprintf("bytes=%d-%d", r.Offset, r.Offset+r.Length-1)
 }
 
 func (t *Transfer) Rel(name string) (*Action, error) {
@@ -65,6 +149,18 @@ func (e *ObjectError) Error() string {
 	return fmt.Sprintf("[%d] %s", e.Code, e.Message)
 }
 
+// ServerMessage returns the message the server gave for the object. The server
+// gives no request ID for single objects.
+func (e *ObjectError) ServerMessage() (string, string) {
+	return e.Message, ""
+}
+
+// HTTPStatus returns the HTTP status code the server gave for the object, so
+// that errors.CategoryOf can tell why it failed.
+func (e *ObjectError) HTTPStatus() int {
+	return e.Code
+}
+
 // newTransfer returns a copy of the given Transfer, with the name and path
 // values set.
 func newTransfer(tr *Transfer, name string, path string) *Transfer {
@@ -75,6 +171,7 @@ func newTransfer(tr *Transfer, name string, path string) *Transfer {
 		Size:          tr.Size,
 		Authenticated: tr.Authenticated,
 		Actions:       make(ActionSet),
+		Extra:         tr.Extra,
 	}
 
 	if tr.Error != nil {
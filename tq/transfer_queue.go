@@ -1,6 +1,7 @@
 package tq
 
 import (
+	"encoding/json"
 	"os"
 	"sort"
 	"sync"
@@ -75,7 +76,7 @@ type batch []*objectTuple
 func (b batch) ToTransfers() []*Transfer {
 	transfers := make([]*Transfer, 0, len(b))
 	for _, t := range b {
-		transfers = append(transfers, &Transfer{Oid: t.Oid, Size: t.Size})
+		transfers = append(transfers, &Transfer{Oid: t.Oid, Size: t.Size, Extra: t.Extra})
 	}
 	return transfers
 }
@@ -97,6 +98,8 @@ type TransferQueue struct {
 	dryRun            bool
 	cb                progress.CopyCallback
 	meter             progress.Meter
+	completeCb        func(t *Transfer)
+	failCb            func(t *Transfer, err error)
 	errors            []error
 	transfers         map[string]*objectTuple
 	batchSize         int
@@ -111,14 +114,34 @@ type TransferQueue struct {
 	// wait is used to keep track of pending transfers. It is incremented
 	// once per unique OID on Add(), and is decremented when that transfer
 	// is marked as completed or failed, but not retried.
-	wait     sync.WaitGroup
-	manifest *Manifest
+	wait sync.WaitGroup
+	// mirrorWait is used to keep track of downloaded objects which are
+	// still being written to the mirror store.
+	mirrorWait sync.WaitGroup
+	manifest   *Manifest
 	rc       *retryCounter
+	// standaloneTransferAgent is the name of the custom transfer agent
+	// used without making batch API requests, if any.
+	standaloneTransferAgent string
+	// group is the name given to WithGroup, if any.
+	group string
+	// backoff is the strategy given to WithBackoff, if any.
+	backoff BackoffStrategy
+	// tracer is the tracer given to WithTracer, if any, and spans are the
+	// spans it has started for transfers still in progress, keyed by OID.
+	tracer Tracer
+	spans  map[string]Span
+	// pause is paused by Pause, and holdActive is the value given to
+	// HoldActiveWhenPaused.
+	pause      pauseGate
+	holdActive bool
 }
 
 type objectTuple struct {
 	Name, Path, Oid string
 	Size            int64
+	Range           *ByteRange
+	Extra           map[string]json.RawMessage
 }
 
 type Option func(*TransferQueue)
@@ -141,6 +164,54 @@ func WithProgressCallback(cb progress.CopyCallback) Option {
 	}
 }
 
+// WithCompletionCallback calls "fn" with each transfer as it completes
+// successfully, before it is reported to any channel returned by Watch.
+func WithCompletionCallback(fn func(t *Transfer)) Option {
+	return func(tq *TransferQueue) {
+		tq.completeCb = fn
+	}
+}
+
+// WithFailureCallback calls "fn" with each transfer which has failed for the
+// last time, and so will not be retried, along with why, which
+// errors.CategoryOf can sort into broad categories. The error is also returned
+// by Errors. Failures of a whole batch API request are not given to "fn".
+func WithFailureCallback(fn func(t *Transfer, err error)) Option {
+	return func(tq *TransferQueue) {
+		tq.failCb = fn
+	}
+}
+
+// WithGroup tags each transfer made by the queue with the group "name", and, if
+// the meter given to WithProgress is a progress.GroupMeter, counts the queue's
+// progress towards that group of it as well. Since the queue does not estimate
+// the size of its transfers itself, callers should Add() them to the meter
+// returned by the meter's Group() method for the same name.
+//
+// Finishing the queue only marks its group as finished, so that several queues
+// can share a meter; the meter itself must then be finished by the caller.
+func WithGroup(name string) Option {
+	return func(tq *TransferQueue) {
+		tq.group = name
+	}
+}
+
+// WithBackoff waits the delays given by "b" between retries of the queue's batch
+// API requests, instead of the default exponential backoff with jitter. A
+// Retry-After header sent by the server is still honored over it, up to thirty
+// seconds.
+func WithBackoff(b BackoffStrategy) Option {
+	return func(tq *TransferQueue) { tq.backoff = b }
+}
+
+// HoldActiveWhenPaused makes Pause hold the transfers already in flight, as
+// well as stopping new ones from starting. Each is held once it has copied the
+// chunk it is copying, without being aborted, and carries on from there when
+// the queue is resumed.
+func HoldActiveWhenPaused(hold bool) Option {
+	return func(tq *TransferQueue) { tq.holdActive = hold }
+}
+
 func WithBatchSize(size int) Option {
 	return func(tq *TransferQueue) { tq.batchSize = size }
 }
@@ -167,6 +238,7 @@ func NewTransferQueue(dir Direction, manifest *Manifest, remote string, options
 	}
 
 	q.rc.MaxRetries = q.manifest.maxRetries
+	q.standaloneTransferAgent = q.manifest.standaloneTransferAgentFor(dir, remote)
 
 	if q.batchSize <= 0 {
 		q.batchSize = defaultBatchSize
@@ -180,6 +252,9 @@ func NewTransferQueue(dir Direction, manifest *Manifest, remote string, options
 	if q.meter == nil {
 		q.meter = progress.Noop()
 	}
+	if gm, ok := q.meter.(progress.GroupMeter); ok && len(q.group) > 0 {
+		q.meter = gm.Group(q.group)
+	}
 
 	q.collectorWait.Add(1)
 	q.errorwait.Add(1)
@@ -191,13 +266,43 @@ func NewTransferQueue(dir Direction, manifest *Manifest, remote string, options
 // Add adds a *Transfer to the transfer queue. It only increments the amount
 // of waiting the TransferQueue has to do if the *Transfer "t" is new.
 func (q *TransferQueue) Add(name, path, oid string, size int64) {
-	t := &objectTuple{
+	q.add(&objectTuple{
 		Name: name,
 		Path: path,
 		Oid:  oid,
 		Size: size,
-	}
-
+	})
+}
+
+// AddRange adds a download of only the bytes of the object in range "r" to the
+// transfer queue, to be written to "path". The object's full size must still be
+// given as "size". As with Add, an object is only transferred once per queue,
+// so the same object may not be added both partially and in full.
+func (q *TransferQueue) AddRange(name, path, oid string, size int64, r *ByteRange) {
+	q.add(&objectTuple{
+		Name:  name,
+		Path:  path,
+		Oid:   oid,
+		Size:  size,
+		Range: r,
+	})
+}
+
+// AddWithExtra adds an object to the transfer queue like Add, sending the
+// fields in "extra" along with it in batch API requests. Any extra fields the
+// server returns for the object are available from the Extra field of the
+// *Transfer given to the WithCompletionCallback option.
+func (q *TransferQueue) AddWithExtra(name, path, oid string, size int64, extra map[string]json.RawMessage) {
+	q.add(&objectTuple{
+		Name:  name,
+		Path:  path,
+		Oid:   oid,
+		Size:  size,
+		Extra: extra,
+	})
+}
+
+func (q *TransferQueue) add(t *objectTuple) {
 	if isNew := q.remember(t); !isNew {
 		tracerx.Printf("already transferring %q, skipping duplicate", t.Oid)
 		return
@@ -232,8 +337,8 @@ func (q *TransferQueue) remember(t *objectTuple) bool {
 //      a. If the read was a channel close, go to step 4.
 //      b. If the read was a TransferTransferable item, go to step 3.
 //   3. Append the item to the batch.
-//   4. Sort the batch by descending object size, make a batch API call, send
-//      the items to the `*adapterBase`.
+//   4. Sort the batch by descending object size, wait while the queue is
+//      paused, make a batch API call, send the items to the `*adapterBase`.
 //   5. Process the worker results, incrementing and appending retries if
 //      possible.
 //   6. If the `q.incoming` channel is open, go to step 2.
@@ -262,6 +367,9 @@ func (q *TransferQueue) collectBatches() {
 		// size.
 		sort.Sort(sort.Reverse(batch))
 
+		// Make no batch API requests while the queue is paused.
+		q.pause.wait(nil)
+
 		retries, err := q.enqueueAndCollectRetriesFor(batch)
 		if err != nil {
 			q.errorc <- err
@@ -290,41 +398,58 @@ func (q *TransferQueue) enqueueAndCollectRetriesFor(batch batch) (batch, error)
 	tracerx.Printf("tq: sending batch of size %d", len(batch))
 
 	q.meter.Pause()
+	span := q.startSpan(BatchSpanName)
+	span.SetAttribute(AttrObjects, len(batch))
+
 	var bRes *BatchResponse
-	if q.manifest.standaloneTransferAgent != "" {
+	if q.standaloneTransferAgent != "" {
 		// Trust the external transfer agent can do everything by itself.
 		objects := make([]*Transfer, 0, len(batch))
 		for _, t := range batch {
-			objects = append(objects, &Transfer{Oid: t.Oid, Size: t.Size, Path: t.Path})
+			objects = append(objects, &Transfer{Oid: t.Oid, Size: t.Size, Path: t.Path, Range: t.Range, Extra: t.Extra})
 		}
 		bRes = &BatchResponse{
 			Objects:             objects,
-			TransferAdapterName: q.manifest.standaloneTransferAgent,
+			TransferAdapterName: q.standaloneTransferAgent,
 		}
 	} else {
 		// Query the Git LFS server for what transfer method to use and
 		// details such as URLs, authentication, etc.
 		var err error
-		bRes, err = Batch(q.manifest, q.direction, q.remote, batch.ToTransfers())
+		bRes, err = q.batch(batch.ToTransfers())
 		if err != nil {
 			// If there was an error making the batch API call, mark all of
 			// the objects for retry, and return them along with the error
 			// that was encountered. If any of the objects couldn't be
-			// retried, they will be marked as failed.
+			// retried, they will be marked as failed. If the batch
+			// API call has retries of its own, they have already
+			// been exhausted, so the objects are not retried.
 			for _, t := range batch {
-				if q.canRetryObject(t.Oid, err) {
+				if q.manifest.BatchRetries() == 0 && q.canRetryObject(t.Oid, err) {
 					q.rc.Increment(t.Oid)
 
 					next = append(next, t)
 				} else {
+					q.audit(&Transfer{Oid: t.Oid, Size: t.Size, Range: t.Range}, err)
 					q.wait.Done()
 				}
 			}
 
+			span.End(err)
 			return next, err
 		}
 	}
 
+	adapterName := bRes.TransferAdapterName
+	if len(adapterName) == 0 {
+		adapterName = BasicAdapterName
+	}
+	span.SetAttribute(AttrAdapter, adapterName)
+	if len(bRes.endpoint.Url) > 0 {
+		span.SetAttribute(AttrEndpoint, auditURL(bRes.endpoint.Url))
+	}
+	span.End(nil)
+
 	if len(bRes.Objects) == 0 {
 		return next, nil
 	}
@@ -336,7 +461,18 @@ func (q *TransferQueue) enqueueAndCollectRetriesFor(batch batch) (batch, error)
 
 	for _, o := range bRes.Objects {
 		if o.Error != nil {
-			q.errorc <- errors.Wrapf(o.Error, "[%v] %v", o.Oid, o.Error.Message)
+			q.trMutex.Lock()
+			t, ok := q.transfers[o.Oid]
+			q.trMutex.Unlock()
+
+			err := errors.Wrapf(o.Error, "[%v] %v", o.Oid, o.Error.Message)
+			if ok {
+				tr := newTransfer(o, t.Name, t.Path)
+				q.startTransferSpan(tr, adapterName, bRes.endpoint.Url).End(err)
+				q.fail(tr, err)
+			} else {
+				q.errorc <- err
+			}
 			q.Skip(o.Size)
 			q.wait.Done()
 
@@ -357,6 +493,18 @@ func (q *TransferQueue) enqueueAndCollectRetriesFor(batch batch) (batch, error)
 			q.wait.Done()
 		} else {
 			tr := newTransfer(o, t.Name, t.Path)
+			tr.Range = t.Range
+			tr.Group = q.group
+			if q.direction == Download && tr.Size != t.Size {
+				if q.manifest.trustServerSize {
+					tracerx.Printf("tq: server reports %d bytes for %s, not %d; accepting the size sent by the server", tr.Size, tr.Oid, t.Size)
+				} else {
+					// Downloads are checked against the
+					// size given by the pointer, not the
+					// one the server reports.
+					tr.Size = t.Size
+				}
+			}
 
 			if a, err := tr.Rel(q.direction.String()); err != nil {
 				// XXX(taylor): duplication
@@ -367,16 +515,17 @@ func (q *TransferQueue) enqueueAndCollectRetriesFor(batch batch) (batch, error)
 					tracerx.Printf("tq: enqueue retry #%d for %q (size: %d): %s", count, tr.Oid, tr.Size, err)
 					next = append(next, t)
 				} else {
-					q.errorc <- errors.Errorf("[%v] %v", tr.Name, err)
+					q.fail(tr, errors.Errorf("[%v] %v", tr.Name, err))
 
 					q.Skip(o.Size)
 					q.wait.Done()
 				}
-			} else if a == nil && q.manifest.standaloneTransferAgent == "" {
+			} else if a == nil && q.standaloneTransferAgent == "" {
 				q.Skip(o.Size)
 				q.wait.Done()
 			} else {
 				q.meter.StartTransfer(t.Name)
+				q.trackTransferSpan(tr, adapterName, bRes.endpoint.Url)
 				toTransfer = append(toTransfer, tr)
 			}
 		}
@@ -395,6 +544,18 @@ func (q *TransferQueue) enqueueAndCollectRetriesFor(batch batch) (batch, error)
 	return next, nil
 }
 
+// batch makes a batch API request for "objects" with the manifest's client, or
+// a copy of it which waits the delays given to WithBackoff between retries.
+func (q *TransferQueue) batch(objects []*Transfer) (*BatchResponse, error) {
+	if q.backoff == nil || len(objects) == 0 {
+		return Batch(q.manifest, q.direction, q.remote, objects)
+	}
+
+	c := *q.manifest.batchClient()
+	c.backoff = q.backoff
+	return batchWith(&c, q.manifest, q.direction, q.remote, objects)
+}
+
 // makeBatch returns a new, empty batch, with a capacity equal to the maximum
 // batch size designated by the `*TransferQueue`.
 func (q *TransferQueue) makeBatch() batch { return make(batch, 0, q.batchSize) }
@@ -413,6 +574,7 @@ func (q *TransferQueue) addToAdapter(e lfsapi.Endpoint, pending []*Transfer) <-c
 
 		q.errorc <- err
 		for _, t := range pending {
+			q.endTransferSpan(t, err)
 			q.Skip(t.Size)
 			q.wait.Done()
 		}
@@ -501,6 +663,18 @@ func (q *TransferQueue) handleTransferResult(
 	res TransferResult, retries chan<- *objectTuple,
 ) {
 	oid := res.Transfer.Oid
+	q.endTransferSpan(res.Transfer, res.Error)
+
+	if cache := q.manifest.batchClient().cache; cache != nil {
+		// Whatever the server said about the object is no longer to
+		// be trusted if the transfer failed, and a successful upload
+		// means that it has the object now.
+		if res.Error != nil {
+			cache.Forget(q.remote, oid)
+		} else if q.direction == Upload {
+			cache.Uploaded(q.remote, oid, res.Transfer.Size)
+		}
+	}
 
 	if res.Error != nil {
 		// If there was an error encountered when processing the
@@ -519,23 +693,40 @@ func (q *TransferQueue) handleTransferResult(
 			if ok {
 				retries <- t
 			} else {
-				q.errorc <- res.Error
+				q.fail(res.Transfer, res.Error)
 			}
 		} else {
 			// If the error wasn't retriable, OR the object has
 			// exceeded its retry budget, it will be NOT be sent to
 			// the retry channel, and the error will be reported
 			// immediately.
-			q.errorc <- res.Error
+			q.fail(res.Transfer, res.Error)
 			q.wait.Done()
 		}
 	} else {
 		// Otherwise, if the transfer was successful, notify all of the
 		// watchers, and mark it as finished.
+		if q.direction == Download {
+			q.audit(res.Transfer, nil)
+		}
+		if q.completeCb != nil {
+			q.completeCb(res.Transfer)
+		}
+
 		for _, c := range q.watchers {
 			c <- oid
 		}
 
+		if q.direction == Download {
+			// Writing to the mirror store may be slow, so it is
+			// not done on the goroutine which handles results.
+			q.mirrorWait.Add(1)
+			go func(t *Transfer) {
+				defer q.mirrorWait.Done()
+				q.mirror(t)
+			}(res.Transfer)
+		}
+
 		q.meter.FinishTransfer(res.Transfer.Name)
 		q.wait.Done()
 	}
@@ -557,6 +748,11 @@ func (q *TransferQueue) useAdapter(name string) {
 		q.finishAdapter()
 	}
 	q.adapter = q.manifest.NewAdapterOrDefault(name, q.direction)
+	if c, ok := q.adapter.(*customAdapter); ok {
+		// Only the standalone agent for this queue's endpoint transfers
+		// objects which the server has not given actions for.
+		c.standalone = len(q.standaloneTransferAgent) > 0 && c.name == q.standaloneTransferAgent
+	}
 }
 
 func (q *TransferQueue) finishAdapter() {
@@ -567,6 +763,61 @@ func (q *TransferQueue) finishAdapter() {
 	}
 }
 
+// ActiveTransfers returns the transfers which are currently in flight, along
+// with the number of bytes transferred so far for each.
+func (q *TransferQueue) ActiveTransfers() []*ActiveTransfer {
+	q.adapterInitMutex.Lock()
+	defer q.adapterInitMutex.Unlock()
+
+	if a, ok := q.adapter.(activeTransferAdapter); ok && q.adapterInProgress {
+		return a.ActiveTransfers()
+	}
+	return nil
+}
+
+// Cancel aborts the in-flight transfer of the object given by "oid". The
+// transfer is not retried, nor marked as complete, and is reported among
+// Errors() as a *TransferCancelledError. Cancel returns false if the object is
+// not currently being transferred.
+func (q *TransferQueue) Cancel(oid string) bool {
+	q.adapterInitMutex.Lock()
+	defer q.adapterInitMutex.Unlock()
+
+	if a, ok := q.adapter.(activeTransferAdapter); ok && q.adapterInProgress {
+		return a.CancelTransfer(oid)
+	}
+	return false
+}
+
+// Pause stops the queue from starting any more transfers, or making batch API
+// requests for them, until Resume is called, without cancelling anything. The
+// transfers already in flight carry on, unless HoldActiveWhenPaused was given.
+// Transfers may still be added and cancelled while the queue is paused, but
+// Wait does not return until it is resumed. Pause returns false if the queue
+// was already paused.
+func (q *TransferQueue) Pause() bool {
+	if !q.pause.pause() {
+		return false
+	}
+	tracerx.Printf("tq: paused")
+	return true
+}
+
+// Resume lets the queue carry on with its transfers after Pause, returning
+// false if it was not paused.
+func (q *TransferQueue) Resume() bool {
+	if !q.pause.resume() {
+		return false
+	}
+	tracerx.Printf("tq: resumed")
+	return true
+}
+
+// Paused returns whether the queue is paused.
+func (q *TransferQueue) Paused() bool {
+	return q.pause.paused()
+}
+
 func (q *TransferQueue) Skip(size int64) {
 	q.meter.Skip(size)
 }
@@ -592,6 +843,10 @@ func (q *TransferQueue) ensureAdapterBegun(e lfsapi.Endpoint) error {
 		return nil
 	}
 
+	if a, ok := q.adapter.(pausableAdapter); ok {
+		a.setPauseGate(&q.pause, q.holdActive)
+	}
+
 	tracerx.Printf("tq: starting transfer adapter %q", q.adapter.Name())
 	err := q.adapter.Begin(q.toAdapterCfg(e), cb)
 	if err != nil {
@@ -624,6 +879,7 @@ func (q *TransferQueue) Wait() {
 
 	q.wait.Wait()
 	q.collectorWait.Wait()
+	q.mirrorWait.Wait()
 
 	q.finishAdapter()
 	close(q.errorc)
@@ -644,6 +900,16 @@ func (q *TransferQueue) Watch() chan string {
 	return c
 }
 
+// fail reports the error "err", which the transfer "t" failed with for the
+// last time, to Errors and to the callback given to WithFailureCallback.
+func (q *TransferQueue) fail(t *Transfer, err error) {
+	q.audit(t, err)
+	if q.failCb != nil {
+		q.failCb(t, err)
+	}
+	q.errorc <- err
+}
+
 // This goroutine collects errors returned from transfers
 func (q *TransferQueue) errorCollector() {
 	for err := range q.errorc {
@@ -1,6 +1,7 @@
 package tq
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/git-lfs/git-lfs/lfsapi"
@@ -139,3 +140,49 @@ func testAdapterRegButBasicOnly(t *testing.T) {
 	lu := m.GetUploadAdapterNames()
 	assert.Equal([]string{BasicAdapterName}, lu)
 }
+
+func TestTransferEncodesExtraFields(t *testing.T) {
+	tr := &Transfer{
+		Oid:  "a",
+		Size: 1,
+		Extra: map[string]json.RawMessage{
+			"project": json.RawMessage(`"example"`),
+			"oid":     json.RawMessage(`"not-a"`),
+		},
+	}
+
+	by, err := json.Marshal(tr)
+	require.Nil(t, err)
+	assert.JSONEq(t, `{"oid":"a","size":1,"project":"example"}`, string(by))
+}
+
+func TestTransferDecodesExtraFields(t *testing.T) {
+	tr := &Transfer{}
+	require.Nil(t, json.Unmarshal([]byte(`{
+		"oid": "a",
+		"size": 1,
+		"actions": {"download": {"href": "http://example.com"}},
+		"project": "example",
+		"tags": ["one", "two"]
+	}`), tr))
+
+	assert.Equal(t, "a", tr.Oid)
+	assert.EqualValues(t, 1, tr.Size)
+	assert.Equal(t, "http://example.com", tr.Actions["download"].Href)
+	assert.Equal(t, map[string]json.RawMessage{
+		"project": json.RawMessage(`"example"`),
+		"tags":    json.RawMessage(`["one", "two"]`),
+	}, tr.Extra)
+
+	assert.Equal(t, tr.Extra, newTransfer(tr, "a.dat", "path").Extra)
+}
+
+func TestTransferWithoutExtraFields(t *testing.T) {
+	tr := &Transfer{}
+	require.Nil(t, json.Unmarshal([]byte(`{"oid":"a","size":1}`), tr))
+	assert.Nil(t, tr.Extra)
+
+	by, err := json.Marshal(tr)
+	require.Nil(t, err)
+	assert.JSONEq(t, `{"oid":"a","size":1}`, string(by))
+}
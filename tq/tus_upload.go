@@ -7,9 +7,9 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/git-lfs/git-lfs/errors"
-	"github.com/git-lfs/git-lfs/lfsapi"
 	"github.com/git-lfs/git-lfs/progress"
 )
 
@@ -21,6 +21,16 @@ const (
 // Adapter for tus.io protocol resumaable uploads
 type tusUploadAdapter struct {
 	*adapterBase
+
+	// chunkSize is the largest number of bytes sent in a single PATCH
+	// request, or 0 to send the rest of the object in one.
+	chunkSize int64
+
+	// created holds the URL of the upload created for each OID by the
+	// Creation extension, so that a retry resumes that upload, rather
+	// than asking about the URL the batch API gave again.
+	created   map[string]string
+	createdMu sync.Mutex
 }
 
 func (a *tusUploadAdapter) ClearTempStorage() error {
@@ -43,38 +53,44 @@ func (a *tusUploadAdapter) DoTransfer(ctx interface{}, t *Transfer, cb ProgressC
 		return errors.Errorf("No upload action for object: %s", t.Oid)
 	}
 
-	// Note not supporting the Creation extension since the batch API generates URLs
-	// Also not supporting Concatenation to support parallel uploads of chunks; forward only
+	// Note not supporting Concatenation to support parallel uploads of chunks; forward only
 
 	// 1. Send HEAD request to determine upload start point
 	//    Request must include Tus-Resumable header (version)
-	a.Trace("xfer: sending tus.io HEAD request for %q", t.Oid)
-	req, err := a.newHTTPRequest("HEAD", rel)
-	if err != nil {
-		return err
-	}
-
-	req.Header.Set("Tus-Resumable", TusVersion)
-
-	res, err := a.doHTTP(t, req)
-	if err != nil {
-		return errors.NewRetriableError(err)
+	var offset int64
+	var exists bool
+	if href, ok := a.createdHref(t.Oid); ok {
+		created := tusAction(rel, href)
+		if offset, exists, err = a.uploadOffset(t, created); err != nil {
+			return err
+		}
+		if exists {
+			rel = created
+		} else {
+			// The server has forgotten the upload it created.
+			a.setCreatedHref(t.Oid, "")
+		}
 	}
-
-	//    Response will contain Upload-Offset if supported
-	offHdr := res.Header.Get("Upload-Offset")
-	if len(offHdr) == 0 {
-		return fmt.Errorf("Missing Upload-Offset header from tus.io HEAD response at %q, contact server admin", rel.Href)
+	if !exists {
+		if offset, exists, err = a.uploadOffset(t, rel); err != nil {
+			return err
+		}
 	}
-	offset, err := strconv.ParseInt(offHdr, 10, 64)
-	if err != nil || offset < 0 {
-		return fmt.Errorf("Invalid Upload-Offset value %q in response from tus.io HEAD at %q, contact server admin", offHdr, rel.Href)
+	if !exists {
+		// The server has not started an upload at the URL the batch
+		// API gave, so create one there with the Creation extension.
+		if rel, err = a.create(t, rel); err != nil {
+			return err
+		}
+		a.setCreatedHref(t.Oid, rel.Href)
 	}
+
 	// Upload-Offset=size means already completed (skip)
 	// Batch API will probably already detect this, but handle just in case
 	if offset >= t.Size {
 		a.Trace("xfer: tus.io HEAD offset %d indicates %q is already fully uploaded, skipping", offset, t.Oid)
 		advanceCallbackProgress(cb, t, t.Size)
+		a.setCreatedHref(t.Oid, "")
 		return nil
 	}
 
@@ -93,38 +109,167 @@ func (a *tusUploadAdapter) DoTransfer(ctx interface{}, t *Transfer, cb ProgressC
 		advanceCallbackProgress(cb, t, offset)
 	}
 
-	// 2. Send PATCH request with byte start point (even if 0) in Upload-Offset
-	//    Response status must be 204
-	//    Response Upload-Offset must be request Upload-Offset plus sent bytes
-	//    Response may include Upload-Expires header in which case check not passed
+	// 2. Send PATCH requests from the offset until the whole object has
+	//    been sent, of at most chunkSize bytes each if it is set.
+	//    A PATCH which fails leaves the server to say, through the HEAD
+	//    request of the retry, where to resume from, so the progress made
+	//    so far is taken back first.
+	for offset < t.Size {
+		n := t.Size - offset
+		if a.chunkSize > 0 && n > a.chunkSize {
+			n = a.chunkSize
+		}
 
-	a.Trace("xfer: sending tus.io PATCH request for %q", t.Oid)
-	req, err = a.newHTTPRequest("PATCH", rel)
+		next, err := a.patch(t, rel, f, offset, n, cb, authOkFunc)
+		if err != nil {
+			if cb != nil {
+				cb(t.Name, t.Size, 0, -int(offset))
+			}
+			return err
+		}
+		offset = next
+		// Auth is only signalled as ok by the first request.
+		authOkFunc = nil
+	}
+	a.setCreatedHref(t.Oid, "")
+
+	return verifyUpload(a.apiClient, a.remote, t)
+}
+
+// createdHref returns the URL of the upload created for "oid" by an earlier
+// attempt to upload it, if there is one.
+func (a *tusUploadAdapter) createdHref(oid string) (string, bool) {
+	a.createdMu.Lock()
+	defer a.createdMu.Unlock()
+
+	href, ok := a.created[oid]
+	return href, ok
+}
+
+// setCreatedHref records "href" as the URL of the upload created for "oid", or
+// forgets it, if "href" is empty.
+func (a *tusUploadAdapter) setCreatedHref(oid, href string) {
+	a.createdMu.Lock()
+	defer a.createdMu.Unlock()
+
+	if len(href) == 0 {
+		delete(a.created, oid)
+	} else {
+		a.created[oid] = href
+	}
+}
+
+// tusAction returns the action for the upload at "href", which is sent with the
+// headers, and expires with, "rel".
+func tusAction(rel *Action, href string) *Action {
+	return &Action{
+		Href:      href,
+		Header:    rel.Header,
+		ExpiresAt: rel.ExpiresAt,
+		ExpiresIn: rel.ExpiresIn,
+		createdAt: rel.createdAt,
+	}
+}
+
+// uploadOffset sends a HEAD request to "rel" and returns the number of bytes of
+// "t" which the server reports having received, and whether it has an upload at
+// "rel" at all.
+func (a *tusUploadAdapter) uploadOffset(t *Transfer, rel *Action) (int64, bool, error) {
+	a.Trace("xfer: sending tus.io HEAD request for %q", t.Oid)
+	req, err := a.newHTTPRequest("HEAD", rel)
 	if err != nil {
-		return err
+		return 0, false, err
+	}
+
+	req.Header.Set("Tus-Resumable", TusVersion)
+
+	res, err := a.doHTTP(t, req)
+	if res != nil && res.StatusCode == 404 {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, errors.NewRetriableError(err)
+	}
+
+	//    Response will contain Upload-Offset if supported
+	offHdr := res.Header.Get("Upload-Offset")
+	if len(offHdr) == 0 {
+		return 0, false, fmt.Errorf("Missing Upload-Offset header from tus.io HEAD response at %q, contact server admin", rel.Href)
+	}
+	offset, err := strconv.ParseInt(offHdr, 10, 64)
+	if err != nil || offset < 0 {
+		return 0, false, fmt.Errorf("Invalid Upload-Offset value %q in response from tus.io HEAD at %q, contact server admin", offHdr, rel.Href)
+	}
+	return offset, true, nil
+}
+
+// create sends a POST request to "rel" to create an upload of "t", as described
+// by the tus.io Creation extension, and returns the action for the upload the
+// server created, at the URL given by the Location header of its response.
+func (a *tusUploadAdapter) create(t *Transfer, rel *Action) (*Action, error) {
+	a.Trace("xfer: sending tus.io POST request to create upload for %q", t.Oid)
+	req, err := a.newHTTPRequest("POST", rel)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Tus-Resumable", TusVersion)
+	req.Header.Set("Upload-Length", strconv.FormatInt(t.Size, 10))
+	req.Header.Set("Content-Length", "0")
+
+	res, err := a.doHTTP(t, req)
+	if err != nil {
+		return nil, errors.NewRetriableError(err)
+	}
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+
+	if res.StatusCode != 201 {
+		return nil, fmt.Errorf("Invalid status for tus.io POST at %q: %d, contact server admin", rel.Href, res.StatusCode)
+	}
+
+	location := res.Header.Get("Location")
+	if len(location) == 0 {
+		return nil, fmt.Errorf("Missing Location header from tus.io POST response at %q, contact server admin", rel.Href)
+	}
+	u, err := req.URL.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid Location value %q in response from tus.io POST at %q, contact server admin", location, rel.Href)
+	}
+
+	return tusAction(rel, u.String()), nil
+}
+
+// patch sends the "n" bytes of "f" from "offset" to "rel" in a single PATCH
+// request, and returns the offset which the server reports having received up
+// to, which must be the end of the bytes that were sent.
+func (a *tusUploadAdapter) patch(t *Transfer, rel *Action, f *os.File, offset, n int64, cb ProgressCallback, authOkFunc func()) (int64, error) {
+	a.Trace("xfer: sending tus.io PATCH request for %q (%d bytes from %d)", t.Oid, n, offset)
+	req, err := a.newHTTPRequest("PATCH", rel)
+	if err != nil {
+		return 0, err
 	}
 
 	req.Header.Set("Tus-Resumable", TusVersion)
 	req.Header.Set("Upload-Offset", strconv.FormatInt(offset, 10))
 	req.Header.Set("Content-Type", "application/offset+octet-stream")
-	req.Header.Set("Content-Length", strconv.FormatInt(t.Size-offset, 10))
-	req.ContentLength = t.Size - offset
+	req.Header.Set("Content-Length", strconv.FormatInt(n, 10))
+	req.ContentLength = n
 
 	// Ensure progress callbacks made while uploading
-	// Wrap callback to give name context
+	// Wrap callback to give name context, and count from the offset
 	ccb := func(totalSize int64, readSoFar int64, readSinceLast int) error {
 		if cb != nil {
-			return cb(t.Name, totalSize, readSoFar, readSinceLast)
+			return cb(t.Name, t.Size, offset+readSoFar, readSinceLast)
 		}
 		return nil
 	}
 
-	var reader lfsapi.ReadSeekCloser = progress.NewBodyWithCallback(f, t.Size, ccb)
-	reader = newStartCallbackReader(reader, func() error {
-		// seek to the offset since lfsapi.Client rewinds the body
-		if _, err := f.Seek(offset, os.SEEK_CUR); err != nil {
-			return err
-		}
+	// The section starts at the offset, so that lfsapi.Client rewinding
+	// the body does not send the bytes before it.
+	section := &multipartSection{io.NewSectionReader(f, offset, n)}
+	cbr := progress.NewBodyWithCallback(section, n, ccb)
+	req.Body = newStartCallbackReader(cbr, func() error {
 		// Signal auth was ok on first read; this frees up other workers to start
 		if authOkFunc != nil {
 			authOkFunc()
@@ -132,40 +277,64 @@ func (a *tusUploadAdapter) DoTransfer(ctx interface{}, t *Transfer, cb ProgressC
 		return nil
 	})
 
-	req.Body = reader
-
 	req = a.apiClient.LogRequest(req, "lfs.data.upload")
-	res, err = a.doHTTP(t, req)
+	res, err := a.doHTTP(t, req)
 	if err != nil {
-		return errors.NewRetriableError(err)
+		// Take back the bytes sent by this request, which the caller
+		// doesn't know about.
+		if perr := cbr.ResetProgress(); perr != nil {
+			err = errors.Wrap(err, perr.Error())
+		}
+
+		return 0, errors.NewRetriableError(err)
 	}
 
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+
 	// A status code of 403 likely means that an authentication token for the
 	// upload has expired. This can be safely retried.
 	if res.StatusCode == 403 {
+		cbr.ResetProgress()
 		err = errors.New("http: received status 403")
-		return errors.NewRetriableError(err)
+		return 0, errors.NewRetriableError(err)
 	}
 
 	if res.StatusCode > 299 {
-		return errors.Wrapf(nil, "Invalid status for %s %s: %d",
+		cbr.ResetProgress()
+		return 0, errors.Wrapf(nil, "Invalid status for %s %s: %d",
 			req.Method,
 			strings.SplitN(req.URL.String(), "?", 2)[0],
 			res.StatusCode,
 		)
 	}
 
-	io.Copy(ioutil.Discard, res.Body)
-	res.Body.Close()
+	// Servers which don't report the new offset are trusted to have
+	// received everything that was sent.
+	expected := offset + n
+	offHdr := res.Header.Get("Upload-Offset")
+	if len(offHdr) == 0 {
+		return expected, nil
+	}
 
-	return verifyUpload(a.apiClient, a.remote, t)
+	next, err := strconv.ParseInt(offHdr, 10, 64)
+	if err != nil || next != expected {
+		cbr.ResetProgress()
+		// Retrying asks the server where to resume from.
+		return 0, errors.NewRetriableError(fmt.Errorf("tus.io PATCH at %q reported Upload-Offset %q, expected %d", rel.Href, offHdr, expected))
+	}
+	return next, nil
 }
 
 func configureTusAdapter(m *Manifest) {
 	m.RegisterNewAdapterFunc(TusAdapterName, Upload, func(name string, dir Direction) Adapter {
 		switch dir {
 		case Upload:
-			bu := &tusUploadAdapter{newAdapterBase(name, dir, nil)}
+			bu := &tusUploadAdapter{
+				adapterBase: newAdapterBase(name, dir, nil),
+				chunkSize:   m.tusChunkSize,
+				created:     make(map[string]string),
+			}
 			// self implements impl
 			bu.transferImpl = bu
 			return bu
@@ -0,0 +1,227 @@
+package tq
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/git-lfs/git-lfs/errors"
+	"github.com/git-lfs/git-lfs/lfsapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tusTestServer is a tus.io server for a single upload, which records the
+// requests made to it.
+type tusTestServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	received []byte
+	exists   bool
+	// path is where the upload is, once it exists.
+	path string
+	// badOffset is sent as the Upload-Offset of the response to the next
+	// PATCH request, if it is set.
+	badOffset string
+	// failPatch is whether the next PATCH request fails, with a 403.
+	failPatch bool
+	requests  []string
+	verified  bool
+}
+
+func newTusTestServer(t *testing.T, exists bool) *tusTestServer {
+	s := &tusTestServer{exists: exists, path: "/upload"}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.requests = append(s.requests, r.Method+" "+r.URL.Path+" "+r.Header.Get("Upload-Offset"))
+
+		if r.URL.Path == "/verify" {
+			s.verified = true
+			return
+		}
+
+		assert.Equal(t, TusVersion, r.Header.Get("Tus-Resumable"))
+
+		switch r.Method {
+		case "POST":
+			assert.Equal(t, "/upload", r.URL.Path)
+			assert.NotEmpty(t, r.Header.Get("Upload-Length"))
+			s.exists = true
+			s.path = "/created"
+			w.Header().Set("Location", "created")
+			w.WriteHeader(201)
+		case "HEAD":
+			if !s.exists || r.URL.Path != s.path {
+				w.WriteHeader(404)
+				return
+			}
+			w.Header().Set("Upload-Offset", strconv.Itoa(len(s.received)))
+		case "PATCH":
+			assert.Equal(t, s.path, r.URL.Path)
+			assert.Equal(t, strconv.Itoa(len(s.received)), r.Header.Get("Upload-Offset"))
+			by, err := ioutil.ReadAll(r.Body)
+			assert.Nil(t, err)
+			if s.failPatch {
+				s.failPatch = false
+				w.WriteHeader(403)
+				return
+			}
+			s.received = append(s.received, by...)
+
+			offset := strconv.Itoa(len(s.received))
+			if len(s.badOffset) > 0 {
+				offset, s.badOffset = s.badOffset, ""
+			}
+			w.Header().Set("Upload-Offset", offset)
+			w.WriteHeader(204)
+		}
+	}))
+	return s
+}
+
+// tusTestUpload uploads "contents" to "srv", as the batch API would give
+// "href", making up to "attempts" attempts while they fail with retriable
+// errors, and returns the error of the last one.
+func tusTestUpload(t *testing.T, srv *tusTestServer, href string, chunkSize string, contents string, attempts int) error {
+	sum := sha256.Sum256([]byte(contents))
+	oid := hex.EncodeToString(sum[:])
+
+	dir, err := ioutil.TempDir("", "tus-upload")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, oid)
+	require.Nil(t, ioutil.WriteFile(path, []byte(contents), 0644))
+
+	cli, err := lfsapi.NewClient(nil, lfsapi.UniqTestEnv{
+		"lfs.tustransfers":           "true",
+		"lfs.tustransfers.chunksize": chunkSize,
+	})
+	require.Nil(t, err)
+
+	m := NewManifestWithClient(cli)
+	a := m.NewUploadAdapter(TusAdapterName)
+	require.NotNil(t, a)
+	require.Nil(t, a.Begin(&adapterConfig{
+		apiClient:           cli,
+		concurrentTransfers: 1,
+		remote:              "origin",
+	}, nil))
+
+	var resErr error
+	for i := 0; i < attempts; i++ {
+		results := a.Add(&Transfer{
+			Name:          "a.dat",
+			Oid:           oid,
+			Size:          int64(len(contents)),
+			Path:          path,
+			Authenticated: true,
+			Actions: ActionSet{
+				"upload": &Action{Href: srv.URL + href},
+				"verify": &Action{Href: srv.URL + "/verify"},
+			},
+		})
+
+		for res := range results {
+			resErr = res.Error
+		}
+		if !errors.IsRetriableError(resErr) {
+			break
+		}
+	}
+	a.End()
+	return resErr
+}
+
+func TestTusUploadSendsChunks(t *testing.T) {
+	srv := newTusTestServer(t, true)
+	defer srv.Close()
+
+	contents := "the quick brown fox jumps over the lazy dog"
+	require.Nil(t, tusTestUpload(t, srv, "/upload", "16", contents, 1))
+
+	assert.Equal(t, contents, string(srv.received))
+	assert.Equal(t, []string{
+		"HEAD /upload ",
+		"PATCH /upload 0",
+		"PATCH /upload 16",
+		"PATCH /upload 32",
+		"POST /verify ",
+	}, srv.requests)
+	assert.True(t, srv.verified)
+}
+
+func TestTusUploadResumesFromServerOffset(t *testing.T) {
+	srv := newTusTestServer(t, true)
+	defer srv.Close()
+
+	contents := "the quick brown fox jumps over the lazy dog"
+	srv.received = []byte(contents[:20])
+	require.Nil(t, tusTestUpload(t, srv, "/upload", "0", contents, 1))
+
+	assert.Equal(t, contents, string(srv.received))
+	assert.Equal(t, []string{
+		"HEAD /upload ",
+		"PATCH /upload 20",
+		"POST /verify ",
+	}, srv.requests)
+}
+
+func TestTusUploadCreatesUpload(t *testing.T) {
+	srv := newTusTestServer(t, false)
+	defer srv.Close()
+
+	contents := "the quick brown fox jumps over the lazy dog"
+	require.Nil(t, tusTestUpload(t, srv, "/upload", "0", contents, 1))
+
+	assert.Equal(t, contents, string(srv.received))
+	assert.Equal(t, []string{
+		"HEAD /upload ",
+		"POST /upload ",
+		"PATCH /created 0",
+		"POST /verify ",
+	}, srv.requests)
+}
+
+func TestTusUploadRetriesUnexpectedOffset(t *testing.T) {
+	srv := newTusTestServer(t, true)
+	defer srv.Close()
+	srv.badOffset = "3"
+
+	err := tusTestUpload(t, srv, "/upload", "16", "the quick brown fox jumps over the lazy dog", 1)
+	require.NotNil(t, err)
+	assert.True(t, errors.IsRetriableError(err))
+	assert.Contains(t, err.Error(), `reported Upload-Offset "3", expected 16`)
+	assert.False(t, srv.verified)
+}
+
+func TestTusUploadRetryResumesCreatedUpload(t *testing.T) {
+	srv := newTusTestServer(t, false)
+	defer srv.Close()
+	srv.failPatch = true
+
+	contents := "the quick brown fox jumps over the lazy dog"
+	require.Nil(t, tusTestUpload(t, srv, "/upload", "16", contents, 2))
+
+	assert.Equal(t, contents, string(srv.received))
+	assert.Equal(t, []string{
+		"HEAD /upload ",
+		"POST /upload ",
+		"PATCH /created 0",
+		"HEAD /created ",
+		"PATCH /created 0",
+		"PATCH /created 16",
+		"PATCH /created 32",
+		"POST /verify ",
+	}, srv.requests)
+}
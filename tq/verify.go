@@ -3,17 +3,45 @@ package tq
 import (
 	"net/http"
 
+	"github.com/git-lfs/git-lfs/errors"
 	"github.com/git-lfs/git-lfs/lfsapi"
 	"github.com/git-lfs/git-lfs/tools"
 	"github.com/rubyist/tracerx"
 )
 
 const (
-	maxVerifiesConfigKey     = "lfs.transfer.maxverifies"
-	defaultMaxVerifyAttempts = 3
+	maxVerifiesConfigKey       = "lfs.transfer.maxverifies"
+	verifyAfterUploadConfigKey = "lfs.transfer.verifyafterupload"
+	defaultMaxVerifyAttempts   = 3
 )
 
+// verifyUpload is called once "t" has been uploaded. It calls the "verify"
+// action of "t", if the server gave one, and then, if
+// "lfs.transfer.verifyafterupload" is enabled, checks that the server can now
+// serve the object, too.
 func verifyUpload(c *lfsapi.Client, remote string, t *Transfer) error {
+	if err := verifyAction(c, remote, t); err != nil {
+		return err
+	}
+
+	if git := c.GitEnv(); git == nil || !git.Bool(verifyAfterUploadConfigKey, false) {
+		return nil
+	}
+	return verifyDownloadable(c, remote, t)
+}
+
+// verifyDownload is called once "t" has been downloaded, but before it is moved
+// into place. It calls the "verify" action of "t", if the server gave one, so
+// that the server knows the object was received. A failed verify request is
+// retriable, like any other failure to transfer the object.
+func verifyDownload(c *lfsapi.Client, remote string, t *Transfer) error {
+	if err := verifyAction(c, remote, t); err != nil {
+		return errors.NewRetriableError(errors.Wrapf(err, "Unable to verify download of %s", t.Oid))
+	}
+	return nil
+}
+
+func verifyAction(c *lfsapi.Client, remote string, t *Transfer) error {
 	action, err := t.Actions.Get("verify")
 	if err != nil {
 		return err
@@ -40,8 +68,7 @@ func verifyUpload(c *lfsapi.Client, remote string, t *Transfer) error {
 	}
 	req.Header.Set("Content-Type", "application/vnd.git-lfs+json")
 
-	mv := c.GitEnv().Int(maxVerifiesConfigKey, defaultMaxVerifyAttempts)
-	mv = tools.MaxInt(defaultMaxVerifyAttempts, mv)
+	mv := maxVerifyAttempts(c)
 	req = c.LogRequest(req, "lfs.verify")
 
 	for i := 1; i <= mv; i++ {
@@ -63,3 +90,54 @@ func verifyUpload(c *lfsapi.Client, remote string, t *Transfer) error {
 	}
 	return err
 }
+
+// verifyDownloadable makes a batch API request to download the object given by
+// "t", and returns an error naming it unless the server has it, with the same
+// size. This catches storage which accepts uploads, but then loses them.
+// Requests that fail are attempted again, up to "lfs.transfer.maxverifies"
+// times, as with the "verify" action.
+func verifyDownloadable(c *lfsapi.Client, remote string, t *Transfer) error {
+	tc := &tqClient{Client: c}
+	mv := maxVerifyAttempts(c)
+
+	var bRes *BatchResponse
+	var err error
+	for i := 1; i <= mv; i++ {
+		tracerx.Printf("tq: verify %s is downloadable attempt #%d (max: %d)", t.Oid[:7], i, mv)
+
+		bRes, err = tc.Batch(remote, &batchRequest{
+			Operation: Download.String(),
+			Objects:   []*Transfer{{Oid: t.Oid, Size: t.Size}},
+		})
+		if err == nil {
+			break
+		}
+		tracerx.Printf("tq: verify err: %+v", err.Error())
+	}
+	if err != nil {
+		return errors.Wrapf(err, "Unable to verify upload of %s", t.Oid)
+	}
+
+	for _, o := range bRes.Objects {
+		if o.Oid != t.Oid {
+			continue
+		}
+
+		if o.Error != nil {
+			return errors.NewVerificationError(errors.Errorf("Object %s is missing from the server after upload: %s", t.Oid, o.Error.Message))
+		}
+		if o.Size != t.Size {
+			return errors.NewVerificationError(errors.Errorf("Object %s has size %d on the server after upload, expected %d", t.Oid, o.Size, t.Size))
+		}
+		if a, err := o.Rel("download"); err != nil || a == nil {
+			return errors.NewVerificationError(errors.Errorf("Object %s is not downloadable from the server after upload", t.Oid))
+		}
+		return nil
+	}
+	return errors.NewVerificationError(errors.Errorf("Object %s is missing from the server after upload", t.Oid))
+}
+
+func maxVerifyAttempts(c *lfsapi.Client) int {
+	mv := c.GitEnv().Int(maxVerifiesConfigKey, defaultMaxVerifyAttempts)
+	return tools.MaxInt(defaultMaxVerifyAttempts, mv)
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync/atomic"
 	"testing"
 
@@ -64,3 +65,107 @@ func TestVerifySuccess(t *testing.T) {
 	assert.Nil(t, verifyUpload(c, "origin", tr))
 	assert.EqualValues(t, 1, called)
 }
+
+func verifyDownloadableServer(t *testing.T, called *uint32, obj map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.String() != "/objects/batch" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		atomic.AddUint32(called, 1)
+
+		var req batchRequest
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "download", req.Operation)
+		require.Len(t, req.Objects, 1)
+		assert.Equal(t, "abcd1234", req.Objects[0].Oid)
+		assert.EqualValues(t, 123, req.Objects[0].Size)
+
+		w.Header().Set("Content-Type", "application/vnd.git-lfs+json")
+		assert.Nil(t, json.NewEncoder(w).Encode(map[string]interface{}{
+			"objects": []interface{}{obj},
+		}))
+	}))
+}
+
+func TestVerifyAfterUploadSuccess(t *testing.T) {
+	var called uint32
+	srv := verifyDownloadableServer(t, &called, map[string]interface{}{
+		"oid":  "abcd1234",
+		"size": 123,
+		"actions": map[string]interface{}{
+			"download": map[string]interface{}{"href": "https://example.com/abcd1234"},
+		},
+	})
+	defer srv.Close()
+
+	c, err := lfsapi.NewClient(nil, lfsapi.UniqTestEnv{
+		"lfs.url":                        srv.URL,
+		"lfs.transfer.verifyafterupload": "true",
+	})
+	require.Nil(t, err)
+
+	assert.Nil(t, verifyUpload(c, "origin", &Transfer{Oid: "abcd1234", Size: 123}))
+	assert.EqualValues(t, 1, called)
+}
+
+func TestVerifyAfterUploadMissing(t *testing.T) {
+	var called uint32
+	srv := verifyDownloadableServer(t, &called, map[string]interface{}{
+		"oid":  "abcd1234",
+		"size": 123,
+		"error": map[string]interface{}{
+			"code":    404,
+			"message": "Object does not exist",
+		},
+	})
+	defer srv.Close()
+
+	c, err := lfsapi.NewClient(nil, lfsapi.UniqTestEnv{
+		"lfs.url":                        srv.URL,
+		"lfs.transfer.verifyafterupload": "true",
+	})
+	require.Nil(t, err)
+
+	err = verifyUpload(c, "origin", &Transfer{Oid: "abcd1234", Size: 123})
+	require.NotNil(t, err)
+	assert.Equal(t, "Object abcd1234 is missing from the server after upload: Object does not exist", err.Error())
+	assert.EqualValues(t, 1, called)
+}
+
+func TestVerifyAfterUploadSizeMismatch(t *testing.T) {
+	var called uint32
+	srv := verifyDownloadableServer(t, &called, map[string]interface{}{
+		"oid":  "abcd1234",
+		"size": 12,
+		"actions": map[string]interface{}{
+			"download": map[string]interface{}{"href": "https://example.com/abcd1234"},
+		},
+	})
+	defer srv.Close()
+
+	c, err := lfsapi.NewClient(nil, lfsapi.UniqTestEnv{
+		"lfs.url":                        srv.URL,
+		"lfs.transfer.verifyafterupload": "true",
+	})
+	require.Nil(t, err)
+
+	err = verifyUpload(c, "origin", &Transfer{Oid: "abcd1234", Size: 123})
+	require.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "has size 12 on the server after upload, expected 123"))
+}
+
+func TestVerifyAfterUploadDisabled(t *testing.T) {
+	var called uint32
+	srv := verifyDownloadableServer(t, &called, nil)
+	defer srv.Close()
+
+	c, err := lfsapi.NewClient(nil, lfsapi.UniqTestEnv{
+		"lfs.url": srv.URL,
+	})
+	require.Nil(t, err)
+
+	assert.Nil(t, verifyUpload(c, "origin", &Transfer{Oid: "abcd1234", Size: 123}))
+	assert.EqualValues(t, 0, called)
+}